@@ -0,0 +1,221 @@
+package accesslog
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/events"
+)
+
+// Mode controls how much of a request ends up in the access log.
+type Mode string
+
+const (
+	// ModeOff disables access logging.
+	ModeOff Mode = "off"
+
+	// ModeFull logs requests including credentials and tokens. Only use it for debugging.
+	ModeFull Mode = "full"
+
+	// ModeRedactTokens replaces credentials and tokens with a placeholder.
+	ModeRedactTokens Mode = "token-redacted"
+
+	// ModeHashSubjects redacts tokens and replaces client ids, users and remote addresses with a keyed hash, so
+	// requests of the same subject can be correlated without revealing who the subject is. Subjects are redacted if
+	// the middleware has no pseudonymizer.
+	ModeHashSubjects Mode = "subject-hashed"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveParameters are query parameters which carry credentials or tokens.
+var sensitiveParameters = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"id_token_hint": true,
+	"code":          true,
+	"token":         true,
+	"client_secret": true,
+	"password":      true,
+	"challenge":     true,
+	"consent":       true,
+}
+
+// subjectParameters are query parameters which identify a subject.
+var subjectParameters = map[string]bool{
+	"client_id": true,
+	"subject":   true,
+	"username":  true,
+}
+
+// Rule sets the mode of all endpoints whose path starts with Prefix.
+type Rule struct {
+	Prefix string
+	Mode   Mode
+}
+
+// Middleware writes one structured log entry per request. The mode is taken from the rule with the longest
+// matching prefix or Default if no rule matches.
+type Middleware struct {
+	Default Mode
+	Rules   []Rule
+
+	// Pseudonymizer hashes subjects in ModeHashSubjects. Unkeyed hashes of client ids or addresses can be reversed
+	// by hashing all candidates, so it must be keyed with a secret.
+	Pseudonymizer events.Pseudonymizer
+}
+
+// Parse creates a middleware from a specification like "token-redacted,/oauth2/token=subject-hashed,/health=off".
+// The entry without a path sets the default mode.
+func Parse(spec string) (*Middleware, error) {
+	m := &Middleware{Default: ModeRedactTokens}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 1 {
+			mode, err := parseMode(parts[0])
+			if err != nil {
+				return nil, err
+			}
+			m.Default = mode
+			continue
+		}
+
+		mode, err := parseMode(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		m.Rules = append(m.Rules, Rule{Prefix: parts[0], Mode: mode})
+	}
+	return m, nil
+}
+
+func parseMode(s string) (Mode, error) {
+	switch mode := Mode(strings.TrimSpace(s)); mode {
+	case ModeOff, ModeFull, ModeRedactTokens, ModeHashSubjects:
+		return mode, nil
+	}
+	return "", errors.Errorf("Unknown access log mode %s", s)
+}
+
+// ModeFor returns the mode which applies to path.
+func (m *Middleware) ModeFor(path string) Mode {
+	mode := m.Default
+	var longest int
+	for _, rule := range m.Rules {
+		if strings.HasPrefix(path, rule.Prefix) && len(rule.Prefix) >= longest {
+			mode = rule.Mode
+			longest = len(rule.Prefix)
+		}
+	}
+	return mode
+}
+
+// Wrap logs all requests handled by h.
+func (m *Middleware) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mode := m.ModeFor(r.URL.Path)
+		if mode == ModeOff {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rw, r)
+
+		logrus.WithFields(m.Fields(mode, r, rw.status, rw.size, time.Since(start))).Info("Request handled")
+	})
+}
+
+// Fields returns the log fields of a request with the privacy mode applied.
+func (m *Middleware) Fields(mode Mode, r *http.Request, status, size int, duration time.Duration) logrus.Fields {
+	fields := logrus.Fields{
+		"method":   r.Method,
+		"path":     r.URL.Path,
+		"status":   status,
+		"size":     size,
+		"duration": duration.String(),
+		"remote":   m.subject(mode, remoteHost(r.RemoteAddr)),
+	}
+
+	if query := m.filterQuery(mode, r.URL.Query()); query != "" {
+		fields["query"] = query
+	}
+
+	if user, _, ok := r.BasicAuth(); ok {
+		fields["client_id"] = m.subject(mode, user)
+	}
+
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if mode == ModeFull {
+			fields["authorization"] = auth
+		} else {
+			fields["authorization"] = strings.SplitN(auth, " ", 2)[0] + " " + redacted
+		}
+	}
+
+	return fields
+}
+
+func (m *Middleware) filterQuery(mode Mode, query url.Values) string {
+	if mode == ModeFull {
+		return query.Encode()
+	}
+
+	filtered := url.Values{}
+	for k, vs := range query {
+		for _, v := range vs {
+			if sensitiveParameters[k] {
+				v = redacted
+			} else if subjectParameters[k] {
+				v = m.subject(mode, v)
+			}
+			filtered.Add(k, v)
+		}
+	}
+	return filtered.Encode()
+}
+
+func (m *Middleware) subject(mode Mode, value string) string {
+	if mode != ModeHashSubjects || value == "" {
+		return value
+	} else if m.Pseudonymizer == nil {
+		return redacted
+	}
+	return m.Pseudonymizer.Pseudonymize(value, time.Now())
+}
+
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
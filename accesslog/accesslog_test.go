@@ -0,0 +1,78 @@
+package accesslog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/ory-am/hydra/accesslog"
+	"github.com/ory-am/hydra/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse("full, /oauth2/token=subject-hashed, /oauth2=token-redacted, /health=off")
+	require.Nil(t, err)
+
+	assert.Equal(t, ModeFull, m.ModeFor("/clients"))
+	assert.Equal(t, ModeRedactTokens, m.ModeFor("/oauth2/auth"))
+	assert.Equal(t, ModeHashSubjects, m.ModeFor("/oauth2/token"))
+	assert.Equal(t, ModeOff, m.ModeFor("/health"))
+
+	m, err = Parse("")
+	require.Nil(t, err)
+	assert.Equal(t, ModeRedactTokens, m.ModeFor("/"))
+
+	_, err = Parse("/oauth2/token=everything")
+	assert.NotNil(t, err)
+}
+
+func TestFields(t *testing.T) {
+	r, err := http.NewRequest("GET", "http://localhost/oauth2/auth?client_id=app&code=secret-code&state=abc", nil)
+	require.Nil(t, err)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Authorization", "Bearer secret-token")
+
+	m := &Middleware{Pseudonymizer: &events.HMACPseudonymizer{Secret: []byte("some-secret")}}
+	full := m.Fields(ModeFull, r, 200, 10, time.Second)
+	assert.Equal(t, "Bearer secret-token", full["authorization"])
+	assert.True(t, strings.Contains(full["query"].(string), "secret-code"))
+	assert.Equal(t, "10.0.0.1", full["remote"])
+
+	redacted := m.Fields(ModeRedactTokens, r, 200, 10, time.Second)
+	assert.Equal(t, "Bearer [REDACTED]", redacted["authorization"])
+	assert.False(t, strings.Contains(redacted["query"].(string), "secret-code"))
+	assert.True(t, strings.Contains(redacted["query"].(string), "client_id=app"))
+
+	hashed := m.Fields(ModeHashSubjects, r, 200, 10, time.Second)
+	assert.False(t, strings.Contains(hashed["query"].(string), "client_id=app"))
+	assert.NotEqual(t, "10.0.0.1", hashed["remote"])
+	assert.Equal(t, hashed["remote"], m.Fields(ModeHashSubjects, r, 200, 10, time.Second)["remote"])
+
+	// Hashes depend on the secret, so they can not be reversed by hashing candidate addresses.
+	other := &Middleware{Pseudonymizer: &events.HMACPseudonymizer{Secret: []byte("other-secret")}}
+	assert.NotEqual(t, hashed["remote"], other.Fields(ModeHashSubjects, r, 200, 10, time.Second)["remote"])
+
+	// Without a pseudonymizer subjects are redacted instead of hashed.
+	unkeyed := (&Middleware{}).Fields(ModeHashSubjects, r, 200, 10, time.Second)
+	assert.Equal(t, "[REDACTED]", unkeyed["remote"])
+	assert.True(t, strings.Contains(unkeyed["query"].(string), "client_id=%5BREDACTED%5D"))
+}
+
+func TestWrap(t *testing.T) {
+	m := &Middleware{Default: ModeRedactTokens}
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}))
+
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest("GET", "http://localhost/", nil)
+	require.Nil(t, err)
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "short and stout", w.Body.String())
+}
@@ -0,0 +1,218 @@
+// Package canonical serializes values to canonical JSON as defined by the JSON Canonicalization Scheme (RFC 8785).
+// The same value always yields the same bytes, regardless of struct field order, map iteration order or the Go version
+// which produced it, so that hashes and signatures over stored and exported objects stay comparable.
+//
+// Object members are sorted by the UTF-16 code units of their names, insignificant whitespace is dropped, strings use
+// the shortest escaping and numbers are formatted like ECMAScript formats IEEE 754 doubles. Integers beyond 2^53
+// therefore lose precision, as they would in any other JCS implementation.
+package canonical
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-errors/errors"
+)
+
+// Marshal returns the canonical JSON encoding of v. v is first encoded with encoding/json, so json tags and Marshaler
+// implementations are honored.
+func Marshal(v interface{}) ([]byte, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return Transform(out)
+}
+
+// Transform canonicalizes a JSON document.
+func Transform(in []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.New(err)
+	} else if dec.More() {
+		return nil, errors.New("Trailing data after JSON document")
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Hash returns the hex encoded SHA-256 hash of the canonical JSON encoding of v.
+func Hash(v interface{}) (string, error) {
+	out, err := Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(out)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case string:
+		encodeString(buf, t)
+	case json.Number:
+		f, err := strconv.ParseFloat(string(t), 64)
+		if err != nil {
+			return errors.Errorf("Number %s can not be represented as a double: %s", t, err)
+		}
+		n, err := formatNumber(f)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(n)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encode(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Sort(byCodeUnits(keys))
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encodeString(buf, k)
+			buf.WriteByte(':')
+			if err := encode(buf, t[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return errors.Errorf("Unexpected type %T", v)
+	}
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+func encodeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[c>>4])
+				buf.WriteByte(hexDigits[c&0xf])
+			} else {
+				buf.WriteRune(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// formatNumber formats f like ECMAScript's Number.prototype.toString.
+func formatNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", errors.New("NaN and infinity are not valid JSON numbers")
+	} else if f == 0 {
+		// This also turns -0 into 0.
+		return "0", nil
+	}
+
+	var sign string
+	if f < 0 {
+		sign, f = "-", -f
+	}
+
+	// The shortest digits which round trip, as d.ddde±x.
+	mantissa, exponent := splitExponent(strconv.FormatFloat(f, 'e', -1, 64))
+	digits := strings.Replace(mantissa, ".", "", 1)
+	k, n := len(digits), exponent+1
+
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k), nil
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:], nil
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits, nil
+	}
+
+	e := "e+"
+	if n-1 < 0 {
+		e = "e-"
+	}
+	e += strconv.Itoa(abs(n - 1))
+
+	if k == 1 {
+		return sign + digits + e, nil
+	}
+	return sign + digits[:1] + "." + digits[1:] + e, nil
+}
+
+func splitExponent(s string) (string, int) {
+	i := strings.IndexByte(s, 'e')
+	exponent, _ := strconv.Atoi(s[i+1:])
+	return s[:i], exponent
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// byCodeUnits sorts strings by their UTF-16 code units, which differs from sorting by bytes for characters outside
+// of the basic multilingual plane.
+type byCodeUnits []string
+
+func (s byCodeUnits) Len() int      { return len(s) }
+func (s byCodeUnits) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byCodeUnits) Less(i, j int) bool {
+	a, b := utf16.Encode([]rune(s[i])), utf16.Encode([]rune(s[j]))
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,51 @@
+package canonical_test
+
+import (
+	"testing"
+
+	. "github.com/ory-am/hydra/canonical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransform(t *testing.T) {
+	for k, c := range []struct {
+		in       string
+		expected string
+	}{
+		{in: `{"b": 1, "a": [true, null, "x"]}`, expected: `{"a":[true,null,"x"],"b":1}`},
+		{in: `{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001]}`, expected: `{"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27]}`},
+		{in: `[-0, 100, 1e21, 1e20, 0.000001, 0.0000001, -1.5e-7]`, expected: `[0,100,1e+21,100000000000000000000,0.000001,1e-7,-1.5e-7]`},
+		{in: `{"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/"}`, expected: `{"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		{in: `"<html> & \u2028"`, expected: "\"<html> & \u2028\""},
+		{in: `{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\ud83d\ude00": "Emoji: Grinning Face", "1": "One", "\u0080": "Control", "\u00f6": "Latin Small Letter O With Diaeresis", "\ufb33": "Hebrew Letter Dalet With Dagesh"}`, expected: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"},
+	} {
+		out, err := Transform([]byte(c.in))
+		require.Nil(t, err, "Case %d", k)
+		assert.Equal(t, c.expected, string(out), "Case %d", k)
+	}
+
+	for k, c := range []string{``, `{"a":1} {}`, `{"a":}`, `[1e400]`} {
+		_, err := Transform([]byte(c))
+		assert.NotNil(t, err, "Case %d", k)
+	}
+}
+
+func TestHash(t *testing.T) {
+	type client struct {
+		Name  string   `json:"name"`
+		ID    string   `json:"id"`
+		Hosts []string `json:"hosts"`
+	}
+
+	a, err := Hash(&client{ID: "foo", Name: "bar", Hosts: []string{"a"}})
+	require.Nil(t, err)
+	b, err := Hash(map[string]interface{}{"hosts": []string{"a"}, "name": "bar", "id": "foo"})
+	require.Nil(t, err)
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 64)
+
+	c, err := Hash(&client{ID: "foo", Name: "bar", Hosts: []string{"b"}})
+	require.Nil(t, err)
+	assert.NotEqual(t, a, c)
+}
@@ -3,7 +3,9 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/julienschmidt/httprouter"
@@ -11,13 +13,38 @@ import (
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/firewall"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/label"
+	"github.com/ory-am/hydra/pagination"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/quota"
 	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
 )
 
 type Handler struct {
 	Manager Manager
 	H       herodot.Herodot
 	W       firewall.Firewall
+
+	// Rotations keeps rotated secrets valid for RotationOverlap, unless the rotation request asks for another
+	// overlap.
+	Rotations       RotationManager
+	RotationOverlap time.Duration
+
+	// Settings stores the preferences of clients, for example the algorithm their ID tokens are signed with.
+	Settings SettingsManager
+
+	// Lifespans are the server's lifespans, which clients can shorten but not extend.
+	Lifespans Lifespans
+
+	// Lockouts are inspected and cleared by administrators. Lockouts are not enabled if nil.
+	Lockouts *Lockouts
+
+	// Labels are removed with the clients they are attached to.
+	Labels label.Manager
+
+	// Preconditions makes the If-Match check and the write atomic, see pkg.Preconditions.
+	Preconditions *pkg.Preconditions
 }
 
 const (
@@ -34,7 +61,15 @@ func (h *Handler) SetRoutes(r *httprouter.Router) {
 	r.GET(ClientsHandlerPath, h.GetAll)
 	r.POST(ClientsHandlerPath, h.Create)
 	r.GET(ClientsHandlerPath+"/:id", h.Get)
+	r.PUT(ClientsHandlerPath+"/:id", h.Update)
 	r.DELETE(ClientsHandlerPath+"/:id", h.Delete)
+	r.DELETE(ClientsHandlerPath, h.DeleteByOwner)
+	r.PATCH(ClientsHandlerPath, h.Bulk)
+	r.POST(ClientsHandlerPath+"/:id/rotate-secret", h.RotateSecret)
+	r.GET(ClientsHandlerPath+"/:id/settings", h.GetSettings)
+	r.PUT(ClientsHandlerPath+"/:id/settings", h.UpdateSettings)
+	r.GET(ClientsHandlerPath+"/:id/lockout", h.GetLockout)
+	r.DELETE(ClientsHandlerPath+"/:id/lockout", h.ClearLockout)
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -46,6 +81,16 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
+	if err := h.create(ctx, r, &c); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.WriteCreated(ctx, w, r, ClientsHandlerPath+"/"+c.GetID(), &c)
+}
+
+// create stores a new client with a generated secret if the caller may create it.
+func (h *Handler) create(ctx context.Context, r *http.Request, c *fosite.DefaultClient) error {
 	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
 		Resource: ClientsResource,
 		Action:   "create",
@@ -53,23 +98,25 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 			"owner": c.Owner,
 		},
 	}, Scope); err != nil {
-		h.H.WriteError(ctx, w, r, err)
-		return
+		return err
 	}
 
 	secret, err := sequence.RuneSequence(12, []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890_-.,:;$%!&/()=?+*#<>"))
 	if err != nil {
-		h.H.WriteError(ctx, w, r, errors.New(err))
-		return
+		return errors.New(err)
 	}
 	c.Secret = []byte(string(secret))
 
-	if err := h.Manager.CreateClient(&c); err != nil {
-		h.H.WriteError(ctx, w, r, err)
-		return
+	// The manager enforces the client quota, see QuotaManager.
+	if err := h.Manager.CreateClient(c); errors.Is(err, quota.ErrQuotaExceeded) {
+		return &herodot.Error{
+			Err:  errors.New("The client quota is exhausted"),
+			Code: http.StatusForbidden,
+		}
+	} else if err != nil {
+		return err
 	}
-
-	h.H.WriteCreated(ctx, w, r, ClientsHandlerPath+"/"+c.GetID(), &c)
+	return nil
 }
 
 func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -83,13 +130,43 @@ func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
+	page, err := pagination.Parse(r, "id", "client_name", "owner")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
 	c, err := h.Manager.GetClients()
 	if err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
-	h.H.Write(ctx, w, r, c)
+	clients := make([]*fosite.DefaultClient, 0, len(c))
+	for _, cl := range c {
+		clients = append(clients, cl)
+	}
+
+	indices, total := page.Apply(len(clients), func(i int, field string) string {
+		switch field {
+		case "id":
+			return clients[i].ID
+		case "client_name":
+			return clients[i].Name
+		case "owner":
+			return clients[i].Owner
+		}
+		return ""
+	})
+
+	// The listing stays a map of client ids to clients, the page decides which clients it contains.
+	res := make(map[string]*fosite.DefaultClient, len(indices))
+	for _, i := range indices {
+		res[clients[i].ID] = clients[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -113,25 +190,426 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 		return
 	}
 
+	pkg.WriteETag(w, c)
 	h.H.Write(ctx, w, r, c)
 }
 
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var c fosite.DefaultClient
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		h.H.WriteError(ctx, w, r, errors.New(err))
+		return
+	}
+
+	if err := h.update(ctx, r, id, r.Header.Get("If-Match"), &c); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, &c)
+}
+
+// update replaces the client with the given id if the caller may update it and ifMatch, if set, matches its entity
+// tag.
+func (h *Handler) update(ctx context.Context, r *http.Request, id, ifMatch string, c *fosite.DefaultClient) error {
+	o, err := h.Manager.GetClient(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(ClientResource, id),
+		Action:   "update",
+		Context: ladon.Context{
+			"owner": o.GetOwner(),
+		},
+	}, Scope); err != nil {
+		return err
+	}
+
+	c.ID = id
+	return h.Preconditions.Write(fmt.Sprintf(ClientResource, id), ifMatch, func() (interface{}, error) {
+		return h.Manager.GetClient(id)
+	}, func() error {
+		return h.Manager.UpdateClient(c)
+	})
+}
+
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var ctx = herodot.NewContext()
 	var id = ps.ByName("id")
 
+	if err := h.delete(ctx, r, id, r.Header.Get("If-Match")); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// delete removes the client with the given id, its settings and labels if the caller may delete it and ifMatch, if set,
+// matches its entity tag.
+func (h *Handler) delete(ctx context.Context, r *http.Request, id, ifMatch string) error {
 	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
 		Resource: fmt.Sprintf(ClientResource, id),
 		Action:   "delete",
 	}, Scope); err != nil {
+		return err
+	}
+
+	return h.Preconditions.Write(fmt.Sprintf(ClientResource, id), ifMatch, func() (interface{}, error) {
+		return h.Manager.GetClient(id)
+	}, func() error {
+		return h.remove(id)
+	})
+}
+
+// remove deletes a client together with its settings and labels.
+func (h *Handler) remove(id string) error {
+	if err := h.Manager.DeleteClient(id); err != nil {
+		return err
+	}
+
+	if h.Settings != nil {
+		if err := h.Settings.DeleteSettings(id); err != nil {
+			return err
+		}
+	}
+	if h.Labels != nil {
+		return h.Labels.DeleteLabels(label.KindClients, id)
+	}
+	return nil
+}
+
+// Bulk creates, updates and deletes many clients in one request, see pkg.BulkOperation. Every operation is authorized
+// and applied on its own, in the order of the request, and has its own result in the response.
+func (h *Handler) Bulk(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	ops, err := pkg.DecodeBulkOperations(r)
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	res := &pkg.BulkResponse{Results: []*pkg.BulkResult{}}
+	for _, op := range ops {
+		if op.Action == pkg.BulkDelete {
+			if err := h.delete(ctx, r, op.ID, op.IfMatch); err != nil {
+				res.Fail(op.ID, err)
+			} else {
+				res.Succeed(op.ID, http.StatusNoContent, nil)
+			}
+			continue
+		}
+
+		var c fosite.DefaultClient
+		if err := json.Unmarshal(op.Data, &c); err != nil {
+			res.Reject(op.ID, errors.New(err))
+			continue
+		}
+
+		if op.Action == pkg.BulkCreate {
+			if err := h.create(ctx, r, &c); err != nil {
+				res.Fail(c.ID, err)
+			} else {
+				res.Succeed(c.ID, http.StatusCreated, &c)
+			}
+		} else if err := h.update(ctx, r, op.ID, op.IfMatch, &c); err != nil {
+			res.Fail(op.ID, err)
+		} else {
+			res.Succeed(op.ID, http.StatusOK, &c)
+		}
+	}
+
+	h.H.Write(ctx, w, r, res)
+}
+
+// DeleteByOwner deletes every client owned by the query parameter owner.
+func (h *Handler) DeleteByOwner(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var owner = r.URL.Query().Get("owner")
+
+	if owner == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Missing query parameter owner"))
+		return
+	} else if !pkg.BulkConfirmed(r) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, pkg.ErrBulkNotConfirmed)
+		return
+	}
+
+	c, err := h.Manager.GetClients()
+	if err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
-	if err := h.Manager.DeleteClient(id); err != nil {
+	// Every client is checked before the first one is deleted, a single denied client aborts the whole deletion.
+	var ids []string
+	for id, cl := range c {
+		if cl.GetOwner() != owner {
+			continue
+		}
+
+		if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+			Resource: fmt.Sprintf(ClientResource, id),
+			Action:   "delete",
+			Context: ladon.Context{
+				"owner": owner,
+			},
+		}, Scope); err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	for _, id := range ids {
+		if err := h.remove(id); err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+	}
+
+	h.H.Write(ctx, w, r, &pkg.BulkDeletion{Deleted: len(ids)})
+}
+
+func (h *Handler) RotateSecret(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var rr SecretRotationRequest
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	o, err := h.Manager.GetClient(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(ClientResource, id),
+		Action:   "rotate",
+		Context: ladon.Context{
+			"owner": o.GetOwner(),
+		},
+	}, Scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if h.Rotations == nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Secret rotation is not enabled"))
+		return
+	}
+
+	// The body is optional.
+	if err := json.NewDecoder(r.Body).Decode(&rr); err != nil && err != io.EOF {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	overlap := h.RotationOverlap
+	if rr.Overlap != "" {
+		if overlap, err = time.ParseDuration(rr.Overlap); err != nil || overlap < 0 {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.Errorf("Overlap %s is not a valid duration", rr.Overlap))
+			return
+		}
+	}
+
+	stored, ok := o.(*fosite.DefaultClient)
+	if !ok {
+		h.H.WriteError(ctx, w, r, errors.Errorf("Client %s can not be rotated", id))
+		return
+	}
+
+	secret, err := pkg.GenerateSecret(26)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, errors.New(err))
+		return
+	}
+
+	rotated := *stored
+	previous := rotated.Secret
+	rotated.Secret = secret
+	if err := h.Manager.UpdateClient(&rotated); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	// UpdateClient replaced the plain text secret with its hash.
+	expiresAt := time.Now().Add(overlap)
+	if overlap > 0 {
+		err = h.Rotations.SetSecretRotation(&SecretRotation{
+			ClientID:       id,
+			Secret:         rotated.Secret,
+			PreviousSecret: previous,
+			ExpiresAt:      expiresAt,
+		})
+	} else {
+		err = h.Rotations.DeleteSecretRotation(id)
+	}
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.WriteCode(ctx, w, r, http.StatusCreated, &SecretRotationResponse{
+		ClientID:                id,
+		ClientSecret:            string(secret),
+		PreviousSecretExpiresAt: expiresAt,
+	})
+}
+
+// GetSettings returns the settings of a client. Clients which never stored settings have empty ones.
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	o, err := h.Manager.GetClient(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(ClientResource, id),
+		Action:   "get",
+		Context: ladon.Context{
+			"owner": o.GetOwner(),
+		},
+	}, Scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	settings, err := h.getSettings(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, settings)
+}
+
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var s Settings
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	o, err := h.Manager.GetClient(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(ClientResource, id),
+		Action:   "update",
+		Context: ladon.Context{
+			"owner": o.GetOwner(),
+		},
+	}, Scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if h.Settings == nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Client settings are not enabled"))
+		return
+	}
+
+	s.ClientID = id
+	if err := s.Validate(); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	} else if err := s.GetLifespans().Validate(h.Lifespans); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	} else if err := h.Settings.SetSettings(&s); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, &s)
+}
+
+// GetLockout returns the failed authentications of a client and until when the client is locked.
+func (h *Handler) GetLockout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if !h.allowLockoutAccess(ctx, w, r, id, "get") {
+		return
+	}
+
+	l, err := h.Lockouts.Manager.GetLockout(id)
+	if errors.Is(err, pkg.ErrNotFound) {
+		l = &Lockout{ClientID: id}
+	} else if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, l)
+}
+
+// ClearLockout unlocks a client and forgets its failed authentications.
+func (h *Handler) ClearLockout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if !h.allowLockoutAccess(ctx, w, r, id, "unlock") {
+		return
+	}
+
+	if err := h.Lockouts.Clear(id); err != nil && !errors.Is(err, pkg.ErrNotFound) {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *Handler) allowLockoutAccess(ctx context.Context, w http.ResponseWriter, r *http.Request, id, action string) bool {
+	o, err := h.Manager.GetClient(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return false
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(ClientResource, id),
+		Action:   action,
+		Context: ladon.Context{
+			"owner": o.GetOwner(),
+		},
+	}, Scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return false
+	}
+
+	if h.Lockouts == nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Client lockouts are not enabled"))
+		return false
+	}
+	return true
+}
+
+func (h *Handler) getSettings(id string) (*Settings, error) {
+	if h.Settings == nil {
+		return &Settings{ClientID: id}, nil
+	}
+
+	s, err := h.Settings.GetSettings(id)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return &Settings{ClientID: id}, nil
+	}
+	return s, err
+}
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+)
+
+const (
+	// EventLocked is published when a client is locked because it failed to authenticate too often.
+	EventLocked = "client.locked"
+
+	// EventUnlocked is published when an administrator clears the lockout of a client.
+	EventUnlocked = "client.unlocked"
+)
+
+var ErrClientLocked = &herodot.Error{
+	Err:  errors.New("The client is locked because it failed to authenticate too often"),
+	Code: http.StatusTooManyRequests,
+}
+
+// Lockout counts the failed authentications of a client.
+type Lockout struct {
+	ClientID string `json:"client_id" gorethink:"id"`
+
+	// Failures is the number of failed authentications since the client was last locked.
+	Failures int `json:"failures" gorethink:"failures"`
+
+	// Locks is the number of times the client was locked. Every lock lasts twice as long as the one before.
+	Locks int `json:"locks" gorethink:"locks"`
+
+	LastFailureAt time.Time `json:"last_failure_at" gorethink:"last_failure_at"`
+
+	// LockedUntil is the zero time if the client was never locked.
+	LockedUntil time.Time `json:"locked_until" gorethink:"locked_until"`
+}
+
+// IsLocked tells whether the client is locked at the given time.
+func (l *Lockout) IsLocked(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
+
+// LockoutManager stores the lockouts of clients which failed to authenticate.
+type LockoutManager interface {
+	// GetLockout returns the lockout of a client or pkg.ErrNotFound if the client has no failed authentications.
+	GetLockout(clientID string) (*Lockout, error)
+
+	SetLockout(l *Lockout) error
+
+	DeleteLockout(clientID string) error
+}
+
+// Lockouts locks clients which fail to authenticate Threshold times in a row. The first lock lasts Duration, every
+// further lock twice as long as the one before, up to MaxDuration. A client which did not fail for MaxDuration
+// starts over.
+type Lockouts struct {
+	Manager LockoutManager
+
+	// Clients is used to ignore failures of clients which do not exist, so that guessing client ids does not
+	// fill the store.
+	Clients Storage
+
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+
+	Publisher events.Publisher
+
+	now func() time.Time
+
+	// The lock serializes the updates of this instance, instances of a cluster may still race each other.
+	sync.Mutex
+}
+
+func (l *Lockouts) timeNow() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now().UTC()
+}
+
+// Check returns ErrClientLocked if the client is locked.
+func (l *Lockouts) Check(clientID string) error {
+	lockout, err := l.Manager.GetLockout(clientID)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if lockout.IsLocked(l.timeNow()) {
+		return errors.New(ErrClientLocked)
+	}
+	return nil
+}
+
+// Fail records a failed authentication of a client and locks the client once it failed Threshold times.
+func (l *Lockouts) Fail(clientID string) error {
+	if clientID == "" {
+		return nil
+	} else if _, err := l.Clients.GetClient(clientID); errors.Is(err, pkg.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.timeNow()
+	lockout, err := l.Manager.GetLockout(clientID)
+	if errors.Is(err, pkg.ErrNotFound) || (err == nil && now.Sub(lockout.LastFailureAt) > l.MaxDuration) {
+		lockout = &Lockout{ClientID: clientID}
+	} else if err != nil {
+		return err
+	}
+
+	lockout.Failures++
+	lockout.LastFailureAt = now
+	if lockout.Failures >= l.Threshold {
+		lockout.Failures = 0
+		lockout.Locks++
+		lockout.LockedUntil = now.Add(l.lockDuration(lockout.Locks))
+		l.publish(EventLocked, lockout)
+	}
+
+	return l.Manager.SetLockout(lockout)
+}
+
+// Succeed forgets the failed authentications of a client.
+func (l *Lockouts) Succeed(clientID string) error {
+	if _, err := l.Manager.GetLockout(clientID); errors.Is(err, pkg.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return l.Manager.DeleteLockout(clientID)
+}
+
+// Clear unlocks a client and forgets its failed authentications.
+func (l *Lockouts) Clear(clientID string) error {
+	lockout, err := l.Manager.GetLockout(clientID)
+	if err != nil {
+		return err
+	} else if err := l.Manager.DeleteLockout(clientID); err != nil {
+		return err
+	}
+
+	l.publish(EventUnlocked, lockout)
+	return nil
+}
+
+func (l *Lockouts) lockDuration(locks int) time.Duration {
+	d := l.Duration
+	for i := 1; i < locks && d < l.MaxDuration; i++ {
+		d *= 2
+	}
+	if d > l.MaxDuration {
+		d = l.MaxDuration
+	}
+	return d
+}
+
+func (l *Lockouts) publish(eventType string, lockout *Lockout) {
+	if l.Publisher == nil {
+		return
+	}
+
+	l.Publisher.Publish(events.New(eventType, map[string]interface{}{
+		"client_id":    lockout.ClientID,
+		"locks":        lockout.Locks,
+		"locked_until": lockout.LockedUntil,
+	}))
+}
+
+// LockingManager refuses to authenticate locked clients and records the outcome of every authentication with
+// Lockouts.
+type LockingManager struct {
+	Manager
+	Lockouts *Lockouts
+}
+
+func (m *LockingManager) Authenticate(id string, secret []byte) (*fosite.DefaultClient, error) {
+	if err := m.Lockouts.Check(id); err != nil {
+		return nil, err
+	}
+
+	c, err := m.Manager.Authenticate(id, secret)
+	if err != nil {
+		if ferr := m.Lockouts.Fail(id); ferr != nil {
+			logger.LogError(ferr)
+		}
+		return nil, err
+	}
+
+	if serr := m.Lockouts.Succeed(id); serr != nil {
+		logger.LogError(serr)
+	}
+	return c, nil
+}
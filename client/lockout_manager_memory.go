@@ -0,0 +1,46 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type LockoutMemoryManager struct {
+	Lockouts map[string]Lockout
+	sync.RWMutex
+}
+
+func NewLockoutMemoryManager() *LockoutMemoryManager {
+	return &LockoutMemoryManager{
+		Lockouts: map[string]Lockout{},
+	}
+}
+
+func (m *LockoutMemoryManager) GetLockout(clientID string) (*Lockout, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	l, ok := m.Lockouts[clientID]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return &l, nil
+}
+
+func (m *LockoutMemoryManager) SetLockout(l *Lockout) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.Lockouts[l.ClientID] = *l
+	return nil
+}
+
+func (m *LockoutMemoryManager) DeleteLockout(clientID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Lockouts, clientID)
+	return nil
+}
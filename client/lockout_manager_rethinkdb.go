@@ -0,0 +1,44 @@
+package client
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// LockoutRethinkManager reads lockouts directly from the database, so that a client which is locked on one
+// instance is locked on all of them.
+type LockoutRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *LockoutRethinkManager) GetLockout(clientID string) (*Lockout, error) {
+	res, err := m.Table.Get(clientID).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var l Lockout
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&l); err != nil {
+		return nil, errors.New(err)
+	}
+	return &l, nil
+}
+
+func (m *LockoutRethinkManager) SetLockout(l *Lockout) error {
+	if _, err := m.Table.Insert(l, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *LockoutRethinkManager) DeleteLockout(clientID string) error {
+	if _, err := m.Table.Get(clientID).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package client_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	. "github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPublisher []*events.Event
+
+func (p *recordingPublisher) Publish(e *events.Event) {
+	*p = append(*p, e)
+}
+
+func TestLockouts(t *testing.T) {
+	var published recordingPublisher
+	mem := &MemoryManager{
+		Clients: map[string]*fosite.DefaultClient{},
+		Hasher:  &pkg.BCrypt{WorkFactor: 4},
+	}
+	require.Nil(t, mem.CreateClient(&fosite.DefaultClient{ID: "lockout-client", Secret: []byte("secret")}))
+
+	l := &Lockouts{
+		Manager:     NewLockoutMemoryManager(),
+		Clients:     mem,
+		Threshold:   2,
+		Duration:    time.Hour,
+		MaxDuration: time.Hour * 3,
+		Publisher:   &published,
+	}
+	m := &LockingManager{Manager: mem, Lockouts: l}
+
+	// Failures of unknown clients are not recorded.
+	require.Nil(t, l.Fail("unknown-client"))
+	_, err := l.Manager.GetLockout("unknown-client")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+
+	// A successful authentication forgets earlier failures.
+	_, err = m.Authenticate("lockout-client", []byte("wrong"))
+	require.NotNil(t, err)
+	_, err = m.Authenticate("lockout-client", []byte("secret"))
+	require.Nil(t, err)
+	_, err = l.Manager.GetLockout("lockout-client")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+
+	// Every lock lasts twice as long as the one before, up to the maximum.
+	for k, expected := range []time.Duration{time.Hour, time.Hour * 2, time.Hour * 3} {
+		require.Nil(t, l.Fail("lockout-client"), "%d", k)
+		require.Nil(t, l.Fail("lockout-client"), "%d", k)
+
+		lockout, err := l.Manager.GetLockout("lockout-client")
+		require.Nil(t, err, "%d", k)
+		assert.Equal(t, k+1, lockout.Locks, "%d", k)
+		assert.WithinDuration(t, time.Now().Add(expected), lockout.LockedUntil, time.Minute, "%d", k)
+	}
+	assert.Len(t, published, 3)
+	assert.Equal(t, EventLocked, published[0].Type)
+
+	// Locked clients are refused even if their secret is right.
+	_, err = m.Authenticate("lockout-client", []byte("secret"))
+	assert.True(t, errors.Is(err, ErrClientLocked))
+
+	require.Nil(t, l.Clear("lockout-client"))
+	assert.Equal(t, EventUnlocked, published[3].Type)
+	assert.Nil(t, l.Check("lockout-client"))
+	_, err = m.Authenticate("lockout-client", []byte("secret"))
+	assert.Nil(t, err)
+}
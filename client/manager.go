@@ -10,11 +10,29 @@ type Manager interface {
 	Authenticate(id string, secret []byte) (*fosite.DefaultClient, error)
 }
 
+// SecretRehasher is implemented by managers which are able to replace outdated secret hashes, for example plain text
+// secrets stored by older versions.
+type SecretRehasher interface {
+	// RehashSecret replaces the secret of every client whose secret equals oldHash with newHash.
+	RehashSecret(oldHash, newHash []byte) error
+}
+
+// Importer is implemented by managers which can store clients whose secrets are hashed already, for example when
+// clients are copied from another store.
+type Importer interface {
+	// ImportClient stores a client as it is, a client with the same id is replaced.
+	ImportClient(c *fosite.DefaultClient) error
+}
+
 type Storage interface {
 	fosite.Storage
 
 	CreateClient(c *fosite.DefaultClient) error
 
+	// UpdateClient replaces a client. The stored secret is kept if the client's secret is empty, otherwise the
+	// new secret is hashed.
+	UpdateClient(c *fosite.DefaultClient) error
+
 	DeleteClient(id string) error
 
 	GetClients() (map[string]*fosite.DefaultClient, error)
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/hash"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/pborman/uuid"
+)
+
+const (
+	dynamoClientPrefix  = "client#"
+	dynamoClientSortKey = "client"
+
+	// dynamoClientIndex is the partition of the first index which lists all clients.
+	dynamoClientIndex = "clients"
+)
+
+// DynamoDBManager stores clients in the DynamoDB table which the DynamoDB backends share, see
+// pkg.CreateDynamoDBTable. Clients are stored as JSON, the secret is hashed before it is encoded. Clients are read
+// consistently, GetClients reads an index and might miss clients which were created moments ago.
+type DynamoDBManager struct {
+	DB    dynamodbiface.DynamoDBAPI
+	Table string
+
+	Hasher hash.Hasher
+}
+
+type dynamoClient struct {
+	PK     string `dynamodbav:"pk"`
+	SK     string `dynamodbav:"sk"`
+	GSI1PK string `dynamodbav:"gsi1pk"`
+	GSI1SK string `dynamodbav:"gsi1sk"`
+	Data   string `dynamodbav:"data"`
+}
+
+// CreateSchemas creates the table unless it exists.
+func (m *DynamoDBManager) CreateSchemas() error {
+	return pkg.CreateDynamoDBTable(m.DB, m.Table)
+}
+
+func (m *DynamoDBManager) GetClient(id string) (fosite.Client, error) {
+	return m.get(id)
+}
+
+func (m *DynamoDBManager) Authenticate(id string, secret []byte) (*fosite.DefaultClient, error) {
+	c, err := m.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.Hasher.Compare(c.GetHashedSecret(), secret); err != nil {
+		return nil, errors.New(err)
+	}
+	return c, nil
+}
+
+// CreateClient stores a new client, it fails if a client with the same id exists.
+func (m *DynamoDBManager) CreateClient(c *fosite.DefaultClient) error {
+	if c.ID == "" {
+		c.ID = uuid.New()
+	}
+
+	hash, err := m.Hasher.Hash(c.Secret)
+	if err != nil {
+		return errors.New(err)
+	}
+	c.Secret = hash
+
+	if err := m.put(c, "attribute_not_exists(pk)"); pkg.IsDynamoDBConditionFailed(err) {
+		return errors.Errorf("Client %s exists already", c.ID)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *DynamoDBManager) UpdateClient(c *fosite.DefaultClient) error {
+	o, err := m.get(c.GetID())
+	if err != nil {
+		return err
+	}
+
+	if len(c.Secret) == 0 {
+		c.Secret = o.Secret
+	} else {
+		hash, err := m.Hasher.Hash(c.Secret)
+		if err != nil {
+			return errors.New(err)
+		}
+		c.Secret = hash
+	}
+	return m.update(c)
+}
+
+func (m *DynamoDBManager) ImportClient(c *fosite.DefaultClient) error {
+	return m.put(c, "")
+}
+
+func (m *DynamoDBManager) RehashSecret(oldHash, newHash []byte) error {
+	clients, err := m.GetClients()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range clients {
+		if bytes.Equal(c.Secret, oldHash) {
+			c.Secret = newHash
+			if err := m.update(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *DynamoDBManager) DeleteClient(id string) error {
+	if _, err := m.DB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(m.Table),
+		Key:       pkg.DynamoDBKey(dynamoClientPrefix+id, dynamoClientSortKey),
+	}); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *DynamoDBManager) GetClients() (map[string]*fosite.DefaultClient, error) {
+	clients := map[string]*fosite.DefaultClient{}
+	var decodeErr error
+	if err := m.DB.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(m.Table),
+		IndexName:              aws.String(pkg.DynamoDBIndex1),
+		KeyConditionExpression: aws.String("gsi1pk = :index"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":index": {S: aws.String(dynamoClientIndex)},
+		},
+	}, func(page *dynamodb.QueryOutput, _ bool) bool {
+		for _, item := range page.Items {
+			c, err := decodeDynamoClient(item)
+			if err != nil {
+				decodeErr = err
+				return false
+			}
+			clients[c.ID] = c
+		}
+		return true
+	}); err != nil {
+		return nil, errors.New(err)
+	} else if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return clients, nil
+}
+
+func (m *DynamoDBManager) get(id string) (*fosite.DefaultClient, error) {
+	out, err := m.DB.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(m.Table),
+		Key:            pkg.DynamoDBKey(dynamoClientPrefix+id, dynamoClientSortKey),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, errors.New(err)
+	} else if len(out.Item) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return decodeDynamoClient(out.Item)
+}
+
+// update replaces a client which must exist.
+func (m *DynamoDBManager) update(c *fosite.DefaultClient) error {
+	if err := m.put(c, "attribute_exists(pk)"); pkg.IsDynamoDBConditionFailed(err) {
+		return errors.New(pkg.ErrNotFound)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// put writes a client if condition holds, an empty condition always holds.
+func (m *DynamoDBManager) put(c *fosite.DefaultClient, condition string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	item, err := dynamodbattribute.MarshalMap(&dynamoClient{
+		PK:     dynamoClientPrefix + c.ID,
+		SK:     dynamoClientSortKey,
+		GSI1PK: dynamoClientIndex,
+		GSI1SK: c.ID,
+		Data:   string(data),
+	})
+	if err != nil {
+		return errors.New(err)
+	}
+
+	input := &dynamodb.PutItemInput{TableName: aws.String(m.Table), Item: item}
+	if condition != "" {
+		input.ConditionExpression = aws.String(condition)
+	}
+	if _, err := m.DB.PutItem(input); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func decodeDynamoClient(item map[string]*dynamodb.AttributeValue) (*fosite.DefaultClient, error) {
+	var d dynamoClient
+	if err := dynamodbattribute.UnmarshalMap(item, &d); err != nil {
+		return nil, errors.New(err)
+	}
+
+	var c fosite.DefaultClient
+	if err := json.Unmarshal([]byte(d.Data), &c); err != nil {
+		return nil, errors.New(err)
+	}
+	return &c, nil
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/pkg"
@@ -30,6 +31,29 @@ func (m *HTTPManager) CreateClient(c *fosite.DefaultClient) error {
 	return r.Create(c)
 }
 
+func (m *HTTPManager) UpdateClient(c *fosite.DefaultClient) error {
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, c.ID).String())
+	r.Client = m.Client
+	return r.Update(c)
+}
+
+// RotateSecret gives the client a new secret. The previous secret stays valid for overlap, or for the server's
+// default overlap if overlap is zero.
+func (m *HTTPManager) RotateSecret(id string, overlap time.Duration) (*SecretRotationResponse, error) {
+	var rr SecretRotationRequest
+	if overlap > 0 {
+		rr.Overlap = overlap.String()
+	}
+
+	var res SecretRotationResponse
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, id, "rotate-secret").String())
+	r.Client = m.Client
+	if err := r.POST(&rr, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
 func (m *HTTPManager) DeleteClient(id string) error {
 	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, id).String())
 	r.Client = m.Client
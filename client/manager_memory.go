@@ -1,6 +1,8 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"sync"
 
 	"github.com/go-errors/errors"
@@ -28,14 +30,14 @@ func (m *MemoryManager) GetClient(id string) (fosite.Client, error) {
 }
 
 func (m *MemoryManager) Authenticate(id string, secret []byte) (*fosite.DefaultClient, error) {
-	m.Lock()
-	defer m.Unlock()
-
+	m.RLock()
 	c, ok := m.Clients[id]
+	m.RUnlock()
 	if !ok {
 		return nil, errors.New(pkg.ErrNotFound)
 	}
 
+	// The lock is released before comparing, the hasher might call RehashSecret.
 	if err := m.Hasher.Compare(c.GetHashedSecret(), secret); err != nil {
 		return nil, errors.New(err)
 	}
@@ -61,6 +63,51 @@ func (m *MemoryManager) CreateClient(c *fosite.DefaultClient) error {
 	return nil
 }
 
+func (m *MemoryManager) UpdateClient(c *fosite.DefaultClient) error {
+	m.Lock()
+	defer m.Unlock()
+
+	o, ok := m.Clients[c.GetID()]
+	if !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	if len(c.Secret) == 0 {
+		c.Secret = o.Secret
+	} else {
+		hash, err := m.Hasher.Hash(c.Secret)
+		if err != nil {
+			return errors.New(err)
+		}
+		c.Secret = hash
+	}
+
+	m.Clients[c.GetID()] = c
+	return nil
+}
+
+func (m *MemoryManager) ImportClient(c *fosite.DefaultClient) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.Clients[c.GetID()] = c
+	return nil
+}
+
+func (m *MemoryManager) RehashSecret(oldHash, newHash []byte) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for id, c := range m.Clients {
+		if bytes.Equal(c.Secret, oldHash) {
+			rehashed := *c
+			rehashed.Secret = newHash
+			m.Clients[id] = &rehashed
+		}
+	}
+	return nil
+}
+
 func (m *MemoryManager) DeleteClient(id string) error {
 	m.Lock()
 	defer m.Unlock()
@@ -75,3 +122,31 @@ func (m *MemoryManager) GetClients() (map[string]*fosite.DefaultClient, error) {
 
 	return m.Clients, nil
 }
+
+// Snapshot returns all clients encoded as JSON.
+func (m *MemoryManager) Snapshot() ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	out, err := json.Marshal(m.Clients)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return out, nil
+}
+
+// Restore replaces all clients with the ones of the snapshot.
+func (m *MemoryManager) Restore(snapshot []byte) error {
+	var c map[string]*fosite.DefaultClient
+	if err := json.Unmarshal(snapshot, &c); err != nil {
+		return errors.New(err)
+	}
+	if c == nil {
+		c = map[string]*fosite.DefaultClient{}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Clients = c
+	return nil
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/quota"
+)
+
+// QuotaManager refuses to create clients once Quota is exhausted. The clients are counted and the new client is
+// created while holding the quota's lease, so concurrent requests can not exceed the quota together, not even on
+// different instances of a cluster, see quota.Quota.Admit.
+type QuotaManager struct {
+	Manager
+	Quota *quota.Quota
+}
+
+func (m *QuotaManager) CreateClient(c *fosite.DefaultClient) error {
+	return m.Quota.Admit("", func() (int, error) {
+		clients, err := m.Manager.GetClients()
+		if err != nil {
+			return 0, err
+		}
+		return len(clients), nil
+	}, func() error {
+		return m.Manager.CreateClient(c)
+	})
+}
@@ -1,10 +1,9 @@
 package client
 
 import (
+	"bytes"
 	"sync"
 
-	"time"
-
 	"github.com/go-errors/errors"
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/fosite/hash"
@@ -21,11 +20,15 @@ type RethinkManager struct {
 
 	Clients map[string]*fosite.DefaultClient
 	Hasher  hash.Hasher
+
+	// Feed streams the changes of Table. It is created by Watch if nil and can be subscribed to by other
+	// subsystems which need to react to client changes.
+	Feed *pkg.ChangeFeed
 }
 
 func (m *RethinkManager) GetClient(id string) (fosite.Client, error) {
-	m.Lock()
-	defer m.Unlock()
+	m.RLock()
+	defer m.RUnlock()
 
 	c, ok := m.Clients[id]
 	if !ok {
@@ -35,14 +38,14 @@ func (m *RethinkManager) GetClient(id string) (fosite.Client, error) {
 }
 
 func (m *RethinkManager) Authenticate(id string, secret []byte) (*fosite.DefaultClient, error) {
-	m.Lock()
-	defer m.Unlock()
-
+	m.RLock()
 	c, ok := m.Clients[id]
+	m.RUnlock()
 	if !ok {
 		return nil, errors.New(pkg.ErrNotFound)
 	}
 
+	// The lock is released before comparing, the hasher might call RehashSecret.
 	if err := m.Hasher.Compare(c.GetHashedSecret(), secret); err != nil {
 		return nil, errors.New(err)
 	}
@@ -68,6 +71,59 @@ func (m *RethinkManager) CreateClient(c *fosite.DefaultClient) error {
 	return nil
 }
 
+func (m *RethinkManager) UpdateClient(c *fosite.DefaultClient) error {
+	m.RLock()
+	o, ok := m.Clients[c.GetID()]
+	m.RUnlock()
+	if !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	if len(c.Secret) == 0 {
+		c.Secret = o.Secret
+	} else {
+		hash, err := m.Hasher.Hash(c.Secret)
+		if err != nil {
+			return errors.New(err)
+		}
+		c.Secret = hash
+	}
+
+	if err := m.publishUpdate(c); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *RethinkManager) ImportClient(c *fosite.DefaultClient) error {
+	if _, err := m.Table.Insert(c, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) RehashSecret(oldHash, newHash []byte) error {
+	var outdated []*fosite.DefaultClient
+	m.RLock()
+	for _, c := range m.Clients {
+		if bytes.Equal(c.Secret, oldHash) {
+			rehashed := *c
+			rehashed.Secret = newHash
+			outdated = append(outdated, &rehashed)
+		}
+	}
+	m.RUnlock()
+
+	// The watcher puts the rehashed clients into the cache.
+	for _, c := range outdated {
+		if err := m.publishUpdate(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *RethinkManager) DeleteClient(id string) error {
 	if err := m.publishDelete(id); err != nil {
 		return err
@@ -77,26 +133,38 @@ func (m *RethinkManager) DeleteClient(id string) error {
 }
 
 func (m *RethinkManager) GetClients() (map[string]*fosite.DefaultClient, error) {
-	m.Lock()
-	defer m.Unlock()
+	m.RLock()
+	defer m.RUnlock()
 
-	return m.Clients, nil
+	// The watcher keeps changing the cache, so hand out a copy.
+	clients := make(map[string]*fosite.DefaultClient, len(m.Clients))
+	for id, c := range m.Clients {
+		clients[id] = c
+	}
+	return clients, nil
 }
 
 func (m *RethinkManager) ColdStart() error {
-	m.Clients = map[string]*fosite.DefaultClient{}
-	clients, err := m.Table.Run(m.Session)
+	rows, err := m.Table.Run(m.Session)
 	if err != nil {
 		return errors.New(err)
 	}
+	defer rows.Close()
 
-	var client fosite.DefaultClient
-	m.Lock()
-	defer m.Unlock()
-	for clients.Next(&client) {
-		m.Clients[client.ID] = &client
+	clients := map[string]*fosite.DefaultClient{}
+	var client *fosite.DefaultClient
+	for rows.Next(&client) {
+		clients[client.ID] = client
+		client = nil
 	}
 
+	if rows.Err() != nil {
+		return errors.New(rows.Err())
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Clients = clients
 	return nil
 }
 
@@ -107,6 +175,13 @@ func (m *RethinkManager) publishCreate(client *fosite.DefaultClient) error {
 	return nil
 }
 
+func (m *RethinkManager) publishUpdate(client *fosite.DefaultClient) error {
+	if _, err := m.Table.Get(client.ID).Replace(client).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
 func (m *RethinkManager) publishDelete(id string) error {
 	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
 		return errors.New(err)
@@ -115,34 +190,25 @@ func (m *RethinkManager) publishDelete(id string) error {
 }
 
 func (m *RethinkManager) Watch(ctx context.Context) {
-	go pkg.Retry(time.Second*15, time.Minute, func() error {
-		clients, err := m.Table.Changes().Run(m.Session)
-		if err != nil {
-			return errors.New(err)
-		}
-		defer clients.Close()
-
-		var update map[string]*fosite.DefaultClient
-		for clients.Next(&update) {
-			newVal := update["new_val"]
-			oldVal := update["old_val"]
-			m.Lock()
-			if newVal == nil && oldVal != nil {
-				delete(m.Clients, oldVal.GetID())
-			} else if newVal != nil && oldVal != nil {
-				delete(m.Clients, oldVal.GetID())
-				m.Clients[newVal.GetID()] = newVal
-			} else {
-				m.Clients[newVal.GetID()] = newVal
-			}
-			m.Unlock()
-		}
+	if m.Feed == nil {
+		m.Feed = &pkg.ChangeFeed{Session: m.Session, Table: m.Table}
+	}
 
-		if clients.Err() != nil {
-			err = errors.New(clients.Err())
-			pkg.LogError(err)
+	m.Feed.Subscribe(m.ColdStart, func(change *pkg.Change) error {
+		var newVal, oldVal *fosite.DefaultClient
+		if err := change.Decode(&oldVal, &newVal); err != nil {
 			return err
 		}
+
+		m.Lock()
+		defer m.Unlock()
+		if oldVal != nil {
+			delete(m.Clients, oldVal.GetID())
+		}
+		if newVal != nil {
+			m.Clients[newVal.GetID()] = newVal
+		}
 		return nil
 	})
+	m.Feed.Start(ctx)
 }
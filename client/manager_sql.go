@@ -0,0 +1,225 @@
+package client
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/hash"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/pborman/uuid"
+)
+
+// sqlSchemas create the client table. Clients are stored as JSON, the secret is hashed before it is encoded.
+var sqlSchemas = map[string][]string{
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS hydra_client (
+	id   varchar(255) NOT NULL PRIMARY KEY,
+	data text NOT NULL
+)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS hydra_client (
+	id   varchar(255) NOT NULL PRIMARY KEY,
+	data text NOT NULL
+)`,
+	},
+}
+
+// migrations are the versions of the client table.
+var migrations = []*pkg.Migration{
+	{
+		ID: "1",
+		Up: sqlSchemas,
+		Down: map[string][]string{
+			"postgres": {`DROP TABLE IF EXISTS hydra_client`},
+			"mysql":    {`DROP TABLE IF EXISTS hydra_client`},
+		},
+	},
+}
+
+// SQLManager stores clients in PostgreSQL or MySQL. Unlike RethinkManager it does not cache clients, every lookup
+// is answered by the database.
+type SQLManager struct {
+	DB *sql.DB
+
+	// Driver is the name of the database/sql driver, either "postgres" or "mysql".
+	Driver string
+
+	Hasher hash.Hasher
+}
+
+// Migrator returns the migrator of the client table.
+func (m *SQLManager) Migrator() *pkg.Migrator {
+	return &pkg.Migrator{DB: m.DB, Driver: m.Driver, Module: "client", Migrations: migrations}
+}
+
+// CreateSchemas applies all migrations of the client table which were not applied yet.
+func (m *SQLManager) CreateSchemas() error {
+	_, err := m.Migrator().Up()
+	return err
+}
+
+func (m *SQLManager) GetClient(id string) (fosite.Client, error) {
+	return m.get(id)
+}
+
+func (m *SQLManager) Authenticate(id string, secret []byte) (*fosite.DefaultClient, error) {
+	c, err := m.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.Hasher.Compare(c.GetHashedSecret(), secret); err != nil {
+		return nil, errors.New(err)
+	}
+	return c, nil
+}
+
+func (m *SQLManager) CreateClient(c *fosite.DefaultClient) error {
+	if c.ID == "" {
+		c.ID = uuid.New()
+	}
+
+	hash, err := m.Hasher.Hash(c.Secret)
+	if err != nil {
+		return errors.New(err)
+	}
+	c.Secret = hash
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	if _, err := m.DB.Exec(m.rebind("INSERT INTO hydra_client (id, data) VALUES (?, ?)"), c.ID, string(data)); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) UpdateClient(c *fosite.DefaultClient) error {
+	o, err := m.get(c.GetID())
+	if err != nil {
+		return err
+	}
+
+	if len(c.Secret) == 0 {
+		c.Secret = o.Secret
+	} else {
+		hash, err := m.Hasher.Hash(c.Secret)
+		if err != nil {
+			return errors.New(err)
+		}
+		c.Secret = hash
+	}
+	return m.update(c)
+}
+
+func (m *SQLManager) ImportClient(c *fosite.DefaultClient) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return errors.New(err)
+	}
+	if _, err := tx.Exec(m.rebind("DELETE FROM hydra_client WHERE id = ?"), c.ID); err != nil {
+		tx.Rollback()
+		return errors.New(err)
+	}
+	if _, err := tx.Exec(m.rebind("INSERT INTO hydra_client (id, data) VALUES (?, ?)"), c.ID, string(data)); err != nil {
+		tx.Rollback()
+		return errors.New(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) RehashSecret(oldHash, newHash []byte) error {
+	clients, err := m.GetClients()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range clients {
+		if bytes.Equal(c.Secret, oldHash) {
+			c.Secret = newHash
+			if err := m.update(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *SQLManager) DeleteClient(id string) error {
+	if _, err := m.DB.Exec(m.rebind("DELETE FROM hydra_client WHERE id = ?"), id); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) GetClients() (map[string]*fosite.DefaultClient, error) {
+	rows, err := m.DB.Query("SELECT data FROM hydra_client")
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	clients := map[string]*fosite.DefaultClient{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, errors.New(err)
+		}
+
+		var c fosite.DefaultClient
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return nil, errors.New(err)
+		}
+		clients[c.ID] = &c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	}
+	return clients, nil
+}
+
+func (m *SQLManager) get(id string) (*fosite.DefaultClient, error) {
+	var data string
+	if err := m.DB.QueryRow(m.rebind("SELECT data FROM hydra_client WHERE id = ?"), id).Scan(&data); err == sql.ErrNoRows {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err != nil {
+		return nil, errors.New(err)
+	}
+
+	var c fosite.DefaultClient
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, errors.New(err)
+	}
+	return &c, nil
+}
+
+func (m *SQLManager) update(c *fosite.DefaultClient) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	// MySQL does not count rows which did not change as affected, the caller checks that the client exists.
+	if _, err := m.DB.Exec(m.rebind("UPDATE hydra_client SET data = ? WHERE id = ?"), string(data), c.ID); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) rebind(query string) string {
+	return pkg.RebindSQL(m.Driver, query)
+}
@@ -1,28 +1,37 @@
 package client_test
 
 import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	r "gopkg.in/dancannon/gorethink.v2"
 
-	"log"
 	"os"
 	"time"
 
+	"github.com/go-errors/errors"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/fosite/hash"
 	. "github.com/ory-am/hydra/client"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/integration"
 	"github.com/ory-am/hydra/internal"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/quota"
 	"github.com/ory-am/ladon"
 	"github.com/pborman/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
-	"gopkg.in/ory-am/dockertest.v2"
 )
 
 var clientManagers = map[string]Storage{}
@@ -39,7 +48,7 @@ func init() {
 		ID:        "1",
 		Subjects:  []string{"alice"},
 		Resources: []string{"rn:hydra:clients<.*>"},
-		Actions:   []string{"create", "get", "delete"},
+		Actions:   []string{"create", "get", "delete", "update"},
 		Effect:    ladon.AllowAccess,
 	})
 
@@ -66,43 +75,41 @@ func init() {
 var rethinkManager *RethinkManager
 
 func TestMain(m *testing.M) {
-	var session *r.Session
-	var err error
-
-	c, err := dockertest.ConnectToRethinkDB(20, time.Second, func(url string) bool {
-		if session, err = r.Connect(r.ConnectOpts{Address: url, Database: "hydra"}); err != nil {
-			return false
-		} else if _, err = r.DBCreate("hydra").RunWrite(session); err != nil {
-			log.Printf("Database exists: %s", err)
-			return false
-		} else if _, err = r.TableCreate("hydra_clients").RunWrite(session); err != nil {
-			log.Printf("Could not create table: %s", err)
-			return false
-		}
+	session := integration.ConnectToRethinkDB("hydra", "hydra_clients")
+	defer session.Close()
+
+	rethinkManager = &RethinkManager{
+		Session: session,
+		Table:   r.Table("hydra_clients"),
+		Clients: make(map[string]*fosite.DefaultClient),
+		Hasher: &hash.BCrypt{
+			// Low workfactor reduces test time
+			WorkFactor: 4,
+		},
+	}
+	rethinkManager.Watch(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	clientManagers["rethink"] = rethinkManager
 
-		rethinkManager = &RethinkManager{
-			Session: session,
-			Table:   r.Table("hydra_clients"),
-			Clients: make(map[string]*fosite.DefaultClient),
-			Hasher: &hash.BCrypt{
-				// Low workfactor reduces test time
-				WorkFactor: 4,
-			},
+	for driver, db := range map[string]*sql.DB{
+		"postgres": integration.ConnectToPostgres(),
+		"mysql":    integration.ConnectToMySQL(),
+	} {
+		sm := &SQLManager{DB: db, Driver: driver, Hasher: &hash.BCrypt{WorkFactor: 4}}
+		if err := sm.CreateSchemas(); err != nil {
+			log.Fatalf("Could not create %s schemas: %s", driver, err)
 		}
-		rethinkManager.Watch(context.Background())
-		time.Sleep(100 * time.Millisecond)
-		return true
-	})
-	if session != nil {
-		defer session.Close()
+		clientManagers[driver] = sm
 	}
-	if err != nil {
-		log.Fatalf("Could not connect to database: %s", err)
+
+	dm := &DynamoDBManager{DB: integration.ConnectToDynamoDB(), Table: "hydra", Hasher: &hash.BCrypt{WorkFactor: 4}}
+	if err := dm.CreateSchemas(); err != nil {
+		log.Fatalf("Could not create dynamodb table: %s", err)
 	}
-	clientManagers["rethink"] = rethinkManager
+	clientManagers["dynamodb"] = dm
 
 	retCode := m.Run()
-	c.KillRemove()
+	integration.KillAll()
 	os.Exit(retCode)
 }
 
@@ -125,6 +132,125 @@ func TestAuthenticateClient(t *testing.T) {
 	assert.Equal(t, "1234", c.ID)
 }
 
+func TestAuthenticateRehashesLegacySecret(t *testing.T) {
+	hasher := &pkg.MigratingHasher{Hasher: &pkg.BCrypt{WorkFactor: 4}}
+	var mem = &MemoryManager{
+		Clients: map[string]*fosite.DefaultClient{
+			"1234": {ID: "1234", Secret: []byte("secret")},
+		},
+		Hasher: hasher,
+	}
+	hasher.OnRehash = func(oldHash, newHash []byte) {
+		assert.Nil(t, mem.RehashSecret(oldHash, newHash))
+	}
+
+	_, err := mem.Authenticate("1234", []byte("secret1"))
+	pkg.AssertError(t, true, err)
+	assert.Equal(t, "secret", string(mem.Clients["1234"].Secret))
+
+	_, err = mem.Authenticate("1234", []byte("secret"))
+	pkg.AssertError(t, false, err)
+	assert.NotEqual(t, "secret", string(mem.Clients["1234"].Secret))
+	assert.False(t, hasher.NeedsRehash(mem.Clients["1234"].Secret))
+
+	_, err = mem.Authenticate("1234", []byte("secret"))
+	pkg.AssertError(t, false, err)
+}
+
+func TestRotatingManager(t *testing.T) {
+	hasher := &RotatingHasher{Hasher: &hash.BCrypt{WorkFactor: 4}}
+	rotations := NewRotationMemoryManager()
+	var mem = &MemoryManager{
+		Clients: map[string]*fosite.DefaultClient{},
+		Hasher:  hasher,
+	}
+	m := &RotatingManager{Manager: mem, Rotations: rotations, Hasher: hasher}
+	require.Nil(t, mem.CreateClient(&fosite.DefaultClient{ID: "1234", Secret: []byte("old")}))
+	previous := mem.Clients["1234"].Secret
+
+	rotated := *mem.Clients["1234"]
+	rotated.Secret = []byte("new")
+	require.Nil(t, mem.UpdateClient(&rotated))
+	require.Nil(t, rotations.SetSecretRotation(&SecretRotation{
+		ClientID:       "1234",
+		Secret:         rotated.Secret,
+		PreviousSecret: previous,
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}))
+
+	// A client which shares the current hash does not share the rotation.
+	require.Nil(t, mem.ImportClient(&fosite.DefaultClient{ID: "5678", Secret: rotated.Secret}))
+
+	// Fosite compares the secret of the client returned by GetClient itself.
+	rotating, err := m.GetClient("1234")
+	require.Nil(t, err)
+	for k, c := range []struct {
+		id     string
+		secret string
+		valid  bool
+	}{
+		{id: "1234", secret: "new", valid: true},
+		{id: "1234", secret: "old", valid: true},
+		{id: "1234", secret: "other"},
+		{id: "5678", secret: "new", valid: true},
+		{id: "5678", secret: "old"},
+	} {
+		_, err := m.Authenticate(c.id, []byte(c.secret))
+		pkg.AssertError(t, !c.valid, err, "Case %d", k)
+
+		stored, err := m.GetClient(c.id)
+		require.Nil(t, err)
+		pkg.AssertError(t, !c.valid, hasher.Compare(stored.GetHashedSecret(), []byte(c.secret)), "Case %d", k)
+	}
+	assert.NotEqual(t, rotated.Secret, rotating.GetHashedSecret())
+
+	// The manager which is not wrapped still returns the stored secret.
+	stored, err := mem.GetClient("1234")
+	require.Nil(t, err)
+	assert.Equal(t, rotated.Secret, stored.GetHashedSecret())
+
+	require.Nil(t, rotations.SetSecretRotation(&SecretRotation{
+		ClientID:       "1234",
+		Secret:         rotated.Secret,
+		PreviousSecret: previous,
+		ExpiresAt:      time.Now().Add(-time.Second),
+	}))
+	_, err = m.Authenticate("1234", []byte("old"))
+	pkg.AssertError(t, true, err)
+	_, err = m.Authenticate("1234", []byte("new"))
+	pkg.AssertError(t, false, err)
+
+	rotating, err = m.GetClient("1234")
+	require.Nil(t, err)
+	assert.Equal(t, rotated.Secret, rotating.GetHashedSecret())
+}
+
+func TestQuotaManager(t *testing.T) {
+	mem := &MemoryManager{
+		Clients: map[string]*fosite.DefaultClient{},
+		Hasher:  &hash.BCrypt{WorkFactor: 4},
+	}
+	m := &QuotaManager{Manager: mem, Quota: &quota.Quota{Name: "clients", Limit: 5}}
+
+	var wg sync.WaitGroup
+	var exceeded int32
+	for k := 0; k < 20; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.CreateClient(&fosite.DefaultClient{Secret: []byte("secret")}); errors.Is(err, quota.ErrQuotaExceeded) {
+				atomic.AddInt32(&exceeded, 1)
+			} else {
+				assert.Nil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, mem.Clients, 5)
+	assert.Equal(t, int32(15), exceeded)
+}
+
 func BenchmarkRethinkGet(b *testing.B) {
 	b.StopTimer()
 
@@ -200,47 +326,134 @@ func TestColdStartRethinkManager(t *testing.T) {
 
 func TestCreateGetDeleteClient(t *testing.T) {
 	for k, m := range clientManagers {
-		_, err := m.GetClient("4321")
-		pkg.AssertError(t, true, err, "%s", k)
-
-		c := &fosite.DefaultClient{
-			ID:                "1234",
-			Secret:            []byte("secret"),
-			RedirectURIs:      []string{"http://redirect"},
-			TermsOfServiceURI: "foo",
-		}
-		err = m.CreateClient(c)
-		pkg.AssertError(t, false, err, "%s", k)
-		if err == nil {
-			compare(t, c, k)
-		}
+		TestHelperCreateGetDeleteClient(t, k, m)
+	}
+}
 
-		// RethinkDB delay
-		time.Sleep(500 * time.Millisecond)
+func TestUpdateClient(t *testing.T) {
+	for k, m := range clientManagers {
+		TestHelperUpdateClient(t, k, m)
+	}
+}
 
-		d, err := m.GetClient("1234")
-		pkg.AssertError(t, false, err, "%s", k)
-		if err == nil {
-			compare(t, d, k)
-		}
+func TestDeleteClientsByOwner(t *testing.T) {
+	localWarden, httpClient := internal.NewFirewall("foo", "alice", fosite.Arguments{Scope}, &ladon.DefaultPolicy{
+		ID:        "1",
+		Subjects:  []string{"alice"},
+		Resources: []string{"rn:hydra:clients:<.*>"},
+		Actions:   []string{"delete"},
+		Effect:    ladon.AllowAccess,
+	})
+
+	m := &MemoryManager{
+		Clients: map[string]*fosite.DefaultClient{},
+		Hasher:  &hash.BCrypt{WorkFactor: 4},
+	}
+	for _, owner := range []string{"peter", "peter", "bob"} {
+		require.Nil(t, m.CreateClient(&fosite.DefaultClient{Owner: owner, Secret: []byte("secret")}))
+	}
 
-		ds, err := m.GetClients()
-		pkg.AssertError(t, false, err, "%s", k)
-		assert.Len(t, ds, 1)
+	router := httprouter.New()
+	(&Handler{Manager: m, H: &herodot.JSON{}, W: localWarden}).SetRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	del := func(query string) *http.Response {
+		req, err := http.NewRequest("DELETE", server.URL+ClientsHandlerPath+"?"+query, nil)
+		require.Nil(t, err)
+		res, err := httpClient.Do(req)
+		require.Nil(t, err)
+		return res
+	}
 
-		err = m.DeleteClient("1234")
-		pkg.AssertError(t, false, err, "%s", k)
+	res := del("confirm=true")
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 
-		// RethinkDB delay
-		time.Sleep(100 * time.Millisecond)
+	res = del("owner=peter")
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 
-		_, err = m.GetClient("1234")
-		pkg.AssertError(t, true, err, "%s", k)
-	}
+	res = del("owner=peter&confirm=true")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var result pkg.BulkDeletion
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&result))
+	assert.Equal(t, 2, result.Deleted)
+
+	clients, err := m.GetClients()
+	require.Nil(t, err)
+	assert.Len(t, clients, 1)
 }
 
-func compare(t *testing.T, c fosite.Client, k string) {
-	assert.Equal(t, c.GetID(), "1234", "%s", k)
-	assert.NotEmpty(t, c.GetHashedSecret(), "%s", k)
-	assert.Equal(t, c.GetRedirectURIs(), []string{"http://redirect"}, "%s", k)
+func TestBulkClients(t *testing.T) {
+	localWarden, httpClient := internal.NewFirewall("foo", "alice", fosite.Arguments{Scope}, &ladon.DefaultPolicy{
+		ID:        "1",
+		Subjects:  []string{"alice"},
+		Resources: []string{"rn:hydra:clients<.*>"},
+		Actions:   []string{"create", "update", "delete"},
+		Effect:    ladon.AllowAccess,
+	}, &ladon.DefaultPolicy{
+		ID:        "2",
+		Subjects:  []string{"alice"},
+		Resources: []string{"rn:hydra:clients:protected"},
+		Actions:   []string{"delete"},
+		Effect:    ladon.DenyAccess,
+	})
+
+	m := &MemoryManager{
+		Clients: map[string]*fosite.DefaultClient{},
+		Hasher:  &hash.BCrypt{WorkFactor: 4},
+	}
+	for _, id := range []string{"existing", "obsolete", "protected"} {
+		require.Nil(t, m.CreateClient(&fosite.DefaultClient{ID: id, Secret: []byte("secret")}))
+	}
+
+	router := httprouter.New()
+	(&Handler{Manager: m, H: &herodot.JSON{}, W: localWarden}).SetRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	patch := func(body string) *http.Response {
+		req, err := http.NewRequest("PATCH", server.URL+ClientsHandlerPath, strings.NewReader(body))
+		require.Nil(t, err)
+		res, err := httpClient.Do(req)
+		require.Nil(t, err)
+		return res
+	}
+
+	res := patch(`[{"action": "rename", "id": "existing"}]`)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = patch(`[
+		{"action": "create", "data": {"id": "new", "client_name": "New"}},
+		{"action": "update", "id": "existing", "data": {"client_name": "Updated"}},
+		{"action": "update", "id": "missing", "data": {}},
+		{"action": "update", "id": "existing", "data": "not a client"},
+		{"action": "delete", "id": "protected"},
+		{"action": "delete", "id": "obsolete"}
+	]`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var result pkg.BulkResponse
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&result))
+	assert.Equal(t, 3, result.Succeeded)
+	assert.Equal(t, 3, result.Failed)
+	require.Len(t, result.Results, 6)
+	for k, status := range []int{http.StatusCreated, http.StatusOK, http.StatusNotFound, http.StatusBadRequest, http.StatusForbidden, http.StatusNoContent} {
+		assert.Equal(t, status, result.Results[k].Status, "Case %d: %s", k, result.Results[k].Error)
+	}
+
+	c, err := m.GetClient("existing")
+	require.Nil(t, err)
+	assert.Equal(t, "Updated", c.(*fosite.DefaultClient).Name)
+	_, err = m.GetClient("new")
+	assert.Nil(t, err)
+	_, err = m.GetClient("protected")
+	assert.Nil(t, err)
+	_, err = m.GetClient("obsolete")
+	assert.NotNil(t, err)
 }
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+// The TestHelper functions are the conformance suite of Storage. Backends of this repository and downstream
+// backends run them against their managers, see package integration.
+
+func TestHelperCreateGetDeleteClient(t *testing.T, k string, m Storage) {
+	_, err := m.GetClient("4321")
+	pkg.AssertError(t, true, err, "%s", k)
+
+	c := &fosite.DefaultClient{
+		ID:                "1234",
+		Secret:            []byte("secret"),
+		RedirectURIs:      []string{"http://redirect"},
+		TermsOfServiceURI: "foo",
+	}
+	err = m.CreateClient(c)
+	pkg.AssertError(t, false, err, "%s", k)
+	if err == nil {
+		compare(t, c, k)
+	}
+
+	// RethinkDB delay
+	time.Sleep(500 * time.Millisecond)
+
+	d, err := m.GetClient("1234")
+	pkg.AssertError(t, false, err, "%s", k)
+	if err == nil {
+		compare(t, d, k)
+	}
+
+	ds, err := m.GetClients()
+	pkg.AssertError(t, false, err, "%s", k)
+	assert.Len(t, ds, 1)
+
+	err = m.DeleteClient("1234")
+	pkg.AssertError(t, false, err, "%s", k)
+
+	// RethinkDB delay
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = m.GetClient("1234")
+	pkg.AssertError(t, true, err, "%s", k)
+}
+
+func TestHelperUpdateClient(t *testing.T, k string, m Storage) {
+	c := &fosite.DefaultClient{
+		ID:                "5678",
+		Secret:            []byte("secret"),
+		RedirectURIs:      []string{"http://redirect"},
+		TermsOfServiceURI: "foo",
+	}
+	err := m.UpdateClient(c)
+	pkg.AssertError(t, true, err, "%s", k)
+
+	err = m.CreateClient(c)
+	pkg.AssertError(t, false, err, "%s", k)
+
+	// RethinkDB delay
+	time.Sleep(100 * time.Millisecond)
+
+	err = m.UpdateClient(&fosite.DefaultClient{
+		ID:                "5678",
+		RedirectURIs:      []string{"http://redirect"},
+		TermsOfServiceURI: "bar",
+	})
+	pkg.AssertError(t, false, err, "%s", k)
+
+	// RethinkDB delay
+	time.Sleep(100 * time.Millisecond)
+
+	d, err := m.GetClient("5678")
+	pkg.AssertError(t, false, err, "%s", k)
+	if err == nil {
+		assert.Equal(t, "bar", d.(*fosite.DefaultClient).TermsOfServiceURI, "%s", k)
+		assert.NotEmpty(t, d.GetHashedSecret(), "%s", k)
+	}
+
+	if a, ok := m.(Manager); ok {
+		_, err = a.Authenticate("5678", []byte("secret"))
+		pkg.AssertError(t, false, err, "%s", k)
+	}
+
+	err = m.DeleteClient("5678")
+	pkg.AssertError(t, false, err, "%s", k)
+}
+
+func compare(t *testing.T, c fosite.Client, k string) {
+	assert.Equal(t, c.GetID(), "1234", "%s", k)
+	assert.NotEmpty(t, c.GetHashedSecret(), "%s", k)
+	assert.Equal(t, c.GetRedirectURIs(), []string{"http://redirect"}, "%s", k)
+}
@@ -0,0 +1,208 @@
+package client
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ory-am/fosite"
+	"github.com/square/go-jose"
+)
+
+const (
+	ErrInvalidRedirectURI    = "invalid_redirect_uri"
+	ErrInvalidClientMetadata = "invalid_client_metadata"
+)
+
+// Metadata is the client metadata of the OAuth 2.0 Dynamic Client Registration Protocol (RFC 7591).
+type Metadata struct {
+	RedirectURIs            []string `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	ClientName              string   `json:"client_name,omitempty"`
+	ClientURI               string   `json:"client_uri,omitempty"`
+	LogoURI                 string   `json:"logo_uri,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	Contacts                []string `json:"contacts,omitempty"`
+	TermsOfServiceURI       string   `json:"tos_uri,omitempty"`
+	PolicyURI               string   `json:"policy_uri,omitempty"`
+
+	// The ID token settings are defined by OpenID Connect Dynamic Client Registration 1.0 section 2.
+	IDTokenSignedResponseAlg    string              `json:"id_token_signed_response_alg,omitempty"`
+	IDTokenEncryptedResponseAlg string              `json:"id_token_encrypted_response_alg,omitempty"`
+	IDTokenEncryptedResponseEnc string              `json:"id_token_encrypted_response_enc,omitempty"`
+	JWKs                        *jose.JsonWebKeySet `json:"jwks,omitempty"`
+	JWKsURI                     string              `json:"jwks_uri,omitempty"`
+
+	// The logout settings are defined by OpenID Connect RP-Initiated Logout 1.0 and Back-Channel Logout 1.0.
+	PostLogoutRedirectURIs           []string `json:"post_logout_redirect_uris,omitempty"`
+	BackChannelLogoutURI             string   `json:"backchannel_logout_uri,omitempty"`
+	BackChannelLogoutSessionRequired bool     `json:"backchannel_logout_session_required,omitempty"`
+}
+
+// RegistrationError is the error response of RFC 7591.
+type RegistrationError struct {
+	Name        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *RegistrationError) Error() string {
+	return e.Description
+}
+
+var registrationGrantTypes = map[string]bool{
+	"authorization_code": true,
+	"implicit":           true,
+	"refresh_token":      true,
+	"client_credentials": true,
+}
+
+var registrationResponseTypes = map[string]bool{
+	"code":     true,
+	"token":    true,
+	"id_token": true,
+}
+
+// Validate checks the metadata and fills in the defaults of RFC 7591.
+func (m *Metadata) Validate() error {
+	if m.TokenEndpointAuthMethod == "" {
+		m.TokenEndpointAuthMethod = "client_secret_basic"
+	} else if m.TokenEndpointAuthMethod != "client_secret_basic" {
+		return &RegistrationError{Name: ErrInvalidClientMetadata, Description: "Only client_secret_basic is supported as token_endpoint_auth_method"}
+	}
+
+	if len(m.GrantTypes) == 0 {
+		m.GrantTypes = []string{"authorization_code"}
+	}
+	if len(m.ResponseTypes) == 0 && contains(m.GrantTypes, "authorization_code") {
+		m.ResponseTypes = []string{"code"}
+	}
+
+	for _, g := range m.GrantTypes {
+		if !registrationGrantTypes[g] {
+			return &RegistrationError{Name: ErrInvalidClientMetadata, Description: "Grant type " + g + " is not supported"}
+		}
+	}
+
+	for _, rt := range m.ResponseTypes {
+		for _, t := range strings.Split(rt, " ") {
+			if !registrationResponseTypes[t] {
+				return &RegistrationError{Name: ErrInvalidClientMetadata, Description: "Response type " + rt + " is not supported"}
+			}
+		}
+	}
+
+	if contains(m.GrantTypes, "authorization_code") != hasResponseType(m.ResponseTypes, "code") {
+		return &RegistrationError{Name: ErrInvalidClientMetadata, Description: "Grant type authorization_code requires response type code and vice versa"}
+	} else if contains(m.GrantTypes, "implicit") != (hasResponseType(m.ResponseTypes, "token") || hasResponseType(m.ResponseTypes, "id_token")) {
+		return &RegistrationError{Name: ErrInvalidClientMetadata, Description: "Grant type implicit requires response type token or id_token and vice versa"}
+	}
+
+	redirects := contains(m.GrantTypes, "authorization_code") || contains(m.GrantTypes, "implicit")
+	if redirects && len(m.RedirectURIs) == 0 {
+		return &RegistrationError{Name: ErrInvalidRedirectURI, Description: "At least one redirect uri is required"}
+	}
+
+	for _, uri := range m.RedirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return &RegistrationError{Name: ErrInvalidRedirectURI, Description: "Redirect uri " + uri + " must be absolute and must not contain a fragment"}
+		}
+	}
+
+	for _, uri := range []string{m.ClientURI, m.LogoURI, m.TermsOfServiceURI, m.PolicyURI} {
+		if uri == "" {
+			continue
+		} else if u, err := url.Parse(uri); err != nil || !u.IsAbs() {
+			return &RegistrationError{Name: ErrInvalidClientMetadata, Description: "Uri " + uri + " must be absolute"}
+		}
+	}
+
+	settings := m.Settings("")
+	if err := settings.Validate(); err != nil {
+		return &RegistrationError{Name: ErrInvalidClientMetadata, Description: err.Error()}
+	}
+	m.SetSettings(settings)
+
+	if m.Scope == "" {
+		m.Scope = "core"
+	}
+	return nil
+}
+
+// Settings returns the client settings the metadata declares.
+func (m *Metadata) Settings(clientID string) *Settings {
+	return &Settings{
+		ClientID:                    clientID,
+		IDTokenSignedResponseAlg:    m.IDTokenSignedResponseAlg,
+		IDTokenEncryptedResponseAlg: m.IDTokenEncryptedResponseAlg,
+		IDTokenEncryptedResponseEnc: m.IDTokenEncryptedResponseEnc,
+		JWKs:                        m.JWKs,
+		JWKsURI:                     m.JWKsURI,
+
+		PostLogoutRedirectURIs:           m.PostLogoutRedirectURIs,
+		BackChannelLogoutURI:             m.BackChannelLogoutURI,
+		BackChannelLogoutSessionRequired: m.BackChannelLogoutSessionRequired,
+	}
+}
+
+// SetSettings applies client settings to the metadata.
+func (m *Metadata) SetSettings(s *Settings) {
+	m.IDTokenSignedResponseAlg = s.IDTokenSignedResponseAlg
+	m.IDTokenEncryptedResponseAlg = s.IDTokenEncryptedResponseAlg
+	m.IDTokenEncryptedResponseEnc = s.IDTokenEncryptedResponseEnc
+	m.JWKs = s.JWKs
+	m.JWKsURI = s.JWKsURI
+	m.PostLogoutRedirectURIs = s.PostLogoutRedirectURIs
+	m.BackChannelLogoutURI = s.BackChannelLogoutURI
+	m.BackChannelLogoutSessionRequired = s.BackChannelLogoutSessionRequired
+}
+
+// ToClient applies the metadata to a client.
+func (m *Metadata) ToClient(c *fosite.DefaultClient) {
+	c.Name = m.ClientName
+	c.RedirectURIs = m.RedirectURIs
+	c.GrantTypes = m.GrantTypes
+	c.ResponseTypes = m.ResponseTypes
+	c.GrantedScopes = strings.Fields(m.Scope)
+	c.ClientURI = m.ClientURI
+	c.LogoURI = m.LogoURI
+	c.Contacts = m.Contacts
+	c.TermsOfServiceURI = m.TermsOfServiceURI
+	c.PolicyURI = m.PolicyURI
+}
+
+// MetadataFromClient returns the registration metadata of a client.
+func MetadataFromClient(c *fosite.DefaultClient) Metadata {
+	return Metadata{
+		RedirectURIs:            c.RedirectURIs,
+		TokenEndpointAuthMethod: "client_secret_basic",
+		GrantTypes:              c.GrantTypes,
+		ResponseTypes:           c.ResponseTypes,
+		ClientName:              c.Name,
+		ClientURI:               c.ClientURI,
+		LogoURI:                 c.LogoURI,
+		Scope:                   strings.Join(c.GrantedScopes, " "),
+		Contacts:                c.Contacts,
+		TermsOfServiceURI:       c.TermsOfServiceURI,
+		PolicyURI:               c.PolicyURI,
+	}
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, h := range haystack {
+		if h == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func hasResponseType(responseTypes []string, needle string) bool {
+	for _, rt := range responseTypes {
+		if contains(strings.Split(rt, " "), needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+package client
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/quota"
+	"github.com/ory-am/hydra/scope"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const (
+	RegistrationHandlerPath = "/oauth2/register"
+)
+
+// RegistrationHandler implements the OAuth 2.0 Dynamic Client Registration Protocol (RFC 7591) and the
+// management of registered clients with their registration access token (RFC 7592).
+type RegistrationHandler struct {
+	Manager       Manager
+	Registrations RegistrationManager
+	Settings      SettingsManager
+	H             herodot.Herodot
+	W             firewall.Firewall
+
+	// Open allows clients to register without an initial access token. Openly registered clients can not
+	// request any of hydra's administrative scopes.
+	Open bool
+
+	// ScopeStrategy is the strategy of the token endpoint, nil is scope.Hierarchic. Openly registered clients can
+	// not request scopes which cover an administrative scope under it.
+	ScopeStrategy scope.Strategy
+
+	// Endpoint is the public address of the registration endpoint, used for registration_client_uri.
+	Endpoint *url.URL
+}
+
+// RegistrationResponse is the client information response of RFC 7591.
+type RegistrationResponse struct {
+	Metadata
+
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at,omitempty"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+}
+
+func (h *RegistrationHandler) SetRoutes(r *httprouter.Router) {
+	r.POST(RegistrationHandlerPath, h.Register)
+	r.GET(RegistrationHandlerPath+"/:id", h.Get)
+	r.PUT(RegistrationHandlerPath+"/:id", h.Update)
+	r.DELETE(RegistrationHandlerPath+"/:id", h.Delete)
+}
+
+func (h *RegistrationHandler) Register(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var m Metadata
+
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		h.writeError(ctx, w, r, &RegistrationError{Name: ErrInvalidClientMetadata, Description: err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	var owner string
+	if !h.Open {
+		fctx, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+			Resource: ClientsResource,
+			Action:   "create",
+		}, Scope)
+		if err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		owner = fctx.Subject
+	}
+
+	if err := m.Validate(); err != nil {
+		h.writeError(ctx, w, r, err)
+		return
+	} else if err := h.checkScopes(m.Scope); err != nil {
+		h.writeError(ctx, w, r, err)
+		return
+	}
+
+	secret, err := pkg.GenerateSecret(32)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, errors.New(err))
+		return
+	}
+
+	token, err := newRegistrationToken()
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	c := &fosite.DefaultClient{Owner: owner, Secret: secret}
+	m.ToClient(c)
+	if err := h.Manager.CreateClient(c); errors.Is(err, quota.ErrQuotaExceeded) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusForbidden, errors.New("The client quota is exhausted"))
+		return
+	} else if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Registrations.SetRegistrationToken(c.GetID(), hashRegistrationToken(token)); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	} else if err := h.setSettings(m.Settings(c.GetID())); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	res := h.response(c)
+	res.ClientSecret = string(secret)
+	res.ClientIDIssuedAt = time.Now().Unix()
+	res.RegistrationAccessToken = token
+	h.H.WriteCreated(ctx, w, r, res.RegistrationClientURI, res)
+}
+
+func (h *RegistrationHandler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	c, err := h.authorize(r, ps.ByName("id"))
+	if err != nil {
+		h.writeUnauthorized(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, h.response(c))
+}
+
+func (h *RegistrationHandler) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	c, err := h.authorize(r, id)
+	if err != nil {
+		h.writeUnauthorized(ctx, w, r, err)
+		return
+	}
+
+	var req struct {
+		Metadata
+		ClientID string `json:"client_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(ctx, w, r, &RegistrationError{Name: ErrInvalidClientMetadata, Description: err.Error()})
+		return
+	}
+	defer r.Body.Close()
+
+	if req.ClientID != "" && req.ClientID != id {
+		h.writeError(ctx, w, r, &RegistrationError{Name: ErrInvalidClientMetadata, Description: "The client_id does not match the registered client"})
+		return
+	} else if err := req.Metadata.Validate(); err != nil {
+		h.writeError(ctx, w, r, err)
+		return
+	} else if err := h.checkScopes(req.Metadata.Scope); err != nil {
+		h.writeError(ctx, w, r, err)
+		return
+	}
+
+	updated := &fosite.DefaultClient{ID: c.ID, Owner: c.Owner}
+	req.Metadata.ToClient(updated)
+	if err := h.Manager.UpdateClient(updated); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	} else if err := h.setSettings(req.Metadata.Settings(id)); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, h.response(updated))
+}
+
+func (h *RegistrationHandler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if _, err := h.authorize(r, id); err != nil {
+		h.writeUnauthorized(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Manager.DeleteClient(id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	} else if err := h.Registrations.DeleteRegistrationToken(id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	} else if h.Settings != nil {
+		if err := h.Settings.DeleteSettings(id); err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorize checks the registration access token of a request and returns the registered client.
+func (h *RegistrationHandler) authorize(r *http.Request, id string) (*fosite.DefaultClient, error) {
+	auth := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(auth) != 2 || !strings.EqualFold(auth[0], "bearer") {
+		return nil, errors.New("Registration access token is missing")
+	}
+
+	hash, err := h.Registrations.GetRegistrationToken(id)
+	if err != nil {
+		return nil, err
+	} else if subtle.ConstantTimeCompare([]byte(hash), []byte(hashRegistrationToken(auth[1]))) != 1 {
+		return nil, errors.New("Registration access token is invalid")
+	}
+
+	c, err := h.Manager.GetClient(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dc, ok := c.(*fosite.DefaultClient)
+	if !ok {
+		return nil, errors.New("Client can not be managed through the registration endpoint")
+	}
+	return dc, nil
+}
+
+// checkScopes prevents openly registered clients from requesting hydra's administrative scopes, or scopes which
+// cover them under the scope strategy, like * and *.keys under scope.Wildcard.
+func (h *RegistrationHandler) checkScopes(requested string) error {
+	if !h.Open {
+		return nil
+	}
+
+	strategy := h.ScopeStrategy
+	if strategy == nil {
+		strategy = scope.Hierarchic
+	}
+
+	for _, s := range strings.Fields(requested) {
+		// A scope whose first segment is replaced by hydra covers an administrative scope if the first segment
+		// matches hydra, which is how * and *.keys cover hydra and hydra.keys.
+		admin := "hydra"
+		if i := strings.Index(s, "."); i >= 0 {
+			admin += s[i:]
+		}
+
+		if s == "hydra" || strings.HasPrefix(s, "hydra.") || strategy([]string{s}, admin) {
+			return &RegistrationError{Name: ErrInvalidClientMetadata, Description: "Scope " + s + " can not be requested by openly registered clients"}
+		}
+	}
+	return nil
+}
+
+func (h *RegistrationHandler) response(c *fosite.DefaultClient) *RegistrationResponse {
+	res := &RegistrationResponse{
+		Metadata:              MetadataFromClient(c),
+		ClientID:              c.GetID(),
+		RegistrationClientURI: pkg.JoinURL(h.Endpoint, c.GetID()).String(),
+	}
+
+	if h.Settings != nil {
+		if s, err := h.Settings.GetSettings(c.GetID()); err == nil {
+			res.SetSettings(s)
+		} else if !errors.Is(err, pkg.ErrNotFound) {
+			logger.LogError(err)
+		}
+	}
+	return res
+}
+
+func (h *RegistrationHandler) setSettings(s *Settings) error {
+	if h.Settings == nil {
+		return nil
+	}
+	return h.Settings.SetSettings(s)
+}
+
+func (h *RegistrationHandler) writeError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	if e, ok := err.(*RegistrationError); ok {
+		h.H.WriteCode(ctx, w, r, http.StatusBadRequest, e)
+		return
+	}
+	h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+}
+
+func (h *RegistrationHandler) writeUnauthorized(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	logger.LogRequestError(r, err)
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	h.H.WriteErrorCode(ctx, w, r, http.StatusUnauthorized, errors.New("The registration access token is missing or invalid"))
+}
+
+func newRegistrationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRegistrationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
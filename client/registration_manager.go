@@ -0,0 +1,12 @@
+package client
+
+// RegistrationManager stores the registration access tokens of dynamically registered clients. Only a hash of
+// each token is stored.
+type RegistrationManager interface {
+	SetRegistrationToken(clientID, hash string) error
+
+	// GetRegistrationToken returns the token hash of a client or pkg.ErrNotFound.
+	GetRegistrationToken(clientID string) (string, error)
+
+	DeleteRegistrationToken(clientID string) error
+}
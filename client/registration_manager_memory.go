@@ -0,0 +1,46 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type RegistrationMemoryManager struct {
+	Tokens map[string]string
+	sync.RWMutex
+}
+
+func NewRegistrationMemoryManager() *RegistrationMemoryManager {
+	return &RegistrationMemoryManager{
+		Tokens: map[string]string{},
+	}
+}
+
+func (m *RegistrationMemoryManager) SetRegistrationToken(clientID, hash string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.Tokens[clientID] = hash
+	return nil
+}
+
+func (m *RegistrationMemoryManager) GetRegistrationToken(clientID string) (string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	hash, ok := m.Tokens[clientID]
+	if !ok {
+		return "", errors.New(pkg.ErrNotFound)
+	}
+	return hash, nil
+}
+
+func (m *RegistrationMemoryManager) DeleteRegistrationToken(clientID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Tokens, clientID)
+	return nil
+}
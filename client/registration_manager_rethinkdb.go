@@ -0,0 +1,52 @@
+package client
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+type registrationSchema struct {
+	ClientID string `gorethink:"id"`
+	Hash     string `gorethink:"hash"`
+}
+
+// RegistrationRethinkManager reads tokens directly from the database. Registration requests are rare, so the
+// tokens are not cached.
+type RegistrationRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *RegistrationRethinkManager) SetRegistrationToken(clientID, hash string) error {
+	if _, err := m.Table.Insert(&registrationSchema{
+		ClientID: clientID,
+		Hash:     hash,
+	}, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RegistrationRethinkManager) GetRegistrationToken(clientID string) (string, error) {
+	res, err := m.Table.Get(clientID).Run(m.Session)
+	if err != nil {
+		return "", errors.New(err)
+	}
+	defer res.Close()
+
+	var s registrationSchema
+	if res.IsNil() {
+		return "", errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&s); err != nil {
+		return "", errors.New(err)
+	}
+	return s.Hash, nil
+}
+
+func (m *RegistrationRethinkManager) DeleteRegistrationToken(clientID string) error {
+	if _, err := m.Table.Get(clientID).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
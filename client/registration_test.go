@@ -0,0 +1,174 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/hash"
+	. "github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	for k, c := range []struct {
+		m     Metadata
+		err   string
+		check func(m Metadata)
+	}{
+		{
+			m: Metadata{RedirectURIs: []string{"https://app/cb"}},
+			check: func(m Metadata) {
+				assert.Equal(t, []string{"authorization_code"}, m.GrantTypes)
+				assert.Equal(t, []string{"code"}, m.ResponseTypes)
+				assert.Equal(t, "client_secret_basic", m.TokenEndpointAuthMethod)
+				assert.Equal(t, "core", m.Scope)
+			},
+		},
+		{m: Metadata{}, err: ErrInvalidRedirectURI},
+		{m: Metadata{RedirectURIs: []string{"/cb"}}, err: ErrInvalidRedirectURI},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb#foo"}}, err: ErrInvalidRedirectURI},
+		{m: Metadata{GrantTypes: []string{"client_credentials"}}},
+		{m: Metadata{GrantTypes: []string{"password"}}, err: ErrInvalidClientMetadata},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, ResponseTypes: []string{"token"}}, err: ErrInvalidClientMetadata},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, GrantTypes: []string{"authorization_code", "implicit"}, ResponseTypes: []string{"code", "code id_token"}}},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, TokenEndpointAuthMethod: "private_key_jwt"}, err: ErrInvalidClientMetadata},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, LogoURI: "logo.png"}, err: ErrInvalidClientMetadata},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, IDTokenSignedResponseAlg: "ES256"}},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, IDTokenSignedResponseAlg: "EdDSA"}, err: ErrInvalidClientMetadata},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, IDTokenSignedResponseAlg: "none"}, err: ErrInvalidClientMetadata},
+		{
+			m: Metadata{RedirectURIs: []string{"https://app/cb"}, IDTokenEncryptedResponseAlg: "RSA-OAEP", JWKsURI: "https://app/jwks.json"},
+			check: func(m Metadata) {
+				assert.Equal(t, "A128CBC-HS256", m.IDTokenEncryptedResponseEnc)
+			},
+		},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, IDTokenEncryptedResponseAlg: "RSA-OAEP"}, err: ErrInvalidClientMetadata},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, IDTokenEncryptedResponseAlg: "dir", JWKsURI: "https://app/jwks.json"}, err: ErrInvalidClientMetadata},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, IDTokenEncryptedResponseEnc: "A128GCM"}, err: ErrInvalidClientMetadata},
+		{m: Metadata{RedirectURIs: []string{"https://app/cb"}, JWKsURI: "http://app/jwks.json"}, err: ErrInvalidClientMetadata},
+	} {
+		err := c.m.Validate()
+		if c.err == "" {
+			require.Nil(t, err, "Case %d", k)
+			if c.check != nil {
+				c.check(c.m)
+			}
+			continue
+		}
+
+		require.NotNil(t, err, "Case %d", k)
+		e, ok := err.(*RegistrationError)
+		require.True(t, ok, "Case %d", k)
+		assert.Equal(t, c.err, e.Name, "Case %d", k)
+	}
+}
+
+func TestRegistrationHandler(t *testing.T) {
+	h := &RegistrationHandler{
+		Manager: &MemoryManager{
+			Clients: map[string]*fosite.DefaultClient{},
+			Hasher:  &hash.BCrypt{WorkFactor: 4},
+		},
+		Registrations: NewRegistrationMemoryManager(),
+		Settings:      NewSettingsMemoryManager(),
+		H:             &herodot.JSON{},
+		Open:          true,
+	}
+
+	r := httprouter.New()
+	h.SetRoutes(r)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	h.Endpoint, _ = url.Parse(ts.URL + RegistrationHandlerPath)
+
+	do := func(method, path, token string, body interface{}) (*http.Response, *RegistrationResponse) {
+		var buf bytes.Buffer
+		if body != nil {
+			require.Nil(t, json.NewEncoder(&buf).Encode(body))
+		}
+
+		req, err := http.NewRequest(method, ts.URL+path, &buf)
+		require.Nil(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer res.Body.Close()
+
+		var out RegistrationResponse
+		json.NewDecoder(res.Body).Decode(&out)
+		return res, &out
+	}
+
+	res, _ := do("POST", RegistrationHandlerPath, "", &Metadata{RedirectURIs: []string{"https://app/cb"}, Scope: "core hydra.clients"})
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// Scopes which cover an administrative scope under the strategy are rejected as well.
+	for k, c := range []struct {
+		strategy scope.Strategy
+		scope    string
+		status   int
+	}{
+		{strategy: scope.Wildcard, scope: "core *", status: http.StatusBadRequest},
+		{strategy: scope.Wildcard, scope: "core *.keys", status: http.StatusBadRequest},
+		{strategy: scope.Wildcard, scope: "core *.*.get", status: http.StatusBadRequest},
+		{strategy: scope.Wildcard, scope: "core photos.*", status: http.StatusCreated},
+		{strategy: scope.Hierarchic, scope: "core *", status: http.StatusCreated},
+		{strategy: scope.Exact, scope: "core *.keys", status: http.StatusCreated},
+	} {
+		h.ScopeStrategy = c.strategy
+		res, _ := do("POST", RegistrationHandlerPath, "", &Metadata{RedirectURIs: []string{"https://app/cb"}, Scope: c.scope})
+		assert.Equal(t, c.status, res.StatusCode, "Case %d", k)
+	}
+	h.ScopeStrategy = nil
+
+	res, registered := do("POST", RegistrationHandlerPath, "", &Metadata{RedirectURIs: []string{"https://app/cb"}, ClientName: "app", IDTokenSignedResponseAlg: "ES256"})
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, "ES256", registered.IDTokenSignedResponseAlg)
+	assert.NotEmpty(t, registered.ClientID)
+	assert.NotEmpty(t, registered.ClientSecret)
+	assert.NotEmpty(t, registered.RegistrationAccessToken)
+	assert.Equal(t, h.Endpoint.String()+"/"+registered.ClientID, registered.RegistrationClientURI)
+
+	path := RegistrationHandlerPath + "/" + registered.ClientID
+	res, _ = do("GET", path, "", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, _ = do("GET", path, "not-the-token", nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res, got := do("GET", path, registered.RegistrationAccessToken, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "app", got.ClientName)
+	assert.Equal(t, "ES256", got.IDTokenSignedResponseAlg)
+	assert.Empty(t, got.ClientSecret)
+
+	res, got = do("PUT", path, registered.RegistrationAccessToken, &Metadata{RedirectURIs: []string{"https://app/cb2"}, ClientName: "renamed"})
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "renamed", got.ClientName)
+	assert.Equal(t, []string{"https://app/cb2"}, got.RedirectURIs)
+	assert.Empty(t, got.IDTokenSignedResponseAlg)
+
+	_, err := h.Manager.Authenticate(registered.ClientID, []byte(registered.ClientSecret))
+	assert.Nil(t, err)
+
+	res, _ = do("DELETE", path, registered.RegistrationAccessToken, nil)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	res, _ = do("GET", path, registered.RegistrationAccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	_, err = h.Settings.GetSettings(registered.ClientID)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/hash"
+)
+
+// SecretRotation keeps the secret a client had before its secret was rotated valid until ExpiresAt, so the
+// client's deployments can pick up the new secret without downtime.
+type SecretRotation struct {
+	ClientID string `json:"id" gorethink:"id"`
+
+	// Secret is the hash of the secret the client was given by the rotation.
+	Secret []byte `json:"-" gorethink:"secret"`
+
+	// PreviousSecret is the hash of the secret the client had before.
+	PreviousSecret []byte `json:"-" gorethink:"previous_secret"`
+
+	ExpiresAt time.Time `json:"expires_at" gorethink:"expires_at"`
+}
+
+// SecretRotationRequest is the body of a rotation request.
+type SecretRotationRequest struct {
+	// Overlap is how long the previous secret stays valid, for example "1h". It defaults to the server's setting.
+	Overlap string `json:"overlap,omitempty"`
+}
+
+// SecretRotationResponse carries the new secret. The secret is only ever returned by the rotation itself.
+type SecretRotationResponse struct {
+	ClientID                string    `json:"client_id"`
+	ClientSecret            string    `json:"client_secret"`
+	PreviousSecretExpiresAt time.Time `json:"previous_secret_expires_at"`
+}
+
+// RotationManager stores the secret rotations which are still in their overlap window.
+type RotationManager interface {
+	SetSecretRotation(r *SecretRotation) error
+
+	// GetSecretRotation returns the last rotation of a client's secret or pkg.ErrNotFound.
+	GetSecretRotation(clientID string) (*SecretRotation, error)
+
+	DeleteSecretRotation(clientID string) error
+}
+
+// RotatingManager accepts the previous secret of a client whose secret was rotated recently. Fosite compares
+// secrets itself, so GetClient returns clients in their overlap window with both hashes, which RotatingHasher
+// understands. Only hand it to the components which authenticate clients, the others must see the stored secret.
+type RotatingManager struct {
+	Manager
+	Rotations RotationManager
+	Hasher    hash.Hasher
+}
+
+func (m *RotatingManager) GetClient(id string) (fosite.Client, error) {
+	c, err := m.Manager.GetClient(id)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, ok := c.(*fosite.DefaultClient)
+	if !ok {
+		return c, nil
+	}
+
+	rotation := m.overlapping(stored)
+	if rotation == nil {
+		return c, nil
+	}
+
+	rotating := *stored
+	rotating.Secret = joinRotatingSecret(stored.Secret, rotation.PreviousSecret)
+	return &rotating, nil
+}
+
+func (m *RotatingManager) Authenticate(id string, secret []byte) (*fosite.DefaultClient, error) {
+	c, err := m.Manager.Authenticate(id, secret)
+	if err == nil {
+		return c, nil
+	}
+
+	o, gerr := m.Manager.GetClient(id)
+	if gerr != nil {
+		return nil, err
+	}
+
+	stored, ok := o.(*fosite.DefaultClient)
+	if !ok {
+		return nil, err
+	} else if rotation := m.overlapping(stored); rotation == nil {
+		return nil, err
+	} else if m.Hasher.Compare(rotation.PreviousSecret, secret) != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// overlapping returns the rotation which gave the client its current secret, if the previous secret is still valid.
+func (m *RotatingManager) overlapping(c *fosite.DefaultClient) *SecretRotation {
+	r, err := m.Rotations.GetSecretRotation(c.ID)
+	if err != nil || time.Now().After(r.ExpiresAt) || !bytes.Equal(r.Secret, c.Secret) {
+		return nil
+	}
+	return r
+}
+
+// rotatingSecretPrefix marks the hashed secret of a client in its overlap window, see RotatingManager.
+const rotatingSecretPrefix = "$rotating$"
+
+func joinRotatingSecret(current, previous []byte) []byte {
+	return []byte(rotatingSecretPrefix + base64.RawStdEncoding.EncodeToString(current) + "$" + base64.RawStdEncoding.EncodeToString(previous))
+}
+
+func splitRotatingSecret(hash []byte) (current, previous []byte, ok bool) {
+	if !bytes.HasPrefix(hash, []byte(rotatingSecretPrefix)) {
+		return nil, nil, false
+	}
+
+	parts := strings.SplitN(string(hash[len(rotatingSecretPrefix):]), "$", 2)
+	if len(parts) != 2 {
+		return nil, nil, false
+	}
+
+	current, err := base64.RawStdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, false
+	}
+	previous, err = base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, false
+	}
+	return current, previous, true
+}
+
+// RotatingHasher accepts both secrets of a client which RotatingManager returned in its overlap window. Other
+// hashes are compared by Hasher.
+type RotatingHasher struct {
+	hash.Hasher
+}
+
+func (h *RotatingHasher) Compare(hash, data []byte) error {
+	current, previous, ok := splitRotatingSecret(hash)
+	if !ok {
+		return h.Hasher.Compare(hash, data)
+	}
+
+	err := h.Hasher.Compare(current, data)
+	if err == nil {
+		return nil
+	} else if h.Hasher.Compare(previous, data) == nil {
+		return nil
+	}
+	return err
+}
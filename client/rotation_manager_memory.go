@@ -0,0 +1,46 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type RotationMemoryManager struct {
+	Rotations map[string]*SecretRotation
+	sync.RWMutex
+}
+
+func NewRotationMemoryManager() *RotationMemoryManager {
+	return &RotationMemoryManager{
+		Rotations: map[string]*SecretRotation{},
+	}
+}
+
+func (m *RotationMemoryManager) SetSecretRotation(r *SecretRotation) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.Rotations[r.ClientID] = r
+	return nil
+}
+
+func (m *RotationMemoryManager) GetSecretRotation(clientID string) (*SecretRotation, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	r, ok := m.Rotations[clientID]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return r, nil
+}
+
+func (m *RotationMemoryManager) DeleteSecretRotation(clientID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Rotations, clientID)
+	return nil
+}
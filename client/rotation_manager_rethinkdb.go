@@ -0,0 +1,43 @@
+package client
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// RotationRethinkManager reads rotations directly from the database, by the id of their client.
+type RotationRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *RotationRethinkManager) SetSecretRotation(rotation *SecretRotation) error {
+	if _, err := m.Table.Insert(rotation, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RotationRethinkManager) GetSecretRotation(clientID string) (*SecretRotation, error) {
+	res, err := m.Table.Get(clientID).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var rotation SecretRotation
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&rotation); err != nil {
+		return nil, errors.New(err)
+	}
+	return &rotation, nil
+}
+
+func (m *RotationRethinkManager) DeleteSecretRotation(clientID string) error {
+	if _, err := m.Table.Get(clientID).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"net/url"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/cors"
+	"github.com/square/go-jose"
+)
+
+// IDTokenSigningAlgorithms are the algorithms clients may ask their ID tokens to be signed with.
+var IDTokenSigningAlgorithms = map[string]bool{
+	"RS256": true,
+	"ES256": true,
+	"ES384": true,
+	"ES512": true,
+}
+
+// IDTokenEncryptionAlgorithms are the key management algorithms ID tokens may be encrypted with. They all encrypt to
+// a public key of the client.
+var IDTokenEncryptionAlgorithms = map[string]bool{
+	string(jose.RSA1_5):         true,
+	string(jose.RSA_OAEP):       true,
+	string(jose.RSA_OAEP_256):   true,
+	string(jose.ECDH_ES):        true,
+	string(jose.ECDH_ES_A128KW): true,
+	string(jose.ECDH_ES_A192KW): true,
+	string(jose.ECDH_ES_A256KW): true,
+}
+
+// IDTokenEncryptionEncodings are the content encryption algorithms ID tokens may be encrypted with.
+var IDTokenEncryptionEncodings = map[string]bool{
+	string(jose.A128CBC_HS256): true,
+	string(jose.A192CBC_HS384): true,
+	string(jose.A256CBC_HS512): true,
+	string(jose.A128GCM):       true,
+	string(jose.A192GCM):       true,
+	string(jose.A256GCM):       true,
+}
+
+// DefaultIDTokenEncryptionEncoding is used if a client declares an encryption algorithm but no encoding, see OpenID
+// Connect Dynamic Client Registration 1.0 section 2.
+const DefaultIDTokenEncryptionEncoding = string(jose.A128CBC_HS256)
+
+// Settings are the preferences of a client which fosite's client model has no room for. They are stored next to
+// the client and removed with it.
+type Settings struct {
+	ClientID string `json:"client_id" gorethink:"id"`
+
+	// IDTokenSignedResponseAlg is the algorithm ID tokens issued to the client are signed with. The server's
+	// default is used if it is empty.
+	IDTokenSignedResponseAlg string `json:"id_token_signed_response_alg,omitempty" gorethink:"id_token_signed_response_alg,omitempty"`
+
+	// IDTokenEncryptedResponseAlg and IDTokenEncryptedResponseEnc turn on ID token encryption. The signed ID token is
+	// encrypted to a key of JWKs or of the set published at JWKsURI.
+	IDTokenEncryptedResponseAlg string `json:"id_token_encrypted_response_alg,omitempty" gorethink:"id_token_encrypted_response_alg,omitempty"`
+	IDTokenEncryptedResponseEnc string `json:"id_token_encrypted_response_enc,omitempty" gorethink:"id_token_encrypted_response_enc,omitempty"`
+
+	JWKs    *jose.JsonWebKeySet `json:"jwks,omitempty" gorethink:"jwks,omitempty"`
+	JWKsURI string              `json:"jwks_uri,omitempty" gorethink:"jwks_uri,omitempty"`
+
+	// PostLogoutRedirectURIs are the URLs the user agent may be sent to after an RP-initiated logout.
+	PostLogoutRedirectURIs []string `json:"post_logout_redirect_uris,omitempty" gorethink:"post_logout_redirect_uris,omitempty"`
+
+	// BackChannelLogoutURI receives a logout token when a session the client took part in ends.
+	// BackChannelLogoutSessionRequired asks for the token to carry the session id.
+	BackChannelLogoutURI             string `json:"backchannel_logout_uri,omitempty" gorethink:"backchannel_logout_uri,omitempty"`
+	BackChannelLogoutSessionRequired bool   `json:"backchannel_logout_session_required,omitempty" gorethink:"backchannel_logout_session_required,omitempty"`
+
+	// AllowedCORSOrigins are the origins of browser based apps which may call the public endpoints as this client.
+	AllowedCORSOrigins []string `json:"allowed_cors_origins,omitempty" gorethink:"allowed_cors_origins,omitempty"`
+
+	// AccessTokenLifespan, RefreshTokenLifespan, IDTokenLifespan and AuthorizeCodeLifespan override the server's
+	// lifespans of the tokens issued to the client. They are durations like 15m, tokens of empty lifespans live as
+	// long as the server says.
+	AccessTokenLifespan   string `json:"access_token_lifespan,omitempty" gorethink:"access_token_lifespan,omitempty"`
+	RefreshTokenLifespan  string `json:"refresh_token_lifespan,omitempty" gorethink:"refresh_token_lifespan,omitempty"`
+	IDTokenLifespan       string `json:"id_token_lifespan,omitempty" gorethink:"id_token_lifespan,omitempty"`
+	AuthorizeCodeLifespan string `json:"authorize_code_lifespan,omitempty" gorethink:"authorize_code_lifespan,omitempty"`
+}
+
+// Lifespans are the lifespans of the tokens issued to a client. A zero lifespan is the server's lifespan.
+type Lifespans struct {
+	AccessToken   time.Duration
+	RefreshToken  time.Duration
+	IDToken       time.Duration
+	AuthorizeCode time.Duration
+}
+
+// GetLifespans returns the lifespans the client overrides. Lifespans which do not parse are ignored, Validate
+// refuses to store them.
+func (s *Settings) GetLifespans() Lifespans {
+	parse := func(lifespan string) time.Duration {
+		d, _ := time.ParseDuration(lifespan)
+		return d
+	}
+
+	return Lifespans{
+		AccessToken:   parse(s.AccessTokenLifespan),
+		RefreshToken:  parse(s.RefreshTokenLifespan),
+		IDToken:       parse(s.IDTokenLifespan),
+		AuthorizeCode: parse(s.AuthorizeCodeLifespan),
+	}
+}
+
+// Validate refuses lifespans which are longer than the server's. The janitor deletes tokens once the server's
+// lifespan elapsed, tokens of longer lifespans would vanish before they expire. Zero server lifespans do not limit.
+func (l Lifespans) Validate(server Lifespans) error {
+	for _, c := range []struct {
+		name          string
+		lifespan, max time.Duration
+	}{
+		{name: "access_token_lifespan", lifespan: l.AccessToken, max: server.AccessToken},
+		{name: "refresh_token_lifespan", lifespan: l.RefreshToken, max: server.RefreshToken},
+		{name: "id_token_lifespan", lifespan: l.IDToken, max: server.IDToken},
+		{name: "authorize_code_lifespan", lifespan: l.AuthorizeCode, max: server.AuthorizeCode},
+	} {
+		if c.max > 0 && c.lifespan > c.max {
+			return errors.Errorf("%s %s must not exceed the server's lifespan of %s", c.name, c.lifespan, c.max)
+		}
+	}
+	return nil
+}
+
+// EncryptsIDTokens returns true if ID tokens issued to the client are encrypted.
+func (s *Settings) EncryptsIDTokens() bool {
+	return s.IDTokenEncryptedResponseAlg != ""
+}
+
+// Validate checks the settings.
+func (s *Settings) Validate() error {
+	if alg := s.IDTokenSignedResponseAlg; alg != "" && !IDTokenSigningAlgorithms[alg] {
+		if alg == "EdDSA" {
+			return errors.New("EdDSA signed ID tokens are not supported because the key store can not hold Ed25519 keys")
+		}
+		return errors.Errorf("ID tokens can not be signed with %s", alg)
+	}
+
+	if s.JWKs != nil && s.JWKsURI != "" {
+		return errors.New("Only one of jwks and jwks_uri may be set")
+	} else if s.JWKs != nil {
+		for _, key := range s.JWKs.Keys {
+			switch key.Key.(type) {
+			case *rsa.PrivateKey, *ecdsa.PrivateKey, []byte:
+				return errors.Errorf("Key %s of jwks is not a public key", key.KeyID)
+			}
+		}
+	} else if s.JWKsURI != "" {
+		if u, err := url.Parse(s.JWKsURI); err != nil || u.Scheme != "https" {
+			return errors.Errorf("jwks_uri %s must be an https url", s.JWKsURI)
+		}
+	}
+
+	for _, uri := range s.PostLogoutRedirectURIs {
+		if u, err := url.Parse(uri); err != nil || !u.IsAbs() || u.Fragment != "" {
+			return errors.Errorf("Post logout redirect uri %s must be absolute and must not contain a fragment", uri)
+		}
+	}
+
+	for _, origin := range s.AllowedCORSOrigins {
+		if err := cors.ValidateOrigin(origin); err != nil {
+			return err
+		}
+	}
+
+	for name, lifespan := range map[string]string{
+		"access_token_lifespan":   s.AccessTokenLifespan,
+		"refresh_token_lifespan":  s.RefreshTokenLifespan,
+		"id_token_lifespan":       s.IDTokenLifespan,
+		"authorize_code_lifespan": s.AuthorizeCodeLifespan,
+	} {
+		if lifespan == "" {
+			continue
+		} else if d, err := time.ParseDuration(lifespan); err != nil || d <= 0 {
+			return errors.Errorf("%s %s must be a positive duration like 15m", name, lifespan)
+		}
+	}
+
+	if uri := s.BackChannelLogoutURI; uri != "" {
+		// Logout tokens identify the subject and its session, they must not be sent in the clear.
+		if u, err := url.Parse(uri); err != nil || u.Scheme != "https" || u.Fragment != "" {
+			return errors.Errorf("Back-channel logout uri %s must be an https url and must not contain a fragment", uri)
+		}
+	} else if s.BackChannelLogoutSessionRequired {
+		return errors.New("backchannel_logout_session_required requires backchannel_logout_uri")
+	}
+
+	if !s.EncryptsIDTokens() {
+		if s.IDTokenEncryptedResponseEnc != "" {
+			return errors.New("id_token_encrypted_response_enc requires id_token_encrypted_response_alg")
+		}
+		return nil
+	}
+
+	if s.IDTokenEncryptedResponseEnc == "" {
+		s.IDTokenEncryptedResponseEnc = DefaultIDTokenEncryptionEncoding
+	}
+
+	if !IDTokenEncryptionAlgorithms[s.IDTokenEncryptedResponseAlg] {
+		return errors.Errorf("ID tokens can not be encrypted with %s", s.IDTokenEncryptedResponseAlg)
+	} else if !IDTokenEncryptionEncodings[s.IDTokenEncryptedResponseEnc] {
+		return errors.Errorf("ID tokens can not be encrypted with %s", s.IDTokenEncryptedResponseEnc)
+	} else if s.JWKs == nil && s.JWKsURI == "" {
+		return errors.New("Encrypted ID tokens require jwks or jwks_uri")
+	}
+	return nil
+}
+
+// CORSOrigins allows the origins clients registered in their settings.
+type CORSOrigins struct {
+	Settings SettingsManager
+}
+
+func (o *CORSOrigins) IsOriginAllowed(clientID, origin string) bool {
+	s, err := o.Settings.GetSettings(clientID)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range s.AllowedCORSOrigins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// SettingsManager stores the settings of clients.
+type SettingsManager interface {
+	// GetSettings returns the settings of a client or pkg.ErrNotFound.
+	GetSettings(clientID string) (*Settings, error)
+
+	SetSettings(s *Settings) error
+
+	DeleteSettings(clientID string) error
+}
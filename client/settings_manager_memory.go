@@ -0,0 +1,49 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type SettingsMemoryManager struct {
+	Settings map[string]*Settings
+	sync.RWMutex
+}
+
+func NewSettingsMemoryManager() *SettingsMemoryManager {
+	return &SettingsMemoryManager{
+		Settings: map[string]*Settings{},
+	}
+}
+
+func (m *SettingsMemoryManager) GetSettings(clientID string) (*Settings, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	s, ok := m.Settings[clientID]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	c := *s
+	return &c, nil
+}
+
+func (m *SettingsMemoryManager) SetSettings(s *Settings) error {
+	m.Lock()
+	defer m.Unlock()
+
+	c := *s
+	m.Settings[s.ClientID] = &c
+	return nil
+}
+
+func (m *SettingsMemoryManager) DeleteSettings(clientID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Settings, clientID)
+	return nil
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// SettingsRethinkManager reads settings directly from the database.
+type SettingsRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *SettingsRethinkManager) GetSettings(clientID string) (*Settings, error) {
+	res, err := m.Table.Get(clientID).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var s Settings
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&s); err != nil {
+		return nil, errors.New(err)
+	}
+	return &s, nil
+}
+
+func (m *SettingsRethinkManager) SetSettings(s *Settings) error {
+	if _, err := m.Table.Insert(s, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SettingsRethinkManager) DeleteSettings(clientID string) error {
+	if _, err := m.Table.Get(clientID).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
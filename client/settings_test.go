@@ -0,0 +1,53 @@
+package client_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/ory-am/hydra/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettingsLifespans(t *testing.T) {
+	s := &Settings{ClientID: "short-lived", AccessTokenLifespan: "5m", IDTokenLifespan: "1h"}
+	assert.Nil(t, s.Validate())
+	assert.Equal(t, Lifespans{AccessToken: 5 * time.Minute, IDToken: time.Hour}, s.GetLifespans())
+
+	for _, lifespan := range []string{"forever", "0s", "-5m"} {
+		s.RefreshTokenLifespan = lifespan
+		assert.NotNil(t, s.Validate(), "%s", lifespan)
+	}
+}
+
+func TestLifespansValidate(t *testing.T) {
+	server := Lifespans{AccessToken: time.Hour, AuthorizeCode: time.Hour}
+	for k, c := range []struct {
+		lifespans Lifespans
+		valid     bool
+	}{
+		{lifespans: Lifespans{}, valid: true},
+		{lifespans: Lifespans{AccessToken: 5 * time.Minute}, valid: true},
+		{lifespans: Lifespans{AccessToken: time.Hour}, valid: true},
+		{lifespans: Lifespans{AccessToken: 2 * time.Hour}},
+		{lifespans: Lifespans{AuthorizeCode: 2 * time.Hour}},
+		{lifespans: Lifespans{RefreshToken: 24 * time.Hour * 365}, valid: true},
+	} {
+		assert.Equal(t, c.valid, c.lifespans.Validate(server) == nil, "Case %d", k)
+	}
+}
+
+func TestSettingsBackChannelLogoutURI(t *testing.T) {
+	for k, c := range []struct {
+		uri   string
+		valid bool
+	}{
+		{uri: "", valid: true},
+		{uri: "https://app.localhost/logout", valid: true},
+		{uri: "http://app.localhost/logout"},
+		{uri: "/logout"},
+		{uri: "https://app.localhost/logout#fragment"},
+	} {
+		s := &Settings{ClientID: "app", BackChannelLogoutURI: c.uri}
+		assert.Equal(t, c.valid, s.Validate() == nil, "Case %d", k)
+	}
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler and RegistrationHandler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "GET", Path: ClientsHandlerPath, ID: "listClients", Tag: "clients", Scope: Scope,
+		Summary:    "List clients, keyed by their id",
+		Pagination: []string{"id", "client_name", "owner"},
+		Responses:  map[int]interface{}{http.StatusOK: map[string]*fosite.DefaultClient{}},
+	},
+	{
+		Method: "POST", Path: ClientsHandlerPath, ID: "createClient", Tag: "clients", Scope: Scope,
+		Summary:   "Create a client",
+		Body:      &fosite.DefaultClient{},
+		Responses: map[int]interface{}{http.StatusCreated: &fosite.DefaultClient{}},
+	},
+	{
+		Method: "PATCH", Path: ClientsHandlerPath, ID: "bulkClients", Tag: "clients", Scope: Scope,
+		Summary:   "Create, update and delete clients in bulk",
+		Body:      []*pkg.BulkOperation{},
+		Responses: map[int]interface{}{http.StatusOK: &pkg.BulkResponse{}},
+	},
+	{
+		Method: "DELETE", Path: ClientsHandlerPath, ID: "deleteClientsByOwner", Tag: "clients", Scope: Scope,
+		Summary:   "Delete the clients of an owner",
+		Query:     []string{"owner", "confirm"},
+		Responses: map[int]interface{}{http.StatusOK: &pkg.BulkDeletion{}},
+	},
+	{
+		Method: "GET", Path: ClientsHandlerPath + "/:id", ID: "getClient", Tag: "clients", Scope: Scope,
+		Summary:   "Get a client, the ETag header identifies its version",
+		Responses: map[int]interface{}{http.StatusOK: &fosite.DefaultClient{}},
+	},
+	{
+		Method: "PUT", Path: ClientsHandlerPath + "/:id", ID: "updateClient", Tag: "clients", Scope: Scope,
+		Summary:   "Update a client, unless If-Match names a version which is not the current one",
+		Body:      &fosite.DefaultClient{},
+		Responses: map[int]interface{}{http.StatusOK: &fosite.DefaultClient{}},
+	},
+	{
+		Method: "DELETE", Path: ClientsHandlerPath + "/:id", ID: "deleteClient", Tag: "clients", Scope: Scope,
+		Summary:   "Delete a client, unless If-Match names a version which is not the current one",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "POST", Path: ClientsHandlerPath + "/:id/rotate-secret", ID: "rotateClientSecret", Tag: "clients", Scope: Scope,
+		Summary:   "Give a client a new secret, the previous secret stays valid for the overlap",
+		Body:      &SecretRotationRequest{},
+		Responses: map[int]interface{}{http.StatusCreated: &SecretRotationResponse{}},
+	},
+	{
+		Method: "GET", Path: ClientsHandlerPath + "/:id/settings", ID: "getClientSettings", Tag: "clients", Scope: Scope,
+		Summary:   "Get the settings of a client",
+		Responses: map[int]interface{}{http.StatusOK: &Settings{}},
+	},
+	{
+		Method: "PUT", Path: ClientsHandlerPath + "/:id/settings", ID: "updateClientSettings", Tag: "clients", Scope: Scope,
+		Summary:   "Update the settings of a client",
+		Body:      &Settings{},
+		Responses: map[int]interface{}{http.StatusOK: &Settings{}},
+	},
+	{
+		Method: "GET", Path: ClientsHandlerPath + "/:id/lockout", ID: "getClientLockout", Tag: "clients", Scope: Scope,
+		Summary:   "Get the lockout of a client after failed authentications",
+		Responses: map[int]interface{}{http.StatusOK: &Lockout{}},
+	},
+	{
+		Method: "DELETE", Path: ClientsHandlerPath + "/:id/lockout", ID: "clearClientLockout", Tag: "clients", Scope: Scope,
+		Summary:   "Unlock a client",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "POST", Path: RegistrationHandlerPath, ID: "registerClient", Tag: "registration", BearerAuth: true,
+		Summary:   "Register a client (RFC 7591), with an initial access token unless registration is open",
+		Body:      &Metadata{},
+		Responses: map[int]interface{}{http.StatusCreated: &RegistrationResponse{}},
+	},
+	{
+		Method: "GET", Path: RegistrationHandlerPath + "/:id", ID: "getRegisteredClient", Tag: "registration", BearerAuth: true,
+		Summary:   "Get a registered client with its registration access token (RFC 7592)",
+		Responses: map[int]interface{}{http.StatusOK: &RegistrationResponse{}},
+	},
+	{
+		Method: "PUT", Path: RegistrationHandlerPath + "/:id", ID: "updateRegisteredClient", Tag: "registration", BearerAuth: true,
+		Summary: "Update a registered client with its registration access token (RFC 7592)",
+		Body: &struct {
+			Metadata
+			ClientID string `json:"client_id"`
+		}{},
+		Responses: map[int]interface{}{http.StatusOK: &RegistrationResponse{}},
+	},
+	{
+		Method: "DELETE", Path: RegistrationHandlerPath + "/:id", ID: "deleteRegisteredClient", Tag: "registration", BearerAuth: true,
+		Summary:   "Delete a registered client with its registration access token (RFC 7592)",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+}
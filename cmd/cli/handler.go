@@ -9,6 +9,11 @@ type Handler struct {
 	Connections *ConnectionHandler
 	Policies    *PolicyHandler
 	Keys        *JWKHandler
+	Conformance *ConformanceHandler
+	Flows       *FlowHandler
+	Janitor     *JanitorHandler
+	Migration   *MigrationHandler
+	Tokens      *TokenHandler
 }
 
 func NewHandler(c *config.Config) *Handler {
@@ -17,5 +22,10 @@ func NewHandler(c *config.Config) *Handler {
 		Connections: newConnectionHandler(c),
 		Policies:    newPolicHandler(c),
 		Keys:        newJWKHandler(c),
+		Conformance: newConformanceHandler(c),
+		Flows:       newFlowHandler(c),
+		Janitor:     newJanitorHandler(c),
+		Migration:   newMigrationHandler(c),
+		Tokens:      newTokenHandler(c),
 	}
 }
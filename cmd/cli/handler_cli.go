@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/go-errors/errors"
 	"github.com/ory-am/hydra/config"
 	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/hydra/policy"
@@ -30,15 +31,12 @@ func (h *PolicyHandler) CreatePolicy(cmd *cobra.Command, args []string) {
 
 	files, _ := cmd.Flags().GetStringSlice("files")
 	if len(files) > 0 {
-		for _, path := range files {
-			reader, err := os.Open(path)
-			pkg.Must(err, "Could not open file %s: %s", path, err)
-			var policy ladon.DefaultPolicy
-			err = json.NewDecoder(reader).Decode(&policy)
-			pkg.Must(err, "Could not parse JSON: %s", err)
-			err = h.M.Create(&policy)
+		policies, err := readPolicyFiles(files)
+		pkg.Must(err, "Could not read policies: %s", err)
+		for _, f := range policies {
+			err = h.M.Create(f.Policy)
 			pkg.Must(err, "Could not create policy: %s", err)
-			fmt.Printf("Imported policy %s from %s.\n", policy.ID, path)
+			fmt.Printf("Imported policy %s from %s.\n", f.Policy.ID, f.Source)
 		}
 		return
 	}
@@ -74,6 +72,65 @@ func (h *PolicyHandler) CreatePolicy(cmd *cobra.Command, args []string) {
 
 }
 
+// ApplyPolicies makes the server's policies match the policies of files. New policies are created, changed ones are
+// replaced and the changes are printed. With --dry-run, only the changes are printed.
+func (h *PolicyHandler) ApplyPolicies(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/policies")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+
+	if len(args) == 0 {
+		fmt.Print(cmd.UsageString())
+		return
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	policies, err := readPolicyFiles(args)
+	pkg.Must(err, "Could not read policies: %s", err)
+
+	var changed int
+	for _, f := range policies {
+		if f.Policy.ID == "" {
+			fmt.Fprintf(os.Stderr, "Policy from %s has no id, applying requires one.\n", f.Source)
+			os.Exit(1)
+		}
+
+		var current *ladon.DefaultPolicy
+		p, err := h.M.Get(f.Policy.ID)
+		if err == nil {
+			current = p.(*ladon.DefaultPolicy)
+		} else if !errors.Is(err, pkg.ErrNotFound) {
+			pkg.Must(err, "Could not get policy %s: %s", f.Policy.ID, err)
+		}
+
+		lines := diffPolicies(current, f.Policy)
+		if len(lines) == 0 {
+			continue
+		}
+
+		changed++
+		fmt.Printf("Policy %s from %s:\n", f.Policy.ID, f.Source)
+		for _, line := range lines {
+			fmt.Printf("  %s\n", line)
+		}
+		if dryRun {
+			continue
+		}
+
+		if current != nil {
+			err := h.M.Delete(f.Policy.ID)
+			pkg.Must(err, "Could not prepare policy %s for update: %s", f.Policy.ID, err)
+		}
+		err = h.M.Create(f.Policy)
+		pkg.Must(err, "Could not apply policy %s: %s", f.Policy.ID, err)
+	}
+
+	if dryRun {
+		fmt.Printf("%d of %d policies would change.\n", changed, len(policies))
+		return
+	}
+	fmt.Printf("Applied %d of %d policies, the others are up to date.\n", changed, len(policies))
+}
+
 func (h *PolicyHandler) AddResourceToPolicy(cmd *cobra.Command, args []string) {
 	h.M.Endpoint = h.Config.Resolve("/policies")
 	h.M.Client = h.Config.OAuth2Client(cmd)
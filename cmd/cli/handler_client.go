@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/client"
@@ -54,10 +56,122 @@ func (h *ClientHandler) CreateClient(cmd *cobra.Command, args []string) {
 	err = h.M.CreateClient(client)
 	pkg.Must(err, "Could not create client: %s", err)
 
+	if jsonOutput(cmd) {
+		printJSON(map[string]string{"client_id": client.ID, "client_secret": string(secret)})
+		return
+	}
+
 	fmt.Printf("Client ID: %s\n", client.ID)
 	fmt.Printf("Client Secret: %s\n", secret)
 }
 
+func (h *ClientHandler) GetClient(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/clients")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+	if len(args) != 1 {
+		fmt.Print(cmd.UsageString())
+		return
+	}
+
+	c, err := h.M.GetClient(args[0])
+	pkg.Must(err, "Could not get client: %s", err)
+
+	if jsonOutput(cmd) {
+		printJSON(c)
+		return
+	}
+	printClient(c.(*fosite.DefaultClient))
+}
+
+func (h *ClientHandler) ListClients(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/clients")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+
+	clients, err := h.M.GetClients()
+	pkg.Must(err, "Could not list clients: %s", err)
+
+	ids := make([]string, 0, len(clients))
+	for id := range clients {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	if jsonOutput(cmd) {
+		list := make([]*fosite.DefaultClient, len(ids))
+		for k, id := range ids {
+			list[k] = clients[id]
+		}
+		printJSON(list)
+		return
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "CLIENT ID\tNAME\tGRANT TYPES\tSCOPES")
+	for _, id := range ids {
+		c := clients[id]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.ID, c.Name, strings.Join(c.GrantTypes, ","), strings.Join(c.GrantedScopes, ","))
+	}
+	w.Flush()
+}
+
+// UpdateClient changes the settings given by flags and keeps all others, including the secret unless --secret is
+// set.
+func (h *ClientHandler) UpdateClient(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/clients")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+	if len(args) != 1 {
+		fmt.Print(cmd.UsageString())
+		return
+	}
+
+	o, err := h.M.GetClient(args[0])
+	pkg.Must(err, "Could not get client: %s", err)
+	client := o.(*fosite.DefaultClient)
+	client.Secret = nil
+
+	flags := cmd.Flags()
+	if flags.Changed("callbacks") {
+		client.RedirectURIs, _ = flags.GetStringSlice("callbacks")
+	}
+	if flags.Changed("grant-types") {
+		client.GrantTypes, _ = flags.GetStringSlice("grant-types")
+	}
+	if flags.Changed("response-types") {
+		client.ResponseTypes, _ = flags.GetStringSlice("response-types")
+	}
+	if flags.Changed("allowed-scopes") {
+		client.GrantedScopes, _ = flags.GetStringSlice("allowed-scopes")
+	}
+	if flags.Changed("name") {
+		client.Name, _ = flags.GetString("name")
+	}
+	if flags.Changed("secret") {
+		secret, _ := flags.GetString("secret")
+		client.Secret = []byte(secret)
+	}
+
+	err = h.M.UpdateClient(client)
+	pkg.Must(err, "Could not update client: %s", err)
+
+	if jsonOutput(cmd) {
+		client.Secret = nil
+		printJSON(client)
+		return
+	}
+	fmt.Printf("Client %s updated.\n", client.ID)
+}
+
+func printClient(c *fosite.DefaultClient) {
+	w := newTable()
+	fmt.Fprintf(w, "Client ID:\t%s\n", c.ID)
+	fmt.Fprintf(w, "Name:\t%s\n", c.Name)
+	fmt.Fprintf(w, "Redirect URIs:\t%s\n", strings.Join(c.RedirectURIs, ", "))
+	fmt.Fprintf(w, "Grant Types:\t%s\n", strings.Join(c.GrantTypes, ", "))
+	fmt.Fprintf(w, "Response Types:\t%s\n", strings.Join(c.ResponseTypes, ", "))
+	fmt.Fprintf(w, "Scopes:\t%s\n", strings.Join(c.GrantedScopes, ", "))
+	w.Flush()
+}
+
 func (h *ClientHandler) DeleteClient(cmd *cobra.Command, args []string) {
 	h.M.Endpoint = h.Config.Resolve("/clients")
 	h.M.Client = h.Config.OAuth2Client(cmd)
@@ -73,3 +187,20 @@ func (h *ClientHandler) DeleteClient(cmd *cobra.Command, args []string) {
 
 	fmt.Println("Client(s) deleted.")
 }
+
+func (h *ClientHandler) RotateSecret(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/clients")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+	if len(args) != 1 {
+		fmt.Print(cmd.UsageString())
+		return
+	}
+
+	overlap, _ := cmd.Flags().GetDuration("overlap")
+	res, err := h.M.RotateSecret(args[0], overlap)
+	pkg.Must(err, "Could not rotate client secret: %s", err)
+
+	fmt.Printf("Client ID: %s\n", res.ClientID)
+	fmt.Printf("Client Secret: %s\n", res.ClientSecret)
+	fmt.Printf("The previous secret is valid until %s\n", res.PreviousSecretExpiresAt)
+}
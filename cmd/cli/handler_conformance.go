@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/internal/conformance"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/spf13/cobra"
+)
+
+type ConformanceHandler struct {
+	Config *config.Config
+}
+
+func newConformanceHandler(c *config.Config) *ConformanceHandler {
+	return &ConformanceHandler{
+		Config: c,
+	}
+}
+
+func (h *ConformanceHandler) Run(cmd *cobra.Command, args []string) {
+	profiles, _ := cmd.Flags().GetStringSlice("profiles")
+	record, _ := cmd.Flags().GetString("record")
+	replay, _ := cmd.Flags().GetString("replay")
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if ok, _ := cmd.Flags().GetBool("skip-tls-verify"); ok {
+		fmt.Println("Warning: Skipping TLS Certificate Verification.")
+		transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	var cassette = new(conformance.Cassette)
+	if replay != "" {
+		var err error
+		cassette, err = conformance.LoadCassette(replay)
+		pkg.Must(err, "Could not load cassette: %s", err)
+		transport = &conformance.Replayer{Cassette: cassette}
+	} else if record != "" {
+		transport = &conformance.Recorder{Transport: transport, Cassette: cassette}
+	}
+
+	runner := &conformance.Runner{
+		Transport:    transport,
+		ClusterURL:   h.Config.Resolve(),
+		ClientID:     h.Config.ClientID,
+		ClientSecret: h.Config.ClientSecret,
+	}
+
+	var failed bool
+	for _, res := range runner.Run(profiles...) {
+		if res.Passed {
+			fmt.Printf("PASS\t%s\t%s\n", res.Profile, res.Name)
+			continue
+		}
+		failed = true
+		fmt.Printf("FAIL\t%s\t%s\n\t%s\n", res.Profile, res.Name, res.Error)
+	}
+
+	if record != "" && replay == "" {
+		err := cassette.Save(record)
+		pkg.Must(err, "Could not save cassette: %s", err)
+		fmt.Printf("Recorded %d interactions to %s.\n", len(cassette.Interactions), record)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/internal/simulation"
+	"github.com/spf13/cobra"
+)
+
+type FlowHandler struct {
+	Config *config.Config
+}
+
+func newFlowHandler(c *config.Config) *FlowHandler {
+	return &FlowHandler{
+		Config: c,
+	}
+}
+
+func (h *FlowHandler) Simulate(cmd *cobra.Command, args []string) {
+	if ok, _ := cmd.Flags().GetBool("dangerous-auto-accept"); !ok {
+		fmt.Println("Simulating a flow accepts consent on behalf of a user with the consent endpoint's private key.")
+		fmt.Println("Pass --dangerous-auto-accept if this is not a production cluster.")
+		os.Exit(1)
+	}
+	logrus.Warnln("Do not use flag --dangerous-auto-accept in production.")
+
+	subject, _ := cmd.Flags().GetString("subject")
+	scopes, _ := cmd.Flags().GetStringSlice("scopes")
+	redirectURL, _ := cmd.Flags().GetString("redirect-url")
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if ok, _ := cmd.Flags().GetBool("skip-tls-verify"); ok {
+		fmt.Println("Warning: Skipping TLS Certificate Verification.")
+		transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	simulator := &simulation.Simulator{
+		Transport:   transport,
+		Admin:       h.Config.OAuth2Client(cmd),
+		ClusterURL:  h.Config.Resolve(),
+		Subject:     subject,
+		Scopes:      scopes,
+		RedirectURL: redirectURL,
+	}
+
+	var failed bool
+	for _, step := range simulator.Run() {
+		if step.Passed {
+			fmt.Printf("PASS\t%s\t%s\n", step.Name, step.Details)
+			continue
+		}
+		failed = true
+		fmt.Printf("FAIL\t%s\n\t%s\n", step.Name, step.Error)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/janitor"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/spf13/cobra"
+)
+
+type JanitorHandler struct {
+	Config *config.Config
+}
+
+func newJanitorHandler(c *config.Config) *JanitorHandler {
+	return &JanitorHandler{
+		Config: c,
+	}
+}
+
+func (h *JanitorHandler) RunJanitor(cmd *cobra.Command, args []string) {
+	endpoint := h.Config.Resolve(janitor.JanitorHandlerPath)
+	if async, _ := cmd.Flags().GetBool("async"); async {
+		endpoint.RawQuery = "async=true"
+	}
+
+	resp, err := h.Config.OAuth2Client(cmd).Post(endpoint.String(), "application/json", nil)
+	pkg.Must(err, "Could not run janitor: %s", err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	pkg.Must(err, "Could not read response: %s", err)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		fmt.Fprintf(os.Stderr, "Could not run janitor: Expected status code %d, got %d.\n%s\n", http.StatusOK, resp.StatusCode, body)
+		os.Exit(1)
+	}
+
+	var out interface{}
+	err = json.Unmarshal(body, &out)
+	pkg.Must(err, "Could not decode response: %s", err)
+	pretty, err := json.MarshalIndent(out, "", "\t")
+	pkg.Must(err, "Could not convert response to JSON: %s", err)
+	fmt.Printf("%s\n", pretty)
+}
@@ -3,11 +3,16 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
+	"github.com/ory-am/hydra/canonical"
 	"github.com/ory-am/hydra/config"
 	"github.com/ory-am/hydra/jwk"
 	"github.com/ory-am/hydra/pkg"
 	"github.com/spf13/cobra"
+	"github.com/square/go-jose"
 )
 
 type JWKHandler struct {
@@ -69,3 +74,158 @@ func (h *JWKHandler) DeleteKeys(cmd *cobra.Command, args []string) {
 	pkg.Must(err, "Could not generate keys: %s", err)
 	fmt.Println("Key set deleted.")
 }
+
+// RotateKeys replaces the keys of a set with new ones. The previous keys can be restored until they are purged if
+// the server keeps deleted keys.
+func (h *JWKHandler) RotateKeys(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/keys")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+	if len(args) == 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	alg, _ := cmd.Flags().GetString("alg")
+	_, err := h.M.GetKeySet(args[0])
+	pkg.Must(err, "Could not fetch keys: %s", err)
+
+	err = h.M.DeleteKeySet(args[0])
+	pkg.Must(err, "Could not delete previous keys: %s", err)
+
+	keys, err := h.M.CreateKeys(args[0], alg)
+	pkg.Must(err, "Could not generate keys: %s", err)
+
+	for _, key := range keys.Keys {
+		fmt.Printf("Created key %s.\n", key.KeyID)
+	}
+	fmt.Printf("Rotated key set %s.\n", args[0])
+}
+
+// ImportKeys adds the PEM encoded keys of files to a set.
+func (h *JWKHandler) ImportKeys(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/keys")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+	if len(args) < 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	id, _ := cmd.Flags().GetString("id")
+	keys := new(jose.JsonWebKeySet)
+	for _, path := range args[1:] {
+		in, err := ioutil.ReadFile(path)
+		pkg.Must(err, "Could not read %s: %s", path, err)
+
+		imported, err := jwk.FromPEM(in, id)
+		pkg.Must(err, "Could not parse %s: %s", path, err)
+		keys.Keys = append(keys.Keys, imported.Keys...)
+	}
+
+	err := h.M.AddKeySet(args[0], keys)
+	pkg.Must(err, "Could not import keys: %s", err)
+	fmt.Printf("Imported %d keys into key set %s.\n", len(keys.Keys), args[0])
+}
+
+// ExportKeys prints a set as JWKS or its asymmetric keys as PEM.
+func (h *JWKHandler) ExportKeys(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/keys")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+	if len(args) == 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	if format != "jwks" && format != "pem" {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	keys, err := h.M.GetKeySet(args[0])
+	pkg.Must(err, "Could not fetch keys: %s", err)
+
+	switch format {
+	case "jwks":
+		out, err := json.MarshalIndent(keys, "", "\t")
+		pkg.Must(err, "Could not marshall keys: %s", err)
+		fmt.Printf("%s\n", out)
+	case "pem":
+		for k := range keys.Keys {
+			out, err := jwk.ToPEM(&keys.Keys[k])
+			pkg.Must(err, "Could not encode keys: %s", err)
+			fmt.Printf("%s", out)
+		}
+	}
+}
+
+func (h *JWKHandler) EscrowKeys(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/keys")
+	h.M.Client = h.Config.OAuth2Client(cmd)
+	if len(args) == 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	shares, _ := cmd.Flags().GetInt("shares")
+	threshold, _ := cmd.Flags().GetInt("threshold")
+	out, _ := cmd.Flags().GetString("out")
+
+	keys, err := h.M.GetKeySet(args[0])
+	pkg.Must(err, "Could not fetch keys: %s", err)
+
+	escrow, parts, err := jwk.NewEscrow(args[0], keys, shares, threshold)
+	pkg.Must(err, "Could not create escrow: %s", err)
+
+	pkg.Must(os.MkdirAll(out, 0700), "Could not create output directory %s", out)
+	writeJSONFile(filepath.Join(out, "escrow.json"), escrow)
+	for _, part := range parts {
+		writeJSONFile(filepath.Join(out, fmt.Sprintf("share-%d.json", part.Index)), part)
+	}
+
+	fmt.Printf("Escrowed key set %s in %s.\n", args[0], filepath.Join(out, "escrow.json"))
+	fmt.Printf("Wrote %d shares, %d of them are required for recovery. Hand every share to a different custodian and remove it from this machine.\n", shares, threshold)
+}
+
+func (h *JWKHandler) RecoverKeys(cmd *cobra.Command, args []string) {
+	h.M.Endpoint = h.Config.Resolve("/keys")
+	if len(args) < 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	var escrow jwk.Escrow
+	readJSONFile(args[0], &escrow)
+
+	parts := make([]jwk.EscrowShare, len(args)-1)
+	for i, path := range args[1:] {
+		readJSONFile(path, &parts[i])
+	}
+
+	keys, err := jwk.RecoverEscrow(&escrow, parts)
+	pkg.Must(err, "Could not recover keys: %s", err)
+
+	if imp, _ := cmd.Flags().GetBool("import"); imp {
+		h.M.Client = h.Config.OAuth2Client(cmd)
+		err := h.M.AddKeySet(escrow.Set, keys)
+		pkg.Must(err, "Could not import keys: %s", err)
+		fmt.Printf("Recovered and imported key set %s.\n", escrow.Set)
+		return
+	}
+
+	out, err := json.MarshalIndent(keys, "", "\t")
+	pkg.Must(err, "Could not marshall keys: %s", err)
+
+	fmt.Printf("%s\n", out)
+}
+
+func writeJSONFile(path string, v interface{}) {
+	out, err := canonical.Marshal(v)
+	pkg.Must(err, "Could not marshall %s: %s", path, err)
+	pkg.Must(ioutil.WriteFile(path, out, 0600), "Could not write %s", path)
+}
+
+func readJSONFile(path string, v interface{}) {
+	in, err := ioutil.ReadFile(path)
+	pkg.Must(err, "Could not read %s: %s", path, err)
+	pkg.Must(json.Unmarshal(in, v), "Could not parse %s", path)
+}
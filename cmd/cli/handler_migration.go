@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/connection"
+	"github.com/ory-am/hydra/internal"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/migration"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/policy"
+	"github.com/ory-am/ladon"
+	"github.com/spf13/cobra"
+	"github.com/square/go-jose"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+type MigrationHandler struct {
+	Config *config.Config
+}
+
+func newMigrationHandler(c *config.Config) *MigrationHandler {
+	return &MigrationHandler{
+		Config: c,
+	}
+}
+
+func (h *MigrationHandler) MigrateSQL(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	db, driver := openSQL(args[0])
+	defer db.Close()
+
+	// The lease table comes first and the token tables last, in the order the cluster needs them when it starts.
+	migrators := []*pkg.Migrator{
+		(&pkg.SQLLeaseManager{DB: db, Driver: driver}).Migrator(),
+		(&jwk.SQLManager{DB: db, Driver: driver}).Migrator(),
+		(&client.SQLManager{DB: db, Driver: driver}).Migrator(),
+		(&policy.SQLManager{DB: db, Driver: driver}).Migrator(),
+		(&connection.SQLManager{DB: db, Driver: driver}).Migrator(),
+		(&internal.FositeSQLStore{DB: db, Driver: driver}).Migrator(),
+	}
+
+	if status, _ := cmd.Flags().GetBool("status"); status {
+		for _, m := range migrators {
+			s, err := m.Status()
+			pkg.Must(err, "Could not get status of %s: %s", m.Module, err)
+			for _, status := range s {
+				applied := "pending"
+				if status.AppliedAt != nil {
+					applied = "applied at " + status.AppliedAt.String()
+				}
+				fmt.Printf("%s\t%s\t%s\n", status.Module, status.ID, applied)
+			}
+		}
+		return
+	}
+
+	if down, _ := cmd.Flags().GetInt("down"); down > 0 {
+		module, _ := cmd.Flags().GetString("module")
+		for _, m := range migrators {
+			if m.Module != module {
+				continue
+			}
+			n, err := m.Down(down)
+			pkg.Must(err, "Could not revert migrations of %s: %s", module, err)
+			fmt.Printf("Reverted %d migrations of %s.\n", n, module)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Flag --module must be one of lease, jwk, client, policy, connection and oauth2, got \"%s\".\n", module)
+		os.Exit(1)
+	}
+
+	for _, m := range migrators {
+		n, err := m.Up()
+		pkg.Must(err, "Could not apply migrations of %s: %s", m.Module, err)
+		fmt.Printf("Applied %d migrations of %s.\n", n, m.Module)
+	}
+}
+
+func (h *MigrationHandler) MigrateData(cmd *cobra.Command, args []string) {
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	if from == "" || to == "" {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	m := &migration.DataMigrator{
+		From: h.openStore(from),
+		To:   h.openStore(to),
+		Progress: func(kind, id, action string) {
+			fmt.Printf("%s\t%s\t%s\n", kind, id, action)
+		},
+	}
+	m.Overwrite, _ = cmd.Flags().GetBool("overwrite")
+
+	results, err := m.Run()
+	out, _ := json.MarshalIndent(results, "", "\t")
+	fmt.Printf("%s\n", out)
+	pkg.Must(err, "Could not migrate data: %s", err)
+
+	for _, result := range results {
+		if len(result.Conflicts) > 0 {
+			fmt.Fprintln(os.Stderr, "Some items differ in the target and were not copied, run again with --overwrite to replace them.")
+			os.Exit(1)
+		}
+	}
+}
+
+// openStore connects to the store of a database url and creates its tables if they do not exist.
+func (h *MigrationHandler) openStore(raw string) *migration.Store {
+	u, err := url.Parse(raw)
+	pkg.Must(err, "Could not parse database url: %s", err)
+	cipher := &jwk.AEAD{Key: h.Config.GetSystemSecret()}
+
+	if u.Scheme == "rethinkdb" {
+		con := &config.RethinkDBConnection{URL: u}
+		for _, table := range []string{"hydra_json_web_keys", "hydra_clients", "hydra_policies"} {
+			con.CreateTableIfNotExists(table)
+		}
+
+		keys := &jwk.RethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_json_web_keys"),
+			Keys:    map[string]jose.JsonWebKeySet{},
+			Cipher:  cipher,
+		}
+		clients := &client.RethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_clients"),
+			Clients: map[string]*fosite.DefaultClient{},
+		}
+		policies := &policy.RethinkManager{
+			Session:  con.GetSession(),
+			Table:    r.Table("hydra_policies"),
+			Policies: map[string]ladon.Policy{},
+		}
+
+		// The caches are followed so that copied items can be verified.
+		for _, s := range []interface {
+			ColdStart() error
+			Watch(context.Context)
+		}{keys, clients, policies} {
+			err := s.ColdStart()
+			pkg.Must(err, "Could not fetch initial state: %s", err)
+			s.Watch(context.Background())
+		}
+		return &migration.Store{Keys: keys, Clients: clients, Policies: policies}
+	}
+
+	db, driver := openSQL(raw)
+	keys := &jwk.SQLManager{DB: db, Driver: driver, Cipher: cipher}
+	clients := &client.SQLManager{DB: db, Driver: driver}
+	policies := &policy.SQLManager{DB: db, Driver: driver}
+	for _, s := range []interface {
+		CreateSchemas() error
+	}{keys, clients, policies} {
+		err := s.CreateSchemas()
+		pkg.Must(err, "Could not create schemas: %s", err)
+	}
+	return &migration.Store{Keys: keys, Clients: clients, Policies: policies}
+}
+
+// openSQL connects to the database of a postgres or mysql url.
+func openSQL(raw string) (*sql.DB, string) {
+	u, err := url.Parse(raw)
+	pkg.Must(err, "Could not parse database url: %s", err)
+	driver, dsn, err := config.SQLDataSource(u)
+	pkg.Must(err, "Could not parse database url: %s", err)
+	db, err := sql.Open(driver, dsn)
+	pkg.Must(err, "Could not open database: %s", err)
+	err = db.Ping()
+	pkg.Must(err, "Could not connect to database: %s", err)
+	return db, driver
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/spf13/cobra"
+)
+
+type TokenHandler struct {
+	Config *config.Config
+}
+
+func newTokenHandler(c *config.Config) *TokenHandler {
+	return &TokenHandler{
+		Config: c,
+	}
+}
+
+// IntrospectToken introspects a token with the CLI's credentials and prints its claims. The claims of JSON Web
+// Tokens are decoded as well, without verifying the signature.
+func (h *TokenHandler) IntrospectToken(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Print(cmd.UsageString())
+		return
+	}
+
+	form := url.Values{"token": {args[0]}}
+	if hint, _ := cmd.Flags().GetString("token-type-hint"); hint != "" {
+		form.Set("token_type_hint", hint)
+	}
+
+	resp, err := h.Config.OAuth2Client(cmd).PostForm(h.Config.Resolve(oauth2.IntrospectionHandlerPath).String(), form)
+	pkg.Must(err, "Could not introspect token: %s", err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	pkg.Must(err, "Could not read response: %s", err)
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Could not introspect token: Expected status code %d, got %d.\n%s\n", http.StatusOK, resp.StatusCode, body)
+		os.Exit(1)
+	}
+
+	var claims map[string]interface{}
+	err = json.Unmarshal(body, &claims)
+	pkg.Must(err, "Could not decode response: %s", err)
+
+	jwtClaims := decodeJWTClaims(args[0])
+	if jsonOutput(cmd) {
+		out := map[string]interface{}{"introspection": claims}
+		if jwtClaims != nil {
+			out["jwt"] = jwtClaims
+		}
+		printJSON(out)
+		return
+	}
+
+	printClaims("Introspection", claims)
+	if jwtClaims != nil {
+		fmt.Println()
+		printClaims("JSON Web Token (signature not verified)", jwtClaims)
+	}
+}
+
+// RevokeToken revokes a token. Tokens can only be revoked by the client they were issued to, so the request is
+// authenticated with the client of --client-id or the CLI's credentials.
+func (h *TokenHandler) RevokeToken(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Print(cmd.UsageString())
+		return
+	}
+
+	id, secret := h.Config.ClientID, h.Config.ClientSecret
+	if flag, _ := cmd.Flags().GetString("client-id"); flag != "" {
+		id = flag
+		secret, _ = cmd.Flags().GetString("client-secret")
+	}
+
+	form := url.Values{"token": {args[0]}}
+	if hint, _ := cmd.Flags().GetString("token-type-hint"); hint != "" {
+		form.Set("token_type_hint", hint)
+	}
+
+	req, err := http.NewRequest("POST", h.Config.Resolve(oauth2.RevocationHandlerPath).String(), strings.NewReader(form.Encode()))
+	pkg.Must(err, "Could not create request: %s", err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(id, secret)
+
+	resp, err := h.Config.HTTPClient(cmd).Do(req)
+	pkg.Must(err, "Could not revoke token: %s", err)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "Could not revoke token: Expected status code %d, got %d.\n%s\n", http.StatusOK, resp.StatusCode, body)
+		os.Exit(1)
+	}
+	fmt.Println("Token revoked.")
+}
+
+// decodeJWTClaims returns the claims of a JSON Web Token or nil if token is not one.
+func decodeJWTClaims(token string) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	payload, err := base64.URLEncoding.DecodeString(padBase64(parts[1]))
+	if err != nil {
+		return nil
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil
+	}
+	return claims
+}
+
+func padBase64(s string) string {
+	if m := len(s) % 4; m != 0 {
+		s += strings.Repeat("=", 4-m)
+	}
+	return s
+}
+
+// printClaims prints claims as a table sorted by name. Timestamps are shown as dates.
+func printClaims(title string, claims map[string]interface{}) {
+	names := make([]string, 0, len(claims))
+	for name := range claims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("%s:\n", title)
+	w := newTable()
+	for _, name := range names {
+		value := claims[name]
+		switch name {
+		case "exp", "iat", "nbf", "auth_time":
+			if f, ok := value.(float64); ok {
+				value = fmt.Sprintf("%s (%.0f)", time.Unix(int64(f), 0).UTC().Format(time.RFC3339), f)
+			}
+		default:
+			if _, ok := value.(string); !ok {
+				out, _ := json.Marshal(value)
+				value = string(out)
+			}
+		}
+		fmt.Fprintf(w, "  %s\t%v\n", name, value)
+	}
+	w.Flush()
+}
@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeJWTClaims(t *testing.T) {
+	payload := strings.TrimRight(base64.URLEncoding.EncodeToString([]byte(`{"sub":"peter","exp":1476489600}`)), "=")
+	claims := decodeJWTClaims("eyJhbGciOiJSUzI1NiJ9." + payload + ".signature")
+	assert.Equal(t, "peter", claims["sub"])
+	assert.Equal(t, float64(1476489600), claims["exp"])
+
+	assert.Nil(t, decodeJWTClaims("opaque-token.signature"))
+	assert.Nil(t, decodeJWTClaims("a.not-base64!.c"))
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ory-am/hydra/pkg"
+	"github.com/spf13/cobra"
+)
+
+// jsonOutput tells whether the command was asked to print JSON with --format json, for example for scripting.
+func jsonOutput(cmd *cobra.Command) bool {
+	format, _ := cmd.Flags().GetString("format")
+	return format == "json"
+}
+
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "\t")
+	pkg.Must(err, "Could not marshal output: %s", err)
+	fmt.Printf("%s\n", out)
+}
+
+// newTable returns a writer which aligns tab separated columns. It must be flushed.
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/ladon"
+	"gopkg.in/yaml.v2"
+)
+
+// policyFile is a policy read from a file, Source is the path it was read from.
+type policyFile struct {
+	Source string
+	Policy *ladon.DefaultPolicy
+}
+
+// readPolicyFiles reads the policies of files and of the JSON and YAML files in directories. The path - reads from
+// stdin. A file holds either a single policy or a list of policies.
+func readPolicyFiles(paths []string) ([]policyFile, error) {
+	var result []policyFile
+	for _, path := range paths {
+		if path == "-" {
+			in, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return nil, errors.New(err)
+			}
+
+			trimmed := bytes.TrimSpace(in)
+			isJSON := len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+			policies, err := parsePolicies(in, !isJSON)
+			if err != nil {
+				return nil, errors.Errorf("Could not parse stdin: %s", err)
+			}
+			result = appendPolicyFiles(result, "stdin", policies)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+
+		files := []string{path}
+		if info.IsDir() {
+			if files, err = policyFilesInDir(path); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, file := range files {
+			in, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, errors.New(err)
+			}
+
+			ext := strings.ToLower(filepath.Ext(file))
+			policies, err := parsePolicies(in, ext == ".yml" || ext == ".yaml")
+			if err != nil {
+				return nil, errors.Errorf("Could not parse %s: %s", file, err)
+			}
+			result = appendPolicyFiles(result, file, policies)
+		}
+	}
+	return result, nil
+}
+
+func appendPolicyFiles(result []policyFile, source string, policies []*ladon.DefaultPolicy) []policyFile {
+	for _, p := range policies {
+		result = append(result, policyFile{Source: source, Policy: p})
+	}
+	return result
+}
+
+// policyFilesInDir returns the JSON and YAML files of a directory in lexical order.
+func policyFilesInDir(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".json", ".yml", ".yaml":
+			if !e.IsDir() {
+				files = append(files, filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// parsePolicies decodes a policy or a list of policies. YAML is converted to JSON first, so that both formats are
+// decoded by the JSON decoders of ladon, including the conditions.
+func parsePolicies(in []byte, isYAML bool) ([]*ladon.DefaultPolicy, error) {
+	if isYAML {
+		var v interface{}
+		if err := yaml.Unmarshal(in, &v); err != nil {
+			return nil, errors.New(err)
+		}
+
+		converted, err := json.Marshal(yamlToJSON(v))
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		in = converted
+	}
+
+	in = bytes.TrimSpace(in)
+	if len(in) > 0 && in[0] == '[' {
+		var policies []*ladon.DefaultPolicy
+		if err := json.Unmarshal(in, &policies); err != nil {
+			return nil, errors.New(err)
+		}
+		return policies, nil
+	}
+
+	var policy ladon.DefaultPolicy
+	if err := json.Unmarshal(in, &policy); err != nil {
+		return nil, errors.New(err)
+	}
+	return []*ladon.DefaultPolicy{&policy}, nil
+}
+
+// yamlToJSON converts the map[interface{}]interface{} values of the YAML decoder, which can not be encoded as JSON.
+func yamlToJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range t {
+			m[fmt.Sprintf("%v", k)] = yamlToJSON(val)
+		}
+		return m
+	case []interface{}:
+		for k, val := range t {
+			t[k] = yamlToJSON(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// diffPolicies describes the changes from current to desired, one line per changed field. current is nil if the
+// policy does not exist yet.
+func diffPolicies(current, desired *ladon.DefaultPolicy) []string {
+	if current == nil {
+		return []string{fmt.Sprintf("+ policy %s", desired.ID)}
+	}
+
+	var lines []string
+	diff := func(field string, from, to interface{}) {
+		if reflect.DeepEqual(from, to) {
+			return
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", field, diffValue(from)), fmt.Sprintf("+ %s: %s", field, diffValue(to)))
+	}
+
+	diff("description", current.Description, desired.Description)
+	diff("effect", current.Effect, desired.Effect)
+	diff("subjects", nonNil(current.Subjects), nonNil(desired.Subjects))
+	diff("resources", nonNil(current.Resources), nonNil(desired.Resources))
+	diff("actions", nonNil(current.Actions), nonNil(desired.Actions))
+
+	from, _ := json.Marshal(current.Conditions)
+	to, _ := json.Marshal(desired.Conditions)
+	if !bytes.Equal(normalizeConditions(from), normalizeConditions(to)) {
+		lines = append(lines, fmt.Sprintf("- conditions: %s", from), fmt.Sprintf("+ conditions: %s", to))
+	}
+	return lines
+}
+
+func diffValue(v interface{}) string {
+	if s, ok := v.([]string); ok {
+		return "[" + strings.Join(s, ", ") + "]"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// normalizeConditions treats missing and empty conditions alike.
+func normalizeConditions(in []byte) []byte {
+	if string(in) == "null" {
+		return []byte("{}")
+	}
+	return in
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPolicyFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policies")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"id": "a", "subjects": ["peter"], "effect": "allow"}`), 0600))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "b.yml"), []byte(`
+- id: b
+  subjects: [max]
+  resources: [blog]
+  actions: [post]
+  effect: deny
+  conditions:
+    owner:
+      type: EqualsSubjectCondition
+      options: {}
+- id: c
+  effect: allow
+`), 0600))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a policy"), 0600))
+
+	files, err := readPolicyFiles([]string{dir})
+	require.Nil(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, "a", files[0].Policy.ID)
+	assert.Equal(t, []string{"peter"}, files[0].Policy.Subjects)
+	assert.Equal(t, "b", files[1].Policy.ID)
+	assert.Equal(t, ladon.DenyAccess, files[1].Policy.Effect)
+	assert.NotNil(t, files[1].Policy.Conditions["owner"])
+	assert.Equal(t, "c", files[2].Policy.ID)
+	assert.Equal(t, filepath.Join(dir, "b.yml"), files[2].Source)
+}
+
+func TestDiffPolicies(t *testing.T) {
+	current := &ladon.DefaultPolicy{ID: "a", Subjects: []string{"peter"}, Effect: ladon.AllowAccess}
+	desired := &ladon.DefaultPolicy{ID: "a", Subjects: []string{"peter"}, Effect: ladon.AllowAccess, Conditions: ladon.Conditions{}}
+
+	assert.Empty(t, diffPolicies(current, desired))
+	assert.Equal(t, []string{"+ policy a"}, diffPolicies(nil, desired))
+
+	desired.Subjects = []string{"peter", "max"}
+	assert.Equal(t, []string{"- subjects: [peter]", "+ subjects: [peter, max]"}, diffPolicies(current, desired))
+}
@@ -27,6 +27,7 @@ var clientsCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(clientsCmd)
+	clientsCmd.PersistentFlags().String("format", "text", "The output format, text or json")
 
 	// Here you will define your flags and configuration settings.
 
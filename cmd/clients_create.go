@@ -24,4 +24,5 @@ func init() {
 	clientsCreateCmd.Flags().StringSliceP("response-types", "r", []string{"code"}, "A list of allowed response types")
 	clientsCreateCmd.Flags().StringSliceP("allowed-scopes", "a", []string{"core"}, "A list of allowed scopes")
 	clientsCreateCmd.Flags().StringP("name", "n", "", "The client's name")
+	clientsCreateCmd.Flags().String("secret", "", "Give the client this secret, a random one is generated if empty")
 }
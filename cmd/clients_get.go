@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// clientsGetCmd represents the get command
+var clientsGetCmd = &cobra.Command{
+	Use:   "get <id>",
+	Short: "Show an OAuth2 client",
+	Run:   cmdHandler.Clients.GetClient,
+}
+
+func init() {
+	clientsCmd.AddCommand(clientsGetCmd)
+}
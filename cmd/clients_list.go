@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// clientsListCmd represents the list command
+var clientsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all OAuth2 clients",
+	Run:   cmdHandler.Clients.ListClients,
+}
+
+func init() {
+	clientsCmd.AddCommand(clientsListCmd)
+}
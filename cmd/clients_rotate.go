@@ -0,0 +1,34 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// clientsRotateCmd represents the rotate command
+var clientsRotateCmd = &cobra.Command{
+	Use:   "rotate <id>",
+	Short: "Rotate the secret of an OAuth2 client",
+	Long: `Gives the client a new secret and prints it. The new secret is not shown again, store it right away.
+
+The previous secret stays valid for the overlap window so that the client can be updated without downtime.`,
+	Run: cmdHandler.Clients.RotateSecret,
+}
+
+func init() {
+	clientsCmd.AddCommand(clientsRotateCmd)
+	clientsRotateCmd.Flags().Duration("overlap", 0, "How long the previous secret stays valid, defaults to the server's setting")
+}
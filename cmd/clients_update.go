@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// clientsUpdateCmd represents the update command
+var clientsUpdateCmd = &cobra.Command{
+	Use:   "update <id>",
+	Short: "Update an OAuth2 client",
+	Long: `This command changes the settings given by flags and keeps all others. The secret is only changed if --secret is set.
+
+Example:
+  hydra clients update d9227bd5-5d47-4557-957d-2fd3bee11035 -c=[http://localhost/cb,http://localhost/cb2] -a [core,foobar]
+`,
+	Run: cmdHandler.Clients.UpdateClient,
+}
+
+func init() {
+	clientsCmd.AddCommand(clientsUpdateCmd)
+	clientsUpdateCmd.Flags().StringSliceP("callbacks", "c", []string{}, "A list of allowed callback URLs")
+	clientsUpdateCmd.Flags().StringSliceP("grant-types", "g", []string{}, "A list of allowed grant types")
+	clientsUpdateCmd.Flags().StringSliceP("response-types", "r", []string{}, "A list of allowed response types")
+	clientsUpdateCmd.Flags().StringSliceP("allowed-scopes", "a", []string{}, "A list of allowed scopes")
+	clientsUpdateCmd.Flags().StringP("name", "n", "", "The client's name")
+	clientsUpdateCmd.Flags().String("secret", "", "Give the client this secret")
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// conformanceCmd represents the conformance command
+var conformanceCmd = &cobra.Command{
+	Use:   "conformance",
+	Short: "Verify a cluster against the OpenID Connect certification profiles",
+}
+
+func init() {
+	RootCmd.AddCommand(conformanceCmd)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// conformanceRunCmd represents the run command
+var conformanceRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the conformance checks against the cluster",
+	Long: `This command runs the checks of the OpenID Certification basic and config profiles which do not require a
+user agent. Runs can be recorded to a cassette file and replayed later without a running cluster.
+
+Example:
+  hydra conformance run --profiles basic,config --record conformance.json
+  hydra conformance run --replay conformance.json
+`,
+	Run: cmdHandler.Conformance.Run,
+}
+
+func init() {
+	conformanceCmd.AddCommand(conformanceRunCmd)
+	conformanceRunCmd.Flags().StringSlice("profiles", []string{"basic", "config"}, "The certification profiles to run")
+	conformanceRunCmd.Flags().String("record", "", "Record all interactions to this file")
+	conformanceRunCmd.Flags().String("replay", "", "Replay the interactions stored in this file instead of talking to the cluster")
+}
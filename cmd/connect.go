@@ -7,23 +7,45 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ory-am/hydra/config"
 	"github.com/spf13/cobra"
 )
 
 // connectCmd represents the connect command
 var connectCmd = &cobra.Command{
-	Use:   "connect",
+	Use:   "connect [<context>]",
 	Short: "Connect with a cluster",
+	Long: `Asks for the cluster url, the client credentials and the certificate authorities to trust and stores them
+in the config file, which is only readable by you.
+
+If a context name is given, the settings are stored as that context and it becomes the current one. Contexts let
+you switch between clusters, see "hydra connect list" and "hydra connect use".
+
+Example:
+  hydra connect staging
+`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if u := input("Cluster URL [" + c.ClusterURL + "]: "); u != "" {
 			c.ClusterURL = u
 		}
-		if u := input("Client ID: "); u != "" {
+		if u := input("Client ID [" + c.ClientID + "]: "); u != "" {
 			c.ClientID = u
 		}
 		if u := input("Client Secret: "); u != "" {
 			c.ClientSecret = u
 		}
+		if u := input("CA bundle, leave empty to use the system's [" + c.CABundle + "]: "); u != "" {
+			if _, err := config.LoadCABundle(u); err != nil {
+				fatal("Could not load CA bundle: %s", err)
+			}
+			c.CABundle = u
+		}
+
+		if len(args) > 0 {
+			c.SaveContext(args[0])
+		} else if c.CurrentContext != "" {
+			c.SaveContext(c.CurrentContext)
+		}
 
 		if err := c.Persist(); err != nil {
 			log.Fatalf("Unable to save config file because %s.", err)
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// connectListCmd represents the list command
+var connectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the contexts of the config file, the current one is marked with *",
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, name := range c.GetContextNames() {
+			marker := " "
+			if name == c.CurrentContext {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\t%s\n", marker, name, c.Contexts[name].ClusterURL)
+		}
+	},
+}
+
+func init() {
+	connectCmd.AddCommand(connectListCmd)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// connectUseCmd represents the use command
+var connectUseCmd = &cobra.Command{
+	Use:   "use <context>",
+	Short: "Make a context the current one",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Print(cmd.UsageString())
+			return
+		}
+
+		if err := c.UseContext(args[0]); err != nil {
+			fatal("%s", err)
+		}
+		if err := c.Persist(); err != nil {
+			fatal("Unable to save config file because %s.", err)
+		}
+		fmt.Printf("Switched to context %s.\n", args[0])
+	},
+}
+
+func init() {
+	connectCmd.AddCommand(connectUseCmd)
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// flowsCmd represents the flows command
+var flowsCmd = &cobra.Command{
+	Use:   "flows",
+	Short: "Exercise OAuth2 flows against the cluster",
+}
+
+func init() {
+	RootCmd.AddCommand(flowsCmd)
+}
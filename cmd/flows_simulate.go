@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/ory-am/hydra/internal/simulation"
+	"github.com/spf13/cobra"
+)
+
+// flowsSimulateCmd represents the simulate command
+var flowsSimulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Walk the authorization code flow end to end and report the outcome of each step",
+	Long: `This command registers a temporary client, walks the authorization code flow with it and removes the client
+again. It stands in for the consent app and accepts the consent challenge on behalf of the given subject, which
+requires access to the private key of the consent endpoint. Do not use this command against a production cluster.
+
+Example:
+  hydra flows simulate --dangerous-auto-accept --subject peter --scopes core,offline
+`,
+	Run: cmdHandler.Flows.Simulate,
+}
+
+func init() {
+	flowsCmd.AddCommand(flowsSimulateCmd)
+	flowsSimulateCmd.Flags().Bool("dangerous-auto-accept", false, "Accept consent challenges with the consent endpoint's private key. Do not use in production.")
+	flowsSimulateCmd.Flags().String("subject", "simulated-user", "The subject consent is given for")
+	flowsSimulateCmd.Flags().StringSlice("scopes", []string{"core", "offline"}, "The scopes to request")
+	flowsSimulateCmd.Flags().String("redirect-url", simulation.DefaultRedirectURL, "The redirect URL of the temporary client, it is never requested")
+}
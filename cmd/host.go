@@ -1,28 +1,64 @@
 package cmd
 
 import (
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"crypto/tls"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/go-errors/errors"
 	"github.com/julienschmidt/httprouter"
+	"github.com/opentracing/opentracing-go"
+	"github.com/ory-am/hydra/accesslog"
+	"github.com/ory-am/hydra/client"
 	"github.com/ory-am/hydra/cmd/server"
+	"github.com/ory-am/hydra/compression"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/cors"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/history"
+	"github.com/ory-am/hydra/idempotency"
 	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/oauth2"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/ratelimit"
+	"github.com/ory-am/hydra/rpc"
+	"github.com/ory-am/hydra/tracing"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	r "gopkg.in/dancannon/gorethink.v2"
 )
 
-const (
-	TLSKeyName = "hydra.tls"
-)
+// ACMEKeySet holds the ACME account key and the certificates obtained via ACME.
+const ACMEKeySet = "hydra.acme"
 
 // hostCmd represents the host command
 var hostCmd = &cobra.Command{
 	Use:   "host",
 	Short: "Start the hydra host service",
-	Run:   runHostCmd,
+	Long: `Starts the hydra host service.
+
+Settings are read from the config file, which is either YAML or JSON, see --config. Environment variables like
+DATABASE_URL override the settings of the config file, whose keys are the lower case names of the variables, for
+example database_url.
+
+Sending SIGHUP to the process reloads the config file and the environment. The log level and format, the allowed
+CORS origins and the lifespans of remembered consent, login sessions and device codes change without a restart,
+all other settings require one.
+`,
+	Run: runHostCmd,
 }
 
 func init() {
@@ -40,6 +76,15 @@ func init() {
 }
 
 func runHostCmd(cmd *cobra.Command, args []string) {
+	// The tracer is set up first, so that the spans of storage calls made during start up are reported as well.
+	tracer, closer, err := tracing.NewTracer(c.TracingProvider, c.TracingServiceName, c.TracingAgentAddress)
+	pkg.Must(err, "Could not set up tracing: %s", err)
+	defer closer.Close()
+	opentracing.SetGlobalTracer(tracer)
+	if c.TracingProvider != "" {
+		logrus.Infof("Reporting traces to %s at %s", c.TracingProvider, c.TracingAgentAddress)
+	}
+
 	router := httprouter.New()
 	serverHandler := &server.Handler{}
 	serverHandler.Start(c, router)
@@ -50,47 +95,289 @@ func runHostCmd(cmd *cobra.Command, args []string) {
 		pkg.Must(err, "Could not write configuration file: %s", err)
 	}
 
-	http.Handle("/", router)
+	// A single CORS middleware serves both listeners, so that reloading the allowed origins applies to both.
+	var corsMiddleware *cors.Middleware
+	if c.CORSAllowedOrigins != "" {
+		corsMiddleware = newCORSMiddleware(serverHandler)
+	}
+	go reloadOnSignal(serverHandler, corsMiddleware)
+
+	if c.AdminAddress == "" {
+		http.Handle("/", newHandler(router, serverHandler, corsMiddleware, nil))
+	} else {
+		admin := &http.Server{
+			Addr:      c.AdminAddress,
+			Handler:   newHandler(router, serverHandler, corsMiddleware, &server.Listener{Public: false}),
+			TLSConfig: &tls.Config{GetCertificate: newTLSCertificate(c.GetAdminTLSKeySet()).GetCertificate},
+		}
+		go func() {
+			logrus.Infof("Serving the admin API on %s", c.AdminAddress)
+			err := admin.ListenAndServeTLS("", "")
+			pkg.Must(err, "Could not serve the admin API: %s", err)
+		}()
+		http.Handle("/", newHandler(router, serverHandler, corsMiddleware, &server.Listener{Public: true}))
+	}
+
+	if c.MetricsAddress != "" {
+		metrics := http.NewServeMux()
+		metrics.Handle(server.MetricsHandlerPath, prometheus.Handler())
+		go func() {
+			logrus.Infof("Serving metrics on %s", c.MetricsAddress)
+			err := http.ListenAndServe(c.MetricsAddress, metrics)
+			pkg.Must(err, "Could not serve metrics: %s", err)
+		}()
+	}
+
+	if c.GRPCAddress != "" {
+		listener, err := net.Listen("tcp", c.GRPCAddress)
+		pkg.Must(err, "Could not listen on %s: %s", c.GRPCAddress, err)
+		g := newGRPCServer(serverHandler)
+		go func() {
+			logrus.Infof("Serving gRPC on %s", c.GRPCAddress)
+			err := g.Serve(listener)
+			pkg.Must(err, "Could not serve gRPC: %s", err)
+		}()
+	}
 
 	srv := &http.Server{
-		Addr: c.GetAddress(),
-		TLSConfig: &tls.Config{
-			Certificates: []tls.Certificate{
-				getOrCreateTLSCertificate(),
-			},
-		},
+		Addr:      c.GetAddress(),
+		TLSConfig: newTLSConfig(),
 	}
 
 	logrus.Infof("Starting server on %s", c.GetAddress())
-	err := srv.ListenAndServeTLS("", "")
+	err = srv.ListenAndServeTLS("", "")
 	pkg.Must(err, "Could not start server: %s %s.", err)
 }
 
-func getOrCreateTLSCertificate() tls.Certificate {
+// newHandler wraps the router in the middlewares of a listener. The rate limits only apply to the public listener,
+// the admin listener is expected to be firewalled off. If listener is nil, all endpoints are served. CORS requests are
+// not answered if corsMiddleware is nil.
+func newHandler(router *httprouter.Router, serverHandler *server.Handler, corsMiddleware *cors.Middleware, listener *server.Listener) http.Handler {
+	// Spans and request ids are looked up by request, these middlewares must receive the request the router does.
+	var handler http.Handler = router
+	if listener != nil {
+		handler = listener.Wrap(handler)
+	}
+	if perClient, perAddress := c.GetRateLimitPerClient(), c.GetRateLimitPerAddress(); (listener == nil || listener.Public) && (perClient.Enabled() || perAddress.Enabled()) {
+		handler = (&ratelimit.Middleware{
+			Store:      newRateLimitStore(),
+			PerClient:  perClient,
+			PerAddress: perAddress,
+			Clients:    serverHandler.AuthenticatingClients,
+			Paths:      []string{"/oauth2/token", oauth2.IntrospectionHandlerPath},
+			H:          &herodot.JSON{},
+		}).Wrap(handler)
+	}
+	if listener == nil || !listener.Public {
+		handler = (&idempotency.Middleware{
+			Store:    newIdempotencyStore(),
+			Lifespan: c.GetIdempotencyKeyLifespan(),
+			Skip:     server.IsPublicPath,
+			Cipher:   &jwk.AEAD{Key: c.GetSystemSecret()},
+			H:        &herodot.JSON{},
+		}).Wrap(handler)
+	}
+	handler = (&tracing.Middleware{}).Wrap(handler)
+	handler = (&logger.Middleware{}).Wrap(handler)
+	if corsMiddleware != nil {
+		handler = corsMiddleware.Wrap(handler)
+	}
+	if !c.DisableCompression {
+		handler = (&compression.Middleware{MinSize: c.CompressionMinSize}).Wrap(handler)
+	}
+
+	// The access log records the size of the compressed response.
+	if c.AccessLog != "" {
+		accessLog, err := accesslog.Parse(c.AccessLog)
+		pkg.Must(err, "Could not parse ACCESS_LOG: %s", err)
+		accessLog.Pseudonymizer = &events.HMACPseudonymizer{
+			Secret:   c.GetPseudonymSecret(),
+			Rotation: c.GetPseudonymRotation(),
+		}
+		handler = accessLog.Wrap(handler)
+	}
+	return handler
+}
+
+// newCORSMiddleware allows the origins of CORS_ALLOWED_ORIGINS. The public endpoints additionally allow the origins
+// clients registered in their settings.
+func newCORSMiddleware(h *server.Handler) *cors.Middleware {
+	origins := c.GetCORSAllowedOrigins()
+	return &cors.Middleware{
+		Groups: []cors.Group{
+			{
+				Name:               cors.GroupPublic,
+				Prefixes:           server.PublicPaths,
+				AllowedOrigins:     origins[cors.GroupPublic],
+				AllowClientOrigins: true,
+			},
+			{
+				Name:           cors.GroupAdmin,
+				Prefixes:       append([]string{"/"}, server.AdminPaths...),
+				AllowedOrigins: origins[cors.GroupAdmin],
+			},
+		},
+		Clients: &client.CORSOrigins{Settings: h.Clients.Settings},
+	}
+}
+
+// reloadOnSignal reloads the config file and the environment on SIGHUP and applies the settings which can change
+// at runtime, see config.ReloadableSettings. Allowing CORS requests at all still requires a restart.
+func reloadOnSignal(h *server.Handler, corsMiddleware *cors.Middleware) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		next := new(config.Config)
+		mutex.Lock()
+		err := loadConfig(next)
+		mutex.Unlock()
+		if err != nil {
+			logrus.Errorf("Could not reload configuration: %s", err)
+			continue
+		}
+
+		changed, err := c.Reload(next)
+		if err != nil {
+			logrus.Errorf("Could not reload configuration: %s", err)
+			continue
+		} else if len(changed) == 0 {
+			logrus.Info("Reloaded configuration, nothing changed")
+			continue
+		}
+
+		if err := logger.Configure(c.LogLevel, c.LogFormat); err != nil {
+			logger.LogError(err)
+		}
+		if corsMiddleware != nil {
+			corsMiddleware.SetAllowedOrigins(c.GetCORSAllowedOrigins())
+		}
+		if h.OAuth2 != nil {
+			h.OAuth2.SetLifespans(c.GetRememberConsentMaxLifespan(), c.GetLoginSessionLifespan())
+			if h.OAuth2.Device != nil {
+				h.OAuth2.Device.SetLifespan(c.GetDeviceCodeLifespan())
+			}
+		}
+		logrus.Infof("Reloaded configuration, changed %s", strings.Join(changed, ", "))
+	}
+}
+
+// newIdempotencyStore keeps the responses to requests with idempotency keys in the database, so that retries which
+// reach another instance are answered as well. Expired responses are purged by the leader.
+func newIdempotencyStore() idempotency.Store {
 	ctx := c.Context()
-	keys, err := ctx.KeyManager.GetKey(TLSKeyName, "private")
-	if errors.Is(err, pkg.ErrNotFound) {
-		logrus.Warn("Key for TLS not found. Creating new one.")
 
-		generator := jwk.ECDSA256Generator{}
-		set, err := generator.Generate("")
-		pkg.Must(err, "Could not generate key: %s", err)
+	var store idempotency.Store
+	switch con := ctx.Connection.(type) {
+	case *config.MemoryConnection:
+		store = idempotency.NewMemoryStore()
+	case *config.SQLConnection:
+		m := &idempotency.SQLStore{
+			DB:     con.GetDatabase(),
+			Driver: con.GetDriver(),
+		}
+		if err := m.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create schemas: %s", err)
+		}
+		store = m
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_idempotency_keys")
+		store = &idempotency.RethinkStore{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_idempotency_keys"),
+		}
+	case *config.DynamoDBConnection:
+		m := &idempotency.DynamoDBStore{
+			DB:    con.GetDB(),
+			Table: con.GetTable(),
+		}
+		if err := m.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create schemas: %s", err)
+		}
+		store = m
+	default:
+		panic("Unknown connection type.")
+	}
 
-		err = ctx.KeyManager.AddKeySet(TLSKeyName, set)
-		pkg.Must(err, "Could not persist key: %s", err)
+	idempotency.PurgeExpiredResponses(context.Background(), store, ctx.Leader, time.Hour)
+	return store
+}
 
-		keys, err = ctx.KeyManager.GetKey(TLSKeyName, "private")
-		pkg.Must(err, "Could not retrieve persisted key: %s", err)
-		logrus.Warn("Temporary key created.")
-	} else {
-		pkg.Must(err, "Could not retrieve key: %s", err)
+// newRateLimitStore shares the rate limits in Redis if RATE_LIMIT_REDIS_URL is set.
+func newRateLimitStore() ratelimit.Store {
+	if c.RateLimitRedisURL == "" {
+		return ratelimit.NewMemoryStore()
+	}
+
+	logrus.Info("Sharing rate limits in Redis.")
+	return ratelimit.NewRedisStore(c.RateLimitRedisURL)
+}
+
+// newGRPCServer serves the warden and token introspection with the certificate of TLS_KEY_SET. Clients need to
+// present a certificate signed by GRPC_CLIENT_CA if it is set.
+func newGRPCServer(h *server.Handler) *grpc.Server {
+	tlsConfig := &tls.Config{GetCertificate: newTLSCertificate(c.GetTLSKeySet()).GetCertificate}
+	if c.GRPCClientCA != "" {
+		pool, err := config.LoadCABundle(c.GRPCClientCA)
+		pkg.Must(err, "Could not load GRPC_CLIENT_CA: %s", err)
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
 	}
 
-	pemCert, pemKey, err := jwk.ToX509PEMKeyPair(jwk.First(keys.Keys).Key)
-	pkg.Must(err, "Could not create X509 PEM Key Pair: %s", err)
+	g := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	s := &rpc.Server{Warden: h.Warden}
+	if h.OAuth2 != nil {
+		s.Introspection = h.OAuth2.Introspection
+	}
+	s.Register(g)
+	return g
+}
+
+// newTLSConfig obtains certificates via ACME if ACME_DOMAINS is set and serves the certificate of TLS_KEY_SET
+// otherwise.
+func newTLSConfig() *tls.Config {
+	domains := c.GetACMEDomains()
+	if len(domains) == 0 {
+		return &tls.Config{GetCertificate: newTLSCertificate(c.GetTLSKeySet()).GetCertificate}
+	}
+
+	// The account key and the certificates are kept in the key store, so that renewals survive restarts and are
+	// not requested by every node of a cluster.
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      &jwk.ACMECache{Manager: c.Context().KeyManager, Set: ACMEKeySet},
+		Email:      c.ACMEEmail,
+	}
+	if c.ACMEDirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: c.ACMEDirectoryURL}
+	}
 
-	cert, err := tls.X509KeyPair(pemCert, pemKey)
-	pkg.Must(err, "Could not create TLS Certificate: %s", err)
+	logrus.Infof("Obtaining TLS certificates for %s via ACME", strings.Join(domains, ", "))
+	return &tls.Config{
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1", acme.ALPNProto},
+	}
+}
+
+// newTLSCertificate loads the certificate from a key set, creating the key set on first boot, and reloads it
+// whenever the key set changes.
+func newTLSCertificate(set string) *jwk.TLSCertificate {
+	ctx := c.Context()
+	cert := &jwk.TLSCertificate{Manager: ctx.KeyManager, Set: set}
+	_, err := cert.Load()
+	pkg.Must(err, "Could not load TLS certificate: %s", err)
 
+	cert.Watch(context.Background(), keyChangeFeed(ctx.KeyManager), time.Minute)
 	return cert
 }
+
+// keyChangeFeed returns the changefeed of the key manager, or nil if the keys are not stored in RethinkDB.
+func keyChangeFeed(m jwk.Manager) *pkg.ChangeFeed {
+	switch k := m.(type) {
+	case *jwk.RethinkManager:
+		return k.Feed
+	case *history.KeyManager:
+		return keyChangeFeed(k.Manager)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// janitorCmd represents the janitor command
+var janitorCmd = &cobra.Command{
+	Use:   "janitor",
+	Short: "Delete expired tokens",
+	Long: `Deletes expired authorize codes, access tokens and, if REFRESH_TOKEN_LIFESPAN is set, refresh tokens right away.
+The cluster runs the janitor every JANITOR_INTERVAL on its own, use this command to clean up a large backlog.
+
+Example:
+  hydra janitor
+  hydra janitor --async
+`,
+	Run: cmdHandler.Janitor.RunJanitor,
+}
+
+func init() {
+	RootCmd.AddCommand(janitorCmd)
+	janitorCmd.Flags().Bool("async", false, "Run the janitor as a background job and print the job instead of waiting for the report")
+}
@@ -0,0 +1,39 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// keysEscrowCmd represents the escrow command
+var keysEscrowCmd = &cobra.Command{
+	Use:   "escrow <set>",
+	Short: "Export a JSON Web Key Set for escrow, split among custodians",
+	Long: `Encrypts the key set with a random wrapping key and splits the wrapping key into shares using Shamir's
+secret sharing. Any threshold of the shares recovers the key set with "hydra keys recover", fewer shares reveal
+nothing about it.
+
+Writes the encrypted key set to escrow.json and one share per custodian to share-<n>.json in the output directory.
+Hand every share to a different custodian and do not keep the shares next to the escrow file.`,
+	Run: cmdHandler.Keys.EscrowKeys,
+}
+
+func init() {
+	keysCmd.AddCommand(keysEscrowCmd)
+	keysEscrowCmd.Flags().Int("shares", 5, "Number of custodian shares")
+	keysEscrowCmd.Flags().Int("threshold", 3, "Number of shares required to recover the key set")
+	keysEscrowCmd.Flags().StringP("out", "o", ".", "Directory the escrow and the shares are written to")
+}
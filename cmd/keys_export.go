@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var keysExportCmd = &cobra.Command{
+	Use:   "export <set>",
+	Short: "Export a JSON Web Key Set as JWKS or PEM",
+	Long:  `Writes the key set to stdout. Symmetric keys can not be exported as PEM.`,
+	Run:   cmdHandler.Keys.ExportKeys,
+}
+
+func init() {
+	keysCmd.AddCommand(keysExportCmd)
+	keysExportCmd.Flags().String("format", "jwks", "The output format, jwks or pem")
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var keysImportCmd = &cobra.Command{
+	Use:   "import <set> <file.pem> [<file.pem>...]",
+	Short: "Import PEM encoded keys into a JSON Web Key Set",
+	Long: `Reads private keys, public keys and certificates from PEM files. A private key is imported along with its
+public key, using the key ids private and public like generated keys.
+
+Example:
+  hydra keys import my-set key.pem --id 2016-10
+`,
+	Run: cmdHandler.Keys.ImportKeys,
+}
+
+func init() {
+	keysCmd.AddCommand(keysImportCmd)
+	keysImportCmd.Flags().String("id", "", "Appended to the key ids, like in private:<id>")
+}
@@ -0,0 +1,33 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// keysRecoverCmd represents the recover command
+var keysRecoverCmd = &cobra.Command{
+	Use:   "recover <escrow.json> <share.json>...",
+	Short: "Recover a JSON Web Key Set from an escrow export",
+	Long: `Combines the custodians' shares, decrypts the escrowed key set and prints it. Use --import to store the
+recovered key set in hydra instead.`,
+	Run: cmdHandler.Keys.RecoverKeys,
+}
+
+func init() {
+	keysCmd.AddCommand(keysRecoverCmd)
+	keysRecoverCmd.Flags().Bool("import", false, "Import the recovered key set into hydra instead of printing it")
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate <set>",
+	Short: "Replace the keys of a JSON Web Key Set with new ones",
+	Long: `Deletes the keys of the set and creates new ones. Tokens signed with the previous keys can not be verified
+anymore, unless the previous keys are restored. The server keeps deleted keys for KEY_RETENTION.`,
+	Run: cmdHandler.Keys.RotateKeys,
+}
+
+func init() {
+	keysCmd.AddCommand(keysRotateCmd)
+	keysRotateCmd.Flags().StringP("alg", "a", "", "REQUIRED name that identifies the algorithm intended for use with the key. Supports: RS256, ES521, HS256")
+}
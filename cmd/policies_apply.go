@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var policiesApplyCmd = &cobra.Command{
+	Use:   "apply <file|directory|-> [<file|directory>...]",
+	Short: "Create or update policies from JSON or YAML files",
+	Long: `Reads policies from files, from all JSON and YAML files of directories, or from stdin if the path is -. A file
+holds a single policy or a list of policies, every policy needs an id.
+
+Policies which do not exist are created, changed ones are replaced. The changes are printed before they are applied.
+
+Example
+  hydra policies apply policies/
+  hydra policies apply --dry-run admin.yml
+  cat policy.json | hydra policies apply -`,
+	Run: cmdHandler.Policies.ApplyPolicies,
+}
+
+func init() {
+	policiesCmd.AddCommand(policiesApplyCmd)
+	policiesApplyCmd.Flags().Bool("dry-run", false, "Only print the changes")
+}
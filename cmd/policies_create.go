@@ -19,7 +19,7 @@ Example
 func init() {
 	policiesCmd.AddCommand(policiesCreateCmd)
 
-	policiesCreateCmd.Flags().StringSliceP("files", "f", []string{}, "A list of paths to JSON encoded policy files")
+	policiesCreateCmd.Flags().StringSliceP("files", "f", []string{}, "A list of paths to JSON or YAML encoded policy files or directories, - reads from stdin")
 	policiesCreateCmd.Flags().StringP("id", "i", "", "The policy's id")
 	policiesCreateCmd.Flags().StringSliceP("description", "d", []string{}, "The policy's description")
 	policiesCreateCmd.Flags().StringSliceP("resources", "r", []string{}, "A list of resource regex strings this policy will match to (required)")
@@ -6,17 +6,22 @@ import (
 
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/go-errors/errors"
 	"github.com/ory-am/hydra/cmd/cli"
 	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/logger"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
 
+var contextName string
+
 var c = new(config.Config)
 
 // This represents the base command when called without any subcommands
@@ -47,7 +52,8 @@ func init() {
 	// Cobra supports Persistent Flags, which, if defined here,
 	// will be global for your application.
 
-	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.hydra.yaml)")
+	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file in YAML or JSON format (default is $HOME/.hydra.yml)")
+	RootCmd.PersistentFlags().StringVar(&contextName, "context", "", "the context of the config file to use (default is the current context)")
 	RootCmd.PersistentFlags().Bool("skip-tls-verify", false, "foolishly accept TLS certificates signed by unkown certificate authorities")
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -58,20 +64,40 @@ func init() {
 func initConfig() {
 	mutex.Lock()
 	if cfgFile != "" {
-		// enable ability to specify config file via flag
+		// enable ability to specify config file via flag, its type (yaml or json) is taken from the extension
 		viper.SetConfigFile(cfgFile)
+	} else {
+		viper.SetConfigType("yaml")
 	}
 
 	path := absPathify("$HOME")
 	if _, err := os.Stat(filepath.Join(path, ".hydra.yml")); err != nil {
-		_, _ = os.Create(filepath.Join(path, ".hydra.yml"))
+		// The config file holds client secrets, only its owner may read it.
+		if f, err := os.OpenFile(filepath.Join(path, ".hydra.yml"), os.O_RDWR|os.O_CREATE, 0600); err == nil {
+			_ = f.Close()
+		}
 	}
 
-	viper.SetConfigType("yaml")
 	viper.SetConfigName(".hydra") // name of config file (without extension)
 	viper.AddConfigPath("$HOME")  // adding home directory as first search path
 	viper.AutomaticEnv()          // read in environment variables that match
 
+	if err := loadConfig(c); err != nil {
+		fatal("%s", err)
+	}
+
+	if err := logger.Configure(c.LogLevel, c.LogFormat); err != nil {
+		fatal("Could not configure logging: %s", err)
+	}
+
+	if c.ClusterURL == "" {
+		fmt.Printf("Pointing cluster at %s\n", c.GetClusterURL())
+	}
+	mutex.Unlock()
+}
+
+// loadConfig reads the config file and the environment, which overrides the config file, into c.
+func loadConfig(c *config.Config) error {
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
 		fmt.Printf(`Config file not found because "%s"`, err)
@@ -79,7 +105,17 @@ func initConfig() {
 	}
 
 	if err := viper.Unmarshal(c); err != nil {
-		fatal("Could not read config because %s.", err)
+		return errors.Errorf("Could not read config because %s.", err)
+	}
+
+	name := contextName
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name != "" {
+		if err := c.UseContext(name); err != nil {
+			return errors.Errorf("Could not use context: %s.", err)
+		}
 	}
 
 	if consentURL, ok := viper.Get("CONSENT_URL").(string); ok {
@@ -90,6 +126,10 @@ func initConfig() {
 		c.ClientID = clientID
 	}
 
+	if caBundle, ok := viper.Get("CA_BUNDLE").(string); ok {
+		c.CABundle = caBundle
+	}
+
 	if systemSecret, ok := viper.Get("SYSTEM_SECRET").(string); ok {
 		c.SystemSecret = []byte(systemSecret)
 	}
@@ -98,14 +138,345 @@ func initConfig() {
 		c.ClientSecret = clientSecret
 	}
 
+	if rootCredentials, ok := viper.Get("FORCE_ROOT_CLIENT_CREDENTIALS").(string); ok {
+		c.ForceRootClientCredentials = rootCredentials
+	}
+
 	if databaseURL, ok := viper.Get("DATABASE_URL").(string); ok {
 		c.DatabaseURL = databaseURL
 	}
 
-	if c.ClusterURL == "" {
-		fmt.Printf("Pointing cluster at %s\n", c.GetClusterURL())
+	if clientsQuota, ok := viper.Get("CLIENTS_QUOTA").(string); ok {
+		quota, err := strconv.Atoi(clientsQuota)
+		if err != nil {
+			return errors.Errorf("CLIENTS_QUOTA must be a number: %s", err)
+		}
+		c.ClientsQuota = quota
 	}
-	mutex.Unlock()
+
+	if tokensQuota, ok := viper.Get("TOKENS_QUOTA").(string); ok {
+		quota, err := strconv.Atoi(tokensQuota)
+		if err != nil {
+			return errors.Errorf("TOKENS_QUOTA must be a number: %s", err)
+		}
+		c.TokensQuota = quota
+	}
+
+	if sessionsQuota, ok := viper.Get("SESSIONS_QUOTA").(string); ok {
+		quota, err := strconv.Atoi(sessionsQuota)
+		if err != nil {
+			return errors.Errorf("SESSIONS_QUOTA must be a number: %s", err)
+		}
+		c.SessionsQuota = quota
+	}
+
+	if thresholds, ok := viper.Get("QUOTA_WARNING_THRESHOLDS").(string); ok {
+		c.QuotaWarningThresholds = []float64{}
+		for _, t := range strings.Split(thresholds, ",") {
+			threshold, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+			if err != nil {
+				return errors.Errorf("QUOTA_WARNING_THRESHOLDS must be a comma separated list of fractions like 0.8,0.9: %s", err)
+			}
+			c.QuotaWarningThresholds = append(c.QuotaWarningThresholds, threshold)
+		}
+	}
+
+	if keyAuditLog, ok := viper.Get("KEY_AUDIT_LOG").(string); ok {
+		c.KeyAuditLog = keyAuditLog
+	}
+
+	if accessLog, ok := viper.Get("ACCESS_LOG").(string); ok {
+		c.AccessLog = accessLog
+	}
+
+	if keyRetention, ok := viper.Get("KEY_RETENTION").(string); ok {
+		c.KeyRetention = keyRetention
+	}
+
+	if tlsKeySet, ok := viper.Get("TLS_KEY_SET").(string); ok {
+		c.TLSKeySet = tlsKeySet
+	}
+
+	if acmeDomains, ok := viper.Get("ACME_DOMAINS").(string); ok {
+		c.ACMEDomains = acmeDomains
+	}
+
+	if acmeEmail, ok := viper.Get("ACME_EMAIL").(string); ok {
+		c.ACMEEmail = acmeEmail
+	}
+
+	if acmeDirectoryURL, ok := viper.Get("ACME_DIRECTORY_URL").(string); ok {
+		c.ACMEDirectoryURL = acmeDirectoryURL
+	}
+
+	if keyValidation, ok := viper.Get("KEY_VALIDATION").(string); ok {
+		c.KeyValidation = keyValidation
+	}
+
+	if enableHistory, ok := viper.Get("ENABLE_HISTORY").(string); ok {
+		c.EnableHistory = enableHistory == "true"
+	}
+
+	if pendingConsentLifespan, ok := viper.Get("PENDING_CONSENT_LIFESPAN").(string); ok {
+		c.PendingConsentLifespan = pendingConsentLifespan
+	}
+
+	if rememberConsentMaxLifespan, ok := viper.Get("REMEMBER_CONSENT_MAX_LIFESPAN").(string); ok {
+		c.RememberConsentMaxLifespan = rememberConsentMaxLifespan
+	}
+
+	if rememberConsentScopeLifespans, ok := viper.Get("REMEMBER_CONSENT_SCOPE_LIFESPANS").(string); ok {
+		c.RememberConsentScopeLifespans = rememberConsentScopeLifespans
+	}
+
+	if loginSessionLifespan, ok := viper.Get("LOGIN_SESSION_LIFESPAN").(string); ok {
+		c.LoginSessionLifespan = loginSessionLifespan
+	}
+
+	if idempotencyKeyLifespan, ok := viper.Get("IDEMPOTENCY_KEY_LIFESPAN").(string); ok {
+		c.IdempotencyKeyLifespan = idempotencyKeyLifespan
+	}
+
+	if logoutRedirectURL, ok := viper.Get("LOGOUT_REDIRECT_URL").(string); ok {
+		c.LogoutRedirectURL = logoutRedirectURL
+	}
+
+	if mtlsBaseURL, ok := viper.Get("MTLS_BASE_URL").(string); ok {
+		c.MTLSBaseURL = mtlsBaseURL
+	}
+
+	if deviceCodeLifespan, ok := viper.Get("DEVICE_CODE_LIFESPAN").(string); ok {
+		c.DeviceCodeLifespan = deviceCodeLifespan
+	}
+
+	if deviceVerificationURL, ok := viper.Get("DEVICE_VERIFICATION_URL").(string); ok {
+		c.DeviceVerificationURL = deviceVerificationURL
+	}
+
+	if legacyIssuers, ok := viper.Get("LEGACY_ISSUERS").(string); ok {
+		c.LegacyIssuers = legacyIssuers
+	}
+
+	if accessTokenStrategy, ok := viper.Get("ACCESS_TOKEN_STRATEGY").(string); ok {
+		c.AccessTokenStrategy = accessTokenStrategy
+	}
+
+	if acceptedFormats, ok := viper.Get("ACCESS_TOKEN_ACCEPTED_FORMATS").(string); ok {
+		c.AccessTokenAcceptedFormats = acceptedFormats
+	}
+
+	if webhookURLs, ok := viper.Get("WEBHOOK_URLS").(string); ok {
+		c.WebhookURLs = webhookURLs
+	}
+
+	if webhookSecret, ok := viper.Get("WEBHOOK_SECRET").(string); ok {
+		c.WebhookSecret = webhookSecret
+	}
+
+	if pseudonymizeEvents, ok := viper.Get("PSEUDONYMIZE_EVENTS").(string); ok {
+		c.PseudonymizeEvents = pseudonymizeEvents == "true"
+	}
+
+	if pseudonymSecret, ok := viper.Get("PSEUDONYM_SECRET").(string); ok {
+		c.PseudonymSecret = pseudonymSecret
+	}
+
+	if pseudonymRotation, ok := viper.Get("PSEUDONYM_ROTATION").(string); ok {
+		c.PseudonymRotation = pseudonymRotation
+	}
+
+	if openClientRegistration, ok := viper.Get("OPEN_CLIENT_REGISTRATION").(string); ok {
+		c.OpenClientRegistration = openClientRegistration == "true"
+	}
+
+	if secretHasher, ok := viper.Get("SECRET_HASHER").(string); ok {
+		c.SecretHasher = secretHasher
+	}
+
+	if workFactor, ok := viper.Get("BCRYPT_WORK_FACTOR").(string); ok {
+		factor, err := strconv.Atoi(workFactor)
+		if err != nil {
+			return errors.Errorf("BCRYPT_WORK_FACTOR must be a number: %s", err)
+		}
+		c.BCryptWorkFactor = factor
+	}
+
+	if iterations, ok := viper.Get("ARGON2_ITERATIONS").(string); ok {
+		n, err := strconv.ParseUint(iterations, 10, 32)
+		if err != nil {
+			return errors.Errorf("ARGON2_ITERATIONS must be a number: %s", err)
+		}
+		c.Argon2Iterations = uint32(n)
+	}
+
+	if memory, ok := viper.Get("ARGON2_MEMORY").(string); ok {
+		n, err := strconv.ParseUint(memory, 10, 32)
+		if err != nil {
+			return errors.Errorf("ARGON2_MEMORY must be the amount of memory in KiB: %s", err)
+		}
+		c.Argon2Memory = uint32(n)
+	}
+
+	if parallelism, ok := viper.Get("ARGON2_PARALLELISM").(string); ok {
+		n, err := strconv.ParseUint(parallelism, 10, 8)
+		if err != nil {
+			return errors.Errorf("ARGON2_PARALLELISM must be a number between 1 and 255: %s", err)
+		}
+		c.Argon2Parallelism = uint8(n)
+	}
+
+	if overlap, ok := viper.Get("SECRET_ROTATION_OVERLAP").(string); ok {
+		c.SecretRotationOverlap = overlap
+	}
+
+	if claimsSource, ok := viper.Get("ID_TOKEN_CLAIMS_SOURCE").(string); ok {
+		c.IDTokenClaimsSource = claimsSource
+	}
+
+	if claimsSecret, ok := viper.Get("ID_TOKEN_CLAIMS_SECRET").(string); ok {
+		c.IDTokenClaimsSecret = claimsSecret
+	}
+
+	if scopeClaims, ok := viper.Get("ID_TOKEN_SCOPE_CLAIMS").(string); ok {
+		c.IDTokenScopeClaims = scopeClaims
+	}
+
+	if scopeStrategy, ok := viper.Get("SCOPE_STRATEGY").(string); ok {
+		c.ScopeStrategy = scopeStrategy
+	}
+
+	if snapshotMaxAge, ok := viper.Get("WARDEN_SNAPSHOT_MAX_AGE").(string); ok {
+		c.WardenSnapshotMaxAge = snapshotMaxAge
+	}
+
+	if decisionCacheTTL, ok := viper.Get("WARDEN_DECISION_CACHE_TTL").(string); ok {
+		c.WardenDecisionCacheTTL = decisionCacheTTL
+	}
+
+	if janitorInterval, ok := viper.Get("JANITOR_INTERVAL").(string); ok {
+		c.JanitorInterval = janitorInterval
+	}
+
+	if batchSize, ok := viper.Get("JANITOR_BATCH_SIZE").(string); ok {
+		size, err := strconv.Atoi(batchSize)
+		if err != nil {
+			return errors.Errorf("JANITOR_BATCH_SIZE must be a number of tokens: %s", err)
+		}
+		c.JanitorBatchSize = size
+	}
+
+	if refreshTokenLifespan, ok := viper.Get("REFRESH_TOKEN_LIFESPAN").(string); ok {
+		c.RefreshTokenLifespan = refreshTokenLifespan
+	}
+
+	if snapshotPath, ok := viper.Get("MEMORY_SNAPSHOT_PATH").(string); ok {
+		c.MemorySnapshotPath = snapshotPath
+	}
+
+	if snapshotInterval, ok := viper.Get("MEMORY_SNAPSHOT_INTERVAL").(string); ok {
+		c.MemorySnapshotInterval = snapshotInterval
+	}
+
+	if hedgedReads, ok := viper.Get("HEDGED_READS").(string); ok {
+		c.HedgedReads = hedgedReads == "true"
+	}
+
+	if keyLookupBudget, ok := viper.Get("KEY_LOOKUP_BUDGET").(string); ok {
+		c.KeyLookupBudget = keyLookupBudget
+	}
+
+	if tokenReadBudget, ok := viper.Get("TOKEN_READ_BUDGET").(string); ok {
+		c.TokenReadBudget = tokenReadBudget
+	}
+
+	if tokenWriteBudget, ok := viper.Get("TOKEN_WRITE_BUDGET").(string); ok {
+		c.TokenWriteBudget = tokenWriteBudget
+	}
+
+	if adminAddress, ok := viper.Get("ADMIN_ADDRESS").(string); ok {
+		c.AdminAddress = adminAddress
+	}
+
+	if adminTLSKeySet, ok := viper.Get("ADMIN_TLS_KEY_SET").(string); ok {
+		c.AdminTLSKeySet = adminTLSKeySet
+	}
+
+	if metricsAddress, ok := viper.Get("METRICS_ADDRESS").(string); ok {
+		c.MetricsAddress = metricsAddress
+	}
+
+	if grpcAddress, ok := viper.Get("GRPC_ADDRESS").(string); ok {
+		c.GRPCAddress = grpcAddress
+	}
+
+	if grpcClientCA, ok := viper.Get("GRPC_CLIENT_CA").(string); ok {
+		c.GRPCClientCA = grpcClientCA
+	}
+
+	if tracingProvider, ok := viper.Get("TRACING_PROVIDER").(string); ok {
+		c.TracingProvider = tracingProvider
+	}
+
+	if tracingServiceName, ok := viper.Get("TRACING_SERVICE_NAME").(string); ok {
+		c.TracingServiceName = tracingServiceName
+	}
+
+	if tracingAgentAddress, ok := viper.Get("TRACING_AGENT_ADDRESS").(string); ok {
+		c.TracingAgentAddress = tracingAgentAddress
+	}
+
+	if logLevel, ok := viper.Get("LOG_LEVEL").(string); ok {
+		c.LogLevel = logLevel
+	}
+
+	if logFormat, ok := viper.Get("LOG_FORMAT").(string); ok {
+		c.LogFormat = logFormat
+	}
+
+	if rateLimitPerClient, ok := viper.Get("RATE_LIMIT_PER_CLIENT").(string); ok {
+		c.RateLimitPerClient = rateLimitPerClient
+	}
+
+	if rateLimitPerAddress, ok := viper.Get("RATE_LIMIT_PER_ADDRESS").(string); ok {
+		c.RateLimitPerAddress = rateLimitPerAddress
+	}
+
+	if rateLimitRedisURL, ok := viper.Get("RATE_LIMIT_REDIS_URL").(string); ok {
+		c.RateLimitRedisURL = rateLimitRedisURL
+	}
+
+	if lockoutThreshold, ok := viper.Get("LOCKOUT_THRESHOLD").(string); ok {
+		threshold, err := strconv.Atoi(lockoutThreshold)
+		if err != nil {
+			return errors.Errorf("LOCKOUT_THRESHOLD must be a number of failed authentications: %s", err)
+		}
+		c.LockoutThreshold = threshold
+	}
+
+	if lockoutDuration, ok := viper.Get("LOCKOUT_DURATION").(string); ok {
+		c.LockoutDuration = lockoutDuration
+	}
+
+	if lockoutMaxDuration, ok := viper.Get("LOCKOUT_MAX_DURATION").(string); ok {
+		c.LockoutMaxDuration = lockoutMaxDuration
+	}
+
+	if corsAllowedOrigins, ok := viper.Get("CORS_ALLOWED_ORIGINS").(string); ok {
+		c.CORSAllowedOrigins = corsAllowedOrigins
+	}
+
+	if disableCompression, ok := viper.Get("DISABLE_COMPRESSION").(string); ok {
+		c.DisableCompression = disableCompression == "true"
+	}
+
+	if minSize, ok := viper.Get("COMPRESSION_MIN_SIZE").(string); ok {
+		size, err := strconv.Atoi(minSize)
+		if err != nil {
+			return errors.Errorf("COMPRESSION_MIN_SIZE must be a number of bytes: %s", err)
+		}
+		c.CompressionMinSize = size
+	}
+	return nil
 }
 
 func absPathify(inPath string) string {
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"fmt"
+
 	"github.com/Sirupsen/logrus"
 	"github.com/go-errors/errors"
 	"github.com/julienschmidt/httprouter"
@@ -9,58 +11,190 @@ import (
 	"github.com/ory-am/hydra/client"
 	"github.com/ory-am/hydra/config"
 	"github.com/ory-am/hydra/connection"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/group"
+	"github.com/ory-am/hydra/health"
+	"github.com/ory-am/hydra/history"
+	"github.com/ory-am/hydra/janitor"
+	"github.com/ory-am/hydra/job"
 	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/label"
 	"github.com/ory-am/hydra/oauth2"
 	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/hydra/policy"
+	"github.com/ory-am/hydra/swagger"
 	"github.com/ory-am/hydra/warden"
 	"github.com/ory-am/ladon"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// MetricsHandlerPath exposes the Prometheus metrics of this instance. It is not protected by the warden so
+// that scrapers do not need OAuth2 credentials; restrict access to it at the network level or serve it on a
+// listener of its own with METRICS_ADDRESS.
+const MetricsHandlerPath = "/metrics"
+
 type Handler struct {
-	Clients     *client.Handler
-	Connections *connection.Handler
-	Keys        *jwk.Handler
-	OAuth2      *oauth2.Handler
-	Policy      *policy.Handler
+	Clients      *client.Handler
+	Config       *config.Handler
+	Connections  *connection.Handler
+	Events       *events.Handler
+	Groups       *group.Handler
+	Health       *health.Handler
+	History      *history.Handler
+	Janitor      *janitor.Handler
+	Jobs         *job.Handler
+	Keys         *jwk.Handler
+	Labels       *label.Handler
+	OAuth2       *oauth2.Handler
+	Policy       *policy.Handler
+	Registration *client.RegistrationHandler
+	Swagger      *swagger.Handler
+	Warden       *warden.WardenHandler
+
+	// AuthenticatingClients verifies client credentials, including previous secrets whose rotation overlaps. It
+	// does not record failed attempts in the lockouts.
+	AuthenticatingClients client.Manager
 }
 
 func (h *Handler) Start(c *config.Config, router *httprouter.Router) {
 	ctx := c.Context()
 
 	// Set up warden
+	secretRotations := newRotationManager(c)
 	clientsManager := newClientManager(c)
-	injectFositeStore(c, clientsManager)
-	ctx.Warden = &warden.LocalWarden{
-		Warden: &ladon.Ladon{
-			Manager: ctx.LadonManager,
-		},
-		TokenValidator: &core.CoreValidator{
-			AccessTokenStrategy: ctx.FositeStrategy,
-			AccessTokenStorage:  ctx.FositeStore,
+	clientSettings := newClientSettingsManager(c)
+	labelsManager := newLabelManager(c)
+	groupsManager := newGroupManager(c)
+
+	// Previous secrets stay valid while their rotation overlaps, wherever clients authenticate.
+	authenticatingClients := &client.RotatingManager{
+		Manager:   clientsManager,
+		Rotations: secretRotations,
+		Hasher:    ctx.Hasher,
+	}
+	injectFositeStore(c, authenticatingClients)
+	h.AuthenticatingClients = authenticatingClients
+
+	// The janitor deletes from the store itself, deleted tokens are not revocations worth recording.
+	tokenStore := ctx.FositeStore
+	injectTokenQuota(c)
+	injectHedgedReads(c)
+	injectTokenBudgets(c)
+	injectTracedStore(c)
+
+	var historyManager history.Manager
+	var historyKeys *history.KeyManager
+	if c.EnableHistory {
+		historyManager = newHistoryManager(c)
+		ctx.FositeStore = &history.TokenStore{
+			FositeStorer: ctx.FositeStore,
+			History:      historyManager,
+			Lifespan:     c.GetAccessTokenLifespan(),
+		}
+	}
+
+	ladonWarden := newDecisionCache(c, &policy.Warden{
+		Manager: &group.SubjectManager{
+			Manager: &label.SelectorManager{
+				Manager: ctx.LadonManager,
+				Labels:  labelsManager,
+			},
+			Groups: groupsManager,
 		},
-		Issuer: c.Issuer,
+	}, groupsManager, labelsManager)
+	tokenValidator := &core.CoreValidator{
+		AccessTokenStrategy: ctx.FositeStrategy,
+		AccessTokenStorage:  ctx.FositeStore,
 	}
+	ctx.Warden = &warden.LocalWarden{
+		Warden:         ladonWarden,
+		TokenValidator: tokenValidator,
+		Issuer:         c.Issuer,
+		ScopeStrategy:  c.GetScopeStrategy(),
+	}
+
+	jobsManager := newJobManager(c)
+	ctx.Jobs = &job.Dispatcher{Manager: jobsManager}
 
 	// Set up handlers
-	h.Clients = newClientHandler(c, router, clientsManager)
+	h.Events = newEventsHandler(c, router)
+	lockouts := newLockouts(c, clientsManager)
+	quotaClients := newQuotaManager(c, clientsManager)
+	h.Clients = newClientHandler(c, router, quotaClients, secretRotations, clientSettings, lockouts)
+	h.Clients.Labels = labelsManager
+	h.Registration = newRegistrationHandler(c, router, quotaClients, clientSettings)
 	h.Keys = newJWKHandler(c, router)
+	keysManager := h.Keys.Manager
+
+	// JWT access tokens are signed with managed keys, the key manager in turn is protected by the warden.
+	injectAccessTokenStrategy(c, h.Keys.Manager)
+	tokenValidator.AccessTokenStrategy = ctx.FositeStrategy
+	ctx.ServiceTokens = &oauth2.ServiceTokenMinter{
+		Strategy: ctx.FositeStrategy,
+		Store:    ctx.FositeStore,
+		Policies: ctx.LadonManager,
+		Lifespan: c.GetAccessTokenLifespan(),
+	}
+
+	if historyManager != nil {
+		historyKeys = &history.KeyManager{Manager: ctx.KeyManager, History: historyManager}
+		ctx.KeyManager = historyKeys
+		h.Keys.Manager = historyKeys
+	}
 	h.Connections = newConnectionHandler(c, router)
-	h.Policy = newPolicyHandler(c, router)
-	h.OAuth2 = newOAuth2Handler(c, router, h.Keys.Manager)
+	h.Policy = newPolicyHandler(c, router, labelsManager)
+	h.Labels = newLabelHandler(c, router, labelsManager, clientsManager)
+	h.Groups = newGroupHandler(c, router, groupsManager)
+	h.Warden = newWardenHandler(c, router, ladonWarden)
+	h.Warden.Snapshots = &warden.SnapshotExporter{
+		Issuer:     c.Issuer,
+		Policies:   ctx.LadonManager,
+		KeyManager: h.Keys.Manager,
+		KeySets:    []string{oauth2.OpenIDConnectKeyName, oauth2.AccessTokenKeyName},
+		History:    historyManager,
+		MaxAge:     c.GetWardenSnapshotMaxAge(),
+	}
+	h.Jobs = newJobHandler(c, router, jobsManager)
+	h.OAuth2 = newOAuth2Handler(c, router, h.Keys.Manager, authenticatingClients, clientSettings, ladonWarden, lockouts)
+	h.OAuth2.Introspection.Filter = h.Warden
+	h.Config = newConfigHandler(c, router)
+	h.Janitor = newJanitorHandler(c, router, tokenStore)
+	h.Health = newHealthHandler(c, router, tokenStore, clientsManager, keysManager, ctx.LadonManager, h.Connections.Manager)
+	if c.MetricsAddress == "" {
+		router.Handler("GET", MetricsHandlerPath, prometheus.Handler())
+	}
+
+	// The snapshot is restored first, so that keys and the root account are only created on a fresh install.
+	startSnapshots(c, map[string]interface{}{
+		"clients":     clientsManager,
+		"keys":        keysManager,
+		"connections": h.Connections.Manager,
+		"groups":      groupsManager,
+		"labels":      labelsManager,
+	})
 
 	// Create root account if new install
 	h.createRS256KeysIfNotExist(c, oauth2.ConsentEndpointKey, "private")
 	h.createRS256KeysIfNotExist(c, oauth2.ConsentChallengeKey, "private")
+	h.createRS256KeysIfNotExist(c, warden.SnapshotKeyName, "private")
+
+	if historyManager != nil {
+		err := historyKeys.Seed(oauth2.OpenIDConnectKeyName, oauth2.ConsentEndpointKey, oauth2.ConsentChallengeKey, oauth2.AccessTokenKeyName, warden.SnapshotKeyName)
+		pkg.Must(err, "Could not record existing keys in history: %s", err)
+		h.History = newHistoryHandler(c, router, historyManager)
+	}
+
+	h.Swagger = newSwaggerHandler(c, router)
 
 	h.createRootIfNewInstall(c)
+	c.Effective().Log()
 }
 
 func (h *Handler) createRS256KeysIfNotExist(c *config.Config, set, lookup string) {
 	ctx := c.Context()
 	generator := jwk.RS256Generator{}
 
-	if _, err := ctx.KeyManager.GetKey(set, lookup); errors.Is(err, pkg.ErrNotFound) {
+	if _, err := jwk.GetKeyConsistent(ctx.KeyManager, set, lookup); errors.Is(err, pkg.ErrNotFound) {
 		logrus.Warnf("Key pair for signing %s is missing. Creating new one.", set)
 
 		keys, err := generator.Generate("")
@@ -80,15 +214,23 @@ func (h *Handler) createRootIfNewInstall(c *config.Config) {
 		return
 	}
 
-	rs, err := pkg.GenerateSecret(16)
-	pkg.Must(err, "Could notgenerate secret because %s", err)
-	secret := []byte(string(rs))
+	id, forced, err := c.GetRootClientCredentials()
+	pkg.Must(err, "Could not create temporary root because %s", err)
+	secret := []byte(forced)
+	if forced == "" {
+		rs, err := pkg.GenerateSecret(16)
+		pkg.Must(err, "Could notgenerate secret because %s", err)
+		secret = []byte(string(rs))
+	}
 
 	logrus.Warn("No clients were found. Creating a temporary root client...")
 	root := &fosite.DefaultClient{
+		ID:            id,
 		Name:          "This temporary client is generated by hydra and is granted all of hydra's administrative privileges. It must be removed when everything is set up.",
 		GrantTypes:    []string{"client_credentials", "authorization_code"},
 		ResponseTypes: []string{"token", "code"},
+		// The hydra scope covers the scopes of all administrative endpoints, like hydra.keys, hydra.clients and
+		// hydra.policies.
 		GrantedScopes: []string{"hydra", "core"},
 		RedirectURIs:  []string{"http://localhost:4445/callback"},
 		Secret:        secret,
@@ -111,7 +253,16 @@ func (h *Handler) createRootIfNewInstall(c *config.Config) {
 	c.Unlock()
 
 	logrus.Warn("Temporary root client created.")
-	logrus.Warnf("client_id: %s", root.GetID())
-	logrus.Warnf("client_secret: %s", string(secret))
-	logrus.Warn("The root client must be removed in production. The root's credentials could be accidentally logged.")
+	if forced != "" {
+		logrus.Warnf("client_id: %s", root.GetID())
+		logrus.Warn("client_secret: as set in FORCE_ROOT_CLIENT_CREDENTIALS")
+		return
+	}
+
+	// The generated credentials are only shown this once. They are printed to stdout without a log entry around them,
+	// but stdout is usually collected like the log, so anyone who can read the log can read the secret until the
+	// root client is removed. Set FORCE_ROOT_CLIENT_CREDENTIALS to keep the secret out of the output.
+	fmt.Printf("client_id: %s\n", root.GetID())
+	fmt.Printf("client_secret: %s\n", string(secret))
+	logrus.Warn("The root client must be removed in production. Its credentials were printed to stdout and are not shown again.")
 }
@@ -1,12 +1,17 @@
 package server
 
 import (
+	"net/url"
+	"time"
+
 	"github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/client"
 	"github.com/ory-am/hydra/config"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
 	"golang.org/x/net/context"
 	r "gopkg.in/dancannon/gorethink.v2"
 )
@@ -14,34 +19,181 @@ import (
 func newClientManager(c *config.Config) client.Manager {
 	ctx := c.Context()
 
+	var migrating *pkg.MigratingHasher
+	if rotating, ok := ctx.Hasher.(*client.RotatingHasher); ok {
+		migrating, _ = rotating.Hasher.(*pkg.MigratingHasher)
+	}
+
+	var m client.Manager
 	switch con := ctx.Connection.(type) {
 	case *config.MemoryConnection:
-		return &client.MemoryManager{
+		m = &client.MemoryManager{
 			Clients: map[string]*fosite.DefaultClient{},
 			Hasher:  ctx.Hasher,
 		}
 	case *config.RethinkDBConnection:
 		con.CreateTableIfNotExists("hydra_clients")
-		m := &client.RethinkManager{
+		rm := &client.RethinkManager{
 			Session: con.GetSession(),
 			Table:   r.Table("hydra_clients"),
 			Hasher:  ctx.Hasher,
 		}
-		if err := m.ColdStart(); err != nil {
+		if err := rm.ColdStart(); err != nil {
 			logrus.Fatalf("Could not fetch initial state: %s", err)
 		}
-		m.Watch(context.Background())
-		return m
+		rm.Watch(context.Background())
+		m = rm
+	case *config.SQLConnection:
+		sm := &client.SQLManager{
+			DB:     con.GetDatabase(),
+			Driver: con.GetDriver(),
+			Hasher: ctx.Hasher,
+		}
+		if err := sm.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create schemas: %s", err)
+		}
+		m = sm
+	case *config.DynamoDBConnection:
+		m = &client.DynamoDBManager{
+			DB:     con.GetDB(),
+			Table:  con.GetTable(),
+			Hasher: ctx.Hasher,
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+
+	// Outdated secret hashes are replaced as soon as a client authenticates with its secret.
+	if migrating != nil {
+		if rehasher, ok := m.(client.SecretRehasher); ok {
+			migrating.OnRehash = func(oldHash, newHash []byte) {
+				if err := rehasher.RehashSecret(oldHash, newHash); err != nil {
+					logger.LogError(err)
+				}
+			}
+		}
+	}
+	return m
+}
+
+// newQuotaManager limits the number of clients the client handlers create if CLIENTS_QUOTA is set. Both handlers
+// share it, so that they publish the warnings of one quota.
+func newQuotaManager(c *config.Config, clients client.Manager) client.Manager {
+	if c.ClientsQuota <= 0 {
+		return clients
+	}
+	return &client.QuotaManager{Manager: clients, Quota: c.GetQuota("clients", c.ClientsQuota)}
+}
+
+func newRotationManager(c *config.Config) client.RotationManager {
+	switch con := c.Context().Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return client.NewRotationMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_client_secret_rotations")
+		return &client.RotationRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_client_secret_rotations"),
+		}
 	default:
 		panic("Unknown connection type.")
 	}
 }
 
-func newClientHandler(c *config.Config, router *httprouter.Router, manager client.Manager) *client.Handler {
+func newClientSettingsManager(c *config.Config) client.SettingsManager {
+	switch con := c.Context().Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return client.NewSettingsMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_client_settings")
+		return &client.SettingsRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_client_settings"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+}
+
+// newLockouts returns the lockouts of clients which fail to authenticate, or nil if LOCKOUT_THRESHOLD is not set.
+func newLockouts(c *config.Config, clients client.Manager) *client.Lockouts {
+	ctx := c.Context()
+	if c.LockoutThreshold <= 0 {
+		return nil
+	}
+
+	l := &client.Lockouts{
+		Clients:     clients,
+		Threshold:   c.LockoutThreshold,
+		Duration:    c.GetLockoutDuration(),
+		MaxDuration: c.GetLockoutMaxDuration(),
+		Publisher:   ctx.Events,
+	}
+	switch con := ctx.Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		l.Manager = client.NewLockoutMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_client_lockouts")
+		l.Manager = &client.LockoutRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_client_lockouts"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+	return l
+}
+
+func newClientHandler(c *config.Config, router *httprouter.Router, manager client.Manager, rotations client.RotationManager, settings client.SettingsManager, lockouts *client.Lockouts) *client.Handler {
 	ctx := c.Context()
 	h := &client.Handler{
 		H: &herodot.JSON{},
 		W: ctx.Warden, Manager: manager,
+		Rotations:       rotations,
+		RotationOverlap: c.GetSecretRotationOverlap(),
+		Settings:        settings,
+		Lockouts:        lockouts,
+		Preconditions:   &pkg.Preconditions{Leases: ctx.Leases},
+
+		// The janitor deletes tokens by these lifespans.
+		Lifespans: client.Lifespans{
+			AccessToken:   c.GetAccessTokenLifespan(),
+			RefreshToken:  c.GetRefreshTokenLifespan(),
+			AuthorizeCode: time.Hour,
+		},
+	}
+
+	h.SetRoutes(router)
+	return h
+}
+
+func newRegistrationHandler(c *config.Config, router *httprouter.Router, manager client.Manager, settings client.SettingsManager) *client.RegistrationHandler {
+	ctx := c.Context()
+	endpoint, err := url.Parse(c.GetClusterURL())
+	pkg.Must(err, "Could not parse cluster url: %s", err)
+
+	h := &client.RegistrationHandler{
+		H:        &herodot.JSON{},
+		W:        ctx.Warden,
+		Manager:  manager,
+		Settings: settings,
+		Open:     c.OpenClientRegistration,
+		Endpoint: pkg.JoinURL(endpoint, client.RegistrationHandlerPath),
+
+		ScopeStrategy: c.GetScopeStrategy(),
+	}
+
+	switch con := ctx.Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		h.Registrations = client.NewRegistrationMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_client_registrations")
+		h.Registrations = &client.RegistrationRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_client_registrations"),
+		}
+	default:
+		panic("Unknown connection type.")
 	}
 
 	h.SetRoutes(router)
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/herodot"
+)
+
+func newConfigHandler(c *config.Config, router *httprouter.Router) *config.Handler {
+	ctx := c.Context()
+	h := &config.Handler{
+		Config: c,
+		H:      &herodot.JSON{},
+		W:      ctx.Warden,
+	}
+	h.SetRoutes(router)
+	return h
+}
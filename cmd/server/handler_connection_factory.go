@@ -19,14 +19,24 @@ func newConnectionHandler(c *config.Config, router *httprouter.Router) *connecti
 	h.SetRoutes(router)
 
 	switch con := ctx.Connection.(type) {
-	case *config.MemoryConnection:
+	case *config.MemoryConnection, *config.DynamoDBConnection:
 		h.Manager = connection.NewMemoryManager()
 		break
+	case *config.SQLConnection:
+		m := &connection.SQLManager{
+			DB:     con.GetDatabase(),
+			Driver: con.GetDriver(),
+		}
+		if err := m.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create schemas: %s", err)
+		}
+		h.Manager = m
+		break
 	case *config.RethinkDBConnection:
-		con.CreateTableIfNotExists("hydra_policies")
+		con.CreateTableIfNotExists("hydra_connections")
 		m := &connection.RethinkManager{
 			Session: con.GetSession(),
-			Table:   r.Table("hydra_policies"),
+			Table:   r.Table("hydra_connections"),
 		}
 		if err := m.ColdStart(); err != nil {
 			logrus.Fatalf("Could not fetch initial state: %s", err)
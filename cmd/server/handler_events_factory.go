@@ -0,0 +1,66 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/herodot"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+func newDeadLetterManager(c *config.Config) events.DeadLetterManager {
+	switch con := c.Context().Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return events.NewDeadLetterMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_event_dead_letters")
+		return &events.DeadLetterRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_event_dead_letters"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+}
+
+// newEventsHandler sets up webhook delivery to WEBHOOK_URLS, a comma separated list, in addition to logging events.
+// With PSEUDONYMIZE_EVENTS, webhooks receive pseudonyms instead of subject identifiers while the log stays exact.
+// It has to run before handlers which capture the event publisher.
+func newEventsHandler(c *config.Config, router *httprouter.Router) *events.Handler {
+	ctx := c.Context()
+	deadLetters := newDeadLetterManager(c)
+	publisher := &events.WebhookPublisher{
+		Secret:      []byte(c.WebhookSecret),
+		DeadLetters: deadLetters,
+	}
+
+	for _, u := range strings.Split(c.WebhookURLs, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			publisher.Destinations = append(publisher.Destinations, events.Destination{URL: u})
+		}
+	}
+	if len(publisher.Destinations) > 0 {
+		var exporter events.Publisher = publisher
+		if c.PseudonymizeEvents {
+			exporter = &events.PseudonymizingPublisher{
+				Publisher: publisher,
+				Pseudonymizer: &events.HMACPseudonymizer{
+					Secret:   c.GetPseudonymSecret(),
+					Rotation: c.GetPseudonymRotation(),
+				},
+			}
+		}
+		ctx.Events = events.Publishers{ctx.Events, exporter}
+	}
+
+	h := &events.Handler{
+		Manager:   deadLetters,
+		Publisher: publisher,
+		H:         &herodot.JSON{},
+		W:         ctx.Warden,
+	}
+	h.SetRoutes(router)
+	return h
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/group"
+	"github.com/ory-am/hydra/herodot"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+func newGroupManager(c *config.Config) group.Manager {
+	ctx := c.Context()
+
+	switch con := ctx.Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return group.NewMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_groups")
+		m := &group.RethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_groups"),
+		}
+		if err := m.ColdStart(); err != nil {
+			logrus.Fatalf("Could not fetch initial state: %s", err)
+		}
+		m.Watch(context.Background())
+		return m
+	default:
+		panic("Unknown connection type.")
+	}
+}
+
+func newGroupHandler(c *config.Config, router *httprouter.Router, manager group.Manager) *group.Handler {
+	ctx := c.Context()
+	h := &group.Handler{
+		H:       &herodot.JSON{},
+		W:       ctx.Warden,
+		Manager: manager,
+	}
+
+	h.SetRoutes(router)
+	return h
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"database/sql"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/connection"
+	"github.com/ory-am/hydra/health"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/internal"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/policy"
+)
+
+// newHealthHandler probes the database connection, every changefeed and the databases of the given managers
+// which are stored in SQL.
+func newHealthHandler(c *config.Config, router *httprouter.Router, managers ...interface{}) *health.Handler {
+	ctx := c.Context()
+	h := &health.Handler{
+		H:           &herodot.JSON{},
+		Checkers:    map[string]health.Checker{},
+		ChangeFeeds: pkg.StartedChangeFeeds,
+	}
+
+	if con, ok := ctx.Connection.(*config.RethinkDBConnection); ok {
+		h.Checkers["rethinkdb"] = health.RethinkDBChecker(con.GetSession())
+	} else if con, ok := ctx.Connection.(*config.DynamoDBConnection); ok {
+		h.Checkers["dynamodb"] = health.DynamoDBChecker(con.GetDB(), con.GetTable())
+	}
+
+	// Managers sharing a pool are probed once.
+	seen := map[*sql.DB]bool{}
+	for _, m := range managers {
+		var db *sql.DB
+		var driver string
+		switch m := m.(type) {
+		case *client.SQLManager:
+			db, driver = m.DB, m.Driver
+		case *jwk.SQLManager:
+			db, driver = m.DB, m.Driver
+		case *policy.SQLManager:
+			db, driver = m.DB, m.Driver
+		case *connection.SQLManager:
+			db, driver = m.DB, m.Driver
+		case *internal.FositeSQLStore:
+			db, driver = m.DB, m.Driver
+		default:
+			continue
+		}
+
+		if !seen[db] {
+			seen[db] = true
+			h.Checkers[driver] = health.SQLChecker(db)
+		}
+	}
+
+	h.SetRoutes(router)
+	return h
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/history"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+func newHistoryManager(c *config.Config) history.Manager {
+	ctx := c.Context()
+
+	switch con := ctx.Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return history.NewMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_key_history")
+		con.CreateTableIfNotExists("hydra_token_history")
+		return &history.RethinkManager{
+			Session:     con.GetSession(),
+			KeysTable:   r.Table("hydra_key_history"),
+			TokensTable: r.Table("hydra_token_history"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+}
+
+func newHistoryHandler(c *config.Config, router *httprouter.Router, manager history.Manager) *history.Handler {
+	ctx := c.Context()
+	h := &history.Handler{
+		H:         &herodot.JSON{},
+		W:         ctx.Warden,
+		Validator: &history.Validator{History: manager},
+	}
+	h.SetRoutes(router)
+	return h
+}
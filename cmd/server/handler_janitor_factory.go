@@ -0,0 +1,49 @@
+package server
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/janitor"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+// newJanitorHandler returns nil if the token store can not delete expired tokens.
+func newJanitorHandler(c *config.Config, router *httprouter.Router, store pkg.FositeStorer) *janitor.Handler {
+	ctx := c.Context()
+	flusher, ok := store.(pkg.TokenFlusher)
+	if !ok {
+		logrus.Info("The token store does not support deleting expired tokens, the janitor is disabled.")
+		return nil
+	}
+
+	j := &janitor.Janitor{
+		Store: flusher,
+		Lifespans: map[string]time.Duration{
+			pkg.TokenKindAuthorizeCode:        time.Hour,
+			pkg.TokenKindOpenIDConnectSession: time.Hour,
+			pkg.TokenKindAccessToken:          c.GetAccessTokenLifespan(),
+			pkg.TokenKindImplicitAccessToken:  c.GetAccessTokenLifespan(),
+			pkg.TokenKindRefreshToken:         c.GetRefreshTokenLifespan(),
+		},
+		BatchSize: c.JanitorBatchSize,
+		Interval:  c.GetJanitorInterval(),
+		Leader:    ctx.Leader,
+	}
+	if j.Interval > 0 {
+		j.Start(context.Background())
+	}
+
+	h := &janitor.Handler{
+		Janitor: j,
+		Jobs:    ctx.Jobs,
+		H:       &herodot.JSON{},
+		W:       ctx.Warden,
+	}
+	h.SetRoutes(router)
+	return h
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/job"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+func newJobManager(c *config.Config) job.Manager {
+	switch con := c.Context().Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return job.NewMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_jobs")
+		return &job.RethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_jobs"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+}
+
+func newJobHandler(c *config.Config, router *httprouter.Router, manager job.Manager) *job.Handler {
+	ctx := c.Context()
+	h := &job.Handler{
+		H:       &herodot.JSON{},
+		W:       ctx.Warden,
+		Manager: manager,
+	}
+	h.SetRoutes(router)
+	return h
+}
@@ -1,11 +1,14 @@
 package server
 
 import (
+	"time"
+
 	"github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/hydra/config"
 	"github.com/ory-am/hydra/herodot"
 	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/pkg"
 	"github.com/square/go-jose"
 	"golang.org/x/net/context"
 	r "gopkg.in/dancannon/gorethink.v2"
@@ -14,14 +17,31 @@ import (
 func newJWKHandler(c *config.Config, router *httprouter.Router) *jwk.Handler {
 	ctx := c.Context()
 	h := &jwk.Handler{
-		H: &herodot.JSON{},
-		W: ctx.Warden,
+		H:             &herodot.JSON{},
+		W:             ctx.Warden,
+		Preconditions: &pkg.Preconditions{Leases: ctx.Leases},
 	}
 	h.SetRoutes(router)
 
+	if c.KeyAuditLog != "" {
+		sink, err := jwk.NewAuditSink(c.KeyAuditLog)
+		if err != nil {
+			logrus.Fatalf("Could not set up key audit log: %s", err)
+		}
+		h.Audit = sink
+	}
+
+	validator, err := jwk.NewKeyValidator(c.KeyValidation)
+	if err != nil {
+		logrus.Fatalf("Could not set up key validation: %s", err)
+	}
+	h.Validator = validator
+
 	switch con := ctx.Connection.(type) {
 	case *config.MemoryConnection:
-		ctx.KeyManager = &jwk.MemoryManager{}
+		ctx.KeyManager = &jwk.MemoryManager{
+			Retention: c.GetKeyRetention(),
+		}
 		break
 	case *config.RethinkDBConnection:
 		con.CreateTableIfNotExists("hydra_json_web_keys")
@@ -32,6 +52,7 @@ func newJWKHandler(c *config.Config, router *httprouter.Router) *jwk.Handler {
 			Cipher: &jwk.AEAD{
 				Key: c.GetSystemSecret(),
 			},
+			Retention: c.GetKeyRetention(),
 		}
 		if err := m.ColdStart(); err != nil {
 			logrus.Fatalf("Could not fetch initial state: %s", err)
@@ -39,10 +60,33 @@ func newJWKHandler(c *config.Config, router *httprouter.Router) *jwk.Handler {
 		m.Watch(context.Background())
 		ctx.KeyManager = m
 		break
+	case *config.SQLConnection:
+		m := &jwk.SQLManager{
+			DB:     con.GetDatabase(),
+			Driver: con.GetDriver(),
+			Cipher: &jwk.AEAD{
+				Key: c.GetSystemSecret(),
+			},
+		}
+		if err := m.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create schemas: %s", err)
+		}
+		ctx.KeyManager = m
+		break
+	case *config.DynamoDBConnection:
+		ctx.KeyManager = &jwk.DynamoDBManager{
+			DB:    con.GetDB(),
+			Table: con.GetTable(),
+			Cipher: &jwk.AEAD{
+				Key: c.GetSystemSecret(),
+			},
+		}
+		break
 	default:
 		logrus.Fatalf("Unknown connection type.")
 	}
 
+	jwk.PurgeDeletedKeys(context.Background(), ctx.KeyManager, ctx.Leader, c.GetKeyRetention(), time.Minute)
 	h.Manager = ctx.KeyManager
 	return h
 }
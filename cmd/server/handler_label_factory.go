@@ -0,0 +1,62 @@
+package server
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/label"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+func newLabelManager(c *config.Config) label.Manager {
+	ctx := c.Context()
+
+	switch con := ctx.Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return label.NewMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_labels")
+		m := &label.RethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_labels"),
+		}
+		if err := m.ColdStart(); err != nil {
+			logrus.Fatalf("Could not fetch initial state: %s", err)
+		}
+		m.Watch(context.Background())
+		return m
+	default:
+		panic("Unknown connection type.")
+	}
+}
+
+func newLabelHandler(c *config.Config, router *httprouter.Router, manager label.Manager, clients client.Manager) *label.Handler {
+	ctx := c.Context()
+	h := &label.Handler{
+		H:       &herodot.JSON{},
+		W:       ctx.Warden,
+		Manager: manager,
+		Exists: func(kind, id string) error {
+			if kind == label.KindClients {
+				_, err := clients.GetClient(id)
+				return err
+			}
+
+			// Only lookups can fail in ladon's memory manager, which does not return pkg.ErrNotFound.
+			_, err := ctx.LadonManager.Get(id)
+			if _, ok := ctx.LadonManager.(*ladon.MemoryManager); ok && err != nil {
+				return errors.New(pkg.ErrNotFound)
+			}
+			return err
+		},
+	}
+
+	h.SetRoutes(router)
+	return h
+}
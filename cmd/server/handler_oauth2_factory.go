@@ -1,6 +1,7 @@
 package server
 
 import (
+	"strings"
 	"time"
 
 	"net/url"
@@ -19,18 +20,36 @@ import (
 	"github.com/ory-am/fosite/handler/oidc/hybrid"
 	oi "github.com/ory-am/fosite/handler/oidc/implicit"
 	os "github.com/ory-am/fosite/handler/oidc/strategy"
-	"github.com/ory-am/fosite/hash"
 	"github.com/ory-am/fosite/token/jwt"
 	"github.com/ory-am/hydra/client"
 	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/herodot"
 	"github.com/ory-am/hydra/internal"
 	"github.com/ory-am/hydra/jwk"
 	"github.com/ory-am/hydra/oauth2"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"github.com/pborman/uuid"
 	"golang.org/x/net/context"
 	r "gopkg.in/dancannon/gorethink.v2"
 )
 
+// newClaimsSource returns the source of additional ID token claims, or nil if ID_TOKEN_CLAIMS_SOURCE is not set.
+func newClaimsSource(c *config.Config) oauth2.ClaimsSource {
+	source := c.IDTokenClaimsSource
+	if source == "" {
+		return nil
+	} else if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return &oauth2.WebhookClaimsSource{URL: source, Secret: []byte(c.IDTokenClaimsSecret)}
+	}
+
+	claims, err := oauth2.GetClaimsSource(source)
+	if err != nil {
+		logrus.Fatalf("Could not load ID_TOKEN_CLAIMS_SOURCE: %s", err)
+	}
+	return claims
+}
+
 func injectFositeStore(c *config.Config, clients client.Manager) {
 	var ctx = c.Context()
 	var store pkg.FositeStorer
@@ -72,6 +91,30 @@ func injectFositeStore(c *config.Config, clients client.Manager) {
 		m.Watch(context.Background())
 		store = m
 		break
+	case *config.SQLConnection:
+		m := &internal.FositeSQLStore{
+			DB:                    con.GetDatabase(),
+			Driver:                con.GetDriver(),
+			Manager:               clients,
+			AuthorizeCodeLifespan: time.Hour,
+			AccessTokenLifespan:   c.GetAccessTokenLifespan(),
+			RefreshTokenLifespan:  c.GetRefreshTokenLifespan(),
+		}
+		if err := m.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create schemas: %s", err)
+		}
+		store = m
+		break
+	case *config.DynamoDBConnection:
+		store = &internal.FositeDynamoDBStore{
+			DB:                    con.GetDB(),
+			Table:                 con.GetTable(),
+			Manager:               clients,
+			AuthorizeCodeLifespan: time.Hour,
+			AccessTokenLifespan:   c.GetAccessTokenLifespan(),
+			RefreshTokenLifespan:  c.GetRefreshTokenLifespan(),
+		}
+		break
 	default:
 		panic("Unknown connection type.")
 	}
@@ -79,11 +122,157 @@ func injectFositeStore(c *config.Config, clients client.Manager) {
 	ctx.FositeStore = store
 }
 
-func newOAuth2Handler(c *config.Config, router *httprouter.Router, km jwk.Manager) *oauth2.Handler {
+// injectTokenQuota limits the number of access tokens of every client if TOKENS_QUOTA is set. It must wrap the store
+// itself, which counts the tokens.
+func injectTokenQuota(c *config.Config) {
+	var ctx = c.Context()
+	if c.TokensQuota <= 0 {
+		return
+	}
+
+	ctx.FositeStore = &internal.FositeQuotaStore{
+		FositeStorer: ctx.FositeStore,
+		Counter:      ctx.FositeStore.(pkg.TokenCounter),
+		Quota:        c.GetQuota("tokens", c.TokensQuota),
+	}
+}
+
+// hedgeMinDelay keeps fast backends from receiving every second read twice.
+const hedgeMinDelay = time.Millisecond * 5
+
+// injectHedgedReads hedges token reads if HEDGED_READS is set and the store reads from a remote backend.
+func injectHedgedReads(c *config.Config) {
+	var ctx = c.Context()
+	if !c.HedgedReads || !pkg.SupportsHedgedReads(ctx.FositeStore) {
+		return
+	}
+
+	logrus.Info("Hedging token reads.")
+	ctx.FositeStore = &internal.FositeHedgedStore{
+		FositeStorer: ctx.FositeStore,
+		Hedger:       &pkg.Hedger{MinDelay: hedgeMinDelay},
+	}
+}
+
+// injectTokenBudgets bounds the latency of token reads and writes if TOKEN_READ_BUDGET or TOKEN_WRITE_BUDGET is set.
+func injectTokenBudgets(c *config.Config) {
+	var ctx = c.Context()
+	read, write := c.GetTokenReadBudget(), c.GetTokenWriteBudget()
+	if read <= 0 && write <= 0 {
+		return
+	}
+
+	logrus.Infof("Token reads must complete within %s, token writes within %s.", read, write)
+	ctx.FositeStore = &internal.FositeBudgetStore{
+		FositeStorer: ctx.FositeStore,
+		ReadBudget:   read,
+		WriteBudget:  write,
+	}
+}
+
+// injectTracedStore starts a span for every token storage call if TRACING_PROVIDER is set. It wraps the hedged and
+// budgeted store, so that the spans show the latency the handlers observe.
+func injectTracedStore(c *config.Config) {
+	var ctx = c.Context()
+	if c.TracingProvider == "" {
+		return
+	}
+
+	var component string
+	switch con := ctx.Connection.(type) {
+	case *config.MemoryConnection:
+		component = "memory"
+	case *config.RethinkDBConnection:
+		component = "rethinkdb"
+	case *config.SQLConnection:
+		component = con.GetDriver()
+	case *config.DynamoDBConnection:
+		component = "dynamodb"
+	}
+	ctx.FositeStore = &internal.FositeTracedStore{
+		FositeStorer: ctx.FositeStore,
+		Component:    component,
+	}
+}
+
+// wrapKeyLookups hedges key lookups if HEDGED_READS is set and the manager reads from a remote backend, and
+// bounds their latency if KEY_LOOKUP_BUDGET is set.
+func wrapKeyLookups(c *config.Config, km jwk.Manager) jwk.Manager {
+	if c.HedgedReads && pkg.SupportsHedgedReads(km) {
+		km = &jwk.HedgedManager{Manager: km, Hedger: &pkg.Hedger{MinDelay: hedgeMinDelay}}
+	}
+
+	budget := c.GetKeyLookupBudget()
+	if budget <= 0 {
+		return km
+	}
+	return &jwk.BudgetManager{Manager: km, Budget: budget}
+}
+
+// injectAccessTokenStrategy issues access tokens in the format of ACCESS_TOKEN_STRATEGY and accepts the formats of
+// ACCESS_TOKEN_ACCEPTED_FORMATS. Refresh tokens and authorize codes remain opaque.
+func injectAccessTokenStrategy(c *config.Config, km jwk.Manager) {
+	var ctx = c.Context()
+	km = wrapKeyLookups(c, km)
+	var opaque = ctx.FositeStrategy
+
+	ctx.IssuerMigration = &oauth2.IssuerMigration{LegacyIssuers: c.GetLegacyIssuers()}
+	if len(ctx.IssuerMigration.LegacyIssuers) > 0 {
+		logrus.Infof("Accepting tokens of the legacy issuers %v.", ctx.IssuerMigration.LegacyIssuers)
+	}
+
+	formats := map[string]core.AccessTokenStrategy{}
+	for _, format := range c.GetAcceptedAccessTokenFormats() {
+		switch format {
+		case oauth2.TokenFormatOpaqueV1:
+			formats[format] = opaque
+		case oauth2.TokenFormatOpaqueV2:
+			formats[format] = &oauth2.OpaqueV2Strategy{AccessTokenStrategy: opaque}
+		case oauth2.TokenFormatJWT:
+			createAccessTokenKeysIfNotExist(km)
+			formats[format] = &oauth2.JWTAccessTokenStrategy{
+				CoreStrategy:        opaque,
+				KeyManager:          km,
+				Issuer:              c.Issuer,
+				AccessTokenLifespan: c.GetAccessTokenLifespan(),
+				IssuerMigration:     ctx.IssuerMigration,
+			}
+		}
+	}
+
+	logrus.Infof("Issuing %s access tokens, accepting %v.", c.GetAccessTokenFormat(), c.GetAcceptedAccessTokenFormats())
+	ctx.FositeStrategy = &oauth2.VersionedAccessTokenStrategy{
+		CoreStrategy: opaque,
+		Issue:        c.GetAccessTokenFormat(),
+		Formats:      formats,
+	}
+}
+
+func createAccessTokenKeysIfNotExist(km jwk.Manager) {
+	_, err := jwk.GetKeySetConsistent(km, oauth2.AccessTokenKeyName)
+	if errors.Is(err, pkg.ErrNotFound) {
+		logrus.Warnln("Could not find access token signing keys. Generating a new keypair...")
+		keys, err := new(jwk.RS256Generator).Generate(uuid.New())
+		pkg.Must(err, "Could not generate signing key for access tokens")
+		pkg.Must(km.AddKeySet(oauth2.AccessTokenKeyName, keys), "Could not store signing key for access tokens")
+		logrus.Warnln("Keypair generated.")
+	} else {
+		pkg.Must(err, "Could not fetch signing key for access tokens")
+	}
+}
+
+func newOAuth2Handler(c *config.Config, router *httprouter.Router, km jwk.Manager, clients client.Manager, settings client.SettingsManager, policies ladon.Warden, lockouts *client.Lockouts) *oauth2.Handler {
 	var ctx = c.Context()
 	var store = ctx.FositeStore
+	km = wrapKeyLookups(c, km)
+
+	// Endpoints which authenticate clients themselves refuse locked clients, the token endpoint checks lockouts
+	// around fosite.
+	if lockouts != nil {
+		clients = &client.LockingManager{Manager: clients, Lockouts: lockouts}
+	}
 
-	keys, err := km.GetKey(oauth2.OpenIDConnectKeyName, "private")
+	keys, err := jwk.GetKeyConsistent(km, oauth2.OpenIDConnectKeyName, "private")
 	if errors.Is(err, pkg.ErrNotFound) {
 		logrus.Warnln("Could not find OpenID Connect singing keys. Generating a new keypair...")
 		keys, err = new(jwk.RS256Generator).Generate("")
@@ -97,10 +286,18 @@ func newOAuth2Handler(c *config.Config, router *httprouter.Router, km jwk.Manage
 
 	rsaKey := jwk.MustRSAPrivate(jwk.First(keys.Keys))
 
-	idStrategy := &os.DefaultStrategy{
-		RS256JWTStrategy: &jwt.RS256JWTStrategy{
-			PrivateKey: rsaKey,
+	// ID tokens are signed with RS256 unless the client asked for another algorithm.
+	idStrategy := &oauth2.ClientIDTokenStrategy{
+		OpenIDConnectTokenStrategy: &os.DefaultStrategy{
+			RS256JWTStrategy: &jwt.RS256JWTStrategy{
+				PrivateKey: rsaKey,
+			},
 		},
+		DefaultKey:  &rsaKey.PublicKey,
+		KeyManager:  km,
+		Settings:    settings,
+		Claims:      newClaimsSource(c),
+		ScopeClaims: c.GetIDTokenScopeClaims(),
 	}
 
 	oauth2HandleHelper := &core.HandleHelper{
@@ -135,6 +332,37 @@ func newOAuth2Handler(c *config.Config, router *httprouter.Router, km jwk.Manage
 	consentURL, err := url.Parse(c.ConsentURL)
 	pkg.Must(err, "Could not parse consent url.")
 
+	scopeLifespans := c.GetRememberConsentScopeLifespans()
+	consentStrategy := &oauth2.DefaultConsentStrategy{
+		Issuer:                        c.Issuer,
+		KeyManager:                    km,
+		RememberConsentScopeLifespans: scopeLifespans,
+	}
+	pendingConsents := newPendingConsentManager(c)
+	rememberedConsents := newRememberedConsentManager(c)
+	loginSessions := newLoginSessionManager(c)
+
+	verificationURL, err := url.Parse(c.DeviceVerificationURL)
+	pkg.Must(err, "Could not parse device verification url.")
+
+	deviceHandler := &oauth2.DeviceHandler{
+		Manager:              newDeviceGrantManager(c),
+		Clients:              clients,
+		Consent:              consentStrategy,
+		ConsentURL:           *consentURL,
+		VerificationURL:      *verificationURL,
+		AccessTokenStrategy:  ctx.FositeStrategy,
+		AccessTokenStorage:   store,
+		AccessTokenLifespan:  c.GetAccessTokenLifespan(),
+		RefreshTokenStrategy: ctx.FositeStrategy,
+		RefreshTokenStorage:  store,
+		Lifespan:             c.GetDeviceCodeLifespan(),
+		H:                    &herodot.JSON{},
+		Settings:             settings,
+		ScopeStrategy:        c.GetScopeStrategy(),
+	}
+	deviceHandler.SetRoutes(router)
+
 	handler := &oauth2.Handler{
 		OAuth2: &fosite.Fosite{
 			Store:          store,
@@ -159,11 +387,14 @@ func newOAuth2Handler(c *config.Config, router *httprouter.Router, km jwk.Manage
 				&refresh.RefreshTokenGrantHandler{
 					AccessTokenStrategy:      ctx.FositeStrategy,
 					RefreshTokenStrategy:     ctx.FositeStrategy,
-					RefreshTokenGrantStorage: store,
+					RefreshTokenGrantStorage: &oauth2.ReuseDetectingStore{FositeStorer: store},
 					AccessTokenLifespan:      c.GetAccessTokenLifespan(),
 				},
-				&oc.ClientCredentialsGrantHandler{
-					HandleHelper: oauth2HandleHelper,
+				&oauth2.ClientCredentialsGrantHandler{
+					TokenEndpointHandler: &oc.ClientCredentialsGrantHandler{
+						HandleHelper: oauth2HandleHelper,
+					},
+					ScopeStrategy: c.GetScopeStrategy(),
 				},
 			},
 			AuthorizedRequestValidators: fosite.AuthorizedRequestValidators{
@@ -172,15 +403,217 @@ func newOAuth2Handler(c *config.Config, router *httprouter.Router, km jwk.Manage
 					AccessTokenStorage:  store,
 				},
 			},
-			Hasher: &hash.BCrypt{},
+			Hasher: ctx.Hasher,
 		},
-		Consent: &oauth2.DefaultConsentStrategy{
-			Issuer:     c.Issuer,
-			KeyManager: km,
+		Consent:                       consentStrategy,
+		ConsentURL:                    *consentURL,
+		PendingConsents:               pendingConsents,
+		RememberedConsents:            rememberedConsents,
+		RememberConsentMaxLifespan:    c.GetRememberConsentMaxLifespan(),
+		RememberConsentScopeLifespans: scopeLifespans,
+		LoginSessions:                 loginSessions,
+		LoginSessionLifespan:          c.GetLoginSessionLifespan(),
+		Lockouts:                      lockouts,
+		Settings:                      settings,
+		Audiences:                     &oauth2.LadonAudiencePolicy{Warden: policies},
+		ScopeStrategy:                 c.GetScopeStrategy(),
+		Device:                        deviceHandler,
+		Exchange: &oauth2.TokenExchangeHandler{
+			Clients: clients,
+			AccessTokens: &core.CoreValidator{
+				AccessTokenStrategy: ctx.FositeStrategy,
+				AccessTokenStorage:  store,
+			},
+			AccessTokenStrategy: ctx.FositeStrategy,
+			AccessTokenStorage:  store,
+			AccessTokenLifespan: c.GetAccessTokenLifespan(),
+			Policy:              &oauth2.LadonTokenExchangePolicy{Warden: policies},
+			Settings:            settings,
 		},
-		ConsentURL: *consentURL,
 	}
 
 	handler.SetRoutes(router)
+
+	baseURL, err := url.Parse(c.GetClusterURL())
+	pkg.Must(err, "Could not parse cluster url.")
+
+	discoveryHandler := &oauth2.DiscoveryHandler{
+		Issuer:        c.Issuer,
+		BaseURL:       *baseURL,
+		KeyManager:    km,
+		IDTokenKeySet: oauth2.OpenIDConnectKeyName,
+		Scopes:        []string{"core"},
+		GrantTypes: []string{
+			"authorization_code",
+			"implicit",
+			"refresh_token",
+			"client_credentials",
+			oauth2.DeviceCodeGrantType,
+			oauth2.TokenExchangeGrantType,
+		},
+		Registration: c.OpenClientRegistration,
+		Logout:       true,
+		H:            &herodot.JSON{},
+	}
+	for _, format := range c.GetAcceptedAccessTokenFormats() {
+		if format == oauth2.TokenFormatJWT {
+			discoveryHandler.KeySets = []string{oauth2.AccessTokenKeyName}
+		}
+	}
+	if c.MTLSBaseURL != "" {
+		mtlsBaseURL, err := url.Parse(c.MTLSBaseURL)
+		pkg.Must(err, "Could not parse mtls base url.")
+		discoveryHandler.MTLSBaseURL = mtlsBaseURL
+	}
+	discoveryHandler.SetRoutes(router)
+
+	pendingHandler := &oauth2.PendingConsentHandler{
+		Manager:    pendingConsents,
+		Consent:    consentStrategy,
+		Lifespan:   c.GetPendingConsentLifespan(),
+		ConsentURL: *consentURL,
+		H:          &herodot.JSON{},
+		W:          ctx.Warden,
+	}
+	pendingHandler.SetRoutes(router)
+
+	rememberedHandler := &oauth2.RememberedConsentHandler{
+		Manager: rememberedConsents,
+		H:       &herodot.JSON{},
+		W:       ctx.Warden,
+	}
+	rememberedHandler.SetRoutes(router)
+
+	logoutURL, err := url.Parse(c.LogoutRedirectURL)
+	pkg.Must(err, "Could not parse logout redirect url.")
+
+	logoutHandler := &oauth2.LogoutHandler{
+		Issuer:     c.Issuer,
+		Sessions:   loginSessions,
+		Settings:   settings,
+		KeyManager: km,
+		LogoutURL:  *logoutURL,
+		H:          &herodot.JSON{},
+	}
+	logoutHandler.SetRoutes(router)
+
+	accountHandler := &oauth2.AccountHandler{
+		LoginSessions:      loginSessions,
+		RememberedConsents: rememberedConsents,
+		Clients:            clients,
+		Tokens:             ctx.FositeStore,
+		Logout:             logoutHandler,
+		H:                  &herodot.JSON{},
+		W:                  ctx.Warden,
+	}
+	accountHandler.SetRoutes(router)
+
+	introspectionHandler := &oauth2.IntrospectionHandler{
+		AccessTokens: &core.CoreValidator{
+			AccessTokenStrategy: ctx.FositeStrategy,
+			AccessTokenStorage:  store,
+		},
+		RefreshTokenStrategy: ctx.FositeStrategy,
+		RefreshTokenStorage:  store,
+		AccessTokenLifespan:  c.GetAccessTokenLifespan(),
+		Issuer:               c.Issuer,
+		H:                    &herodot.Negotiating{},
+		W:                    ctx.Warden,
+	}
+	introspectionHandler.SetRoutes(router)
+	handler.Introspection = introspectionHandler
+
+	revocationHandler := &oauth2.RevocationHandler{
+		Clients:              clients,
+		AccessTokenStrategy:  ctx.FositeStrategy,
+		RefreshTokenStrategy: ctx.FositeStrategy,
+		Storage:              store,
+		H:                    &herodot.JSON{},
+	}
+	revocationHandler.SetRoutes(router)
+
+	tokensHandler := &oauth2.TokensHandler{
+		Storage: store,
+		Jobs:    ctx.Jobs,
+		H:       &herodot.JSON{},
+		W:       ctx.Warden,
+	}
+	tokensHandler.SetRoutes(router)
+
+	issuerMigrationHandler := &oauth2.IssuerMigrationHandler{
+		Issuer:    c.Issuer,
+		Migration: ctx.IssuerMigration,
+		Clients:   clients,
+		H:         &herodot.JSON{},
+		W:         ctx.Warden,
+	}
+	issuerMigrationHandler.SetRoutes(router)
 	return handler
 }
+
+func newPendingConsentManager(c *config.Config) oauth2.PendingConsentManager {
+	switch con := c.Context().Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return oauth2.NewPendingConsentMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_oauth2_pending_consent")
+		return &oauth2.PendingConsentRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_oauth2_pending_consent"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+}
+
+// newLoginSessionManager limits the number of login sessions of every subject if SESSIONS_QUOTA is set.
+func newLoginSessionManager(c *config.Config) oauth2.LoginSessionManager {
+	var m oauth2.LoginSessionManager
+	switch con := c.Context().Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		m = oauth2.NewLoginSessionMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_oauth2_login_session")
+		m = &oauth2.LoginSessionRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_oauth2_login_session"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+
+	if c.SessionsQuota <= 0 {
+		return m
+	}
+	return &oauth2.LoginSessionQuotaManager{LoginSessionManager: m, Quota: c.GetQuota("sessions", c.SessionsQuota)}
+}
+
+func newRememberedConsentManager(c *config.Config) oauth2.RememberedConsentManager {
+	switch con := c.Context().Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return oauth2.NewRememberedConsentMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_oauth2_remembered_consent")
+		return &oauth2.RememberedConsentRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_oauth2_remembered_consent"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+}
+
+func newDeviceGrantManager(c *config.Config) oauth2.DeviceGrantManager {
+	switch con := c.Context().Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return oauth2.NewDeviceGrantMemoryManager()
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_oauth2_device_grants")
+		return &oauth2.DeviceGrantRethinkManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_oauth2_device_grants"),
+		}
+	default:
+		panic("Unknown connection type.")
+	}
+}
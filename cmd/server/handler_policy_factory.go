@@ -1,19 +1,59 @@
 package server
 
 import (
+	"github.com/Sirupsen/logrus"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/group"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/label"
+	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/hydra/policy"
+	"github.com/ory-am/ladon"
 )
 
-func newPolicyHandler(c *config.Config, router *httprouter.Router) *policy.Handler {
+func newPolicyHandler(c *config.Config, router *httprouter.Router, labels label.Manager) *policy.Handler {
 	ctx := c.Context()
 	h := &policy.Handler{
-		H:       &herodot.JSON{},
-		W:       ctx.Warden,
-		Manager: ctx.LadonManager,
+		H:             &herodot.JSON{},
+		W:             ctx.Warden,
+		Manager:       ctx.LadonManager,
+		Labels:        labels,
+		Preconditions: &pkg.Preconditions{Leases: ctx.Leases},
 	}
 	h.SetRoutes(router)
 	return h
 }
+
+// newDecisionCache caches the decisions of w if WARDEN_DECISION_CACHE_TTL is not zero. The cache is dropped through
+// the changefeeds of the policies, groups and labels, so it is only used with RethinkDB; in memory decisions are
+// cheap anyway.
+func newDecisionCache(c *config.Config, w ladon.Warden, groups group.Manager, labels label.Manager) ladon.Warden {
+	ctx := c.Context()
+	ttl := c.GetWardenDecisionCacheTTL()
+	if ttl <= 0 {
+		return w
+	}
+
+	switch ctx.Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		return w
+	case *config.RethinkDBConnection:
+		logrus.Infof("Caching warden decisions for %s.", ttl)
+		cache := &policy.DecisionCache{Warden: w, TTL: ttl}
+
+		// The cache subscribes after the managers, so it is dropped once they applied a change.
+		if m, ok := ctx.LadonManager.(*policy.RethinkManager); ok {
+			cache.Watch(m.Feed)
+		}
+		if m, ok := groups.(*group.RethinkManager); ok {
+			cache.Watch(m.Feed)
+		}
+		if m, ok := labels.(*label.RethinkManager); ok {
+			cache.Watch(m.Feed)
+		}
+		return cache
+	default:
+		panic("Unknown connection type.")
+	}
+}
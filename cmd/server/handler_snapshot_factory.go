@@ -0,0 +1,35 @@
+package server
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/policy"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+// startSnapshots restores the in-memory managers from MEMORY_SNAPSHOT_PATH and saves them there periodically.
+// Tokens are not part of the snapshot, clients have to request new ones after a restart. It does nothing if a
+// database is used.
+func startSnapshots(c *config.Config, managers map[string]interface{}) {
+	ctx := c.Context()
+	if _, ok := ctx.Connection.(*config.MemoryConnection); !ok || c.MemorySnapshotPath == "" {
+		return
+	}
+
+	f := &pkg.SnapshotFile{Path: c.MemorySnapshotPath, Snapshotters: map[string]pkg.Snapshotter{}}
+	for name, m := range managers {
+		if s, ok := m.(pkg.Snapshotter); ok {
+			f.Snapshotters[name] = s
+		}
+	}
+	if m, ok := ctx.LadonManager.(*ladon.MemoryManager); ok {
+		f.Snapshotters["policies"] = &policy.MemorySnapshotter{Manager: m}
+	}
+
+	err := f.Load()
+	pkg.Must(err, "Could not load snapshot: %s", err)
+	logrus.Infof("Restored in-memory state from %s, saving it every %s.", f.Path, c.GetMemorySnapshotInterval())
+	f.Start(context.Background(), c.GetMemorySnapshotInterval())
+}
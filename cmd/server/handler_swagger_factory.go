@@ -0,0 +1,49 @@
+package server
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/connection"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/group"
+	"github.com/ory-am/hydra/health"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/history"
+	"github.com/ory-am/hydra/janitor"
+	"github.com/ory-am/hydra/job"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/label"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/policy"
+	"github.com/ory-am/hydra/swagger"
+	"github.com/ory-am/hydra/warden"
+)
+
+// newSwaggerHandler describes the routes of router, so it has to be created after all other handlers.
+func newSwaggerHandler(c *config.Config, router *httprouter.Router) *swagger.Handler {
+	doc := swagger.New(c.Issuer)
+	doc.Add(swagger.Routed(router,
+		client.Operations,
+		config.Operations,
+		connection.Operations,
+		events.Operations,
+		group.Operations,
+		health.Operations,
+		history.Operations,
+		janitor.Operations,
+		job.Operations,
+		jwk.Operations,
+		label.Operations,
+		oauth2.Operations,
+		policy.Operations,
+		warden.Operations,
+	)...)
+
+	h := &swagger.Handler{
+		Document: doc,
+		H:        &herodot.JSON{},
+	}
+	h.SetRoutes(router)
+	return h
+}
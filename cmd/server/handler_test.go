@@ -4,7 +4,11 @@ import (
 	"testing"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/client"
 	"github.com/ory-am/hydra/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStart(t *testing.T) {
@@ -12,3 +16,37 @@ func TestStart(t *testing.T) {
 	h := &Handler{}
 	h.Start(&config.Config{}, router)
 }
+
+func TestCreateRootIfNewInstall(t *testing.T) {
+	for k, tc := range []struct {
+		credentials string
+		id          string
+		secret      string
+	}{
+		{credentials: "root:sec:ret", id: "root", secret: "sec:ret"},
+		{},
+	} {
+		c := &config.Config{ForceRootClientCredentials: tc.credentials, BCryptWorkFactor: 4}
+		clients := &client.MemoryManager{Clients: map[string]*fosite.DefaultClient{}, Hasher: c.Context().Hasher}
+		h := &Handler{Clients: &client.Handler{Manager: clients}}
+
+		h.createRootIfNewInstall(c)
+		require.Len(t, clients.Clients, 1, "Case %d", k)
+		if tc.id != "" {
+			assert.Equal(t, tc.id, c.ClientID, "Case %d", k)
+			assert.Equal(t, tc.secret, c.ClientSecret, "Case %d", k)
+		}
+
+		root, err := clients.Authenticate(c.ClientID, []byte(c.ClientSecret))
+		require.Nil(t, err, "Case %d", k)
+		assert.Equal(t, []string{"hydra", "core"}, root.GrantedScopes, "Case %d", k)
+
+		policies, err := c.Context().LadonManager.FindPoliciesForSubject(root.ID)
+		require.Nil(t, err, "Case %d", k)
+		assert.Len(t, policies, 1, "Case %d", k)
+
+		// The root client is only created on a fresh install.
+		h.createRootIfNewInstall(c)
+		assert.Len(t, clients.Clients, 1, "Case %d", k)
+	}
+}
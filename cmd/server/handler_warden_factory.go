@@ -0,0 +1,58 @@
+package server
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/config"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/warden"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+func newWardenHandler(c *config.Config, router *httprouter.Router, ladonWarden ladon.Warden) *warden.WardenHandler {
+	ctx := c.Context()
+	h := &warden.WardenHandler{
+		H:      &herodot.Negotiating{},
+		Warden: ctx.Warden,
+		Ladon:  ladonWarden,
+	}
+	h.SetRoutes(router)
+
+	switch con := ctx.Connection.(type) {
+	case *config.MemoryConnection, *config.SQLConnection, *config.DynamoDBConnection:
+		h.Templates = warden.NewTemplateMemoryManager()
+		h.ResourceServers = warden.NewResourceServerMemoryManager()
+		break
+	case *config.RethinkDBConnection:
+		con.CreateTableIfNotExists("hydra_warden_templates")
+		m := &warden.TemplateRethinkManager{
+			Session:   con.GetSession(),
+			Table:     r.Table("hydra_warden_templates"),
+			Templates: map[string]*warden.ResourceTemplate{},
+		}
+		if err := m.ColdStart(); err != nil {
+			logrus.Fatalf("Could not fetch initial state: %s", err)
+		}
+		m.Watch(context.Background())
+		h.Templates = m
+
+		con.CreateTableIfNotExists("hydra_warden_resource_servers")
+		rs := &warden.ResourceServerRethinkManager{
+			Session:         con.GetSession(),
+			Table:           r.Table("hydra_warden_resource_servers"),
+			ResourceServers: map[string]*warden.ResourceServer{},
+		}
+		if err := rs.ColdStart(); err != nil {
+			logrus.Fatalf("Could not fetch initial state: %s", err)
+		}
+		rs.Watch(context.Background())
+		h.ResourceServers = rs
+		break
+	default:
+		logrus.Fatalf("Unknown connection type.")
+	}
+
+	return h
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ory-am/hydra/health"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/swagger"
+)
+
+var (
+	// PublicPaths are served by the public listener. These are the endpoints clients and browsers talk to.
+	PublicPaths = []string{"/oauth2/", "/.well-known/"}
+
+	// AdminPaths below PublicPaths are administrative nonetheless and served by the admin listener.
+	AdminPaths = []string{
+		oauth2.TokensHandlerPath,
+		oauth2.PendingConsentHandlerPath,
+		oauth2.RememberedConsentHandlerPath,
+		oauth2.IssuerMigrationHandlerPath,
+	}
+
+	// sharedPaths are served by both listeners, so that each of them can be health checked and describes the API.
+	sharedPaths = []string{health.AliveCheckPath, health.ReadyCheckPath, swagger.SpecHandlerPath}
+)
+
+// IsPublicPath tells whether a path belongs to the public endpoints. The longest matching prefix of PublicPaths and
+// AdminPaths decides, paths which match neither are administrative.
+func IsPublicPath(path string) bool {
+	public, admin := longestPrefix(path, PublicPaths), longestPrefix(path, AdminPaths)
+	return public > admin
+}
+
+func longestPrefix(path string, prefixes []string) int {
+	length := -1
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > length {
+			length = len(prefix)
+		}
+	}
+	return length
+}
+
+// Listener serves the endpoints of one listener and answers all others with 404, so that the admin API is not
+// reachable through the public listener and the other way around.
+type Listener struct {
+	Public bool
+}
+
+func (l *Listener) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsPublicPath(r.URL.Path) != l.Public && longestPrefix(r.URL.Path, sharedPaths) < 0 {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
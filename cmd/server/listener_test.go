@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListener(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	public := (&Listener{Public: true}).Wrap(ok)
+	admin := (&Listener{Public: false}).Wrap(ok)
+
+	for path, isPublic := range map[string]bool{
+		"/oauth2/token":                      true,
+		"/.well-known/jwks.json":             true,
+		"/oauth2/tokens":                     false,
+		"/oauth2/consent/pending/foo":        false,
+		"/clients":                           false,
+		"/keys/hydra.openid.id-token":        false,
+		"/warden/token/allowed":              false,
+		"/oauth2/consent/remembered/subject": false,
+	} {
+		assert.Equal(t, isPublic, IsPublicPath(path), path)
+
+		w := httptest.NewRecorder()
+		public.ServeHTTP(w, request(path))
+		assert.Equal(t, isPublic, w.Code == http.StatusOK, "public %s", path)
+
+		w = httptest.NewRecorder()
+		admin.ServeHTTP(w, request(path))
+		assert.Equal(t, !isPublic, w.Code == http.StatusOK, "admin %s", path)
+	}
+
+	for _, h := range []http.Handler{public, admin} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, request("/health/alive"))
+		assert.Equal(t, http.StatusOK, w.Code, "health checks are served by both listeners")
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, request("/swagger.json"))
+		assert.Equal(t, http.StatusOK, w.Code, "the API description is served by both listeners")
+	}
+}
+
+func request(path string) *http.Request {
+	r, _ := http.NewRequest("GET", path, nil)
+	return r
+}
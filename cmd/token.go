@@ -12,4 +12,17 @@ var tokenCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(tokenCmd)
+	tokenCmd.PersistentFlags().String("client-id", "", "Use this client instead of the CLI's credentials")
+	tokenCmd.PersistentFlags().String("client-secret", "", "The secret of the client given by --client-id")
+	tokenCmd.PersistentFlags().StringSlice("scopes", []string{"core", "hydra"}, "The scopes to request")
+}
+
+// tokenClientCredentials returns the client of --client-id and --client-secret, or the CLI's credentials if
+// --client-id is not set.
+func tokenClientCredentials(cmd *cobra.Command) (id, secret string) {
+	if id, _ = cmd.Flags().GetString("client-id"); id == "" {
+		return c.ClientID, c.ClientSecret
+	}
+	secret, _ = cmd.Flags().GetString("client-secret")
+	return id, secret
 }
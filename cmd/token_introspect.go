@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var tokenIntrospectCmd = &cobra.Command{
+	Use:   "introspect <token>",
+	Short: "Introspect an access or refresh token",
+	Long: `Prints whether the token is active along with its claims. The claims of JSON Web Tokens are decoded as well,
+without verifying their signature. The CLI's credentials need to be allowed to introspect tokens.
+
+Example:
+  hydra token introspect --format json <token>`,
+	Run: cmdHandler.Tokens.IntrospectToken,
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenIntrospectCmd)
+	tokenIntrospectCmd.Flags().String("token-type-hint", "", "access_token or refresh_token")
+	tokenIntrospectCmd.Flags().String("format", "text", "The output format, text or json")
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token>",
+	Short: "Revoke an access or refresh token",
+	Long: `Tokens can only be revoked by the client they were issued to. Set --client-id and --client-secret unless the
+token was issued to the CLI's client. Revoking a refresh token revokes the access tokens of the same grant.`,
+	Run: cmdHandler.Tokens.RevokeToken,
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	tokenRevokeCmd.Flags().String("token-type-hint", "", "access_token or refresh_token")
+}
@@ -16,8 +16,11 @@ import (
 // tokenSelfCmd represents the self command
 var tokenSelfCmd = &cobra.Command{
 	Use:   "client",
-	Short: "Generate an OAuth2 token the client grant type",
-	Long:  "This command uses the CLI's credentials to create an access token.",
+	Short: "Generate an OAuth2 token using the client credentials grant",
+	Long: `This command uses the CLI's credentials, or the client given by --client-id, to create an access token.
+
+Example:
+  hydra token client --client-id my-client --client-secret secret --scopes photos.read`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
 		if ok, _ := cmd.Flags().GetBool("skip-tls-verify"); ok {
@@ -27,14 +30,13 @@ var tokenSelfCmd = &cobra.Command{
 			}})
 		}
 
+		id, secret := tokenClientCredentials(cmd)
+		scopes, _ := cmd.Flags().GetStringSlice("scopes")
 		oauthConfig := clientcredentials.Config{
-			ClientID:     c.ClientID,
-			ClientSecret: c.ClientSecret,
+			ClientID:     id,
+			ClientSecret: secret,
 			TokenURL:     pkg.JoinURLStrings(c.ClusterURL, "/oauth2/token"),
-			Scopes: []string{
-				"core",
-				"hydra",
-			},
+			Scopes:       scopes,
 		}
 
 		t, err := oauthConfig.Token(ctx)
@@ -20,6 +20,12 @@ import (
 var tokenUserCmd = &cobra.Command{
 	Use:   "user",
 	Short: "Generate an OAuth2 token using the code flow",
+	Long: `This command opens the authorization url in a browser and listens for the redirect on
+http://localhost:<port>/callback, which must be a redirect url of the client. The code is exchanged for tokens which
+are printed.
+
+Example:
+  hydra token user --client-id my-app --client-secret secret --scopes openid,offline --port 4446`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
 		if ok, _ := cmd.Flags().GetBool("skip-tls-verify"); ok {
@@ -29,14 +35,19 @@ var tokenUserCmd = &cobra.Command{
 			}})
 		}
 
+		id, secret := tokenClientCredentials(cmd)
+		scopes, _ := cmd.Flags().GetStringSlice("scopes")
+		port, _ := cmd.Flags().GetInt("port")
+		callback := fmt.Sprintf("http://localhost:%d/callback", port)
 		conf := oauth2.Config{
-			ClientID:     c.ClientID,
-			ClientSecret: c.ClientSecret,
+			ClientID:     id,
+			ClientSecret: secret,
 			Endpoint: oauth2.Endpoint{
 				TokenURL: pkg.JoinURLStrings(c.ClusterURL, "/oauth2/token"),
 				AuthURL:  pkg.JoinURLStrings(c.ClusterURL, "/oauth2/auth"),
 			},
-			Scopes: []string{"core", "hydra"},
+			RedirectURL: callback,
+			Scopes:      scopes,
 		}
 
 		state, err := sequence.RuneSequence(24, []rune("abcdefghijklmnopqrstuvwxyz"))
@@ -52,12 +63,12 @@ var tokenUserCmd = &cobra.Command{
 		}
 		fmt.Printf("If your browser does not open automatically, navigate to: %s\n", location)
 
-		fmt.Println("Setting up callback listener on http://localhost:4445/callback")
+		fmt.Printf("Setting up callback listener on %s\n", callback)
 		fmt.Println("Press ctrl + c on Linux / Windows or cmd + c on OSX to end the process.")
 
 		srv := &graceful.Server{
 			Timeout: 2 * time.Second,
-			Server:  &http.Server{Addr: "localhost:4445"},
+			Server:  &http.Server{Addr: fmt.Sprintf("localhost:%d", port)},
 		}
 		r := httprouter.New()
 		r.GET("/callback", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -106,4 +117,5 @@ var tokenUserCmd = &cobra.Command{
 func init() {
 	tokenCmd.AddCommand(tokenUserCmd)
 	tokenUserCmd.Flags().Bool("no-open", false, "Do not open a browser window with the authorize url")
+	tokenUserCmd.Flags().Int("port", 4445, "The port of the callback listener")
 }
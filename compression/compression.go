@@ -0,0 +1,187 @@
+package compression
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultMinSize is the default response size in bytes below which responses are sent uncompressed. Compressing
+// small responses costs more than it saves.
+const DefaultMinSize = 1024
+
+// encodings are the supported content codings in order of preference.
+var encodings = []string{"gzip", "deflate"}
+
+// Middleware compresses responses with gzip or deflate if the client accepts one of them and the response is
+// large enough and of a compressible type, for example the JSON of key sets, client lists and policy lists.
+type Middleware struct {
+	// MinSize defaults to DefaultMinSize.
+	MinSize int
+
+	// Level is the compression level, it defaults to gzip.DefaultCompression.
+	Level int
+}
+
+// Wrap compresses the responses of h.
+func (m *Middleware) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := Negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == "HEAD" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: m.minSize(), level: m.level(), status: http.StatusOK}
+		defer cw.Close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+func (m *Middleware) minSize() int {
+	if m.MinSize <= 0 {
+		return DefaultMinSize
+	}
+	return m.MinSize
+}
+
+func (m *Middleware) level() int {
+	if m.Level == 0 {
+		return gzip.DefaultCompression
+	}
+	return m.Level
+}
+
+// Negotiate returns the preferred supported content coding of an Accept-Encoding header or an empty string if the
+// client does not accept any of them.
+func Negotiate(acceptEncoding string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qualities[coding] = q
+	}
+
+	var best string
+	var bestQ float64
+	for _, coding := range encodings {
+		q, ok := qualities[coding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+func compressible(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.HasSuffix(contentType, "+json") ||
+		contentType == "application/json" ||
+		contentType == "application/javascript" ||
+		contentType == "application/xml"
+}
+
+// compressWriter buffers the response until it is known whether it reaches the minimum size.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	level    int
+	status   int
+
+	buf     []byte
+	decided bool
+	writer  io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.writer != nil {
+			return w.writer.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// start sends the headers and the buffered body, compressed if compress is true and the response allows it.
+func (w *compressWriter) start(compress bool) error {
+	w.decided = true
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if compress && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		var err error
+		if w.encoding == "gzip" {
+			w.writer, err = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+		} else {
+			w.writer, err = flate.NewWriter(w.ResponseWriter, w.level)
+		}
+		if err != nil {
+			return err
+		}
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// Close sends responses which stayed below the minimum size and flushes the compressor.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.start(false); err != nil {
+			return err
+		}
+	}
+
+	if w.writer != nil {
+		return w.writer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package compression_test
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/ory-am/hydra/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	for k, c := range []struct {
+		header   string
+		expected string
+	}{
+		{header: "", expected: ""},
+		{header: "gzip", expected: "gzip"},
+		{header: "deflate, gzip", expected: "gzip"},
+		{header: "gzip;q=0.5, deflate", expected: "deflate"},
+		{header: "gzip;q=0, deflate;q=0", expected: ""},
+		{header: "br, *", expected: "gzip"},
+		{header: "*;q=0, deflate", expected: "deflate"},
+		{header: "identity", expected: ""},
+	} {
+		assert.Equal(t, c.expected, Negotiate(c.header), "Case %d", k)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	large := "[" + strings.Repeat(`{"id":"foo"},`, 200) + `{"id":"bar"}]`
+	m := &Middleware{MinSize: 512}
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(large[:100]))
+			w.Write([]byte(large[100:]))
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"foo"}`))
+		case "/binary":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(large))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("GET", "http://localhost"+path, nil)
+		require.Nil(t, err)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		return w
+	}
+
+	w := get("/large", "gzip")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	gr, err := gzip.NewReader(w.Body)
+	require.Nil(t, err)
+	body, err := ioutil.ReadAll(gr)
+	require.Nil(t, err)
+	assert.Equal(t, large, string(body))
+
+	w = get("/large", "deflate")
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	body, err = ioutil.ReadAll(flate.NewReader(w.Body))
+	require.Nil(t, err)
+	assert.Equal(t, large, string(body))
+
+	w = get("/large", "")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, w.Body.String())
+
+	w = get("/small", "gzip")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"id":"foo"}`, w.Body.String())
+
+	w = get("/binary", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, w.Body.String())
+
+	w = get("/empty", "gzip")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
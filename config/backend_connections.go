@@ -1,12 +1,19 @@
 package config
 
 import (
+	"database/sql"
+	"fmt"
 	"net/url"
 
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/go-errors/errors"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	"github.com/ory-am/hydra/pkg"
 	r "gopkg.in/dancannon/gorethink.v2"
 )
@@ -72,3 +79,109 @@ func (c *RethinkDBConnection) CreateTableIfNotExists(table string) {
 		logrus.Fatalf("Could not create table: %s", err)
 	}
 }
+
+// SQLConnection connects to PostgreSQL or MySQL, the scheme of the url is the name of the driver.
+type SQLConnection struct {
+	db  *sql.DB
+	URL *url.URL
+}
+
+func (c *SQLConnection) GetDatabase() *sql.DB {
+	if c.db != nil {
+		return c.db
+	}
+
+	driver, dsn, err := SQLDataSource(c.URL)
+	if err != nil {
+		logrus.Fatalf("Could not parse DATABASE_URL: %s", err)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		logrus.Fatalf("Could not open database: %s", err)
+	}
+
+	if err := pkg.Retry(time.Second*15, time.Minute*2, func() error {
+		logrus.Infof("Connecting with %s: %s", driver, c.URL.Host)
+		if err := db.Ping(); err != nil {
+			return errors.Errorf("Could not connect to %s: %s", driver, err)
+		}
+		logrus.Infof("Connected to %s!", driver)
+		return nil
+	}); err != nil {
+		logrus.Fatalf("Could not connect to %s: %s", driver, err)
+	}
+
+	c.db = db
+	return c.db
+}
+
+func (c *SQLConnection) GetDriver() string {
+	return c.URL.Scheme
+}
+
+// SQLDataSource converts a database url to the name of the driver and the data source name the driver expects.
+func SQLDataSource(u *url.URL) (string, string, error) {
+	switch u.Scheme {
+	case "postgres":
+		return "postgres", u.String(), nil
+	case "mysql":
+		// The mysql driver does not accept urls, and the token store needs timestamps to be parsed.
+		q := u.Query()
+		q.Set("parseTime", "true")
+		dsn := fmt.Sprintf("tcp(%s)%s?%s", u.Host, u.Path, q.Encode())
+		if u.User != nil {
+			user := u.User.Username()
+			if p, ok := u.User.Password(); ok {
+				user += ":" + p
+			}
+			dsn = user + "@" + dsn
+		}
+		return "mysql", dsn, nil
+	}
+	return "", "", errors.Errorf("Database url scheme %s is not supported", u.Scheme)
+}
+
+// DynamoDBConnection connects to DynamoDB, the url is dynamodb://region/table. DynamoDB Local or another endpoint is
+// selected with the endpoint parameter, for example dynamodb://us-east-1/hydra?endpoint=http://localhost:8000.
+// Credentials are taken from the environment, the shared credentials file or the role of the instance.
+type DynamoDBConnection struct {
+	db  *dynamodb.DynamoDB
+	URL *url.URL
+}
+
+func (c *DynamoDBConnection) GetDB() *dynamodb.DynamoDB {
+	if c.db != nil {
+		return c.db
+	}
+
+	awsConfig := &aws.Config{Region: aws.String(c.URL.Host)}
+	if endpoint := c.URL.Query().Get("endpoint"); endpoint != "" {
+		awsConfig.Endpoint = aws.String(endpoint)
+	}
+
+	s, err := session.NewSession(awsConfig)
+	if err != nil {
+		logrus.Fatalf("Could not connect to DynamoDB: %s", err)
+	}
+	c.db = dynamodb.New(s)
+	return c.db
+}
+
+// GetTable returns the name of the table which the DynamoDB backends share.
+func (c *DynamoDBConnection) GetTable() string {
+	if len(c.URL.Path) > 1 {
+		return c.URL.Path[1:]
+	}
+	return "hydra"
+}
+
+// CreateTableIfNotExists creates the shared table, see pkg.CreateDynamoDBTable.
+func (c *DynamoDBConnection) CreateTableIfNotExists() {
+	if err := pkg.Retry(time.Second*15, time.Minute*2, func() error {
+		logrus.Infof("Connecting with DynamoDB: %s (%s)", c.URL.Host, c.GetTable())
+		return pkg.CreateDynamoDBTable(c.GetDB(), c.GetTable())
+	}); err != nil {
+		logrus.Fatalf("Could not create table: %s", err)
+	}
+}
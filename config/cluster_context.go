@@ -0,0 +1,113 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/spf13/cobra"
+)
+
+// ClusterContext holds the cluster url and the credentials the CLI uses to talk to one cluster. The CLI keeps a
+// context per cluster and switches between them with `hydra connect use`, like kubectl does.
+type ClusterContext struct {
+	ClusterURL   string `mapstructure:"cluster_url" yaml:"cluster_url"`
+	ClientID     string `mapstructure:"client_id" yaml:"client_id"`
+	ClientSecret string `mapstructure:"client_secret" yaml:"client_secret"`
+	CABundle     string `mapstructure:"ca_bundle" yaml:"ca_bundle,omitempty"`
+}
+
+// UseContext points the CLI at the cluster of a context.
+func (c *Config) UseContext(name string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return errors.Errorf("Context %s does not exist", name)
+	}
+
+	c.ClusterURL = ctx.ClusterURL
+	c.ClientID = ctx.ClientID
+	c.ClientSecret = ctx.ClientSecret
+	c.CABundle = ctx.CABundle
+	c.CurrentContext = name
+	c.cluster = nil
+	c.oauth2Client = nil
+	return nil
+}
+
+// SaveContext stores the cluster url and the credentials the CLI currently uses as a context and makes it the
+// current context.
+func (c *Config) SaveContext(name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.Contexts == nil {
+		c.Contexts = map[string]*ClusterContext{}
+	}
+	c.Contexts[name] = &ClusterContext{
+		ClusterURL:   c.ClusterURL,
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		CABundle:     c.CABundle,
+	}
+	c.CurrentContext = name
+}
+
+// GetContextNames returns the names of all contexts in lexical order.
+func (c *Config) GetContextNames() []string {
+	c.Lock()
+	defer c.Unlock()
+
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadCABundle reads a PEM encoded CA bundle.
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	in, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(in) {
+		return nil, errors.Errorf("No PEM encoded certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// HTTPClient returns a client without credentials which trusts the certificate authorities of CABundle, if set, or
+// skips TLS verification if --skip-tls-verify is set.
+func (c *Config) HTTPClient(cmd *cobra.Command) *http.Client {
+	c.Lock()
+	bundle := c.CABundle
+	c.Unlock()
+	return httpClient(cmd, bundle)
+}
+
+func httpClient(cmd *cobra.Command, bundle string) *http.Client {
+	tlsConfig := &tls.Config{}
+	if ok, _ := cmd.Flags().GetBool("skip-tls-verify"); ok {
+		fmt.Println("Warning: Skipping TLS Certificate Verification.")
+		tlsConfig.InsecureSkipVerify = true
+	} else if bundle != "" {
+		pool, err := LoadCABundle(bundle)
+		pkg.Must(err, "Could not load CA bundle: %s", err)
+		tlsConfig.RootCAs = pool
+	} else {
+		return http.DefaultClient
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+}
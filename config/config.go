@@ -1,22 +1,30 @@
 package config
 
 import (
-	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/go-errors/errors"
 	"github.com/ory-am/fosite/handler/core/strategy"
-	"github.com/ory-am/fosite/hash"
 	"github.com/ory-am/fosite/token/hmac"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/cors"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/idempotency"
+	hoauth2 "github.com/ory-am/hydra/oauth2"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/policy"
+	"github.com/ory-am/hydra/quota"
+	"github.com/ory-am/hydra/ratelimit"
+	"github.com/ory-am/hydra/scope"
 	"github.com/ory-am/ladon"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -34,6 +42,8 @@ type Config struct {
 
 	Issuer string `mapstructure:"issuer" yaml:"issuer,omitempty"`
 
+	LegacyIssuers string `mapstructure:"legacy_issuers" yaml:"legacy_issuers,omitempty"`
+
 	SystemSecret []byte `mapstructure:"system_secret" yaml:"-"`
 
 	DatabaseURL string `mapstructure:"database_url" yaml:"database_url,omitempty"`
@@ -46,8 +56,196 @@ type Config struct {
 
 	ClientSecret string `mapstructure:"client_secret" yaml:"client_secret,omitempty"`
 
+	// CABundle is the path of a PEM encoded bundle of the certificate authorities the CLI trusts.
+	CABundle string `mapstructure:"ca_bundle" yaml:"ca_bundle,omitempty"`
+
+	// Contexts are the clusters the CLI knows, CurrentContext is the one it talks to.
+	Contexts map[string]*ClusterContext `mapstructure:"contexts" yaml:"contexts,omitempty"`
+
+	CurrentContext string `mapstructure:"current_context" yaml:"current_context,omitempty"`
+
+	// ForceRootClientCredentials are the id and secret, separated by a colon, of the root client created on first
+	// start. A random secret is generated if it is empty.
+	ForceRootClientCredentials string `mapstructure:"force_root_client_credentials" yaml:"-"`
+
 	ForceHTTP bool `mapstructure:"foolishly_force_http" yaml:"-"`
 
+	// ClientsQuota limits the number of clients.
+	ClientsQuota int `mapstructure:"clients_quota" yaml:"clients_quota,omitempty"`
+
+	// TokensQuota limits the number of access tokens of every client.
+	TokensQuota int `mapstructure:"tokens_quota" yaml:"tokens_quota,omitempty"`
+
+	// SessionsQuota limits the number of login sessions of every subject.
+	SessionsQuota int `mapstructure:"sessions_quota" yaml:"sessions_quota,omitempty"`
+
+	QuotaWarningThresholds []float64 `mapstructure:"quota_warning_thresholds" yaml:"quota_warning_thresholds,omitempty"`
+
+	KeyAuditLog string `mapstructure:"key_audit_log" yaml:"key_audit_log,omitempty"`
+
+	AccessLog string `mapstructure:"access_log" yaml:"access_log,omitempty"`
+
+	KeyRetention string `mapstructure:"key_retention" yaml:"key_retention,omitempty"`
+
+	// TLSKeySet is the key set whose private key the HTTP server uses for TLS.
+	TLSKeySet string `mapstructure:"tls_key_set" yaml:"tls_key_set,omitempty"`
+
+	// ACMEDomains is a comma separated list of domains. If set, the TLS certificate is obtained and renewed via
+	// ACME instead of being issued from TLSKeySet.
+	ACMEDomains string `mapstructure:"acme_domains" yaml:"acme_domains,omitempty"`
+
+	// ACMEEmail is the contact address of the ACME account.
+	ACMEEmail string `mapstructure:"acme_email" yaml:"acme_email,omitempty"`
+
+	// ACMEDirectoryURL is the directory of the ACME certificate authority, Let's Encrypt by default.
+	ACMEDirectoryURL string `mapstructure:"acme_directory_url" yaml:"acme_directory_url,omitempty"`
+
+	KeyValidation string `mapstructure:"key_validation" yaml:"key_validation,omitempty"`
+
+	EnableHistory bool `mapstructure:"enable_history" yaml:"enable_history,omitempty"`
+
+	PendingConsentLifespan string `mapstructure:"pending_consent_lifespan" yaml:"pending_consent_lifespan,omitempty"`
+
+	RememberConsentMaxLifespan string `mapstructure:"remember_consent_max_lifespan" yaml:"remember_consent_max_lifespan,omitempty"`
+
+	RememberConsentScopeLifespans string `mapstructure:"remember_consent_scope_lifespans" yaml:"remember_consent_scope_lifespans,omitempty"`
+
+	LoginSessionLifespan string `mapstructure:"login_session_lifespan" yaml:"login_session_lifespan,omitempty"`
+
+	// IdempotencyKeyLifespan is how long the responses to admin requests with an Idempotency-Key header are kept.
+	IdempotencyKeyLifespan string `mapstructure:"idempotency_key_lifespan" yaml:"idempotency_key_lifespan,omitempty"`
+
+	LogoutRedirectURL string `mapstructure:"logout_redirect_url" yaml:"logout_redirect_url,omitempty"`
+
+	// MTLSBaseURL is the URL of a proxy in front of hydra which authenticates clients by their TLS certificate.
+	MTLSBaseURL string `mapstructure:"mtls_base_url" yaml:"mtls_base_url,omitempty"`
+
+	DeviceCodeLifespan string `mapstructure:"device_code_lifespan" yaml:"device_code_lifespan,omitempty"`
+
+	DeviceVerificationURL string `mapstructure:"device_verification_url" yaml:"device_verification_url,omitempty"`
+
+	AccessTokenStrategy string `mapstructure:"access_token_strategy" yaml:"access_token_strategy,omitempty"`
+
+	AccessTokenAcceptedFormats string `mapstructure:"access_token_accepted_formats" yaml:"access_token_accepted_formats,omitempty"`
+
+	WebhookURLs string `mapstructure:"webhook_urls" yaml:"webhook_urls,omitempty"`
+
+	WebhookSecret string `mapstructure:"webhook_secret" yaml:"-"`
+
+	// PseudonymizeEvents replaces subject identifiers in events delivered to webhooks with pseudonyms.
+	PseudonymizeEvents bool `mapstructure:"pseudonymize_events" yaml:"pseudonymize_events,omitempty"`
+
+	// PseudonymSecret keys the pseudonyms of events and of subjects in subject-hashed access logs.
+	PseudonymSecret string `mapstructure:"pseudonym_secret" yaml:"-"`
+
+	// IDTokenClaimsSource adds claims from the user store to ID tokens. It is either the URL of a webhook or the name
+	// of a claims source compiled into hydra, see oauth2.RegisterClaimsSource.
+	IDTokenClaimsSource string `mapstructure:"id_token_claims_source" yaml:"id_token_claims_source,omitempty"`
+
+	IDTokenClaimsSecret string `mapstructure:"id_token_claims_secret" yaml:"-"`
+
+	// IDTokenScopeClaims overrides which claims a scope releases into ID tokens, as a comma separated list of
+	// scope=claim pairs with space separated claims.
+	IDTokenScopeClaims string `mapstructure:"id_token_scope_claims" yaml:"id_token_scope_claims,omitempty"`
+
+	PseudonymRotation string `mapstructure:"pseudonym_rotation" yaml:"pseudonym_rotation,omitempty"`
+
+	OpenClientRegistration bool `mapstructure:"open_client_registration" yaml:"open_client_registration,omitempty"`
+
+	SecretHasher string `mapstructure:"secret_hasher" yaml:"secret_hasher,omitempty"`
+
+	BCryptWorkFactor int `mapstructure:"bcrypt_work_factor" yaml:"bcrypt_work_factor,omitempty"`
+
+	Argon2Iterations uint32 `mapstructure:"argon2_iterations" yaml:"argon2_iterations,omitempty"`
+
+	Argon2Memory uint32 `mapstructure:"argon2_memory" yaml:"argon2_memory,omitempty"`
+
+	Argon2Parallelism uint8 `mapstructure:"argon2_parallelism" yaml:"argon2_parallelism,omitempty"`
+
+	SecretRotationOverlap string `mapstructure:"secret_rotation_overlap" yaml:"secret_rotation_overlap,omitempty"`
+
+	DisableCompression bool `mapstructure:"disable_compression" yaml:"disable_compression,omitempty"`
+
+	CompressionMinSize int `mapstructure:"compression_min_size" yaml:"compression_min_size,omitempty"`
+
+	// ScopeStrategy decides which granted scopes cover a requested scope, it is one of hierarchic (the default),
+	// exact or wildcard.
+	ScopeStrategy string `mapstructure:"scope_strategy" yaml:"scope_strategy,omitempty"`
+
+	WardenSnapshotMaxAge string `mapstructure:"warden_snapshot_max_age" yaml:"warden_snapshot_max_age,omitempty"`
+
+	// WardenDecisionCacheTTL is how long warden decisions are cached, zero disables the cache.
+	WardenDecisionCacheTTL string `mapstructure:"warden_decision_cache_ttl" yaml:"warden_decision_cache_ttl,omitempty"`
+
+	JanitorInterval string `mapstructure:"janitor_interval" yaml:"janitor_interval,omitempty"`
+
+	JanitorBatchSize int `mapstructure:"janitor_batch_size" yaml:"janitor_batch_size,omitempty"`
+
+	RefreshTokenLifespan string `mapstructure:"refresh_token_lifespan" yaml:"refresh_token_lifespan,omitempty"`
+
+	MemorySnapshotPath string `mapstructure:"memory_snapshot_path" yaml:"memory_snapshot_path,omitempty"`
+
+	MemorySnapshotInterval string `mapstructure:"memory_snapshot_interval" yaml:"memory_snapshot_interval,omitempty"`
+
+	HedgedReads bool `mapstructure:"hedged_reads" yaml:"hedged_reads,omitempty"`
+
+	KeyLookupBudget string `mapstructure:"key_lookup_budget" yaml:"key_lookup_budget,omitempty"`
+
+	TokenReadBudget string `mapstructure:"token_read_budget" yaml:"token_read_budget,omitempty"`
+
+	TokenWriteBudget string `mapstructure:"token_write_budget" yaml:"token_write_budget,omitempty"`
+
+	// AdminAddress is the address of a listener which serves the administrative endpoints. If set, the default
+	// listener only serves the public endpoints, like /oauth2/* and /.well-known/*, and the CLI needs CLUSTER_URL
+	// to point to the admin listener.
+	AdminAddress string `mapstructure:"admin_address" yaml:"admin_address,omitempty"`
+
+	// AdminTLSKeySet is the key set of the TLS certificate of the admin listener, TLSKeySet by default.
+	AdminTLSKeySet string `mapstructure:"admin_tls_key_set" yaml:"admin_tls_key_set,omitempty"`
+
+	// MetricsAddress is the address of a plain HTTP listener which serves the metrics instead of the TLS listener.
+	MetricsAddress string `mapstructure:"metrics_address" yaml:"metrics_address,omitempty"`
+
+	// GRPCAddress is the address of a listener which serves the warden and token introspection via gRPC, see
+	// rpc/hydra.proto. The listener is disabled if GRPCAddress is empty.
+	GRPCAddress string `mapstructure:"grpc_address" yaml:"grpc_address,omitempty"`
+
+	// GRPCClientCA is the path of a PEM encoded CA bundle. If set, the gRPC listener requires clients to present a
+	// certificate signed by one of its certificate authorities.
+	GRPCClientCA string `mapstructure:"grpc_client_ca" yaml:"grpc_client_ca,omitempty"`
+
+	// TracingProvider is either empty, which disables tracing, or jaeger.
+	TracingProvider string `mapstructure:"tracing_provider" yaml:"tracing_provider,omitempty"`
+
+	TracingServiceName string `mapstructure:"tracing_service_name" yaml:"tracing_service_name,omitempty"`
+
+	TracingAgentAddress string `mapstructure:"tracing_agent_address" yaml:"tracing_agent_address,omitempty"`
+
+	// LogLevel is one of debug, info, warn or error.
+	LogLevel string `mapstructure:"log_level" yaml:"log_level,omitempty"`
+
+	// LogFormat is either text or json.
+	LogFormat string `mapstructure:"log_format" yaml:"log_format,omitempty"`
+
+	RateLimitPerClient string `mapstructure:"rate_limit_per_client" yaml:"rate_limit_per_client,omitempty"`
+
+	RateLimitPerAddress string `mapstructure:"rate_limit_per_address" yaml:"rate_limit_per_address,omitempty"`
+
+	// RateLimitRedisURL shares the rate limits of all instances in Redis. They are kept in memory if it is empty.
+	RateLimitRedisURL string `mapstructure:"rate_limit_redis_url" yaml:"rate_limit_redis_url,omitempty"`
+
+	// LockoutThreshold is the number of failed authentications after which a client is locked. Zero disables
+	// lockouts.
+	LockoutThreshold int `mapstructure:"lockout_threshold" yaml:"lockout_threshold,omitempty"`
+
+	LockoutDuration string `mapstructure:"lockout_duration" yaml:"lockout_duration,omitempty"`
+
+	LockoutMaxDuration string `mapstructure:"lockout_max_duration" yaml:"lockout_max_duration,omitempty"`
+
+	// CORSAllowedOrigins lists the origins which may call the public and the admin endpoints, for example
+	// "public=https://app.example.com,admin=https://console.example.com". CORS is disabled if it is empty.
+	CORSAllowedOrigins string `mapstructure:"cors_allowed_origins" yaml:"cors_allowed_origins,omitempty"`
+
 	cluster *url.URL
 
 	oauth2Client *http.Client
@@ -103,40 +301,87 @@ func (c *Config) Context() *Context {
 		case "rethinkdb":
 			connection = &RethinkDBConnection{URL: u}
 			break
+		case "postgres", "mysql":
+			connection = &SQLConnection{URL: u}
+			break
+		case "dynamodb":
+			connection = &DynamoDBConnection{URL: u}
+			break
 		default:
 			logrus.Fatalf("Unkown DSN in DATABASE_URL: %s", c.DatabaseURL)
 		}
 	}
 
 	var manager ladon.Manager
+	var leases, writeLeases pkg.LeaseManager
 	switch con := connection.(type) {
 	case *MemoryConnection:
 		logrus.Printf("DATABASE_URL not set, connecting to ephermal in-memory database.")
 		manager = ladon.NewMemoryManager()
+		leases = &pkg.MemoryLeaseManager{}
 		break
 	case *RethinkDBConnection:
 		logrus.Printf("DATABASE_URL set, connecting to RethinkDB.")
 		con.CreateTableIfNotExists("hydra_policies")
-		m := &ladon.RethinkManager{
-			Session: con.GetSession(),
-			Table:   r.Table("hydra_policies"),
+		m := &policy.RethinkManager{
+			Session:  con.GetSession(),
+			Table:    r.Table("hydra_policies"),
+			Policies: map[string]ladon.Policy{},
 		}
 		m.Watch(context.Background())
 		if err := m.ColdStart(); err != nil {
 			logrus.Fatalf("Could not fetch initial state: %s", err)
 		}
 		manager = m
+
+		con.CreateTableIfNotExists("hydra_leases")
+		leases = &pkg.RethinkDBLeaseManager{
+			Session: con.GetSession(),
+			Table:   r.Table("hydra_leases"),
+		}
+		break
+	case *SQLConnection:
+		logrus.Printf("DATABASE_URL set, connecting to %s.", con.GetDriver())
+		logrus.Warnf("Only clients, connections, keys, policies and tokens are stored in %s, the other managers keep their data in memory.", con.GetDriver())
+		m := &policy.SQLManager{DB: con.GetDatabase(), Driver: con.GetDriver()}
+		if err := m.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create schemas: %s", err)
+		}
+		manager = m
+
+		l := &pkg.SQLLeaseManager{DB: con.GetDatabase(), Driver: con.GetDriver()}
+		if err := l.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create schemas: %s", err)
+		}
+		leases = l
+		break
+	case *DynamoDBConnection:
+		logrus.Printf("DATABASE_URL set, connecting to DynamoDB.")
+		logrus.Warnf("Only clients, keys and tokens are stored in DynamoDB, the other managers keep their data in memory and every instance runs the background jobs.")
+		con.CreateTableIfNotExists()
+		manager = ladon.NewMemoryManager()
+		leases = &pkg.MemoryLeaseManager{}
+
+		// The clients and keys are shared by all instances, so are the leases which guard writes to them.
+		writeLeases = &pkg.DynamoDBLeaseManager{DB: con.GetDB(), Table: con.GetTable()}
 		break
 	default:
 		panic("Unknown connection type.")
 	}
 
+	leader := &pkg.Elector{Leases: leases, Name: "background-jobs"}
+	leader.Campaign(context.Background())
+	if writeLeases == nil {
+		writeLeases = leases
+	}
+
 	c.context = &Context{
-		Connection: connection,
-		Hasher: &hash.BCrypt{
-			WorkFactor: 11,
-		},
+		Connection:   connection,
+		Hasher:       &client.RotatingHasher{Hasher: c.newHasher()},
 		LadonManager: manager,
+		Events:       &events.LogPublisher{},
+		Leader:       leader,
+		Leases:       writeLeases,
 		FositeStrategy: &strategy.HMACSHAStrategy{
 			Enigma: &hmac.HMACStrategy{
 				GlobalSecret: secret,
@@ -147,6 +392,32 @@ func (c *Config) Context() *Context {
 	return c.context
 }
 
+// newHasher returns the hasher for client secrets. Secrets hashed by the other supported algorithm or stored in
+// plain text are still accepted and hashed again once they were used successfully.
+func (c *Config) newHasher() *pkg.MigratingHasher {
+	workFactor := c.BCryptWorkFactor
+	if workFactor == 0 {
+		workFactor = 11
+	}
+
+	bcrypt := &pkg.BCrypt{WorkFactor: workFactor}
+	argon2 := &pkg.Argon2{
+		Iterations:  c.Argon2Iterations,
+		Memory:      c.Argon2Memory,
+		Parallelism: c.Argon2Parallelism,
+	}
+
+	switch c.SecretHasher {
+	case "", "bcrypt":
+		return &pkg.MigratingHasher{Hasher: bcrypt, Legacy: []pkg.Hasher{argon2}}
+	case "argon2":
+		return &pkg.MigratingHasher{Hasher: argon2, Legacy: []pkg.Hasher{bcrypt}}
+	default:
+		logrus.Fatalf("Unknown SECRET_HASHER %s, expected bcrypt or argon2", c.SecretHasher)
+		return nil
+	}
+}
+
 func (c *Config) Resolve(join ...string) *url.URL {
 	c.Lock()
 	defer c.Unlock()
@@ -182,13 +453,7 @@ func (c *Config) OAuth2Client(cmd *cobra.Command) *http.Client {
 		},
 	}
 
-	ctx := context.Background()
-	if ok, _ := cmd.Flags().GetBool("skip-tls-verify"); ok {
-		fmt.Println("Warning: Skipping TLS Certificate Verification.")
-		ctx = context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}})
-	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient(cmd, c.CABundle))
 
 	_, err := oauthConfig.Token(ctx)
 	if err != nil {
@@ -241,6 +506,531 @@ func (c *Config) GetIssuer() string {
 	return c.Issuer
 }
 
+// GetLegacyIssuers returns the previous issuers whose tokens are still accepted.
+func (c *Config) GetLegacyIssuers() []string {
+	issuer := c.GetIssuer()
+
+	c.Lock()
+	defer c.Unlock()
+
+	issuers := []string{}
+	for _, legacy := range strings.Split(c.LegacyIssuers, ",") {
+		if legacy = strings.TrimSpace(legacy); legacy != "" && legacy != issuer {
+			issuers = append(issuers, legacy)
+		}
+	}
+	return issuers
+}
+
+// GetQuota returns the quota named name with the given limit. The quota publishes its warnings to the
+// context's event publisher and is enforced across the cluster with the context's leases.
+func (c *Config) GetQuota(name string, limit int) *quota.Quota {
+	ctx := c.Context()
+	c.Lock()
+	defer c.Unlock()
+
+	return &quota.Quota{
+		Name:       name,
+		Limit:      limit,
+		Thresholds: c.QuotaWarningThresholds,
+		Publisher:  ctx.Events,
+		Leases:     ctx.Leases,
+	}
+}
+
+// GetKeyRetention returns how long deleted keys are kept before they are purged. Zero disables soft deletes.
+func (c *Config) GetKeyRetention() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.KeyRetention == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(c.KeyRetention)
+	if err != nil {
+		logrus.Fatalf("Could not parse KEY_RETENTION %s: %s", c.KeyRetention, err)
+	}
+	return d
+}
+
+// GetRootClientCredentials returns the forced credentials of the root client or two empty strings if they are not
+// set. The secret may contain colons, the id may not.
+func (c *Config) GetRootClientCredentials() (id, secret string, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.ForceRootClientCredentials == "" {
+		return "", "", nil
+	}
+
+	parts := strings.SplitN(c.ForceRootClientCredentials, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.New("FORCE_ROOT_CLIENT_CREDENTIALS must be formatted as id:secret")
+	}
+	return parts[0], parts[1], nil
+}
+
+// GetTLSKeySet returns the key set of the TLS certificate, hydra.tls by default.
+func (c *Config) GetTLSKeySet() string {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.TLSKeySet == "" {
+		return "hydra.tls"
+	}
+	return c.TLSKeySet
+}
+
+// GetAdminTLSKeySet returns the key set of the TLS certificate of the admin listener.
+func (c *Config) GetAdminTLSKeySet() string {
+	c.Lock()
+	set := c.AdminTLSKeySet
+	c.Unlock()
+
+	if set == "" {
+		return c.GetTLSKeySet()
+	}
+	return set
+}
+
+// GetACMEDomains returns the domains for which certificates are obtained via ACME.
+func (c *Config) GetACMEDomains() []string {
+	c.Lock()
+	defer c.Unlock()
+
+	domains := []string{}
+	for _, domain := range strings.Split(c.ACMEDomains, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// GetPendingConsentLifespan returns how long an authorization request is held while its consent decision is
+// pending.
+func (c *Config) GetPendingConsentLifespan() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.PendingConsentLifespan == "" {
+		return time.Minute * 5
+	}
+
+	d, err := time.ParseDuration(c.PendingConsentLifespan)
+	if err != nil {
+		logrus.Fatalf("Could not parse PENDING_CONSENT_LIFESPAN %s: %s", c.PendingConsentLifespan, err)
+	}
+	return d
+}
+
+// GetRememberConsentMaxLifespan returns how long a consent decision is remembered at most.
+func (c *Config) GetRememberConsentMaxLifespan() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.RememberConsentMaxLifespan == "" {
+		return hoauth2.DefaultRememberConsentMaxLifespan
+	}
+
+	d, err := time.ParseDuration(c.RememberConsentMaxLifespan)
+	if err != nil {
+		logrus.Fatalf("Could not parse REMEMBER_CONSENT_MAX_LIFESPAN %s: %s", c.RememberConsentMaxLifespan, err)
+	}
+	return d
+}
+
+// GetRememberConsentScopeLifespans returns how long consent decisions are remembered for individual scopes. They are
+// configured as a comma separated list of scope=duration pairs, duration never lifts the cap for the scope.
+func (c *Config) GetRememberConsentScopeLifespans() map[string]time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	lifespans := map[string]time.Duration{}
+	for _, pair := range strings.Split(c.RememberConsentScopeLifespans, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			logrus.Fatalf("Could not parse REMEMBER_CONSENT_SCOPE_LIFESPANS %s: expected scope=duration", pair)
+		} else if parts[1] == "never" {
+			lifespans[parts[0]] = 0
+			continue
+		}
+
+		d, err := time.ParseDuration(parts[1])
+		if err != nil {
+			logrus.Fatalf("Could not parse REMEMBER_CONSENT_SCOPE_LIFESPANS %s: %s", pair, err)
+		} else if d <= 0 {
+			logrus.Fatalf("Could not parse REMEMBER_CONSENT_SCOPE_LIFESPANS %s: the duration must be positive", pair)
+		}
+		lifespans[parts[0]] = d
+	}
+	return lifespans
+}
+
+// GetLoginSessionLifespan returns how long a login session lasts.
+func (c *Config) GetLoginSessionLifespan() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.LoginSessionLifespan == "" {
+		return hoauth2.DefaultLoginSessionLifespan
+	}
+
+	d, err := time.ParseDuration(c.LoginSessionLifespan)
+	if err != nil {
+		logrus.Fatalf("Could not parse LOGIN_SESSION_LIFESPAN %s: %s", c.LoginSessionLifespan, err)
+	}
+	return d
+}
+
+// GetIdempotencyKeyLifespan returns how long retries with an idempotency key receive the response of the first request.
+func (c *Config) GetIdempotencyKeyLifespan() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.IdempotencyKeyLifespan == "" {
+		return idempotency.DefaultLifespan
+	}
+
+	d, err := time.ParseDuration(c.IdempotencyKeyLifespan)
+	if err != nil {
+		logrus.Fatalf("Could not parse IDEMPOTENCY_KEY_LIFESPAN %s: %s", c.IdempotencyKeyLifespan, err)
+	}
+	return d
+}
+
+// GetDeviceCodeLifespan returns how long device codes of the device authorization grant are valid.
+func (c *Config) GetDeviceCodeLifespan() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.DeviceCodeLifespan == "" {
+		return time.Minute * 10
+	}
+
+	d, err := time.ParseDuration(c.DeviceCodeLifespan)
+	if err != nil {
+		logrus.Fatalf("Could not parse DEVICE_CODE_LIFESPAN %s: %s", c.DeviceCodeLifespan, err)
+	}
+	return d
+}
+
+// GetSecretRotationOverlap returns how long a client's previous secret stays valid after its secret was rotated.
+func (c *Config) GetSecretRotationOverlap() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.SecretRotationOverlap == "" {
+		return time.Hour * 24
+	}
+
+	d, err := time.ParseDuration(c.SecretRotationOverlap)
+	if err != nil {
+		logrus.Fatalf("Could not parse SECRET_ROTATION_OVERLAP %s: %s", c.SecretRotationOverlap, err)
+	}
+	return d
+}
+
+// GetAccessTokenFormat returns the format new access tokens are issued in. ACCESS_TOKEN_STRATEGY opaque is an alias
+// of opaque-v1.
+func (c *Config) GetAccessTokenFormat() string {
+	c.Lock()
+	defer c.Unlock()
+
+	switch c.AccessTokenStrategy {
+	case "", "opaque", hoauth2.TokenFormatOpaqueV1:
+		return hoauth2.TokenFormatOpaqueV1
+	case hoauth2.TokenFormatOpaqueV2, hoauth2.TokenFormatJWT:
+		return c.AccessTokenStrategy
+	}
+	logrus.Fatalf("Unknown ACCESS_TOKEN_STRATEGY %s, expected opaque-v1, opaque-v2 or jwt", c.AccessTokenStrategy)
+	return ""
+}
+
+// GetAcceptedAccessTokenFormats returns the formats access tokens are accepted in, which always include the issued
+// format. Both opaque formats are accepted unless ACCESS_TOKEN_ACCEPTED_FORMATS says otherwise.
+func (c *Config) GetAcceptedAccessTokenFormats() []string {
+	issued := c.GetAccessTokenFormat()
+
+	c.Lock()
+	defer c.Unlock()
+
+	accepted := map[string]bool{issued: true}
+	if c.AccessTokenAcceptedFormats == "" {
+		accepted[hoauth2.TokenFormatOpaqueV1] = true
+		accepted[hoauth2.TokenFormatOpaqueV2] = true
+	}
+	for _, format := range strings.Split(c.AccessTokenAcceptedFormats, ",") {
+		switch format = strings.TrimSpace(format); format {
+		case "":
+		case hoauth2.TokenFormatOpaqueV1, hoauth2.TokenFormatOpaqueV2, hoauth2.TokenFormatJWT:
+			accepted[format] = true
+		default:
+			logrus.Fatalf("Unknown access token format %s in ACCESS_TOKEN_ACCEPTED_FORMATS", format)
+		}
+	}
+
+	formats := []string{}
+	for _, format := range []string{hoauth2.TokenFormatOpaqueV1, hoauth2.TokenFormatOpaqueV2, hoauth2.TokenFormatJWT} {
+		if accepted[format] {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// GetPseudonymSecret returns the key pseudonyms are derived from, which is the system secret unless
+// PSEUDONYM_SECRET is set.
+func (c *Config) GetPseudonymSecret() []byte {
+	c.Lock()
+	secret := c.PseudonymSecret
+	c.Unlock()
+
+	if secret != "" {
+		return []byte(secret)
+	}
+	return c.GetSystemSecret()
+}
+
+// GetPseudonymRotation returns how long a subject keeps its pseudonym.
+func (c *Config) GetPseudonymRotation() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.PseudonymRotation == "" {
+		return events.DefaultPseudonymRotation
+	}
+
+	d, err := time.ParseDuration(c.PseudonymRotation)
+	if err != nil {
+		logrus.Fatalf("Could not parse PSEUDONYM_ROTATION %s: %s", c.PseudonymRotation, err)
+	}
+	return d
+}
+
+// GetWardenSnapshotMaxAge returns how long edge devices may enforce decisions with a warden snapshot.
+func (c *Config) GetWardenSnapshotMaxAge() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.WardenSnapshotMaxAge == "" {
+		return time.Hour
+	}
+
+	d, err := time.ParseDuration(c.WardenSnapshotMaxAge)
+	if err != nil {
+		logrus.Fatalf("Could not parse WARDEN_SNAPSHOT_MAX_AGE %s: %s", c.WardenSnapshotMaxAge, err)
+	}
+	return d
+}
+
+// GetWardenDecisionCacheTTL returns how long warden decisions are cached. Zero disables the cache.
+func (c *Config) GetWardenDecisionCacheTTL() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.WardenDecisionCacheTTL == "" {
+		return time.Second * 5
+	}
+
+	d, err := time.ParseDuration(c.WardenDecisionCacheTTL)
+	if err != nil {
+		logrus.Fatalf("Could not parse WARDEN_DECISION_CACHE_TTL %s: %s", c.WardenDecisionCacheTTL, err)
+	}
+	return d
+}
+
+// GetJanitorInterval returns how often expired tokens are deleted. Zero disables the scheduled runs.
+func (c *Config) GetJanitorInterval() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.JanitorInterval == "" {
+		return time.Hour
+	}
+
+	d, err := time.ParseDuration(c.JanitorInterval)
+	if err != nil {
+		logrus.Fatalf("Could not parse JANITOR_INTERVAL %s: %s", c.JanitorInterval, err)
+	}
+	return d
+}
+
+// GetRefreshTokenLifespan returns how long refresh tokens are kept. Zero means refresh tokens never expire.
+func (c *Config) GetRefreshTokenLifespan() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.RefreshTokenLifespan == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(c.RefreshTokenLifespan)
+	if err != nil {
+		logrus.Fatalf("Could not parse REFRESH_TOKEN_LIFESPAN %s: %s", c.RefreshTokenLifespan, err)
+	}
+	return d
+}
+
+// GetMemorySnapshotInterval returns how often the in-memory state is saved to MEMORY_SNAPSHOT_PATH.
+func (c *Config) GetMemorySnapshotInterval() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.MemorySnapshotInterval == "" {
+		return time.Minute
+	}
+
+	d, err := time.ParseDuration(c.MemorySnapshotInterval)
+	if err != nil {
+		logrus.Fatalf("Could not parse MEMORY_SNAPSHOT_INTERVAL %s: %s", c.MemorySnapshotInterval, err)
+	}
+	return d
+}
+
+// GetKeyLookupBudget returns how long key lookups may take. Zero means key lookups are not bounded.
+func (c *Config) GetKeyLookupBudget() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	return parseBudget("KEY_LOOKUP_BUDGET", c.KeyLookupBudget)
+}
+
+// GetTokenReadBudget returns how long token reads may take. Zero means token reads are not bounded.
+func (c *Config) GetTokenReadBudget() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	return parseBudget("TOKEN_READ_BUDGET", c.TokenReadBudget)
+}
+
+// GetTokenWriteBudget returns how long token writes may take. Zero means token writes are not bounded.
+func (c *Config) GetTokenWriteBudget() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	return parseBudget("TOKEN_WRITE_BUDGET", c.TokenWriteBudget)
+}
+
+func parseBudget(name, budget string) time.Duration {
+	if budget == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(budget)
+	if err != nil {
+		logrus.Fatalf("Could not parse %s %s: %s", name, budget, err)
+	}
+	return d
+}
+
+// GetRateLimitPerClient returns how many token and introspection requests an authenticated client may send. The zero
+// limit disables the limit.
+func (c *Config) GetRateLimitPerClient() ratelimit.Limit {
+	c.Lock()
+	defer c.Unlock()
+
+	return parseRateLimit("RATE_LIMIT_PER_CLIENT", c.RateLimitPerClient)
+}
+
+// GetRateLimitPerAddress returns how many token and introspection requests a remote address may send. The zero
+// limit disables the limit.
+func (c *Config) GetRateLimitPerAddress() ratelimit.Limit {
+	c.Lock()
+	defer c.Unlock()
+
+	return parseRateLimit("RATE_LIMIT_PER_ADDRESS", c.RateLimitPerAddress)
+}
+
+func parseRateLimit(name, spec string) ratelimit.Limit {
+	l, err := ratelimit.ParseLimit(spec)
+	if err != nil {
+		logrus.Fatalf("Could not parse %s %s: %s", name, spec, err)
+	}
+	return l
+}
+
+// GetLockoutDuration returns how long a client is locked the first time.
+func (c *Config) GetLockoutDuration() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.LockoutDuration == "" {
+		return time.Minute
+	}
+
+	d, err := time.ParseDuration(c.LockoutDuration)
+	if err != nil {
+		logrus.Fatalf("Could not parse LOCKOUT_DURATION %s: %s", c.LockoutDuration, err)
+	}
+	return d
+}
+
+// GetLockoutMaxDuration returns the longest a client is locked.
+func (c *Config) GetLockoutMaxDuration() time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.LockoutMaxDuration == "" {
+		return time.Hour
+	}
+
+	d, err := time.ParseDuration(c.LockoutMaxDuration)
+	if err != nil {
+		logrus.Fatalf("Could not parse LOCKOUT_MAX_DURATION %s: %s", c.LockoutMaxDuration, err)
+	}
+	return d
+}
+
+// GetCORSAllowedOrigins returns the allowed origins of every endpoint group.
+func (c *Config) GetCORSAllowedOrigins() map[string][]string {
+	c.Lock()
+	defer c.Unlock()
+
+	origins, err := cors.ParseOrigins(c.CORSAllowedOrigins)
+	if err != nil {
+		logrus.Fatalf("Could not parse CORS_ALLOWED_ORIGINS %s: %s", c.CORSAllowedOrigins, err)
+	}
+	return origins
+}
+
+// GetIDTokenScopeClaims returns the claims scopes release into ID tokens, see IDTokenScopeClaims.
+func (c *Config) GetIDTokenScopeClaims() map[string][]string {
+	c.Lock()
+	defer c.Unlock()
+
+	claims := map[string][]string{}
+	for _, pair := range strings.Split(c.IDTokenScopeClaims, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			logrus.Fatalf("Could not parse ID_TOKEN_SCOPE_CLAIMS %s: expected scope=claim", pair)
+		}
+		claims[parts[0]] = strings.Fields(parts[1])
+	}
+	return claims
+}
+
+// GetScopeStrategy returns the strategy of SCOPE_STRATEGY.
+func (c *Config) GetScopeStrategy() scope.Strategy {
+	c.Lock()
+	defer c.Unlock()
+
+	strategy, err := scope.Parse(c.ScopeStrategy)
+	if err != nil {
+		logrus.Fatalf("Could not parse SCOPE_STRATEGY: %s", err)
+	}
+	return strategy
+}
+
 func (c *Config) GetAccessTokenLifespan() time.Duration {
 	c.Lock()
 	defer c.Unlock()
@@ -258,8 +1048,11 @@ func (c *Config) Persist() error {
 		return errors.New(err)
 	}
 
-	if err := ioutil.WriteFile(viper.ConfigFileUsed(), out, 0700); err != nil {
+	// The file holds client secrets, it must only be readable by its owner.
+	if err := ioutil.WriteFile(viper.ConfigFileUsed(), out, 0600); err != nil {
 		return errors.Errorf(`Could not write to "%s" because: %s`, viper.ConfigFileUsed(), err)
+	} else if err := os.Chmod(viper.ConfigFileUsed(), 0600); err != nil {
+		return errors.Errorf(`Could not restrict the permissions of "%s" because: %s`, viper.ConfigFileUsed(), err)
 	}
 	return nil
 }
@@ -3,8 +3,11 @@ package config
 import (
 	"github.com/ory-am/fosite/handler/core"
 	"github.com/ory-am/fosite/hash"
+	"github.com/ory-am/hydra/events"
 	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/job"
 	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/oauth2"
 	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/ladon"
 )
@@ -18,4 +21,20 @@ type Context struct {
 	FositeStrategy core.CoreStrategy
 	FositeStore    pkg.FositeStorer
 	KeyManager     jwk.Manager
+	Events         events.Publisher
+
+	// Leader decides which node runs the periodic jobs of the cluster.
+	Leader pkg.Leader
+
+	// Leases guard the conditional writes of the handlers, see pkg.Preconditions.
+	Leases pkg.LeaseManager
+
+	// Jobs runs long operations in the background.
+	Jobs *job.Dispatcher
+
+	// ServiceTokens authenticates background components which call the admin api.
+	ServiceTokens *oauth2.ServiceTokenMinter
+
+	// IssuerMigration accepts tokens of the legacy issuers and records who still uses them.
+	IssuerMigration *oauth2.IssuerMigration
 }
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Redacted replaces the values of secrets in the effective configuration.
+const Redacted = "[redacted]"
+
+// secretSettings are the settings whose values are never logged or returned.
+var secretSettings = map[string]bool{
+	"system_secret":    true,
+	"client_secret":    true,
+	"webhook_secret":   true,
+	"pseudonym_secret": true,
+
+	"id_token_claims_secret": true,
+
+	"force_root_client_credentials": true,
+	"contexts":                      true,
+}
+
+// EffectiveConfig is the configuration an instance runs with, including the defaults of unset settings. Secrets
+// are redacted.
+type EffectiveConfig struct {
+	// Settings maps the name of every setting to its value.
+	Settings map[string]interface{} `json:"settings"`
+
+	// Storage is the storage driver, either memory or rethinkdb.
+	Storage string `json:"storage"`
+
+	// Endpoints are the URLs this instance is reached at and calls out to.
+	Endpoints map[string]string `json:"endpoints"`
+
+	// Security are the options which weaken or harden the instance.
+	Security map[string]interface{} `json:"security"`
+}
+
+// Effective returns the effective configuration with secrets redacted.
+func (c *Config) Effective() *EffectiveConfig {
+	e := &EffectiveConfig{
+		Storage: "memory",
+		Endpoints: map[string]string{
+			"address": c.GetAddress(),
+			"issuer":  c.GetIssuer(),
+			"cluster": c.GetClusterURL(),
+		},
+		Security: map[string]interface{}{
+			"access_token_format":           c.GetAccessTokenFormat(),
+			"access_token_accepted_formats": c.GetAcceptedAccessTokenFormats(),
+			"legacy_issuers":                c.GetLegacyIssuers(),
+		},
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	e.Settings = settings(c)
+	if u, err := url.Parse(c.DatabaseURL); err == nil && u.Scheme != "" {
+		e.Storage = u.Scheme
+	}
+	for name, endpoint := range map[string]string{
+		"consent":             c.ConsentURL,
+		"logout_redirect":     c.LogoutRedirectURL,
+		"mtls":                c.MTLSBaseURL,
+		"device_verification": c.DeviceVerificationURL,
+		"metrics":             c.MetricsAddress,
+		"admin":               c.AdminAddress,
+		"grpc":                c.GRPCAddress,
+		"tracing_agent":       c.TracingAgentAddress,
+		"acme_directory":      c.ACMEDirectoryURL,
+	} {
+		if endpoint != "" {
+			e.Endpoints[name] = endpoint
+		}
+	}
+
+	e.Security["tls"] = !c.ForceHTTP
+	e.Security["acme_domains"] = c.ACMEDomains
+	e.Security["open_client_registration"] = c.OpenClientRegistration
+	e.Security["secret_hasher"] = c.SecretHasher
+	e.Security["key_validation"] = c.KeyValidation
+	e.Security["history"] = c.EnableHistory
+	e.Security["pseudonymize_events"] = c.PseudonymizeEvents
+	e.Security["rate_limit_per_client"] = c.RateLimitPerClient
+	e.Security["rate_limit_per_address"] = c.RateLimitPerAddress
+	e.Security["lockout_threshold"] = c.LockoutThreshold
+	e.Security["cors_allowed_origins"] = c.CORSAllowedOrigins
+	e.Security["scope_strategy"] = c.ScopeStrategy
+	e.Security["grpc_client_certificates"] = c.GRPCClientCA != ""
+	return e
+}
+
+// settings maps the mapstructure name of every exported field to its value.
+func settings(c *Config) map[string]interface{} {
+	result := map[string]interface{}{}
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("mapstructure")
+		if name == "" {
+			continue
+		}
+
+		value := v.Field(i).Interface()
+		if secretSettings[name] {
+			if !isZero(value) {
+				value = Redacted
+			}
+		} else if name == "database_url" {
+			value = redactURL(c.DatabaseURL)
+		} else if name == "rate_limit_redis_url" {
+			value = redactURL(c.RateLimitRedisURL)
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// redactURL removes the password of a URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Redacted
+	} else if u.User == nil {
+		return raw
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+	return u.String()
+}
+
+// Log writes the effective configuration to the log, one line per section.
+func (e *EffectiveConfig) Log() {
+	logrus.WithField("storage", e.Storage).Info("Effective configuration")
+	logrus.WithFields(logrus.Fields(toFields(e.Endpoints))).Info("Effective endpoints")
+	logrus.WithFields(logrus.Fields(e.Security)).Info("Effective security options")
+
+	// Unset settings are left out to keep the line readable.
+	fields := logrus.Fields{}
+	for name, value := range e.Settings {
+		if !isZero(value) {
+			fields[strings.ToUpper(name)] = value
+		}
+	}
+	logrus.WithFields(fields).Info("Effective settings")
+}
+
+func isZero(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(value, reflect.Zero(v.Type()).Interface())
+}
+
+func toFields(m map[string]string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for k, v := range m {
+		fields[k] = v
+	}
+	return fields
+}
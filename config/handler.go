@@ -0,0 +1,42 @@
+package config
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/ladon"
+)
+
+const (
+	ConfigHandlerPath = "/admin/config"
+
+	configResource = "rn:hydra:config"
+	scope          = "hydra.config"
+)
+
+// Handler exposes the effective configuration of the instance.
+type Handler struct {
+	Config *Config
+	H      herodot.Herodot
+	W      firewall.Firewall
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.GET(ConfigHandlerPath, h.Get)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: configResource,
+		Action:   "get",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, h.Config.Effective())
+}
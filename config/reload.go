@@ -0,0 +1,73 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/cors"
+)
+
+// ReloadableSettings are the settings Reload applies to a running instance. All other settings only take effect
+// after a restart.
+var ReloadableSettings = []string{
+	"log_level",
+	"log_format",
+	"cors_allowed_origins",
+	"remember_consent_max_lifespan",
+	"login_session_lifespan",
+	"device_code_lifespan",
+}
+
+func (c *Config) reloadable() map[string]*string {
+	return map[string]*string{
+		"log_level":                     &c.LogLevel,
+		"log_format":                    &c.LogFormat,
+		"cors_allowed_origins":          &c.CORSAllowedOrigins,
+		"remember_consent_max_lifespan": &c.RememberConsentMaxLifespan,
+		"login_session_lifespan":        &c.LoginSessionLifespan,
+		"device_code_lifespan":          &c.DeviceCodeLifespan,
+	}
+}
+
+// Reload copies the reloadable settings of next to c and returns the names of the settings which changed. Nothing is
+// copied if one of the settings of next is invalid, so that a broken config file does not take a running instance
+// down.
+func (c *Config) Reload(next *Config) ([]string, error) {
+	if next.LogLevel != "" {
+		if _, err := logrus.ParseLevel(next.LogLevel); err != nil {
+			return nil, errors.Errorf("Could not parse LOG_LEVEL %s: %s", next.LogLevel, err)
+		}
+	}
+	if next.LogFormat != "" && next.LogFormat != "text" && next.LogFormat != "json" {
+		return nil, errors.Errorf("Log format %s is not supported, use text or json", next.LogFormat)
+	}
+	if _, err := cors.ParseOrigins(next.CORSAllowedOrigins); err != nil {
+		return nil, errors.Errorf("Could not parse CORS_ALLOWED_ORIGINS %s: %s", next.CORSAllowedOrigins, err)
+	}
+	for name, value := range map[string]string{
+		"REMEMBER_CONSENT_MAX_LIFESPAN": next.RememberConsentMaxLifespan,
+		"LOGIN_SESSION_LIFESPAN":        next.LoginSessionLifespan,
+		"DEVICE_CODE_LIFESPAN":          next.DeviceCodeLifespan,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, errors.Errorf("Could not parse %s %s: %s", name, value, err)
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	var changed []string
+	current, reloaded := c.reloadable(), next.reloadable()
+	for _, name := range ReloadableSettings {
+		if *current[name] != *reloaded[name] {
+			*current[name] = *reloaded[name]
+			changed = append(changed, name)
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	c := &Config{LogLevel: "info", LoginSessionLifespan: "1h", DatabaseURL: "memory"}
+
+	changed, err := c.Reload(&Config{LogLevel: "debug", LoginSessionLifespan: "1h", DatabaseURL: "rethinkdb://localhost"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"log_level"}, changed)
+	assert.Equal(t, "debug", c.LogLevel)
+	assert.Equal(t, "memory", c.DatabaseURL, "Settings which require a restart are not reloaded")
+
+	_, err = c.Reload(&Config{LogLevel: "info", LoginSessionLifespan: "forever"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "debug", c.LogLevel, "Nothing is reloaded if a setting is invalid")
+	assert.Equal(t, "1h", c.LoginSessionLifespan)
+}
@@ -0,0 +1,16 @@
+package config
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "GET", Path: ConfigHandlerPath, ID: "getConfig", Tag: "config", Scope: scope,
+		Summary:   "Get the effective configuration of the instance, secrets are redacted",
+		Responses: map[int]interface{}{http.StatusOK: &EffectiveConfig{}},
+	},
+}
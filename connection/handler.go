@@ -10,6 +10,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/hydra/firewall"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/pagination"
 	"github.com/ory-am/ladon"
 	"github.com/pborman/uuid"
 	"golang.org/x/net/context"
@@ -93,13 +94,35 @@ func (h *Handler) FindLocal(w http.ResponseWriter, r *http.Request, ps httproute
 		return
 	}
 
+	page, err := pagination.Parse(r, "id", "provider")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
 	conns, err := h.Manager.FindAllByLocalSubject(r.URL.Query().Get("local_subject"))
 	if err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
-	h.H.Write(ctx, w, r, conns)
+	indices, total := page.Apply(len(conns), func(i int, field string) string {
+		switch field {
+		case "id":
+			return conns[i].ID
+		case "provider":
+			return conns[i].Provider
+		}
+		return ""
+	})
+
+	res := make([]*Connection, len(indices))
+	for k, i := range indices {
+		res[k] = conns[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
 }
 
 func (h *Handler) FindRemote(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
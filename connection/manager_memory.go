@@ -1,6 +1,7 @@
 package connection
 
 import (
+	"encoding/json"
 	"sync"
 
 	"github.com/go-errors/errors"
@@ -69,3 +70,31 @@ func (m *MemoryManager) FindByRemoteSubject(provider, subject string) (*Connecti
 	}
 	return nil, errors.New(pkg.ErrNotFound)
 }
+
+// Snapshot returns all connections encoded as JSON.
+func (m *MemoryManager) Snapshot() ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	out, err := json.Marshal(m.Connections)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return out, nil
+}
+
+// Restore replaces all connections with the ones of the snapshot.
+func (m *MemoryManager) Restore(snapshot []byte) error {
+	var c map[string]*Connection
+	if err := json.Unmarshal(snapshot, &c); err != nil {
+		return errors.New(err)
+	}
+	if c == nil {
+		c = map[string]*Connection{}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Connections = c
+	return nil
+}
@@ -5,8 +5,6 @@ import (
 
 	r "gopkg.in/dancannon/gorethink.v2"
 
-	"time"
-
 	"github.com/go-errors/errors"
 	"github.com/ory-am/hydra/pkg"
 	"golang.org/x/net/context"
@@ -18,6 +16,10 @@ type RethinkManager struct {
 
 	Connections map[string]*Connection
 
+	// Feed streams the changes of Table. It is created by Watch if nil and can be subscribed to by other
+	// subsystems which need to react to connection changes.
+	Feed *pkg.ChangeFeed
+
 	sync.RWMutex
 }
 
@@ -73,19 +75,26 @@ func (m *RethinkManager) FindByRemoteSubject(provider, subject string) (*Connect
 }
 
 func (m *RethinkManager) ColdStart() error {
-	m.Connections = map[string]*Connection{}
-	clients, err := m.Table.Run(m.Session)
+	rows, err := m.Table.Run(m.Session)
 	if err != nil {
 		return errors.New(err)
 	}
+	defer rows.Close()
 
-	var connection Connection
-	m.Lock()
-	defer m.Unlock()
-	for clients.Next(&connection) {
-		m.Connections[connection.ID] = &connection
+	connections := map[string]*Connection{}
+	var connection *Connection
+	for rows.Next(&connection) {
+		connections[connection.ID] = connection
+		connection = nil
 	}
 
+	if rows.Err() != nil {
+		return errors.New(rows.Err())
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Connections = connections
 	return nil
 }
 
@@ -104,34 +113,25 @@ func (m *RethinkManager) publishDelete(id string) error {
 }
 
 func (m *RethinkManager) Watch(ctx context.Context) {
-	go pkg.Retry(time.Second*15, time.Minute, func() error {
-		connections, err := m.Table.Changes().Run(m.Session)
-		if err != nil {
-			return errors.New(err)
-		}
-		defer connections.Close()
-
-		var update map[string]*Connection
-		for connections.Next(&update) {
-			newVal := update["new_val"]
-			oldVal := update["old_val"]
-			m.Lock()
-			if newVal == nil && oldVal != nil {
-				delete(m.Connections, oldVal.GetID())
-			} else if newVal != nil && oldVal != nil {
-				delete(m.Connections, oldVal.GetID())
-				m.Connections[newVal.GetID()] = newVal
-			} else {
-				m.Connections[newVal.GetID()] = newVal
-			}
-			m.Unlock()
-		}
+	if m.Feed == nil {
+		m.Feed = &pkg.ChangeFeed{Session: m.Session, Table: m.Table}
+	}
 
-		if connections.Err() != nil {
-			err = errors.New(connections.Err())
-			pkg.LogError(err)
+	m.Feed.Subscribe(m.ColdStart, func(change *pkg.Change) error {
+		var newVal, oldVal *Connection
+		if err := change.Decode(&oldVal, &newVal); err != nil {
 			return err
 		}
+
+		m.Lock()
+		defer m.Unlock()
+		if oldVal != nil {
+			delete(m.Connections, oldVal.GetID())
+		}
+		if newVal != nil {
+			m.Connections[newVal.GetID()] = newVal
+		}
 		return nil
 	})
+	m.Feed.Start(ctx)
 }
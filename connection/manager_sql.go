@@ -0,0 +1,122 @@
+package connection
+
+import (
+	"database/sql"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+// sqlSchemas create the connection table. A remote identity can be linked to a single local subject only.
+var sqlSchemas = map[string][]string{
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS hydra_connection (
+	id             varchar(255) NOT NULL PRIMARY KEY,
+	provider       varchar(255) NOT NULL,
+	local_subject  varchar(255) NOT NULL,
+	remote_subject varchar(255) NOT NULL,
+	UNIQUE (provider, remote_subject)
+)`,
+		`CREATE INDEX IF NOT EXISTS hydra_connection_local_subject_idx ON hydra_connection (local_subject)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS hydra_connection (
+	id             varchar(255) NOT NULL PRIMARY KEY,
+	provider       varchar(255) NOT NULL,
+	local_subject  varchar(255) NOT NULL,
+	remote_subject varchar(255) NOT NULL,
+	UNIQUE KEY hydra_connection_remote_idx (provider, remote_subject),
+	INDEX hydra_connection_local_subject_idx (local_subject)
+)`,
+	},
+}
+
+// migrations are the versions of the connection table. The first version creates the table only if it does not
+// exist, so that databases which were set up before migrations were versioned are adopted as they are.
+var migrations = []*pkg.Migration{
+	{
+		ID: "1",
+		Up: sqlSchemas,
+		Down: map[string][]string{
+			"postgres": {`DROP TABLE IF EXISTS hydra_connection`},
+			"mysql":    {`DROP TABLE IF EXISTS hydra_connection`},
+		},
+	},
+}
+
+// SQLManager stores connections in PostgreSQL or MySQL.
+type SQLManager struct {
+	DB *sql.DB
+
+	// Driver is the name of the database/sql driver, either "postgres" or "mysql".
+	Driver string
+}
+
+// Migrator returns the migrator of the connection table.
+func (m *SQLManager) Migrator() *pkg.Migrator {
+	return &pkg.Migrator{DB: m.DB, Driver: m.Driver, Module: "connection", Migrations: migrations}
+}
+
+// CreateSchemas applies all migrations of the connection table which were not applied yet.
+func (m *SQLManager) CreateSchemas() error {
+	_, err := m.Migrator().Up()
+	return err
+}
+
+func (m *SQLManager) Create(c *Connection) error {
+	if _, err := m.DB.Exec(m.rebind("INSERT INTO hydra_connection (id, provider, local_subject, remote_subject) VALUES (?, ?, ?, ?)"),
+		c.GetID(), c.GetProvider(), c.GetLocalSubject(), c.GetRemoteSubject()); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) Delete(id string) error {
+	if _, err := m.DB.Exec(m.rebind("DELETE FROM hydra_connection WHERE id = ?"), id); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) Get(id string) (*Connection, error) {
+	return m.findOne(m.rebind("SELECT id, provider, local_subject, remote_subject FROM hydra_connection WHERE id = ?"), id)
+}
+
+func (m *SQLManager) FindAllByLocalSubject(subject string) ([]*Connection, error) {
+	rows, err := m.DB.Query(m.rebind("SELECT id, provider, local_subject, remote_subject FROM hydra_connection WHERE local_subject = ?"), subject)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var cs []*Connection
+	for rows.Next() {
+		var c Connection
+		if err := rows.Scan(&c.ID, &c.Provider, &c.LocalSubject, &c.RemoteSubject); err != nil {
+			return nil, errors.New(err)
+		}
+		cs = append(cs, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	}
+	return cs, nil
+}
+
+func (m *SQLManager) FindByRemoteSubject(provider, subject string) (*Connection, error) {
+	return m.findOne(m.rebind("SELECT id, provider, local_subject, remote_subject FROM hydra_connection WHERE provider = ? AND remote_subject = ?"), provider, subject)
+}
+
+func (m *SQLManager) findOne(query string, args ...interface{}) (*Connection, error) {
+	var c Connection
+	if err := m.DB.QueryRow(query, args...).Scan(&c.ID, &c.Provider, &c.LocalSubject, &c.RemoteSubject); err == sql.ErrNoRows {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err != nil {
+		return nil, errors.New(err)
+	}
+	return &c, nil
+}
+
+func (m *SQLManager) rebind(query string) string {
+	return pkg.RebindSQL(m.Driver, query)
+}
@@ -1,6 +1,7 @@
 package connection
 
 import (
+	"database/sql"
 	"testing"
 
 	"net/http/httptest"
@@ -13,26 +14,13 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/integration"
 	"github.com/ory-am/hydra/internal"
-	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/ladon"
-	"github.com/pborman/uuid"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 	r "gopkg.in/dancannon/gorethink.v2"
-	"gopkg.in/ory-am/dockertest.v2"
 )
 
-var connections = []*Connection{
-	{
-		ID:            uuid.New(),
-		LocalSubject:  "peter",
-		RemoteSubject: "peterson",
-		Provider:      "google",
-	},
-}
-
 var managers = map[string]Manager{
 	"memory": NewMemoryManager(),
 }
@@ -70,39 +58,31 @@ func init() {
 var rethinkManager *RethinkManager
 
 func TestMain(m *testing.M) {
-	var session *r.Session
-	var err error
+	session := integration.ConnectToRethinkDB("hydra", "hydra_clients")
+	defer session.Close()
 
-	c, err := dockertest.ConnectToRethinkDB(20, time.Second, func(url string) bool {
-		if session, err = r.Connect(r.ConnectOpts{Address: url, Database: "hydra"}); err != nil {
-			return false
-		} else if _, err = r.DBCreate("hydra").RunWrite(session); err != nil {
-			log.Printf("Database exists: %s", err)
-			return false
-		} else if _, err = r.TableCreate("hydra_clients").RunWrite(session); err != nil {
-			log.Printf("Could not create table: %s", err)
-			return false
-		}
+	rethinkManager = &RethinkManager{
+		Session:     session,
+		Table:       r.Table("hydra_clients"),
+		Connections: make(map[string]*Connection),
+	}
+	rethinkManager.Watch(context.Background())
+	time.Sleep(500 * time.Millisecond)
+	managers["rethink"] = rethinkManager
 
-		rethinkManager = &RethinkManager{
-			Session:     session,
-			Table:       r.Table("hydra_clients"),
-			Connections: make(map[string]*Connection),
+	for driver, db := range map[string]*sql.DB{
+		"postgres": integration.ConnectToPostgres(),
+		"mysql":    integration.ConnectToMySQL(),
+	} {
+		sm := &SQLManager{DB: db, Driver: driver}
+		if err := sm.CreateSchemas(); err != nil {
+			log.Fatalf("Could not create %s schemas: %s", driver, err)
 		}
-		rethinkManager.Watch(context.Background())
-		time.Sleep(500 * time.Millisecond)
-		return true
-	})
-	if session != nil {
-		defer session.Close()
-	}
-	if err != nil {
-		log.Fatalf("Could not connect to database: %s", err)
+		managers[driver] = sm
 	}
-	managers["rethink"] = rethinkManager
 
 	retCode := m.Run()
-	c.KillRemove()
+	integration.KillAll()
 	os.Exit(retCode)
 }
 
@@ -128,36 +108,7 @@ func BenchmarkRethinkGet(b *testing.B) {
 }
 
 func TestCreateGetFindDelete(t *testing.T) {
-	for _, store := range managers {
-		for _, c := range connections {
-			_, err := store.Get("asdf")
-			pkg.RequireError(t, true, err)
-
-			err = store.Create(c)
-			pkg.RequireError(t, false, err)
-
-			time.Sleep(100 * time.Millisecond)
-
-			res, err := store.Get(c.GetID())
-			pkg.RequireError(t, false, err)
-			require.Equal(t, c, res)
-
-			cs, err := store.FindAllByLocalSubject("peter")
-			pkg.RequireError(t, false, err)
-			assert.Len(t, cs, 1)
-			require.Equal(t, c, cs[0])
-
-			res, err = store.FindByRemoteSubject("google", "peterson")
-			pkg.RequireError(t, false, err)
-			require.Equal(t, c, res)
-
-			err = store.Delete(c.GetID())
-			pkg.RequireError(t, false, err)
-
-			time.Sleep(100 * time.Millisecond)
-
-			_, err = store.Get(c.GetID())
-			pkg.RequireError(t, true, err)
-		}
+	for name, m := range managers {
+		TestHelperCreateGetFindDelete(t, name, m)
 	}
 }
@@ -0,0 +1,52 @@
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory-am/hydra/pkg"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperCreateGetFindDelete is the conformance suite of Manager. Backends of this repository and downstream
+// backends run it against their managers, see package integration.
+func TestHelperCreateGetFindDelete(t *testing.T, name string, m Manager) {
+	c := &Connection{
+		ID:            uuid.New(),
+		LocalSubject:  "peter",
+		RemoteSubject: "peterson",
+		Provider:      "google",
+	}
+
+	_, err := m.Get("asdf")
+	pkg.RequireError(t, true, err, name)
+
+	err = m.Create(c)
+	pkg.RequireError(t, false, err, name)
+
+	// Managers which update their cache through a changefeed need time to catch up.
+	time.Sleep(100 * time.Millisecond)
+
+	res, err := m.Get(c.GetID())
+	pkg.RequireError(t, false, err, name)
+	require.Equal(t, c, res, name)
+
+	cs, err := m.FindAllByLocalSubject("peter")
+	pkg.RequireError(t, false, err, name)
+	assert.Len(t, cs, 1, name)
+	require.Equal(t, c, cs[0], name)
+
+	res, err = m.FindByRemoteSubject("google", "peterson")
+	pkg.RequireError(t, false, err, name)
+	require.Equal(t, c, res, name)
+
+	err = m.Delete(c.GetID())
+	pkg.RequireError(t, false, err, name)
+
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = m.Get(c.GetID())
+	pkg.RequireError(t, true, err, name)
+}
@@ -0,0 +1,34 @@
+package connection
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "GET", Path: "/connections", ID: "findConnections", Tag: "connections", Scope: scope,
+		Summary:    "List the connections of a local subject, or of a remote subject at a provider",
+		Query:      []string{"local_subject", "remote_subject", "provider"},
+		Pagination: []string{"id", "provider"},
+		Responses:  map[int]interface{}{http.StatusOK: []*Connection{}},
+	},
+	{
+		Method: "POST", Path: "/connections", ID: "createConnection", Tag: "connections", Scope: scope,
+		Summary:   "Create a connection",
+		Body:      &Connection{},
+		Responses: map[int]interface{}{http.StatusCreated: &Connection{}},
+	},
+	{
+		Method: "GET", Path: "/connections/:id", ID: "getConnection", Tag: "connections", Scope: scope,
+		Summary:   "Get a connection",
+		Responses: map[int]interface{}{http.StatusOK: &Connection{}},
+	},
+	{
+		Method: "DELETE", Path: "/connections/:id", ID: "deleteConnection", Tag: "connections", Scope: scope,
+		Summary:   "Delete a connection",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+}
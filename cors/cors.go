@@ -0,0 +1,204 @@
+// Package cors answers cross-origin requests of browser based apps. Endpoints are split into groups by path, every
+// group has its own allowed origins, and groups of public endpoints may additionally allow the origins a client
+// registered for itself.
+//
+// Only origins which are configured explicitly may send credentials, that is the cookies of the login session and of
+// remembered consent. Origins allowed by * or by a client are answered without Access-Control-Allow-Credentials, as
+// anybody may register a client which allows their own origin.
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+const (
+	// GroupPublic are the endpoints browser based apps talk to, like /oauth2/token and the JWKS endpoint.
+	GroupPublic = "public"
+
+	// GroupAdmin are all other endpoints.
+	GroupAdmin = "admin"
+)
+
+// DefaultMaxAge is how long browsers may cache the answer to a preflight request.
+const DefaultMaxAge = time.Hour
+
+// ClientOrigins tells whether a client allows cross-origin requests from an origin.
+type ClientOrigins interface {
+	IsOriginAllowed(clientID, origin string) bool
+}
+
+// Group applies to all endpoints whose path starts with one of Prefixes.
+type Group struct {
+	Name     string
+	Prefixes []string
+
+	// AllowedOrigins are origins like https://app.example.com, which may send credentials. The origin * allows all
+	// origins without credentials.
+	AllowedOrigins []string
+
+	// AllowClientOrigins allows the origins of the client a request is sent by without credentials, in addition to
+	// AllowedOrigins.
+	AllowClientOrigins bool
+}
+
+// allowsOrigin tells whether the origin is configured explicitly and whether * is configured.
+func (g *Group) allowsOrigin(origin string) (explicit bool, wildcard bool) {
+	for _, o := range g.AllowedOrigins {
+		if o == origin {
+			explicit = true
+		} else if o == "*" {
+			wildcard = true
+		}
+	}
+	return explicit, wildcard
+}
+
+// Middleware adds CORS headers to the responses of groups which allow the origin of a request and answers preflight
+// requests. Requests to paths which belong to no group are passed on untouched.
+type Middleware struct {
+	Groups  []Group
+	Clients ClientOrigins
+
+	// MaxAge is DefaultMaxAge if zero.
+	MaxAge time.Duration
+
+	sync.RWMutex
+}
+
+// SetAllowedOrigins replaces the allowed origins of every group by the origins of the group's name, for example
+// after the configuration was reloaded.
+func (m *Middleware) SetAllowedOrigins(origins map[string][]string) {
+	m.Lock()
+	defer m.Unlock()
+
+	for k := range m.Groups {
+		m.Groups[k].AllowedOrigins = origins[m.Groups[k].Name]
+	}
+}
+
+const (
+	allowedMethods = "GET, POST, PUT, DELETE"
+	allowedHeaders = "Authorization, Content-Type, Accept, If-Match, Idempotency-Key"
+	exposedHeaders = "Location, Retry-After, X-Request-ID, ETag"
+)
+
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		m.RLock()
+		g := m.group(r.URL.Path)
+		m.RUnlock()
+		if origin == "" || g == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		explicit, wildcard := g.allowsOrigin(origin)
+		preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			// Preflight requests do not carry credentials, so the client is not known yet. The response to the
+			// actual request is only readable if the client allows the origin.
+			if explicit || wildcard || (g.AllowClientOrigins && m.Clients != nil) {
+				maxAge := m.MaxAge
+				if maxAge == 0 {
+					maxAge = DefaultMaxAge
+				}
+				allowOrigin(w, origin, explicit, wildcard)
+				w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if explicit || wildcard || (g.AllowClientOrigins && m.clientAllowsOrigin(r, origin)) {
+			allowOrigin(w, origin, explicit, wildcard)
+			w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowOrigin allows credentials only for explicitly configured origins. Other origins are answered with * if the
+// group allows all origins, which browsers never combine with credentials.
+func allowOrigin(w http.ResponseWriter, origin string, explicit, wildcard bool) {
+	if explicit {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	} else if wildcard {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+}
+
+// group returns a copy of the group with the longest prefix of path or nil.
+func (m *Middleware) group(path string) *Group {
+	var match *Group
+	var length = -1
+	for k, g := range m.Groups {
+		for _, prefix := range g.Prefixes {
+			if strings.HasPrefix(path, prefix) && len(prefix) > length {
+				match, length = &m.Groups[k], len(prefix)
+			}
+		}
+	}
+	if match == nil {
+		return nil
+	}
+	g := *match
+	return &g
+}
+
+// clientAllowsOrigin identifies the client by basic auth or the client_id parameter of the query or form. The client
+// id is taken from the request itself, so it never grants credentials.
+func (m *Middleware) clientAllowsOrigin(r *http.Request, origin string) bool {
+	if m.Clients == nil {
+		return false
+	}
+
+	id, _, ok := r.BasicAuth()
+	if !ok {
+		id = r.FormValue("client_id")
+	}
+	return id != "" && m.Clients.IsOriginAllowed(id, origin)
+}
+
+// ParseOrigins parses a specification like "public=https://app.example.com,admin=https://console.example.com" into
+// the allowed origins of every group. A group may be listed more than once.
+func ParseOrigins(spec string) (map[string][]string, error) {
+	origins := map[string][]string{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || (parts[0] != GroupPublic && parts[0] != GroupAdmin) {
+			return nil, errors.Errorf("CORS origin %s must look like public=https://app.example.com or admin=https://console.example.com", entry)
+		} else if err := ValidateOrigin(parts[1]); err != nil && parts[1] != "*" {
+			return nil, err
+		}
+		origins[parts[0]] = append(origins[parts[0]], parts[1])
+	}
+	return origins, nil
+}
+
+// ValidateOrigin checks that origin consists of a scheme, a host and optionally a port, and nothing else.
+func ValidateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return errors.Errorf("Origin %s must look like https://app.example.com", origin)
+	}
+	return nil
+}
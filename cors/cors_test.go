@@ -0,0 +1,128 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clientOrigins map[string]string
+
+func (o clientOrigins) IsOriginAllowed(clientID, origin string) bool {
+	return o[clientID] == origin
+}
+
+func TestMiddleware(t *testing.T) {
+	m := &Middleware{
+		Groups: []Group{
+			{Name: GroupPublic, Prefixes: []string{"/oauth2/"}, AllowedOrigins: []string{"https://app.example.com"}, AllowClientOrigins: true},
+			{Name: GroupAdmin, Prefixes: []string{"/", "/oauth2/tokens"}, AllowedOrigins: []string{"https://console.example.com"}},
+		},
+		Clients: clientOrigins{"spa": "https://spa.example.com"},
+	}
+	handler := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for k, c := range []struct {
+		method      string
+		path        string
+		origin      string
+		client      string
+		preflight   bool
+		allowed     bool
+		credentials bool
+	}{
+		{method: "POST", path: "/oauth2/token", origin: "https://app.example.com", allowed: true, credentials: true},
+		{method: "POST", path: "/oauth2/token", origin: "https://spa.example.com", client: "spa", allowed: true},
+		{method: "POST", path: "/oauth2/token", origin: "https://spa.example.com", client: "other", allowed: false},
+		{method: "POST", path: "/oauth2/token", origin: "https://console.example.com", allowed: false},
+		{method: "OPTIONS", path: "/oauth2/token", origin: "https://spa.example.com", preflight: true, allowed: true},
+		{method: "OPTIONS", path: "/oauth2/token", origin: "https://app.example.com", preflight: true, allowed: true, credentials: true},
+		{method: "GET", path: "/clients", origin: "https://console.example.com", allowed: true, credentials: true},
+		{method: "GET", path: "/oauth2/tokens", origin: "https://app.example.com", allowed: false},
+		{method: "OPTIONS", path: "/clients", origin: "https://spa.example.com", preflight: true, allowed: false},
+	} {
+		r, err := http.NewRequest(c.method, "http://localhost"+c.path, nil)
+		require.Nil(t, err)
+		r.Header.Set("Origin", c.origin)
+		if c.client != "" {
+			r.SetBasicAuth(c.client, "secret")
+		}
+		if c.preflight {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if c.allowed {
+			assert.Equal(t, c.origin, w.Header().Get("Access-Control-Allow-Origin"), "%d", k)
+		} else {
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), "%d", k)
+		}
+		if c.credentials {
+			assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"), "%d", k)
+		} else {
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"), "%d", k)
+		}
+		if c.preflight {
+			assert.Equal(t, http.StatusNoContent, w.Code, "%d", k)
+		}
+	}
+
+	m.SetAllowedOrigins(map[string][]string{GroupAdmin: {"https://admin.example.com"}})
+	r, err := http.NewRequest("GET", "http://localhost/clients", nil)
+	require.Nil(t, err)
+	r.Header.Set("Origin", "https://admin.example.com")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestMiddlewareWildcard(t *testing.T) {
+	m := &Middleware{
+		Groups: []Group{{Name: GroupPublic, Prefixes: []string{"/"}, AllowedOrigins: []string{"*", "https://app.example.com"}}},
+	}
+	handler := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for k, c := range []struct {
+		origin      string
+		allowOrigin string
+		credentials bool
+	}{
+		{origin: "https://attacker.example.com", allowOrigin: "*"},
+		{origin: "https://app.example.com", allowOrigin: "https://app.example.com", credentials: true},
+	} {
+		for _, preflight := range []bool{false, true} {
+			r, err := http.NewRequest("POST", "http://localhost/oauth2/token", nil)
+			require.Nil(t, err)
+			r.Header.Set("Origin", c.origin)
+			if preflight {
+				r.Method = "OPTIONS"
+				r.Header.Set("Access-Control-Request-Method", "POST")
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, c.allowOrigin, w.Header().Get("Access-Control-Allow-Origin"), "%d", k)
+			assert.Equal(t, c.credentials, w.Header().Get("Access-Control-Allow-Credentials") == "true", "%d", k)
+		}
+	}
+}
+
+func TestParseOrigins(t *testing.T) {
+	origins, err := ParseOrigins("public=https://app.example.com, public=*,admin=http://localhost:3000")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"https://app.example.com", "*"}, origins[GroupPublic])
+	assert.Equal(t, []string{"http://localhost:3000"}, origins[GroupAdmin])
+
+	for _, spec := range []string{"https://app.example.com", "other=https://app.example.com", "public=https://app.example.com/callback"} {
+		_, err := ParseOrigins(spec)
+		assert.NotNil(t, err, "%s", spec)
+	}
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker is a circuit breaker. It opens after Threshold consecutive failures and rejects calls until Cooldown has
+// passed. Then it lets a single trial call through which closes it again on success.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	failures int
+	openedAt time.Time
+	trial    bool
+	sync.Mutex
+}
+
+// Allow returns true if a call may be made.
+func (b *Breaker) Allow() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.failures < b.Threshold {
+		return true
+	} else if b.trial || time.Since(b.openedAt) < b.Cooldown {
+		return false
+	}
+
+	b.trial = true
+	return true
+}
+
+// Success records a successful call and closes the breaker.
+func (b *Breaker) Success() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+// Failure records a failed call. A failed trial call opens the breaker for another cooldown.
+func (b *Breaker) Failure() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.Threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// IsOpen returns true if the breaker rejects calls.
+func (b *Breaker) IsOpen() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.failures >= b.Threshold
+}
@@ -0,0 +1,27 @@
+package events
+
+import "time"
+
+// DeadLetter is an event which could not be delivered to a destination. It is kept until it is redelivered or
+// discarded through the dead letter endpoints.
+type DeadLetter struct {
+	ID          string    `json:"id" gorethink:"id"`
+	Destination string    `json:"destination" gorethink:"destination"`
+	Event       *Event    `json:"event" gorethink:"event"`
+	Attempts    int       `json:"attempts" gorethink:"attempts"`
+	Error       string    `json:"error" gorethink:"error"`
+	FailedAt    time.Time `json:"failed_at" gorethink:"failed_at"`
+}
+
+// DeadLetterManager persists dead letters.
+type DeadLetterManager interface {
+	AddDeadLetter(d *DeadLetter) error
+
+	// GetDeadLetter returns a dead letter or pkg.ErrNotFound.
+	GetDeadLetter(id string) (*DeadLetter, error)
+
+	// GetDeadLetters returns all dead letters, oldest first.
+	GetDeadLetters() ([]*DeadLetter, error)
+
+	DeleteDeadLetter(id string) error
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type DeadLetterMemoryManager struct {
+	DeadLetters map[string]*DeadLetter
+	sync.RWMutex
+}
+
+func NewDeadLetterMemoryManager() *DeadLetterMemoryManager {
+	return &DeadLetterMemoryManager{
+		DeadLetters: map[string]*DeadLetter{},
+	}
+}
+
+func (m *DeadLetterMemoryManager) AddDeadLetter(d *DeadLetter) error {
+	m.Lock()
+	defer m.Unlock()
+
+	c := *d
+	m.DeadLetters[d.ID] = &c
+	return nil
+}
+
+func (m *DeadLetterMemoryManager) GetDeadLetter(id string) (*DeadLetter, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	d, ok := m.DeadLetters[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	c := *d
+	return &c, nil
+}
+
+func (m *DeadLetterMemoryManager) GetDeadLetters() ([]*DeadLetter, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	result := make([]*DeadLetter, 0, len(m.DeadLetters))
+	for _, d := range m.DeadLetters {
+		c := *d
+		result = append(result, &c)
+	}
+	sort.Sort(byFailedAt(result))
+	return result, nil
+}
+
+func (m *DeadLetterMemoryManager) DeleteDeadLetter(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.DeadLetters[id]; !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+	delete(m.DeadLetters, id)
+	return nil
+}
+
+type byFailedAt []*DeadLetter
+
+func (s byFailedAt) Len() int           { return len(s) }
+func (s byFailedAt) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byFailedAt) Less(i, j int) bool { return s[i].FailedAt.Before(s[j].FailedAt) }
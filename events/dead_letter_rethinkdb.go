@@ -0,0 +1,61 @@
+package events
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// DeadLetterRethinkManager reads and writes directly against the database so that every instance sees the dead
+// letters of the others.
+type DeadLetterRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *DeadLetterRethinkManager) AddDeadLetter(d *DeadLetter) error {
+	if _, err := m.Table.Insert(d).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *DeadLetterRethinkManager) GetDeadLetter(id string) (*DeadLetter, error) {
+	res, err := m.Table.Get(id).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var d DeadLetter
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&d); err != nil {
+		return nil, errors.New(err)
+	}
+	return &d, nil
+}
+
+func (m *DeadLetterRethinkManager) GetDeadLetters() ([]*DeadLetter, error) {
+	res, err := m.Table.OrderBy("failed_at").Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var result []*DeadLetter
+	if err := res.All(&result); err != nil {
+		return nil, errors.New(err)
+	}
+	return result, nil
+}
+
+func (m *DeadLetterRethinkManager) DeleteDeadLetter(id string) error {
+	res, err := m.Table.Get(id).Delete().RunWrite(m.Session)
+	if err != nil {
+		return errors.New(err)
+	} else if res.Deleted == 0 {
+		return errors.New(pkg.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Event is a notification about something that happened inside hydra, for example a quota reaching a warning
+// threshold.
+type Event struct {
+	Type string                 `json:"type" gorethink:"type"`
+	Time time.Time              `json:"time" gorethink:"time"`
+	Data map[string]interface{} `json:"data,omitempty" gorethink:"data,omitempty"`
+}
+
+// New creates an event of the given type which happened now.
+func New(eventType string, data map[string]interface{}) *Event {
+	return &Event{
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+}
+
+// Publisher delivers events to whoever is interested in them. Publish must not block the caller for long.
+type Publisher interface {
+	Publish(e *Event)
+}
+
+// LogPublisher writes events to the log.
+type LogPublisher struct{}
+
+func (p *LogPublisher) Publish(e *Event) {
+	logrus.WithFields(logrus.Fields(e.Data)).WithField("event", e.Type).Warn("Event published")
+}
+
+// Publishers fans an event out to several publishers.
+type Publishers []Publisher
+
+func (ps Publishers) Publish(e *Event) {
+	for _, p := range ps {
+		p.Publish(e)
+	}
+}
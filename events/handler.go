@@ -0,0 +1,154 @@
+package events
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/pagination"
+	"github.com/ory-am/ladon"
+)
+
+const (
+	DeadLettersHandlerPath = "/admin/events/dead-letters"
+
+	deadLettersResource = "rn:hydra:events:dead-letters"
+	deadLetterResource  = "rn:hydra:events:dead-letters:%s"
+	scope               = "hydra.events"
+)
+
+// Handler lets administrators inspect, redeliver and discard dead letters.
+type Handler struct {
+	Manager   DeadLetterManager
+	Publisher *WebhookPublisher
+	H         herodot.Herodot
+	W         firewall.Firewall
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.GET(DeadLettersHandlerPath, h.GetAll)
+	r.GET(DeadLettersHandlerPath+"/:id", h.Get)
+	r.POST(DeadLettersHandlerPath+"/:id/redeliver", h.Redeliver)
+	r.DELETE(DeadLettersHandlerPath+"/:id", h.Delete)
+}
+
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: deadLettersResource,
+		Action:   "get",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	page, err := pagination.Parse(r, "failed_at", "id", "destination")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	letters, err := h.Manager.GetDeadLetters()
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	indices, total := page.Apply(len(letters), func(i int, field string) string {
+		switch field {
+		case "failed_at":
+			return letters[i].FailedAt.UTC().Format(time.RFC3339)
+		case "id":
+			return letters[i].ID
+		case "destination":
+			return letters[i].Destination
+		}
+		return ""
+	})
+
+	res := make([]*DeadLetter, len(indices))
+	for k, i := range indices {
+		res[k] = letters[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(deadLetterResource, id),
+		Action:   "get",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	d, err := h.Manager.GetDeadLetter(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, d)
+}
+
+// Redeliver sends a dead letter to its destination right away and removes it if the destination accepted it. If the
+// delivery fails, the dead letter is kept and the response is 502 Bad Gateway.
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(deadLetterResource, id),
+		Action:   "redeliver",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	d, err := h.Manager.GetDeadLetter(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Publisher.Redeliver(d); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	if err := h.Manager.DeleteDeadLetter(id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(deadLetterResource, id),
+		Action:   "delete",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Manager.DeleteDeadLetter(id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// DefaultPseudonymRotation is how long a subject keeps its pseudonym if the pseudonymizer does not say otherwise.
+const DefaultPseudonymRotation = 30 * 24 * time.Hour
+
+// DefaultPseudonymizedFields are the fields of event data which identify subjects.
+var DefaultPseudonymizedFields = []string{"subject", "sub", "local_subject", "owner"}
+
+// Pseudonymizer replaces a subject identifier with a pseudonym. The same subject must get the same pseudonym for
+// a while, so that data teams can correlate events without learning who the subject is.
+type Pseudonymizer interface {
+	Pseudonymize(subject string, at time.Time) string
+}
+
+// HMACPseudonymizer derives pseudonyms with HMAC-SHA256. The key is derived from Secret and rotated every
+// Rotation, pseudonyms of different periods can not be linked. Pseudonyms are prefixed with their period.
+type HMACPseudonymizer struct {
+	Secret []byte
+
+	// Rotation is DefaultPseudonymRotation if zero.
+	Rotation time.Duration
+}
+
+func (p *HMACPseudonymizer) Pseudonymize(subject string, at time.Time) string {
+	rotation := p.Rotation
+	if rotation <= 0 {
+		rotation = DefaultPseudonymRotation
+	}
+	period := strconv.FormatInt(at.UnixNano()/int64(rotation), 10)
+
+	key := hmac.New(sha256.New, p.Secret)
+	key.Write([]byte("pseudonym:" + period))
+
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	mac.Write([]byte(subject))
+	return "p" + period + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18])
+}
+
+// PseudonymizingPublisher replaces subject identifiers in the data of events before handing them to Publisher.
+// Wrap the publishers which export events for analysis with it, and leave the ones which keep an audit trail as
+// they are.
+type PseudonymizingPublisher struct {
+	Publisher     Publisher
+	Pseudonymizer Pseudonymizer
+
+	// Fields are DefaultPseudonymizedFields if empty.
+	Fields []string
+}
+
+func (p *PseudonymizingPublisher) Publish(e *Event) {
+	fields := p.Fields
+	if len(fields) == 0 {
+		fields = DefaultPseudonymizedFields
+	}
+
+	// The event is shared with the other publishers, so the data is copied.
+	data := make(map[string]interface{}, len(e.Data))
+	for k, v := range e.Data {
+		data[k] = v
+	}
+	for _, field := range fields {
+		if subject, ok := data[field].(string); ok && subject != "" {
+			data[field] = p.Pseudonymizer.Pseudonymize(subject, e.Time)
+		}
+	}
+
+	c := *e
+	c.Data = data
+	p.Publisher.Publish(&c)
+}
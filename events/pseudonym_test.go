@@ -0,0 +1,43 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/ory-am/hydra/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	events []*Event
+}
+
+func (r *recorder) Publish(e *Event) {
+	r.events = append(r.events, e)
+}
+
+func TestHMACPseudonymizer(t *testing.T) {
+	p := &HMACPseudonymizer{Secret: []byte("some-secret"), Rotation: time.Hour}
+	now := time.Unix(7200, 0)
+
+	assert.Equal(t, p.Pseudonymize("peter", now), p.Pseudonymize("peter", now.Add(time.Minute)))
+	assert.NotEqual(t, p.Pseudonymize("peter", now), p.Pseudonymize("alice", now))
+	assert.NotEqual(t, p.Pseudonymize("peter", now), p.Pseudonymize("peter", now.Add(time.Hour)), "Pseudonyms must change with the key")
+	assert.NotEqual(t, p.Pseudonymize("peter", now), (&HMACPseudonymizer{Secret: []byte("other-secret"), Rotation: time.Hour}).Pseudonymize("peter", now))
+	assert.NotContains(t, p.Pseudonymize("peter", now), "peter")
+}
+
+func TestPseudonymizingPublisher(t *testing.T) {
+	exported := new(recorder)
+	audited := new(recorder)
+	p := &HMACPseudonymizer{Secret: []byte("some-secret")}
+	publisher := Publishers{audited, &PseudonymizingPublisher{Publisher: exported, Pseudonymizer: p}}
+
+	e := New("login", map[string]interface{}{"subject": "peter", "client_id": "app"})
+	publisher.Publish(e)
+
+	assert.Equal(t, "peter", audited.events[0].Data["subject"])
+	assert.Equal(t, p.Pseudonymize("peter", e.Time), exported.events[0].Data["subject"])
+	assert.Equal(t, "app", exported.events[0].Data["client_id"])
+	assert.Equal(t, "peter", e.Data["subject"], "The published event must not be modified")
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "GET", Path: DeadLettersHandlerPath, ID: "listDeadLetters", Tag: "events", Scope: scope,
+		Summary:    "List the events which could not be delivered",
+		Pagination: []string{"failed_at", "id", "destination"},
+		Responses:  map[int]interface{}{http.StatusOK: []*DeadLetter{}},
+	},
+	{
+		Method: "GET", Path: DeadLettersHandlerPath + "/:id", ID: "getDeadLetter", Tag: "events", Scope: scope,
+		Summary:   "Get an event which could not be delivered",
+		Responses: map[int]interface{}{http.StatusOK: &DeadLetter{}},
+	},
+	{
+		Method: "POST", Path: DeadLettersHandlerPath + "/:id/redeliver", ID: "redeliverDeadLetter", Tag: "events", Scope: scope,
+		Summary:   "Deliver an event again",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "DELETE", Path: DeadLettersHandlerPath + "/:id", ID: "deleteDeadLetter", Tag: "events", Scope: scope,
+		Summary:   "Discard an event which could not be delivered",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+}
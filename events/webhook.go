@@ -0,0 +1,278 @@
+package events
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/ory-am/hydra/logger"
+	"github.com/pborman/uuid"
+)
+
+// The defaults of WebhookPublisher.
+const (
+	DefaultWebhookWorkers     = 4
+	DefaultWebhookQueueSize   = 1000
+	DefaultWebhookAttempts    = 5
+	DefaultWebhookBackoff     = time.Second
+	DefaultWebhookMaxBackoff  = 5 * time.Minute
+	DefaultBreakerThreshold   = 5
+	DefaultBreakerCooldown    = time.Minute
+	DefaultWebhookHTTPTimeout = 10 * time.Second
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body if the publisher has a secret.
+const SignatureHeader = "X-Hydra-Signature"
+
+// Destination is a receiver of webhooks.
+type Destination struct {
+	URL string
+
+	// Types restricts the destination to events of these types, all events are delivered if it is empty.
+	Types []string
+}
+
+func (d *Destination) accepts(e *Event) bool {
+	if len(d.Types) == 0 {
+		return true
+	}
+	for _, t := range d.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPublisher posts events as canonical JSON to destinations. Deliveries run on a bounded pool of workers,
+// failed deliveries are retried with exponential backoff without occupying a worker while waiting.
+//
+// Each destination has its own circuit breaker. While it is open, deliveries to the destination fail right away
+// instead of tying up workers with requests which time out, so one dead receiver does not delay the others.
+// Deliveries which fail MaxAttempts times are stored as dead letters and can be redelivered later.
+type WebhookPublisher struct {
+	Destinations []Destination
+
+	// Secret signs request bodies, see SignatureHeader.
+	Secret []byte
+
+	// Client sends the requests. It defaults to a client which keeps idle connections to every destination.
+	Client *http.Client
+
+	// DeadLetters stores undeliverable events. They are logged and dropped if it is nil.
+	DeadLetters DeadLetterManager
+
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	once     sync.Once
+	queue    chan *delivery
+	breakers map[string]*Breaker
+	sync.Mutex
+}
+
+type delivery struct {
+	destination string
+	event       *Event
+	attempts    int
+}
+
+func (p *WebhookPublisher) init() {
+	p.once.Do(func() {
+		if p.Workers < 1 {
+			p.Workers = DefaultWebhookWorkers
+		}
+		if p.QueueSize < 1 {
+			p.QueueSize = DefaultWebhookQueueSize
+		}
+		if p.MaxAttempts < 1 {
+			p.MaxAttempts = DefaultWebhookAttempts
+		}
+		if p.Backoff == 0 {
+			p.Backoff = DefaultWebhookBackoff
+		}
+		if p.MaxBackoff == 0 {
+			p.MaxBackoff = DefaultWebhookMaxBackoff
+		}
+		if p.BreakerThreshold < 1 {
+			p.BreakerThreshold = DefaultBreakerThreshold
+		}
+		if p.BreakerCooldown == 0 {
+			p.BreakerCooldown = DefaultBreakerCooldown
+		}
+		if p.Client == nil {
+			p.Client = &http.Client{
+				Timeout: DefaultWebhookHTTPTimeout,
+				Transport: &http.Transport{
+					Proxy:               http.ProxyFromEnvironment,
+					MaxIdleConnsPerHost: p.Workers,
+				},
+			}
+		}
+
+		p.breakers = map[string]*Breaker{}
+		p.queue = make(chan *delivery, p.QueueSize)
+		for i := 0; i < p.Workers; i++ {
+			go p.work()
+		}
+	})
+}
+
+// Publish queues the event for every destination which accepts it. It does not block, events which do not fit
+// into the queue become dead letters.
+func (p *WebhookPublisher) Publish(e *Event) {
+	p.init()
+	for _, d := range p.Destinations {
+		if d.accepts(e) {
+			p.enqueue(&delivery{destination: d.URL, event: e})
+		}
+	}
+}
+
+// Redeliver sends a dead letter once, regardless of the state of the destination's circuit breaker. A successful
+// redelivery closes the circuit.
+func (p *WebhookPublisher) Redeliver(d *DeadLetter) error {
+	p.init()
+
+	b := p.breaker(d.Destination)
+	if err := p.send(d.Destination, d.Event); err != nil {
+		b.Failure()
+		return err
+	}
+	b.Success()
+	return nil
+}
+
+// IsOpen returns true if the circuit breaker of destination is open.
+func (p *WebhookPublisher) IsOpen(destination string) bool {
+	p.init()
+	return p.breaker(destination).IsOpen()
+}
+
+func (p *WebhookPublisher) enqueue(d *delivery) {
+	select {
+	case p.queue <- d:
+	default:
+		p.deadLetter(d, errors.New("The delivery queue is full"))
+	}
+}
+
+func (p *WebhookPublisher) work() {
+	for d := range p.queue {
+		p.attempt(d)
+	}
+}
+
+func (p *WebhookPublisher) attempt(d *delivery) {
+	d.attempts++
+
+	var err error
+	if b := p.breaker(d.destination); !b.Allow() {
+		err = errors.New("The circuit breaker of the destination is open")
+	} else if err = p.send(d.destination, d.event); err == nil {
+		b.Success()
+		return
+	} else {
+		b.Failure()
+	}
+
+	if d.attempts >= p.MaxAttempts {
+		p.deadLetter(d, err)
+		return
+	}
+	time.AfterFunc(p.backoff(d.attempts), func() { p.enqueue(d) })
+}
+
+// backoff returns the time to wait after the given number of failed attempts.
+func (p *WebhookPublisher) backoff(attempts int) time.Duration {
+	wait := p.Backoff
+	for i := 1; i < attempts && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return wait
+}
+
+func (p *WebhookPublisher) breaker(destination string) *Breaker {
+	p.Lock()
+	defer p.Unlock()
+
+	b, ok := p.breakers[destination]
+	if !ok {
+		b = &Breaker{Threshold: p.BreakerThreshold, Cooldown: p.BreakerCooldown}
+		p.breakers[destination] = b
+	}
+	return b
+}
+
+func (p *WebhookPublisher) send(destination string, e *Event) error {
+	body, err := canonical.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", destination, bytes.NewReader(body))
+	if err != nil {
+		return errors.New(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hydra-Event", e.Type)
+	if len(p.Secret) > 0 {
+		mac := hmac.New(sha256.New, p.Secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := p.Client.Do(req)
+	if err != nil {
+		return errors.New(err)
+	}
+	defer res.Body.Close()
+
+	// Drain the body so that the connection can be reused.
+	io.Copy(ioutil.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.Errorf("Destination answered with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func (p *WebhookPublisher) deadLetter(d *delivery, reason error) {
+	logrus.WithFields(logrus.Fields{
+		"event":       d.event.Type,
+		"destination": d.destination,
+		"attempts":    d.attempts,
+	}).Warnf("Could not deliver event: %s", reason)
+
+	if p.DeadLetters == nil {
+		return
+	}
+
+	if err := p.DeadLetters.AddDeadLetter(&DeadLetter{
+		ID:          uuid.New(),
+		Destination: d.destination,
+		Event:       d.event,
+		Attempts:    d.attempts,
+		Error:       reason.Error(),
+		FailedAt:    time.Now().UTC(),
+	}); err != nil {
+		logger.LogError(err)
+	}
+}
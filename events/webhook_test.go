@@ -0,0 +1,136 @@
+package events_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/ory-am/hydra/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receiver struct {
+	status   int
+	received []string
+	sync.Mutex
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.Lock()
+	defer rc.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	if r.Header.Get(SignatureHeader) != hex.EncodeToString(mac.Sum(nil)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rc.received = append(rc.received, r.Header.Get("X-Hydra-Event"))
+	w.WriteHeader(rc.status)
+}
+
+func (rc *receiver) count() int {
+	rc.Lock()
+	defer rc.Unlock()
+	return len(rc.received)
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	for i := 0; i < 200; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Condition was not met in time")
+}
+
+func TestBreaker(t *testing.T) {
+	b := &Breaker{Threshold: 2, Cooldown: 20 * time.Millisecond}
+	assert.True(t, b.Allow())
+	b.Failure()
+	assert.True(t, b.Allow())
+	b.Failure()
+	assert.True(t, b.IsOpen())
+	assert.False(t, b.Allow())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow(), "only one trial call is allowed while half open")
+	b.Failure()
+	assert.False(t, b.Allow())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.False(t, b.IsOpen())
+	assert.True(t, b.Allow())
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	healthy := &receiver{status: http.StatusOK}
+	dead := &receiver{status: http.StatusInternalServerError}
+	hs, ds := httptest.NewServer(healthy), httptest.NewServer(dead)
+	defer hs.Close()
+	defer ds.Close()
+
+	letters := NewDeadLetterMemoryManager()
+	p := &WebhookPublisher{
+		Destinations: []Destination{
+			{URL: hs.URL},
+			{URL: ds.URL},
+			{URL: hs.URL + "/filtered", Types: []string{"quota.exceeded"}},
+		},
+		Secret:           []byte("secret"),
+		DeadLetters:      letters,
+		Workers:          1,
+		MaxAttempts:      3,
+		Backoff:          time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	}
+
+	for i := 0; i < 5; i++ {
+		p.Publish(New("quota.warning", map[string]interface{}{"quota": "clients"}))
+	}
+
+	eventually(t, func() bool {
+		all, err := letters.GetDeadLetters()
+		require.Nil(t, err)
+		return len(all) == 5
+	})
+	assert.Equal(t, 5, healthy.count())
+	assert.True(t, p.IsOpen(ds.URL))
+	assert.False(t, p.IsOpen(hs.URL))
+
+	// The open circuit keeps further deliveries from reaching the dead receiver.
+	assert.Equal(t, 2, dead.count())
+
+	all, err := letters.GetDeadLetters()
+	require.Nil(t, err)
+	for k, d := range all {
+		assert.Equal(t, ds.URL, d.Destination, "Case %d", k)
+		assert.Equal(t, "quota.warning", d.Event.Type, "Case %d", k)
+		assert.Equal(t, 3, d.Attempts, "Case %d", k)
+		assert.NotEmpty(t, d.Error, "Case %d", k)
+	}
+
+	assert.NotNil(t, p.Redeliver(all[0]))
+	dead.Lock()
+	dead.status = http.StatusNoContent
+	dead.Unlock()
+	require.Nil(t, p.Redeliver(all[0]))
+	assert.False(t, p.IsOpen(ds.URL))
+
+	p.Publish(New("quota.exceeded", nil))
+	eventually(t, func() bool { return healthy.count() == 7 && dead.count() == 5 })
+}
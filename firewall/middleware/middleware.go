@@ -0,0 +1,302 @@
+// Package middleware authenticates the requests of Go resource servers with the access tokens hydra issues, without
+// asking the warden for every request. JWT access tokens are verified with the public keys of the cluster, other
+// tokens are introspected if an introspector is configured.
+//
+// Verifying a JWT does not tell whether it was revoked, a revoked token is accepted until it expires. Introspect all
+// tokens instead if that is not acceptable.
+package middleware
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/scope"
+	"github.com/square/go-jose"
+	"golang.org/x/net/context"
+)
+
+// ErrInsufficientScope is the error of requests whose token lacks a scope the route requires.
+var ErrInsufficientScope = &herodot.Error{
+	Err:  errors.New("The token lacks a scope which is required"),
+	Code: http.StatusForbidden,
+}
+
+// Keys returns the public key a token was signed with by its key id, usually a jwk.CachingFetcher. It returns
+// pkg.ErrNotFound if the key is not known.
+type Keys interface {
+	GetKey(kid string) (*jose.JsonWebKey, error)
+}
+
+// Rule requires scopes for the requests whose path starts with Prefix. Method restricts the rule to a request
+// method, the rule applies to all methods if Method is empty.
+type Rule struct {
+	Method string
+	Prefix string
+	Scopes []string
+}
+
+// Middleware authenticates every request by its bearer token and enforces the scopes of the first rule which matches
+// the request. Requests without a valid token are answered with 401 Unauthorized, requests whose token lacks a scope
+// with 403 Forbidden. The authorization context of a request is available to the handlers through FromRequest.
+type Middleware struct {
+	// Keys are the keys JWT access tokens are verified with, JWTs are introspected if Keys is nil. Keys must only
+	// contain the keys of the access token set, oauth2.AccessTokenKeyName, which are served at
+	// /keys/hydra.access-token/public. The /.well-known/jwks.json of the cluster also publishes the keys ID tokens and
+	// logout tokens are signed with.
+	Keys Keys
+
+	// Issuer is the issuer of the cluster, JWTs of other issuers are rejected.
+	Issuer string
+
+	// Audience, if set, is the audience tokens must have been issued for.
+	Audience string
+
+	// Introspection validates the tokens which are not JWTs, and JWTs whose key could not be fetched. Such tokens
+	// are rejected if Introspection is nil.
+	Introspection oauth2.Introspector
+
+	Rules []Rule
+
+	// Scopes decides whether the granted scopes cover a required scope, nil is scope.Hierarchic.
+	Scopes scope.Strategy
+
+	// H writes the error responses, nil is herodot.JSON.
+	H herodot.Herodot
+}
+
+// Requests do not carry a context in Go 1.6, so the middleware keeps the authorization context of every request
+// which is being served in a registry.
+var (
+	contexts     = map[*http.Request]*firewall.Context{}
+	contextsLock sync.RWMutex
+)
+
+// FromRequest returns the authorization context of a request which is being served by the middleware, or nil.
+func FromRequest(r *http.Request) *firewall.Context {
+	contextsLock.RLock()
+	defer contextsLock.RUnlock()
+
+	return contexts[r]
+}
+
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var scopes []string
+		for _, rule := range m.Rules {
+			if (rule.Method == "" || rule.Method == r.Method) && strings.HasPrefix(r.URL.Path, rule.Prefix) {
+				scopes = rule.Scopes
+				break
+			}
+		}
+
+		m.serve(w, r, next, scopes)
+	})
+}
+
+// Require wraps a single handler and requires the scopes instead of the scopes of the rules, which is handy with
+// routers which match paths themselves.
+func (m *Middleware) Require(next http.Handler, scopes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.serve(w, r, next, scopes)
+	})
+}
+
+func (m *Middleware) serve(w http.ResponseWriter, r *http.Request, next http.Handler, scopes []string) {
+	ctx := herodot.NewContext()
+	c, err := m.Validate(ctx, bearerToken(r))
+	if err != nil {
+		if e := herodot.ToError(err); e.Code >= http.StatusInternalServerError {
+			m.writer().WriteError(ctx, w, r, e)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		m.writer().WriteErrorCode(ctx, w, r, http.StatusUnauthorized, err)
+		return
+	}
+
+	strategy := m.Scopes
+	if strategy == nil {
+		strategy = scope.Hierarchic
+	}
+	for _, s := range scopes {
+		if !strategy(c.GrantedScopes, s) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+			m.writer().WriteError(ctx, w, r, ErrInsufficientScope)
+			return
+		}
+	}
+
+	contextsLock.Lock()
+	contexts[r] = c
+	contextsLock.Unlock()
+	defer func() {
+		contextsLock.Lock()
+		delete(contexts, r)
+		contextsLock.Unlock()
+	}()
+
+	next.ServeHTTP(w, r)
+}
+
+func (m *Middleware) writer() herodot.Herodot {
+	if m.H == nil {
+		return &herodot.JSON{}
+	}
+	return m.H
+}
+
+// Validate returns the authorization context of a token. JWTs are verified locally unless their key can not be
+// fetched, all other tokens are introspected. Tokens which could not be validated because the cluster is unavailable
+// fail with an error of code 503.
+func (m *Middleware) Validate(ctx context.Context, token string) (*firewall.Context, error) {
+	if token == "" {
+		return nil, errors.New(pkg.ErrUnauthorized)
+	}
+
+	if m.Keys != nil && strings.Count(token, ".") == 2 {
+		c, err := m.verify(token)
+		if err == nil {
+			return c, nil
+		} else if herodot.ToError(err).Code != http.StatusServiceUnavailable || m.Introspection == nil {
+			return nil, err
+		}
+	}
+
+	if m.Introspection == nil {
+		return nil, errors.New("Only JWT access tokens are accepted")
+	}
+	return m.introspect(ctx, token)
+}
+
+// unavailable is the error of tokens which could not be validated because the cluster could not be reached, which
+// does not make them invalid.
+func unavailable(err error) error {
+	return &herodot.Error{Err: errors.New(err), Code: http.StatusServiceUnavailable}
+}
+
+func (m *Middleware) verify(token string) (*firewall.Context, error) {
+	var keyErr error
+	t, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.Errorf("Unexpected signing method: %v", t.Header["alg"])
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("Token has no key id")
+		}
+
+		key, err := m.Keys.GetKey(kid)
+		if errors.Is(err, pkg.ErrNotFound) {
+			return nil, errors.Errorf("Key %s is unknown", kid)
+		} else if err != nil {
+			keyErr = err
+			return nil, err
+		}
+
+		switch k := key.Key.(type) {
+		case *rsa.PublicKey:
+			return k, nil
+		case *rsa.PrivateKey:
+			return &k.PublicKey, nil
+		}
+		return nil, errors.Errorf("Key %s is not an RSA key", kid)
+	})
+	if keyErr != nil {
+		return nil, unavailable(keyErr)
+	} else if err != nil {
+		return nil, errors.Errorf("Couldn't parse token: %v", err)
+	} else if !t.Valid {
+		return nil, errors.New("Token is invalid")
+	}
+
+	// ID tokens and logout tokens of the cluster carry neither scopes nor a client id, they must not be accepted in
+	// case they are signed with a key of Keys.
+	if _, ok := t.Claims["scp"]; !ok || claim(t.Claims, "client_id") == "" {
+		return nil, errors.New("Token is not an access token")
+	}
+
+	c := &firewall.Context{
+		Subject:   claim(t.Claims, "sub"),
+		Issuer:    claim(t.Claims, "iss"),
+		Audience:  claim(t.Claims, "aud"),
+		IssuedAt:  unix(t.Claims["iat"]),
+		ExpiresAt: unix(t.Claims["exp"]),
+	}
+	if scopes, ok := t.Claims["scp"].([]interface{}); ok {
+		for _, s := range scopes {
+			if s, ok := s.(string); ok {
+				c.GrantedScopes = append(c.GrantedScopes, s)
+			}
+		}
+	}
+	if err := m.check(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (m *Middleware) introspect(ctx context.Context, token string) (*firewall.Context, error) {
+	i, err := m.Introspection.IntrospectToken(ctx, token, "access_token")
+	if err != nil {
+		return nil, unavailable(err)
+	} else if !i.Active || i.TokenType != "access_token" {
+		return nil, errors.New("Token is not active")
+	}
+
+	c := &firewall.Context{
+		Subject:       i.Subject,
+		GrantedScopes: strings.Fields(i.Scope),
+		Issuer:        i.Issuer,
+		Audience:      i.Audience,
+		IssuedAt:      time.Unix(i.IssuedAt, 0),
+	}
+	if i.ExpiresAt > 0 {
+		c.ExpiresAt = time.Unix(i.ExpiresAt, 0)
+	}
+	if err := m.check(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// check rejects tokens of other issuers and audiences.
+func (m *Middleware) check(c *firewall.Context) error {
+	if m.Issuer != "" && c.Issuer != m.Issuer {
+		return errors.Errorf("Token was issued by %s", c.Issuer)
+	} else if m.Audience != "" && c.Audience != m.Audience {
+		return errors.Errorf("Token was issued for %s", c.Audience)
+	}
+	return nil
+}
+
+func bearerToken(r *http.Request) string {
+	split := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(split) != 2 || !strings.EqualFold(split[0], "bearer") {
+		return ""
+	}
+	return split[1]
+}
+
+func claim(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+func unix(v interface{}) time.Time {
+	if f, ok := v.(float64); ok {
+		return time.Unix(int64(f), 0)
+	}
+	return time.Time{}
+}
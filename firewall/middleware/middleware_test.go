@@ -0,0 +1,151 @@
+package middleware_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ory-am/fosite"
+	. "github.com/ory-am/hydra/firewall/middleware"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type introspector map[string]*oauth2.Introspection
+
+func (i introspector) IntrospectToken(_ context.Context, token, _ string) (*oauth2.Introspection, error) {
+	if in, ok := i[token]; ok {
+		return in, nil
+	}
+	return &oauth2.Introspection{Active: false}, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	km := &jwk.MemoryManager{}
+	keys, err := new(jwk.RS256Generator).Generate("1")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(oauth2.AccessTokenKeyName, keys))
+
+	var fetches int
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		(&herodot.JSON{}).Write(herodot.NewContext(), w, r, &jose.JsonWebKeySet{Keys: jwk.PublicKeys(keys.Keys)})
+	}))
+	defer jwks.Close()
+
+	issue := func(issuer string, scopes ...string) string {
+		request := fosite.NewAccessRequest(&oauth2.Session{Subject: "peter"})
+		request.Client = &fosite.DefaultClient{ID: "app"}
+		for _, s := range scopes {
+			request.GrantScope(s)
+		}
+
+		token, _, err := (&oauth2.JWTAccessTokenStrategy{
+			KeyManager:          km,
+			Issuer:              issuer,
+			AccessTokenLifespan: time.Hour,
+		}).GenerateAccessToken(context.Background(), request)
+		require.Nil(t, err)
+		return token
+	}
+
+	m := &Middleware{
+		Keys:   &jwk.CachingFetcher{URL: jwks.URL},
+		Issuer: "https://hydra.localhost",
+		Introspection: introspector{
+			"opaque": {Active: true, Subject: "alice", Scope: "photos", Issuer: "https://hydra.localhost", TokenType: "access_token"},
+		},
+		Rules: []Rule{{Method: "POST", Prefix: "/photos", Scopes: []string{"photos.upload"}}},
+	}
+	server := httptest.NewServer(m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(FromRequest(r).Subject))
+	})))
+	defer server.Close()
+
+	for k, c := range []struct {
+		method string
+		token  string
+		status int
+		sub    string
+	}{
+		{method: "GET", token: issue("https://hydra.localhost", "core"), status: http.StatusOK, sub: "peter"},
+		{method: "POST", token: issue("https://hydra.localhost", "photos"), status: http.StatusOK, sub: "peter"},
+		{method: "POST", token: issue("https://hydra.localhost", "core"), status: http.StatusForbidden},
+		{method: "GET", token: issue("https://attacker.localhost", "core"), status: http.StatusUnauthorized},
+		{method: "POST", token: "opaque", status: http.StatusOK, sub: "alice"},
+		{method: "GET", token: "unknown", status: http.StatusUnauthorized},
+		{method: "GET", status: http.StatusUnauthorized},
+	} {
+		req, err := http.NewRequest(c.method, server.URL+"/photos", nil)
+		require.Nil(t, err)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err, "Case %d", k)
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		require.Nil(t, err, "Case %d", k)
+		assert.Equal(t, c.status, res.StatusCode, "Case %d", k)
+		if c.status == http.StatusOK {
+			assert.Equal(t, c.sub, string(body), "Case %d", k)
+		} else if c.status == http.StatusUnauthorized {
+			assert.Contains(t, res.Header.Get("WWW-Authenticate"), "invalid_token", "Case %d", k)
+		}
+	}
+
+	// The authorization contexts of served requests are forgotten.
+	contextsLock.RLock()
+	assert.Empty(t, contexts)
+	contextsLock.RUnlock()
+
+	// ID tokens signed with a key of the set are not access tokens.
+	idToken := jwt.New(jwt.SigningMethodRS256)
+	idToken.Header["kid"] = "public:1"
+	idToken.Claims = map[string]interface{}{
+		"iss": "https://hydra.localhost",
+		"sub": "peter",
+		"aud": "app",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	signed, err := idToken.SignedString(jwk.First(keys.Key("private:1")).Key)
+	require.Nil(t, err)
+	_, err = m.Validate(context.Background(), signed)
+	assert.NotNil(t, err)
+
+	// The key set is fetched once, unknown keys do not trigger another fetch right away.
+	assert.Equal(t, 1, fetches)
+	_, err = m.Keys.GetKey("public:2")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, fetches)
+}
+
+func TestMiddlewareFallsBackToIntrospection(t *testing.T) {
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer jwks.Close()
+
+	m := &Middleware{Keys: &jwk.CachingFetcher{URL: jwks.URL}}
+	token := "eyJhbGciOiJSUzI1NiIsImtpZCI6InB1YmxpYzoxIn0.e30.c2lnbmF0dXJl"
+	_, err := m.Validate(context.Background(), token)
+	require.NotNil(t, err)
+
+	m = &Middleware{
+		Keys:          &jwk.CachingFetcher{URL: jwks.URL},
+		Introspection: introspector{token: {Active: true, Subject: "peter", TokenType: "access_token"}},
+	}
+	c, err := m.Validate(context.Background(), token)
+	require.Nil(t, err)
+	assert.Equal(t, "peter", c.Subject)
+}
@@ -15,6 +15,13 @@ type Context struct {
 	Audience      string    `json:"aud"`
 	IssuedAt      time.Time `json:"iat"`
 	ExpiresAt     time.Time `json:"exp"`
+
+	// AuthenticationMethods and AuthenticationContext describe how the subject logged in.
+	AuthenticationMethods []string `json:"amr,omitempty"`
+	AuthenticationContext string   `json:"acr,omitempty"`
+
+	// UserVerified is true if the subject logged in with a WebAuthn credential and the authenticator verified the user.
+	UserVerified bool `json:"webauthn_uv,omitempty"`
 }
 
 type Firewall interface {
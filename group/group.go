@@ -0,0 +1,26 @@
+package group
+
+// SubjectPrefix marks a policy subject as a group, for example "group:admins".
+const SubjectPrefix = "group:"
+
+// Group is a named set of subjects, for example a role. Policies whose subjects contain Subject(id) apply to all
+// members of the group.
+type Group struct {
+	ID      string   `json:"id" gorethink:"id"`
+	Members []string `json:"members" gorethink:"members"`
+}
+
+// Subject returns the policy subject which stands for the members of the group.
+func Subject(id string) string {
+	return SubjectPrefix + id
+}
+
+// HasMember returns true if the subject is a member of the group.
+func (g *Group) HasMember(subject string) bool {
+	for _, m := range g.Members {
+		if m == subject {
+			return true
+		}
+	}
+	return false
+}
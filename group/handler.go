@@ -0,0 +1,213 @@
+package group
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/pagination"
+	"github.com/ory-am/ladon"
+	"github.com/pborman/uuid"
+)
+
+const (
+	GroupsHandlerPath = "/warden/groups"
+)
+
+const (
+	groupsResource = "rn:hydra:warden:groups"
+	groupResource  = "rn:hydra:warden:groups:%s"
+	scope          = "hydra.groups"
+)
+
+type Handler struct {
+	Manager Manager
+	H       herodot.Herodot
+	W       firewall.Firewall
+}
+
+// membersRequest is the body of requests which add members to a group.
+type membersRequest struct {
+	Members []string `json:"members"`
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.POST(GroupsHandlerPath, h.Create)
+	r.GET(GroupsHandlerPath, h.FindGroupNames)
+	r.GET(GroupsHandlerPath+"/:id", h.Get)
+	r.DELETE(GroupsHandlerPath+"/:id", h.Delete)
+	r.POST(GroupsHandlerPath+"/:id/members", h.AddMembers)
+	r.DELETE(GroupsHandlerPath+"/:id/members/:member", h.RemoveMember)
+}
+
+// FindGroupNames returns the ids of the groups the subject given by the query parameter member belongs to.
+func (h *Handler) FindGroupNames(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var member = r.URL.Query().Get("member")
+
+	if member == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Missing query parameter member"))
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: groupsResource,
+		Action:   "find",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	page, err := pagination.Parse(r, "id")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ids, err := h.Manager.FindGroupNames(member)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	indices, total := page.Apply(len(ids), func(i int, _ string) string {
+		return ids[i]
+	})
+
+	res := make([]string, len(indices))
+	for k, i := range indices {
+		res[k] = ids[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var g Group
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: groupsResource,
+		Action:   "create",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	if g.ID == "" {
+		g.ID = uuid.New()
+	}
+	if g.Members == nil {
+		g.Members = []string{}
+	}
+
+	if err := h.Manager.CreateGroup(&g); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.WriteCreated(ctx, w, r, GroupsHandlerPath+"/"+g.ID, &g)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(groupResource, id),
+		Action:   "get",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	g, err := h.Manager.GetGroup(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, g)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(groupResource, id),
+		Action:   "delete",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Manager.DeleteGroup(id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AddMembers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+	var m membersRequest
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(groupResource, id),
+		Action:   "members.add",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	if err := h.Manager.AddGroupMembers(id, m.Members); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	g, err := h.Manager.GetGroup(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.WriteCreated(ctx, w, r, GroupsHandlerPath+"/"+id, g)
+}
+
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(groupResource, id),
+		Action:   "members.remove",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Manager.RemoveGroupMembers(id, []string{ps.ByName("member")}); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,20 @@
+package group
+
+// Manager stores groups and their members.
+type Manager interface {
+	CreateGroup(g *Group) error
+
+	// GetGroup returns a group or pkg.ErrNotFound if it does not exist.
+	GetGroup(id string) (*Group, error)
+
+	DeleteGroup(id string) error
+
+	// AddGroupMembers adds subjects to a group. Subjects which already are members are ignored.
+	AddGroupMembers(group string, members []string) error
+
+	// RemoveGroupMembers removes subjects from a group.
+	RemoveGroupMembers(group string, members []string) error
+
+	// FindGroupNames returns the ids of all groups the subject is a member of.
+	FindGroupNames(subject string) ([]string, error)
+}
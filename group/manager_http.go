@@ -0,0 +1,66 @@
+package group
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/ory-am/hydra/pkg"
+)
+
+type HTTPManager struct {
+	Endpoint *url.URL
+	Client   *http.Client
+}
+
+func (m *HTTPManager) CreateGroup(g *Group) error {
+	var r = pkg.NewSuperAgent(m.Endpoint.String())
+	r.Client = m.Client
+	return r.Create(g)
+}
+
+func (m *HTTPManager) GetGroup(id string) (*Group, error) {
+	var g Group
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, id).String())
+	r.Client = m.Client
+	if err := r.Get(&g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (m *HTTPManager) DeleteGroup(id string) error {
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, id).String())
+	r.Client = m.Client
+	return r.Delete()
+}
+
+func (m *HTTPManager) AddGroupMembers(group string, members []string) error {
+	var g Group
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, group, "members").String())
+	r.Client = m.Client
+	return r.POST(&membersRequest{Members: members}, &g)
+}
+
+func (m *HTTPManager) RemoveGroupMembers(group string, members []string) error {
+	for _, member := range members {
+		var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, group, "members", member).String())
+		r.Client = m.Client
+		if err := r.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *HTTPManager) FindGroupNames(subject string) ([]string, error) {
+	var ids []string
+	var u = pkg.CopyURL(m.Endpoint)
+	u.RawQuery = url.Values{"member": {subject}}.Encode()
+
+	var r = pkg.NewSuperAgent(u.String())
+	r.Client = m.Client
+	if err := r.Get(&ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
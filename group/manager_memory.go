@@ -0,0 +1,146 @@
+package group
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type MemoryManager struct {
+	Groups map[string]*Group
+	sync.RWMutex
+}
+
+func NewMemoryManager() *MemoryManager {
+	return &MemoryManager{
+		Groups: map[string]*Group{},
+	}
+}
+
+func (m *MemoryManager) CreateGroup(g *Group) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.Groups == nil {
+		m.Groups = map[string]*Group{}
+	}
+	m.Groups[g.ID] = copyGroup(g)
+	return nil
+}
+
+func (m *MemoryManager) GetGroup(id string) (*Group, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	g, ok := m.Groups[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return copyGroup(g), nil
+}
+
+func (m *MemoryManager) DeleteGroup(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Groups, id)
+	return nil
+}
+
+func (m *MemoryManager) AddGroupMembers(group string, members []string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	g, ok := m.Groups[group]
+	if !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	for _, member := range members {
+		if !g.HasMember(member) {
+			g.Members = append(g.Members, member)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryManager) RemoveGroupMembers(group string, members []string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	g, ok := m.Groups[group]
+	if !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	g.Members = without(g.Members, members)
+	return nil
+}
+
+func (m *MemoryManager) FindGroupNames(subject string) ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return findGroupNames(m.Groups, subject), nil
+}
+
+func findGroupNames(groups map[string]*Group, subject string) []string {
+	ids := []string{}
+	for id, g := range groups {
+		if g.HasMember(subject) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func copyGroup(g *Group) *Group {
+	return &Group{ID: g.ID, Members: append([]string{}, g.Members...)}
+}
+
+func without(members, removed []string) []string {
+	result := []string{}
+	for _, member := range members {
+		var found bool
+		for _, r := range removed {
+			if r == member {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, member)
+		}
+	}
+	return result
+}
+
+// Snapshot returns all groups encoded as JSON.
+func (m *MemoryManager) Snapshot() ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	out, err := json.Marshal(m.Groups)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return out, nil
+}
+
+// Restore replaces all groups with the ones of the snapshot.
+func (m *MemoryManager) Restore(snapshot []byte) error {
+	var g map[string]*Group
+	if err := json.Unmarshal(snapshot, &g); err != nil {
+		return errors.New(err)
+	}
+	if g == nil {
+		g = map[string]*Group{}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Groups = g
+	return nil
+}
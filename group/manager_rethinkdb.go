@@ -0,0 +1,133 @@
+package group
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// RethinkManager keeps all groups in memory because the warden looks up the groups of a subject on every
+// request. Writes go to the database and reach the cache through the changefeed.
+type RethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+
+	Groups map[string]*Group
+
+	// Feed streams the changes of Table. It is created by Watch if nil and can be subscribed to by other
+	// subsystems which need to react to group changes.
+	Feed *pkg.ChangeFeed
+
+	sync.RWMutex
+}
+
+func (m *RethinkManager) CreateGroup(g *Group) error {
+	if g.Members == nil {
+		g.Members = []string{}
+	}
+
+	if _, err := m.Table.Insert(g, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) GetGroup(id string) (*Group, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	g, ok := m.Groups[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return copyGroup(g), nil
+}
+
+func (m *RethinkManager) DeleteGroup(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) AddGroupMembers(group string, members []string) error {
+	return m.updateMembers(group, func(row r.Term) interface{} {
+		return row.Field("members").Default([]string{}).SetUnion(members)
+	})
+}
+
+func (m *RethinkManager) RemoveGroupMembers(group string, members []string) error {
+	return m.updateMembers(group, func(row r.Term) interface{} {
+		return row.Field("members").Default([]string{}).SetDifference(members)
+	})
+}
+
+func (m *RethinkManager) updateMembers(group string, members func(row r.Term) interface{}) error {
+	res, err := m.Table.Get(group).Update(func(row r.Term) interface{} {
+		return map[string]interface{}{"members": members(row)}
+	}).RunWrite(m.Session)
+	if err != nil {
+		return errors.New(err)
+	} else if res.Skipped > 0 {
+		return errors.New(pkg.ErrNotFound)
+	}
+	return nil
+}
+
+func (m *RethinkManager) FindGroupNames(subject string) ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return findGroupNames(m.Groups, subject), nil
+}
+
+func (m *RethinkManager) ColdStart() error {
+	rows, err := m.Table.Run(m.Session)
+	if err != nil {
+		return errors.New(err)
+	}
+	defer rows.Close()
+
+	groups := map[string]*Group{}
+	var g *Group
+	for rows.Next(&g) {
+		groups[g.ID] = g
+		g = nil
+	}
+
+	if rows.Err() != nil {
+		return errors.New(rows.Err())
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Groups = groups
+	return nil
+}
+
+func (m *RethinkManager) Watch(ctx context.Context) {
+	if m.Feed == nil {
+		m.Feed = &pkg.ChangeFeed{Session: m.Session, Table: m.Table}
+	}
+
+	m.Feed.Subscribe(m.ColdStart, func(change *pkg.Change) error {
+		var newVal, oldVal *Group
+		if err := change.Decode(&oldVal, &newVal); err != nil {
+			return err
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		if oldVal != nil {
+			delete(m.Groups, oldVal.ID)
+		}
+		if newVal != nil {
+			m.Groups[newVal.ID] = newVal
+		}
+		return nil
+	})
+	m.Feed.Start(ctx)
+}
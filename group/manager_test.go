@@ -0,0 +1,111 @@
+package group
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/internal"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var managers = map[string]Manager{
+	"memory": NewMemoryManager(),
+}
+
+func init() {
+	localWarden, httpClient := internal.NewFirewall("hydra", "alice", fosite.Arguments{scope},
+		&ladon.DefaultPolicy{
+			ID:        "1",
+			Subjects:  []string{"alice"},
+			Resources: []string{"rn:hydra:warden:groups<.*>"},
+			Actions:   []string{"create", "get", "delete", "find", "members.add", "members.remove"},
+			Effect:    ladon.AllowAccess,
+		},
+	)
+
+	h := &Handler{
+		Manager: NewMemoryManager(),
+		H:       &herodot.JSON{},
+		W:       localWarden,
+	}
+
+	r := httprouter.New()
+	h.SetRoutes(r)
+	ts := httptest.NewServer(r)
+
+	u, _ := url.Parse(ts.URL + GroupsHandlerPath)
+	managers["http"] = &HTTPManager{
+		Client:   httpClient,
+		Endpoint: u,
+	}
+}
+
+func TestManagers(t *testing.T) {
+	for k, m := range managers {
+		_, err := m.GetGroup("admins")
+		pkg.AssertError(t, true, err, k)
+
+		require.Nil(t, m.CreateGroup(&Group{ID: "admins", Members: []string{"peter"}}), k)
+		require.Nil(t, m.CreateGroup(&Group{ID: "editors", Members: []string{"peter", "max"}}), k)
+
+		g, err := m.GetGroup("admins")
+		require.Nil(t, err, k)
+		assert.Equal(t, []string{"peter"}, g.Members, k)
+
+		require.Nil(t, m.AddGroupMembers("admins", []string{"max", "peter"}), k)
+		g, err = m.GetGroup("admins")
+		require.Nil(t, err, k)
+		assert.Equal(t, []string{"peter", "max"}, g.Members, k)
+
+		ids, err := m.FindGroupNames("max")
+		require.Nil(t, err, k)
+		assert.Len(t, ids, 2, k)
+
+		require.Nil(t, m.RemoveGroupMembers("admins", []string{"max"}), k)
+		ids, err = m.FindGroupNames("max")
+		require.Nil(t, err, k)
+		assert.Equal(t, []string{"editors"}, ids, k)
+
+		pkg.AssertError(t, true, m.AddGroupMembers("unknown", []string{"max"}), k)
+
+		require.Nil(t, m.DeleteGroup("editors"), k)
+		ids, err = m.FindGroupNames("max")
+		require.Nil(t, err, k)
+		assert.Empty(t, ids, k)
+	}
+}
+
+func TestSubjectManager(t *testing.T) {
+	groups := NewMemoryManager()
+	require.Nil(t, groups.CreateGroup(&Group{ID: "admins", Members: []string{"peter"}}))
+
+	w := &ladon.Ladon{
+		Manager: &SubjectManager{
+			Groups: groups,
+			Manager: &ladon.MemoryManager{
+				Policies: map[string]ladon.Policy{
+					"1": &ladon.DefaultPolicy{
+						ID:        "1",
+						Subjects:  []string{Subject("admins")},
+						Resources: []string{"rn:hydra:clients<.*>"},
+						Actions:   []string{"delete"},
+						Effect:    ladon.AllowAccess,
+					},
+				},
+			},
+		},
+	}
+
+	assert.Nil(t, w.IsAllowed(&ladon.Request{Subject: "peter", Resource: "rn:hydra:clients:1", Action: "delete"}))
+	assert.NotNil(t, w.IsAllowed(&ladon.Request{Subject: "max", Resource: "rn:hydra:clients:1", Action: "delete"}))
+
+	require.Nil(t, groups.AddGroupMembers("admins", []string{"max"}))
+	assert.Nil(t, w.IsAllowed(&ladon.Request{Subject: "max", Resource: "rn:hydra:clients:1", Action: "delete"}))
+}
@@ -0,0 +1,66 @@
+package group
+
+import (
+	"github.com/ory-am/ladon"
+)
+
+// SubjectManager decorates a ladon.Manager and expands the groups of a subject when policies are looked up for
+// evaluation. A policy with the subject "group:admins" applies to every member of the group admins, so policies
+// can be written for roles instead of individual users.
+type SubjectManager struct {
+	ladon.Manager
+
+	Groups Manager
+}
+
+func (m *SubjectManager) FindPoliciesForSubject(subject string) (ladon.Policies, error) {
+	policies, err := m.Manager.FindPoliciesForSubject(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := m.Groups.FindGroupNames(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, p := range policies {
+		seen[p.GetID()] = true
+	}
+
+	for _, id := range groups {
+		found, err := m.Manager.FindPoliciesForSubject(Subject(id))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range found {
+			if seen[p.GetID()] {
+				continue
+			}
+			seen[p.GetID()] = true
+
+			// The warden matches the subjects of a policy against the subject of the request, which is why the
+			// member is added to the subjects of the group's policies.
+			subjects := make([]string, len(p.GetSubjects()), len(p.GetSubjects())+1)
+			copy(subjects, p.GetSubjects())
+			policies = append(policies, &groupPolicy{
+				Policy:   p,
+				subjects: append(subjects, subject),
+			})
+		}
+	}
+
+	return policies, nil
+}
+
+type groupPolicy struct {
+	ladon.Policy
+
+	subjects []string
+}
+
+func (p *groupPolicy) GetSubjects() []string {
+	return p.subjects
+}
@@ -0,0 +1,45 @@
+package group
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "POST", Path: GroupsHandlerPath, ID: "createGroup", Tag: "groups", Scope: scope,
+		Summary:   "Create a group",
+		Body:      &Group{},
+		Responses: map[int]interface{}{http.StatusCreated: &Group{}},
+	},
+	{
+		Method: "GET", Path: GroupsHandlerPath, ID: "findGroups", Tag: "groups", Scope: scope,
+		Summary:    "List the ids of the groups a member belongs to",
+		Query:      []string{"member"},
+		Pagination: []string{"id"},
+		Responses:  map[int]interface{}{http.StatusOK: []string{}},
+	},
+	{
+		Method: "GET", Path: GroupsHandlerPath + "/:id", ID: "getGroup", Tag: "groups", Scope: scope,
+		Summary:   "Get a group",
+		Responses: map[int]interface{}{http.StatusOK: &Group{}},
+	},
+	{
+		Method: "DELETE", Path: GroupsHandlerPath + "/:id", ID: "deleteGroup", Tag: "groups", Scope: scope,
+		Summary:   "Delete a group",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "POST", Path: GroupsHandlerPath + "/:id/members", ID: "addMembersToGroup", Tag: "groups", Scope: scope,
+		Summary:   "Add members to a group",
+		Body:      &membersRequest{},
+		Responses: map[int]interface{}{http.StatusCreated: &Group{}},
+	},
+	{
+		Method: "DELETE", Path: GroupsHandlerPath + "/:id/members/:member", ID: "removeMemberFromGroup", Tag: "groups", Scope: scope,
+		Summary:   "Remove a member from a group",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+}
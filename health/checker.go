@@ -0,0 +1,56 @@
+package health
+
+import (
+	"database/sql"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// Checker returns an error if a dependency can not serve requests.
+type Checker func() error
+
+// RethinkDBChecker runs a trivial query with the session.
+func RethinkDBChecker(session *r.Session) Checker {
+	return func() error {
+		if err := r.Expr(1).Exec(session); err != nil {
+			return errors.New(err)
+		}
+		return nil
+	}
+}
+
+// SQLChecker runs a trivial query with the pool.
+func SQLChecker(db *sql.DB) Checker {
+	return func() error {
+		var one int
+		if err := db.QueryRow("SELECT 1").Scan(&one); err != nil {
+			return errors.New(err)
+		}
+		return nil
+	}
+}
+
+// DynamoDBChecker describes the table.
+func DynamoDBChecker(db dynamodbiface.DynamoDBAPI, table string) Checker {
+	return func() error {
+		if _, err := db.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)}); err != nil {
+			return errors.New(err)
+		}
+		return nil
+	}
+}
+
+// ChangeFeedChecker fails while the feed is reconnecting, the cache of its managers might be stale meanwhile.
+func ChangeFeedChecker(feed *pkg.ChangeFeed) Checker {
+	return func() error {
+		if !feed.Connected() {
+			return errors.New("Changefeed is not connected")
+		}
+		return nil
+	}
+}
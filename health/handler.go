@@ -0,0 +1,125 @@
+package health
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/pkg"
+)
+
+// The health endpoints are not protected by the warden so that load balancers and orchestrators do not need
+// OAuth2 credentials. They reveal which dependencies are down, restrict access to them at the network level.
+const (
+	AliveCheckPath = "/health/alive"
+	ReadyCheckPath = "/health/ready"
+
+	// DefaultCheckTimeout is how long a dependency may take to answer a readiness probe.
+	DefaultCheckTimeout = 2 * time.Second
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Status is the status of the instance or one of its dependencies.
+type Status struct {
+	Status       string             `json:"status"`
+	Error        string             `json:"error,omitempty"`
+	Dependencies map[string]*Status `json:"dependencies,omitempty"`
+}
+
+type Handler struct {
+	H herodot.Herodot
+
+	// Checkers are the dependencies probed by the readiness check, keyed by name.
+	Checkers map[string]Checker
+
+	// ChangeFeeds returns the changefeeds which the readiness check requires to be connected, it may be nil.
+	// It is called on every check because feeds are started while the instance boots.
+	ChangeFeeds func() []*pkg.ChangeFeed
+
+	// Timeout is DefaultCheckTimeout if zero.
+	Timeout time.Duration
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.GET(AliveCheckPath, h.Alive)
+	r.GET(ReadyCheckPath, h.Ready)
+}
+
+// Alive answers as long as the process serves HTTP requests, it does not probe any dependency.
+func (h *Handler) Alive(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.H.Write(herodot.NewContext(), w, r, &Status{Status: StatusOK})
+}
+
+// Ready probes all dependencies concurrently and answers with 503 if one of them failed.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	status := h.Check()
+	if status.Status != StatusOK {
+		h.H.WriteCode(ctx, w, r, http.StatusServiceUnavailable, status)
+		return
+	}
+	h.H.Write(ctx, w, r, status)
+}
+
+// Check probes all dependencies. A dependency which does not answer within Timeout is unavailable.
+func (h *Handler) Check() *Status {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+
+	checkers := map[string]Checker{}
+	for name, checker := range h.Checkers {
+		checkers[name] = checker
+	}
+	if h.ChangeFeeds != nil {
+		for _, feed := range h.ChangeFeeds() {
+			checkers["changefeed:"+feed.Table.String()] = ChangeFeedChecker(feed)
+		}
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	result := &Status{Status: StatusOK, Dependencies: map[string]*Status{}}
+	for name, checker := range checkers {
+		wg.Add(1)
+		go func(name string, checker Checker) {
+			defer wg.Done()
+			s := probe(checker, timeout)
+
+			lock.Lock()
+			defer lock.Unlock()
+			result.Dependencies[name] = s
+			if s.Status != StatusOK {
+				result.Status = StatusUnavailable
+			}
+		}(name, checker)
+	}
+	wg.Wait()
+	return result
+}
+
+func probe(checker Checker, timeout time.Duration) *Status {
+	// Buffered, so that a checker which answers after the timeout does not block forever.
+	done := make(chan error, 1)
+	go func() {
+		done <- checker()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return &Status{Status: StatusUnavailable, Error: err.Error()}
+		}
+		return &Status{Status: StatusOK}
+	case <-time.After(timeout):
+		return &Status{Status: StatusUnavailable, Error: "Check timed out after " + timeout.String()}
+	}
+}
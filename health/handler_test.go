@@ -0,0 +1,59 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReady(t *testing.T) {
+	h := &Handler{
+		H:       &herodot.JSON{},
+		Timeout: 50 * time.Millisecond,
+		Checkers: map[string]Checker{
+			"ok": func() error { return nil },
+		},
+	}
+	router := httprouter.New()
+	h.SetRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	for _, path := range []string{AliveCheckPath, ReadyCheckPath} {
+		resp, err := http.Get(ts.URL + path)
+		require.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+
+	h.Checkers["broken"] = func() error { return errors.New("connection refused") }
+	h.Checkers["slow"] = func() error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	resp, err := http.Get(ts.URL + ReadyCheckPath)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	var status Status
+	require.Nil(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Equal(t, StatusUnavailable, status.Status)
+	assert.Equal(t, StatusOK, status.Dependencies["ok"].Status)
+	assert.Equal(t, "connection refused", status.Dependencies["broken"].Error)
+	assert.Equal(t, StatusUnavailable, status.Dependencies["slow"].Status)
+
+	resp, err = http.Get(ts.URL + AliveCheckPath)
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
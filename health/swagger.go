@@ -0,0 +1,24 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "GET", Path: AliveCheckPath, ID: "isAlive", Tag: "health",
+		Summary:   "Check whether the instance serves HTTP requests",
+		Responses: map[int]interface{}{http.StatusOK: &Status{}},
+	},
+	{
+		Method: "GET", Path: ReadyCheckPath, ID: "isReady", Tag: "health",
+		Summary: "Check whether the dependencies of the instance are available",
+		Responses: map[int]interface{}{
+			http.StatusOK:                 &Status{},
+			http.StatusServiceUnavailable: &Status{},
+		},
+	},
+}
@@ -15,6 +15,11 @@ func (e Error) Error() string {
 	return e.Err.Error()
 }
 
+// ErrorStack returns the stack trace of where the underlying error was created.
+func (e Error) ErrorStack() string {
+	return e.Err.ErrorStack()
+}
+
 var (
 	ErrNotFound = &Error{
 		Err:  errors.New("Not found"),
@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/Sirupsen/logrus"
 	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
 	"github.com/pborman/uuid"
 	"golang.org/x/net/context"
 )
@@ -17,7 +17,8 @@ type jsonError struct {
 }
 
 type JSON struct {
-	Logger logrus.FieldLogger
+	// Logger is the logger of the request if nil.
+	Logger logger.Logger
 }
 
 func (h *JSON) WriteCreated(ctx context.Context, w http.ResponseWriter, r *http.Request, location string, e interface{}) {
@@ -52,30 +53,46 @@ func (h *JSON) WriteError(ctx context.Context, w http.ResponseWriter, r *http.Re
 }
 
 func (h *JSON) WriteErrorCode(ctx context.Context, w http.ResponseWriter, r *http.Request, code int, err error) {
-	id, _ := ctx.Value(RequestIDKey).(string)
+	code, body := h.logError(ctx, r, code, err)
+	h.WriteCode(ctx, w, r, code, body)
+}
+
+// logError logs the error and returns the status code and body of the error response. The request id of the
+// response is the one the logger middleware assigned, if any, so that clients can refer to the log lines.
+func (h *JSON) logError(ctx context.Context, r *http.Request, code int, err error) (int, *jsonError) {
+	id := logger.RequestID(r)
+	if id == "" {
+		id, _ = ctx.Value(RequestIDKey).(string)
+	}
 	if id == "" {
 		id = uuid.New()
 	}
 
-	if h.Logger == nil {
-		h.Logger = logrus.New()
+	l := h.Logger
+	if l == nil {
+		l = logger.FromRequest(r)
 	}
-
+	l = l.WithError(err).WithField("request_id", id).WithField("status", code)
 	if e, ok := err.(*Error); ok {
-		h.Logger.WithError(e).WithField("request_id", id).WithField("status", code).WithField("stack", e.Err.ErrorStack()).Printf("Got error.")
+		l = l.WithField("stack", e.Err.ErrorStack())
 	} else if e, ok := err.(*errors.Error); ok {
-		h.Logger.WithError(e).WithField("request_id", id).WithField("status", code).WithField("stack", e.ErrorStack()).Printf("Got error.")
+		l = l.WithField("stack", e.ErrorStack())
+	}
+
+	// Errors of the caller are expected in normal operation and are not logged as errors.
+	if code > 0 && code < http.StatusInternalServerError {
+		l.Infof("Got error.")
 	} else {
-		h.Logger.WithError(err).WithField("request_id", id).WithField("status", code).Printf("Got error.")
+		l.Errorf("Got error.")
 	}
 
 	if code == 0 {
 		code = http.StatusInternalServerError
 	}
 
-	h.WriteCode(ctx, w, r, code, &jsonError{
+	return code, &jsonError{
 		RequestID: id,
 		Error:     err.Error(),
 		Code:      code,
-	})
+	}
 }
@@ -0,0 +1,129 @@
+package herodot
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/ugorji/go/codec"
+	"golang.org/x/net/context"
+)
+
+const (
+	CBORContentType    = "application/cbor"
+	MsgpackContentType = "application/msgpack"
+)
+
+var mapType = reflect.TypeOf(map[string]interface{}(nil))
+
+var handles = map[string]codec.Handle{
+	CBORContentType:         cborHandle(),
+	MsgpackContentType:      msgpackHandle(),
+	"application/x-msgpack": msgpackHandle(),
+}
+
+// Maps are decoded as map[string]interface{} so that decoded values look like decoded JSON.
+func cborHandle() *codec.CborHandle {
+	h := new(codec.CborHandle)
+	h.MapType = mapType
+	return h
+}
+
+func msgpackHandle() *codec.MsgpackHandle {
+	h := new(codec.MsgpackHandle)
+	h.MapType = mapType
+	h.RawToString = true
+	h.WriteExt = true
+	return h
+}
+
+// Negotiating writes responses as CBOR or msgpack if the request's Accept header asks for one of them and as JSON
+// otherwise. Binary encodings reduce the serialization overhead for internal callers with high request rates.
+// Structs are encoded using their json tags.
+type Negotiating struct {
+	JSON
+}
+
+func (h *Negotiating) WriteCreated(ctx context.Context, w http.ResponseWriter, r *http.Request, location string, e interface{}) {
+	w.Header().Set("Location", location)
+	h.WriteCode(ctx, w, r, http.StatusCreated, e)
+}
+
+func (h *Negotiating) Write(ctx context.Context, w http.ResponseWriter, r *http.Request, e interface{}) {
+	h.WriteCode(ctx, w, r, http.StatusOK, e)
+}
+
+func (h *Negotiating) WriteCode(ctx context.Context, w http.ResponseWriter, r *http.Request, code int, e interface{}) {
+	contentType := negotiate(r.Header.Get("Accept"))
+	handle, ok := handles[contentType]
+	if !ok {
+		h.JSON.WriteCode(ctx, w, r, code, e)
+		return
+	}
+
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, handle).Encode(e); err != nil {
+		h.JSON.WriteError(ctx, w, r, errors.New(err))
+		return
+	}
+
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	w.Write(out)
+}
+
+func (h *Negotiating) WriteError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	e := ToError(err)
+	h.WriteErrorCode(ctx, w, r, e.Code, e)
+}
+
+func (h *Negotiating) WriteErrorCode(ctx context.Context, w http.ResponseWriter, r *http.Request, code int, err error) {
+	code, body := h.logError(ctx, r, code, err)
+	h.WriteCode(ctx, w, r, code, body)
+}
+
+// negotiate returns the first binary content type listed in accept or an empty string. JSON is used unless a
+// caller explicitly asks for a binary encoding, so q-values are not weighed against each other.
+func negotiate(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		contentType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if _, ok := handles[contentType]; ok {
+			return contentType
+		}
+	}
+	return ""
+}
+
+// Decode reads the request body as CBOR or msgpack if the Content-Type header says so and as JSON otherwise.
+func Decode(r *http.Request, v interface{}) error {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	handle, ok := handles[contentType]
+	if !ok {
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+			return errors.New(err)
+		}
+		return nil
+	}
+
+	if err := codec.NewDecoder(r.Body, handle).Decode(v); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// IsBinary returns true if the request body is encoded as CBOR or msgpack.
+func IsBinary(r *http.Request) bool {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	_, ok := handles[contentType]
+	return ok
+}
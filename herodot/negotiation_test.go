@@ -0,0 +1,109 @@
+package herodot
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-errors/errors"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+	"golang.org/x/net/context"
+)
+
+func TestNegotiate(t *testing.T) {
+	for k, c := range []struct {
+		accept   string
+		expected string
+	}{
+		{accept: "", expected: ""},
+		{accept: "application/json", expected: ""},
+		{accept: "application/cbor", expected: CBORContentType},
+		{accept: "application/json, application/msgpack", expected: MsgpackContentType},
+		{accept: "application/x-msgpack", expected: "application/x-msgpack"},
+		{accept: "application/cbor;q=0, application/json", expected: ""},
+		{accept: "text/html, application/cbor; q=0.5", expected: CBORContentType},
+	} {
+		assert.Equal(t, c.expected, negotiate(c.accept), "Case %d", k)
+	}
+}
+
+func TestNegotiatingWrite(t *testing.T) {
+	foo := map[string]interface{}{"foo": "bar"}
+
+	h := &Negotiating{}
+	r := mux.NewRouter()
+	r.HandleFunc("/do", func(w http.ResponseWriter, r *http.Request) {
+		h.Write(context.Background(), w, r, &foo)
+	})
+	r.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		h.WriteError(context.Background(), w, r, errors.New(ErrNotFound))
+	})
+	ts := httptest.NewServer(r)
+
+	for _, contentType := range []string{CBORContentType, MsgpackContentType} {
+		req, err := http.NewRequest("GET", ts.URL+"/do", nil)
+		require.Nil(t, err)
+		req.Header.Set("Accept", contentType)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, contentType, resp.Header.Get("Content-Type"))
+
+		var result map[string]interface{}
+		require.Nil(t, codec.NewDecoder(resp.Body, handles[contentType]).Decode(&result))
+		resp.Body.Close()
+		assert.Equal(t, foo, result)
+
+		req, err = http.NewRequest("GET", ts.URL+"/error", nil)
+		require.Nil(t, err)
+		req.Header.Set("Accept", contentType)
+
+		resp, err = http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		var j jsonError
+		require.Nil(t, codec.NewDecoder(resp.Body, handles[contentType]).Decode(&j))
+		resp.Body.Close()
+		assert.Equal(t, ErrNotFound.Error(), j.Error)
+		assert.NotEmpty(t, j.RequestID)
+	}
+
+	resp, err := http.Get(ts.URL + "/do")
+	require.Nil(t, err)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+}
+
+func TestDecode(t *testing.T) {
+	type body struct {
+		Token string `json:"token"`
+	}
+
+	for _, contentType := range []string{CBORContentType, MsgpackContentType} {
+		var in []byte
+		require.Nil(t, codec.NewEncoderBytes(&in, handles[contentType]).Encode(&body{Token: "foo"}))
+
+		req, err := http.NewRequest("POST", "/", bytes.NewReader(in))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", contentType)
+		assert.True(t, IsBinary(req))
+
+		var out body
+		require.Nil(t, Decode(req, &out))
+		assert.Equal(t, "foo", out.Token)
+	}
+
+	req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"token":"bar"}`))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	assert.False(t, IsBinary(req))
+
+	var out body
+	require.Nil(t, Decode(req, &out))
+	assert.Equal(t, "bar", out.Token)
+}
@@ -0,0 +1,80 @@
+package history
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const (
+	ValidateHandlerPath = "/history/validate"
+)
+
+type Handler struct {
+	Validator *Validator
+	H         herodot.Herodot
+	W         firewall.Firewall
+}
+
+type ValidateRequest struct {
+	// Token is the token to validate.
+	Token string `json:"token"`
+
+	// At is the point in time the token is validated at. Defaults to now.
+	At time.Time `json:"at"`
+
+	// Set is the key set JSON Web Tokens are verified against. Defaults to the OpenID Connect key set.
+	Set string `json:"set"`
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.POST(ValidateHandlerPath, h.Validate)
+}
+
+func (h *Handler) Validate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = context.Background()
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:history:tokens",
+		Action:   "validate",
+	}, "hydra.history"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	var vr ValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&vr); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+	defer r.Body.Close()
+
+	if vr.Token == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Token must not be empty"))
+		return
+	}
+
+	if vr.At.IsZero() {
+		vr.At = time.Now().UTC()
+	}
+
+	if vr.Set == "" {
+		vr.Set = oauth2.OpenIDConnectKeyName
+	}
+
+	res, err := h.Validator.Validate(vr.Token, vr.Set, vr.At)
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, res)
+}
@@ -0,0 +1,76 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// KeyRecord keeps the public part of a key together with the time range in which it was part of a key set.
+type KeyRecord struct {
+	ID         string     `json:"id" gorethink:"id"`
+	Set        string     `json:"set" gorethink:"set"`
+	KeyID      string     `json:"kid" gorethink:"kid"`
+	Key        string     `json:"key" gorethink:"key"`
+	ValidFrom  time.Time  `json:"valid_from" gorethink:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" gorethink:"valid_until,omitempty"`
+}
+
+// ValidAt returns true if the key was part of its key set at the given time.
+func (k *KeyRecord) ValidAt(at time.Time) bool {
+	return !k.ValidFrom.After(at) && (k.ValidUntil == nil || at.Before(*k.ValidUntil))
+}
+
+// TokenRecord keeps the metadata of an access token which is needed to reproduce an introspection response. The
+// token itself is not stored, only a hash of its signature.
+type TokenRecord struct {
+	ID        string     `json:"id" gorethink:"id"`
+	ClientID  string     `json:"client_id" gorethink:"client_id"`
+	Subject   string     `json:"sub" gorethink:"sub"`
+	Scopes    []string   `json:"scopes" gorethink:"scopes"`
+	IssuedAt  time.Time  `json:"iat" gorethink:"iat"`
+	ExpiresAt time.Time  `json:"exp" gorethink:"exp"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" gorethink:"revoked_at,omitempty"`
+}
+
+// ActiveAt returns true if the token was issued, not expired and not revoked at the given time.
+func (t *TokenRecord) ActiveAt(at time.Time) bool {
+	return !t.IssuedAt.After(at) && at.Before(t.ExpiresAt) && (t.RevokedAt == nil || at.Before(*t.RevokedAt))
+}
+
+// RevokedBefore returns true if the token was revoked before its expiry and before the given time.
+func (t *TokenRecord) RevokedBefore(at time.Time) bool {
+	return t.RevokedAt != nil && !t.RevokedAt.After(at) && at.Before(t.ExpiresAt)
+}
+
+// TokenID derives the id of a token record from the token's signature.
+func TokenID(signature string) string {
+	sum := sha256.Sum256([]byte(signature))
+	return hex.EncodeToString(sum[:])
+}
+
+// Manager stores the history of keys and tokens. Records are never removed so that investigations can look at
+// any point in time.
+type Manager interface {
+	// AddKey records that the key was added to the set at the given time.
+	AddKey(set string, key *KeyRecord) error
+
+	// RetireKey records that the key was removed from the set at the given time.
+	RetireKey(set, kid string, at time.Time) error
+
+	// GetKeys returns all keys with the given id which were part of the set at the given time. An empty id
+	// matches every key of the set.
+	GetKeys(set, kid string, at time.Time) ([]*KeyRecord, error)
+
+	// AddToken records a newly issued token.
+	AddToken(token *TokenRecord) error
+
+	// RevokeToken records that a token was revoked at the given time.
+	RevokeToken(id string, at time.Time) error
+
+	// GetToken returns a token record or pkg.ErrNotFound.
+	GetToken(id string) (*TokenRecord, error)
+
+	// GetRevokedTokens returns the tokens which were revoked at the given time but had not expired yet.
+	GetRevokedTokens(at time.Time) ([]*TokenRecord, error)
+}
@@ -0,0 +1,126 @@
+package history
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyHistory(t *testing.T) {
+	m := NewMemoryManager()
+	start := time.Now().UTC()
+
+	require.Nil(t, m.AddKey("set", &KeyRecord{KeyID: "a", ValidFrom: start}))
+	require.Nil(t, m.RetireKey("set", "a", start.Add(time.Hour)))
+	require.Nil(t, m.AddKey("set", &KeyRecord{KeyID: "a", ValidFrom: start.Add(time.Hour * 2)}))
+
+	for k, c := range []struct {
+		at    time.Time
+		found int
+	}{
+		{at: start.Add(-time.Minute), found: 0},
+		{at: start, found: 1},
+		{at: start.Add(time.Minute * 30), found: 1},
+		{at: start.Add(time.Hour), found: 0},
+		{at: start.Add(time.Hour * 3), found: 1},
+	} {
+		keys, err := m.GetKeys("set", "a", c.at)
+		require.Nil(t, err)
+		assert.Len(t, keys, c.found, "Case %d", k)
+	}
+
+	keys, err := m.GetKeys("other", "a", start)
+	require.Nil(t, err)
+	assert.Len(t, keys, 0)
+}
+
+func TestValidateOpaque(t *testing.T) {
+	m := NewMemoryManager()
+	v := &Validator{History: m}
+	issued := time.Now().UTC()
+
+	require.Nil(t, m.AddToken(&TokenRecord{
+		ID:        TokenID("signature"),
+		ClientID:  "client",
+		Subject:   "peter",
+		Scopes:    []string{"core", "hydra"},
+		IssuedAt:  issued,
+		ExpiresAt: issued.Add(time.Hour),
+	}))
+	require.Nil(t, m.RevokeToken(TokenID("signature"), issued.Add(time.Minute*30)))
+	require.Nil(t, m.RevokeToken(TokenID("signature"), issued.Add(time.Minute*40)))
+
+	for k, c := range []struct {
+		token  string
+		at     time.Time
+		active bool
+	}{
+		{token: "key.signature", at: issued.Add(time.Minute), active: true},
+		{token: "key.signature", at: issued.Add(-time.Minute), active: false},
+		{token: "key.signature", at: issued.Add(time.Minute * 35), active: false},
+		{token: "key.unknown", at: issued.Add(time.Minute), active: false},
+	} {
+		res, err := v.Validate(c.token, "", c.at)
+		require.Nil(t, err, "Case %d", k)
+		assert.Equal(t, c.active, res.Active, "Case %d", k)
+		if c.active {
+			assert.Equal(t, "client", res.ClientID, "Case %d", k)
+			assert.Equal(t, "peter", res.Subject, "Case %d", k)
+			assert.Equal(t, "core hydra", res.Scope, "Case %d", k)
+		} else {
+			assert.NotEmpty(t, res.Reason, "Case %d", k)
+		}
+	}
+}
+
+func TestValidateJWT(t *testing.T) {
+	m := NewMemoryManager()
+	v := &Validator{History: m}
+	now := time.Now().UTC()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(t, err)
+
+	public, err := json.Marshal(&jose.JsonWebKey{Key: &key.PublicKey, KeyID: "public"})
+	require.Nil(t, err)
+	require.Nil(t, m.AddKey("set", &KeyRecord{KeyID: "public", Key: string(public), ValidFrom: now.Add(-time.Hour)}))
+	require.Nil(t, m.RetireKey("set", "public", now.Add(time.Hour)))
+
+	signer, err := jose.NewSigner(jose.RS256, key)
+	require.Nil(t, err)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"sub": "peter",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour * 2).Unix(),
+	})
+	require.Nil(t, err)
+
+	sig, err := signer.Sign(payload)
+	require.Nil(t, err)
+	token, err := sig.CompactSerialize()
+	require.Nil(t, err)
+
+	for k, c := range []struct {
+		at     time.Time
+		active bool
+	}{
+		{at: now.Add(time.Minute), active: true},
+		{at: now.Add(-time.Minute), active: false},
+		{at: now.Add(time.Hour + time.Minute), active: false},
+	} {
+		res, err := v.Validate(token, "set", c.at)
+		require.Nil(t, err, "Case %d", k)
+		assert.Equal(t, c.active, res.Active, "Case %d", k)
+		if c.active {
+			assert.Equal(t, "peter", res.Subject, "Case %d", k)
+			assert.Equal(t, "public", res.KeyID, "Case %d", k)
+		}
+	}
+}
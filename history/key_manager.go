@@ -0,0 +1,176 @@
+package history
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+)
+
+// KeyManager records the public part of every key added to or removed from the wrapped manager. Symmetric keys
+// are not recorded because they can not be stored without keeping the secret.
+type KeyManager struct {
+	jwk.Manager
+
+	History Manager
+}
+
+func (m *KeyManager) AddKey(set string, key *jose.JsonWebKey) error {
+	if err := m.Manager.AddKey(set, key); err != nil {
+		return err
+	}
+	return m.record(set, *key)
+}
+
+func (m *KeyManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
+	if err := m.Manager.AddKeySet(set, keys); err != nil {
+		return err
+	}
+
+	for _, key := range keys.Keys {
+		if err := m.record(set, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *KeyManager) DeleteKey(set, kid string) error {
+	if err := m.Manager.DeleteKey(set, kid); err != nil {
+		return err
+	}
+	return m.History.RetireKey(set, kid, time.Now().UTC())
+}
+
+func (m *KeyManager) DeleteKeySet(set string) error {
+	keys, err := m.Manager.GetKeySet(set)
+	if err != nil && !errors.Is(err, pkg.ErrNotFound) {
+		return err
+	}
+
+	if err := m.Manager.DeleteKeySet(set); err != nil {
+		return err
+	}
+
+	if keys == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	for _, key := range keys.Keys {
+		if err := m.History.RetireKey(set, key.KeyID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *KeyManager) ConsistentGetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	return jwk.GetKeyConsistent(m.Manager, set, kid)
+}
+
+func (m *KeyManager) ConsistentGetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	return jwk.GetKeySetConsistent(m.Manager, set)
+}
+
+func (m *KeyManager) GetKeyByThumbprint(set, thumbprint string) (*jose.JsonWebKeySet, error) {
+	return jwk.GetKeyByThumbprint(m.Manager, set, thumbprint)
+}
+
+func (m *KeyManager) GetKeySetNames() ([]string, error) {
+	return jwk.GetKeySetNames(m.Manager)
+}
+
+func (m *KeyManager) RestoreKey(set, kid string) error {
+	s, ok := m.Manager.(jwk.SoftDeleter)
+	if !ok {
+		return errors.New("The key store does not support restoring deleted keys")
+	}
+
+	if err := s.RestoreKey(set, kid); err != nil {
+		return err
+	}
+
+	keys, err := jwk.GetKeyConsistent(m.Manager, set, kid)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys.Keys {
+		if err := m.record(set, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *KeyManager) GetDeletedKeySet(set string) (*jose.JsonWebKeySet, error) {
+	s, ok := m.Manager.(jwk.SoftDeleter)
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return s.GetDeletedKeySet(set)
+}
+
+func (m *KeyManager) Purge(before time.Time) error {
+	if s, ok := m.Manager.(jwk.SoftDeleter); ok {
+		return s.Purge(before)
+	}
+	return nil
+}
+
+// Seed records the keys of the given sets which are not part of the history yet, for example because they
+// were created before history was enabled.
+func (m *KeyManager) Seed(sets ...string) error {
+	now := time.Now().UTC()
+	for _, set := range sets {
+		keys, err := m.Manager.GetKeySet(set)
+		if errors.Is(err, pkg.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		for _, key := range keys.Keys {
+			known, err := m.History.GetKeys(set, key.KeyID, now)
+			if err != nil {
+				return err
+			} else if len(known) > 0 {
+				continue
+			}
+
+			if err := m.record(set, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *KeyManager) record(set string, key jose.JsonWebKey) error {
+	switch k := key.Key.(type) {
+	case *rsa.PrivateKey:
+		key.Key = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		key.Key = &k.PublicKey
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil
+	}
+
+	out, err := canonical.Marshal(&key)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	return m.History.AddKey(set, &KeyRecord{
+		KeyID:     key.KeyID,
+		Key:       string(out),
+		ValidFrom: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,107 @@
+package history
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type MemoryManager struct {
+	Keys   []*KeyRecord
+	Tokens map[string]*TokenRecord
+	sync.RWMutex
+}
+
+func NewMemoryManager() *MemoryManager {
+	return &MemoryManager{
+		Tokens: map[string]*TokenRecord{},
+	}
+}
+
+func (m *MemoryManager) AddKey(set string, key *KeyRecord) error {
+	m.Lock()
+	defer m.Unlock()
+
+	key.Set = set
+	m.Keys = append(m.Keys, key)
+	return nil
+}
+
+func (m *MemoryManager) RetireKey(set, kid string, at time.Time) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, k := range m.Keys {
+		if k.Set == set && k.KeyID == kid && k.ValidUntil == nil {
+			until := at
+			k.ValidUntil = &until
+		}
+	}
+	return nil
+}
+
+func (m *MemoryManager) GetKeys(set, kid string, at time.Time) ([]*KeyRecord, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var keys []*KeyRecord
+	for _, k := range m.Keys {
+		if k.Set == set && (kid == "" || k.KeyID == kid) && k.ValidAt(at) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (m *MemoryManager) AddToken(token *TokenRecord) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.Tokens == nil {
+		m.Tokens = map[string]*TokenRecord{}
+	}
+	m.Tokens[token.ID] = token
+	return nil
+}
+
+func (m *MemoryManager) RevokeToken(id string, at time.Time) error {
+	m.Lock()
+	defer m.Unlock()
+
+	t, ok := m.Tokens[id]
+	if !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	if t.RevokedAt == nil {
+		revoked := at
+		t.RevokedAt = &revoked
+	}
+	return nil
+}
+
+func (m *MemoryManager) GetToken(id string) (*TokenRecord, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	t, ok := m.Tokens[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return t, nil
+}
+
+func (m *MemoryManager) GetRevokedTokens(at time.Time) ([]*TokenRecord, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var tokens []*TokenRecord
+	for _, t := range m.Tokens {
+		if t.RevokedBefore(at) {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,133 @@
+package history
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/pborman/uuid"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// RethinkManager reads and writes directly against the database. History is only queried during
+// investigations, so it is not cached.
+type RethinkManager struct {
+	Session *r.Session
+
+	KeysTable   r.Term
+	TokensTable r.Term
+}
+
+func (m *RethinkManager) AddKey(set string, key *KeyRecord) error {
+	key.Set = set
+	if key.ID == "" {
+		key.ID = uuid.New()
+	}
+
+	if _, err := m.KeysTable.Insert(key).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) RetireKey(set, kid string, at time.Time) error {
+	if _, err := m.KeysTable.Filter(map[string]interface{}{
+		"set": set,
+		"kid": kid,
+	}).Filter(r.Row.HasFields("valid_until").Not()).Update(map[string]interface{}{
+		"valid_until": at,
+	}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) GetKeys(set, kid string, at time.Time) ([]*KeyRecord, error) {
+	filter := map[string]interface{}{"set": set}
+	if kid != "" {
+		filter["kid"] = kid
+	}
+
+	rows, err := m.KeysTable.Filter(filter).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var keys []*KeyRecord
+	var key *KeyRecord
+	for rows.Next(&key) {
+		if key.ValidAt(at) {
+			keys = append(keys, key)
+		}
+		key = nil
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.New(rows.Err())
+	}
+	return keys, nil
+}
+
+func (m *RethinkManager) AddToken(token *TokenRecord) error {
+	if _, err := m.TokensTable.Insert(token, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) RevokeToken(id string, at time.Time) error {
+	res, err := m.TokensTable.Get(id).Update(func(row r.Term) interface{} {
+		return r.Branch(row.HasFields("revoked_at"), map[string]interface{}{}, map[string]interface{}{
+			"revoked_at": at,
+		})
+	}).RunWrite(m.Session)
+	if err != nil {
+		return errors.New(err)
+	} else if res.Replaced == 0 && res.Unchanged == 0 {
+		return errors.New(pkg.ErrNotFound)
+	}
+	return nil
+}
+
+func (m *RethinkManager) GetToken(id string) (*TokenRecord, error) {
+	res, err := m.TokensTable.Get(id).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	var token TokenRecord
+	if err := res.One(&token); err != nil {
+		return nil, errors.New(err)
+	}
+	return &token, nil
+}
+
+func (m *RethinkManager) GetRevokedTokens(at time.Time) ([]*TokenRecord, error) {
+	rows, err := m.TokensTable.Filter(func(row r.Term) interface{} {
+		return row.HasFields("revoked_at").And(row.Field("exp").Gt(at))
+	}).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var tokens []*TokenRecord
+	var token *TokenRecord
+	for rows.Next(&token) {
+		if token.RevokedBefore(at) {
+			tokens = append(tokens, token)
+		}
+		token = nil
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.New(rows.Err())
+	}
+	return tokens, nil
+}
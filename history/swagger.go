@@ -0,0 +1,17 @@
+package history
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "POST", Path: ValidateHandlerPath, ID: "validateTokenAt", Tag: "history", Scope: "hydra.history",
+		Summary:   "Check whether a token was valid at a point in time",
+		Body:      &ValidateRequest{},
+		Responses: map[int]interface{}{http.StatusOK: &Result{}},
+	},
+}
@@ -0,0 +1,116 @@
+package history
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+// TokenStore records the metadata of every access token persisted through the wrapped store.
+type TokenStore struct {
+	pkg.FositeStorer
+
+	History  Manager
+	Lifespan time.Duration
+}
+
+func (s *TokenStore) CreateAccessTokenSession(ctx context.Context, signature string, request fosite.Requester) error {
+	if err := s.FositeStorer.CreateAccessTokenSession(ctx, signature, request); err != nil {
+		return err
+	}
+	return s.record(signature, request)
+}
+
+func (s *TokenStore) CreateImplicitAccessTokenSession(ctx context.Context, signature string, request fosite.Requester) error {
+	if err := s.FositeStorer.CreateImplicitAccessTokenSession(ctx, signature, request); err != nil {
+		return err
+	}
+	return s.record(signature, request)
+}
+
+func (s *TokenStore) PersistAuthorizeCodeGrantSession(ctx context.Context, authorizeCode, accessSignature, refreshSignature string, request fosite.Requester) error {
+	if err := s.FositeStorer.PersistAuthorizeCodeGrantSession(ctx, authorizeCode, accessSignature, refreshSignature, request); err != nil {
+		return err
+	}
+	return s.record(accessSignature, request)
+}
+
+func (s *TokenStore) PersistRefreshTokenGrantSession(ctx context.Context, originalRefreshSignature, accessSignature, refreshSignature string, request fosite.Requester) error {
+	if err := s.FositeStorer.PersistRefreshTokenGrantSession(ctx, originalRefreshSignature, accessSignature, refreshSignature, request); err != nil {
+		return err
+	}
+	return s.record(accessSignature, request)
+}
+
+func (s *TokenStore) DeleteAccessTokenSession(ctx context.Context, signature string) error {
+	if err := s.FositeStorer.DeleteAccessTokenSession(ctx, signature); err != nil {
+		return err
+	}
+	// Tokens issued before history was enabled are not known and can not be revoked in the history.
+	if err := s.History.RevokeToken(TokenID(signature), time.Now().UTC()); err != nil && !errors.Is(err, pkg.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *TokenStore) RevokeRefreshToken(ctx context.Context, signature string) ([]string, error) {
+	revoked, err := s.FositeStorer.RevokeRefreshToken(ctx, signature)
+	if err != nil {
+		return nil, err
+	}
+	return revoked, s.revoke(revoked)
+}
+
+func (s *TokenStore) RevokeClientTokens(ctx context.Context, clientID string) ([]string, error) {
+	revoked, err := s.FositeStorer.RevokeClientTokens(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return revoked, s.revoke(revoked)
+}
+
+func (s *TokenStore) RevokeSubjectTokens(ctx context.Context, subject, clientID string) ([]string, error) {
+	revoked, err := s.FositeStorer.RevokeSubjectTokens(ctx, subject, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return revoked, s.revoke(revoked)
+}
+
+// revoke records the revocation of tokens. Refresh tokens and tokens issued before history was enabled are not
+// recorded and are skipped.
+func (s *TokenStore) revoke(signatures []string) error {
+	now := time.Now().UTC()
+	for _, signature := range signatures {
+		if err := s.History.RevokeToken(TokenID(signature), now); err != nil && !errors.Is(err, pkg.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TokenStore) record(signature string, request fosite.Requester) error {
+	var subject string
+	if sess, ok := request.GetSession().(*oauth2.Session); ok {
+		subject = sess.Subject
+	}
+
+	scopes := request.GetGrantedScopes()
+	if len(scopes) == 0 {
+		scopes = request.GetScopes()
+	}
+
+	issuedAt := request.GetRequestedAt().UTC()
+	return s.History.AddToken(&TokenRecord{
+		ID:        TokenID(signature),
+		ClientID:  request.GetClient().GetID(),
+		Subject:   subject,
+		Scopes:    []string(scopes),
+		IssuedAt:  issuedAt,
+		ExpiresAt: issuedAt.Add(s.Lifespan),
+	})
+}
@@ -0,0 +1,141 @@
+package history
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+)
+
+// Result describes whether a token was valid at a point in time and, for opaque tokens, what an introspection
+// request would have returned back then.
+type Result struct {
+	Active    bool                   `json:"active"`
+	At        time.Time              `json:"at"`
+	Format    string                 `json:"format"`
+	KeyID     string                 `json:"kid,omitempty"`
+	ClientID  string                 `json:"client_id,omitempty"`
+	Subject   string                 `json:"sub,omitempty"`
+	Scope     string                 `json:"scope,omitempty"`
+	IssuedAt  int64                  `json:"iat,omitempty"`
+	ExpiresAt int64                  `json:"exp,omitempty"`
+	Claims    map[string]interface{} `json:"claims,omitempty"`
+	Reason    string                 `json:"reason,omitempty"`
+}
+
+// Validator validates tokens against the state recorded in the history.
+type Validator struct {
+	History Manager
+}
+
+// Validate checks if the token was valid at the given time. JSON Web Tokens are verified using the keys which
+// were part of the set at that time, all other tokens are looked up by their signature.
+func (v *Validator) Validate(token, set string, at time.Time) (*Result, error) {
+	if strings.Count(token, ".") == 2 {
+		return v.validateJWT(token, set, at)
+	}
+	return v.validateOpaque(token, at)
+}
+
+func (v *Validator) validateJWT(token, set string, at time.Time) (*Result, error) {
+	res := &Result{At: at, Format: "jwt"}
+
+	sig, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, errors.New(err)
+	} else if len(sig.Signatures) == 0 {
+		return nil, errors.New("Token is not signed")
+	}
+
+	kid := sig.Signatures[0].Header.KeyID
+	keys, err := v.History.GetKeys(set, kid, at)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	for _, k := range keys {
+		var key jose.JsonWebKey
+		if err := json.Unmarshal([]byte(k.Key), &key); err != nil {
+			return nil, errors.New(err)
+		}
+
+		if payload, err = sig.Verify(key.Key); err == nil {
+			res.KeyID = k.KeyID
+			break
+		}
+	}
+
+	if payload == nil {
+		res.Reason = "No key of the set was able to verify the signature at the given time"
+		return res, nil
+	}
+
+	if err := json.Unmarshal(payload, &res.Claims); err != nil {
+		return nil, errors.New(err)
+	}
+
+	res.Subject, _ = res.Claims["sub"].(string)
+	res.IssuedAt = claimTime(res.Claims, "iat")
+	res.ExpiresAt = claimTime(res.Claims, "exp")
+	nbf := claimTime(res.Claims, "nbf")
+
+	switch {
+	case res.IssuedAt > 0 && at.Unix() < res.IssuedAt:
+		res.Reason = "Token was issued after the given time"
+	case nbf > 0 && at.Unix() < nbf:
+		res.Reason = "Token was not valid yet at the given time"
+	case res.ExpiresAt > 0 && at.Unix() >= res.ExpiresAt:
+		res.Reason = "Token was expired at the given time"
+	default:
+		res.Active = true
+	}
+	return res, nil
+}
+
+func (v *Validator) validateOpaque(token string, at time.Time) (*Result, error) {
+	res := &Result{At: at, Format: "opaque"}
+
+	signature := token
+	if i := strings.LastIndex(token, "."); i >= 0 {
+		signature = token[i+1:]
+	}
+
+	rec, err := v.History.GetToken(TokenID(signature))
+	if errors.Is(err, pkg.ErrNotFound) {
+		res.Reason = "Token is unknown"
+		return res, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	res.Active = rec.ActiveAt(at)
+	if !res.Active {
+		switch {
+		case at.Before(rec.IssuedAt):
+			res.Reason = "Token was issued after the given time"
+		case rec.RevokedAt != nil && !at.Before(*rec.RevokedAt):
+			res.Reason = "Token was revoked before the given time"
+		default:
+			res.Reason = "Token was expired at the given time"
+		}
+		return res, nil
+	}
+
+	res.ClientID = rec.ClientID
+	res.Subject = rec.Subject
+	res.Scope = strings.Join(rec.Scopes, " ")
+	res.IssuedAt = rec.IssuedAt.Unix()
+	res.ExpiresAt = rec.ExpiresAt.Unix()
+	return res, nil
+}
+
+func claimTime(claims map[string]interface{}, name string) int64 {
+	if v, ok := claims[name].(float64); ok {
+		return int64(v)
+	}
+	return 0
+}
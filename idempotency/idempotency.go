@@ -0,0 +1,111 @@
+// Package idempotency lets automation retry mutating admin requests safely. A request which carries an
+// Idempotency-Key header is executed once, retries with the same key receive the response of the first request
+// until the key expires.
+package idempotency
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+const (
+	// KeyHeader carries the idempotency key of a request.
+	KeyHeader = "Idempotency-Key"
+
+	// ReplayedHeader is set on responses which were replayed from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// MaxKeyLength is the length of the longest key a caller may choose.
+	MaxKeyLength = 255
+
+	// DefaultLifespan is how long responses are kept if the middleware does not say otherwise.
+	DefaultLifespan = time.Hour * 24
+)
+
+// ErrConflict is returned by Store.CreateResponse if the key is taken.
+var ErrConflict = errors.New("The idempotency key is taken")
+
+// Response is the response to the first request with a key. Status is zero while the request is in progress.
+type Response struct {
+	// ID is derived from the key and the credentials of the caller, so that callers can not see each other's
+	// responses.
+	ID string `json:"id" gorethink:"id"`
+
+	// Fingerprint identifies the method, URL and body of the request. A retry must match it.
+	Fingerprint string `json:"fingerprint" gorethink:"fingerprint"`
+
+	Status int         `json:"status" gorethink:"status"`
+	Header http.Header `json:"header,omitempty" gorethink:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty" gorethink:"body,omitempty"`
+
+	ExpiresAt time.Time `json:"expires_at" gorethink:"expires_at"`
+}
+
+// IsExpired returns true if the key may be used again.
+func (r *Response) IsExpired() bool {
+	return !time.Now().Before(r.ExpiresAt)
+}
+
+// IsComplete returns true if the first request finished.
+func (r *Response) IsComplete() bool {
+	return r.Status != 0
+}
+
+// Cipher encrypts the bodies of stored responses, which carry secrets like generated keys and client secrets.
+type Cipher interface {
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
+
+// Store persists responses.
+type Store interface {
+	// CreateResponse reserves the key of a response, it returns ErrConflict if the key is taken and not expired.
+	CreateResponse(r *Response) error
+
+	// GetResponse returns a response or pkg.ErrNotFound if it does not exist or is expired.
+	GetResponse(id string) (*Response, error)
+
+	// UpdateResponse stores the response of a finished request.
+	UpdateResponse(r *Response) error
+
+	DeleteResponse(id string) error
+}
+
+// Purger is implemented by stores which keep expired responses until they are purged. Stores whose database expires
+// rows by itself do not implement it.
+type Purger interface {
+	// Purge deletes the responses which expired before the given time.
+	Purge(before time.Time) error
+}
+
+// PurgeExpiredResponses periodically purges expired responses until ctx is done. Only the leader purges, unless
+// leader is nil. It does nothing if the store does not implement Purger.
+func PurgeExpiredResponses(ctx context.Context, s Store, leader pkg.Leader, interval time.Duration) {
+	p, ok := s.(Purger)
+	if !ok {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if leader != nil && !leader.IsLeader() {
+					continue
+				}
+				if err := p.Purge(time.Now()); err != nil {
+					logger.LogError(err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,209 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+)
+
+var (
+	ErrKeyTooLong = &herodot.Error{
+		Err:  errors.Errorf("The idempotency key must not be longer than %d characters", MaxKeyLength),
+		Code: http.StatusBadRequest,
+	}
+	ErrInProgress = &herodot.Error{
+		Err:  errors.New("A request with this idempotency key is in progress"),
+		Code: http.StatusConflict,
+	}
+	ErrKeyReused = &herodot.Error{
+		Err:  errors.New("The idempotency key was used for a different request"),
+		Code: 422, // Unprocessable Entity, which net/http does not name before Go 1.7.
+	}
+)
+
+// Middleware executes POST, PUT and PATCH requests with an Idempotency-Key header once and answers retries with the
+// stored response. Responses with status codes of 500 and above are not stored, so that a failed request can be
+// retried with the same key.
+type Middleware struct {
+	Store Store
+
+	// Lifespan is how long responses are kept, it defaults to DefaultLifespan.
+	Lifespan time.Duration
+
+	// Skip exempts paths, for example the public endpoints.
+	Skip func(path string) bool
+
+	// Cipher encrypts the bodies of stored responses. Bodies are stored as they are if it is nil, which is only
+	// safe for stores which do not leave the process.
+	Cipher Cipher
+
+	H herodot.Herodot
+}
+
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(KeyHeader)
+		if key == "" || (r.Method != "POST" && r.Method != "PUT" && r.Method != "PATCH") || (m.Skip != nil && m.Skip(r.URL.Path)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := herodot.NewContext()
+		if len(key) > MaxKeyLength {
+			m.H.WriteError(ctx, w, r, ErrKeyTooLong)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			m.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		res := &Response{
+			ID:          hash(r.Header.Get("Authorization"), key),
+			Fingerprint: hash(r.Method, r.URL.RequestURI(), string(body)),
+			ExpiresAt:   time.Now().Add(m.lifespan()),
+		}
+
+		if err := m.Store.CreateResponse(res); errors.Is(err, ErrConflict) {
+			m.replay(w, r, res)
+			return
+		} else if err != nil {
+			m.H.WriteError(ctx, w, r, err)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		if rec.status >= http.StatusInternalServerError {
+			err = m.Store.DeleteResponse(res.ID)
+		} else {
+			res.Status = rec.status
+			res.Header = rec.header
+			if res.Body, err = m.encrypt(rec.body.Bytes()); err == nil {
+				err = m.Store.UpdateResponse(res)
+			} else if derr := m.Store.DeleteResponse(res.ID); derr != nil {
+				logger.LogRequestError(r, derr)
+			}
+		}
+		if err != nil {
+			// The response was sent already, a retry executes the request again once the key expires.
+			logger.LogRequestError(r, err)
+		}
+	})
+}
+
+// replay answers a retry with the stored response of the first request.
+func (m *Middleware) replay(w http.ResponseWriter, r *http.Request, res *Response) {
+	ctx := herodot.NewContext()
+	stored, err := m.Store.GetResponse(res.ID)
+	if errors.Is(err, pkg.ErrNotFound) {
+		// The first request failed in the meantime.
+		m.H.WriteError(ctx, w, r, ErrInProgress)
+		return
+	} else if err != nil {
+		m.H.WriteError(ctx, w, r, err)
+		return
+	} else if stored.Fingerprint != res.Fingerprint {
+		m.H.WriteError(ctx, w, r, ErrKeyReused)
+		return
+	} else if !stored.IsComplete() {
+		m.H.WriteError(ctx, w, r, ErrInProgress)
+		return
+	}
+
+	body, err := m.decrypt(stored.Body)
+	if err != nil {
+		m.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	for k, v := range stored.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	w.Write(body)
+}
+
+func (m *Middleware) encrypt(body []byte) ([]byte, error) {
+	if m.Cipher == nil || len(body) == 0 {
+		return body, nil
+	}
+
+	ciphertext, err := m.Cipher.Encrypt(body)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return []byte(ciphertext), nil
+}
+
+func (m *Middleware) decrypt(body []byte) ([]byte, error) {
+	if m.Cipher == nil || len(body) == 0 {
+		return body, nil
+	}
+
+	plaintext, err := m.Cipher.Decrypt(string(body))
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return plaintext, nil
+}
+
+func (m *Middleware) lifespan() time.Duration {
+	if m.Lifespan > 0 {
+		return m.Lifespan
+	}
+	return DefaultLifespan
+}
+
+func hash(values ...string) string {
+	h := sha256.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes the response on and keeps a copy of it.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.header = http.Header{}
+		for k, v := range r.ResponseWriter.Header() {
+			r.header[k] = append([]string{}, v...)
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,118 @@
+package idempotency_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ory-am/hydra/herodot"
+	. "github.com/ory-am/hydra/idempotency"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls int
+	status := http.StatusCreated
+	m := &Middleware{
+		Store: NewMemoryStore(),
+		Skip: func(path string) bool {
+			return path == "/public"
+		},
+		H: &herodot.JSON{},
+	}
+	ts := httptest.NewServer(m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Location", "/clients/1")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "%d:%s", calls, body)
+	})))
+	defer ts.Close()
+
+	do := func(method, path, key, authorization, body string) (*http.Response, string) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.Nil(t, err)
+		if key != "" {
+			req.Header.Set(KeyHeader, key)
+		}
+		req.Header.Set("Authorization", authorization)
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer res.Body.Close()
+		out, err := ioutil.ReadAll(res.Body)
+		require.Nil(t, err)
+		return res, string(out)
+	}
+
+	for k, c := range []struct {
+		method, path, key, authorization, body string
+		status                                 int
+		response                               string
+		replayed                               bool
+	}{
+		{method: "POST", path: "/clients", key: "a", authorization: "alice", body: "x", status: http.StatusCreated, response: "1:x"},
+		{method: "POST", path: "/clients", key: "a", authorization: "alice", body: "x", status: http.StatusCreated, response: "1:x", replayed: true},
+		{method: "POST", path: "/clients", key: "a", authorization: "alice", body: "y", status: 422},
+		{method: "POST", path: "/clients", key: "a", authorization: "bob", body: "y", status: http.StatusCreated, response: "2:y"},
+		{method: "POST", path: "/clients", authorization: "alice", body: "x", status: http.StatusCreated, response: "3:x"},
+		{method: "DELETE", path: "/clients", key: "a", authorization: "alice", status: http.StatusCreated, response: "4:"},
+		{method: "POST", path: "/public", key: "a", authorization: "alice", body: "x", status: http.StatusCreated, response: "5:x"},
+		{method: "POST", path: "/clients", key: strings.Repeat("a", MaxKeyLength+1), authorization: "alice", status: http.StatusBadRequest},
+	} {
+		res, body := do(c.method, c.path, c.key, c.authorization, c.body)
+		assert.Equal(t, c.status, res.StatusCode, "Case %d", k)
+		if c.response != "" {
+			assert.Equal(t, c.response, body, "Case %d", k)
+			assert.Equal(t, "/clients/1", res.Header.Get("Location"), "Case %d", k)
+		}
+		assert.Equal(t, c.replayed, res.Header.Get(ReplayedHeader) == "true", "Case %d", k)
+	}
+
+	// Failed requests are not stored and can be retried with the same key.
+	status = http.StatusInternalServerError
+	res, _ := do("PUT", "/clients/1", "b", "alice", "z")
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+	status = http.StatusOK
+	res, body := do("PUT", "/clients/1", "b", "alice", "z")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "7:z", body)
+}
+
+func TestMiddlewareEncryptsStoredBodies(t *testing.T) {
+	store := NewMemoryStore()
+	m := &Middleware{
+		Store:  store,
+		Cipher: &jwk.AEAD{Key: []byte("some-very-long-system-secret-of-32-characters")},
+		H:      &herodot.JSON{},
+	}
+	ts := httptest.NewServer(m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"k":"private-key"}`))
+	})))
+	defer ts.Close()
+
+	for k := 0; k < 2; k++ {
+		req, err := http.NewRequest("POST", ts.URL+"/keys/foo", nil)
+		require.Nil(t, err)
+		req.Header.Set(KeyHeader, "a")
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		require.Nil(t, err)
+		assert.Equal(t, `{"k":"private-key"}`, string(body), "Case %d", k)
+	}
+
+	require.Len(t, store.Responses, 1)
+	for _, res := range store.Responses {
+		assert.False(t, strings.Contains(string(res.Body), "private-key"))
+	}
+}
@@ -0,0 +1,147 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+const dynamoResponsePrefix = "idempotency#"
+
+// DynamoDBStore stores responses in the DynamoDB table which the DynamoDB backends share, see
+// pkg.CreateDynamoDBTable. Every response is an item of its own, which the table deletes some time after it
+// expired.
+type DynamoDBStore struct {
+	DB    dynamodbiface.DynamoDBAPI
+	Table string
+}
+
+type dynamoResponse struct {
+	PK          string `dynamodbav:"pk"`
+	SK          string `dynamodbav:"sk"`
+	Fingerprint string `dynamodbav:"fingerprint"`
+	Status      int    `dynamodbav:"status"`
+	Header      string `dynamodbav:"header"`
+	Body        []byte `dynamodbav:"body,omitempty"`
+	ExpiresAt   int64  `dynamodbav:"expires_at"`
+	TTL         int64  `dynamodbav:"ttl"`
+}
+
+// CreateSchemas creates the table unless it exists.
+func (m *DynamoDBStore) CreateSchemas() error {
+	return pkg.CreateDynamoDBTable(m.DB, m.Table)
+}
+
+// CreateResponse reserves the key with a conditional put, which succeeds only if the key is free or expired. The
+// table may keep expired items for a while, so their expiry is checked as well.
+func (m *DynamoDBStore) CreateResponse(res *Response) error {
+	item, err := m.newItem(res)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.DB.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(m.Table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(pk) OR expires_at <= :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10))},
+		},
+	}); pkg.IsDynamoDBConditionFailed(err) {
+		return errors.New(ErrConflict)
+	} else if err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *DynamoDBStore) GetResponse(id string) (*Response, error) {
+	out, err := m.DB.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(m.Table),
+		Key:            pkg.DynamoDBKey(dynamoResponsePrefix+id, dynamoResponsePrefix+id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, errors.New(err)
+	} else if len(out.Item) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	var item dynamoResponse
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &item); err != nil {
+		return nil, errors.New(err)
+	}
+
+	res := &Response{
+		ID:          id,
+		Fingerprint: item.Fingerprint,
+		Status:      item.Status,
+		Header:      http.Header{},
+		Body:        item.Body,
+		ExpiresAt:   time.Unix(0, item.ExpiresAt).UTC(),
+	}
+	if res.IsExpired() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := json.Unmarshal([]byte(item.Header), &res.Header); err != nil {
+		return nil, errors.New(err)
+	}
+	return res, nil
+}
+
+func (m *DynamoDBStore) UpdateResponse(res *Response) error {
+	item, err := m.newItem(res)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.DB.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(m.Table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(pk)"),
+	}); pkg.IsDynamoDBConditionFailed(err) {
+		return errors.New(pkg.ErrNotFound)
+	} else if err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *DynamoDBStore) DeleteResponse(id string) error {
+	if _, err := m.DB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(m.Table),
+		Key:       pkg.DynamoDBKey(dynamoResponsePrefix+id, dynamoResponsePrefix+id),
+	}); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *DynamoDBStore) newItem(res *Response) (map[string]*dynamodb.AttributeValue, error) {
+	header, err := json.Marshal(res.Header)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	item, err := dynamodbattribute.MarshalMap(&dynamoResponse{
+		PK:          dynamoResponsePrefix + res.ID,
+		SK:          dynamoResponsePrefix + res.ID,
+		Fingerprint: res.Fingerprint,
+		Status:      res.Status,
+		Header:      string(header),
+		Body:        res.Body,
+		ExpiresAt:   res.ExpiresAt.UnixNano(),
+		TTL:         res.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return item, nil
+}
@@ -0,0 +1,77 @@
+package idempotency
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type MemoryStore struct {
+	Responses map[string]*Response
+	sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		Responses: map[string]*Response{},
+	}
+}
+
+// CreateResponse reserves the key and forgets the expired responses, so that the store does not grow without bounds.
+func (m *MemoryStore) CreateResponse(r *Response) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for id, stored := range m.Responses {
+		if stored.IsExpired() {
+			delete(m.Responses, id)
+		}
+	}
+
+	if _, ok := m.Responses[r.ID]; ok {
+		return errors.New(ErrConflict)
+	}
+	m.Responses[r.ID] = copyResponse(r)
+	return nil
+}
+
+func (m *MemoryStore) GetResponse(id string) (*Response, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	r, ok := m.Responses[id]
+	if !ok || r.IsExpired() {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return copyResponse(r), nil
+}
+
+func (m *MemoryStore) UpdateResponse(r *Response) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.Responses[r.ID]; !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+	m.Responses[r.ID] = copyResponse(r)
+	return nil
+}
+
+func (m *MemoryStore) DeleteResponse(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Responses, id)
+	return nil
+}
+
+func copyResponse(r *Response) *Response {
+	c := *r
+	c.Header = map[string][]string{}
+	for k, v := range r.Header {
+		c.Header[k] = append([]string{}, v...)
+	}
+	c.Body = append([]byte{}, r.Body...)
+	return &c
+}
@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// RethinkStore reads and writes directly against the database, so that a retry which reaches another instance sees
+// the response of the first request.
+type RethinkStore struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+// CreateResponse reserves the key with a single conditional replace, which stores the response if the key is free
+// or expired and leaves a response which holds the key unchanged.
+func (m *RethinkStore) CreateResponse(res *Response) error {
+	now := time.Now()
+	result, err := m.Table.Get(res.ID).Replace(func(row r.Term) interface{} {
+		return r.Branch(row.Eq(nil).Or(row.Field("expires_at").Le(now)), res, row)
+	}).RunWrite(m.Session)
+	if err != nil {
+		return errors.New(err)
+	} else if result.Inserted+result.Replaced == 0 {
+		return errors.New(ErrConflict)
+	}
+	return nil
+}
+
+func (m *RethinkStore) GetResponse(id string) (*Response, error) {
+	rows, err := m.Table.Get(id).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var res Response
+	if rows.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := rows.One(&res); err != nil {
+		return nil, errors.New(err)
+	} else if res.IsExpired() {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return &res, nil
+}
+
+func (m *RethinkStore) UpdateResponse(res *Response) error {
+	if _, err := m.Table.Get(res.ID).Replace(res).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkStore) DeleteResponse(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// Purge deletes the responses which expired before the given time.
+func (m *RethinkStore) Purge(before time.Time) error {
+	if _, err := m.Table.Filter(r.Row.Field("expires_at").Lt(before)).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package idempotency
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+var sqlSchemas = map[string][]string{
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS hydra_idempotency_key (
+	id          varchar(255) NOT NULL PRIMARY KEY,
+	fingerprint varchar(255) NOT NULL,
+	status      integer NOT NULL,
+	header      text NOT NULL,
+	body        bytea NOT NULL,
+	expires_at  timestamp NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS hydra_idempotency_key_expires_at_idx ON hydra_idempotency_key (expires_at)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS hydra_idempotency_key (
+	id          varchar(255) NOT NULL PRIMARY KEY,
+	fingerprint varchar(255) NOT NULL,
+	status      integer NOT NULL,
+	header      text NOT NULL,
+	body        mediumblob NOT NULL,
+	expires_at  datetime NOT NULL,
+	INDEX hydra_idempotency_key_expires_at_idx (expires_at)
+)`,
+	},
+}
+
+var migrations = []*pkg.Migration{
+	{
+		ID: "1",
+		Up: sqlSchemas,
+		Down: map[string][]string{
+			"postgres": {`DROP TABLE IF EXISTS hydra_idempotency_key`},
+			"mysql":    {`DROP TABLE IF EXISTS hydra_idempotency_key`},
+		},
+	},
+}
+
+// SQLStore stores responses in PostgreSQL or MySQL. Expired rows are taken over by the next request with their key
+// and deleted by Purge.
+type SQLStore struct {
+	DB *sql.DB
+
+	// Driver is the name of the database/sql driver, either "postgres" or "mysql".
+	Driver string
+}
+
+// Migrator returns the migrator of the idempotency table.
+func (m *SQLStore) Migrator() *pkg.Migrator {
+	return &pkg.Migrator{DB: m.DB, Driver: m.Driver, Module: "idempotency", Migrations: migrations}
+}
+
+// CreateSchemas applies all migrations of the idempotency table which were not applied yet.
+func (m *SQLStore) CreateSchemas() error {
+	_, err := m.Migrator().Up()
+	return err
+}
+
+// CreateResponse takes over an expired row with a conditional update and inserts the reservation otherwise. The
+// insert fails on the primary key if another request holds the key.
+func (m *SQLStore) CreateResponse(res *Response) error {
+	header, err := json.Marshal(res.Header)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	result, err := m.DB.Exec(m.rebind("UPDATE hydra_idempotency_key SET fingerprint=?, status=?, header=?, body=?, expires_at=? WHERE id=? AND expires_at<=?"),
+		res.Fingerprint, res.Status, string(header), res.Body, res.ExpiresAt.UTC(), res.ID, time.Now().UTC())
+	if err != nil {
+		return errors.New(err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return errors.New(err)
+	} else if n > 0 {
+		return nil
+	}
+
+	if _, err := m.DB.Exec(m.rebind("INSERT INTO hydra_idempotency_key (id, fingerprint, status, header, body, expires_at) VALUES (?, ?, ?, ?, ?, ?)"),
+		res.ID, res.Fingerprint, res.Status, string(header), res.Body, res.ExpiresAt.UTC()); err == nil {
+		return nil
+	}
+
+	// The insert fails if another request holds the key, anything else is an error.
+	var id string
+	if err := m.DB.QueryRow(m.rebind("SELECT id FROM hydra_idempotency_key WHERE id=?"), res.ID).Scan(&id); err != nil {
+		return errors.New(err)
+	}
+	return errors.New(ErrConflict)
+}
+
+func (m *SQLStore) GetResponse(id string) (*Response, error) {
+	var res Response
+	var header string
+	if err := m.DB.QueryRow(m.rebind("SELECT id, fingerprint, status, header, body, expires_at FROM hydra_idempotency_key WHERE id=?"), id).
+		Scan(&res.ID, &res.Fingerprint, &res.Status, &header, &res.Body, &res.ExpiresAt); err == sql.ErrNoRows {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err != nil {
+		return nil, errors.New(err)
+	} else if res.IsExpired() {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	res.Header = http.Header{}
+	if err := json.Unmarshal([]byte(header), &res.Header); err != nil {
+		return nil, errors.New(err)
+	}
+	return &res, nil
+}
+
+func (m *SQLStore) UpdateResponse(res *Response) error {
+	header, err := json.Marshal(res.Header)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	result, err := m.DB.Exec(m.rebind("UPDATE hydra_idempotency_key SET fingerprint=?, status=?, header=?, body=?, expires_at=? WHERE id=?"),
+		res.Fingerprint, res.Status, string(header), res.Body, res.ExpiresAt.UTC(), res.ID)
+	if err != nil {
+		return errors.New(err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return errors.New(err)
+	} else if n == 0 {
+		return errors.New(pkg.ErrNotFound)
+	}
+	return nil
+}
+
+func (m *SQLStore) DeleteResponse(id string) error {
+	if _, err := m.DB.Exec(m.rebind("DELETE FROM hydra_idempotency_key WHERE id=?"), id); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// Purge deletes the responses which expired before the given time.
+func (m *SQLStore) Purge(before time.Time) error {
+	if _, err := m.DB.Exec(m.rebind("DELETE FROM hydra_idempotency_key WHERE expires_at<?"), before.UTC()); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLStore) rebind(query string) string {
+	return pkg.RebindSQL(m.Driver, query)
+}
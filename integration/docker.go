@@ -0,0 +1,161 @@
+// Package integration starts the databases which backends are tested against in docker containers. It is used by
+// the tests of this repository and can be used the same way by downstream backend implementations:
+//
+//	func TestMain(m *testing.M) {
+//		db := integration.ConnectToPostgres()
+//		managers["postgres"] = &SQLManager{DB: db, Driver: "postgres"}
+//
+//		code := m.Run()
+//		integration.KillAll()
+//		os.Exit(code)
+//	}
+//
+// Every function retries until the database accepts connections and exits the test binary if it never does.
+package integration
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	r "gopkg.in/dancannon/gorethink.v2"
+	"gopkg.in/ory-am/dockertest.v2"
+)
+
+const (
+	tries = 20
+	delay = time.Second
+)
+
+var (
+	containers []dockertest.ContainerID
+	mutex      sync.Mutex
+)
+
+func track(c dockertest.ContainerID, err error, database string) {
+	if err != nil {
+		log.Fatalf("Could not connect to %s: %s", database, err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	containers = append(containers, c)
+}
+
+// KillAll removes all containers started by this package.
+func KillAll() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, c := range containers {
+		if err := c.KillRemove(); err != nil {
+			log.Printf("Could not remove container %s: %s", c, err)
+		}
+	}
+	containers = nil
+}
+
+// ConnectToRethinkDB starts RethinkDB and returns a session using database. The database and tables are created.
+func ConnectToRethinkDB(database string, tables ...string) *r.Session {
+	var session *r.Session
+	c, err := dockertest.ConnectToRethinkDB(tries, delay, func(url string) bool {
+		var err error
+		if session, err = r.Connect(r.ConnectOpts{Address: url, Database: database}); err != nil {
+			return false
+		} else if _, err = r.DBCreate(database).RunWrite(session); err != nil {
+			log.Printf("Database exists: %s", err)
+			return false
+		}
+
+		for _, table := range tables {
+			if _, err = r.TableCreate(table).RunWrite(session); err != nil {
+				log.Printf("Could not create table: %s", err)
+				return false
+			}
+		}
+		return true
+	})
+	track(c, err, "RethinkDB")
+	return session
+}
+
+// ConnectToPostgres starts PostgreSQL and returns a connection to its default database.
+func ConnectToPostgres() *sql.DB {
+	var db *sql.DB
+	c, err := dockertest.ConnectToPostgreSQL(tries, delay, func(url string) bool {
+		var err error
+		if db, err = sql.Open("postgres", url); err != nil {
+			return false
+		}
+		return db.Ping() == nil
+	})
+	track(c, err, "PostgreSQL")
+	return db
+}
+
+// ConnectToMySQL starts MySQL and returns a connection to its default database. Timestamps are parsed, as the SQL
+// backends require.
+func ConnectToMySQL() *sql.DB {
+	var db *sql.DB
+	c, err := dockertest.ConnectToMySQL(tries, delay, func(url string) bool {
+		var err error
+		dsn := url
+		if strings.Contains(dsn, "?") {
+			dsn += "&parseTime=true"
+		} else {
+			dsn += "?parseTime=true"
+		}
+		if db, err = sql.Open("mysql", dsn); err != nil {
+			return false
+		}
+		return db.Ping() == nil
+	})
+	track(c, err, "MySQL")
+	return db
+}
+
+// ConnectToRedis starts Redis and returns its address. This repository has no Redis backend, so no client is
+// returned; downstream backends connect with the client of their choice.
+func ConnectToRedis() string {
+	var address string
+	c, err := dockertest.ConnectToRedis(tries, delay, func(url string) bool {
+		conn, err := net.Dial("tcp", url)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		address = url
+		return true
+	})
+	track(c, err, "Redis")
+	return address
+}
+
+// ConnectToDynamoDB starts DynamoDB Local and returns a client of it. DynamoDB Local accepts any credentials.
+func ConnectToDynamoDB() *dynamodb.DynamoDB {
+	var db *dynamodb.DynamoDB
+	c, ip, port, err := dockertest.SetupCustomContainer("amazon/dynamodb-local", 8000, time.Minute)
+	if err == nil {
+		err = dockertest.ConnectToCustomContainer(fmt.Sprintf("http://%s:%d", ip, port), tries, delay, func(url string) bool {
+			db = dynamodb.New(session.New(&aws.Config{
+				Endpoint:    aws.String(url),
+				Region:      aws.String("us-east-1"),
+				Credentials: credentials.NewStaticCredentials("hydra", "hydra", ""),
+			}))
+			_, err := db.ListTables(&dynamodb.ListTablesInput{})
+			return err == nil
+		})
+	}
+	track(c, err, "DynamoDB")
+	return db
+}
@@ -0,0 +1,69 @@
+package conformance
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/go-errors/errors"
+)
+
+// Interaction is a single HTTP exchange captured by the Recorder.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Cassette holds all interactions of a conformance run in the order they happened.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+	sync.Mutex
+}
+
+func LoadCassette(path string) (*Cassette, error) {
+	var c Cassette
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, errors.New(err)
+	}
+	return &c, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.New(err)
+	}
+
+	if err := ioutil.WriteFile(path, out, 0600); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (c *Cassette) add(i *Interaction) {
+	c.Lock()
+	defer c.Unlock()
+	c.Interactions = append(c.Interactions, i)
+}
@@ -0,0 +1,167 @@
+package conformance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// DefaultChecks covers the parts of the OpenID Certification basic and config profiles which can be verified
+// without a user agent.
+var DefaultChecks = []Check{
+	{
+		Name:    "token-endpoint-client-credentials",
+		Profile: ProfileBasic,
+		Run: func(r *Runner) error {
+			_, err := r.Token("core")
+			return err
+		},
+	},
+	{
+		Name:    "token-endpoint-rejects-invalid-client",
+		Profile: ProfileBasic,
+		Run: func(r *Runner) error {
+			form := url.Values{"grant_type": {"client_credentials"}, "scope": {"core"}}
+			req, err := http.NewRequest("POST", r.URL("/oauth2/token").String(), strings.NewReader(form.Encode()))
+			if err != nil {
+				return errors.New(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth(r.ClientID, r.ClientSecret+"-invalid")
+
+			resp, body, err := r.Do(req)
+			if err != nil {
+				return err
+			} else if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusBadRequest {
+				return errors.Errorf("Expected status code 400 or 401 but got %d", resp.StatusCode)
+			}
+
+			var rfcerr struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(body, &rfcerr); err != nil {
+				return errors.New(err)
+			} else if rfcerr.Error == "" {
+				return errors.New("Error response did not contain the error field")
+			}
+			return nil
+		},
+	},
+	{
+		Name:    "authorize-endpoint-redirects-to-consent",
+		Profile: ProfileBasic,
+		Run: func(r *Runner) error {
+			u := r.URL("/oauth2/auth")
+			u.RawQuery = url.Values{
+				"response_type": {"code"},
+				"client_id":     {r.ClientID},
+				"scope":         {"core openid"},
+				"state":         {"conformance-state"},
+				"nonce":         {"conformance-nonce"},
+			}.Encode()
+
+			req, err := http.NewRequest("GET", u.String(), nil)
+			if err != nil {
+				return errors.New(err)
+			}
+
+			resp, _, err := r.Do(req)
+			if err != nil {
+				return err
+			} else if resp.StatusCode != http.StatusFound {
+				return errors.Errorf("Expected status code %d but got %d", http.StatusFound, resp.StatusCode)
+			}
+
+			location, err := url.Parse(resp.Header.Get("Location"))
+			if err != nil {
+				return errors.New(err)
+			} else if location.Query().Get("error") != "" {
+				return errors.Errorf("Authorize endpoint returned error %s: %s", location.Query().Get("error"), location.Query().Get("error_description"))
+			} else if location.Query().Get("challenge") == "" {
+				return errors.Errorf("Expected redirect to the consent endpoint with a challenge but got %s", location)
+			}
+			return nil
+		},
+	},
+	{
+		Name:    "authorize-endpoint-rejects-unknown-client",
+		Profile: ProfileBasic,
+		Run: func(r *Runner) error {
+			u := r.URL("/oauth2/auth")
+			u.RawQuery = url.Values{
+				"response_type": {"code"},
+				"client_id":     {"conformance-unknown-client"},
+				"scope":         {"core openid"},
+				"state":         {"conformance-state"},
+			}.Encode()
+
+			req, err := http.NewRequest("GET", u.String(), nil)
+			if err != nil {
+				return errors.New(err)
+			}
+
+			resp, _, err := r.Do(req)
+			if err != nil {
+				return err
+			} else if resp.StatusCode == http.StatusFound {
+				location, err := url.Parse(resp.Header.Get("Location"))
+				if err != nil {
+					return errors.New(err)
+				} else if location.Query().Get("error") == "" {
+					return errors.Errorf("Expected an error redirect but got %s", location)
+				}
+				return nil
+			} else if resp.StatusCode < 400 {
+				return errors.Errorf("Expected an error but got status code %d", resp.StatusCode)
+			}
+			return nil
+		},
+	},
+	{
+		Name:    "id-token-signing-key-published",
+		Profile: ProfileConfig,
+		Run: func(r *Runner) error {
+			token, err := r.Token("core", "hydra.keys.get")
+			if err != nil {
+				return err
+			}
+
+			req, err := http.NewRequest("GET", r.URL("/keys/hydra.openid.connect/public").String(), nil)
+			if err != nil {
+				return errors.New(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, body, err := r.Do(req)
+			if err != nil {
+				return err
+			} else if resp.StatusCode != http.StatusOK {
+				return errors.Errorf("Expected status code %d but got %d: %s", http.StatusOK, resp.StatusCode, body)
+			}
+
+			var keys struct {
+				Keys []struct {
+					KeyType  string `json:"kty"`
+					PrivateD string `json:"d"`
+				} `json:"keys"`
+			}
+			if err := json.Unmarshal(body, &keys); err != nil {
+				return errors.New(err)
+			} else if len(keys.Keys) == 0 {
+				return errors.New("Key set for hydra.openid.connect does not contain a public key")
+			}
+
+			for _, k := range keys.Keys {
+				if k.KeyType != "RSA" {
+					return errors.Errorf("Expected key type RSA but got %s", k.KeyType)
+				} else if k.PrivateD != "" {
+					return errors.New("Public key endpoint exposes private key material")
+				}
+			}
+			return nil
+		},
+	},
+}
@@ -0,0 +1,112 @@
+package conformance
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/token":
+			if _, secret, _ := r.BasicAuth(); secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_client"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"foo","token_type":"bearer"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.Nil(t, err)
+
+	checks := DefaultChecks[:2]
+	cassette := new(Cassette)
+	recording := &Runner{
+		Transport:    &Recorder{Cassette: cassette},
+		ClusterURL:   u,
+		ClientID:     "app",
+		ClientSecret: "secret",
+		Checks:       checks,
+	}
+	for _, res := range recording.Run(ProfileBasic) {
+		assert.True(t, res.Passed, "%s: %s", res.Name, res.Error)
+	}
+	require.Len(t, cassette.Interactions, 2)
+	assert.Equal(t, "redacted", cassette.Interactions[0].Request.Header.Get("Authorization"))
+
+	dir, err := ioutil.TempDir("", "conformance")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cassette.json")
+	pkg.RequireError(t, false, cassette.Save(path))
+	loaded, err := LoadCassette(path)
+	pkg.RequireError(t, false, err)
+
+	ts.Close()
+	replaying := &Runner{
+		Transport:    &Replayer{Cassette: loaded},
+		ClusterURL:   u,
+		ClientID:     "app",
+		ClientSecret: "secret",
+		Checks:       checks,
+	}
+	for _, res := range replaying.Run(ProfileBasic) {
+		assert.True(t, res.Passed, "%s: %s", res.Name, res.Error)
+	}
+
+	_, err = (&Replayer{Cassette: loaded}).RoundTrip(&http.Request{Method: "GET", URL: &url.URL{Path: "/foo"}})
+	pkg.AssertError(t, true, err)
+}
+
+func TestRedactBody(t *testing.T) {
+	for k, c := range []struct {
+		contentType string
+		body        string
+		expected    string
+	}{
+		{
+			contentType: "application/x-www-form-urlencoded",
+			body:        "grant_type=client_credentials&client_id=app&client_secret=secret",
+			expected:    "client_id=app&client_secret=redacted&grant_type=client_credentials",
+		},
+		{
+			contentType: "application/json;charset=UTF-8",
+			body:        `{"access_token":"foo","refresh_token":"bar","token_type":"bearer"}`,
+			expected:    `{"access_token":"redacted","refresh_token":"redacted","token_type":"bearer"}`,
+		},
+		{
+			contentType: "application/json",
+			body:        `[{"client_id":"app","client_secret":"secret"}]`,
+			expected:    `[{"client_id":"app","client_secret":"redacted"}]`,
+		},
+		{
+			contentType: "application/json",
+			body:        `{"error": "invalid_client"}`,
+			expected:    `{"error": "invalid_client"}`,
+		},
+		{
+			contentType: "text/plain",
+			body:        "access_token=foo",
+			expected:    "access_token=foo",
+		},
+	} {
+		h := http.Header{}
+		h.Set("Content-Type", c.contentType)
+		assert.Equal(t, c.expected, redactBody(h, []byte(c.body)), "Case %d", k)
+	}
+}
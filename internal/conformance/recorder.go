@@ -0,0 +1,137 @@
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// redactedFields are the form and JSON fields of request and response bodies which carry credentials.
+var redactedFields = map[string]bool{
+	"client_secret": true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+}
+
+// Recorder is a http.RoundTripper which forwards requests to Transport and stores every exchange in Cassette.
+type Recorder struct {
+	Transport http.RoundTripper
+	Cassette  *Cassette
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, errors.New(err)
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	r.Cassette.add(&Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: redact(req.Header),
+			Body:   redactBody(req.Header, reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       redactBody(resp.Header, respBody),
+		},
+	})
+	return resp, nil
+}
+
+// redact removes credentials so cassettes can be shared and checked in.
+func redact(h http.Header) http.Header {
+	c := http.Header{}
+	for k, v := range h {
+		if http.CanonicalHeaderKey(k) == "Authorization" {
+			c.Set(k, "redacted")
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+// redactBody removes credentials from form and JSON bodies. Bodies of other types and bodies without credentials are
+// kept as they are.
+func redactBody(h http.Header, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		// A malformed form is redacted as far as it parses.
+		form, _ := url.ParseQuery(string(body))
+		var redacted bool
+		for k := range form {
+			if redactedFields[k] {
+				form[k] = []string{"redacted"}
+				redacted = true
+			}
+		}
+		if redacted {
+			return form.Encode()
+		}
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil || !redactJSON(v) {
+			break
+		}
+		if out, err := json.Marshal(v); err == nil {
+			return string(out)
+		}
+	}
+	return string(body)
+}
+
+// redactJSON replaces the credentials of decoded JSON in place and returns true if it replaced any.
+func redactJSON(v interface{}) bool {
+	var redacted bool
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if redactedFields[k] {
+				v[k] = "redacted"
+				redacted = true
+			} else if redactJSON(field) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if redactJSON(item) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
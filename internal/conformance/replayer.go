@@ -0,0 +1,57 @@
+package conformance
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/go-errors/errors"
+)
+
+// Replayer is a http.RoundTripper which answers requests from a Cassette instead of the network. Interactions are
+// replayed in the order they were recorded and a request must match the recorded method and path.
+type Replayer struct {
+	Cassette *Cassette
+
+	position int
+	sync.Mutex
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.position >= len(r.Cassette.Interactions) {
+		return nil, errors.Errorf("Cassette exhausted, no interaction recorded for %s %s", req.Method, req.URL.Path)
+	}
+
+	i := r.Cassette.Interactions[r.position]
+	recorded, err := url.Parse(i.Request.URL)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	if i.Request.Method != req.Method || recorded.Path != req.URL.Path {
+		return nil, errors.Errorf("Interaction %d mismatch, expected %s %s but got %s %s", r.position, i.Request.Method, recorded.Path, req.Method, req.URL.Path)
+	}
+	r.position++
+
+	header := http.Header{}
+	for k, v := range i.Response.Header {
+		header[k] = v
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(i.Response.StatusCode),
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewBufferString(i.Response.Body)),
+		ContentLength: int64(len(i.Response.Body)),
+		Request:       req,
+	}, nil
+}
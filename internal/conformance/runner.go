@@ -0,0 +1,128 @@
+package conformance
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+const (
+	ProfileBasic  = "basic"
+	ProfileConfig = "config"
+)
+
+// Check is a single conformance assertion belonging to an OpenID Certification profile.
+type Check struct {
+	Name    string
+	Profile string
+	Run     func(r *Runner) error
+}
+
+type Result struct {
+	Name    string `json:"name"`
+	Profile string `json:"profile"`
+	Passed  bool   `json:"passed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Runner executes checks against a cluster. Set Transport to a Recorder to capture a run, or to a Replayer to
+// repeat a previously captured run without a live cluster.
+type Runner struct {
+	Transport    http.RoundTripper
+	ClusterURL   *url.URL
+	ClientID     string
+	ClientSecret string
+	Checks       []Check
+}
+
+func (r *Runner) Run(profiles ...string) []Result {
+	checks := r.Checks
+	if len(checks) == 0 {
+		checks = DefaultChecks
+	}
+
+	var results []Result
+	for _, c := range checks {
+		if !contains(profiles, c.Profile) {
+			continue
+		}
+
+		res := Result{Name: c.Name, Profile: c.Profile, Passed: true}
+		if err := c.Run(r); err != nil {
+			res.Passed = false
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func (r *Runner) transport() http.RoundTripper {
+	if r.Transport == nil {
+		return http.DefaultTransport
+	}
+	return r.Transport
+}
+
+// Do sends the request without following redirects, because several checks assert on the redirect itself.
+func (r *Runner) Do(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := r.transport().RoundTrip(req)
+	if err != nil {
+		return nil, nil, errors.New(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.New(err)
+	}
+	return resp, body, nil
+}
+
+func (r *Runner) URL(path ...string) *url.URL {
+	return pkg.JoinURL(r.ClusterURL, path...)
+}
+
+func (r *Runner) Token(scopes ...string) (string, error) {
+	form := url.Values{"grant_type": {"client_credentials"}, "scope": {strings.Join(scopes, " ")}}
+	req, err := http.NewRequest("POST", r.URL("/oauth2/token").String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", errors.New(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(r.ClientID, r.ClientSecret)
+
+	resp, body, err := r.Do(req)
+	if err != nil {
+		return "", err
+	} else if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("Expected status code %d but got %d: %s", http.StatusOK, resp.StatusCode, body)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", errors.New(err)
+	} else if token.AccessToken == "" {
+		return "", errors.New("Token response did not contain an access_token")
+	} else if !strings.EqualFold(token.TokenType, "bearer") {
+		return "", errors.Errorf("Expected token_type bearer but got %s", token.TokenType)
+	}
+	return token.AccessToken, nil
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, h := range haystack {
+		if h == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+// FositeBudgetStore fails token reads and writes which take longer than their budget, so that a degraded
+// database makes token requests fail fast instead of hanging. Reads are never served from a stale copy, a token
+// might have been revoked since. A write which exceeded its budget might still complete in the background.
+// Revocations are not bounded.
+type FositeBudgetStore struct {
+	pkg.FositeStorer
+
+	ReadBudget  time.Duration
+	WriteBudget time.Duration
+}
+
+func (s *FositeBudgetStore) read(operation string, f func() (fosite.Requester, error)) (fosite.Requester, error) {
+	var req fosite.Requester
+	err := pkg.WithinBudget("oauth2_"+operation, s.ReadBudget, func() (err error) {
+		req, err = f()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (s *FositeBudgetStore) write(operation string, f func() error) error {
+	return pkg.WithinBudget("oauth2_"+operation, s.WriteBudget, f)
+}
+
+func (s *FositeBudgetStore) GetClient(id string) (fosite.Client, error) {
+	var c fosite.Client
+	err := pkg.WithinBudget("oauth2_get_client", s.ReadBudget, func() (err error) {
+		c, err = s.FositeStorer.GetClient(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *FositeBudgetStore) CreateOpenIDConnectSession(ctx context.Context, authorizeCode string, requester fosite.Requester) error {
+	return s.write("create_openid_connect_session", func() error {
+		return s.FositeStorer.CreateOpenIDConnectSession(ctx, authorizeCode, requester)
+	})
+}
+
+func (s *FositeBudgetStore) GetOpenIDConnectSession(ctx context.Context, authorizeCode string, requester fosite.Requester) (fosite.Requester, error) {
+	return s.read("get_openid_connect_session", func() (fosite.Requester, error) {
+		return s.FositeStorer.GetOpenIDConnectSession(ctx, authorizeCode, requester)
+	})
+}
+
+func (s *FositeBudgetStore) CreateAuthorizeCodeSession(ctx context.Context, code string, req fosite.Requester) error {
+	return s.write("create_authorize_code_session", func() error {
+		return s.FositeStorer.CreateAuthorizeCodeSession(ctx, code, req)
+	})
+}
+
+func (s *FositeBudgetStore) GetAuthorizeCodeSession(ctx context.Context, code string, session interface{}) (fosite.Requester, error) {
+	return s.read("get_authorize_code_session", func() (fosite.Requester, error) {
+		return s.FositeStorer.GetAuthorizeCodeSession(ctx, code, session)
+	})
+}
+
+func (s *FositeBudgetStore) CreateAccessTokenSession(ctx context.Context, signature string, req fosite.Requester) error {
+	return s.write("create_access_token_session", func() error {
+		return s.FositeStorer.CreateAccessTokenSession(ctx, signature, req)
+	})
+}
+
+func (s *FositeBudgetStore) GetAccessTokenSession(ctx context.Context, signature string, session interface{}) (fosite.Requester, error) {
+	return s.read("get_access_token_session", func() (fosite.Requester, error) {
+		return s.FositeStorer.GetAccessTokenSession(ctx, signature, session)
+	})
+}
+
+func (s *FositeBudgetStore) CreateRefreshTokenSession(ctx context.Context, signature string, req fosite.Requester) error {
+	return s.write("create_refresh_token_session", func() error {
+		return s.FositeStorer.CreateRefreshTokenSession(ctx, signature, req)
+	})
+}
+
+func (s *FositeBudgetStore) GetRefreshTokenSession(ctx context.Context, signature string, session interface{}) (fosite.Requester, error) {
+	return s.read("get_refresh_token_session", func() (fosite.Requester, error) {
+		return s.FositeStorer.GetRefreshTokenSession(ctx, signature, session)
+	})
+}
+
+func (s *FositeBudgetStore) CreateImplicitAccessTokenSession(ctx context.Context, signature string, req fosite.Requester) error {
+	return s.write("create_implicit_access_token_session", func() error {
+		return s.FositeStorer.CreateImplicitAccessTokenSession(ctx, signature, req)
+	})
+}
+
+func (s *FositeBudgetStore) PersistAuthorizeCodeGrantSession(ctx context.Context, authorizeCode, accessSignature, refreshSignature string, request fosite.Requester) error {
+	return s.write("persist_authorize_code_grant_session", func() error {
+		return s.FositeStorer.PersistAuthorizeCodeGrantSession(ctx, authorizeCode, accessSignature, refreshSignature, request)
+	})
+}
+
+func (s *FositeBudgetStore) PersistRefreshTokenGrantSession(ctx context.Context, originalRefreshSignature, accessSignature, refreshSignature string, request fosite.Requester) error {
+	return s.write("persist_refresh_token_grant_session", func() error {
+		return s.FositeStorer.PersistRefreshTokenGrantSession(ctx, originalRefreshSignature, accessSignature, refreshSignature, request)
+	})
+}
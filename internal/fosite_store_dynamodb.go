@@ -0,0 +1,514 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+const (
+	dynamoTokenPrefix  = "token#"
+	dynamoTokenSortKey = "token"
+	dynamoClientPrefix = "client#"
+	dynamoGrantPrefix  = "grant#"
+)
+
+// dynamoRevocableKinds are the kinds of tokens which RevokeClientTokens deletes, only they are indexed by client.
+var dynamoRevocableKinds = map[string]bool{
+	pkg.TokenKindAccessToken:         true,
+	pkg.TokenKindImplicitAccessToken: true,
+	pkg.TokenKindRefreshToken:        true,
+}
+
+// FositeDynamoDBStore stores OAuth2 sessions in the DynamoDB table which the DynamoDB backends share, see
+// pkg.CreateDynamoDBTable. Signatures are unique, a session is only written if no session of the same kind has the
+// signature, and grants are persisted in transactions.
+//
+// Sessions set the TTL attribute of the table to the time their lifespan ends, or to the expiry the session carries
+// if the client overrides the server's lifespan, so the table deletes them instead of a janitor. DynamoDB deletes expired items up to two days late, expired sessions are therefore not returned even if
+// they were not deleted yet. Sessions of a kind whose lifespan is zero never expire.
+//
+// Tokens are looked up by client and grant through the indexes of the table, which are updated asynchronously:
+// tokens issued moments before their client or grant is revoked might survive the revocation.
+type FositeDynamoDBStore struct {
+	DB    dynamodbiface.DynamoDBAPI
+	Table string
+
+	client.Manager
+
+	// AuthorizeCodeLifespan is how long authorize codes and the OpenID Connect sessions they belong to are kept.
+	AuthorizeCodeLifespan time.Duration
+
+	// AccessTokenLifespan is how long access tokens are kept.
+	AccessTokenLifespan time.Duration
+
+	// RefreshTokenLifespan is how long refresh tokens are kept. They are kept until they are revoked if zero.
+	RefreshTokenLifespan time.Duration
+}
+
+type dynamoToken struct {
+	PK     string `dynamodbav:"pk"`
+	SK     string `dynamodbav:"sk"`
+	GSI1PK string `dynamodbav:"gsi1pk,omitempty"`
+	GSI1SK string `dynamodbav:"gsi1sk,omitempty"`
+	GSI2PK string `dynamodbav:"gsi2pk,omitempty"`
+	GSI2SK string `dynamodbav:"gsi2sk,omitempty"`
+
+	Kind          string    `dynamodbav:"kind"`
+	Signature     string    `dynamodbav:"signature"`
+	RequestedAt   time.Time `dynamodbav:"requested_at"`
+	ClientID      string    `dynamodbav:"client_id"`
+	ClientData    string    `dynamodbav:"client_data"`
+	Scopes        string    `dynamodbav:"scopes"`
+	GrantedScopes string    `dynamodbav:"granted_scopes"`
+	FormData      string    `dynamodbav:"form_data"`
+	SessionData   string    `dynamodbav:"session_data"`
+	GrantID       string    `dynamodbav:"grant_id"`
+	Rotated       bool      `dynamodbav:"rotated"`
+
+	// TTL is the unix time the session expires at, zero if it does not expire.
+	TTL int64 `dynamodbav:"ttl,omitempty"`
+}
+
+// CreateSchemas creates the table unless it exists.
+func (s *FositeDynamoDBStore) CreateSchemas() error {
+	return pkg.CreateDynamoDBTable(s.DB, s.Table)
+}
+
+func (s *FositeDynamoDBStore) CreateOpenIDConnectSession(_ context.Context, authorizeCode string, requester fosite.Requester) error {
+	return s.insert(pkg.TokenKindOpenIDConnectSession, authorizeCode, "", requester)
+}
+
+func (s *FositeDynamoDBStore) GetOpenIDConnectSession(_ context.Context, authorizeCode string, requester fosite.Requester) (fosite.Requester, error) {
+	return s.get(pkg.TokenKindOpenIDConnectSession, authorizeCode, requester.GetSession())
+}
+
+func (s *FositeDynamoDBStore) DeleteOpenIDConnectSession(_ context.Context, authorizeCode string) error {
+	return s.delete(pkg.TokenKindOpenIDConnectSession, authorizeCode)
+}
+
+func (s *FositeDynamoDBStore) CreateAuthorizeCodeSession(_ context.Context, code string, requester fosite.Requester) error {
+	return s.insert(pkg.TokenKindAuthorizeCode, code, "", requester)
+}
+
+func (s *FositeDynamoDBStore) GetAuthorizeCodeSession(_ context.Context, code string, sess interface{}) (fosite.Requester, error) {
+	return s.get(pkg.TokenKindAuthorizeCode, code, sess)
+}
+
+func (s *FositeDynamoDBStore) DeleteAuthorizeCodeSession(_ context.Context, code string) error {
+	return s.delete(pkg.TokenKindAuthorizeCode, code)
+}
+
+func (s *FositeDynamoDBStore) CreateAccessTokenSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.insert(pkg.TokenKindAccessToken, signature, "", requester)
+}
+
+func (s *FositeDynamoDBStore) GetAccessTokenSession(_ context.Context, signature string, sess interface{}) (fosite.Requester, error) {
+	return s.get(pkg.TokenKindAccessToken, signature, sess)
+}
+
+func (s *FositeDynamoDBStore) DeleteAccessTokenSession(_ context.Context, signature string) error {
+	return s.delete(pkg.TokenKindAccessToken, signature)
+}
+
+func (s *FositeDynamoDBStore) CreateRefreshTokenSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.insert(pkg.TokenKindRefreshToken, signature, "", requester)
+}
+
+func (s *FositeDynamoDBStore) GetRefreshTokenSession(_ context.Context, signature string, sess interface{}) (fosite.Requester, error) {
+	return s.get(pkg.TokenKindRefreshToken, signature, sess)
+}
+
+func (s *FositeDynamoDBStore) DeleteRefreshTokenSession(_ context.Context, signature string) error {
+	return s.delete(pkg.TokenKindRefreshToken, signature)
+}
+
+func (s *FositeDynamoDBStore) CreateImplicitAccessTokenSession(_ context.Context, code string, requester fosite.Requester) error {
+	return s.insert(pkg.TokenKindImplicitAccessToken, code, "", requester)
+}
+
+// PersistAuthorizeCodeGrantSession exchanges the authorize code in one transaction, which fails if the code was
+// exchanged concurrently.
+func (s *FositeDynamoDBStore) PersistAuthorizeCodeGrantSession(ctx context.Context, authorizeCode, accessSignature, refreshSignature string, request fosite.Requester) error {
+	access, err := s.put(pkg.TokenKindAccessToken, accessSignature, refreshSignature, request)
+	if err != nil {
+		return err
+	}
+	refresh, err := s.put(pkg.TokenKindRefreshToken, refreshSignature, refreshSignature, request)
+	if err != nil {
+		return err
+	}
+
+	return s.transaction(
+		&dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+			TableName:           aws.String(s.Table),
+			Key:                 pkg.DynamoDBKey(dynamoTokenKey(pkg.TokenKindAuthorizeCode, authorizeCode), dynamoTokenSortKey),
+			ConditionExpression: aws.String("attribute_exists(pk)"),
+		}},
+		&dynamodb.TransactWriteItem{Put: access},
+		&dynamodb.TransactWriteItem{Put: refresh},
+	)
+}
+
+// PersistRefreshTokenGrantSession rotates the refresh token in one transaction, which fails if the refresh token
+// was rotated concurrently.
+func (s *FositeDynamoDBStore) PersistRefreshTokenGrantSession(ctx context.Context, originalRefreshSignature, accessSignature, refreshSignature string, request fosite.Requester) error {
+	grant, err := s.grant(originalRefreshSignature)
+	if err != nil {
+		return err
+	} else if grant == "" {
+		grant = refreshSignature
+	}
+
+	access, err := s.put(pkg.TokenKindAccessToken, accessSignature, grant, request)
+	if err != nil {
+		return err
+	}
+	refresh, err := s.put(pkg.TokenKindRefreshToken, refreshSignature, grant, request)
+	if err != nil {
+		return err
+	}
+
+	// The rotated refresh token is kept until its grant is revoked so that a reuse can be detected.
+	return s.transaction(
+		&dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+			TableName:           aws.String(s.Table),
+			Key:                 pkg.DynamoDBKey(dynamoTokenKey(pkg.TokenKindRefreshToken, originalRefreshSignature), dynamoTokenSortKey),
+			UpdateExpression:    aws.String("SET rotated = :rotated, grant_id = :grant, gsi2pk = :grantKey, gsi2sk = :sortKey"),
+			ConditionExpression: aws.String("rotated = :notRotated"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":rotated":    {BOOL: aws.Bool(true)},
+				":notRotated": {BOOL: aws.Bool(false)},
+				":grant":      {S: aws.String(grant)},
+				":grantKey":   {S: aws.String(dynamoGrantPrefix + grant)},
+				":sortKey":    {S: aws.String(dynamoTokenKey(pkg.TokenKindRefreshToken, originalRefreshSignature))},
+			},
+		}},
+		&dynamodb.TransactWriteItem{Put: access},
+		&dynamodb.TransactWriteItem{Put: refresh},
+	)
+}
+
+func (s *FositeDynamoDBStore) IsRefreshTokenRotated(_ context.Context, signature string) (bool, error) {
+	t, err := s.fetch(pkg.TokenKindRefreshToken, signature)
+	if errors.Is(err, fosite.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return t.Rotated, nil
+}
+
+func (s *FositeDynamoDBStore) RevokeRefreshToken(ctx context.Context, signature string) ([]string, error) {
+	grant, err := s.grant(signature)
+	if err != nil {
+		return nil, err
+	} else if err := s.delete(pkg.TokenKindRefreshToken, signature); err != nil {
+		return nil, err
+	} else if grant == "" {
+		return nil, nil
+	}
+
+	tokens, err := s.query(pkg.DynamoDBIndex2, pkg.DynamoDBIndex2PartitionKey, dynamoGrantPrefix+grant)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []string
+	for _, t := range tokens {
+		if t.Kind == pkg.TokenKindAccessToken {
+			revoked = append(revoked, t.Signature)
+		}
+	}
+	return revoked, s.deleteAll(tokens)
+}
+
+// CountClientAccessTokens skips the tokens which expired but were not deleted by the table yet.
+func (s *FositeDynamoDBStore) CountClientAccessTokens(_ context.Context, clientID string) (int, error) {
+	tokens, err := s.query(pkg.DynamoDBIndex1, pkg.DynamoDBIndex1PartitionKey, dynamoClientPrefix+clientID)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	now := time.Now().Unix()
+	for _, t := range tokens {
+		if t.Kind != pkg.TokenKindAccessToken && t.Kind != pkg.TokenKindImplicitAccessToken {
+			continue
+		} else if t.TTL == 0 || t.TTL > now {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *FositeDynamoDBStore) RevokeClientTokens(_ context.Context, clientID string) ([]string, error) {
+	tokens, err := s.query(pkg.DynamoDBIndex1, pkg.DynamoDBIndex1PartitionKey, dynamoClientPrefix+clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []string
+	for _, t := range tokens {
+		revoked = append(revoked, t.Signature)
+	}
+	return revoked, s.deleteAll(tokens)
+}
+
+// RevokeSubjectTokens looks the tokens up by client, the subject is only known to the session.
+func (s *FositeDynamoDBStore) RevokeSubjectTokens(_ context.Context, subject, clientID string) ([]string, error) {
+	tokens, err := s.query(pkg.DynamoDBIndex1, pkg.DynamoDBIndex1PartitionKey, dynamoClientPrefix+clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []string
+	var matching []*dynamoToken
+	for _, t := range tokens {
+		if sessionSubject([]byte(t.SessionData)) == subject {
+			matching = append(matching, t)
+			revoked = append(revoked, t.Signature)
+		}
+	}
+	return revoked, s.deleteAll(matching)
+}
+
+// SupportsHedgedReads returns true, every read is a request which may be sent twice.
+func (s *FositeDynamoDBStore) SupportsHedgedReads() bool {
+	return true
+}
+
+func (s *FositeDynamoDBStore) insert(kind, signature, grant string, requester fosite.Requester) error {
+	put, err := s.put(kind, signature, grant, requester)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.DB.PutItem(&dynamodb.PutItemInput{
+		TableName:           put.TableName,
+		Item:                put.Item,
+		ConditionExpression: put.ConditionExpression,
+	}); pkg.IsDynamoDBConditionFailed(err) {
+		return errors.Errorf("A %s with this signature exists already", kind)
+	} else if err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// put returns the write of a new session, it fails if a session of the same kind has the signature.
+func (s *FositeDynamoDBStore) put(kind, signature, grant string, requester fosite.Requester) (*dynamodb.Put, error) {
+	t := &dynamoToken{
+		PK:          dynamoTokenKey(kind, signature),
+		SK:          dynamoTokenSortKey,
+		Kind:        kind,
+		Signature:   signature,
+		RequestedAt: requester.GetRequestedAt().UTC(),
+		ClientID:    requester.GetClient().GetID(),
+		GrantID:     grant,
+	}
+	if dynamoRevocableKinds[kind] {
+		t.GSI1PK, t.GSI1SK = dynamoClientPrefix+t.ClientID, t.PK
+	}
+	if grant != "" {
+		t.GSI2PK, t.GSI2SK = dynamoGrantPrefix+grant, t.PK
+	}
+	t.TTL = s.ttl(kind, requester)
+
+	for _, field := range []struct {
+		target *string
+		v      interface{}
+	}{
+		{target: &t.ClientData, v: requester.GetClient()},
+		{target: &t.Scopes, v: requester.GetScopes()},
+		{target: &t.GrantedScopes, v: requester.GetGrantedScopes()},
+		{target: &t.FormData, v: requester.GetRequestForm()},
+		{target: &t.SessionData, v: requester.GetSession()},
+	} {
+		data, err := json.Marshal(field.v)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		*field.target = string(data)
+	}
+
+	item, err := dynamodbattribute.MarshalMap(t)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	return &dynamodb.Put{
+		TableName:           aws.String(s.Table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	}, nil
+}
+
+// get returns the session of a token. Rotated refresh tokens are not found.
+func (s *FositeDynamoDBStore) get(kind, signature string, sess interface{}) (fosite.Requester, error) {
+	t, err := s.fetch(kind, signature)
+	if err != nil {
+		return nil, err
+	} else if t.Rotated {
+		return nil, fosite.ErrNotFound
+	}
+
+	item := &RdbSchema{
+		ID:          signature,
+		RequestedAt: t.RequestedAt,
+		Client:      new(fosite.DefaultClient),
+		Form:        url.Values{},
+		Session:     json.RawMessage(t.SessionData),
+	}
+	for _, field := range []struct {
+		data string
+		v    interface{}
+	}{
+		{data: t.ClientData, v: item.Client},
+		{data: t.Scopes, v: &item.Scopes},
+		{data: t.GrantedScopes, v: &item.GrantedScopes},
+		{data: t.FormData, v: &item.Form},
+	} {
+		if err := json.Unmarshal([]byte(field.data), field.v); err != nil {
+			return nil, errors.New(err)
+		}
+	}
+	return requestFromRDB(item, sess)
+}
+
+// fetch reads a token, expired tokens which were not deleted yet are not found.
+func (s *FositeDynamoDBStore) fetch(kind, signature string) (*dynamoToken, error) {
+	out, err := s.DB.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(s.Table),
+		Key:            pkg.DynamoDBKey(dynamoTokenKey(kind, signature), dynamoTokenSortKey),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, errors.New(err)
+	} else if len(out.Item) == 0 {
+		return nil, fosite.ErrNotFound
+	}
+
+	var t dynamoToken
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &t); err != nil {
+		return nil, errors.New(err)
+	} else if t.TTL > 0 && time.Now().Unix() >= t.TTL {
+		return nil, fosite.ErrNotFound
+	}
+	return &t, nil
+}
+
+func (s *FositeDynamoDBStore) delete(kind, signature string) error {
+	if _, err := s.DB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(s.Table),
+		Key:       pkg.DynamoDBKey(dynamoTokenKey(kind, signature), dynamoTokenSortKey),
+	}); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (s *FositeDynamoDBStore) deleteAll(tokens []*dynamoToken) error {
+	keys := make([]map[string]*dynamodb.AttributeValue, len(tokens))
+	for k, t := range tokens {
+		keys[k] = pkg.DynamoDBKey(t.PK, t.SK)
+	}
+	return pkg.DeleteDynamoDBItems(s.DB, s.Table, keys)
+}
+
+// grant returns the grant a refresh token was issued by, or an empty string if it is not known.
+func (s *FositeDynamoDBStore) grant(signature string) (string, error) {
+	t, err := s.fetch(pkg.TokenKindRefreshToken, signature)
+	if errors.Is(err, fosite.ErrNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return t.GrantID, nil
+}
+
+// query returns the tokens whose attribute of an index equals value.
+func (s *FositeDynamoDBStore) query(index, attribute, value string) ([]*dynamoToken, error) {
+	var tokens []*dynamoToken
+	var decodeErr error
+	if err := s.DB.QueryPages(&dynamodb.QueryInput{
+		TableName:                aws.String(s.Table),
+		IndexName:                aws.String(index),
+		KeyConditionExpression:   aws.String("#attribute = :value"),
+		ExpressionAttributeNames: map[string]*string{"#attribute": aws.String(attribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":value": {S: aws.String(value)},
+		},
+	}, func(page *dynamodb.QueryOutput, _ bool) bool {
+		for _, item := range page.Items {
+			var t dynamoToken
+			if err := dynamodbattribute.UnmarshalMap(item, &t); err != nil {
+				decodeErr = errors.New(err)
+				return false
+			}
+			tokens = append(tokens, &t)
+		}
+		return true
+	}); err != nil {
+		return nil, errors.New(err)
+	} else if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return tokens, nil
+}
+
+func (s *FositeDynamoDBStore) transaction(items ...*dynamodb.TransactWriteItem) error {
+	if _, err := s.DB.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: items}); pkg.IsDynamoDBTransactionCanceled(err) {
+		return errors.Errorf("The grant was persisted concurrently or its token is gone: %s", err)
+	} else if err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// tokenExpirer is implemented by sessions which carry the expiry of the tokens of clients that override the server's
+// lifespans.
+type tokenExpirer interface {
+	GetTokenExpiresAt(kind string) (time.Time, bool)
+}
+
+// ttl returns the unix time a token of the kind expires at, or zero if it does not expire. The session's expiry wins
+// over the server's lifespan.
+func (s *FositeDynamoDBStore) ttl(kind string, requester fosite.Requester) int64 {
+	if session, ok := requester.GetSession().(tokenExpirer); ok {
+		if expiresAt, ok := session.GetTokenExpiresAt(kind); ok {
+			return expiresAt.Unix()
+		}
+	}
+
+	if lifespan := s.lifespan(kind); lifespan > 0 {
+		return requester.GetRequestedAt().Add(lifespan).Unix()
+	}
+	return 0
+}
+
+func (s *FositeDynamoDBStore) lifespan(kind string) time.Duration {
+	switch kind {
+	case pkg.TokenKindAuthorizeCode, pkg.TokenKindOpenIDConnectSession:
+		return s.AuthorizeCodeLifespan
+	case pkg.TokenKindAccessToken, pkg.TokenKindImplicitAccessToken:
+		return s.AccessTokenLifespan
+	case pkg.TokenKindRefreshToken:
+		return s.RefreshTokenLifespan
+	}
+	return 0
+}
+
+func dynamoTokenKey(kind, signature string) string {
+	return dynamoTokenPrefix + kind + "#" + signature
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"reflect"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+// FositeHedgedStore hedges the token reads of introspection, the warden and the token endpoint. Writes are never
+// hedged.
+type FositeHedgedStore struct {
+	pkg.FositeStorer
+
+	Hedger *pkg.Hedger
+}
+
+type hedgedRead struct {
+	request fosite.Requester
+	session interface{}
+}
+
+// read hedges f. Stores decode the session into the pointer they are given, so every call decodes into a
+// session of its own and the session of the call which answered first is copied into session.
+func (s *FositeHedgedStore) read(operation string, session interface{}, f func(session interface{}) (fosite.Requester, error)) (fosite.Requester, error) {
+	v := reflect.ValueOf(session)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		req, err := s.Hedger.Do("oauth2_"+operation, func() (interface{}, error) {
+			return f(session)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return req.(fosite.Requester), nil
+	}
+
+	res, err := s.Hedger.Do("oauth2_"+operation, func() (interface{}, error) {
+		own := reflect.New(v.Elem().Type()).Interface()
+		req, err := f(own)
+		return &hedgedRead{request: req, session: own}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := res.(*hedgedRead)
+	v.Elem().Set(reflect.ValueOf(r.session).Elem())
+	if req, ok := r.request.(*fosite.Request); ok {
+		req.Session = session
+	}
+	return r.request, nil
+}
+
+func (s *FositeHedgedStore) GetAccessTokenSession(ctx context.Context, signature string, session interface{}) (fosite.Requester, error) {
+	return s.read("get_access_token_session", session, func(session interface{}) (fosite.Requester, error) {
+		return s.FositeStorer.GetAccessTokenSession(ctx, signature, session)
+	})
+}
+
+func (s *FositeHedgedStore) GetRefreshTokenSession(ctx context.Context, signature string, session interface{}) (fosite.Requester, error) {
+	return s.read("get_refresh_token_session", session, func(session interface{}) (fosite.Requester, error) {
+		return s.FositeStorer.GetRefreshTokenSession(ctx, signature, session)
+	})
+}
+
+func (s *FositeHedgedStore) GetAuthorizeCodeSession(ctx context.Context, code string, session interface{}) (fosite.Requester, error) {
+	return s.read("get_authorize_code_session", session, func(session interface{}) (fosite.Requester, error) {
+		return s.FositeStorer.GetAuthorizeCodeSession(ctx, code, session)
+	})
+}
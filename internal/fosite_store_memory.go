@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"encoding/json"
+
+	"github.com/go-errors/errors"
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/client"
 	"golang.org/x/net/context"
@@ -14,6 +17,21 @@ type FositeMemoryStore struct {
 	AccessTokens   map[string]fosite.Requester
 	Implicit       map[string]fosite.Requester
 	RefreshTokens  map[string]fosite.Requester
+
+	// grants maps the signatures of access and refresh tokens to the authorization grant they were issued by.
+	grants map[string]string
+
+	// rotated maps the signatures of refresh tokens which were exchanged by a refresh grant to their grant.
+	rotated map[string]string
+}
+
+func (s *FositeMemoryStore) setGrant(grant string, signatures ...string) {
+	if s.grants == nil {
+		s.grants = make(map[string]string)
+	}
+	for _, signature := range signatures {
+		s.grants[signature] = grant
+	}
 }
 
 func (s *FositeMemoryStore) CreateOpenIDConnectSession(_ context.Context, authorizeCode string, requester fosite.Requester) error {
@@ -67,6 +85,7 @@ func (s *FositeMemoryStore) GetAccessTokenSession(_ context.Context, signature s
 
 func (s *FositeMemoryStore) DeleteAccessTokenSession(_ context.Context, signature string) error {
 	delete(s.AccessTokens, signature)
+	delete(s.grants, signature)
 	return nil
 }
 
@@ -85,6 +104,7 @@ func (s *FositeMemoryStore) GetRefreshTokenSession(_ context.Context, signature
 
 func (s *FositeMemoryStore) DeleteRefreshTokenSession(_ context.Context, signature string) error {
 	delete(s.RefreshTokens, signature)
+	delete(s.grants, signature)
 	return nil
 }
 
@@ -102,10 +122,16 @@ func (s *FositeMemoryStore) PersistAuthorizeCodeGrantSession(ctx context.Context
 		return err
 	}
 
+	s.setGrant(refreshSignature, accessSignature, refreshSignature)
 	return nil
 }
 
 func (s *FositeMemoryStore) PersistRefreshTokenGrantSession(ctx context.Context, originalRefreshSignature, accessSignature, refreshSignature string, request fosite.Requester) error {
+	grant, ok := s.grants[originalRefreshSignature]
+	if !ok {
+		grant = refreshSignature
+	}
+
 	if err := s.DeleteRefreshTokenSession(ctx, originalRefreshSignature); err != nil {
 		return err
 	} else if err := s.CreateAccessTokenSession(ctx, accessSignature, request); err != nil {
@@ -114,5 +140,95 @@ func (s *FositeMemoryStore) PersistRefreshTokenGrantSession(ctx context.Context,
 		return err
 	}
 
+	s.setGrant(grant, accessSignature, refreshSignature)
+	if s.rotated == nil {
+		s.rotated = make(map[string]string)
+	}
+	s.rotated[originalRefreshSignature] = grant
 	return nil
 }
+
+func (s *FositeMemoryStore) IsRefreshTokenRotated(_ context.Context, signature string) (bool, error) {
+	_, ok := s.rotated[signature]
+	return ok, nil
+}
+
+func (s *FositeMemoryStore) RevokeRefreshToken(ctx context.Context, signature string) ([]string, error) {
+	grant, ok := s.grants[signature]
+	if !ok {
+		grant, ok = s.rotated[signature]
+	}
+
+	if err := s.DeleteRefreshTokenSession(ctx, signature); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	for id, g := range s.rotated {
+		if g == grant {
+			delete(s.rotated, id)
+		}
+	}
+
+	var revoked []string
+	for id, g := range s.grants {
+		if g != grant {
+			continue
+		}
+
+		if _, ok := s.AccessTokens[id]; ok {
+			delete(s.AccessTokens, id)
+			revoked = append(revoked, id)
+		}
+		delete(s.RefreshTokens, id)
+		delete(s.grants, id)
+	}
+	return revoked, nil
+}
+
+func (s *FositeMemoryStore) CountClientAccessTokens(_ context.Context, clientID string) (int, error) {
+	var n int
+	for _, tokens := range []map[string]fosite.Requester{s.AccessTokens, s.Implicit} {
+		for _, request := range tokens {
+			if request.GetClient().GetID() == clientID {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+func (s *FositeMemoryStore) RevokeClientTokens(_ context.Context, clientID string) ([]string, error) {
+	var revoked []string
+	for _, tokens := range []map[string]fosite.Requester{s.AccessTokens, s.Implicit, s.RefreshTokens} {
+		for signature, request := range tokens {
+			if request.GetClient().GetID() != clientID {
+				continue
+			}
+			delete(tokens, signature)
+			delete(s.grants, signature)
+			revoked = append(revoked, signature)
+		}
+	}
+	return revoked, nil
+}
+
+func (s *FositeMemoryStore) RevokeSubjectTokens(_ context.Context, subject, clientID string) ([]string, error) {
+	var revoked []string
+	for _, tokens := range []map[string]fosite.Requester{s.AccessTokens, s.Implicit, s.RefreshTokens} {
+		for signature, request := range tokens {
+			if request.GetClient().GetID() != clientID {
+				continue
+			} else if session, err := json.Marshal(request.GetSession()); err != nil {
+				return nil, errors.New(err)
+			} else if sessionSubject(session) != subject {
+				continue
+			}
+			delete(tokens, signature)
+			delete(s.grants, signature)
+			revoked = append(revoked, signature)
+		}
+	}
+	return revoked, nil
+}
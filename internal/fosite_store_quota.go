@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/quota"
+	"golang.org/x/net/context"
+)
+
+// FositeQuotaStore refuses to store an access token once its client holds as many access tokens as Quota allows.
+// The tokens are counted by Counter, usually the store itself, and the token is stored while holding the quota's
+// lease of the client, see quota.Quota.Admit. Refreshing a token replaces it and is not limited.
+type FositeQuotaStore struct {
+	pkg.FositeStorer
+
+	Counter pkg.TokenCounter
+	Quota   *quota.Quota
+}
+
+func (s *FositeQuotaStore) CreateAccessTokenSession(ctx context.Context, signature string, req fosite.Requester) error {
+	return s.admit(ctx, req, func() error {
+		return s.FositeStorer.CreateAccessTokenSession(ctx, signature, req)
+	})
+}
+
+func (s *FositeQuotaStore) CreateImplicitAccessTokenSession(ctx context.Context, signature string, req fosite.Requester) error {
+	return s.admit(ctx, req, func() error {
+		return s.FositeStorer.CreateImplicitAccessTokenSession(ctx, signature, req)
+	})
+}
+
+func (s *FositeQuotaStore) admit(ctx context.Context, req fosite.Requester, create func() error) error {
+	clientID := req.GetClient().GetID()
+	return s.Quota.Admit(clientID, func() (int, error) {
+		return s.Counter.CountClientAccessTokens(ctx, clientID)
+	}, create)
+}
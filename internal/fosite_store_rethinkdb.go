@@ -9,6 +9,7 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/logger"
 	"github.com/ory-am/hydra/pkg"
 	"golang.org/x/net/context"
 	r "gopkg.in/dancannon/gorethink.v2"
@@ -44,12 +45,31 @@ type RdbSchema struct {
 	GrantedScopes fosite.Arguments      `json:"grantedScopes" gorethink:"grantedScopes"`
 	Form          url.Values            `json:"form" gorethink:"form"`
 	Session       json.RawMessage       `json:"session" gorethink:"session"`
+
+	// GrantID identifies the authorization grant which issued an access or refresh token.
+	GrantID string `json:"grantId,omitempty" gorethink:"grantId,omitempty"`
+
+	// Rotated is true for refresh tokens which were exchanged by a refresh grant. They are kept until their grant
+	// is revoked so that a reuse can be detected.
+	Rotated bool `json:"rotated,omitempty" gorethink:"rotated,omitempty"`
+}
+
+// sessionSubject returns the subject of a session which was encoded as JSON, see oauth2.Session. Sessions which
+// can not be decoded have no subject.
+func sessionSubject(data []byte) string {
+	var session struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return ""
+	}
+	return session.Subject
 }
 
 func requestFromRDB(s *RdbSchema, proto interface{}) (*fosite.Request, error) {
 	if proto != nil {
 		if err := json.Unmarshal(s.Session, proto); err != nil {
-			pkg.LogError(errors.New(err))
+			logger.LogError(errors.New(err))
 			return nil, errors.New(err)
 		}
 	}
@@ -65,24 +85,28 @@ func requestFromRDB(s *RdbSchema, proto interface{}) (*fosite.Request, error) {
 }
 
 func (m *FositeRehinkDBStore) ColdStart() error {
-	if err := m.AccessTokens.coldStart(m.Session, m.RWMutex, m.AccessTokensTable); err != nil {
+	if err := m.AccessTokens.coldStart(m.Session, &m.RWMutex, m.AccessTokensTable); err != nil {
 		return err
-	} else if err := m.AuthorizeCodes.coldStart(m.Session, m.RWMutex, m.AuthorizeCodesTable); err != nil {
+	} else if err := m.AuthorizeCodes.coldStart(m.Session, &m.RWMutex, m.AuthorizeCodesTable); err != nil {
 		return err
-	} else if err := m.IDSessions.coldStart(m.Session, m.RWMutex, m.IDSessionsTable); err != nil {
+	} else if err := m.IDSessions.coldStart(m.Session, &m.RWMutex, m.IDSessionsTable); err != nil {
 		return err
-	} else if err := m.Implicit.coldStart(m.Session, m.RWMutex, m.ImplicitTable); err != nil {
+	} else if err := m.Implicit.coldStart(m.Session, &m.RWMutex, m.ImplicitTable); err != nil {
 		return err
-	} else if err := m.RefreshTokens.coldStart(m.Session, m.RWMutex, m.RefreshTokensTable); err != nil {
+	} else if err := m.RefreshTokens.coldStart(m.Session, &m.RWMutex, m.RefreshTokensTable); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (s *FositeRehinkDBStore) publishInsert(table r.Term, id string, requester fosite.Requester) error {
+	return s.publishGrantInsert(table, id, "", requester)
+}
+
+func (s *FositeRehinkDBStore) publishGrantInsert(table r.Term, id, grantID string, requester fosite.Requester) error {
 	sess, err := json.Marshal(requester.GetSession())
 	if err != nil {
-		pkg.LogError(errors.New(err))
+		logger.LogError(errors.New(err))
 		return errors.New(err)
 	}
 
@@ -94,6 +118,7 @@ func (s *FositeRehinkDBStore) publishInsert(table r.Term, id string, requester f
 		GrantedScopes: requester.GetGrantedScopes(),
 		Form:          requester.GetRequestForm(),
 		Session:       sess,
+		GrantID:       grantID,
 	}).RunWrite(s.Session); err != nil {
 		return errors.New(err)
 	}
@@ -111,7 +136,9 @@ func (s *FositeRehinkDBStore) CreateOpenIDConnectSession(_ context.Context, auth
 }
 
 func (s *FositeRehinkDBStore) GetOpenIDConnectSession(_ context.Context, authorizeCode string, requester fosite.Requester) (fosite.Requester, error) {
+	s.RLock()
 	cl, ok := s.IDSessions[authorizeCode]
+	s.RUnlock()
 	if !ok {
 		return nil, fosite.ErrNotFound
 	}
@@ -127,7 +154,9 @@ func (s *FositeRehinkDBStore) CreateAuthorizeCodeSession(_ context.Context, code
 }
 
 func (s *FositeRehinkDBStore) GetAuthorizeCodeSession(_ context.Context, code string, sess interface{}) (fosite.Requester, error) {
+	s.RLock()
 	rel, ok := s.AuthorizeCodes[code]
+	s.RUnlock()
 	if !ok {
 		return nil, fosite.ErrNotFound
 	}
@@ -144,7 +173,9 @@ func (s *FositeRehinkDBStore) CreateAccessTokenSession(_ context.Context, signat
 }
 
 func (s *FositeRehinkDBStore) GetAccessTokenSession(_ context.Context, signature string, sess interface{}) (fosite.Requester, error) {
+	s.RLock()
 	rel, ok := s.AccessTokens[signature]
+	s.RUnlock()
 	if !ok {
 		return nil, fosite.ErrNotFound
 	}
@@ -161,8 +192,10 @@ func (s *FositeRehinkDBStore) CreateRefreshTokenSession(_ context.Context, signa
 }
 
 func (s *FositeRehinkDBStore) GetRefreshTokenSession(_ context.Context, signature string, sess interface{}) (fosite.Requester, error) {
+	s.RLock()
 	rel, ok := s.RefreshTokens[signature]
-	if !ok {
+	s.RUnlock()
+	if !ok || rel.Rotated {
 		return nil, fosite.ErrNotFound
 	}
 
@@ -180,9 +213,9 @@ func (s *FositeRehinkDBStore) CreateImplicitAccessTokenSession(_ context.Context
 func (s *FositeRehinkDBStore) PersistAuthorizeCodeGrantSession(ctx context.Context, authorizeCode, accessSignature, refreshSignature string, request fosite.Requester) error {
 	if err := s.DeleteAuthorizeCodeSession(ctx, authorizeCode); err != nil {
 		return err
-	} else if err := s.CreateAccessTokenSession(ctx, accessSignature, request); err != nil {
+	} else if err := s.publishGrantInsert(s.AccessTokensTable, accessSignature, refreshSignature, request); err != nil {
 		return err
-	} else if err := s.CreateRefreshTokenSession(ctx, refreshSignature, request); err != nil {
+	} else if err := s.publishGrantInsert(s.RefreshTokensTable, refreshSignature, refreshSignature, request); err != nil {
 		return err
 	}
 
@@ -190,76 +223,243 @@ func (s *FositeRehinkDBStore) PersistAuthorizeCodeGrantSession(ctx context.Conte
 }
 
 func (s *FositeRehinkDBStore) PersistRefreshTokenGrantSession(ctx context.Context, originalRefreshSignature, accessSignature, refreshSignature string, request fosite.Requester) error {
-	if err := s.DeleteRefreshTokenSession(ctx, originalRefreshSignature); err != nil {
+	grant := refreshSignature
+	s.RLock()
+	if rel, ok := s.RefreshTokens[originalRefreshSignature]; ok && rel.GrantID != "" {
+		grant = rel.GrantID
+	}
+	s.RUnlock()
+
+	if err := s.rotateRefreshToken(originalRefreshSignature, grant); err != nil {
 		return err
-	} else if err := s.CreateAccessTokenSession(ctx, accessSignature, request); err != nil {
+	} else if err := s.publishGrantInsert(s.AccessTokensTable, accessSignature, grant, request); err != nil {
 		return err
-	} else if err := s.CreateRefreshTokenSession(ctx, refreshSignature, request); err != nil {
+	} else if err := s.publishGrantInsert(s.RefreshTokensTable, refreshSignature, grant, request); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// rotateRefreshToken marks a refresh token as rotated. The local cache is updated right away, the changefeed
+// might deliver the change only after the client retried the rotated token.
+func (s *FositeRehinkDBStore) rotateRefreshToken(signature, grant string) error {
+	if _, err := s.RefreshTokensTable.Get(signature).Update(map[string]interface{}{
+		"rotated": true,
+		"grantId": grant,
+	}).RunWrite(s.Session); err != nil {
+		return errors.New(err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if rel, ok := s.RefreshTokens[signature]; ok {
+		rotated := *rel
+		rotated.Rotated = true
+		rotated.GrantID = grant
+		s.RefreshTokens[signature] = &rotated
+	}
+	return nil
+}
+
+func (s *FositeRehinkDBStore) IsRefreshTokenRotated(_ context.Context, signature string) (bool, error) {
+	s.RLock()
+	defer s.RUnlock()
+	rel, ok := s.RefreshTokens[signature]
+	return ok && rel.Rotated, nil
+}
+
+func (s *FositeRehinkDBStore) RevokeRefreshToken(ctx context.Context, signature string) ([]string, error) {
+	var grant string
+	var revoked []string
+	s.RLock()
+	if rel, ok := s.RefreshTokens[signature]; ok {
+		grant = rel.GrantID
+	}
+	for id, item := range s.AccessTokens {
+		if grant != "" && item.GrantID == grant {
+			revoked = append(revoked, id)
+		}
+	}
+	s.RUnlock()
+
+	if err := s.DeleteRefreshTokenSession(ctx, signature); err != nil {
+		return nil, err
+	} else if grant == "" {
+		return nil, nil
+	}
+
+	for _, table := range []r.Term{s.AccessTokensTable, s.RefreshTokensTable} {
+		if _, err := table.Filter(r.Row.Field("grantId").Eq(grant)).Delete().RunWrite(s.Session); err != nil {
+			return nil, errors.New(err)
+		}
+	}
+	return revoked, nil
+}
+
+// CountClientAccessTokens counts in the tables rather than the cache, which lags behind the writes of other instances.
+func (s *FositeRehinkDBStore) CountClientAccessTokens(_ context.Context, clientID string) (int, error) {
+	var total int
+	for _, table := range []r.Term{s.AccessTokensTable, s.ImplicitTable} {
+		var n int
+		if res, err := table.Filter(r.Row.Field("client").Field("id").Eq(clientID)).Count().Run(s.Session); err != nil {
+			return 0, errors.New(err)
+		} else if err := res.One(&n); err != nil {
+			return 0, errors.New(err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (s *FositeRehinkDBStore) RevokeClientTokens(_ context.Context, clientID string) ([]string, error) {
+	var revoked []string
+	s.RLock()
+	for _, items := range []RDBItems{s.AccessTokens, s.Implicit, s.RefreshTokens} {
+		for id, item := range items {
+			if item.Client != nil && item.Client.ID == clientID {
+				revoked = append(revoked, id)
+			}
+		}
+	}
+	s.RUnlock()
+
+	for _, table := range []r.Term{s.AccessTokensTable, s.ImplicitTable, s.RefreshTokensTable} {
+		if _, err := table.Filter(r.Row.Field("client").Field("id").Eq(clientID)).Delete().RunWrite(s.Session); err != nil {
+			return nil, errors.New(err)
+		}
+	}
+	return revoked, nil
+}
+
+func (s *FositeRehinkDBStore) RevokeSubjectTokens(_ context.Context, subject, clientID string) ([]string, error) {
+	var revoked []string
+	tables := []r.Term{s.AccessTokensTable, s.ImplicitTable, s.RefreshTokensTable}
+	ids := make([][]interface{}, len(tables))
+	s.RLock()
+	for k, items := range []RDBItems{s.AccessTokens, s.Implicit, s.RefreshTokens} {
+		for id, item := range items {
+			if item.Client != nil && item.Client.ID == clientID && sessionSubject(item.Session) == subject {
+				ids[k] = append(ids[k], id)
+				revoked = append(revoked, id)
+			}
+		}
+	}
+	s.RUnlock()
+
+	for k, table := range tables {
+		if len(ids[k]) == 0 {
+			continue
+		}
+		if _, err := table.GetAll(ids[k]...).Delete().RunWrite(s.Session); err != nil {
+			return nil, errors.New(err)
+		}
+	}
+	return revoked, nil
+}
+
+// FlushExpiredTokens deletes a batch of tokens of the kind which were requested before requestedBefore. The
+// deleted tokens are removed from the cache right away, so that the next batch does not select them again.
+func (s *FositeRehinkDBStore) FlushExpiredTokens(kind string, requestedBefore time.Time, limit int) (int, error) {
+	var items RDBItems
+	var table r.Term
+	switch kind {
+	case pkg.TokenKindAuthorizeCode:
+		items, table = s.AuthorizeCodes, s.AuthorizeCodesTable
+	case pkg.TokenKindOpenIDConnectSession:
+		items, table = s.IDSessions, s.IDSessionsTable
+	case pkg.TokenKindAccessToken:
+		items, table = s.AccessTokens, s.AccessTokensTable
+	case pkg.TokenKindImplicitAccessToken:
+		items, table = s.Implicit, s.ImplicitTable
+	case pkg.TokenKindRefreshToken:
+		items, table = s.RefreshTokens, s.RefreshTokensTable
+	default:
+		return 0, errors.Errorf("Unknown token kind %s", kind)
+	}
+
+	var ids []interface{}
+	s.RLock()
+	for id, item := range items {
+		if len(ids) >= limit {
+			break
+		} else if item.RequestedAt.Before(requestedBefore) {
+			ids = append(ids, id)
+		}
+	}
+	s.RUnlock()
+
+	if len(ids) == 0 {
+		return 0, nil
+	} else if _, err := table.GetAll(ids...).Delete().RunWrite(s.Session); err != nil {
+		return 0, errors.New(err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, id := range ids {
+		delete(items, id.(string))
+	}
+	return len(ids), nil
+}
+
 func (m *FositeRehinkDBStore) Watch(ctx context.Context) {
 	ctx.Done()
-	m.AccessTokens.watch(ctx, m.Session, m.RWMutex, m.AccessTokensTable)
-	m.AuthorizeCodes.watch(ctx, m.Session, m.RWMutex, m.AuthorizeCodesTable)
-	m.IDSessions.watch(ctx, m.Session, m.RWMutex, m.IDSessionsTable)
-	m.Implicit.watch(ctx, m.Session, m.RWMutex, m.ImplicitTable)
-	m.RefreshTokens.watch(ctx, m.Session, m.RWMutex, m.RefreshTokensTable)
+	m.AccessTokens.watch(ctx, m.Session, &m.RWMutex, m.AccessTokensTable)
+	m.AuthorizeCodes.watch(ctx, m.Session, &m.RWMutex, m.AuthorizeCodesTable)
+	m.IDSessions.watch(ctx, m.Session, &m.RWMutex, m.IDSessionsTable)
+	m.Implicit.watch(ctx, m.Session, &m.RWMutex, m.ImplicitTable)
+	m.RefreshTokens.watch(ctx, m.Session, &m.RWMutex, m.RefreshTokensTable)
 }
 
-func (items RDBItems) coldStart(sess *r.Session, lock sync.RWMutex, table r.Term) error {
+func (items RDBItems) coldStart(sess *r.Session, lock *sync.RWMutex, table r.Term) error {
 	rows, err := table.Run(sess)
 	if err != nil {
 		return errors.New(err)
 	}
+	defer rows.Close()
 
-	var item RdbSchema
-	lock.Lock()
-	defer lock.Unlock()
+	fetched := RDBItems{}
+	var item *RdbSchema
 	for rows.Next(&item) {
-		items[item.ID] = &item
+		fetched[item.ID] = item
+		item = nil
 	}
 
 	if rows.Err() != nil {
 		return errors.New(rows.Err())
 	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for id := range items {
+		delete(items, id)
+	}
+	for id, item := range fetched {
+		items[id] = item
+	}
 	return nil
 }
 
-func (items RDBItems) watch(ctx context.Context, sess *r.Session, lock sync.RWMutex, table r.Term) {
-	go pkg.Retry(time.Second*15, time.Minute, func() error {
-		changes, err := table.Changes().Run(sess)
-		if err != nil {
-			pkg.LogError(errors.New(err))
-			return errors.New(err)
-		}
-		defer changes.Close()
-
-		var update = map[string]*RdbSchema{}
-		for changes.Next(&update) {
-			newVal := update["new_val"]
-			oldVal := update["old_val"]
-			lock.Lock()
-			if newVal == nil && oldVal != nil {
-				delete(items, oldVal.ID)
-			} else if newVal != nil && oldVal != nil {
-				delete(items, oldVal.ID)
-				items[newVal.ID] = newVal
-			} else {
-				items[newVal.ID] = newVal
-			}
-			lock.Unlock()
-		}
-
-		if changes.Err() != nil {
-			err = errors.New(changes.Err())
-			pkg.LogError(err)
+func (items RDBItems) watch(ctx context.Context, sess *r.Session, lock *sync.RWMutex, table r.Term) {
+	feed := &pkg.ChangeFeed{Session: sess, Table: table}
+	feed.Subscribe(func() error {
+		return items.coldStart(sess, lock, table)
+	}, func(change *pkg.Change) error {
+		var newVal, oldVal *RdbSchema
+		if err := change.Decode(&oldVal, &newVal); err != nil {
 			return err
 		}
 
+		lock.Lock()
+		defer lock.Unlock()
+		if oldVal != nil {
+			delete(items, oldVal.ID)
+		}
+		if newVal != nil {
+			items[newVal.ID] = newVal
+		}
 		return nil
 	})
+	feed.Start(ctx)
 }
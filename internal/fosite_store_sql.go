@@ -0,0 +1,538 @@
+package internal
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+const (
+	sqlTableAuthorizeCodes = "hydra_oauth2_authorize_code"
+	sqlTableIDSessions     = "hydra_oauth2_id_sessions"
+	sqlTableAccessTokens   = "hydra_oauth2_access_token"
+	sqlTableImplicit       = "hydra_oauth2_implicit"
+	sqlTableRefreshTokens  = "hydra_oauth2_refresh_token"
+)
+
+var sqlTables = []string{sqlTableAuthorizeCodes, sqlTableIDSessions, sqlTableAccessTokens, sqlTableImplicit, sqlTableRefreshTokens}
+
+// sqlKindTables maps token kinds to the table they are stored in.
+var sqlKindTables = map[string]string{
+	pkg.TokenKindAuthorizeCode:        sqlTableAuthorizeCodes,
+	pkg.TokenKindOpenIDConnectSession: sqlTableIDSessions,
+	pkg.TokenKindAccessToken:          sqlTableAccessTokens,
+	pkg.TokenKindImplicitAccessToken:  sqlTableImplicit,
+	pkg.TokenKindRefreshToken:         sqlTableRefreshTokens,
+}
+
+// sqlSchemas create one table per kind of token. The tables are indexed by client and grant, which revocation
+// looks tokens up by, and by the time they were requested at, which the cleanup deletes expired tokens by.
+var sqlSchemas = map[string][]string{
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS %[1]s (
+	signature      varchar(255) NOT NULL PRIMARY KEY,
+	requested_at   timestamp NOT NULL,
+	client_id      varchar(255) NOT NULL,
+	client_data    text NOT NULL,
+	scopes         text NOT NULL,
+	granted_scopes text NOT NULL,
+	form_data      text NOT NULL,
+	session_data   text NOT NULL,
+	grant_id       varchar(255) NOT NULL DEFAULT '',
+	rotated        bool NOT NULL DEFAULT false
+)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_client_idx ON %[1]s (client_id)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_grant_idx ON %[1]s (grant_id)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_requested_at_idx ON %[1]s (requested_at)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS %[1]s (
+	signature      varchar(255) NOT NULL PRIMARY KEY,
+	requested_at   datetime NOT NULL,
+	client_id      varchar(255) NOT NULL,
+	client_data    text NOT NULL,
+	scopes         text NOT NULL,
+	granted_scopes text NOT NULL,
+	form_data      text NOT NULL,
+	session_data   text NOT NULL,
+	grant_id       varchar(255) NOT NULL DEFAULT '',
+	rotated        bool NOT NULL DEFAULT false,
+	INDEX %[1]s_client_idx (client_id),
+	INDEX %[1]s_grant_idx (grant_id),
+	INDEX %[1]s_requested_at_idx (requested_at)
+)`,
+	},
+}
+
+// migrations are the versions of the token tables. The first version creates the tables only if they do not
+// exist, so that databases which were set up before migrations were versioned are adopted as they are.
+var migrations = []*pkg.Migration{
+	{
+		ID:   "1",
+		Up:   perTable(sqlSchemas),
+		Down: perTable(map[string][]string{"postgres": {`DROP TABLE IF EXISTS %[1]s`}, "mysql": {`DROP TABLE IF EXISTS %[1]s`}}),
+	},
+}
+
+// perTable formats every statement once for every token table.
+func perTable(schemas map[string][]string) map[string][]string {
+	result := map[string][]string{}
+	for driver, statements := range schemas {
+		for _, table := range sqlTables {
+			for _, statement := range statements {
+				result[driver] = append(result[driver], fmt.Sprintf(statement, table))
+			}
+		}
+	}
+	return result
+}
+
+// FositeSQLStore stores OAuth2 sessions in PostgreSQL or MySQL. MySQL connections must be opened with
+// parseTime=true. Unlike FositeRehinkDBStore it does not cache sessions, every lookup is answered by the database.
+type FositeSQLStore struct {
+	DB *sql.DB
+
+	// Driver is the name of the database/sql driver, either "postgres" or "mysql".
+	Driver string
+
+	client.Manager
+
+	// AuthorizeCodeLifespan is how long authorize codes and the OpenID Connect sessions they belong to are kept.
+	AuthorizeCodeLifespan time.Duration
+
+	// AccessTokenLifespan is how long access tokens are kept.
+	AccessTokenLifespan time.Duration
+
+	// RefreshTokenLifespan is how long refresh tokens are kept. They are kept until they are revoked if zero.
+	RefreshTokenLifespan time.Duration
+}
+
+// sqlQuerier is implemented by *sql.DB and *sql.Tx.
+type sqlQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Migrator returns the migrator of the token tables.
+func (s *FositeSQLStore) Migrator() *pkg.Migrator {
+	return &pkg.Migrator{DB: s.DB, Driver: s.Driver, Module: "oauth2", Migrations: migrations}
+}
+
+// CreateSchemas applies all migrations of the token tables which were not applied yet.
+func (s *FositeSQLStore) CreateSchemas() error {
+	_, err := s.Migrator().Up()
+	return err
+}
+
+func (s *FositeSQLStore) CreateOpenIDConnectSession(_ context.Context, authorizeCode string, requester fosite.Requester) error {
+	return s.insert(s.DB, sqlTableIDSessions, authorizeCode, "", requester)
+}
+
+func (s *FositeSQLStore) GetOpenIDConnectSession(_ context.Context, authorizeCode string, requester fosite.Requester) (fosite.Requester, error) {
+	return s.get(sqlTableIDSessions, authorizeCode, requester.GetSession())
+}
+
+func (s *FositeSQLStore) DeleteOpenIDConnectSession(_ context.Context, authorizeCode string) error {
+	return s.delete(s.DB, sqlTableIDSessions, authorizeCode)
+}
+
+func (s *FositeSQLStore) CreateAuthorizeCodeSession(_ context.Context, code string, requester fosite.Requester) error {
+	return s.insert(s.DB, sqlTableAuthorizeCodes, code, "", requester)
+}
+
+func (s *FositeSQLStore) GetAuthorizeCodeSession(_ context.Context, code string, sess interface{}) (fosite.Requester, error) {
+	return s.get(sqlTableAuthorizeCodes, code, sess)
+}
+
+func (s *FositeSQLStore) DeleteAuthorizeCodeSession(_ context.Context, code string) error {
+	return s.delete(s.DB, sqlTableAuthorizeCodes, code)
+}
+
+func (s *FositeSQLStore) CreateAccessTokenSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.insert(s.DB, sqlTableAccessTokens, signature, "", requester)
+}
+
+func (s *FositeSQLStore) GetAccessTokenSession(_ context.Context, signature string, sess interface{}) (fosite.Requester, error) {
+	return s.get(sqlTableAccessTokens, signature, sess)
+}
+
+func (s *FositeSQLStore) DeleteAccessTokenSession(_ context.Context, signature string) error {
+	return s.delete(s.DB, sqlTableAccessTokens, signature)
+}
+
+func (s *FositeSQLStore) CreateRefreshTokenSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.insert(s.DB, sqlTableRefreshTokens, signature, "", requester)
+}
+
+func (s *FositeSQLStore) GetRefreshTokenSession(_ context.Context, signature string, sess interface{}) (fosite.Requester, error) {
+	return s.get(sqlTableRefreshTokens, signature, sess)
+}
+
+func (s *FositeSQLStore) DeleteRefreshTokenSession(_ context.Context, signature string) error {
+	return s.delete(s.DB, sqlTableRefreshTokens, signature)
+}
+
+func (s *FositeSQLStore) CreateImplicitAccessTokenSession(_ context.Context, code string, requester fosite.Requester) error {
+	return s.insert(s.DB, sqlTableImplicit, code, "", requester)
+}
+
+func (s *FositeSQLStore) PersistAuthorizeCodeGrantSession(ctx context.Context, authorizeCode, accessSignature, refreshSignature string, request fosite.Requester) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		if err := s.delete(tx, sqlTableAuthorizeCodes, authorizeCode); err != nil {
+			return err
+		} else if err := s.insert(tx, sqlTableAccessTokens, accessSignature, refreshSignature, request); err != nil {
+			return err
+		}
+		return s.insert(tx, sqlTableRefreshTokens, refreshSignature, refreshSignature, request)
+	})
+}
+
+func (s *FositeSQLStore) PersistRefreshTokenGrantSession(ctx context.Context, originalRefreshSignature, accessSignature, refreshSignature string, request fosite.Requester) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		grant, err := s.grant(tx, originalRefreshSignature)
+		if err != nil {
+			return err
+		} else if grant == "" {
+			grant = refreshSignature
+		}
+
+		// The rotated refresh token is kept until its grant is revoked so that a reuse can be detected.
+		if _, err := tx.Exec(s.rebind(fmt.Sprintf("UPDATE %s SET rotated = ?, grant_id = ? WHERE signature = ?", sqlTableRefreshTokens)),
+			true, grant, originalRefreshSignature); err != nil {
+			return errors.New(err)
+		} else if err := s.insert(tx, sqlTableAccessTokens, accessSignature, grant, request); err != nil {
+			return err
+		}
+		return s.insert(tx, sqlTableRefreshTokens, refreshSignature, grant, request)
+	})
+}
+
+func (s *FositeSQLStore) IsRefreshTokenRotated(_ context.Context, signature string) (bool, error) {
+	var rotated bool
+	if err := s.DB.QueryRow(s.rebind(fmt.Sprintf("SELECT rotated FROM %s WHERE signature = ?", sqlTableRefreshTokens)), signature).Scan(&rotated); err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, errors.New(err)
+	}
+	return rotated, nil
+}
+
+func (s *FositeSQLStore) RevokeRefreshToken(ctx context.Context, signature string) ([]string, error) {
+	var revoked []string
+	err := s.transaction(func(tx *sql.Tx) error {
+		grant, err := s.grant(tx, signature)
+		if err != nil {
+			return err
+		} else if err := s.delete(tx, sqlTableRefreshTokens, signature); err != nil {
+			return err
+		} else if grant == "" {
+			return nil
+		}
+
+		if revoked, err = s.signatures(tx, sqlTableAccessTokens, "grant_id", grant); err != nil {
+			return err
+		}
+		for _, table := range []string{sqlTableAccessTokens, sqlTableRefreshTokens} {
+			if _, err := tx.Exec(s.rebind(fmt.Sprintf("DELETE FROM %s WHERE grant_id = ?", table)), grant); err != nil {
+				return errors.New(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+func (s *FositeSQLStore) CountClientAccessTokens(_ context.Context, clientID string) (int, error) {
+	var total int
+	for _, table := range []string{sqlTableAccessTokens, sqlTableImplicit} {
+		var n int
+		if err := s.DB.QueryRow(s.rebind(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE client_id = ?", table)), clientID).Scan(&n); err != nil {
+			return 0, errors.New(err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (s *FositeSQLStore) RevokeClientTokens(_ context.Context, clientID string) ([]string, error) {
+	var revoked []string
+	err := s.transaction(func(tx *sql.Tx) error {
+		for _, table := range []string{sqlTableAccessTokens, sqlTableImplicit, sqlTableRefreshTokens} {
+			signatures, err := s.signatures(tx, table, "client_id", clientID)
+			if err != nil {
+				return err
+			}
+			revoked = append(revoked, signatures...)
+
+			if _, err := tx.Exec(s.rebind(fmt.Sprintf("DELETE FROM %s WHERE client_id = ?", table)), clientID); err != nil {
+				return errors.New(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+// RevokeSubjectTokens looks the tokens up by client, the subject is only known to the session.
+func (s *FositeSQLStore) RevokeSubjectTokens(_ context.Context, subject, clientID string) ([]string, error) {
+	var revoked []string
+	err := s.transaction(func(tx *sql.Tx) error {
+		for _, table := range []string{sqlTableAccessTokens, sqlTableImplicit, sqlTableRefreshTokens} {
+			rows, err := tx.Query(s.rebind(fmt.Sprintf("SELECT signature, session_data FROM %s WHERE client_id = ?", table)), clientID)
+			if err != nil {
+				return errors.New(err)
+			}
+
+			var signatures []string
+			for rows.Next() {
+				var signature, session string
+				if err := rows.Scan(&signature, &session); err != nil {
+					rows.Close()
+					return errors.New(err)
+				} else if sessionSubject([]byte(session)) == subject {
+					signatures = append(signatures, signature)
+				}
+			}
+			if err := rows.Err(); err != nil {
+				rows.Close()
+				return errors.New(err)
+			}
+			rows.Close()
+
+			for _, signature := range signatures {
+				if err := s.delete(tx, table, signature); err != nil {
+					return err
+				}
+			}
+			revoked = append(revoked, signatures...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+// DeleteExpired deletes the sessions which were requested longer than their lifespan ago. Sessions of a kind
+// whose lifespan is zero are never deleted.
+func (s *FositeSQLStore) DeleteExpired(now time.Time) error {
+	for table, lifespan := range map[string]time.Duration{
+		sqlTableAuthorizeCodes: s.AuthorizeCodeLifespan,
+		sqlTableIDSessions:     s.AuthorizeCodeLifespan,
+		sqlTableAccessTokens:   s.AccessTokenLifespan,
+		sqlTableImplicit:       s.AccessTokenLifespan,
+		sqlTableRefreshTokens:  s.RefreshTokenLifespan,
+	} {
+		if lifespan <= 0 {
+			continue
+		}
+
+		if _, err := s.DB.Exec(s.rebind(fmt.Sprintf("DELETE FROM %s WHERE requested_at < ?", table)), now.Add(-lifespan).UTC()); err != nil {
+			return errors.New(err)
+		}
+	}
+	return nil
+}
+
+// FlushExpiredTokens deletes a batch of tokens of the kind which were requested before requestedBefore.
+func (s *FositeSQLStore) FlushExpiredTokens(kind string, requestedBefore time.Time, limit int) (int, error) {
+	table, ok := sqlKindTables[kind]
+	if !ok {
+		return 0, errors.Errorf("Unknown token kind %s", kind)
+	}
+
+	// PostgreSQL does not support DELETE ... LIMIT, MySQL does not support LIMIT in IN subqueries.
+	query := fmt.Sprintf("DELETE FROM %s WHERE requested_at < ? LIMIT ?", table)
+	if s.Driver == "postgres" {
+		query = fmt.Sprintf("DELETE FROM %[1]s WHERE signature IN (SELECT signature FROM %[1]s WHERE requested_at < ? LIMIT ?)", table)
+	}
+
+	res, err := s.DB.Exec(s.rebind(query), requestedBefore.UTC(), limit)
+	if err != nil {
+		return 0, errors.New(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.New(err)
+	}
+	return int(n), nil
+}
+
+// Cleanup periodically deletes expired sessions until ctx is done. Only the leader deletes, unless leader is nil.
+func (s *FositeSQLStore) Cleanup(ctx context.Context, leader pkg.Leader, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if leader != nil && !leader.IsLeader() {
+					continue
+				}
+				if err := s.DeleteExpired(time.Now()); err != nil {
+					logger.LogError(err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *FositeSQLStore) insert(q sqlQuerier, table, signature, grant string, requester fosite.Requester) error {
+	cl, err := json.Marshal(requester.GetClient())
+	if err != nil {
+		return errors.New(err)
+	}
+	scopes, err := json.Marshal(requester.GetScopes())
+	if err != nil {
+		return errors.New(err)
+	}
+	grantedScopes, err := json.Marshal(requester.GetGrantedScopes())
+	if err != nil {
+		return errors.New(err)
+	}
+	form, err := json.Marshal(requester.GetRequestForm())
+	if err != nil {
+		return errors.New(err)
+	}
+	sess, err := json.Marshal(requester.GetSession())
+	if err != nil {
+		return errors.New(err)
+	}
+
+	if _, err := q.Exec(s.rebind(fmt.Sprintf("INSERT INTO %s (signature, requested_at, client_id, client_data, scopes, granted_scopes, form_data, session_data, grant_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", table)),
+		signature,
+		requester.GetRequestedAt().UTC(),
+		requester.GetClient().GetID(),
+		string(cl),
+		string(scopes),
+		string(grantedScopes),
+		string(form),
+		string(sess),
+		grant,
+	); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// get returns the session of a token. Rotated refresh tokens are not found.
+func (s *FositeSQLStore) get(table, signature string, sess interface{}) (fosite.Requester, error) {
+	var cl, scopes, grantedScopes, form, session string
+	var rotated bool
+	var requestedAt time.Time
+	if err := s.DB.QueryRow(s.rebind(fmt.Sprintf("SELECT requested_at, client_data, scopes, granted_scopes, form_data, session_data, rotated FROM %s WHERE signature = ?", table)), signature).
+		Scan(&requestedAt, &cl, &scopes, &grantedScopes, &form, &session, &rotated); err == sql.ErrNoRows {
+		return nil, fosite.ErrNotFound
+	} else if err != nil {
+		return nil, errors.New(err)
+	} else if rotated {
+		return nil, fosite.ErrNotFound
+	}
+
+	item := &RdbSchema{
+		ID:          signature,
+		RequestedAt: requestedAt,
+		Client:      new(fosite.DefaultClient),
+		Form:        url.Values{},
+		Session:     json.RawMessage(session),
+	}
+	for _, field := range []struct {
+		data string
+		v    interface{}
+	}{
+		{data: cl, v: item.Client},
+		{data: scopes, v: &item.Scopes},
+		{data: grantedScopes, v: &item.GrantedScopes},
+		{data: form, v: &item.Form},
+	} {
+		if err := json.Unmarshal([]byte(field.data), field.v); err != nil {
+			return nil, errors.New(err)
+		}
+	}
+	return requestFromRDB(item, sess)
+}
+
+func (s *FositeSQLStore) delete(q sqlQuerier, table, signature string) error {
+	if _, err := q.Exec(s.rebind(fmt.Sprintf("DELETE FROM %s WHERE signature = ?", table)), signature); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// grant returns the grant a refresh token was issued by, or an empty string if it is not known.
+func (s *FositeSQLStore) grant(q sqlQuerier, signature string) (string, error) {
+	var grant string
+	if err := q.QueryRow(s.rebind(fmt.Sprintf("SELECT grant_id FROM %s WHERE signature = ?", sqlTableRefreshTokens)), signature).Scan(&grant); err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", errors.New(err)
+	}
+	return grant, nil
+}
+
+func (s *FositeSQLStore) signatures(q sqlQuerier, table, column, value string) ([]string, error) {
+	rows, err := q.Query(s.rebind(fmt.Sprintf("SELECT signature FROM %s WHERE %s = ?", table, column)), value)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var signatures []string
+	for rows.Next() {
+		var signature string
+		if err := rows.Scan(&signature); err != nil {
+			return nil, errors.New(err)
+		}
+		signatures = append(signatures, signature)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	}
+	return signatures, nil
+}
+
+func (s *FositeSQLStore) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return errors.New(err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// SupportsHedgedReads returns true, every read is a query which may be sent twice.
+func (s *FositeSQLStore) SupportsHedgedReads() bool {
+	return true
+}
+
+func (s *FositeSQLStore) rebind(query string) string {
+	return pkg.RebindSQL(s.Driver, query)
+}
@@ -1,16 +1,21 @@
 package internal
 
 import (
+	"database/sql"
 	"net/url"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
 	c "github.com/ory-am/common/pkg"
 	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/integration"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/quota"
 	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	r "gopkg.in/dancannon/gorethink.v2"
 	"gopkg.in/ory-am/dockertest.v2"
@@ -83,8 +88,26 @@ func TestMain(m *testing.M) {
 	}
 	clientManagers["rethink"] = rethinkManager
 
+	dynamoStore := &FositeDynamoDBStore{DB: integration.ConnectToDynamoDB(), Table: "hydra"}
+	if err := dynamoStore.CreateSchemas(); err != nil {
+		logrus.Fatalf("Could not create dynamodb table: %s", err)
+	}
+	clientManagers["dynamodb"] = dynamoStore
+
+	for driver, db := range map[string]*sql.DB{
+		"postgres": integration.ConnectToPostgres(),
+		"mysql":    integration.ConnectToMySQL(),
+	} {
+		sqlStore := &FositeSQLStore{DB: db, Driver: driver}
+		if err := sqlStore.CreateSchemas(); err != nil {
+			logrus.Fatalf("Could not create %s schemas: %s", driver, err)
+		}
+		clientManagers[driver] = sqlStore
+	}
+
 	retCode := m.Run()
 	c.KillRemove()
+	integration.KillAll()
 	os.Exit(retCode)
 }
 
@@ -235,3 +258,188 @@ func TestCreateGetDeleteRefreshTokenSession(t *testing.T) {
 		pkg.AssertError(t, true, err, "%s", k)
 	}
 }
+
+func TestRevokeRefreshTokenCascades(t *testing.T) {
+	ctx := context.Background()
+	for k, m := range clientManagers {
+		code, access, refresh := uuid.New(), uuid.New(), uuid.New()
+		nextAccess, nextRefresh := uuid.New(), uuid.New()
+
+		err := m.CreateAuthorizeCodeSession(ctx, code, &defaultRequest)
+		pkg.AssertError(t, false, err, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		err = m.PersistAuthorizeCodeGrantSession(ctx, code, access, refresh, &defaultRequest)
+		pkg.AssertError(t, false, err, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		err = m.PersistRefreshTokenGrantSession(ctx, refresh, nextAccess, nextRefresh, &defaultRequest)
+		pkg.AssertError(t, false, err, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		revoked, err := m.RevokeRefreshToken(ctx, nextRefresh)
+		pkg.RequireError(t, false, err, "%s", k)
+		assert.Len(t, revoked, 2, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		for _, signature := range []string{access, nextAccess} {
+			_, err = m.GetAccessTokenSession(ctx, signature, &testSession{})
+			pkg.AssertError(t, true, err, "%s", k)
+		}
+		_, err = m.GetRefreshTokenSession(ctx, nextRefresh, &testSession{})
+		pkg.AssertError(t, true, err, "%s", k)
+	}
+}
+
+func TestRotatedRefreshTokenReuse(t *testing.T) {
+	ctx := context.Background()
+	for k, m := range clientManagers {
+		code, access, refresh := uuid.New(), uuid.New(), uuid.New()
+		nextAccess, nextRefresh := uuid.New(), uuid.New()
+
+		err := m.CreateAuthorizeCodeSession(ctx, code, &defaultRequest)
+		pkg.AssertError(t, false, err, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		err = m.PersistAuthorizeCodeGrantSession(ctx, code, access, refresh, &defaultRequest)
+		pkg.AssertError(t, false, err, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		err = m.PersistRefreshTokenGrantSession(ctx, refresh, nextAccess, nextRefresh, &defaultRequest)
+		pkg.AssertError(t, false, err, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		// The rotated refresh token can not be used anymore, but it is remembered.
+		_, err = m.GetRefreshTokenSession(ctx, refresh, &testSession{})
+		pkg.AssertError(t, true, err, "%s", k)
+		rotated, err := m.IsRefreshTokenRotated(ctx, refresh)
+		pkg.RequireError(t, false, err, "%s", k)
+		assert.True(t, rotated, "%s", k)
+		rotated, err = m.IsRefreshTokenRotated(ctx, nextRefresh)
+		pkg.RequireError(t, false, err, "%s", k)
+		assert.False(t, rotated, "%s", k)
+
+		// Reusing the rotated refresh token revokes the tokens the legitimate client holds as well.
+		revoked, err := m.RevokeRefreshToken(ctx, refresh)
+		pkg.RequireError(t, false, err, "%s", k)
+		assert.Len(t, revoked, 2, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		for _, signature := range []string{access, nextAccess} {
+			_, err = m.GetAccessTokenSession(ctx, signature, &testSession{})
+			pkg.AssertError(t, true, err, "%s", k)
+		}
+		_, err = m.GetRefreshTokenSession(ctx, nextRefresh, &testSession{})
+		pkg.AssertError(t, true, err, "%s", k)
+		rotated, err = m.IsRefreshTokenRotated(ctx, refresh)
+		pkg.RequireError(t, false, err, "%s", k)
+		assert.False(t, rotated, "%s", k)
+	}
+}
+
+type subjectSession struct {
+	Subject string `json:"sub" gorethink:"sub"`
+}
+
+func TestRevokeSubjectTokens(t *testing.T) {
+	ctx := context.Background()
+	for k, m := range clientManagers {
+		issue := func(subject, clientID string) (string, string) {
+			code, access, refresh := uuid.New(), uuid.New(), uuid.New()
+			request := &fosite.Request{
+				RequestedAt: time.Now().Round(time.Second),
+				Client:      &fosite.DefaultClient{ID: clientID},
+				Session:     &subjectSession{Subject: subject},
+			}
+
+			err := m.CreateAuthorizeCodeSession(ctx, code, request)
+			pkg.AssertError(t, false, err, "%s", k)
+			time.Sleep(100 * time.Millisecond)
+			err = m.PersistAuthorizeCodeGrantSession(ctx, code, access, refresh, request)
+			pkg.AssertError(t, false, err, "%s", k)
+			return access, refresh
+		}
+
+		client := uuid.New()
+		access, refresh := issue("peter", client)
+		otherSubjectAccess, otherSubjectRefresh := issue("alice", client)
+		otherClientAccess, otherClientRefresh := issue("peter", uuid.New())
+		time.Sleep(100 * time.Millisecond)
+
+		revoked, err := m.RevokeSubjectTokens(ctx, "peter", client)
+		pkg.RequireError(t, false, err, "%s", k)
+		assert.Contains(t, revoked, access, "%s", k)
+		assert.Contains(t, revoked, refresh, "%s", k)
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = m.GetAccessTokenSession(ctx, access, &subjectSession{})
+		pkg.AssertError(t, true, err, "%s", k)
+		_, err = m.GetRefreshTokenSession(ctx, refresh, &subjectSession{})
+		pkg.AssertError(t, true, err, "%s", k)
+		for _, signature := range []string{otherSubjectAccess, otherClientAccess} {
+			_, err = m.GetAccessTokenSession(ctx, signature, &subjectSession{})
+			pkg.AssertError(t, false, err, "%s", k)
+		}
+		for _, signature := range []string{otherSubjectRefresh, otherClientRefresh} {
+			_, err = m.GetRefreshTokenSession(ctx, signature, &subjectSession{})
+			pkg.AssertError(t, false, err, "%s", k)
+		}
+	}
+}
+
+func TestTokenQuota(t *testing.T) {
+	ctx := context.Background()
+	for k, m := range clientManagers {
+		store := &FositeQuotaStore{
+			FositeStorer: m,
+			Counter:      m.(pkg.TokenCounter),
+			Quota:        &quota.Quota{Name: "tokens", Limit: 2, Leases: &pkg.MemoryLeaseManager{}},
+		}
+		issue := func(clientID string) error {
+			return store.CreateAccessTokenSession(ctx, uuid.New(), &fosite.Request{
+				RequestedAt: time.Now().Round(time.Second),
+				Client:      &fosite.DefaultClient{ID: clientID},
+				Session:     &subjectSession{Subject: "peter"},
+			})
+		}
+
+		client := uuid.New()
+		pkg.AssertError(t, false, issue(client), "%s", k)
+		pkg.AssertError(t, false, issue(client), "%s", k)
+		assert.True(t, errors.Is(issue(client), quota.ErrQuotaExceeded), "%s", k)
+		pkg.AssertError(t, false, issue(uuid.New()), "%s", k)
+
+		n, err := m.(pkg.TokenCounter).CountClientAccessTokens(ctx, client)
+		pkg.RequireError(t, false, err, "%s", k)
+		assert.Equal(t, 2, n, "%s", k)
+	}
+}
+
+type expiringSession struct {
+	expiresAt map[string]time.Time
+}
+
+func (s *expiringSession) GetTokenExpiresAt(kind string) (time.Time, bool) {
+	expiresAt, ok := s.expiresAt[kind]
+	return expiresAt, ok
+}
+
+func TestDynamoDBTokenTTL(t *testing.T) {
+	store := &FositeDynamoDBStore{AccessTokenLifespan: time.Hour}
+	requestedAt := time.Now().Round(time.Second)
+	short := requestedAt.Add(5 * time.Minute)
+
+	for k, c := range []struct {
+		kind    string
+		session interface{}
+		ttl     int64
+	}{
+		{kind: pkg.TokenKindAccessToken, session: &expiringSession{}, ttl: requestedAt.Add(time.Hour).Unix()},
+		{kind: pkg.TokenKindAccessToken, session: &expiringSession{expiresAt: map[string]time.Time{pkg.TokenKindAccessToken: short}}, ttl: short.Unix()},
+		{kind: pkg.TokenKindRefreshToken, session: &expiringSession{}, ttl: 0},
+		{kind: pkg.TokenKindRefreshToken, session: &expiringSession{expiresAt: map[string]time.Time{pkg.TokenKindRefreshToken: short}}, ttl: short.Unix()},
+	} {
+		request := &fosite.Request{RequestedAt: requestedAt, Session: c.session}
+		assert.Equal(t, c.ttl, store.ttl(c.kind, request), "Case %d", k)
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"github.com/opentracing/opentracing-go"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/tracing"
+	"golang.org/x/net/context"
+)
+
+// FositeTracedStore starts a span for every call which receives a context. The spans are children of the span in
+// the context, which is the span of the request if the handler created the context with tracing.Context.
+type FositeTracedStore struct {
+	pkg.FositeStorer
+
+	// Component names the backend in the spans, for example rethinkdb.
+	Component string
+}
+
+func (s *FositeTracedStore) start(ctx context.Context, operation string) (opentracing.Span, context.Context) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "oauth2."+operation)
+	if s.Component != "" {
+		span.SetTag("component", s.Component)
+	}
+	return span, ctx
+}
+
+func (s *FositeTracedStore) CreateOpenIDConnectSession(ctx context.Context, authorizeCode string, requester fosite.Requester) (err error) {
+	span, ctx := s.start(ctx, "create_openid_connect_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.CreateOpenIDConnectSession(ctx, authorizeCode, requester)
+}
+
+func (s *FositeTracedStore) GetOpenIDConnectSession(ctx context.Context, authorizeCode string, requester fosite.Requester) (_ fosite.Requester, err error) {
+	span, ctx := s.start(ctx, "get_openid_connect_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.GetOpenIDConnectSession(ctx, authorizeCode, requester)
+}
+
+func (s *FositeTracedStore) DeleteOpenIDConnectSession(ctx context.Context, authorizeCode string) (err error) {
+	span, ctx := s.start(ctx, "delete_openid_connect_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.DeleteOpenIDConnectSession(ctx, authorizeCode)
+}
+
+func (s *FositeTracedStore) CreateAuthorizeCodeSession(ctx context.Context, code string, req fosite.Requester) (err error) {
+	span, ctx := s.start(ctx, "create_authorize_code_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.CreateAuthorizeCodeSession(ctx, code, req)
+}
+
+func (s *FositeTracedStore) GetAuthorizeCodeSession(ctx context.Context, code string, session interface{}) (_ fosite.Requester, err error) {
+	span, ctx := s.start(ctx, "get_authorize_code_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.GetAuthorizeCodeSession(ctx, code, session)
+}
+
+func (s *FositeTracedStore) DeleteAuthorizeCodeSession(ctx context.Context, code string) (err error) {
+	span, ctx := s.start(ctx, "delete_authorize_code_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.DeleteAuthorizeCodeSession(ctx, code)
+}
+
+func (s *FositeTracedStore) CreateAccessTokenSession(ctx context.Context, signature string, req fosite.Requester) (err error) {
+	span, ctx := s.start(ctx, "create_access_token_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.CreateAccessTokenSession(ctx, signature, req)
+}
+
+func (s *FositeTracedStore) GetAccessTokenSession(ctx context.Context, signature string, session interface{}) (_ fosite.Requester, err error) {
+	span, ctx := s.start(ctx, "get_access_token_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.GetAccessTokenSession(ctx, signature, session)
+}
+
+func (s *FositeTracedStore) DeleteAccessTokenSession(ctx context.Context, signature string) (err error) {
+	span, ctx := s.start(ctx, "delete_access_token_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.DeleteAccessTokenSession(ctx, signature)
+}
+
+func (s *FositeTracedStore) CreateRefreshTokenSession(ctx context.Context, signature string, req fosite.Requester) (err error) {
+	span, ctx := s.start(ctx, "create_refresh_token_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.CreateRefreshTokenSession(ctx, signature, req)
+}
+
+func (s *FositeTracedStore) GetRefreshTokenSession(ctx context.Context, signature string, session interface{}) (_ fosite.Requester, err error) {
+	span, ctx := s.start(ctx, "get_refresh_token_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.GetRefreshTokenSession(ctx, signature, session)
+}
+
+func (s *FositeTracedStore) DeleteRefreshTokenSession(ctx context.Context, signature string) (err error) {
+	span, ctx := s.start(ctx, "delete_refresh_token_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.DeleteRefreshTokenSession(ctx, signature)
+}
+
+func (s *FositeTracedStore) CreateImplicitAccessTokenSession(ctx context.Context, signature string, req fosite.Requester) (err error) {
+	span, ctx := s.start(ctx, "create_implicit_access_token_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.CreateImplicitAccessTokenSession(ctx, signature, req)
+}
+
+func (s *FositeTracedStore) PersistAuthorizeCodeGrantSession(ctx context.Context, authorizeCode, accessSignature, refreshSignature string, request fosite.Requester) (err error) {
+	span, ctx := s.start(ctx, "persist_authorize_code_grant_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.PersistAuthorizeCodeGrantSession(ctx, authorizeCode, accessSignature, refreshSignature, request)
+}
+
+func (s *FositeTracedStore) PersistRefreshTokenGrantSession(ctx context.Context, originalRefreshSignature, accessSignature, refreshSignature string, request fosite.Requester) (err error) {
+	span, ctx := s.start(ctx, "persist_refresh_token_grant_session")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.PersistRefreshTokenGrantSession(ctx, originalRefreshSignature, accessSignature, refreshSignature, request)
+}
+
+func (s *FositeTracedStore) RevokeRefreshToken(ctx context.Context, signature string) (_ []string, err error) {
+	span, ctx := s.start(ctx, "revoke_refresh_token")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.RevokeRefreshToken(ctx, signature)
+}
+
+func (s *FositeTracedStore) IsRefreshTokenRotated(ctx context.Context, signature string) (_ bool, err error) {
+	span, ctx := s.start(ctx, "is_refresh_token_rotated")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.IsRefreshTokenRotated(ctx, signature)
+}
+
+func (s *FositeTracedStore) RevokeClientTokens(ctx context.Context, clientID string) (_ []string, err error) {
+	span, ctx := s.start(ctx, "revoke_client_tokens")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.RevokeClientTokens(ctx, clientID)
+}
+
+func (s *FositeTracedStore) RevokeSubjectTokens(ctx context.Context, subject, clientID string) (_ []string, err error) {
+	span, ctx := s.start(ctx, "revoke_subject_tokens")
+	defer func() { tracing.Finish(span, err) }()
+	return s.FositeStorer.RevokeSubjectTokens(ctx, subject, clientID)
+}
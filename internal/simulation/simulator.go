@@ -0,0 +1,326 @@
+package simulation
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	ejwt "github.com/ory-am/fosite/token/jwt"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/pborman/uuid"
+)
+
+// DefaultRedirectURL is never requested, the simulator reads the authorization code from the redirect itself.
+const DefaultRedirectURL = "http://localhost:4446/simulation/callback"
+
+// Step is the outcome of one step of a simulated flow.
+type Step struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Error   string `json:"error,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
+// Simulator walks the authorization code flow against a running cluster. It registers a temporary client and
+// stands in for the consent app by accepting every challenge on behalf of Subject. Accepting challenges requires
+// the private key of the consent endpoint, so never point a simulator at a production cluster.
+type Simulator struct {
+	// Transport is used for the requests a user agent and the temporary client send. Redirects are never followed.
+	Transport http.RoundTripper
+
+	// Admin is used to manage the temporary client and to fetch the consent keys.
+	Admin *http.Client
+
+	ClusterURL  *url.URL
+	Subject     string
+	Scopes      []string
+	RedirectURL string
+}
+
+type flow struct {
+	client     *fosite.DefaultClient
+	secret     string
+	keys       *jwk.HTTPManager
+	consentKey *rsa.PrivateKey
+	challenge  string
+	code       string
+	tokens     tokenResponse
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+}
+
+// Run executes the steps in order and stops at the first failing one. The temporary client is removed in any case.
+func (s *Simulator) Run() (steps []Step) {
+	f := &flow{keys: &jwk.HTTPManager{Client: s.Admin, Endpoint: s.url("/keys")}}
+	clients := &client.HTTPManager{Client: s.Admin, Endpoint: s.url("/clients")}
+
+	run := func(name string, do func(f *flow) (string, error)) bool {
+		details, err := do(f)
+		step := Step{Name: name, Passed: err == nil, Details: details}
+		if err != nil {
+			step.Error = err.Error()
+		}
+		steps = append(steps, step)
+		return err == nil
+	}
+
+	if !run("create-client", func(f *flow) (string, error) { return s.createClient(clients, f) }) {
+		return steps
+	}
+	defer func() {
+		run("delete-client", func(f *flow) (string, error) {
+			return "", clients.DeleteClient(f.client.ID)
+		})
+	}()
+
+	for _, step := range []struct {
+		name string
+		do   func(f *flow) (string, error)
+	}{
+		{name: "fetch-consent-key", do: s.fetchConsentKey},
+		{name: "authorize", do: s.authorize},
+		{name: "accept-consent", do: s.acceptConsent},
+		{name: "exchange-code", do: s.exchangeCode},
+		{name: "refresh-token", do: s.refreshToken},
+	} {
+		if !run(step.name, step.do) {
+			break
+		}
+	}
+	return steps
+}
+
+func (s *Simulator) createClient(clients *client.HTTPManager, f *flow) (string, error) {
+	secret, err := pkg.GenerateSecret(26)
+	if err != nil {
+		return "", err
+	}
+
+	f.secret = string(secret)
+	f.client = &fosite.DefaultClient{
+		ID:            "simulation-" + uuid.New(),
+		Name:          "Flow simulation",
+		Secret:        secret,
+		RedirectURIs:  []string{s.redirectURL()},
+		GrantTypes:    []string{"authorization_code", "refresh_token"},
+		ResponseTypes: []string{"code"},
+		GrantedScopes: s.Scopes,
+	}
+	if err := clients.CreateClient(f.client); err != nil {
+		return "", err
+	}
+	return f.client.ID, nil
+}
+
+func (s *Simulator) fetchConsentKey(f *flow) (string, error) {
+	keys, err := f.keys.GetKey(oauth2.ConsentEndpointKey, "private")
+	if err != nil {
+		return "", err
+	}
+
+	f.consentKey, err = jwk.ToRSAPrivate(jwk.First(keys.Keys))
+	if err != nil {
+		return "", err
+	}
+	return oauth2.ConsentEndpointKey, nil
+}
+
+func (s *Simulator) authorize(f *flow) (string, error) {
+	u := s.url("/oauth2/auth")
+	u.RawQuery = url.Values{
+		"response_type": {"code"},
+		"client_id":     {f.client.ID},
+		"redirect_uri":  {s.redirectURL()},
+		"scope":         {strings.Join(s.Scopes, " ")},
+		"state":         {"simulation-state"},
+		"nonce":         {"simulation-nonce"},
+	}.Encode()
+
+	location, err := s.redirect(u.String())
+	if err != nil {
+		return "", err
+	} else if location.Query().Get("error") != "" {
+		return "", errors.Errorf("Authorize endpoint returned error %s: %s", location.Query().Get("error"), location.Query().Get("error_description"))
+	}
+
+	f.challenge = location.Query().Get("challenge")
+	if f.challenge == "" {
+		return "", errors.Errorf("Expected redirect to the consent endpoint with a challenge but got %s", location)
+	}
+
+	location.RawQuery = ""
+	return location.String(), nil
+}
+
+func (s *Simulator) acceptConsent(f *flow) (string, error) {
+	keys, err := f.keys.GetKey(oauth2.ConsentChallengeKey, "public")
+	if err != nil {
+		return "", err
+	}
+
+	challenge, err := jwt.Parse(f.challenge, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.Errorf("Unexpected signing method: %v", t.Header["alg"])
+		}
+		return jwk.MustRSAPublic(jwk.First(keys.Keys)), nil
+	})
+	if err != nil {
+		return "", errors.Errorf("Couldn't parse challenge: %v", err)
+	}
+
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Claims = map[string]interface{}{
+		"jti": ejwt.ToString(challenge.Claims["jti"]),
+		"aud": f.client.ID,
+		"sub": s.Subject,
+		"scp": challenge.Claims["scp"],
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	consent, err := token.SignedString(f.consentKey)
+	if err != nil {
+		return "", errors.New(err)
+	}
+
+	location, err := s.redirect(ejwt.ToString(challenge.Claims["redir"]) + "&consent=" + url.QueryEscape(consent))
+	if err != nil {
+		return "", err
+	} else if location.Query().Get("error") != "" {
+		return "", errors.Errorf("Authorize endpoint returned error %s: %s", location.Query().Get("error"), location.Query().Get("error_description"))
+	} else if state := location.Query().Get("state"); state != "simulation-state" {
+		return "", errors.Errorf("Expected state simulation-state but got %s", state)
+	}
+
+	f.code = location.Query().Get("code")
+	if f.code == "" {
+		return "", errors.Errorf("Expected redirect with an authorization code but got %s", location)
+	}
+	return fmt.Sprintf("Accepted consent for %s", s.Subject), nil
+}
+
+func (s *Simulator) exchangeCode(f *flow) (string, error) {
+	if err := s.token(f, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {f.code},
+		"redirect_uri": {s.redirectURL()},
+	}); err != nil {
+		return "", err
+	}
+	return describeTokens(f.tokens), nil
+}
+
+func (s *Simulator) refreshToken(f *flow) (string, error) {
+	if f.tokens.RefreshToken == "" {
+		return "Skipped, no refresh token was issued because the offline scope was not requested", nil
+	}
+
+	if err := s.token(f, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {f.tokens.RefreshToken},
+	}); err != nil {
+		return "", err
+	}
+	return describeTokens(f.tokens), nil
+}
+
+func (s *Simulator) token(f *flow, form url.Values) error {
+	req, err := http.NewRequest("POST", s.url("/oauth2/token").String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.New(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(f.client.ID, f.secret)
+
+	resp, err := s.transport().RoundTrip(req)
+	if err != nil {
+		return errors.New(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.New(err)
+	} else if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Expected status code %d but got %d: %s", http.StatusOK, resp.StatusCode, body)
+	}
+
+	f.tokens = tokenResponse{}
+	if err := json.Unmarshal(body, &f.tokens); err != nil {
+		return errors.New(err)
+	} else if f.tokens.AccessToken == "" {
+		return errors.New("Token response did not contain an access_token")
+	}
+	return nil
+}
+
+// redirect requests the URL and returns the location it redirects to.
+func (s *Simulator) redirect(location string) (*url.URL, error) {
+	req, err := http.NewRequest("GET", location, nil)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	resp, err := s.transport().RoundTrip(req)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, errors.Errorf("Expected status code %d but got %d: %s", http.StatusFound, resp.StatusCode, body)
+	}
+
+	u, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return u, nil
+}
+
+func (s *Simulator) transport() http.RoundTripper {
+	if s.Transport == nil {
+		return http.DefaultTransport
+	}
+	return s.Transport
+}
+
+func (s *Simulator) url(path string) *url.URL {
+	return pkg.JoinURL(s.ClusterURL, path)
+}
+
+func (s *Simulator) redirectURL() string {
+	if s.RedirectURL == "" {
+		return DefaultRedirectURL
+	}
+	return s.RedirectURL
+}
+
+func describeTokens(t tokenResponse) string {
+	issued := []string{"access token"}
+	if t.RefreshToken != "" {
+		issued = append(issued, "refresh token")
+	}
+	if t.IDToken != "" {
+		issued = append(issued, "id token")
+	}
+	return fmt.Sprintf("Issued %s expiring in %ds with scope %q", strings.Join(issued, ", "), t.ExpiresIn, t.Scope)
+}
@@ -0,0 +1,88 @@
+package simulation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulator(t *testing.T) {
+	generator := &jwk.RS256Generator{}
+	challengeKeys, err := generator.Generate("")
+	require.Nil(t, err)
+	consentKeys, err := generator.Generate("")
+	require.Nil(t, err)
+
+	var ts *httptest.Server
+	var deleted string
+	router := httprouter.New()
+	router.POST("/clients", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+	router.DELETE("/clients/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		deleted = ps.ByName("id")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.GET("/keys/:set/:kid", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		keys := challengeKeys
+		if ps.ByName("set") == oauth2.ConsentEndpointKey {
+			keys = consentKeys
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys.Key(ps.ByName("kid"))})
+	})
+	router.GET("/oauth2/auth", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		q := r.URL.Query()
+		if q.Get("consent") == "" {
+			token := jwt.New(jwt.SigningMethodRS256)
+			token.Claims = map[string]interface{}{
+				"jti":   "challenge-id",
+				"scp":   []string{"core", "offline"},
+				"aud":   q.Get("client_id"),
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"redir": ts.URL + r.URL.String(),
+			}
+			challenge, err := token.SignedString(jwk.First(challengeKeys.Key("private")).Key)
+			require.Nil(t, err)
+			http.Redirect(w, r, ts.URL+"/consent?challenge="+challenge, http.StatusFound)
+			return
+		}
+
+		consent, err := jwt.Parse(q.Get("consent"), func(*jwt.Token) (interface{}, error) {
+			return jwk.MustRSAPublic(jwk.First(consentKeys.Key("public"))), nil
+		})
+		require.Nil(t, err)
+		assert.Equal(t, "peter", consent.Claims["sub"])
+		assert.Equal(t, "challenge-id", consent.Claims["jti"])
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=some-code&state="+q.Get("state"), http.StatusFound)
+	})
+	router.POST("/oauth2/token", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Write([]byte(`{"access_token":"foo","refresh_token":"bar","token_type":"bearer","expires_in":3600,"scope":"core offline"}`))
+	})
+	ts = httptest.NewServer(router)
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.Nil(t, err)
+
+	s := &Simulator{Admin: http.DefaultClient, ClusterURL: u, Subject: "peter", Scopes: []string{"core", "offline"}}
+	steps := s.Run()
+
+	var names []string
+	for _, step := range steps {
+		assert.True(t, step.Passed, "%s: %s", step.Name, step.Error)
+		names = append(names, step.Name)
+	}
+	assert.Equal(t, []string{"create-client", "fetch-consent-key", "authorize", "accept-consent", "exchange-code", "refresh-token", "delete-client"}, names)
+	assert.NotEmpty(t, deleted)
+}
@@ -0,0 +1,69 @@
+package janitor
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/job"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const (
+	JanitorHandlerPath = "/admin/janitor"
+
+	janitorResource = "rn:hydra:janitor"
+	scope           = "hydra.janitor"
+)
+
+type Handler struct {
+	Janitor *Janitor
+
+	// Jobs runs janitors requested with async=true in the background. Janitors always run synchronously if Jobs
+	// is nil.
+	Jobs *job.Dispatcher
+
+	H herodot.Herodot
+	W firewall.Firewall
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.POST(JanitorHandlerPath, h.Run)
+}
+
+// Run deletes all expired tokens right away instead of waiting for the next scheduled run.
+func (h *Handler) Run(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: janitorResource,
+		Action:   "run",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	run := func(ctx context.Context) (interface{}, error) {
+		return h.Janitor.Run(ctx)
+	}
+
+	if r.URL.Query().Get("async") == "true" && h.Jobs != nil {
+		j, err := h.Jobs.Submit("janitor.run", run)
+		if err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		job.WriteAccepted(ctx, h.H, w, r, j)
+		return
+	}
+
+	res, err := run(ctx)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, res)
+}
@@ -0,0 +1,114 @@
+// Package janitor deletes expired tokens, which the token stores would otherwise keep forever.
+package janitor
+
+import (
+	"time"
+
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+const (
+	// DefaultBatchSize is how many tokens are deleted at once if the janitor does not say otherwise.
+	DefaultBatchSize = 100
+
+	// DefaultInterval is how often the janitor runs if it does not say otherwise.
+	DefaultInterval = time.Hour
+)
+
+// Janitor deletes tokens of the kinds in Lifespans once they were requested longer than the kind's lifespan ago.
+// Tokens are deleted in batches so that a large backlog does not lock the store for long.
+type Janitor struct {
+	Store pkg.TokenFlusher
+
+	// Lifespans maps token kinds to their lifespan. Tokens of kinds without a lifespan are never deleted.
+	Lifespans map[string]time.Duration
+
+	// BatchSize is DefaultBatchSize if zero.
+	BatchSize int
+
+	// Interval is DefaultInterval if zero.
+	Interval time.Duration
+
+	// Leader skips scheduled runs on all nodes but the leader. Scheduled runs happen on every node if nil.
+	Leader pkg.Leader
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	// Deleted maps token kinds to the number of tokens which were deleted.
+	Deleted map[string]int `json:"deleted"`
+
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Run deletes all expired tokens. The report contains the tokens deleted before an error occurred.
+func (j *Janitor) Run(ctx context.Context) (*Report, error) {
+	report := &Report{Deleted: map[string]int{}, StartedAt: time.Now().UTC()}
+	defer func() {
+		report.FinishedAt = time.Now().UTC()
+		observeRun(report)
+	}()
+
+	batchSize := j.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	for _, kind := range pkg.TokenKinds {
+		lifespan, ok := j.Lifespans[kind]
+		if !ok || lifespan <= 0 {
+			continue
+		}
+
+		requestedBefore := report.StartedAt.Add(-lifespan)
+		for {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			default:
+			}
+
+			n, err := j.Store.FlushExpiredTokens(kind, requestedBefore, batchSize)
+			if err != nil {
+				observeFailure(kind)
+				return report, err
+			}
+
+			report.Deleted[kind] += n
+			observeDeleted(kind, n)
+			if n < batchSize {
+				break
+			}
+		}
+	}
+	return report, nil
+}
+
+// Start runs the janitor every Interval until ctx is done, on the leader only if Leader is set.
+func (j *Janitor) Start(ctx context.Context) {
+	interval := j.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if j.Leader != nil && !j.Leader.IsLeader() {
+					continue
+				}
+				if _, err := j.Run(ctx); err != nil {
+					logger.LogError(err)
+				}
+			}
+		}
+	}()
+}
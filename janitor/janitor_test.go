@@ -0,0 +1,72 @@
+package janitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type fakeFlusher struct {
+	expired map[string]int
+	before  map[string]time.Time
+	calls   int
+	err     error
+}
+
+func (f *fakeFlusher) FlushExpiredTokens(kind string, requestedBefore time.Time, limit int) (int, error) {
+	f.calls++
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	f.before[kind] = requestedBefore
+	n := f.expired[kind]
+	if n > limit {
+		n = limit
+	}
+	f.expired[kind] -= n
+	return n, nil
+}
+
+func TestRun(t *testing.T) {
+	f := &fakeFlusher{
+		expired: map[string]int{pkg.TokenKindAccessToken: 25, pkg.TokenKindRefreshToken: 7},
+		before:  map[string]time.Time{},
+	}
+	j := &Janitor{
+		Store: f,
+		Lifespans: map[string]time.Duration{
+			pkg.TokenKindAccessToken:  time.Hour,
+			pkg.TokenKindRefreshToken: 0,
+		},
+		BatchSize: 10,
+	}
+
+	report, err := j.Run(context.Background())
+	require.Nil(t, err)
+	assert.Equal(t, map[string]int{pkg.TokenKindAccessToken: 25}, report.Deleted)
+	assert.Equal(t, 3, f.calls)
+	assert.Equal(t, 7, f.expired[pkg.TokenKindRefreshToken])
+	assert.WithinDuration(t, report.StartedAt.Add(-time.Hour), f.before[pkg.TokenKindAccessToken], time.Second)
+	assert.False(t, report.FinishedAt.Before(report.StartedAt))
+}
+
+func TestRunStopsOnError(t *testing.T) {
+	f := &fakeFlusher{expired: map[string]int{}, before: map[string]time.Time{}, err: errors.New("store is down")}
+	j := &Janitor{
+		Store: f,
+		Lifespans: map[string]time.Duration{
+			pkg.TokenKindAuthorizeCode: time.Hour,
+			pkg.TokenKindAccessToken:   time.Hour,
+		},
+	}
+
+	_, err := j.Run(context.Background())
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, f.calls)
+}
@@ -0,0 +1,53 @@
+package janitor
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var deletedTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "janitor",
+	Name:      "deleted_tokens_total",
+	Help:      "Number of expired tokens deleted, partitioned by kind.",
+}, []string{"kind"})
+
+var failures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "janitor",
+	Name:      "failures_total",
+	Help:      "Number of batches which could not be deleted, partitioned by kind.",
+}, []string{"kind"})
+
+var runDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Namespace: "hydra",
+	Subsystem: "janitor",
+	Name:      "run_duration_seconds",
+	Help:      "Time a run of the janitor took.",
+})
+
+var lastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "hydra",
+	Subsystem: "janitor",
+	Name:      "last_run_timestamp_seconds",
+	Help:      "Time the janitor finished its last run at.",
+})
+
+func init() {
+	prometheus.MustRegister(deletedTokens)
+	prometheus.MustRegister(failures)
+	prometheus.MustRegister(runDuration)
+	prometheus.MustRegister(lastRun)
+}
+
+func observeDeleted(kind string, n int) {
+	deletedTokens.WithLabelValues(kind).Add(float64(n))
+}
+
+func observeFailure(kind string) {
+	failures.WithLabelValues(kind).Inc()
+}
+
+func observeRun(r *Report) {
+	runDuration.Observe(r.FinishedAt.Sub(r.StartedAt).Seconds())
+	lastRun.Set(float64(r.FinishedAt.Unix()))
+}
@@ -0,0 +1,21 @@
+package janitor
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/job"
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "POST", Path: JanitorHandlerPath, ID: "runJanitor", Tag: "janitor", Scope: scope,
+		Summary: "Delete the expired tokens, in the background if async is true",
+		Query:   []string{"async"},
+		Responses: map[int]interface{}{
+			http.StatusOK:       &Report{},
+			http.StatusAccepted: &job.Job{},
+		},
+	},
+}
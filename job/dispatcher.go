@@ -0,0 +1,94 @@
+package job
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/ory-am/hydra/logger"
+	"github.com/pborman/uuid"
+	"golang.org/x/net/context"
+)
+
+// DefaultWorkers is the number of jobs which run at the same time if the dispatcher does not say otherwise.
+const DefaultWorkers = 4
+
+// Dispatcher persists submitted jobs and runs them on a bounded number of workers. Jobs which are running when
+// the process exits are not resumed, their status stays running.
+type Dispatcher struct {
+	Manager Manager
+
+	// Workers is the number of jobs which run at the same time.
+	Workers int
+
+	once  sync.Once
+	slots chan struct{}
+}
+
+// Submit persists a new job of the given type and runs fn in the background. The returned job is pending.
+func (d *Dispatcher) Submit(kind string, fn Func) (*Job, error) {
+	d.once.Do(func() {
+		workers := d.Workers
+		if workers < 1 {
+			workers = DefaultWorkers
+		}
+		d.slots = make(chan struct{}, workers)
+	})
+
+	j := &Job{
+		ID:        uuid.New(),
+		Type:      kind,
+		Status:    StatusPending,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := d.Manager.CreateJob(j); err != nil {
+		return nil, err
+	}
+
+	c := *j
+	go d.run(&c, fn)
+	return j, nil
+}
+
+func (d *Dispatcher) run(j *Job, fn Func) {
+	d.slots <- struct{}{}
+	defer func() { <-d.slots }()
+
+	started := time.Now().UTC()
+	j.Status = StatusRunning
+	j.StartedAt = &started
+	if err := d.Manager.UpdateJob(j); err != nil {
+		logger.LogError(err)
+	}
+
+	result, err := call(fn)
+	if err == nil {
+		j.Result, err = canonical.Marshal(result)
+	}
+
+	finished := time.Now().UTC()
+	j.FinishedAt = &finished
+	if err != nil {
+		logger.LogError(err)
+		j.Status = StatusFailed
+		j.Error = err.Error()
+		j.Result = nil
+	} else {
+		j.Status = StatusSucceeded
+	}
+
+	if err := d.Manager.UpdateJob(j); err != nil {
+		logger.LogError(err)
+	}
+}
+
+// call runs fn and turns a panic into an error so that a broken job does not take down the server.
+func call(fn Func) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("Job panicked: %v", r)
+		}
+	}()
+	return fn(context.Background())
+}
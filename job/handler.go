@@ -0,0 +1,57 @@
+package job
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const (
+	JobsHandlerPath = "/admin/jobs"
+
+	jobResource = "rn:hydra:jobs:%s"
+	scope       = "hydra.jobs"
+)
+
+type Handler struct {
+	Manager Manager
+	H       herodot.Herodot
+	W       firewall.Firewall
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.GET(JobsHandlerPath+"/:id", h.Get)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(jobResource, id),
+		Action:   "get",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	j, err := h.Manager.GetJob(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, j)
+}
+
+// WriteAccepted answers a request whose work continues in the job j. The Location header points to the status
+// of the job.
+func WriteAccepted(ctx context.Context, hd herodot.Herodot, w http.ResponseWriter, r *http.Request, j *Job) {
+	w.Header().Set("Location", JobsHandlerPath+"/"+j.ID)
+	hd.WriteCode(ctx, w, r, http.StatusAccepted, j)
+}
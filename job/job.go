@@ -0,0 +1,43 @@
+// Package job runs long operations, for example bulk revocations, in the background. Jobs are persisted so that
+// their status can be queried from any instance while they run.
+package job
+
+import (
+	"encoding/json"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Job is a long operation which runs in the background.
+type Job struct {
+	ID string `json:"id" gorethink:"id"`
+
+	// Type names the operation, for example "oauth2.tokens.revoke".
+	Type string `json:"type" gorethink:"type"`
+
+	Status string `json:"status" gorethink:"status"`
+
+	// Result is the outcome of a successful job, Error the reason a job failed.
+	Result json.RawMessage `json:"result,omitempty" gorethink:"result,omitempty"`
+	Error  string          `json:"error,omitempty" gorethink:"error,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at" gorethink:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty" gorethink:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" gorethink:"finished_at,omitempty"`
+}
+
+// IsDone returns true if the job succeeded or failed.
+func (j *Job) IsDone() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Func performs the work of a job. The returned result is stored as JSON.
+type Func func(ctx context.Context) (interface{}, error)
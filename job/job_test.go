@@ -0,0 +1,87 @@
+package job_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	. "github.com/ory-am/hydra/job"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func waitFor(t *testing.T, m Manager, id string) *Job {
+	for i := 0; i < 100; i++ {
+		j, err := m.GetJob(id)
+		require.Nil(t, err)
+		if j.IsDone() {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return nil
+}
+
+func TestDispatcher(t *testing.T) {
+	m := NewMemoryManager()
+	d := &Dispatcher{Manager: m, Workers: 1}
+
+	block := make(chan struct{})
+	j, err := d.Submit("test.success", func(_ context.Context) (interface{}, error) {
+		<-block
+		return map[string]int{"deleted": 3}, nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, StatusPending, j.Status)
+	assert.Equal(t, "test.success", j.Type)
+
+	for i := 0; i < 100; i++ {
+		if r, err := m.GetJob(j.ID); err == nil && r.Status == StatusRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	failed, err := d.Submit("test.failure", func(_ context.Context) (interface{}, error) {
+		return nil, errors.New("Database went away")
+	})
+	require.Nil(t, err)
+
+	panicked, err := d.Submit("test.panic", func(_ context.Context) (interface{}, error) {
+		panic("oops")
+	})
+	require.Nil(t, err)
+
+	// A single worker runs one job at a time, the others wait until the first job finished.
+	time.Sleep(50 * time.Millisecond)
+	for _, id := range []string{failed.ID, panicked.ID} {
+		p, err := m.GetJob(id)
+		require.Nil(t, err)
+		assert.Equal(t, StatusPending, p.Status)
+	}
+	close(block)
+
+	res := waitFor(t, m, j.ID)
+	assert.Equal(t, StatusSucceeded, res.Status)
+	assert.NotNil(t, res.StartedAt)
+	assert.NotNil(t, res.FinishedAt)
+
+	var result map[string]int
+	require.Nil(t, json.Unmarshal(res.Result, &result))
+	assert.Equal(t, 3, result["deleted"])
+
+	res = waitFor(t, m, failed.ID)
+	assert.Equal(t, StatusFailed, res.Status)
+	assert.Equal(t, "Database went away", res.Error)
+
+	res = waitFor(t, m, panicked.ID)
+	assert.Equal(t, StatusFailed, res.Status)
+	assert.Contains(t, res.Error, "oops")
+
+	_, err = m.GetJob("unknown")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+}
@@ -0,0 +1,12 @@
+package job
+
+// Manager persists jobs.
+type Manager interface {
+	CreateJob(j *Job) error
+
+	// GetJob returns a job or pkg.ErrNotFound.
+	GetJob(id string) (*Job, error)
+
+	// UpdateJob replaces a job.
+	UpdateJob(j *Job) error
+}
@@ -0,0 +1,54 @@
+package job
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type MemoryManager struct {
+	Jobs map[string]*Job
+	sync.RWMutex
+}
+
+func NewMemoryManager() *MemoryManager {
+	return &MemoryManager{
+		Jobs: map[string]*Job{},
+	}
+}
+
+func (m *MemoryManager) CreateJob(j *Job) error {
+	m.Lock()
+	defer m.Unlock()
+
+	c := *j
+	m.Jobs[j.ID] = &c
+	return nil
+}
+
+func (m *MemoryManager) GetJob(id string) (*Job, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	j, ok := m.Jobs[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	c := *j
+	return &c, nil
+}
+
+func (m *MemoryManager) UpdateJob(j *Job) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.Jobs[j.ID]; !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	c := *j
+	m.Jobs[j.ID] = &c
+	return nil
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// RethinkManager reads and writes directly against the database because the status of a job may be queried on
+// a different instance than the one running it.
+type RethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *RethinkManager) CreateJob(j *Job) error {
+	if _, err := m.Table.Insert(j).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) GetJob(id string) (*Job, error) {
+	res, err := m.Table.Get(id).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var j Job
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&j); err != nil {
+		return nil, errors.New(err)
+	}
+	return &j, nil
+}
+
+func (m *RethinkManager) UpdateJob(j *Job) error {
+	if _, err := m.Table.Get(j.ID).Replace(j).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
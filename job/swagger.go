@@ -0,0 +1,16 @@
+package job
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "GET", Path: JobsHandlerPath + "/:id", ID: "getJob", Tag: "jobs", Scope: scope,
+		Summary:   "Get the status and result of a job",
+		Responses: map[int]interface{}{http.StatusOK: &Job{}},
+	},
+}
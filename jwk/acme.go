@@ -0,0 +1,49 @@
+package jwk
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/context"
+)
+
+// ACMECache stores the account key and the certificates obtained via ACME in a key set. Every entry is kept as a
+// symmetric key whose key id is the name of the entry, so it is encrypted at rest like any other key and shared by
+// all nodes which use the same manager.
+type ACMECache struct {
+	Manager Manager
+	Set     string
+}
+
+// Get implements autocert.Cache.
+func (c *ACMECache) Get(ctx context.Context, name string) ([]byte, error) {
+	keys, err := GetKeyConsistent(c.Manager, c.Set, name)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return nil, autocert.ErrCacheMiss
+	} else if err != nil {
+		return nil, err
+	}
+
+	data, ok := First(keys.Keys).Key.([]byte)
+	if !ok {
+		return nil, errors.Errorf("Key %s of set %s is not an ACME cache entry", name, c.Set)
+	}
+	return data, nil
+}
+
+// Put implements autocert.Cache.
+func (c *ACMECache) Put(ctx context.Context, name string, data []byte) error {
+	if err := c.Delete(ctx, name); err != nil {
+		return err
+	}
+	return c.Manager.AddKey(c.Set, &jose.JsonWebKey{Key: data, KeyID: name})
+}
+
+// Delete implements autocert.Cache.
+func (c *ACMECache) Delete(ctx context.Context, name string) error {
+	if err := c.Manager.DeleteKey(c.Set, name); err != nil && !errors.Is(err, pkg.ErrNotFound) {
+		return err
+	}
+	return nil
+}
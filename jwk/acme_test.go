@@ -0,0 +1,30 @@
+package jwk_test
+
+import (
+	"testing"
+
+	. "github.com/ory-am/hydra/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/context"
+)
+
+func TestACMECache(t *testing.T) {
+	ctx := context.Background()
+	c := &ACMECache{Manager: &MemoryManager{}, Set: "acme"}
+
+	_, err := c.Get(ctx, "example.com")
+	assert.Equal(t, autocert.ErrCacheMiss, err)
+
+	require.Nil(t, c.Put(ctx, "example.com", []byte("first")))
+	require.Nil(t, c.Put(ctx, "example.com", []byte("renewed")))
+	data, err := c.Get(ctx, "example.com")
+	require.Nil(t, err)
+	assert.Equal(t, []byte("renewed"), data)
+
+	require.Nil(t, c.Delete(ctx, "example.com"))
+	require.Nil(t, c.Delete(ctx, "example.com"), "deleting a missing entry is not an error")
+	_, err = c.Get(ctx, "example.com")
+	assert.Equal(t, autocert.ErrCacheMiss, err)
+}
@@ -0,0 +1,42 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+
+	"github.com/square/go-jose"
+)
+
+// SigningAlgorithm returns the algorithm key signs with. This is the key's alg parameter if it has one, otherwise
+// RS256 for RSA keys and the ECDSA algorithm matching the curve of EC keys. It returns an empty string for keys
+// without an alg parameter whose algorithm can not be derived, for example symmetric keys.
+func SigningAlgorithm(key *jose.JsonWebKey) string {
+	if key.Algorithm != "" {
+		return key.Algorithm
+	}
+
+	switch key.Key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return "RS256"
+	case *ecdsa.PrivateKey, *ecdsa.PublicKey:
+		curve := curveName(key.Key)
+		for alg, c := range curveAlgorithms {
+			if c == curve {
+				return alg
+			}
+		}
+	}
+	return ""
+}
+
+// PublicKeys returns the asymmetric public keys among keys. Private and symmetric keys are left out.
+func PublicKeys(keys []jose.JsonWebKey) []jose.JsonWebKey {
+	result := []jose.JsonWebKey{}
+	for _, key := range keys {
+		switch key.Key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			result = append(result, key)
+		}
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package jwk
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+)
+
+const (
+	AuditActionAddKey       = "add_key"
+	AuditActionRotateKeySet = "rotate_key_set"
+	AuditActionDeleteKey    = "delete_key"
+	AuditActionDeleteKeySet = "delete_key_set"
+	AuditActionRestoreKey   = "restore_key"
+	AuditActionAccessDenied = "access_denied"
+)
+
+// AuditEvent records a single change of, or a failed attempt to access, the key store.
+type AuditEvent struct {
+	Time   time.Time `json:"timestamp"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Set    string    `json:"set"`
+	KeyID  string    `json:"kid,omitempty"`
+
+	// Remote is the address of the peer which sent the request.
+	Remote string `json:"remote,omitempty"`
+
+	// Reason explains why an access attempt failed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuditSink receives audit events.
+type AuditSink interface {
+	Write(e *AuditEvent) error
+}
+
+// JSONAuditSink writes one JSON document per line to Writer. Use it with os.Stdout, a file or a syslog writer.
+type JSONAuditSink struct {
+	Writer io.Writer
+	sync.Mutex
+}
+
+func (s *JSONAuditSink) Write(e *AuditEvent) error {
+	out, err := canonical.Marshal(e)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if _, err := s.Writer.Write(append(out, '\n')); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// NewFileAuditSink appends audit events to the file at path.
+func NewFileAuditSink(path string) (*JSONAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return &JSONAuditSink{Writer: f}, nil
+}
+
+// NewAuditSink creates a sink from a destination like "stdout", "syslog" or "file:/var/log/hydra/keys.log".
+func NewAuditSink(destination string) (AuditSink, error) {
+	switch {
+	case destination == "stdout":
+		return &JSONAuditSink{Writer: os.Stdout}, nil
+	case destination == "syslog":
+		return NewSyslogAuditSink("hydra")
+	case strings.HasPrefix(destination, "file:"):
+		return NewFileAuditSink(strings.TrimPrefix(destination, "file:"))
+	}
+	return nil, errors.Errorf("Unknown audit log destination %s", destination)
+}
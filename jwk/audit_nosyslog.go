@@ -0,0 +1,13 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package jwk
+
+import (
+	"github.com/go-errors/errors"
+)
+
+// NewSyslogAuditSink is not available on this platform.
+func NewSyslogAuditSink(tag string) (*JSONAuditSink, error) {
+	return nil, errors.New("Syslog is not supported on this platform")
+}
@@ -0,0 +1,19 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package jwk
+
+import (
+	"log/syslog"
+
+	"github.com/go-errors/errors"
+)
+
+// NewSyslogAuditSink sends audit events to the local syslog daemon using the auth facility.
+func NewSyslogAuditSink(tag string) (*JSONAuditSink, error) {
+	w, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return &JSONAuditSink{Writer: w}, nil
+}
@@ -0,0 +1,52 @@
+package jwk_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/ory-am/hydra/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &JSONAuditSink{Writer: &buf}
+
+	require.Nil(t, sink.Write(&AuditEvent{Time: time.Now(), Actor: "alice", Action: AuditActionAddKey, Set: "foo", KeyID: "private"}))
+	require.Nil(t, sink.Write(&AuditEvent{Time: time.Now(), Action: AuditActionAccessDenied, Set: "foo", Reason: "Forbidden"}))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var e map[string]interface{}
+	require.Nil(t, json.Unmarshal(lines[0], &e))
+	assert.Equal(t, "alice", e["actor"])
+	assert.Equal(t, AuditActionAddKey, e["action"])
+	assert.Equal(t, "foo", e["set"])
+	assert.Equal(t, "private", e["kid"])
+	assert.NotNil(t, e["timestamp"])
+}
+
+func TestNewAuditSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hydra-audit")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keys.log")
+	sink, err := NewAuditSink("file:" + path)
+	require.Nil(t, err)
+	require.Nil(t, sink.Write(&AuditEvent{Time: time.Now(), Action: AuditActionDeleteKeySet, Set: "foo"}))
+
+	out, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.True(t, bytes.Contains(out, []byte(AuditActionDeleteKeySet)))
+
+	_, err = NewAuditSink("carrier-pigeon")
+	assert.NotNil(t, err)
+}
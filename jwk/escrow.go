@@ -0,0 +1,132 @@
+package jwk
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/square/go-jose"
+)
+
+const EscrowVersion = 1
+
+// Escrow is a key set encrypted with a random wrapping key. The wrapping key is split among custodians so that a
+// threshold of them has to come together to recover the key set, no single person can decrypt the backup.
+type Escrow struct {
+	Version int    `json:"version"`
+	ID      string `json:"id"`
+	Set     string `json:"set"`
+
+	// Threshold is the number of shares required to recover the key set, Shares the number of shares issued.
+	Threshold int `json:"threshold"`
+	Shares    int `json:"shares"`
+
+	// Ciphertext is the key set, encrypted with AES-GCM under the wrapping key.
+	Ciphertext string    `json:"ciphertext"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EscrowShare is the part of the wrapping key handed to one custodian.
+type EscrowShare struct {
+	Escrow string `json:"escrow"`
+	Set    string `json:"set"`
+	Index  int    `json:"index"`
+	Share  string `json:"share"`
+}
+
+// NewEscrow encrypts keys with a new wrapping key and splits the wrapping key into shares of which threshold are
+// needed for recovery.
+func NewEscrow(set string, keys *jose.JsonWebKeySet, shares, threshold int) (*Escrow, []EscrowShare, error) {
+	wrappingKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, wrappingKey); err != nil {
+		return nil, nil, errors.New(err)
+	}
+
+	parts, err := SplitSecret(wrappingKey, shares, threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := canonical.Marshal(keys)
+	if err != nil {
+		return nil, nil, errors.New(err)
+	}
+
+	ciphertext, err := (&AEAD{Key: wrappingKey}).Encrypt(plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e := &Escrow{
+		Version:    EscrowVersion,
+		ID:         escrowID(wrappingKey),
+		Set:        set,
+		Threshold:  threshold,
+		Shares:     shares,
+		Ciphertext: ciphertext,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	result := make([]EscrowShare, len(parts))
+	for i, part := range parts {
+		result[i] = EscrowShare{
+			Escrow: e.ID,
+			Set:    set,
+			Index:  int(part[0]),
+			Share:  base64.RawURLEncoding.EncodeToString(part),
+		}
+	}
+	return e, result, nil
+}
+
+// RecoverEscrow combines the custodians' shares and decrypts the key set.
+func RecoverEscrow(e *Escrow, shares []EscrowShare) (*jose.JsonWebKeySet, error) {
+	if e.Version != EscrowVersion {
+		return nil, errors.Errorf("Escrow version %d is not supported", e.Version)
+	} else if len(shares) < e.Threshold {
+		return nil, errors.Errorf("Recovering the key set requires %d shares, got %d", e.Threshold, len(shares))
+	}
+
+	parts := make([][]byte, len(shares))
+	for i, share := range shares {
+		if share.Escrow != e.ID {
+			return nil, errors.Errorf("Share %d belongs to another escrow", share.Index)
+		}
+
+		part, err := base64.RawURLEncoding.DecodeString(share.Share)
+		if err != nil {
+			return nil, errors.Errorf("Share %d is malformed: %s", share.Index, err)
+		}
+		parts[i] = part
+	}
+
+	wrappingKey, err := CombineShares(parts)
+	if err != nil {
+		return nil, err
+	} else if escrowID(wrappingKey) != e.ID {
+		return nil, errors.New("The shares do not recover the wrapping key, at least one of them is corrupted")
+	}
+
+	plaintext, err := (&AEAD{Key: wrappingKey}).Decrypt(e.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys jose.JsonWebKeySet
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, errors.New(err)
+	}
+	return &keys, nil
+}
+
+// escrowID identifies an escrow by a hash of its wrapping key, which also detects corrupted shares on recovery.
+func escrowID(wrappingKey []byte) string {
+	sum := sha256.Sum256(append([]byte("hydra-escrow:"), wrappingKey...))
+	return hex.EncodeToString(sum[:16])
+}
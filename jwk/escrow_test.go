@@ -0,0 +1,73 @@
+package jwk_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	. "github.com/ory-am/hydra/jwk"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShamir(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	shares, err := SplitSecret(secret, 5, 3)
+	require.Nil(t, err)
+	require.Len(t, shares, 5)
+
+	for k, c := range []struct {
+		shares   [][]byte
+		expected bool
+	}{
+		{shares: [][]byte{shares[0], shares[1], shares[2]}, expected: true},
+		{shares: [][]byte{shares[4], shares[2], shares[0]}, expected: true},
+		{shares: shares, expected: true},
+		{shares: [][]byte{shares[0], shares[3]}, expected: false},
+	} {
+		recovered, err := CombineShares(c.shares)
+		require.Nil(t, err, "Case %d", k)
+		assert.Equal(t, c.expected, bytes.Equal(secret, recovered), "Case %d", k)
+	}
+
+	_, err = CombineShares([][]byte{shares[0], shares[0]})
+	assert.NotNil(t, err)
+	_, err = SplitSecret(secret, 2, 3)
+	assert.NotNil(t, err)
+	_, err = SplitSecret(secret, 3, 1)
+	assert.NotNil(t, err)
+}
+
+func TestEscrow(t *testing.T) {
+	keys := &jose.JsonWebKeySet{Keys: []jose.JsonWebKey{
+		{Key: []byte("some-super-secret-symmetric-key"), KeyID: "private"},
+	}}
+
+	e, shares, err := NewEscrow("foo", keys, 3, 2)
+	require.Nil(t, err)
+	require.Len(t, shares, 3)
+	assert.Equal(t, "foo", e.Set)
+
+	recovered, err := RecoverEscrow(e, []EscrowShare{shares[2], shares[0]})
+	require.Nil(t, err)
+
+	expected, err := json.Marshal(keys)
+	require.Nil(t, err)
+	actual, err := json.Marshal(recovered)
+	require.Nil(t, err)
+	assert.Equal(t, string(expected), string(actual))
+
+	_, err = RecoverEscrow(e, shares[:1])
+	assert.NotNil(t, err)
+
+	other, otherShares, err := NewEscrow("foo", keys, 3, 2)
+	require.Nil(t, err)
+	_, err = RecoverEscrow(other, []EscrowShare{shares[0], otherShares[1]})
+	assert.NotNil(t, err)
+
+	corrupted := shares[1]
+	corrupted.Share = shares[2].Share[:2] + shares[1].Share[2:]
+	_, err = RecoverEscrow(e, []EscrowShare{shares[0], corrupted})
+	assert.NotNil(t, err)
+}
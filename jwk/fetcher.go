@@ -0,0 +1,206 @@
+package jwk
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+	"golang.org/x/net/context"
+)
+
+const (
+	// DefaultFetcherTTL is how long a CachingFetcher keeps keys whose response does not set a max-age.
+	DefaultFetcherTTL = time.Minute * 5
+
+	// DefaultFetcherMinInterval is the least time between two fetches of a CachingFetcher.
+	DefaultFetcherMinInterval = time.Second * 30
+)
+
+// CachingFetcher fetches a remote JSON web key set, usually the /.well-known/jwks.json of a cluster, and caches its
+// keys by key id. The keys are kept for the max-age of the Cache-Control header of the response, or TTL if it has
+// none, and are refreshed when they are looked up afterwards or, see Watch, in the background.
+//
+// A key id which is not cached makes the fetcher refresh the key set right away, which is how keys which were added by
+// a key rotation are found. Refreshes happen at most once per MinInterval, so that tokens with made up key ids do not
+// flood the cluster with requests. If a refresh fails the previous keys are served until a refresh succeeds.
+type CachingFetcher struct {
+	URL string
+
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+
+	// TTL defaults to DefaultFetcherTTL.
+	TTL time.Duration
+
+	// MinInterval defaults to DefaultFetcherMinInterval.
+	MinInterval time.Duration
+
+	sync.RWMutex
+	keys      map[string]*jose.JsonWebKey
+	fetchedAt time.Time
+	expiresAt time.Time
+	err       error
+
+	// fetching serializes the fetches, so that a burst of unknown key ids results in one request.
+	fetching sync.Mutex
+}
+
+// GetKey returns the key with the key id, or pkg.ErrNotFound if the key set does not contain it.
+func (f *CachingFetcher) GetKey(kid string) (*jose.JsonWebKey, error) {
+	f.RLock()
+	key, ok := f.keys[kid]
+	fresh := time.Now().Before(f.expiresAt)
+	f.RUnlock()
+	observeCache(ok && fresh)
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := f.refresh(); err != nil && ok {
+		logger.LogError(err)
+		return key, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	f.RLock()
+	key, ok = f.keys[kid]
+	f.RUnlock()
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return key, nil
+}
+
+// Refresh fetches the key set regardless of when it was fetched last.
+func (f *CachingFetcher) Refresh() error {
+	f.fetching.Lock()
+	defer f.fetching.Unlock()
+
+	return f.update()
+}
+
+// Watch refreshes the keys in the background when they expire until ctx is done, so that lookups do not wait for the
+// refresh. A failed refresh is retried after MinInterval.
+func (f *CachingFetcher) Watch(ctx context.Context) {
+	go func() {
+		for {
+			f.RLock()
+			wait := f.expiresAt.Sub(time.Now())
+			failed := f.err != nil
+			f.RUnlock()
+			if failed || wait < f.minInterval() {
+				wait = f.minInterval()
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+				if err := f.Refresh(); err != nil {
+					logger.LogError(err)
+				}
+			}
+		}
+	}()
+}
+
+// refresh fetches the key set unless it was fetched less than MinInterval ago, in which case the error of that fetch
+// is returned.
+func (f *CachingFetcher) refresh() error {
+	f.fetching.Lock()
+	defer f.fetching.Unlock()
+
+	f.RLock()
+	recent := time.Since(f.fetchedAt) < f.minInterval()
+	err := f.err
+	f.RUnlock()
+	if recent {
+		return err
+	}
+	return f.update()
+}
+
+func (f *CachingFetcher) update() error {
+	keys, ttl, err := f.fetch()
+
+	f.Lock()
+	defer f.Unlock()
+
+	f.fetchedAt = time.Now()
+	f.err = err
+	if err != nil {
+		return err
+	}
+
+	f.keys = map[string]*jose.JsonWebKey{}
+	for k := range keys.Keys {
+		f.keys[keys.Keys[k].KeyID] = &keys.Keys[k]
+	}
+	f.expiresAt = f.fetchedAt.Add(ttl)
+	return nil
+}
+
+func (f *CachingFetcher) fetch() (*jose.JsonWebKeySet, time.Duration, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(f.URL)
+	if err != nil {
+		return nil, 0, errors.New(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		all, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, 0, errors.New(err)
+		}
+
+		return nil, 0, errors.Errorf("Could not fetch %s, got error (%d): %s", f.URL, resp.StatusCode, all)
+	}
+
+	var keys jose.JsonWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return nil, 0, errors.New(err)
+	}
+	return &keys, f.ttl(resp.Header.Get("Cache-Control")), nil
+}
+
+// ttl returns the max-age of a Cache-Control header. Responses which must not be cached expire right away, which
+// makes every lookup refresh the keys at most once per MinInterval.
+func (f *CachingFetcher) ttl(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && seconds >= 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	if f.TTL == 0 {
+		return DefaultFetcherTTL
+	}
+	return f.TTL
+}
+
+func (f *CachingFetcher) minInterval() time.Duration {
+	if f.MinInterval == 0 {
+		return DefaultFetcherMinInterval
+	}
+	return f.MinInterval
+}
@@ -0,0 +1,97 @@
+package jwk_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	. "github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type jwksServer struct {
+	sync.Mutex
+	keys         *jose.JsonWebKeySet
+	cacheControl string
+	fail         bool
+	fetches      int
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.fetches++
+	if s.fail {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Cache-Control", s.cacheControl)
+	json.NewEncoder(w).Encode(s.keys)
+}
+
+func (s *jwksServer) Fetches() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.fetches
+}
+
+func TestCachingFetcher(t *testing.T) {
+	first, err := new(RS256Generator).Generate("1")
+	require.Nil(t, err)
+	second, err := new(RS256Generator).Generate("2")
+	require.Nil(t, err)
+
+	s := &jwksServer{keys: &jose.JsonWebKeySet{Keys: PublicKeys(first.Keys)}, cacheControl: "public, max-age=3600"}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	f := &CachingFetcher{URL: server.URL, MinInterval: time.Millisecond * 50}
+	key, err := f.GetKey("public:1")
+	require.Nil(t, err)
+	assert.Equal(t, "public:1", key.KeyID)
+	assert.Equal(t, 1, s.Fetches())
+
+	// Cached keys and unknown keys right after a fetch do not make the fetcher ask again.
+	_, err = f.GetKey("public:1")
+	require.Nil(t, err)
+	_, err = f.GetKey("public:2")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+	assert.Equal(t, 1, s.Fetches())
+
+	// A rotated key is found once MinInterval passed.
+	s.Lock()
+	s.keys = &jose.JsonWebKeySet{Keys: append(PublicKeys(first.Keys), PublicKeys(second.Keys)...)}
+	s.cacheControl = "no-cache"
+	s.Unlock()
+	time.Sleep(time.Millisecond * 60)
+	key, err = f.GetKey("public:2")
+	require.Nil(t, err)
+	assert.Equal(t, "public:2", key.KeyID)
+	assert.Equal(t, 2, s.Fetches())
+
+	// Keys which must not be cached are refreshed on lookup, the previous keys are served if the refresh fails.
+	s.Lock()
+	s.fail = true
+	s.Unlock()
+	time.Sleep(time.Millisecond * 60)
+	key, err = f.GetKey("public:2")
+	require.Nil(t, err)
+	assert.Equal(t, "public:2", key.KeyID)
+	assert.Equal(t, 3, s.Fetches())
+
+	// Watch retries the failed refresh in the background.
+	ctx, cancel := context.WithCancel(context.Background())
+	f.Watch(ctx)
+	time.Sleep(time.Millisecond * 120)
+	cancel()
+	assert.True(t, s.Fetches() > 3)
+}
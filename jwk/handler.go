@@ -4,11 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/hydra/firewall"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pagination"
+	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/ladon"
 	"github.com/square/go-jose"
 	"golang.org/x/net/context"
@@ -19,6 +24,16 @@ type Handler struct {
 	Generators map[string]KeyGenerator
 	H          herodot.Herodot
 	W          firewall.Firewall
+
+	// Audit receives an event for every change of the key store and every denied access. Auditing is disabled
+	// if Audit is nil.
+	Audit AuditSink
+
+	// Validator checks keys added through UpdateKeySet and UpdateKey. Keys are not validated if Validator is nil.
+	Validator *KeyValidator
+
+	// Preconditions makes the If-Match check and the write atomic, see pkg.Preconditions.
+	Preconditions *pkg.Preconditions
 }
 
 func (h *Handler) GetGenerators() map[string]KeyGenerator {
@@ -39,10 +54,12 @@ func (h *Handler) SetRoutes(r *httprouter.Router) {
 	r.PUT("/keys/:set", h.UpdateKeySet)
 	r.GET("/keys/:set", h.GetKeySet)
 	r.DELETE("/keys/:set", h.DeleteKeySet)
+	r.DELETE("/keys", h.DeleteKeySets)
 
 	r.PUT("/keys/:set/:key", h.UpdateKey)
 	r.GET("/keys/:set/:key", h.GetKey)
 	r.DELETE("/keys/:set/:key", h.DeleteKey)
+	r.POST("/keys/:set/:key/restore", h.RestoreKey)
 
 }
 
@@ -60,18 +77,21 @@ func (h *Handler) DeleteKey(w http.ResponseWriter, r *http.Request, ps httproute
 	var setName = ps.ByName("set")
 	var keyName = ps.ByName("key")
 
-	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+	fctx, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
 		Resource: "rn:hydra:keys:" + setName + ":" + keyName,
 		Action:   "delete",
-	}, "hydra.keys.delete"); err != nil {
+	}, "hydra.keys.delete")
+	if err != nil {
+		h.auditDenied(r, setName, keyName, err)
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
-	if err := h.Manager.DeleteKey(setName, keyName); err != nil {
+	if err := h.write(r, setName, func() error { return h.Manager.DeleteKey(setName, keyName) }); err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
+	h.audit(r, fctx, AuditActionDeleteKey, setName, keyName)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -80,37 +100,93 @@ func (h *Handler) DeleteKeySet(w http.ResponseWriter, r *http.Request, ps httpro
 	var ctx = context.Background()
 	var setName = ps.ByName("set")
 
-	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+	fctx, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
 		Resource: "rn:hydra:keys:" + setName,
 		Action:   "delete",
-	}, "hydra.keys.delete"); err != nil {
+	}, "hydra.keys.delete")
+	if err != nil {
+		h.auditDenied(r, setName, "", err)
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
-	if err := h.Manager.DeleteKeySet(setName); err != nil {
+	if err := h.write(r, setName, func() error { return h.Manager.DeleteKeySet(setName) }); err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
+	h.audit(r, fctx, AuditActionDeleteKeySet, setName, "")
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// DeleteKeySets deletes every key set whose name starts with the query parameter prefix. The response counts the
+// deleted key sets.
+func (h *Handler) DeleteKeySets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = context.Background()
+	var prefix = r.URL.Query().Get("prefix")
+
+	if prefix == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Missing query parameter prefix"))
+		return
+	} else if !pkg.BulkConfirmed(r) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, pkg.ErrBulkNotConfirmed)
+		return
+	}
+
+	names, err := GetKeySetNames(h.Manager)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	// Every set is checked before the first one is deleted, a single denied set aborts the whole deletion.
+	contexts := map[string]*firewall.Context{}
+	for _, set := range names {
+		if !strings.HasPrefix(set, prefix) {
+			continue
+		}
+
+		fctx, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+			Resource: "rn:hydra:keys:" + set,
+			Action:   "delete",
+		}, "hydra.keys.delete")
+		if err != nil {
+			h.auditDenied(r, set, "", err)
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		contexts[set] = fctx
+	}
+
+	for set, fctx := range contexts {
+		if err := h.Manager.DeleteKeySet(set); err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		h.audit(r, fctx, AuditActionDeleteKeySet, set, "")
+	}
+
+	h.H.Write(ctx, w, r, &pkg.BulkDeletion{Deleted: len(contexts)})
+}
+
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var ctx = context.Background()
 	var keyRequest createRequest
 	var set = ps.ByName("set")
 
-	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+	fctx, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
 		Resource: "rn:hydra:keys:" + set,
 		Action:   "create",
-	}, "hydra.keys.create"); err != nil {
+	}, "hydra.keys.create")
+	if err != nil {
+		h.auditDenied(r, set, "", err)
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&keyRequest); err != nil {
 		h.H.WriteError(ctx, w, r, errors.New(err))
+		return
 	}
 
 	generator, found := h.GetGenerators()[keyRequest.Algorithm]
@@ -125,10 +201,12 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
+	rotated := h.keySetExists(set)
 	if err := h.Manager.AddKeySet(set, keys); err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
+	h.auditKeySet(r, fctx, set, keys, rotated)
 
 	h.H.WriteCreated(ctx, w, r, fmt.Sprintf("%s://%s/keys/%s", r.URL.Scheme, r.URL.Host, set), keys)
 }
@@ -139,10 +217,12 @@ func (h *Handler) UpdateKeySet(w http.ResponseWriter, r *http.Request, ps httpro
 	var keySet = new(jose.JsonWebKeySet)
 	var set = ps.ByName("set")
 
-	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+	fctx, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
 		Resource: "rn:hydra:keys:" + set,
 		Action:   "update",
-	}, "hydra.keys.update"); err != nil {
+	}, "hydra.keys.update")
+	if err != nil {
+		h.auditDenied(r, set, "", err)
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
@@ -156,14 +236,27 @@ func (h *Handler) UpdateKeySet(w http.ResponseWriter, r *http.Request, ps httpro
 		key := &jose.JsonWebKey{}
 		if err := key.UnmarshalJSON(request); err != nil {
 			h.H.WriteError(ctx, w, r, errors.New(err))
+			return
 		}
 		keySet.Keys = append(keySet.Keys, *key)
 	}
 
-	if err := h.Manager.AddKeySet(set, keySet); err != nil {
+	if h.Validator != nil {
+		if err := h.Validator.ValidateKeySet(keySet); err != nil {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	var rotated bool
+	if err := h.write(r, set, func() error {
+		rotated = h.keySetExists(set)
+		return h.Manager.AddKeySet(set, keySet)
+	}); err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
+	h.auditKeySet(r, fctx, set, keySet, rotated)
 
 	h.H.Write(ctx, w, r, keySet)
 }
@@ -178,18 +271,28 @@ func (h *Handler) UpdateKey(w http.ResponseWriter, r *http.Request, ps httproute
 		return
 	}
 
-	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+	fctx, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
 		Resource: "rn:hydra:keys:" + set + ":" + key.KeyID,
 		Action:   "update",
-	}, "hydra.keys.update"); err != nil {
+	}, "hydra.keys.update")
+	if err != nil {
+		h.auditDenied(r, set, key.KeyID, err)
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
-	if err := h.Manager.AddKey(set, &key); err != nil {
+	if h.Validator != nil {
+		if err := h.Validator.ValidateKey(&key); err != nil {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := h.write(r, set, func() error { return h.Manager.AddKey(set, &key) }); err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
+	h.audit(r, fctx, AuditActionAddKey, set, key.KeyID)
 
 	h.H.Write(ctx, w, r, key)
 }
@@ -203,11 +306,18 @@ func (h *Handler) GetKey(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		Resource: "rn:hydra:keys:" + setName + ":" + keyName,
 		Action:   "get",
 	}, "hydra.keys.get"); err != nil {
+		h.auditDenied(r, setName, keyName, err)
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
-	keys, err := h.Manager.GetKey(setName, keyName)
+	var keys *jose.JsonWebKeySet
+	var err error
+	if r.URL.Query().Get("consistent") == "true" {
+		keys, err = GetKeyConsistent(h.Manager, setName, keyName)
+	} else {
+		keys, err = h.Manager.GetKey(setName, keyName)
+	}
 	if err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
@@ -220,7 +330,17 @@ func (h *Handler) GetKeySet(w http.ResponseWriter, r *http.Request, ps httproute
 	var ctx = context.Background()
 	var setName = ps.ByName("set")
 
-	keys, err := h.Manager.GetKeySet(setName)
+	var keys *jose.JsonWebKeySet
+	var err error
+	if r.URL.Query().Get("deleted") == "true" {
+		keys, err = h.getDeletedKeySet(setName)
+	} else if thumbprint := r.URL.Query().Get("thumbprint"); thumbprint != "" {
+		keys, err = GetKeyByThumbprint(h.Manager, setName, thumbprint)
+	} else if r.URL.Query().Get("consistent") == "true" {
+		keys, err = GetKeySetConsistent(h.Manager, setName)
+	} else {
+		keys, err = h.Manager.GetKeySet(setName)
+	}
 	if err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
@@ -231,10 +351,154 @@ func (h *Handler) GetKeySet(w http.ResponseWriter, r *http.Request, ps httproute
 			Resource: "rn:hydra:keys:" + setName + ":" + key.KeyID,
 			Action:   "get",
 		}, "hydra.keys.get"); err != nil {
+			h.auditDenied(r, setName, key.KeyID, err)
 			h.H.WriteError(ctx, w, r, err)
 			return
 		}
 	}
 
-	h.H.Write(ctx, w, r, keys)
+	// Keys keep the order of the set unless the caller sorts them.
+	page, err := pagination.Parse(r, "kid", "use", "alg")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	} else if r.URL.Query().Get("sort") == "" {
+		page.Sort = ""
+	}
+
+	indices, total := page.Apply(len(keys.Keys), func(i int, field string) string {
+		switch field {
+		case "kid":
+			return keys.Keys[i].KeyID
+		case "use":
+			return keys.Keys[i].Use
+		case "alg":
+			return keys.Keys[i].Algorithm
+		}
+		return ""
+	})
+
+	res := &jose.JsonWebKeySet{Keys: make([]jose.JsonWebKey, len(indices))}
+	for k, i := range indices {
+		res.Keys[k] = keys.Keys[i]
+	}
+
+	// The entity tag describes the whole set regardless of the page, writes to the set are checked against it.
+	if r.URL.Query().Get("deleted") != "true" && r.URL.Query().Get("thumbprint") == "" {
+		pkg.WriteETag(w, keys)
+	}
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
+}
+
+func (h *Handler) RestoreKey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = context.Background()
+	var setName = ps.ByName("set")
+	var keyName = ps.ByName("key")
+
+	fctx, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:keys:" + setName + ":" + keyName,
+		Action:   "restore",
+	}, "hydra.keys.update")
+	if err != nil {
+		h.auditDenied(r, setName, keyName, err)
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	s, ok := h.Manager.(SoftDeleter)
+	if !ok {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusNotImplemented, errors.New("The key store does not support restoring deleted keys"))
+		return
+	}
+
+	// Restoring is serialized with the other writes to the set, so that no key with the same id is added meanwhile.
+	if err := h.write(r, setName, func() error { return s.RestoreKey(setName, keyName) }); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+	h.audit(r, fctx, AuditActionRestoreKey, setName, keyName)
+
+	keys, err := GetKeyConsistent(h.Manager, setName, keyName)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.WriteCreated(ctx, w, r, "/keys/"+setName+"/"+keyName, keys)
+}
+
+// write calls write if the If-Match header of a write to a key set or one of its keys matches the entity tag of the
+// set, see pkg.Preconditions.
+func (h *Handler) write(r *http.Request, set string, write func() error) error {
+	return h.Preconditions.Write("rn:hydra:keys:"+set, r.Header.Get("If-Match"), func() (interface{}, error) {
+		return h.Manager.GetKeySet(set)
+	}, write)
+}
+
+func (h *Handler) getDeletedKeySet(set string) (*jose.JsonWebKeySet, error) {
+	s, ok := h.Manager.(SoftDeleter)
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return s.GetDeletedKeySet(set)
+}
+
+func (h *Handler) keySetExists(set string) bool {
+	if h.Audit == nil {
+		return false
+	}
+
+	keys, err := h.Manager.GetKeySet(set)
+	return err == nil && len(keys.Keys) > 0
+}
+
+func (h *Handler) auditKeySet(r *http.Request, fctx *firewall.Context, set string, keys *jose.JsonWebKeySet, rotated bool) {
+	if rotated {
+		h.audit(r, fctx, AuditActionRotateKeySet, set, "")
+	}
+	for _, key := range keys.Keys {
+		h.audit(r, fctx, AuditActionAddKey, set, key.KeyID)
+	}
+}
+
+func (h *Handler) audit(r *http.Request, fctx *firewall.Context, action, set, kid string) {
+	if h.Audit == nil {
+		return
+	}
+
+	var actor string
+	if fctx != nil {
+		actor = fctx.Subject
+	}
+
+	h.writeAudit(&AuditEvent{
+		Time:   time.Now().UTC(),
+		Actor:  actor,
+		Action: action,
+		Set:    set,
+		KeyID:  kid,
+		Remote: r.RemoteAddr,
+	})
+}
+
+func (h *Handler) auditDenied(r *http.Request, set, kid string, reason error) {
+	if h.Audit == nil {
+		return
+	}
+
+	h.writeAudit(&AuditEvent{
+		Time:   time.Now().UTC(),
+		Action: AuditActionAccessDenied,
+		Set:    set,
+		KeyID:  kid,
+		Remote: r.RemoteAddr,
+		Reason: reason.Error(),
+	})
+}
+
+func (h *Handler) writeAudit(e *AuditEvent) {
+	if err := h.Audit.Write(e); err != nil {
+		logger.LogError(err)
+	}
 }
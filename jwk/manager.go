@@ -1,6 +1,22 @@
 package jwk
 
-import "github.com/square/go-jose"
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+	"golang.org/x/net/context"
+)
+
+// ErrKeyExists rejects restoring a deleted key while the set holds a key with the same id.
+var ErrKeyExists = &herodot.Error{
+	Err:  errors.New("The key set holds a key with this id, delete it before restoring the deleted one"),
+	Code: http.StatusConflict,
+}
 
 type Manager interface {
 	AddKey(set string, key *jose.JsonWebKey) error
@@ -15,3 +31,110 @@ type Manager interface {
 
 	DeleteKeySet(set string) error
 }
+
+// SoftDeleter is implemented by managers which keep deleted keys as tombstones for a retention window instead
+// of removing them right away. Until they are purged, deleted keys can be restored, so an accidental deletion of a
+// signing key does not instantly invalidate all tokens signed with it.
+type SoftDeleter interface {
+	// RestoreKey restores the most recently deleted key with the given id. It returns ErrKeyExists if the set
+	// holds a key with that id.
+	RestoreKey(set, kid string) error
+
+	// GetDeletedKeySet returns the deleted keys of a set which have not been purged yet.
+	GetDeletedKeySet(set string) (*jose.JsonWebKeySet, error)
+
+	// Purge permanently removes all keys which were deleted before the given time.
+	Purge(before time.Time) error
+}
+
+// ConsistentReader is implemented by managers which serve reads from a local cache. Its methods bypass the cache
+// and read from the backing store directly, which is required when a key was written moments ago, for example
+// right after a rotation, and the changefeed might not have caught up yet.
+type ConsistentReader interface {
+	ConsistentGetKey(set, kid string) (*jose.JsonWebKeySet, error)
+
+	ConsistentGetKeySet(set string) (*jose.JsonWebKeySet, error)
+}
+
+// GetKeyConsistent reads a key from the backing store if the manager supports consistent reads and falls back
+// to GetKey otherwise.
+func GetKeyConsistent(m Manager, set, kid string) (*jose.JsonWebKeySet, error) {
+	if c, ok := m.(ConsistentReader); ok {
+		return c.ConsistentGetKey(set, kid)
+	}
+	return m.GetKey(set, kid)
+}
+
+// GetKeySetConsistent reads a key set from the backing store if the manager supports consistent reads and falls
+// back to GetKeySet otherwise.
+func GetKeySetConsistent(m Manager, set string) (*jose.JsonWebKeySet, error) {
+	if c, ok := m.(ConsistentReader); ok {
+		return c.ConsistentGetKeySet(set)
+	}
+	return m.GetKeySet(set)
+}
+
+// ThumbprintReader is implemented by managers which can look up keys by their RFC 7638 thumbprint. Verifiers use
+// it to resolve keys received in "jwk" headers independently of their key id.
+type ThumbprintReader interface {
+	GetKeyByThumbprint(set, thumbprint string) (*jose.JsonWebKeySet, error)
+}
+
+// GetKeyByThumbprint returns the keys of a set whose thumbprint equals thumbprint.
+func GetKeyByThumbprint(m Manager, set, thumbprint string) (*jose.JsonWebKeySet, error) {
+	if t, ok := m.(ThumbprintReader); ok {
+		return t.GetKeyByThumbprint(set, thumbprint)
+	}
+
+	keys, err := m.GetKeySet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ThumbprintKeys(keys.Keys, thumbprint)
+	if len(result) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return &jose.JsonWebKeySet{Keys: result}, nil
+}
+
+// SetLister is implemented by managers which can list the names of their key sets.
+type SetLister interface {
+	GetKeySetNames() ([]string, error)
+}
+
+// GetKeySetNames returns the names of all key sets or an error if the manager can not list them.
+func GetKeySetNames(m Manager) ([]string, error) {
+	if l, ok := m.(SetLister); ok {
+		return l.GetKeySetNames()
+	}
+	return nil, errors.New("The key store does not support listing key sets")
+}
+
+// PurgeDeletedKeys periodically purges keys whose retention window has expired until ctx is done. Only the
+// leader purges, unless leader is nil. It does nothing if the manager does not support soft deletes or retention
+// is zero.
+func PurgeDeletedKeys(ctx context.Context, m Manager, leader pkg.Leader, retention, interval time.Duration) {
+	s, ok := m.(SoftDeleter)
+	if !ok || retention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if leader != nil && !leader.IsLeader() {
+					continue
+				}
+				if err := s.Purge(time.Now().Add(-retention)); err != nil {
+					logger.LogError(err)
+				}
+			}
+		}
+	}()
+}
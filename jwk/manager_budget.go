@@ -0,0 +1,99 @@
+package jwk
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+)
+
+// BudgetManager fails key lookups which take longer than Budget. Keys change rarely, so instead of failing it
+// serves the result of the last successful lookup if there is one. Writes are not bounded.
+type BudgetManager struct {
+	Manager
+
+	Budget time.Duration
+
+	keys map[string]*jose.JsonWebKeySet
+	sync.RWMutex
+}
+
+func (m *BudgetManager) GetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	return m.lookup("get_key", set+"/"+kid, func() (*jose.JsonWebKeySet, error) {
+		return m.Manager.GetKey(set, kid)
+	})
+}
+
+func (m *BudgetManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	return m.lookup("get_key_set", set, func() (*jose.JsonWebKeySet, error) {
+		return m.Manager.GetKeySet(set)
+	})
+}
+
+func (m *BudgetManager) DeleteKey(set, kid string) error {
+	m.forget(set + "/" + kid)
+	m.forget(set)
+	return m.Manager.DeleteKey(set, kid)
+}
+
+func (m *BudgetManager) DeleteKeySet(set string) error {
+	m.Lock()
+	for id := range m.keys {
+		if id == set || strings.HasPrefix(id, set+"/") {
+			delete(m.keys, id)
+		}
+	}
+	m.Unlock()
+	return m.Manager.DeleteKeySet(set)
+}
+
+// ConsistentGetKey is not bounded, consistent reads are only used when keys were just written.
+func (m *BudgetManager) ConsistentGetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	return GetKeyConsistent(m.Manager, set, kid)
+}
+
+// ConsistentGetKeySet is not bounded, consistent reads are only used when keys were just written.
+func (m *BudgetManager) ConsistentGetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	return GetKeySetConsistent(m.Manager, set)
+}
+
+func (m *BudgetManager) lookup(operation, id string, f func() (*jose.JsonWebKeySet, error)) (*jose.JsonWebKeySet, error) {
+	var keys *jose.JsonWebKeySet
+	err := pkg.WithinBudget("jwk_"+operation, m.Budget, func() (err error) {
+		keys, err = f()
+		return err
+	})
+	if errors.Is(err, pkg.ErrBudgetExceeded) {
+		m.RLock()
+		cached, ok := m.keys[id]
+		m.RUnlock()
+		if ok {
+			logrus.WithField("keys", id).Warn("Key lookup exceeded its latency budget, serving the last known keys.")
+			return cached, nil
+		}
+		return nil, err
+	} else if errors.Is(err, pkg.ErrNotFound) {
+		m.forget(id)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if m.keys == nil {
+		m.keys = map[string]*jose.JsonWebKeySet{}
+	}
+	m.keys[id] = keys
+	return keys, nil
+}
+
+func (m *BudgetManager) forget(id string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.keys, id)
+}
@@ -0,0 +1,47 @@
+package jwk_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	. "github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type slowManager struct {
+	*MemoryManager
+	delay time.Duration
+}
+
+func (m *slowManager) GetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	time.Sleep(m.delay)
+	return m.MemoryManager.GetKey(set, kid)
+}
+
+func TestBudgetManager(t *testing.T) {
+	keys, err := testGenerator.Generate("")
+	require.Nil(t, err)
+
+	slow := &slowManager{MemoryManager: &MemoryManager{}}
+	require.Nil(t, slow.AddKeySet("foo", keys))
+	m := &BudgetManager{Manager: slow, Budget: time.Millisecond * 20}
+
+	_, err = m.GetKey("foo", "private")
+	require.Nil(t, err)
+
+	slow.delay = time.Millisecond * 50
+	got, err := m.GetKey("foo", "private")
+	require.Nil(t, err, "the last known key is served")
+	assert.Equal(t, keys.Key("private"), got.Keys)
+
+	_, err = m.GetKey("foo", "public")
+	assert.True(t, errors.Is(err, pkg.ErrBudgetExceeded), "%s", err)
+
+	require.Nil(t, m.DeleteKey("foo", "private"))
+	_, err = m.GetKey("foo", "private")
+	assert.True(t, errors.Is(err, pkg.ErrBudgetExceeded), "deleted keys are not served: %s", err)
+}
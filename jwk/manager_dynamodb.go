@@ -0,0 +1,290 @@
+package jwk
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+)
+
+const (
+	dynamoKeySetPrefix = "jwk#"
+
+	// dynamoKeySetIndex is the partition of the first index which lists the keys of all sets.
+	dynamoKeySetIndex = "jwk"
+
+	// dynamoKeyTimeFormat has a fixed width, so that keys can be ordered by comparing their creation times.
+	dynamoKeyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+)
+
+// DynamoDBManager stores keys in the DynamoDB table which the DynamoDB backends share, see
+// pkg.CreateDynamoDBTable. The keys of a set share a partition. Like the keys stored in the other backends they are
+// encrypted with the system secret.
+type DynamoDBManager struct {
+	DB    dynamodbiface.DynamoDBAPI
+	Table string
+
+	Cipher *AEAD
+}
+
+type dynamoKey struct {
+	PK        string `dynamodbav:"pk"`
+	SK        string `dynamodbav:"sk"`
+	GSI1PK    string `dynamodbav:"gsi1pk"`
+	GSI1SK    string `dynamodbav:"gsi1sk"`
+	Set       string `dynamodbav:"sid"`
+	KeyData   string `dynamodbav:"keydata"`
+	CreatedAt string `dynamodbav:"created_at"`
+}
+
+// CreateSchemas creates the table unless it exists.
+func (m *DynamoDBManager) CreateSchemas() error {
+	return pkg.CreateDynamoDBTable(m.DB, m.Table)
+}
+
+func (m *DynamoDBManager) AddKey(set string, key *jose.JsonWebKey) error {
+	defer observeOperation("add_key", time.Now())
+	if err := assignKeyID(key); err != nil {
+		return err
+	}
+
+	item, err := m.newItem(set, *key, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.DB.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(m.Table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	}); pkg.IsDynamoDBConditionFailed(err) {
+		return errors.Errorf("Key %s exists already in set %s", key.KeyID, set)
+	} else if err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// AddKeySet adds all keys in one transaction, none of them is stored if one of them exists already. A transaction
+// holds at most pkg.DynamoDBBatchSize keys.
+func (m *DynamoDBManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
+	defer observeOperation("add_key_set", time.Now())
+	if len(keys.Keys) > pkg.DynamoDBBatchSize {
+		return errors.Errorf("Can not add more than %d keys at once", pkg.DynamoDBBatchSize)
+	}
+
+	now := time.Now()
+	var items []*dynamodb.TransactWriteItem
+	for k := range keys.Keys {
+		if err := assignKeyID(&keys.Keys[k]); err != nil {
+			return err
+		}
+
+		// Keys of the same set are ordered by the time they were added, like the keys of the SQL backend.
+		item, err := m.newItem(set, keys.Keys[k], now.Add(time.Duration(k)))
+		if err != nil {
+			return err
+		}
+
+		items = append(items, &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+			TableName:           aws.String(m.Table),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		}})
+	}
+
+	if len(items) == 0 {
+		return nil
+	} else if _, err := m.DB.TransactWriteItems(&dynamodb.TransactWriteItemsInput{TransactItems: items}); pkg.IsDynamoDBTransactionCanceled(err) {
+		return errors.Errorf("Could not add keys to set %s, a key exists already or the set was changed concurrently: %s", set, err)
+	} else if err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *DynamoDBManager) GetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	out, err := m.DB.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(m.Table),
+		Key:            pkg.DynamoDBKey(dynamoKeySetPrefix+set, kid),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, errors.New(err)
+	} else if len(out.Item) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	var k dynamoKey
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &k); err != nil {
+		return nil, errors.New(err)
+	}
+
+	key, err := m.decrypt(&k)
+	if err != nil {
+		return nil, err
+	}
+	return &jose.JsonWebKeySet{Keys: []jose.JsonWebKey{*key}}, nil
+}
+
+func (m *DynamoDBManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	items, err := m.query(set)
+	if err != nil {
+		return nil, err
+	} else if len(items) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	sort.Sort(dynamoKeysByCreation(items))
+	var keys = new(jose.JsonWebKeySet)
+	for _, item := range items {
+		key, err := m.decrypt(item)
+		if err != nil {
+			return nil, err
+		}
+		keys.Keys = append(keys.Keys, *key)
+	}
+	return keys, nil
+}
+
+// GetKeySetNames reads an index and might miss sets which were created moments ago.
+func (m *DynamoDBManager) GetKeySetNames() ([]string, error) {
+	seen := map[string]bool{}
+	names := []string{}
+	var decodeErr error
+	if err := m.DB.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(m.Table),
+		IndexName:              aws.String(pkg.DynamoDBIndex1),
+		KeyConditionExpression: aws.String("gsi1pk = :index"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":index": {S: aws.String(dynamoKeySetIndex)},
+		},
+	}, func(page *dynamodb.QueryOutput, _ bool) bool {
+		for _, item := range page.Items {
+			var k dynamoKey
+			if err := dynamodbattribute.UnmarshalMap(item, &k); err != nil {
+				decodeErr = errors.New(err)
+				return false
+			} else if !seen[k.Set] {
+				seen[k.Set] = true
+				names = append(names, k.Set)
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, errors.New(err)
+	} else if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return names, nil
+}
+
+func (m *DynamoDBManager) DeleteKey(set, kid string) error {
+	defer observeOperation("delete_key", time.Now())
+	if _, err := m.DB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:           aws.String(m.Table),
+		Key:                 pkg.DynamoDBKey(dynamoKeySetPrefix+set, kid),
+		ConditionExpression: aws.String("attribute_exists(pk)"),
+	}); pkg.IsDynamoDBConditionFailed(err) {
+		return errors.New(pkg.ErrNotFound)
+	} else if err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *DynamoDBManager) DeleteKeySet(set string) error {
+	defer observeOperation("delete_key_set", time.Now())
+	items, err := m.query(set)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]map[string]*dynamodb.AttributeValue, len(items))
+	for k, item := range items {
+		keys[k] = pkg.DynamoDBKey(item.PK, item.SK)
+	}
+	return pkg.DeleteDynamoDBItems(m.DB, m.Table, keys)
+}
+
+func (m *DynamoDBManager) newItem(set string, key jose.JsonWebKey, createdAt time.Time) (map[string]*dynamodb.AttributeValue, error) {
+	out, err := canonical.Marshal(key)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	encrypted, err := m.Cipher.Encrypt(out)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	item, err := dynamodbattribute.MarshalMap(&dynamoKey{
+		PK:        dynamoKeySetPrefix + set,
+		SK:        key.KeyID,
+		GSI1PK:    dynamoKeySetIndex,
+		GSI1SK:    set + "#" + key.KeyID,
+		Set:       set,
+		KeyData:   encrypted,
+		CreatedAt: createdAt.UTC().Format(dynamoKeyTimeFormat),
+	})
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return item, nil
+}
+
+// query returns all keys of a set.
+func (m *DynamoDBManager) query(set string) ([]*dynamoKey, error) {
+	var items []*dynamoKey
+	var decodeErr error
+	if err := m.DB.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(m.Table),
+		KeyConditionExpression: aws.String("pk = :set"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":set": {S: aws.String(dynamoKeySetPrefix + set)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}, func(page *dynamodb.QueryOutput, _ bool) bool {
+		for _, item := range page.Items {
+			var k dynamoKey
+			if err := dynamodbattribute.UnmarshalMap(item, &k); err != nil {
+				decodeErr = errors.New(err)
+				return false
+			}
+			items = append(items, &k)
+		}
+		return true
+	}); err != nil {
+		return nil, errors.New(err)
+	} else if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return items, nil
+}
+
+func (m *DynamoDBManager) decrypt(k *dynamoKey) (*jose.JsonWebKey, error) {
+	pt, err := m.Cipher.Decrypt(k.KeyData)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	var key jose.JsonWebKey
+	if err := json.Unmarshal(pt, &key); err != nil {
+		return nil, errors.New(err)
+	}
+	return &key, nil
+}
+
+type dynamoKeysByCreation []*dynamoKey
+
+func (k dynamoKeysByCreation) Len() int           { return len(k) }
+func (k dynamoKeysByCreation) Less(i, j int) bool { return k[i].CreatedAt < k[j].CreatedAt }
+func (k dynamoKeysByCreation) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
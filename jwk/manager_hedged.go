@@ -0,0 +1,41 @@
+package jwk
+
+import (
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+)
+
+// HedgedManager hedges key lookups, which the JWKS and token endpoints perform on every request.
+type HedgedManager struct {
+	Manager
+
+	Hedger *pkg.Hedger
+}
+
+func (m *HedgedManager) GetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	keys, err := m.Hedger.Do("jwk_get_key", func() (interface{}, error) {
+		return m.Manager.GetKey(set, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys.(*jose.JsonWebKeySet), nil
+}
+
+func (m *HedgedManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	keys, err := m.Hedger.Do("jwk_get_key_set", func() (interface{}, error) {
+		return m.Manager.GetKeySet(set)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys.(*jose.JsonWebKeySet), nil
+}
+
+func (m *HedgedManager) ConsistentGetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	return GetKeyConsistent(m.Manager, set, kid)
+}
+
+func (m *HedgedManager) ConsistentGetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	return GetKeySetConsistent(m.Manager, set)
+}
@@ -55,6 +55,20 @@ func (m *HTTPManager) GetKey(set, kid string) (*jose.JsonWebKeySet, error) {
 	return &c, nil
 }
 
+func (m *HTTPManager) GetKeyByThumbprint(set, thumbprint string) (*jose.JsonWebKeySet, error) {
+	var c jose.JsonWebKeySet
+	var u = pkg.JoinURL(m.Endpoint, set)
+	u.RawQuery = url.Values{"thumbprint": {thumbprint}}.Encode()
+
+	var r = pkg.NewSuperAgent(u.String())
+	r.Client = m.Client
+	if err := r.Get(&c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
 func (m *HTTPManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
 	var c jose.JsonWebKeySet
 	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, set).String())
@@ -66,6 +80,61 @@ func (m *HTTPManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
 	return &c, nil
 }
 
+func (m *HTTPManager) ConsistentGetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	var c jose.JsonWebKeySet
+	var r = pkg.NewSuperAgent(consistent(pkg.JoinURL(m.Endpoint, set, kid)).String())
+	r.Client = m.Client
+	if err := r.Get(&c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (m *HTTPManager) ConsistentGetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	var c jose.JsonWebKeySet
+	var r = pkg.NewSuperAgent(consistent(pkg.JoinURL(m.Endpoint, set)).String())
+	r.Client = m.Client
+	if err := r.Get(&c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (m *HTTPManager) RestoreKey(set, kid string) error {
+	var c jose.JsonWebKeySet
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, set, kid, "restore").String())
+	r.Client = m.Client
+	return r.POST(nil, &c)
+}
+
+func (m *HTTPManager) GetDeletedKeySet(set string) (*jose.JsonWebKeySet, error) {
+	var c jose.JsonWebKeySet
+	var u = pkg.JoinURL(m.Endpoint, set)
+	u.RawQuery = url.Values{"deleted": {"true"}}.Encode()
+
+	var r = pkg.NewSuperAgent(u.String())
+	r.Client = m.Client
+	if err := r.Get(&c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func consistent(u *url.URL) *url.URL {
+	q := u.Query()
+	q.Set("consistent", "true")
+	u.RawQuery = q.Encode()
+	return u
+}
+
+// SupportsHedgedReads returns true, every read is a request to the cluster which may be sent twice.
+func (m *HTTPManager) SupportsHedgedReads() bool {
+	return true
+}
+
 func (m *HTTPManager) DeleteKey(set, kid string) error {
 	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, set, kid).String())
 	r.Client = m.Client
@@ -1,7 +1,9 @@
 package jwk
 
 import (
+	"encoding/json"
 	"sync"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/ory-am/hydra/pkg"
@@ -10,10 +12,26 @@ import (
 
 type MemoryManager struct {
 	Keys map[string]*jose.JsonWebKeySet
+
+	// Retention is how long deleted keys are kept before they can be purged. Keys are deleted right away if
+	// Retention is zero.
+	Retention time.Duration
+
+	tombstones map[string][]tombstone
 	sync.RWMutex
 }
 
+type tombstone struct {
+	key       jose.JsonWebKey
+	deletedAt time.Time
+}
+
 func (m *MemoryManager) AddKey(set string, key *jose.JsonWebKey) error {
+	defer observeOperation("add_key", time.Now())
+	if err := assignKeyID(key); err != nil {
+		return err
+	}
+
 	m.Lock()
 	defer m.Unlock()
 
@@ -22,12 +40,16 @@ func (m *MemoryManager) AddKey(set string, key *jose.JsonWebKey) error {
 		m.Keys[set] = &jose.JsonWebKeySet{Keys: []jose.JsonWebKey{}}
 	}
 	m.Keys[set].Keys = append(m.Keys[set].Keys, *key)
+	observeKeys(set, len(m.Keys[set].Keys))
 	return nil
 }
 
 func (m *MemoryManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
-	for _, key := range keys.Keys {
-		m.AddKey(set, &key)
+	defer observeOperation("add_key_set", time.Now())
+	for k := range keys.Keys {
+		if err := m.AddKey(set, &keys.Keys[k]); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -65,35 +87,191 @@ func (m *MemoryManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
 	return keys, nil
 }
 
+func (m *MemoryManager) GetKeyByThumbprint(set, thumbprint string) (*jose.JsonWebKeySet, error) {
+	keys, err := m.GetKeySet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	result := ThumbprintKeys(keys.Keys, thumbprint)
+	if len(result) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	return &jose.JsonWebKeySet{
+		Keys: result,
+	}, nil
+}
+
+func (m *MemoryManager) GetKeySetNames() ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	names := []string{}
+	for set, keys := range m.Keys {
+		if len(keys.Keys) > 0 {
+			names = append(names, set)
+		}
+	}
+	return names, nil
+}
+
 func (m *MemoryManager) DeleteKey(set, kid string) error {
+	defer observeOperation("delete_key", time.Now())
 	keys, err := m.GetKeySet(set)
 	if err != nil {
 		return err
 	}
 
 	m.Lock()
+	defer m.Unlock()
 	var results []jose.JsonWebKey
 	for _, key := range keys.Keys {
 		if key.KeyID != kid {
-			results = append(results)
+			results = append(results, key)
+		} else {
+			m.bury(set, key)
 		}
 	}
 	m.Keys[set].Keys = results
-	defer m.Unlock()
+	observeKeys(set, len(results))
 
 	return nil
 }
 
 func (m *MemoryManager) DeleteKeySet(set string) error {
+	defer observeOperation("delete_key_set", time.Now())
 	m.Lock()
 	defer m.Unlock()
 
+	if keys, ok := m.Keys[set]; ok {
+		for _, key := range keys.Keys {
+			m.bury(set, key)
+		}
+	}
+
 	delete(m.Keys, set)
+	observeKeys(set, 0)
 	return nil
 }
 
+func (m *MemoryManager) RestoreKey(set, kid string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if keys, ok := m.Keys[set]; ok {
+		for _, key := range keys.Keys {
+			if key.KeyID == kid {
+				return errors.New(ErrKeyExists)
+			}
+		}
+	}
+
+	// Tombstones are appended as keys are deleted, so the last one of the key is the newest. Older tombstones of the
+	// same key id are left to be purged.
+	newest := -1
+	for k, t := range m.tombstones[set] {
+		if t.key.KeyID == kid {
+			newest = k
+		}
+	}
+	if newest < 0 {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	tombstones := m.tombstones[set]
+	m.alloc()
+	if m.Keys[set] == nil {
+		m.Keys[set] = &jose.JsonWebKeySet{Keys: []jose.JsonWebKey{}}
+	}
+	m.Keys[set].Keys = append(m.Keys[set].Keys, tombstones[newest].key)
+	m.tombstones[set] = append(append([]tombstone{}, tombstones[:newest]...), tombstones[newest+1:]...)
+	observeKeys(set, len(m.Keys[set].Keys))
+	return nil
+}
+
+func (m *MemoryManager) GetDeletedKeySet(set string) (*jose.JsonWebKeySet, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	tombstones := m.tombstones[set]
+	if len(tombstones) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	keys := &jose.JsonWebKeySet{}
+	for _, t := range tombstones {
+		keys.Keys = append(keys.Keys, t.key)
+	}
+	return keys, nil
+}
+
+func (m *MemoryManager) Purge(before time.Time) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for set, tombstones := range m.tombstones {
+		var remaining []tombstone
+		for _, t := range tombstones {
+			if !t.deletedAt.Before(before) {
+				remaining = append(remaining, t)
+			}
+		}
+
+		if len(remaining) == 0 {
+			delete(m.tombstones, set)
+		} else {
+			m.tombstones[set] = remaining
+		}
+	}
+	return nil
+}
+
+// bury keeps a deleted key if soft deletes are enabled. The caller must hold the lock.
+func (m *MemoryManager) bury(set string, key jose.JsonWebKey) {
+	if m.Retention <= 0 {
+		return
+	}
+
+	if m.tombstones == nil {
+		m.tombstones = map[string][]tombstone{}
+	}
+	m.tombstones[set] = append(m.tombstones[set], tombstone{key: key, deletedAt: time.Now()})
+}
+
 func (m *MemoryManager) alloc() {
 	if m.Keys == nil {
 		m.Keys = make(map[string]*jose.JsonWebKeySet)
 	}
 }
+
+// Snapshot returns all key sets encoded as JSON.
+// Deleted keys which were not purged yet are not part of the snapshot.
+func (m *MemoryManager) Snapshot() ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	out, err := json.Marshal(m.Keys)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return out, nil
+}
+
+// Restore replaces all key sets with the ones of the snapshot.
+func (m *MemoryManager) Restore(snapshot []byte) error {
+	var k map[string]*jose.JsonWebKeySet
+	if err := json.Unmarshal(snapshot, &k); err != nil {
+		return errors.New(err)
+	}
+	if k == nil {
+		k = map[string]*jose.JsonWebKeySet{}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Keys = k
+	return nil
+}
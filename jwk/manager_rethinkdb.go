@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/ory-am/hydra/logger"
 	"github.com/ory-am/hydra/pkg"
 	"github.com/square/go-jose"
 	"golang.org/x/net/context"
@@ -21,7 +23,15 @@ type RethinkManager struct {
 
 	Cipher *AEAD
 
+	// Retention is how long deleted keys are kept before they can be purged. Keys are deleted right away if
+	// Retention is zero.
+	Retention time.Duration
+
 	Keys map[string]jose.JsonWebKeySet
+
+	// Feed streams the changes of Table. It is created by Watch if nil and can be subscribed to by other
+	// subsystems which need to react to key changes.
+	Feed *pkg.ChangeFeed
 }
 
 func (m *RethinkManager) SetUpIndex() error {
@@ -32,6 +42,11 @@ func (m *RethinkManager) SetUpIndex() error {
 }
 
 func (m *RethinkManager) AddKey(set string, key *jose.JsonWebKey) error {
+	defer observeOperation("add_key", time.Now())
+	if err := assignKeyID(key); err != nil {
+		return err
+	}
+
 	if err := m.publishAdd(set, []jose.JsonWebKey{*key}); err != nil {
 		return err
 	}
@@ -39,6 +54,7 @@ func (m *RethinkManager) AddKey(set string, key *jose.JsonWebKey) error {
 }
 
 func (m *RethinkManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
+	defer observeOperation("add_key_set", time.Now())
 	if err := m.publishAdd(set, keys.Keys); err != nil {
 		return err
 	}
@@ -52,10 +68,12 @@ func (m *RethinkManager) GetKey(set, kid string) (*jose.JsonWebKeySet, error) {
 	m.alloc()
 	keys, found := m.Keys[set]
 	if !found {
+		observeCache(false)
 		return nil, errors.New(pkg.ErrNotFound)
 	}
 
 	result := keys.Key(kid)
+	observeCache(len(result) > 0)
 	if len(result) == 0 {
 		return nil, errors.New(pkg.ErrNotFound)
 	}
@@ -71,6 +89,7 @@ func (m *RethinkManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
 
 	m.alloc()
 	keys, found := m.Keys[set]
+	observeCache(found && len(keys.Keys) > 0)
 	if !found {
 		return nil, errors.New(pkg.ErrNotFound)
 	}
@@ -82,7 +101,138 @@ func (m *RethinkManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
 	return &keys, nil
 }
 
+func (m *RethinkManager) GetKeyByThumbprint(set, thumbprint string) (*jose.JsonWebKeySet, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	keys, found := m.Keys[set]
+	observeCache(found)
+	if !found {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	result := ThumbprintKeys(keys.Keys, thumbprint)
+	if len(result) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	return &jose.JsonWebKeySet{
+		Keys: result,
+	}, nil
+}
+
+func (m *RethinkManager) ConsistentGetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	return m.fetch(m.Table.Filter(map[string]interface{}{
+		"set": set,
+		"kid": kid,
+	}).Filter(r.Row.HasFields("deleted_at").Not()))
+}
+
+func (m *RethinkManager) ConsistentGetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	return m.fetch(m.Table.Filter(map[string]interface{}{
+		"set": set,
+	}).Filter(r.Row.HasFields("deleted_at").Not()))
+}
+
+func (m *RethinkManager) GetDeletedKeySet(set string) (*jose.JsonWebKeySet, error) {
+	return m.fetch(m.Table.Filter(map[string]interface{}{
+		"set": set,
+	}).Filter(r.Row.HasFields("deleted_at")))
+}
+
+func (m *RethinkManager) RestoreKey(set, kid string) error {
+	if _, err := m.ConsistentGetKey(set, kid); err == nil {
+		return errors.New(ErrKeyExists)
+	} else if !errors.Is(err, pkg.ErrNotFound) {
+		return err
+	}
+
+	// Only the newest tombstone is restored, older tombstones of the same key id are left to be purged.
+	rows, err := m.Table.Filter(map[string]interface{}{
+		"set": set,
+		"kid": kid,
+	}).Filter(r.Row.HasFields("deleted_at")).OrderBy(r.Desc("deleted_at")).Limit(1).Pluck("id").Run(m.Session)
+	if err != nil {
+		return errors.New(err)
+	}
+	defer rows.Close()
+
+	var newest struct {
+		ID string `gorethink:"id"`
+	}
+	if !rows.Next(&newest) {
+		if rows.Err() != nil {
+			return errors.New(rows.Err())
+		}
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	res, err := m.Table.Get(newest.ID).Replace(func(row r.Term) interface{} {
+		return r.Branch(row.Eq(nil), row, r.Branch(row.HasFields("deleted_at"), row.Without("deleted_at"), row))
+	}).RunWrite(m.Session)
+	if err != nil {
+		return errors.New(err)
+	} else if res.Replaced == 0 {
+		return errors.New(pkg.ErrNotFound)
+	}
+	return nil
+}
+
+func (m *RethinkManager) Purge(before time.Time) error {
+	if _, err := m.Table.Filter(
+		r.Row.HasFields("deleted_at").And(r.Row.Field("deleted_at").Lt(before)),
+	).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) fetch(query r.Term) (*jose.JsonWebKeySet, error) {
+	rows, err := query.Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var keys = new(jose.JsonWebKeySet)
+	var raw rethinkSchema
+	for rows.Next(&raw) {
+		pt, err := m.Cipher.Decrypt(raw.Key)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+
+		var key jose.JsonWebKey
+		if err := json.Unmarshal(pt, &key); err != nil {
+			return nil, errors.New(err)
+		}
+		keys.Keys = append(keys.Keys, key)
+		raw = rethinkSchema{}
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.New(rows.Err())
+	} else if len(keys.Keys) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return keys, nil
+}
+
+func (m *RethinkManager) GetKeySetNames() ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	names := []string{}
+	for set, keys := range m.Keys {
+		if len(keys.Keys) > 0 {
+			names = append(names, set)
+		}
+	}
+	return names, nil
+}
+
 func (m *RethinkManager) DeleteKey(set, kid string) error {
+	defer observeOperation("delete_key", time.Now())
 	keys, err := m.GetKey(set, kid)
 	if err != nil {
 		return errors.New(err)
@@ -95,6 +245,7 @@ func (m *RethinkManager) DeleteKey(set, kid string) error {
 }
 
 func (m *RethinkManager) DeleteKeySet(set string) error {
+	defer observeOperation("delete_key_set", time.Now())
 	if err := m.publishDeleteAll(set); err != nil {
 		return errors.New(err)
 	}
@@ -108,15 +259,23 @@ func (m *RethinkManager) alloc() {
 }
 
 type rethinkSchema struct {
-	KID string `gorethink:"kid"`
-	Set string `gorethink:"set"`
-	Key string `gorethink:"key"`
+	KID       string     `gorethink:"kid"`
+	Set       string     `gorethink:"set"`
+	Key       string     `gorethink:"key"`
+	CreatedAt time.Time  `gorethink:"created_at"`
+	DeletedAt *time.Time `gorethink:"deleted_at,omitempty"`
 }
 
 func (m *RethinkManager) publishAdd(set string, keys []jose.JsonWebKey) error {
 	raws := make([]string, len(keys))
+	for k := range keys {
+		if err := assignKeyID(&keys[k]); err != nil {
+			return err
+		}
+	}
+
 	for k, key := range keys {
-		out, err := json.Marshal(key)
+		out, err := canonical.Marshal(key)
 		if err != nil {
 			return errors.New(err)
 		}
@@ -129,9 +288,10 @@ func (m *RethinkManager) publishAdd(set string, keys []jose.JsonWebKey) error {
 
 	for k, raw := range raws {
 		if _, err := m.Table.Insert(&rethinkSchema{
-			KID: keys[k].KeyID,
-			Set: set,
-			Key: raw,
+			KID:       keys[k].KeyID,
+			Set:       set,
+			Key:       raw,
+			CreatedAt: time.Now().UTC(),
 		}).RunWrite(m.Session); err != nil {
 			return errors.New(err)
 		}
@@ -140,9 +300,19 @@ func (m *RethinkManager) publishAdd(set string, keys []jose.JsonWebKey) error {
 	return nil
 }
 func (m *RethinkManager) publishDeleteAll(set string) error {
-	if err := m.Table.Filter(map[string]interface{}{
+	query := m.Table.Filter(map[string]interface{}{
 		"set": set,
-	}).Delete().Exec(m.Session); err != nil {
+	})
+
+	if m.Retention > 0 {
+		query = query.Filter(r.Row.HasFields("deleted_at").Not()).Update(map[string]interface{}{
+			"deleted_at": time.Now().UTC(),
+		})
+	} else {
+		query = query.Delete()
+	}
+
+	if err := query.Exec(m.Session); err != nil {
 		return errors.New(err)
 	}
 	return nil
@@ -150,10 +320,20 @@ func (m *RethinkManager) publishDeleteAll(set string) error {
 
 func (m *RethinkManager) publishDelete(set string, keys []jose.JsonWebKey) error {
 	for _, key := range keys {
-		if _, err := m.Table.Filter(map[string]interface{}{
+		query := m.Table.Filter(map[string]interface{}{
 			"kid": key.KeyID,
 			"set": set,
-		}).Delete().RunWrite(m.Session); err != nil {
+		})
+
+		if m.Retention > 0 {
+			query = query.Filter(r.Row.HasFields("deleted_at").Not()).Update(map[string]interface{}{
+				"deleted_at": time.Now().UTC(),
+			})
+		} else {
+			query = query.Delete()
+		}
+
+		if _, err := query.RunWrite(m.Session); err != nil {
 			return errors.New(err)
 		}
 	}
@@ -161,54 +341,63 @@ func (m *RethinkManager) publishDelete(set string, keys []jose.JsonWebKey) error
 }
 
 func (m *RethinkManager) Watch(ctx context.Context) {
-	go pkg.Retry(time.Second*15, time.Minute, func() error {
-		connections, err := m.Table.Changes().Run(m.Session)
-		if err != nil {
-			return errors.New(err)
+	if m.Feed == nil {
+		m.Feed = &pkg.ChangeFeed{Session: m.Session, Table: m.Table}
+	}
+
+	var connects int
+	m.Feed.Subscribe(func() error {
+		if connects > 0 {
+			changefeedReconnects.Inc()
 		}
-		defer connections.Close()
-
-		var update map[string]*rethinkSchema
-		for connections.Next(&update) {
-			newVal := update["new_val"]
-			oldVal := update["old_val"]
-			m.Lock()
-			if newVal == nil && oldVal != nil {
-				m.watcherRemove(oldVal)
-			} else if newVal != nil && oldVal != nil {
-				m.watcherRemove(oldVal)
-				m.watcherInsert(newVal)
-			} else {
-				m.watcherInsert(newVal)
-			}
-			m.Unlock()
+		connects++
+		return m.ColdStart()
+	}, func(change *pkg.Change) error {
+		var newVal, oldVal *rethinkSchema
+		if err := change.Decode(&oldVal, &newVal); err != nil {
+			return err
 		}
 
-		if connections.Err() != nil {
-			err = errors.New(connections.Err())
-			pkg.LogError(err)
-			return err
+		m.Lock()
+		defer m.Unlock()
+		if oldVal != nil {
+			m.watcherRemove(oldVal)
+		}
+		if newVal != nil {
+			m.watcherInsert(newVal)
+		}
+
+		if oldVal == nil && newVal != nil {
+			observeChange(newVal.CreatedAt)
+		} else {
+			observeChange(time.Time{})
 		}
 		return nil
 	})
+	m.Feed.Start(ctx)
 }
 
 func (m *RethinkManager) watcherInsert(val *rethinkSchema) {
+	if val.DeletedAt != nil {
+		return
+	}
+
 	var c jose.JsonWebKey
 	key, err := m.Cipher.Decrypt(val.Key)
 	if err != nil {
-		pkg.LogError(errors.New(err))
+		logger.LogError(errors.New(err))
 		return
 	}
 
 	if err := json.Unmarshal(key, &c); err != nil {
-		pkg.LogError(errors.New(err))
+		logger.LogError(errors.New(err))
 		return
 	}
 
 	keys := m.Keys[val.Set]
 	keys.Keys = append(keys.Keys, c)
 	m.Keys[val.Set] = keys
+	observeKeys(val.Set, len(keys.Keys))
 }
 
 func (m *RethinkManager) watcherRemove(val *rethinkSchema) {
@@ -221,37 +410,50 @@ func (m *RethinkManager) watcherRemove(val *rethinkSchema) {
 		return k.KeyID != val.KID
 	})
 	m.Keys[val.Set] = keys
+	observeKeys(val.Set, len(keys.Keys))
 }
 
 func (m *RethinkManager) ColdStart() error {
-	m.Keys = map[string]jose.JsonWebKeySet{}
-	clients, err := m.Table.Run(m.Session)
+	rows, err := m.Table.Run(m.Session)
 	if err != nil {
 		return errors.New(err)
 	}
+	defer rows.Close()
 
+	sets := map[string]jose.JsonWebKeySet{}
 	var raw *rethinkSchema
-	var key jose.JsonWebKey
-	m.Lock()
-	defer m.Unlock()
-	for clients.Next(&raw) {
+	for rows.Next(&raw) {
+		if raw.DeletedAt != nil {
+			raw = nil
+			continue
+		}
+
 		pt, err := m.Cipher.Decrypt(raw.Key)
 		if err != nil {
 			return errors.New(err)
 		}
 
+		var key jose.JsonWebKey
 		if err := json.Unmarshal(pt, &key); err != nil {
 			return errors.New(err)
 		}
 
-		keys, ok := m.Keys[raw.Set]
-		if !ok {
-			keys = jose.JsonWebKeySet{}
-		}
+		keys := sets[raw.Set]
 		keys.Keys = append(keys.Keys, key)
-		m.Keys[raw.Set] = keys
+		sets[raw.Set] = keys
+		raw = nil
+	}
+
+	if rows.Err() != nil {
+		return errors.New(rows.Err())
 	}
 
+	m.Lock()
+	defer m.Unlock()
+	m.Keys = sets
+	for set, keys := range m.Keys {
+		observeKeys(set, len(keys.Keys))
+	}
 	return nil
 }
 
@@ -0,0 +1,210 @@
+package jwk
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+)
+
+// sqlSchemas create the key table. Keys are encrypted with the system secret, like the keys stored in RethinkDB,
+// so that they can be copied between both stores without being decrypted by the database.
+var sqlSchemas = map[string][]string{
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS hydra_jwk (
+	sid        varchar(255) NOT NULL,
+	kid        varchar(255) NOT NULL,
+	keydata    text NOT NULL,
+	created_at timestamp NOT NULL,
+	PRIMARY KEY (sid, kid)
+)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS hydra_jwk (
+	sid        varchar(255) NOT NULL,
+	kid        varchar(255) NOT NULL,
+	keydata    text NOT NULL,
+	created_at datetime NOT NULL,
+	PRIMARY KEY (sid, kid)
+)`,
+	},
+}
+
+// migrations are the versions of the key table.
+var migrations = []*pkg.Migration{
+	{
+		ID: "1",
+		Up: sqlSchemas,
+		Down: map[string][]string{
+			"postgres": {`DROP TABLE IF EXISTS hydra_jwk`},
+			"mysql":    {`DROP TABLE IF EXISTS hydra_jwk`},
+		},
+	},
+}
+
+// SQLManager stores keys in PostgreSQL or MySQL. Unlike RethinkManager it does not cache keys and deletes them
+// right away.
+type SQLManager struct {
+	DB *sql.DB
+
+	// Driver is the name of the database/sql driver, either "postgres" or "mysql".
+	Driver string
+
+	Cipher *AEAD
+}
+
+// Migrator returns the migrator of the key table.
+func (m *SQLManager) Migrator() *pkg.Migrator {
+	return &pkg.Migrator{DB: m.DB, Driver: m.Driver, Module: "jwk", Migrations: migrations}
+}
+
+// CreateSchemas applies all migrations of the key table which were not applied yet.
+func (m *SQLManager) CreateSchemas() error {
+	_, err := m.Migrator().Up()
+	return err
+}
+
+func (m *SQLManager) AddKey(set string, key *jose.JsonWebKey) error {
+	defer observeOperation("add_key", time.Now())
+	if err := assignKeyID(key); err != nil {
+		return err
+	}
+	return m.add(set, []jose.JsonWebKey{*key})
+}
+
+func (m *SQLManager) AddKeySet(set string, keys *jose.JsonWebKeySet) error {
+	defer observeOperation("add_key_set", time.Now())
+	for k := range keys.Keys {
+		if err := assignKeyID(&keys.Keys[k]); err != nil {
+			return err
+		}
+	}
+	return m.add(set, keys.Keys)
+}
+
+func (m *SQLManager) GetKey(set, kid string) (*jose.JsonWebKeySet, error) {
+	return m.fetch(m.rebind("SELECT keydata FROM hydra_jwk WHERE sid = ? AND kid = ?"), set, kid)
+}
+
+func (m *SQLManager) GetKeySet(set string) (*jose.JsonWebKeySet, error) {
+	return m.fetch(m.rebind("SELECT keydata FROM hydra_jwk WHERE sid = ? ORDER BY created_at"), set)
+}
+
+func (m *SQLManager) GetKeySetNames() ([]string, error) {
+	rows, err := m.DB.Query("SELECT DISTINCT sid FROM hydra_jwk")
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.New(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	}
+	return names, nil
+}
+
+func (m *SQLManager) DeleteKey(set, kid string) error {
+	defer observeOperation("delete_key", time.Now())
+	res, err := m.DB.Exec(m.rebind("DELETE FROM hydra_jwk WHERE sid = ? AND kid = ?"), set, kid)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.New(err)
+	} else if n == 0 {
+		return errors.New(pkg.ErrNotFound)
+	}
+	return nil
+}
+
+func (m *SQLManager) DeleteKeySet(set string) error {
+	defer observeOperation("delete_key_set", time.Now())
+	if _, err := m.DB.Exec(m.rebind("DELETE FROM hydra_jwk WHERE sid = ?"), set); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// add inserts all keys in one transaction, none of them is stored if one of them exists already.
+func (m *SQLManager) add(set string, keys []jose.JsonWebKey) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return errors.New(err)
+	}
+
+	for _, key := range keys {
+		out, err := canonical.Marshal(key)
+		if err != nil {
+			tx.Rollback()
+			return errors.New(err)
+		}
+
+		encrypted, err := m.Cipher.Encrypt(out)
+		if err != nil {
+			tx.Rollback()
+			return errors.New(err)
+		}
+
+		if _, err := tx.Exec(m.rebind("INSERT INTO hydra_jwk (sid, kid, keydata, created_at) VALUES (?, ?, ?, ?)"),
+			set, key.KeyID, encrypted, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return errors.New(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) fetch(query string, args ...interface{}) (*jose.JsonWebKeySet, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var keys = new(jose.JsonWebKeySet)
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, errors.New(err)
+		}
+
+		pt, err := m.Cipher.Decrypt(raw)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+
+		var key jose.JsonWebKey
+		if err := json.Unmarshal(pt, &key); err != nil {
+			return nil, errors.New(err)
+		}
+		keys.Keys = append(keys.Keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	} else if len(keys.Keys) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return keys, nil
+}
+
+func (m *SQLManager) rebind(query string) string {
+	return pkg.RebindSQL(m.Driver, query)
+}
@@ -1,6 +1,8 @@
 package jwk_test
 
 import (
+	"database/sql"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
@@ -8,11 +10,13 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/integration"
 	"github.com/ory-am/hydra/internal"
 	. "github.com/ory-am/hydra/jwk"
 	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/ladon"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	r "gopkg.in/dancannon/gorethink.v2"
 
 	"log"
@@ -22,7 +26,6 @@ import (
 	"github.com/ory-am/fosite/rand"
 	"github.com/square/go-jose"
 	"golang.org/x/net/context"
-	"gopkg.in/ory-am/dockertest.v2"
 )
 
 var managers = map[string]Manager{}
@@ -65,47 +68,44 @@ func init() {
 var rethinkManager *RethinkManager
 
 func TestMain(m *testing.M) {
-	var session *r.Session
-	var err error
+	session := integration.ConnectToRethinkDB("hydra", "hydra_keys")
+	defer session.Close()
 
-	c, err := dockertest.ConnectToRethinkDB(20, time.Second, func(url string) bool {
-		if session, err = r.Connect(r.ConnectOpts{Address: url, Database: "hydra"}); err != nil {
-			return false
-		} else if _, err = r.DBCreate("hydra").RunWrite(session); err != nil {
-			log.Printf("Database exists: %s", err)
-			return false
-		} else if _, err = r.TableCreate("hydra_keys").RunWrite(session); err != nil {
-			log.Printf("Could not create table: %s", err)
-			return false
-		}
+	key, err := rand.RandomBytes(32)
+	if err != nil {
+		log.Fatalf("Could not generate key: %s", err)
+	}
+	rethinkManager = &RethinkManager{
+		Keys:    map[string]jose.JsonWebKeySet{},
+		Session: session,
+		Table:   r.Table("hydra_keys"),
+		Cipher: &AEAD{
+			Key: key,
+		},
+	}
+	rethinkManager.Watch(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	managers["rethink"] = rethinkManager
 
-		key, err := rand.RandomBytes(32)
-		if err != nil {
-			log.Printf("Could not watch: %s", err)
-			return false
-		}
-		rethinkManager = &RethinkManager{
-			Keys:    map[string]jose.JsonWebKeySet{},
-			Session: session,
-			Table:   r.Table("hydra_keys"),
-			Cipher: &AEAD{
-				Key: key,
-			},
+	for driver, db := range map[string]*sql.DB{
+		"postgres": integration.ConnectToPostgres(),
+		"mysql":    integration.ConnectToMySQL(),
+	} {
+		sm := &SQLManager{DB: db, Driver: driver, Cipher: &AEAD{Key: key}}
+		if err := sm.CreateSchemas(); err != nil {
+			log.Fatalf("Could not create %s schemas: %s", driver, err)
 		}
-		rethinkManager.Watch(context.Background())
-		time.Sleep(100 * time.Millisecond)
-		return true
-	})
-	if session != nil {
-		defer session.Close()
+		managers[driver] = sm
 	}
-	if err != nil {
-		log.Fatalf("Could not connect to database: %s", err)
+
+	dm := &DynamoDBManager{DB: integration.ConnectToDynamoDB(), Table: "hydra", Cipher: &AEAD{Key: key}}
+	if err := dm.CreateSchemas(); err != nil {
+		log.Fatalf("Could not create dynamodb table: %s", err)
 	}
-	managers["rethink"] = rethinkManager
+	managers["dynamodb"] = dm
 
 	retCode := m.Run()
-	c.KillRemove()
+	integration.KillAll()
 	os.Exit(retCode)
 }
 
@@ -151,78 +151,71 @@ func TestColdStartRethinkManager(t *testing.T) {
 }
 
 func TestManagerKey(t *testing.T) {
-	ks, _ := testGenerator.Generate("")
-	priv := ks.Key("private")
-	pub := ks.Key("public")
-
 	for name, m := range managers {
-		t.Logf("Running test %s", name)
-
-		_, err := m.GetKey("faz", "baz")
-		pkg.AssertError(t, true, err, name)
-
-		err = m.AddKey("faz", First(priv))
-		pkg.AssertError(t, false, err, name)
-
-		time.Sleep(time.Millisecond * 100)
-
-		got, err := m.GetKey("faz", "private")
-		pkg.RequireError(t, false, err, name)
-		assert.Equal(t, priv, got.Keys, "%s", name)
-
-		err = m.AddKey("faz", First(pub))
-		pkg.AssertError(t, false, err, name)
-
-		time.Sleep(time.Millisecond * 100)
-
-		got, err = m.GetKey("faz", "private")
-		pkg.RequireError(t, false, err, name)
-		assert.Equal(t, priv, got.Keys, "%s", name)
-
-		got, err = m.GetKey("faz", "public")
-		pkg.RequireError(t, false, err, name)
-		assert.Equal(t, pub, got.Keys, "%s", name)
-
-		err = m.DeleteKey("faz", "public")
-		pkg.AssertError(t, false, err, name)
-
-		time.Sleep(time.Millisecond * 100)
-
-		ks, err = m.GetKey("faz", "public")
-		pkg.AssertError(t, true, err, name)
+		TestHelperManagerKey(t, name, m)
 	}
 
-	err := managers["http"].AddKey("nonono", First(priv))
+	ks, _ := testGenerator.Generate("")
+	err := managers["http"].AddKey("nonono", First(ks.Key("private")))
 	pkg.AssertError(t, true, err, "%s")
 }
 
 func TestManagerKeySet(t *testing.T) {
-	ks, _ := testGenerator.Generate("")
-	ks.Key("private")
-
 	for name, m := range managers {
-		_, err := m.GetKeySet("foo")
-		pkg.AssertError(t, true, err, name)
-
-		err = m.AddKeySet("bar", ks)
-		pkg.AssertError(t, false, err, name)
-
-		time.Sleep(time.Millisecond * 100)
-
-		got, err := m.GetKeySet("bar")
-		pkg.RequireError(t, false, err, name)
-		assert.Equal(t, ks.Key("public"), got.Key("public"), name)
-		assert.Equal(t, ks.Key("private"), got.Key("private"), name)
-
-		err = m.DeleteKeySet("bar")
-		pkg.AssertError(t, false, err, name)
-
-		time.Sleep(time.Millisecond * 100)
-
-		_, err = m.GetKeySet("bar")
-		pkg.AssertError(t, true, err, name)
+		TestHelperManagerKeySet(t, name, m)
 	}
 
+	ks, _ := testGenerator.Generate("")
 	err := managers["http"].AddKeySet("nonono", ks)
 	pkg.AssertError(t, true, err, "%s")
 }
+
+func TestConsistentRead(t *testing.T) {
+	for name, m := range managers {
+		TestHelperConsistentRead(t, name, m)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	m := &MemoryManager{Retention: time.Hour}
+	ks, err := testGenerator.Generate("")
+	pkg.RequireError(t, false, err)
+	pkg.RequireError(t, false, m.AddKeySet("soft", ks))
+
+	pkg.AssertError(t, false, m.DeleteKey("soft", "private"))
+	_, err = m.GetKey("soft", "private")
+	pkg.AssertError(t, true, err)
+
+	deleted, err := m.GetDeletedKeySet("soft")
+	pkg.RequireError(t, false, err)
+	assert.Len(t, deleted.Keys, 1)
+
+	// A deleted key can not be restored while a key with its id is live.
+	other, err := testGenerator.Generate("")
+	pkg.RequireError(t, false, err)
+	pkg.RequireError(t, false, m.AddKey("soft", &other.Key("private")[0]))
+	err = m.RestoreKey("soft", "private")
+	pkg.RequireError(t, true, err)
+	assert.Equal(t, http.StatusConflict, herodot.ToError(err).Code)
+
+	// Only the newest of both deleted keys is restored.
+	pkg.RequireError(t, false, m.DeleteKey("soft", "private"))
+	pkg.AssertError(t, false, m.RestoreKey("soft", "private"))
+	restored, err := m.GetKey("soft", "private")
+	pkg.RequireError(t, false, err)
+	require.Len(t, restored.Keys, 1)
+	assert.Equal(t, other.Key("private")[0].Key, restored.Keys[0].Key)
+	err = m.RestoreKey("soft", "private")
+	pkg.RequireError(t, true, err)
+	assert.Equal(t, http.StatusConflict, herodot.ToError(err).Code)
+
+	pkg.AssertError(t, false, m.DeleteKeySet("soft"))
+	deleted, err = m.GetDeletedKeySet("soft")
+	pkg.RequireError(t, false, err)
+	assert.Len(t, deleted.Keys, 3)
+
+	pkg.AssertError(t, false, m.Purge(time.Now().Add(time.Minute)))
+	_, err = m.GetDeletedKeySet("soft")
+	pkg.AssertError(t, true, err)
+	pkg.AssertError(t, true, m.RestoreKey("soft", "public"))
+}
@@ -0,0 +1,101 @@
+package jwk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+// The TestHelper functions are the conformance suite of Manager. Backends of this repository and downstream
+// backends run them against their managers, see package integration.
+
+// changefeedDelay gives managers which update their cache through a changefeed time to catch up.
+const changefeedDelay = time.Millisecond * 100
+
+func TestHelperManagerKey(t *testing.T, name string, m Manager) {
+	ks, _ := (&RS256Generator{}).Generate("")
+	priv := ks.Key("private")
+	pub := ks.Key("public")
+
+	_, err := m.GetKey("faz", "baz")
+	pkg.AssertError(t, true, err, name)
+
+	err = m.AddKey("faz", First(priv))
+	pkg.AssertError(t, false, err, name)
+
+	time.Sleep(changefeedDelay)
+
+	got, err := m.GetKey("faz", "private")
+	pkg.RequireError(t, false, err, name)
+	assert.Equal(t, priv, got.Keys, "%s", name)
+
+	err = m.AddKey("faz", First(pub))
+	pkg.AssertError(t, false, err, name)
+
+	time.Sleep(changefeedDelay)
+
+	got, err = m.GetKey("faz", "private")
+	pkg.RequireError(t, false, err, name)
+	assert.Equal(t, priv, got.Keys, "%s", name)
+
+	got, err = m.GetKey("faz", "public")
+	pkg.RequireError(t, false, err, name)
+	assert.Equal(t, pub, got.Keys, "%s", name)
+
+	err = m.DeleteKey("faz", "public")
+	pkg.AssertError(t, false, err, name)
+
+	time.Sleep(changefeedDelay)
+
+	_, err = m.GetKey("faz", "public")
+	pkg.AssertError(t, true, err, name)
+}
+
+func TestHelperManagerKeySet(t *testing.T, name string, m Manager) {
+	ks, _ := (&RS256Generator{}).Generate("")
+
+	_, err := m.GetKeySet("foo")
+	pkg.AssertError(t, true, err, name)
+
+	err = m.AddKeySet("bar", ks)
+	pkg.AssertError(t, false, err, name)
+
+	time.Sleep(changefeedDelay)
+
+	got, err := m.GetKeySet("bar")
+	pkg.RequireError(t, false, err, name)
+	assert.Equal(t, ks.Key("public"), got.Key("public"), name)
+	assert.Equal(t, ks.Key("private"), got.Key("private"), name)
+
+	err = m.DeleteKeySet("bar")
+	pkg.AssertError(t, false, err, name)
+
+	time.Sleep(changefeedDelay)
+
+	_, err = m.GetKeySet("bar")
+	pkg.AssertError(t, true, err, name)
+}
+
+func TestHelperConsistentRead(t *testing.T, name string, m Manager) {
+	ks, _ := (&RS256Generator{}).Generate("")
+
+	_, err := GetKeySetConsistent(m, "consistent")
+	pkg.AssertError(t, true, err, name)
+
+	err = m.AddKeySet("consistent", ks)
+	pkg.AssertError(t, false, err, name)
+
+	// No delay, consistent reads must not depend on the changefeed
+	got, err := GetKeyConsistent(m, "consistent", "public")
+	pkg.RequireError(t, false, err, name)
+	assert.Equal(t, ks.Key("public"), got.Keys, "%s", name)
+
+	got, err = GetKeySetConsistent(m, "consistent")
+	pkg.RequireError(t, false, err, name)
+	assert.Len(t, got.Keys, 2, "%s", name)
+
+	err = m.DeleteKeySet("consistent")
+	pkg.AssertError(t, false, err, name)
+}
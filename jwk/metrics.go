@@ -0,0 +1,84 @@
+package jwk
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "hydra",
+		Subsystem: "jwk",
+		Name:      "cache_requests_total",
+		Help:      "Number of key lookups served from the local cache, partitioned by hit or miss.",
+	}, []string{"result"})
+
+	changefeedLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "hydra",
+		Subsystem: "jwk",
+		Name:      "changefeed_lag_seconds",
+		Help:      "Time between a key being written and the change arriving through the changefeed.",
+		Buckets:   []float64{.005, .01, .05, .1, .5, 1, 5, 10, 30},
+	})
+
+	changefeedLastEvent = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "hydra",
+		Subsystem: "jwk",
+		Name:      "changefeed_last_event_timestamp_seconds",
+		Help:      "Unix time of the last change received through the changefeed.",
+	})
+
+	changefeedReconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "hydra",
+		Subsystem: "jwk",
+		Name:      "changefeed_reconnects_total",
+		Help:      "Number of times the changefeed had to be re-established.",
+	})
+
+	keysPerSet = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "hydra",
+		Subsystem: "jwk",
+		Name:      "keys",
+		Help:      "Number of keys per key set.",
+	}, []string{"set"})
+
+	operationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "hydra",
+		Subsystem: "jwk",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of key add and delete operations.",
+	}, []string{"operation"})
+)
+
+func init() {
+	prometheus.MustRegister(cacheRequests, changefeedLag, changefeedLastEvent, changefeedReconnects, keysPerSet, operationDuration)
+}
+
+func observeCache(found bool) {
+	if found {
+		cacheRequests.WithLabelValues("hit").Inc()
+	} else {
+		cacheRequests.WithLabelValues("miss").Inc()
+	}
+}
+
+// observeOperation records the latency of an operation. Use it with defer: defer observeOperation("add_key", time.Now())
+func observeOperation(operation string, start time.Time) {
+	operationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func observeKeys(set string, keys int) {
+	if keys == 0 {
+		keysPerSet.DeleteLabelValues(set)
+		return
+	}
+	keysPerSet.WithLabelValues(set).Set(float64(keys))
+}
+
+func observeChange(written time.Time) {
+	changefeedLastEvent.Set(float64(time.Now().Unix()))
+	if !written.IsZero() {
+		changefeedLag.Observe(time.Since(written).Seconds())
+	}
+}
@@ -0,0 +1,51 @@
+package jwk
+
+import (
+	"testing"
+
+	"github.com/pborman/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func metricValue(t *testing.T, m prometheus.Metric) float64 {
+	var d dto.Metric
+	require.Nil(t, m.Write(&d))
+	if d.Counter != nil {
+		return d.Counter.GetValue()
+	}
+	return d.Gauge.GetValue()
+}
+
+func TestObserveCache(t *testing.T) {
+	hits := metricValue(t, cacheRequests.WithLabelValues("hit"))
+	misses := metricValue(t, cacheRequests.WithLabelValues("miss"))
+
+	observeCache(true)
+	observeCache(false)
+	observeCache(false)
+
+	assert.Equal(t, hits+1, metricValue(t, cacheRequests.WithLabelValues("hit")))
+	assert.Equal(t, misses+2, metricValue(t, cacheRequests.WithLabelValues("miss")))
+}
+
+func TestKeysPerSetGauge(t *testing.T) {
+	m := &MemoryManager{}
+	set := "metrics-" + uuid.New()
+
+	keys, err := new(RS256Generator).Generate("")
+	require.Nil(t, err)
+	require.Nil(t, m.AddKeySet(set, keys))
+	assert.Equal(t, float64(len(keys.Keys)), metricValue(t, keysPerSet.WithLabelValues(set)))
+
+	require.Nil(t, m.DeleteKey(set, keys.Keys[0].KeyID))
+	remaining, err := m.GetKeySet(set)
+	require.Nil(t, err)
+	assert.Equal(t, float64(len(remaining.Keys)), metricValue(t, keysPerSet.WithLabelValues(set)))
+
+	// Deleted sets are removed from the gauge instead of being reported with zero keys.
+	require.Nil(t, m.DeleteKeySet(set))
+	assert.False(t, keysPerSet.DeleteLabelValues(set))
+}
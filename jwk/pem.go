@@ -0,0 +1,98 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/go-errors/errors"
+	"github.com/square/go-jose"
+)
+
+// FromPEM parses the private keys, public keys and certificates of PEM encoded data. A private key yields the key
+// pair with the key ids private and public, a public key or certificate yields the key id public. If id is not
+// empty, it is appended to the key ids like in private:id, as the generators do.
+func FromPEM(data []byte, id string) (*jose.JsonWebKeySet, error) {
+	var keys []jose.JsonWebKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		key, err := parsePEMBlock(block)
+		if err != nil {
+			return nil, err
+		}
+
+		if public := publicKey(key); public != nil {
+			keys = append(keys, jose.JsonWebKey{Key: key, KeyID: ider("private", id)})
+			key = public
+		}
+		keys = append(keys, jose.JsonWebKey{Key: key, KeyID: ider("public", id)})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("No PEM encoded keys found")
+	}
+	return &jose.JsonWebKeySet{Keys: keys}, nil
+}
+
+func parsePEMBlock(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		return key, nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		return key, nil
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, errors.Errorf("PEM block type %s is not supported", block.Type)
+	}
+}
+
+// ToPEM encodes an RSA or ECDSA key in PEM. Symmetric keys can not be encoded.
+func ToPEM(key *jose.JsonWebKey) ([]byte, error) {
+	switch k := key.Key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		block, err := pemBlockForKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(block), nil
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+	default:
+		return nil, errors.Errorf("Key %s of type %T can not be encoded in PEM", key.KeyID, key.Key)
+	}
+}
@@ -0,0 +1,46 @@
+package jwk
+
+import (
+	"testing"
+
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPEM(t *testing.T) {
+	for _, generator := range []KeyGenerator{&RS256Generator{}, &ECDSA256Generator{}} {
+		keys, err := generator.Generate("")
+		require.Nil(t, err)
+
+		private, err := ToPEM(&keys.Key("private")[0])
+		require.Nil(t, err)
+		public, err := ToPEM(&keys.Key("public")[0])
+		require.Nil(t, err)
+
+		parsed, err := FromPEM(private, "imported")
+		require.Nil(t, err)
+		require.Len(t, parsed.Keys, 2, "a private key yields the key pair")
+		assert.Equal(t, thumbprint(t, keys.Key("private")[0]), thumbprint(t, parsed.Key("private:imported")[0]))
+		assert.Equal(t, thumbprint(t, keys.Key("public")[0]), thumbprint(t, parsed.Key("public:imported")[0]))
+
+		parsed, err = FromPEM(public, "")
+		require.Nil(t, err)
+		require.Len(t, parsed.Keys, 1)
+		assert.Equal(t, thumbprint(t, keys.Key("public")[0]), thumbprint(t, parsed.Key("public")[0]))
+	}
+
+	symmetric, err := (&HS256Generator{}).Generate("")
+	require.Nil(t, err)
+	_, err = ToPEM(&symmetric.Keys[0])
+	assert.NotNil(t, err)
+
+	_, err = FromPEM([]byte("no pem"), "")
+	assert.NotNil(t, err)
+}
+
+func thumbprint(t *testing.T, key jose.JsonWebKey) string {
+	tp, err := Thumbprint(&key)
+	require.Nil(t, err)
+	return tp
+}
@@ -0,0 +1,113 @@
+package jwk
+
+import (
+	"crypto/rand"
+	"io"
+
+	"github.com/go-errors/errors"
+)
+
+// The exponent and logarithm tables of GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1 and generator 3.
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		// multiply by the generator 3, that is x * 2 + x
+		double := x << 1
+		if x&0x80 != 0 {
+			double ^= 0x1b
+		}
+		x ^= double
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// SplitSecret splits secret into n shares of which any threshold shares recover it (Shamir's secret sharing). The
+// first byte of a share is its x coordinate, the remaining bytes are the evaluations of one random polynomial per
+// secret byte.
+func SplitSecret(secret []byte, n, threshold int) ([][]byte, error) {
+	if threshold < 2 {
+		return nil, errors.New("The threshold must be at least two")
+	} else if n < threshold {
+		return nil, errors.New("The number of shares must not be smaller than the threshold")
+	} else if n > 255 {
+		return nil, errors.New("A secret can be split into at most 255 shares")
+	} else if len(secret) == 0 {
+		return nil, errors.New("The secret must not be empty")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+	for j, b := range secret {
+		coefficients[0] = b
+		if _, err := io.ReadFull(rand.Reader, coefficients[1:]); err != nil {
+			return nil, errors.New(err)
+		}
+
+		for _, share := range shares {
+			// Horner's method
+			var y byte
+			for k := threshold - 1; k >= 0; k-- {
+				y = gfMul(y, share[0]) ^ coefficients[k]
+			}
+			share[j+1] = y
+		}
+	}
+	return shares, nil
+}
+
+// CombineShares recovers a secret split by SplitSecret. Combining fewer shares than the threshold yields a wrong
+// secret rather than an error, callers need to verify the result.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("At least two shares are required")
+	}
+
+	size := len(shares[0])
+	seen := map[byte]bool{}
+	for _, share := range shares {
+		if len(share) != size || size < 2 {
+			return nil, errors.New("Shares must have the same length")
+		} else if share[0] == 0 || seen[share[0]] {
+			return nil, errors.New("Shares must be distinct")
+		}
+		seen[share[0]] = true
+	}
+
+	secret := make([]byte, size-1)
+	for j := range secret {
+		// Lagrange interpolation at x = 0, subtraction and addition are both xor in GF(2^8).
+		var y byte
+		for i, si := range shares {
+			basis := byte(1)
+			for k, sk := range shares {
+				if i != k {
+					basis = gfMul(basis, gfDiv(sk[0], sk[0]^si[0]))
+				}
+			}
+			y ^= gfMul(si[j+1], basis)
+		}
+		secret[j] = y
+	}
+	return secret, nil
+}
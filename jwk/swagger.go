@@ -0,0 +1,65 @@
+package jwk
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/swagger"
+	"github.com/square/go-jose"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "POST", Path: "/keys/:set", ID: "createKeySet", Tag: "keys", Scope: "hydra.keys.create",
+		Summary:   "Generate a key set",
+		Body:      &createRequest{},
+		Responses: map[int]interface{}{http.StatusCreated: &jose.JsonWebKeySet{}},
+	},
+	{
+		Method: "PUT", Path: "/keys/:set", ID: "updateKeySet", Tag: "keys", Scope: "hydra.keys.update",
+		Summary:   "Replace a key set, unless If-Match names a version which is not the current one",
+		Body:      &joseWebKeySetRequest{},
+		Responses: map[int]interface{}{http.StatusOK: &jose.JsonWebKeySet{}},
+	},
+	{
+		Method: "GET", Path: "/keys/:set", ID: "getKeySet", Tag: "keys", Scope: "hydra.keys.get",
+		Summary:    "Get the keys of a set, the ETag header identifies the version of the set",
+		Query:      []string{"deleted", "thumbprint", "consistent"},
+		Pagination: []string{"kid", "use", "alg"},
+		Responses:  map[int]interface{}{http.StatusOK: &jose.JsonWebKeySet{}},
+	},
+	{
+		Method: "DELETE", Path: "/keys/:set", ID: "deleteKeySet", Tag: "keys", Scope: "hydra.keys.delete",
+		Summary:   "Delete a key set, unless If-Match names a version which is not the current one",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "DELETE", Path: "/keys", ID: "deleteKeySets", Tag: "keys", Scope: "hydra.keys.delete",
+		Summary:   "Delete the key sets whose names start with a prefix",
+		Query:     []string{"prefix", "confirm"},
+		Responses: map[int]interface{}{http.StatusOK: &pkg.BulkDeletion{}},
+	},
+	{
+		Method: "PUT", Path: "/keys/:set/:key", ID: "updateKey", Tag: "keys", Scope: "hydra.keys.update",
+		Summary:   "Add or replace a key of a set",
+		Body:      &jose.JsonWebKey{},
+		Responses: map[int]interface{}{http.StatusOK: &jose.JsonWebKey{}},
+	},
+	{
+		Method: "GET", Path: "/keys/:set/:key", ID: "getKey", Tag: "keys", Scope: "hydra.keys.get",
+		Summary:   "Get a key of a set",
+		Query:     []string{"consistent"},
+		Responses: map[int]interface{}{http.StatusOK: &jose.JsonWebKeySet{}},
+	},
+	{
+		Method: "DELETE", Path: "/keys/:set/:key", ID: "deleteKey", Tag: "keys", Scope: "hydra.keys.delete",
+		Summary:   "Delete a key of a set",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "POST", Path: "/keys/:set/:key/restore", ID: "restoreKey", Tag: "keys", Scope: "hydra.keys.update",
+		Summary:   "Restore a deleted key",
+		Responses: map[int]interface{}{http.StatusCreated: &jose.JsonWebKeySet{}},
+	},
+}
@@ -0,0 +1,100 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/go-errors/errors"
+	"github.com/square/go-jose"
+)
+
+// Thumbprint computes the RFC 7638 SHA-256 thumbprint of a key. The thumbprint of a private key equals the
+// thumbprint of its public key.
+func Thumbprint(key *jose.JsonWebKey) (string, error) {
+	var input string
+	switch k := key.Key.(type) {
+	case *rsa.PrivateKey:
+		input = rsaThumbprintInput(&k.PublicKey)
+	case *rsa.PublicKey:
+		input = rsaThumbprintInput(k)
+	case *ecdsa.PrivateKey:
+		return ecdsaThumbprint(&k.PublicKey)
+	case *ecdsa.PublicKey:
+		return ecdsaThumbprint(k)
+	case []byte:
+		input = fmt.Sprintf(`{"k":"%s","kty":"oct"}`, encode(k))
+	default:
+		return "", errors.Errorf("Can not compute thumbprint of key type %T", key.Key)
+	}
+
+	return hash(input), nil
+}
+
+// ThumbprintKeys returns all keys whose thumbprint equals thumbprint. This usually is the public and the private
+// key of a key pair.
+func ThumbprintKeys(keys []jose.JsonWebKey, thumbprint string) []jose.JsonWebKey {
+	var result []jose.JsonWebKey
+	for _, key := range keys {
+		if tp, err := Thumbprint(&key); err == nil && tp == thumbprint {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// assignKeyID sets the key id to the key's thumbprint if the key has none.
+func assignKeyID(key *jose.JsonWebKey) error {
+	if key.KeyID != "" {
+		return nil
+	}
+
+	tp, err := Thumbprint(key)
+	if err != nil {
+		return err
+	}
+	key.KeyID = tp
+	return nil
+}
+
+func rsaThumbprintInput(k *rsa.PublicKey) string {
+	return fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, encode(big.NewInt(int64(k.E)).Bytes()), encode(k.N.Bytes()))
+}
+
+func ecdsaThumbprint(k *ecdsa.PublicKey) (string, error) {
+	var crv string
+	switch k.Curve.Params().BitSize {
+	case 256:
+		crv = "P-256"
+	case 384:
+		crv = "P-384"
+	case 521:
+		crv = "P-521"
+	default:
+		return "", errors.Errorf("Unsupported elliptic curve %s", k.Curve.Params().Name)
+	}
+
+	size := (k.Curve.Params().BitSize + 7) / 8
+	return hash(fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, crv, encode(pad(k.X.Bytes(), size)), encode(pad(k.Y.Bytes(), size)))), nil
+}
+
+func pad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hash(input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return encode(sum[:])
+}
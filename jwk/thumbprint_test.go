@@ -0,0 +1,49 @@
+package jwk_test
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	. "github.com/ory-am/hydra/jwk"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbprint(t *testing.T) {
+	// Example from RFC 7638, section 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	require.Nil(t, err)
+
+	tp, err := Thumbprint(&jose.JsonWebKey{Key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}})
+	require.Nil(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", tp)
+}
+
+func TestThumbprintKeyIDs(t *testing.T) {
+	for _, g := range []KeyGenerator{&RS256Generator{}, &ECDSA256Generator{}} {
+		keys, err := g.Generate("")
+		require.Nil(t, err)
+
+		private, err := Thumbprint(&keys.Keys[0])
+		require.Nil(t, err)
+		public, err := Thumbprint(&keys.Keys[1])
+		require.Nil(t, err)
+		assert.Equal(t, private, public)
+
+		m := &MemoryManager{}
+		key := keys.Keys[1]
+		key.KeyID = ""
+		require.Nil(t, m.AddKey("foo", &key))
+		assert.Equal(t, public, key.KeyID)
+
+		found, err := GetKeyByThumbprint(m, "foo", public)
+		require.Nil(t, err)
+		assert.Len(t, found.Keys, 1)
+
+		_, err = GetKeyByThumbprint(m, "foo", "not-a-thumbprint")
+		assert.NotNil(t, err)
+	}
+}
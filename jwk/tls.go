@@ -0,0 +1,162 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+	"golang.org/x/net/context"
+)
+
+// TLSCertificate serves the certificate of the HTTP server from the first private key of a key set. If the set
+// does not exist, a key is generated and persisted on the first load, so that all nodes of a cluster share it.
+//
+// The certificate is self-signed and issued whenever the key changes, use GetCertificate in a tls.Config to pick up
+// new keys without restarting the server.
+type TLSCertificate struct {
+	Manager Manager
+	Set     string
+
+	sync.RWMutex
+	cert       *tls.Certificate
+	thumbprint string
+}
+
+// Load returns the current certificate and generates the key set if it does not exist yet.
+func (c *TLSCertificate) Load() (*tls.Certificate, error) {
+	if err := c.Reload(); errors.Is(err, pkg.ErrNotFound) {
+		logger.Default.Warnf("Key for TLS not found in key set %s. Creating new one.", c.Set)
+
+		keys, err := (&ECDSA256Generator{}).Generate("")
+		if err != nil {
+			return nil, err
+		} else if err := c.Manager.AddKeySet(c.Set, keys); err != nil {
+			return nil, err
+		} else if err := c.Reload(); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+	return c.cert, nil
+}
+
+// Reload reads the key set and issues a new certificate if its private key changed.
+func (c *TLSCertificate) Reload() error {
+	keys, err := GetKeySetConsistent(c.Manager, c.Set)
+	if err != nil {
+		return err
+	}
+
+	key := firstPrivateKey(keys.Keys)
+	if key == nil {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	thumbprint, err := Thumbprint(key)
+	if err != nil {
+		return err
+	}
+
+	c.RLock()
+	unchanged := c.cert != nil && c.thumbprint == thumbprint
+	c.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	pemCert, pemKey, err := ToX509PEMKeyPair(key.Key)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.X509KeyPair(pemCert, pemKey)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if c.cert != nil {
+		logger.Default.Infof("Reloaded TLS certificate from key set %s.", c.Set)
+	}
+	c.cert = &cert
+	c.thumbprint = thumbprint
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (c *TLSCertificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.cert == nil {
+		return nil, errors.New("The TLS certificate has not been loaded yet")
+	}
+	return c.cert, nil
+}
+
+// Watch reloads the certificate when the key set changes until ctx is done. Changes are streamed by feed, the
+// changefeed of a RethinkManager, if it is not nil. Otherwise the key set is polled every interval. A failed reload
+// keeps the previous certificate in place.
+func (c *TLSCertificate) Watch(ctx context.Context, feed *pkg.ChangeFeed, interval time.Duration) {
+	if feed != nil {
+		feed.Subscribe(c.reload, func(change *pkg.Change) error {
+			var newVal, oldVal *rethinkSchema
+			if err := change.Decode(&oldVal, &newVal); err != nil {
+				return err
+			}
+
+			if (oldVal != nil && oldVal.Set == c.Set) || (newVal != nil && newVal.Set == c.Set) {
+				return c.reload()
+			}
+			return nil
+		})
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.reload(); err != nil {
+					logger.LogError(err)
+				}
+			}
+		}
+	}()
+}
+
+// reload keeps serving the previous certificate if the key set was deleted.
+func (c *TLSCertificate) reload() error {
+	if err := c.Reload(); errors.Is(err, pkg.ErrNotFound) {
+		logger.Default.Warnf("Key for TLS not found in key set %s, keeping the current certificate.", c.Set)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func firstPrivateKey(keys []jose.JsonWebKey) *jose.JsonWebKey {
+	for k := range keys {
+		switch keys[k].Key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+			return &keys[k]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package jwk_test
+
+import (
+	"testing"
+
+	. "github.com/ory-am/hydra/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSCertificate(t *testing.T) {
+	m := &MemoryManager{}
+	c := &TLSCertificate{Manager: m, Set: "tls"}
+
+	_, err := c.GetCertificate(nil)
+	assert.NotNil(t, err, "no certificate is served before it is loaded")
+
+	first, err := c.Load()
+	require.Nil(t, err)
+	_, err = m.GetKeySet("tls")
+	require.Nil(t, err, "the key set is created on first load")
+
+	got, err := c.GetCertificate(nil)
+	require.Nil(t, err)
+	assert.Equal(t, first, got)
+
+	require.Nil(t, c.Reload())
+	got, _ = c.GetCertificate(nil)
+	assert.Equal(t, first, got, "the certificate is not reissued if the key did not change")
+
+	keys, err := testGenerator.Generate("")
+	require.Nil(t, err)
+	require.Nil(t, m.DeleteKeySet("tls"))
+	require.Nil(t, m.AddKeySet("tls", keys))
+	require.Nil(t, c.Reload())
+	got, _ = c.GetCertificate(nil)
+	assert.NotEqual(t, first, got, "a new key is picked up")
+}
@@ -0,0 +1,217 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"regexp"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/square/go-jose"
+)
+
+const (
+	KeyValidationOff     = "off"
+	KeyValidationDefault = "default"
+	KeyValidationStrict  = "strict"
+)
+
+// keyIDPattern allows the key ids hydra generates (private:foo) as well as RFC 7638 thumbprints.
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,256}$`)
+
+var algorithms = map[string]string{
+	"RS256": "RSA", "RS384": "RSA", "RS512": "RSA",
+	"PS256": "RSA", "PS384": "RSA", "PS512": "RSA",
+	"RSA1_5": "RSA", "RSA-OAEP": "RSA", "RSA-OAEP-256": "RSA",
+	"ES256": "EC", "ES384": "EC", "ES512": "EC",
+	"ECDH-ES": "EC", "ECDH-ES+A128KW": "EC", "ECDH-ES+A192KW": "EC", "ECDH-ES+A256KW": "EC",
+	"HS256": "oct", "HS384": "oct", "HS512": "oct",
+	"A128KW": "oct", "A192KW": "oct", "A256KW": "oct", "dir": "oct",
+}
+
+// curveAlgorithms maps the ECDSA signature algorithms to the curve they require.
+var curveAlgorithms = map[string]string{
+	"ES256": "P-256",
+	"ES384": "P-384",
+	"ES512": "P-521",
+}
+
+// KeyValidator checks keys which are added through the HTTP API, so that malformed keys can not end up in the key
+// sets hydra publishes.
+type KeyValidator struct {
+	// MinRSABits is the minimum modulus size of RSA keys, MinOctetBytes the minimum length of symmetric keys.
+	MinRSABits    int
+	MinOctetBytes int
+
+	// RequireUse and RequireAlgorithm reject keys without use or alg parameter.
+	RequireUse       bool
+	RequireAlgorithm bool
+}
+
+// NewKeyValidator returns the validator for the given strictness. It returns nil if validation is turned off.
+func NewKeyValidator(strictness string) (*KeyValidator, error) {
+	switch strictness {
+	case "", KeyValidationDefault:
+		// The minimums match the keys generated by the RS256 and HS256 generators.
+		return &KeyValidator{MinRSABits: 1024, MinOctetBytes: 12}, nil
+	case KeyValidationStrict:
+		return &KeyValidator{MinRSABits: 2048, MinOctetBytes: 32, RequireUse: true, RequireAlgorithm: true}, nil
+	case KeyValidationOff:
+		return nil, nil
+	}
+	return nil, errors.Errorf("Key validation must be one of %s, %s or %s", KeyValidationOff, KeyValidationDefault, KeyValidationStrict)
+}
+
+// ValidateKeySet validates every key of a key set and makes sure that key pairs named like the ones hydra generates
+// (private:foo and public:foo) belong together.
+func (v *KeyValidator) ValidateKeySet(keys *jose.JsonWebKeySet) error {
+	for i := range keys.Keys {
+		if err := v.ValidateKey(&keys.Keys[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, private := range keys.Keys {
+		if !isPrivate(private.Key) || !strings.HasPrefix(private.KeyID, "private") {
+			continue
+		}
+
+		expected, err := Thumbprint(&private)
+		if err != nil {
+			return err
+		}
+
+		kid := "public" + strings.TrimPrefix(private.KeyID, "private")
+		for _, public := range keys.Key(kid) {
+			if tp, err := Thumbprint(&public); err != nil {
+				return err
+			} else if tp != expected {
+				return errors.Errorf("Key %s is not the public key of %s", kid, private.KeyID)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateKey validates the type, size and parameters of a key.
+func (v *KeyValidator) ValidateKey(key *jose.JsonWebKey) error {
+	if key.KeyID != "" && !keyIDPattern.MatchString(key.KeyID) {
+		return errors.Errorf("Key id %q may only contain letters, digits and the characters . _ : - and must not be longer than 256 characters", key.KeyID)
+	}
+
+	name := key.KeyID
+	if name == "" {
+		name = "without id"
+	}
+
+	kty, err := v.validateMaterial(key.Key)
+	if err != nil {
+		return errors.Errorf("Key %s: %s", name, err)
+	}
+
+	switch key.Use {
+	case "sig", "enc":
+	case "":
+		if v.RequireUse {
+			return errors.Errorf("Key %s: parameter use is required", name)
+		}
+	default:
+		return errors.Errorf("Key %s: use must be sig or enc, not %s", name, key.Use)
+	}
+
+	if key.Algorithm == "" {
+		if v.RequireAlgorithm {
+			return errors.Errorf("Key %s: parameter alg is required", name)
+		}
+		return nil
+	}
+
+	if t, ok := algorithms[key.Algorithm]; !ok {
+		return errors.Errorf("Key %s: algorithm %s is not supported", name, key.Algorithm)
+	} else if t != kty {
+		return errors.Errorf("Key %s: algorithm %s can not be used with %s keys", name, key.Algorithm, kty)
+	}
+
+	if curve, ok := curveAlgorithms[key.Algorithm]; ok && curve != curveName(key.Key) {
+		return errors.Errorf("Key %s: algorithm %s requires curve %s", name, key.Algorithm, curve)
+	}
+	return nil
+}
+
+// validateMaterial checks the key itself and returns its key type.
+func (v *KeyValidator) validateMaterial(key interface{}) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if err := k.Validate(); err != nil {
+			return "", errors.Errorf("private key is inconsistent: %s", err)
+		}
+		return "RSA", v.validateRSA(&k.PublicKey)
+	case *rsa.PublicKey:
+		return "RSA", v.validateRSA(k)
+	case *ecdsa.PrivateKey:
+		if err := validateECDSA(&k.PublicKey); err != nil {
+			return "", err
+		}
+		if x, y := k.Curve.ScalarBaseMult(k.D.Bytes()); x.Cmp(k.X) != 0 || y.Cmp(k.Y) != 0 {
+			return "", errors.New("private key does not match its public key")
+		}
+		return "EC", nil
+	case *ecdsa.PublicKey:
+		return "EC", validateECDSA(k)
+	case []byte:
+		if len(k) < v.MinOctetBytes {
+			return "", errors.Errorf("symmetric keys must be at least %d bytes long", v.MinOctetBytes)
+		}
+		return "oct", nil
+	}
+	return "", errors.Errorf("key type %T is not supported", key)
+}
+
+func (v *KeyValidator) validateRSA(k *rsa.PublicKey) error {
+	if k.N == nil || k.N.BitLen() < v.MinRSABits {
+		return errors.Errorf("RSA keys must be at least %d bits long", v.MinRSABits)
+	} else if k.E < 3 || k.E%2 == 0 {
+		return errors.New("RSA public exponent is invalid")
+	}
+	return nil
+}
+
+func validateECDSA(k *ecdsa.PublicKey) error {
+	if curveName(k) == "" {
+		return errors.New("curve is not supported, use P-256, P-384 or P-521")
+	} else if k.X == nil || k.Y == nil || !k.Curve.IsOnCurve(k.X, k.Y) {
+		return errors.New("point is not on the curve")
+	}
+	return nil
+}
+
+func curveName(key interface{}) string {
+	var curve elliptic.Curve
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		curve = k.Curve
+	case *ecdsa.PublicKey:
+		curve = k.Curve
+	default:
+		return ""
+	}
+
+	switch curve {
+	case elliptic.P256():
+		return "P-256"
+	case elliptic.P384():
+		return "P-384"
+	case elliptic.P521():
+		return "P-521"
+	}
+	return ""
+}
+
+func isPrivate(key interface{}) bool {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		return true
+	}
+	return false
+}
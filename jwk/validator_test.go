@@ -0,0 +1,83 @@
+package jwk_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	. "github.com/ory-am/hydra/jwk"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyValidator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	broken := *rsaKey
+	broken.D = big.NewInt(42)
+	mismatch := *ecKey
+	mismatch.D = big.NewInt(42)
+	offCurve := ecKey.PublicKey
+	offCurve.Y = new(big.Int).Add(offCurve.Y, big.NewInt(1))
+
+	def, err := NewKeyValidator("")
+	require.Nil(t, err)
+	strict, err := NewKeyValidator(KeyValidationStrict)
+	require.Nil(t, err)
+
+	for k, c := range []struct {
+		key       jose.JsonWebKey
+		validator *KeyValidator
+		expectErr bool
+	}{
+		{key: jose.JsonWebKey{Key: rsaKey, KeyID: "private:foo"}, validator: def},
+		{key: jose.JsonWebKey{Key: &rsaKey.PublicKey, KeyID: "public", Use: "sig", Algorithm: "RS256"}, validator: def},
+		{key: jose.JsonWebKey{Key: &ecKey.PublicKey, Use: "sig", Algorithm: "ES256"}, validator: def},
+		{key: jose.JsonWebKey{Key: []byte("0123456789abcdef"), Algorithm: "HS256"}, validator: def},
+		{key: jose.JsonWebKey{Key: &rsaKey.PublicKey, KeyID: "public"}, validator: strict, expectErr: true},
+		{key: jose.JsonWebKey{Key: &rsaKey.PublicKey, KeyID: "public", Use: "sig", Algorithm: "RS256"}, validator: strict, expectErr: true},
+		{key: jose.JsonWebKey{Key: &ecKey.PublicKey, Use: "sig"}, validator: strict, expectErr: true},
+		{key: jose.JsonWebKey{Key: &ecKey.PublicKey, Use: "sig", Algorithm: "ES256"}, validator: strict},
+		{key: jose.JsonWebKey{Key: &rsaKey.PublicKey, KeyID: "with spaces"}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: &rsaKey.PublicKey, Use: "verify"}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: &rsaKey.PublicKey, Algorithm: "ES256"}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: &ecKey.PublicKey, Algorithm: "ES384"}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: &rsaKey.PublicKey, Algorithm: "none"}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: []byte("short")}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: &broken}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: &mismatch}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: &offCurve}, validator: def, expectErr: true},
+		{key: jose.JsonWebKey{Key: "foo"}, validator: def, expectErr: true},
+	} {
+		err := c.validator.ValidateKey(&c.key)
+		assert.Equal(t, c.expectErr, err != nil, "Case %d: %v", k, err)
+	}
+
+	_, err = NewKeyValidator("sloppy")
+	assert.NotNil(t, err)
+
+	off, err := NewKeyValidator(KeyValidationOff)
+	require.Nil(t, err)
+	assert.Nil(t, off)
+}
+
+func TestKeyValidatorKeyPairs(t *testing.T) {
+	keys, err := new(RS256Generator).Generate("foo")
+	require.Nil(t, err)
+
+	v, err := NewKeyValidator(KeyValidationDefault)
+	require.Nil(t, err)
+	require.Nil(t, v.ValidateKeySet(keys))
+
+	other, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(t, err)
+	keys.Keys[1].Key = &other.PublicKey
+	assert.NotNil(t, v.ValidateKeySet(keys))
+}
@@ -0,0 +1,191 @@
+package label
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/pagination"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+)
+
+const (
+	LabelsHandlerPath = "/labels"
+)
+
+const (
+	labelsResource = "rn:hydra:labels:%s"
+	labelResource  = "rn:hydra:labels:%s:%s"
+	scope          = "hydra.labels"
+)
+
+type Handler struct {
+	Manager Manager
+	H       herodot.Herodot
+	W       firewall.Firewall
+
+	// Exists returns pkg.ErrNotFound if the entity of a kind does not exist, labels of entities which do not exist
+	// are rejected. Entities are not looked up if Exists is nil.
+	Exists func(kind, id string) error
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.GET(LabelsHandlerPath+"/:kind", h.Find)
+	r.GET(LabelsHandlerPath+"/:kind/:id", h.Get)
+	r.PUT(LabelsHandlerPath+"/:kind/:id", h.Set)
+	r.DELETE(LabelsHandlerPath+"/:kind/:id", h.Delete)
+}
+
+func (h *Handler) Find(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var kind = ps.ByName("kind")
+	var key = r.URL.Query().Get("key")
+	var value = r.URL.Query().Get("value")
+
+	if !IsKind(kind) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.Errorf("Labels are not supported for %s", kind))
+		return
+	} else if key == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Missing query parameter key"))
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(labelsResource, kind),
+		Action:   "find",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	page, err := pagination.Parse(r, "id")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ids, err := h.Manager.FindIDs(kind, key, value)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	indices, total := page.Apply(len(ids), func(i int, _ string) string {
+		return ids[i]
+	})
+
+	res := make([]string, len(indices))
+	for k, i := range indices {
+		res[k] = ids[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var kind = ps.ByName("kind")
+	var id = ps.ByName("id")
+
+	if !IsKind(kind) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.Errorf("Labels are not supported for %s", kind))
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(labelResource, kind, id),
+		Action:   "get",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	labels, err := h.Manager.GetLabels(kind, id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, labels)
+}
+
+func (h *Handler) Set(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var kind = ps.ByName("kind")
+	var id = ps.ByName("id")
+	var labels Labels
+
+	if !IsKind(kind) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.Errorf("Labels are not supported for %s", kind))
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(labelResource, kind, id),
+		Action:   "update",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&labels); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	for k := range labels {
+		if k == "" {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Label keys must not be empty"))
+			return
+		}
+	}
+
+	if h.Exists != nil {
+		if err := h.Exists(kind, id); errors.Is(err, pkg.ErrNotFound) {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusNotFound, errors.Errorf("Entity %s of kind %s does not exist", id, kind))
+			return
+		} else if err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+	}
+
+	if err := h.Manager.SetLabels(kind, id, labels); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, labels)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = herodot.NewContext()
+	var kind = ps.ByName("kind")
+	var id = ps.ByName("id")
+
+	if !IsKind(kind) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.Errorf("Labels are not supported for %s", kind))
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(labelResource, kind, id),
+		Action:   "delete",
+	}, scope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Manager.DeleteLabels(kind, id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
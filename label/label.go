@@ -0,0 +1,51 @@
+package label
+
+import (
+	"strings"
+)
+
+const (
+	// KindClients identifies labels attached to OAuth2 clients.
+	KindClients = "clients"
+
+	// KindPolicies identifies labels attached to policies.
+	KindPolicies = "policies"
+)
+
+// Kinds lists all entity types which can carry labels.
+var Kinds = []string{KindClients, KindPolicies}
+
+// SelectorPrefix marks a policy subject or resource as a label selector, for example "label:team=payments".
+const SelectorPrefix = "label:"
+
+// Labels are key/value pairs attached to an entity.
+type Labels map[string]string
+
+// IsKind returns true if kind is a known entity type.
+func IsKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Selector returns the selector which matches entities carrying the given label.
+func Selector(key, value string) string {
+	return SelectorPrefix + key + "=" + value
+}
+
+// ParseSelector splits a selector like "label:team=payments" into its key and value. ok is false if s is not
+// a selector.
+func ParseSelector(s string) (key, value string, ok bool) {
+	if !strings.HasPrefix(s, SelectorPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(s, SelectorPrefix), "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
@@ -0,0 +1,16 @@
+package label
+
+// Manager stores labels of clients and policies.
+type Manager interface {
+	// SetLabels replaces the labels of an entity.
+	SetLabels(kind, id string, labels Labels) error
+
+	// GetLabels returns the labels of an entity or pkg.ErrNotFound if it carries none.
+	GetLabels(kind, id string) (Labels, error)
+
+	// DeleteLabels removes all labels of an entity.
+	DeleteLabels(kind, id string) error
+
+	// FindIDs returns the ids of all entities of the given kind which carry the label key=value.
+	FindIDs(kind, key, value string) ([]string, error)
+}
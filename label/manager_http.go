@@ -0,0 +1,48 @@
+package label
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/ory-am/hydra/pkg"
+)
+
+type HTTPManager struct {
+	Endpoint *url.URL
+	Client   *http.Client
+}
+
+func (m *HTTPManager) SetLabels(kind, id string, labels Labels) error {
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, kind, id).String())
+	r.Client = m.Client
+	return r.Update(&labels)
+}
+
+func (m *HTTPManager) GetLabels(kind, id string) (Labels, error) {
+	var labels Labels
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, kind, id).String())
+	r.Client = m.Client
+	if err := r.Get(&labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (m *HTTPManager) DeleteLabels(kind, id string) error {
+	var r = pkg.NewSuperAgent(pkg.JoinURL(m.Endpoint, kind, id).String())
+	r.Client = m.Client
+	return r.Delete()
+}
+
+func (m *HTTPManager) FindIDs(kind, key, value string) ([]string, error) {
+	var ids []string
+	var u = pkg.JoinURL(m.Endpoint, kind)
+	u.RawQuery = url.Values{"key": {key}, "value": {value}}.Encode()
+
+	var r = pkg.NewSuperAgent(u.String())
+	r.Client = m.Client
+	if err := r.Get(&ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
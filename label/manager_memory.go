@@ -0,0 +1,98 @@
+package label
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type MemoryManager struct {
+	Labels map[string]map[string]Labels
+	sync.RWMutex
+}
+
+func NewMemoryManager() *MemoryManager {
+	return &MemoryManager{
+		Labels: map[string]map[string]Labels{},
+	}
+}
+
+func (m *MemoryManager) SetLabels(kind, id string, labels Labels) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.Labels == nil {
+		m.Labels = map[string]map[string]Labels{}
+	}
+	if _, ok := m.Labels[kind]; !ok {
+		m.Labels[kind] = map[string]Labels{}
+	}
+	m.Labels[kind][id] = labels
+	return nil
+}
+
+func (m *MemoryManager) GetLabels(kind, id string) (Labels, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	labels, ok := m.Labels[kind][id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return labels, nil
+}
+
+func (m *MemoryManager) DeleteLabels(kind, id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Labels[kind], id)
+	return nil
+}
+
+func (m *MemoryManager) FindIDs(kind, key, value string) ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return findIDs(m.Labels[kind], key, value), nil
+}
+
+func findIDs(entities map[string]Labels, key, value string) []string {
+	var ids []string
+	for id, labels := range entities {
+		if v, ok := labels[key]; ok && v == value {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Snapshot returns all labels encoded as JSON.
+func (m *MemoryManager) Snapshot() ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	out, err := json.Marshal(m.Labels)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return out, nil
+}
+
+// Restore replaces all labels with the ones of the snapshot.
+func (m *MemoryManager) Restore(snapshot []byte) error {
+	var l map[string]map[string]Labels
+	if err := json.Unmarshal(snapshot, &l); err != nil {
+		return errors.New(err)
+	}
+	if l == nil {
+		l = map[string]map[string]Labels{}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Labels = l
+	return nil
+}
@@ -0,0 +1,133 @@
+package label
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+type rethinkSchema struct {
+	ID     string `gorethink:"id"`
+	Kind   string `gorethink:"kind"`
+	Entity string `gorethink:"entity"`
+	Labels Labels `gorethink:"labels"`
+}
+
+type RethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+
+	Labels map[string]map[string]Labels
+
+	// Feed streams the changes of Table. It is created by Watch if nil and can be subscribed to by other
+	// subsystems which need to react to label changes.
+	Feed *pkg.ChangeFeed
+
+	sync.RWMutex
+}
+
+func documentID(kind, id string) string {
+	return kind + ":" + id
+}
+
+func (m *RethinkManager) SetLabels(kind, id string, labels Labels) error {
+	if _, err := m.Table.Insert(&rethinkSchema{
+		ID:     documentID(kind, id),
+		Kind:   kind,
+		Entity: id,
+		Labels: labels,
+	}, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) GetLabels(kind, id string) (Labels, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	labels, ok := m.Labels[kind][id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return labels, nil
+}
+
+func (m *RethinkManager) DeleteLabels(kind, id string) error {
+	if _, err := m.Table.Get(documentID(kind, id)).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) FindIDs(kind, key, value string) ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return findIDs(m.Labels[kind], key, value), nil
+}
+
+func (m *RethinkManager) ColdStart() error {
+	rows, err := m.Table.Run(m.Session)
+	if err != nil {
+		return errors.New(err)
+	}
+	defer rows.Close()
+
+	var documents []*rethinkSchema
+	var document *rethinkSchema
+	for rows.Next(&document) {
+		documents = append(documents, document)
+		document = nil
+	}
+
+	if rows.Err() != nil {
+		return errors.New(rows.Err())
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Labels = map[string]map[string]Labels{}
+	for _, document := range documents {
+		m.add(document)
+	}
+	return nil
+}
+
+func (m *RethinkManager) add(document *rethinkSchema) {
+	if _, ok := m.Labels[document.Kind]; !ok {
+		m.Labels[document.Kind] = map[string]Labels{}
+	}
+	m.Labels[document.Kind][document.Entity] = document.Labels
+}
+
+func (m *RethinkManager) remove(document *rethinkSchema) {
+	delete(m.Labels[document.Kind], document.Entity)
+}
+
+func (m *RethinkManager) Watch(ctx context.Context) {
+	if m.Feed == nil {
+		m.Feed = &pkg.ChangeFeed{Session: m.Session, Table: m.Table}
+	}
+
+	m.Feed.Subscribe(m.ColdStart, func(change *pkg.Change) error {
+		var newVal, oldVal *rethinkSchema
+		if err := change.Decode(&oldVal, &newVal); err != nil {
+			return err
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		if oldVal != nil {
+			m.remove(oldVal)
+		}
+		if newVal != nil {
+			m.add(newVal)
+		}
+		return nil
+	})
+	m.Feed.Start(ctx)
+}
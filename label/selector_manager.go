@@ -0,0 +1,130 @@
+package label
+
+import (
+	"fmt"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+)
+
+// SelectorManager decorates a ladon.Manager and resolves label selectors in policy subjects and resources when
+// policies are looked up for evaluation. A policy with the subject "label:team=payments" applies to every client
+// labeled team=payments, and the resource "label:team=payments" covers the resource names of all labeled clients
+// and policies, for example "rn:hydra:clients:<id>". Because selectors are resolved on every lookup, labeling a new
+// client is enough to grant it the policies of its team.
+type SelectorManager struct {
+	ladon.Manager
+
+	Labels Manager
+}
+
+var resourceTemplates = map[string]string{
+	KindClients:  "rn:hydra:clients:%s",
+	KindPolicies: "rn:hydra:policies:%s",
+}
+
+func (m *SelectorManager) FindPoliciesForSubject(subject string) (ladon.Policies, error) {
+	policies, err := m.Manager.FindPoliciesForSubject(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := m.Labels.GetLabels(KindClients, subject)
+	if err != nil && !errors.Is(err, pkg.ErrNotFound) {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, p := range policies {
+		seen[p.GetID()] = true
+	}
+
+	for key, value := range labels {
+		selected, err := m.Manager.FindPoliciesForSubject(Selector(key, value))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range selected {
+			if seen[p.GetID()] {
+				continue
+			}
+			seen[p.GetID()] = true
+			subjects := make([]string, len(p.GetSubjects()), len(p.GetSubjects())+1)
+			copy(subjects, p.GetSubjects())
+			policies = append(policies, &selectedPolicy{
+				Policy:   p,
+				subjects: append(subjects, subject),
+			})
+		}
+	}
+
+	for k, p := range policies {
+		resources, err := m.resolveResources(p.GetResources())
+		if err != nil {
+			return nil, err
+		} else if resources == nil {
+			continue
+		}
+
+		if sp, ok := p.(*selectedPolicy); ok {
+			sp.resources = resources
+		} else {
+			policies[k] = &selectedPolicy{Policy: p, resources: resources}
+		}
+	}
+
+	return policies, nil
+}
+
+// resolveResources replaces label selectors with the resource names of the labeled entities. It returns nil if
+// resources contains no selector.
+func (m *SelectorManager) resolveResources(resources []string) ([]string, error) {
+	var resolved = []string{}
+	var found bool
+	for _, resource := range resources {
+		key, value, ok := ParseSelector(resource)
+		if !ok {
+			resolved = append(resolved, resource)
+			continue
+		}
+
+		found = true
+		for _, kind := range Kinds {
+			ids, err := m.Labels.FindIDs(kind, key, value)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				resolved = append(resolved, fmt.Sprintf(resourceTemplates[kind], id))
+			}
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return resolved, nil
+}
+
+type selectedPolicy struct {
+	ladon.Policy
+
+	subjects  []string
+	resources []string
+}
+
+func (p *selectedPolicy) GetSubjects() []string {
+	if p.subjects == nil {
+		return p.Policy.GetSubjects()
+	}
+	return p.subjects
+}
+
+func (p *selectedPolicy) GetResources() []string {
+	if p.resources == nil {
+		return p.Policy.GetResources()
+	}
+	return p.resources
+}
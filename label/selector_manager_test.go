@@ -0,0 +1,61 @@
+package label_test
+
+import (
+	"testing"
+
+	. "github.com/ory-am/hydra/label"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelector(t *testing.T) {
+	for k, c := range []struct {
+		s     string
+		key   string
+		value string
+		ok    bool
+	}{
+		{s: "label:team=payments", key: "team", value: "payments", ok: true},
+		{s: "label:team=", key: "team", value: "", ok: true},
+		{s: "label:=payments"},
+		{s: "label:team"},
+		{s: "team=payments"},
+	} {
+		key, value, ok := ParseSelector(c.s)
+		assert.Equal(t, c.ok, ok, "Case %d", k)
+		assert.Equal(t, c.key, key, "Case %d", k)
+		assert.Equal(t, c.value, value, "Case %d", k)
+	}
+}
+
+func TestSelectorManager(t *testing.T) {
+	labels := NewMemoryManager()
+	require.Nil(t, labels.SetLabels(KindClients, "alice", Labels{"team": "payments"}))
+	require.Nil(t, labels.SetLabels(KindClients, "bob", Labels{"team": "search"}))
+	require.Nil(t, labels.SetLabels(KindClients, "invoices", Labels{"owner": "payments"}))
+
+	w := &ladon.Ladon{
+		Manager: &SelectorManager{
+			Labels: labels,
+			Manager: &ladon.MemoryManager{
+				Policies: map[string]ladon.Policy{
+					"1": &ladon.DefaultPolicy{
+						ID:        "1",
+						Subjects:  []string{Selector("team", "payments")},
+						Resources: []string{Selector("owner", "payments")},
+						Actions:   []string{"get"},
+						Effect:    ladon.AllowAccess,
+					},
+				},
+			},
+		},
+	}
+
+	assert.Nil(t, w.IsAllowed(&ladon.Request{Subject: "alice", Resource: "rn:hydra:clients:invoices", Action: "get"}))
+	assert.NotNil(t, w.IsAllowed(&ladon.Request{Subject: "alice", Resource: "rn:hydra:clients:bob", Action: "get"}))
+	assert.NotNil(t, w.IsAllowed(&ladon.Request{Subject: "bob", Resource: "rn:hydra:clients:invoices", Action: "get"}))
+
+	require.Nil(t, labels.SetLabels(KindClients, "bob", Labels{"team": "payments"}))
+	assert.Nil(t, w.IsAllowed(&ladon.Request{Subject: "bob", Resource: "rn:hydra:clients:invoices", Action: "get"}))
+}
@@ -0,0 +1,34 @@
+package label
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "GET", Path: LabelsHandlerPath + "/:kind", ID: "findLabeled", Tag: "labels", Scope: scope,
+		Summary:    "List the ids of the resources of a kind which carry a label",
+		Query:      []string{"key", "value"},
+		Pagination: []string{"id"},
+		Responses:  map[int]interface{}{http.StatusOK: []string{}},
+	},
+	{
+		Method: "GET", Path: LabelsHandlerPath + "/:kind/:id", ID: "getLabels", Tag: "labels", Scope: scope,
+		Summary:   "Get the labels of a resource",
+		Responses: map[int]interface{}{http.StatusOK: Labels{}},
+	},
+	{
+		Method: "PUT", Path: LabelsHandlerPath + "/:kind/:id", ID: "setLabels", Tag: "labels", Scope: scope,
+		Summary:   "Replace the labels of a resource",
+		Body:      Labels{},
+		Responses: map[int]interface{}{http.StatusOK: Labels{}},
+	},
+	{
+		Method: "DELETE", Path: LabelsHandlerPath + "/:kind/:id", ID: "deleteLabels", Tag: "labels", Scope: scope,
+		Summary:   "Remove all labels of a resource",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+}
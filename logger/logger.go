@@ -0,0 +1,108 @@
+// Package logger writes structured, leveled log lines. Lines which are written while a request is served carry the
+// request id and, once they are known, the subject and client id of the request.
+//
+// Logger is implemented on top of logrus, other logging libraries can be plugged in by implementing it and
+// replacing Default.
+package logger
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+)
+
+// Fields are the structured fields of a log line.
+type Fields map[string]interface{}
+
+// Logger writes leveled log lines with structured fields.
+type Logger interface {
+	WithField(key string, value interface{}) Logger
+	WithFields(fields Fields) Logger
+	WithError(err error) Logger
+
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Default writes the lines which do not belong to a request. It writes to the standard logrus logger, so that its
+// level and format apply to the packages which still use logrus directly.
+var Default Logger = NewLogrusLogger(logrus.StandardLogger())
+
+// Configure sets the level (debug, info, warn or error) and the format (text or json) of the standard logrus
+// logger. Empty values leave the defaults, info and text, in place.
+func Configure(level, format string) error {
+	l := logrus.StandardLogger()
+	if level != "" {
+		lvl, err := logrus.ParseLevel(level)
+		if err != nil {
+			return errors.New(err)
+		}
+		l.Level = lvl
+	}
+
+	switch format {
+	case "", "text":
+		l.Formatter = &logrus.TextFormatter{}
+	case "json":
+		l.Formatter = &logrus.JSONFormatter{}
+	default:
+		return errors.Errorf("Log format %s is not supported, use text or json", format)
+	}
+	return nil
+}
+
+// LogError logs an error which does not belong to a request, including its stack trace if it has one.
+func LogError(err error) {
+	logError(Default, err)
+}
+
+// stackTracer is implemented by errors which carry the stack trace of where they were created.
+type stackTracer interface {
+	ErrorStack() string
+}
+
+func logError(l Logger, err error) {
+	l = l.WithError(err)
+	if e, ok := err.(stackTracer); ok {
+		l = l.WithField("stack", e.ErrorStack())
+	}
+	l.Errorf("Got error.")
+}
+
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+// NewLogrusLogger returns a Logger which writes to l.
+func NewLogrusLogger(l *logrus.Logger) Logger {
+	return &logrusLogger{entry: logrus.NewEntry(l)}
+}
+
+func (l *logrusLogger) WithField(key string, value interface{}) Logger {
+	return &logrusLogger{entry: l.entry.WithField(key, value)}
+}
+
+func (l *logrusLogger) WithFields(fields Fields) Logger {
+	return &logrusLogger{entry: l.entry.WithFields(logrus.Fields(fields))}
+}
+
+func (l *logrusLogger) WithError(err error) Logger {
+	return &logrusLogger{entry: l.entry.WithError(err)}
+}
+
+func (l *logrusLogger) Debugf(format string, args ...interface{}) {
+	l.entry.Debugf(format, args...)
+}
+
+func (l *logrusLogger) Infof(format string, args ...interface{}) {
+	l.entry.Infof(format, args...)
+}
+
+func (l *logrusLogger) Warnf(format string, args ...interface{}) {
+	l.entry.Warnf(format, args...)
+}
+
+func (l *logrusLogger) Errorf(format string, args ...interface{}) {
+	l.entry.Errorf(format, args...)
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	l := logrus.New()
+	l.Out = &out
+	l.Formatter = &logrus.JSONFormatter{}
+	defer func(d Logger) { Default = d }(Default)
+	Default = NewLogrusLogger(l)
+
+	handler := (&Middleware{}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddFields(r, Fields{"subject": "peter", "client_id": "app"})
+		LogRequestError(r, errors.New("foo"))
+	}))
+
+	for k, c := range []struct {
+		header string
+		id     string
+	}{
+		{header: "", id: ""},
+		{header: "from-the-caller", id: "from-the-caller"},
+	} {
+		out.Reset()
+		r, err := http.NewRequest("GET", "http://localhost/clients", nil)
+		require.Nil(t, err)
+		if c.header != "" {
+			r.Header.Set(RequestIDHeader, c.header)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, "", RequestID(r), "%d", k)
+
+		var line map[string]interface{}
+		require.Nil(t, json.Unmarshal(out.Bytes(), &line), "%d", k)
+		assert.Equal(t, w.Header().Get(RequestIDHeader), line["request_id"], "%d", k)
+		if c.id != "" {
+			assert.Equal(t, c.id, line["request_id"], "%d", k)
+		} else {
+			assert.NotEmpty(t, line["request_id"], "%d", k)
+		}
+		assert.Equal(t, "peter", line["subject"], "%d", k)
+		assert.Equal(t, "app", line["client_id"], "%d", k)
+		assert.Equal(t, "error", line["level"], "%d", k)
+		assert.NotEmpty(t, line["stack"], "%d", k)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure("info", "text")
+
+	assert.Nil(t, Configure("debug", "json"))
+	assert.Equal(t, logrus.DebugLevel, logrus.StandardLogger().Level)
+	assert.NotNil(t, Configure("loud", ""))
+	assert.NotNil(t, Configure("", "xml"))
+}
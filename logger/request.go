@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/pborman/uuid"
+)
+
+// RequestIDHeader carries the request id. An id the caller sent is kept, so that log lines can be correlated
+// across services.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the ids which are taken from callers, they end up in every log line of the request.
+const maxRequestIDLength = 128
+
+type requestState struct {
+	id     string
+	fields Fields
+}
+
+var (
+	requests     = map[*http.Request]*requestState{}
+	requestsLock sync.RWMutex
+)
+
+// Middleware assigns an id to every request and returns it in the RequestIDHeader response header. Requests do
+// not carry a context in Go 1.6, so the state of every request which is being served is kept in a registry.
+type Middleware struct{}
+
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.New()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		requestsLock.Lock()
+		requests[r] = &requestState{id: id, fields: Fields{"request_id": id}}
+		requestsLock.Unlock()
+
+		defer func() {
+			requestsLock.Lock()
+			delete(requests, r)
+			requestsLock.Unlock()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestID returns the id of a request which is being served, or an empty string if the request has none.
+func RequestID(r *http.Request) string {
+	requestsLock.RLock()
+	defer requestsLock.RUnlock()
+
+	if s, ok := requests[r]; ok {
+		return s.id
+	}
+	return ""
+}
+
+// AddFields adds fields, for example the subject and client id once the request was authenticated, to all
+// following log lines of a request. It does nothing if the request is not served by the middleware.
+func AddFields(r *http.Request, fields Fields) {
+	requestsLock.Lock()
+	defer requestsLock.Unlock()
+
+	s, ok := requests[r]
+	if !ok {
+		return
+	}
+	for k, v := range fields {
+		s.fields[k] = v
+	}
+}
+
+// FromRequest returns a logger which adds the fields of a request to every line, or Default if the request is not
+// served by the middleware.
+func FromRequest(r *http.Request) Logger {
+	requestsLock.RLock()
+	defer requestsLock.RUnlock()
+
+	s, ok := requests[r]
+	if !ok {
+		return Default
+	}
+
+	fields := Fields{}
+	for k, v := range s.fields {
+		fields[k] = v
+	}
+	return Default.WithFields(fields)
+}
+
+// LogRequestError logs an error which occurred while serving a request, including its stack trace if it has one.
+func LogRequestError(r *http.Request, err error) {
+	logError(FromRequest(r), err)
+}
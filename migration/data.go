@@ -0,0 +1,316 @@
+package migration
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/warden"
+	"github.com/ory-am/ladon"
+	"github.com/square/go-jose"
+)
+
+// DefaultVerifyTimeout is how long a copied item may take to become readable in the target.
+const DefaultVerifyTimeout = 5 * time.Second
+
+// The actions reported to Progress.
+const (
+	ActionCopied      = "copied"
+	ActionSkipped     = "skipped"
+	ActionConflict    = "conflict"
+	ActionOverwritten = "overwritten"
+)
+
+// Store bundles the managers of one backend.
+type Store struct {
+	Keys     jwk.Manager
+	Clients  client.Manager
+	Policies ladon.Manager
+}
+
+// Result counts what happened to the items of one kind.
+type Result struct {
+	Kind        string   `json:"kind"`
+	Copied      int      `json:"copied"`
+	Skipped     int      `json:"skipped"`
+	Overwritten int      `json:"overwritten"`
+	Conflicts   []string `json:"conflicts,omitempty"`
+}
+
+// DataMigrator copies key sets, clients and policies from one store to another.
+//
+// Items which exist in the target already are skipped if they are equal, so an interrupted migration is resumed
+// by running it again. Items which differ are reported as conflicts and left alone, unless Overwrite is set. Every
+// item which was written is read back from the target and compared with the source.
+//
+// Keys are decrypted with the system secret of the source and encrypted again by the target, both stores must be
+// set up with the same secret. Client secrets are copied as they are hashed.
+type DataMigrator struct {
+	From *Store
+	To   *Store
+
+	// Overwrite replaces items which differ in the target.
+	Overwrite bool
+
+	// VerifyTimeout is DefaultVerifyTimeout if zero. Targets which read from a cache need a moment until a write
+	// becomes visible.
+	VerifyTimeout time.Duration
+
+	// Progress is called for every item, it may be nil.
+	Progress func(kind, id, action string)
+}
+
+// Run copies all key sets, clients and policies. It returns the results of the kinds which were migrated so far
+// if it fails.
+func (m *DataMigrator) Run() ([]*Result, error) {
+	var results []*Result
+	for _, step := range []struct {
+		kind    string
+		migrate func(*Result) error
+	}{
+		{kind: "keys", migrate: m.migrateKeys},
+		{kind: "clients", migrate: m.migrateClients},
+		{kind: "policies", migrate: m.migratePolicies},
+	} {
+		result := &Result{Kind: step.kind}
+		results = append(results, result)
+		if err := step.migrate(result); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (m *DataMigrator) migrateKeys(result *Result) error {
+	sets, err := jwk.GetKeySetNames(m.From.Keys)
+	if err != nil {
+		return err
+	}
+
+	for _, set := range sets {
+		keys, err := jwk.GetKeySetConsistent(m.From.Keys, set)
+		if errors.Is(err, pkg.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		existing := map[string]jose.JsonWebKey{}
+		if target, err := jwk.GetKeySetConsistent(m.To.Keys, set); err == nil {
+			for _, key := range target.Keys {
+				existing[key.KeyID] = key
+			}
+		} else if !errors.Is(err, pkg.ErrNotFound) {
+			return err
+		}
+
+		for k := range keys.Keys {
+			key := &keys.Keys[k]
+			id := set + "/" + key.KeyID
+
+			var action = ActionCopied
+			if other, ok := existing[key.KeyID]; ok {
+				if equal, err := canonicalEqual(key, &other); err != nil {
+					return err
+				} else if equal {
+					m.count(result, "keys", id, ActionSkipped)
+					continue
+				} else if !m.Overwrite {
+					m.count(result, "keys", id, ActionConflict)
+					continue
+				}
+
+				if err := m.To.Keys.DeleteKey(set, key.KeyID); err != nil {
+					return err
+				}
+				action = ActionOverwritten
+			}
+
+			if err := m.To.Keys.AddKey(set, key); err != nil {
+				return err
+			}
+			if err := m.verify(id, key, func() (interface{}, error) {
+				got, err := jwk.GetKeyConsistent(m.To.Keys, set, key.KeyID)
+				if err != nil {
+					return nil, err
+				}
+				return jwk.First(got.Keys), nil
+			}); err != nil {
+				return err
+			}
+			m.count(result, "keys", id, action)
+		}
+	}
+	return nil
+}
+
+func (m *DataMigrator) migrateClients(result *Result) error {
+	importer, ok := m.To.Clients.(client.Importer)
+	if !ok {
+		return errors.New("The target client store does not support importing clients")
+	}
+
+	clients, err := m.From.Clients.GetClients()
+	if err != nil {
+		return err
+	}
+	existing, err := m.To.Clients.GetClients()
+	if err != nil {
+		return err
+	}
+
+	for id, c := range clients {
+		var action = ActionCopied
+		if other, ok := existing[id]; ok {
+			if equal, err := canonicalEqual(c, other); err != nil {
+				return err
+			} else if equal {
+				m.count(result, "clients", id, ActionSkipped)
+				continue
+			} else if !m.Overwrite {
+				m.count(result, "clients", id, ActionConflict)
+				continue
+			}
+			action = ActionOverwritten
+		}
+
+		if err := importer.ImportClient(c); err != nil {
+			return err
+		}
+		if err := m.verify(id, c, func() (interface{}, error) {
+			got, err := m.To.Clients.GetClient(id)
+			if err != nil {
+				return nil, err
+			}
+			return got, nil
+		}); err != nil {
+			return err
+		}
+		m.count(result, "clients", id, action)
+	}
+	return nil
+}
+
+func (m *DataMigrator) migratePolicies(result *Result) error {
+	policies, err := warden.ListPolicies(m.From.Policies)
+	if err != nil {
+		return err
+	}
+	targets, err := warden.ListPolicies(m.To.Policies)
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]ladon.Policy{}
+	for _, p := range targets {
+		existing[p.GetID()] = p
+	}
+
+	for _, p := range policies {
+		id := p.GetID()
+		var action = ActionCopied
+		if other, ok := existing[id]; ok {
+			if equal, err := canonicalEqual(toDefaultPolicy(p), toDefaultPolicy(other)); err != nil {
+				return err
+			} else if equal {
+				m.count(result, "policies", id, ActionSkipped)
+				continue
+			} else if !m.Overwrite {
+				m.count(result, "policies", id, ActionConflict)
+				continue
+			}
+
+			if err := m.To.Policies.Delete(id); err != nil {
+				return err
+			}
+			action = ActionOverwritten
+		}
+
+		if err := m.To.Policies.Create(p); err != nil {
+			return err
+		}
+		if err := m.verify(id, toDefaultPolicy(p), func() (interface{}, error) {
+			got, err := m.To.Policies.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			return toDefaultPolicy(got), nil
+		}); err != nil {
+			return err
+		}
+		m.count(result, "policies", id, action)
+	}
+	return nil
+}
+
+// verify reads an item back from the target until it equals want or the timeout expires.
+func (m *DataMigrator) verify(id string, want interface{}, read func() (interface{}, error)) error {
+	timeout := m.VerifyTimeout
+	if timeout <= 0 {
+		timeout = DefaultVerifyTimeout
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		got, err := read()
+		if err == nil {
+			if equal, err := canonicalEqual(want, got); err != nil {
+				return err
+			} else if equal {
+				return nil
+			}
+			err = errors.New("Stored item differs from source")
+		}
+
+		if time.Now().After(deadline) {
+			return errors.Errorf("Could not verify %s: %s", id, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (m *DataMigrator) count(result *Result, kind, id, action string) {
+	switch action {
+	case ActionCopied:
+		result.Copied++
+	case ActionSkipped:
+		result.Skipped++
+	case ActionOverwritten:
+		result.Overwritten++
+	case ActionConflict:
+		result.Conflicts = append(result.Conflicts, id)
+	}
+
+	if m.Progress != nil {
+		m.Progress(kind, id, action)
+	}
+}
+
+func canonicalEqual(a, b interface{}) (bool, error) {
+	ca, err := canonical.Marshal(a)
+	if err != nil {
+		return false, errors.New(err)
+	}
+	cb, err := canonical.Marshal(b)
+	if err != nil {
+		return false, errors.New(err)
+	}
+	return bytes.Equal(ca, cb), nil
+}
+
+// toDefaultPolicy copies a policy, managers return their own types which encode differently.
+func toDefaultPolicy(p ladon.Policy) *ladon.DefaultPolicy {
+	return &ladon.DefaultPolicy{
+		ID:          p.GetID(),
+		Description: p.GetDescription(),
+		Subjects:    p.GetSubjects(),
+		Effect:      p.GetEffect(),
+		Resources:   p.GetResources(),
+		Actions:     p.GetActions(),
+		Conditions:  p.GetConditions(),
+	}
+}
@@ -0,0 +1,67 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/hash"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMemoryStore() *Store {
+	return &Store{
+		Keys:     &jwk.MemoryManager{},
+		Clients:  &client.MemoryManager{Clients: map[string]*fosite.DefaultClient{}, Hasher: &hash.BCrypt{WorkFactor: 4}},
+		Policies: ladon.NewMemoryManager(),
+	}
+}
+
+func TestDataMigrator(t *testing.T) {
+	from, to := newMemoryStore(), newMemoryStore()
+
+	keys, err := (&jwk.HS256Generator{}).Generate("")
+	require.Nil(t, err)
+	require.Nil(t, from.Keys.AddKeySet("tls", keys))
+	require.Nil(t, from.Clients.CreateClient(&fosite.DefaultClient{ID: "app", Secret: []byte("secret")}))
+	require.Nil(t, from.Policies.Create(&ladon.DefaultPolicy{ID: "1", Subjects: []string{"peter"}, Resources: []string{"rn:foo"}, Actions: []string{"get"}, Effect: ladon.AllowAccess}))
+
+	m := &DataMigrator{From: from, To: to}
+	results, err := m.Run()
+	require.Nil(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, len(keys.Keys), results[0].Copied)
+	assert.Equal(t, 1, results[1].Copied)
+	assert.Equal(t, 1, results[2].Copied)
+
+	// The secret is copied as it was hashed, not hashed again.
+	_, err = to.Clients.Authenticate("app", []byte("secret"))
+	assert.Nil(t, err)
+
+	// Running the migration again resumes it, nothing is copied twice.
+	results, err = m.Run()
+	require.Nil(t, err)
+	assert.Equal(t, 0, results[0].Copied+results[1].Copied+results[2].Copied)
+	assert.Equal(t, len(keys.Keys), results[0].Skipped)
+	assert.Equal(t, 1, results[1].Skipped)
+	assert.Equal(t, 1, results[2].Skipped)
+
+	// Items which differ are reported and left alone unless they may be overwritten.
+	require.Nil(t, to.Policies.Delete("1"))
+	require.Nil(t, to.Policies.Create(&ladon.DefaultPolicy{ID: "1", Subjects: []string{"alice"}, Effect: ladon.DenyAccess}))
+	results, err = m.Run()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"1"}, results[2].Conflicts)
+
+	m.Overwrite = true
+	results, err = m.Run()
+	require.Nil(t, err)
+	assert.Equal(t, 1, results[2].Overwritten)
+
+	p, err := to.Policies.Get("1")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"peter"}, p.GetSubjects())
+}
@@ -0,0 +1,172 @@
+package oauth2
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/tracing"
+)
+
+const (
+	AccountHandlerPath = "/oauth2/account"
+)
+
+// AccountHandler lets users see where they are logged in and which applications they allowed to act on their
+// behalf, and revoke either, for example on a "manage your devices" page. It acts for the subject of the access
+// token, which needs the hydra.account scope.
+type AccountHandler struct {
+	LoginSessions      LoginSessionManager
+	RememberedConsents RememberedConsentManager
+	Clients            client.Manager
+
+	// Tokens revokes the tokens an application holds when the user revokes it.
+	Tokens pkg.TokenRevocationStorage
+
+	// Logout notifies the clients of a revoked session through back-channel logout. Clients are not notified if it
+	// is nil.
+	Logout *LogoutHandler
+
+	H herodot.Herodot
+	W firewall.Firewall
+}
+
+// Account is what a user sees on their account page.
+type Account struct {
+	Subject      string                `json:"subject"`
+	Sessions     []*LoginSession       `json:"sessions"`
+	Applications []*AccountApplication `json:"applications"`
+}
+
+// AccountApplication is a client the user remembered their consent for.
+type AccountApplication struct {
+	// ID identifies the consent decision, revoking it makes the application ask for consent again.
+	ID         string    `json:"id"`
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name,omitempty"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (h *AccountHandler) SetRoutes(r *httprouter.Router) {
+	r.GET(AccountHandlerPath, h.Get)
+	r.DELETE(AccountHandlerPath+"/sessions/:id", h.DeleteSession)
+	r.DELETE(AccountHandlerPath+"/applications/:id", h.DeleteApplication)
+}
+
+func (h *AccountHandler) Get(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+	authCtx, err := h.W.HTTPAuthorized(ctx, r, "hydra.account")
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	sessions, err := h.LoginSessions.GetLoginSessions(authCtx.Subject)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	consents, err := h.RememberedConsents.GetRememberedConsents(authCtx.Subject)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	applications := []*AccountApplication{}
+	for _, c := range consents {
+		app := &AccountApplication{
+			ID:        c.ID,
+			ClientID:  c.ClientID,
+			Scopes:    c.Scopes,
+			CreatedAt: c.CreatedAt,
+			ExpiresAt: c.ExpiresAt,
+		}
+
+		if cl, err := h.Clients.GetClient(c.ClientID); err == nil {
+			if dc, ok := cl.(*fosite.DefaultClient); ok {
+				app.ClientName = dc.Name
+			}
+		} else if !errors.Is(err, pkg.ErrNotFound) {
+			logger.LogRequestError(r, err)
+		}
+		applications = append(applications, app)
+	}
+
+	h.H.Write(ctx, w, r, &Account{
+		Subject:      authCtx.Subject,
+		Sessions:     sessions,
+		Applications: applications,
+	})
+}
+
+// DeleteSession ends one of the user's login sessions, which logs the device it was started on out.
+func (h *AccountHandler) DeleteSession(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := herodot.NewContext()
+	authCtx, err := h.W.HTTPAuthorized(ctx, r, "hydra.account")
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	session, err := h.LoginSessions.GetLoginSession(ps.ByName("id"))
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	} else if session.Subject != authCtx.Subject {
+		// Sessions of other users are reported as missing so that their ids can not be probed.
+		h.H.WriteError(ctx, w, r, errors.New(pkg.ErrNotFound))
+		return
+	}
+
+	if err := h.LoginSessions.DeleteLoginSession(session.ID); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if h.Logout != nil {
+		h.Logout.notifyClients(session)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteApplication forgets a remembered consent decision and revokes the access and refresh tokens the application
+// holds on behalf of the user.
+func (h *AccountHandler) DeleteApplication(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := herodot.NewContext()
+	authCtx, err := h.W.HTTPAuthorized(ctx, r, "hydra.account")
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	c, err := h.RememberedConsents.GetRememberedConsent(ps.ByName("id"))
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	} else if c.Subject != authCtx.Subject {
+		h.H.WriteError(ctx, w, r, errors.New(pkg.ErrNotFound))
+		return
+	}
+
+	if err := h.RememberedConsents.ForgetConsent(c.ID); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := h.Tokens.RevokeSubjectTokens(tracing.Context(r), c.Subject, c.ClientID); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
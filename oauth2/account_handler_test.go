@@ -0,0 +1,149 @@
+package oauth2_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	hc "github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/internal"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestAccountHandler(t *testing.T) {
+	clients := &hc.MemoryManager{Clients: map[string]*fosite.DefaultClient{}, Hasher: hasher}
+	require.Nil(t, clients.CreateClient(&fosite.DefaultClient{ID: "photos", Name: "Photos", Secret: []byte("secret")}))
+
+	loginSessions := NewLoginSessionMemoryManager()
+	rememberedConsents := NewRememberedConsentMemoryManager()
+	tokens := &internal.FositeMemoryStore{
+		Manager:        clients,
+		AuthorizeCodes: make(map[string]fosite.Requester),
+		IDSessions:     make(map[string]fosite.Requester),
+		AccessTokens:   make(map[string]fosite.Requester),
+		Implicit:       make(map[string]fosite.Requester),
+		RefreshTokens:  make(map[string]fosite.Requester),
+	}
+
+	now := time.Now().Round(time.Second)
+	for _, s := range []*LoginSession{
+		{ID: "peter-session", Subject: "peter", AuthenticatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: "alice-session", Subject: "alice", AuthenticatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		require.Nil(t, loginSessions.CreateLoginSession(s))
+	}
+	for _, c := range []*RememberedConsent{
+		{ID: "peter-photos", Subject: "peter", ClientID: "photos", Scopes: []string{"photos"}, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: "alice-photos", Subject: "alice", ClientID: "photos", Scopes: []string{"photos"}, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		require.Nil(t, rememberedConsents.RememberConsent(c))
+	}
+
+	ctx := context.Background()
+	issue := func(subject string) (string, string) {
+		request := &fosite.Request{
+			RequestedAt: now,
+			Client:      &fosite.DefaultClient{ID: "photos"},
+			Session:     &Session{Subject: subject},
+		}
+		signatures := pkg.Tokens(3)
+		code, access, refresh := signatures[0][0], signatures[1][0], signatures[2][0]
+		require.Nil(t, tokens.CreateAuthorizeCodeSession(ctx, code, request))
+		require.Nil(t, tokens.PersistAuthorizeCodeGrantSession(ctx, code, access, refresh, request))
+		return access, refresh
+	}
+	peterAccess, peterRefresh := issue("peter")
+	aliceAccess, aliceRefresh := issue("alice")
+
+	serve := func(scopes ...string) (*httptest.Server, *http.Client) {
+		w, httpClient := internal.NewFirewall("hydra", "peter", scopes)
+		h := &AccountHandler{
+			LoginSessions:      loginSessions,
+			RememberedConsents: rememberedConsents,
+			Clients:            clients,
+			Tokens:             tokens,
+			H:                  &herodot.JSON{},
+			W:                  w,
+		}
+		r := httprouter.New()
+		h.SetRoutes(r)
+		return httptest.NewServer(r), httpClient
+	}
+	do := func(c *http.Client, method, url string) *http.Response {
+		req, err := http.NewRequest(method, url, nil)
+		require.Nil(t, err)
+		res, err := c.Do(req)
+		require.Nil(t, err)
+		return res
+	}
+
+	// The account api needs the hydra.account scope.
+	forbidden, forbiddenClient := serve("core")
+	defer forbidden.Close()
+	for _, c := range []struct{ method, path string }{
+		{"GET", AccountHandlerPath},
+		{"DELETE", AccountHandlerPath + "/sessions/peter-session"},
+		{"DELETE", AccountHandlerPath + "/applications/peter-photos"},
+	} {
+		res := do(forbiddenClient, c.method, forbidden.URL+c.path)
+		res.Body.Close()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode, "%s %s", c.method, c.path)
+	}
+
+	server, httpClient := serve("hydra.account")
+	defer server.Close()
+
+	res := do(httpClient, "GET", server.URL+AccountHandlerPath)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var account Account
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&account))
+	res.Body.Close()
+	assert.Equal(t, "peter", account.Subject)
+	require.Len(t, account.Sessions, 1)
+	assert.Equal(t, "peter-session", account.Sessions[0].ID)
+	require.Len(t, account.Applications, 1)
+	assert.Equal(t, "peter-photos", account.Applications[0].ID)
+	assert.Equal(t, "Photos", account.Applications[0].ClientName)
+
+	// Sessions and applications of other users are reported as missing and are kept.
+	for _, path := range []string{"/sessions/alice-session", "/applications/alice-photos", "/sessions/unknown", "/applications/unknown"} {
+		res := do(httpClient, "DELETE", server.URL+AccountHandlerPath+path)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, "%s", path)
+	}
+	_, err := loginSessions.GetLoginSession("alice-session")
+	assert.Nil(t, err)
+	_, err = rememberedConsents.GetRememberedConsent("alice-photos")
+	assert.Nil(t, err)
+
+	res = do(httpClient, "DELETE", server.URL+AccountHandlerPath+"/sessions/peter-session")
+	res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	_, err = loginSessions.GetLoginSession("peter-session")
+	assert.NotNil(t, err)
+
+	// Revoking an application revokes the tokens it holds on behalf of the user, but not those of other users.
+	res = do(httpClient, "DELETE", server.URL+AccountHandlerPath+"/applications/peter-photos")
+	res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	_, err = rememberedConsents.GetRememberedConsent("peter-photos")
+	assert.NotNil(t, err)
+
+	_, err = tokens.GetAccessTokenSession(ctx, peterAccess, &Session{})
+	assert.NotNil(t, err)
+	_, err = tokens.GetRefreshTokenSession(ctx, peterRefresh, &Session{})
+	assert.NotNil(t, err)
+	_, err = tokens.GetAccessTokenSession(ctx, aliceAccess, &Session{})
+	assert.Nil(t, err)
+	_, err = tokens.GetRefreshTokenSession(ctx, aliceRefresh, &Session{})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,107 @@
+package oauth2
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/ladon"
+)
+
+const audienceResource = "rn:hydra:oauth2:audiences:%s"
+
+// AudiencePolicy decides which audiences clients may obtain tokens for.
+type AudiencePolicy interface {
+	// AllowAudience returns an error if the client may not obtain tokens for the audience.
+	AllowAudience(clientID, audience string, scopes []string) error
+}
+
+// LadonAudiencePolicy allows audiences which are allowed by a policy. The policy's subject is the client, the
+// resource is rn:hydra:oauth2:audiences:<audience> and the action is request. The granted scopes are available to
+// conditions.
+type LadonAudiencePolicy struct {
+	Warden ladon.Warden
+}
+
+func (p *LadonAudiencePolicy) AllowAudience(clientID, audience string, scopes []string) error {
+	return p.Warden.IsAllowed(&ladon.Request{
+		Subject:  clientID,
+		Resource: fmt.Sprintf(audienceResource, audience),
+		Action:   "request",
+		Context: ladon.Context{
+			"scopes": scopes,
+		},
+	})
+}
+
+// requestedAudience returns the audience a client asked for with the audience parameter of RFC 8693 or the resource
+// parameter of RFC 8707, or an empty string. Tokens are meant for a single audience, so only one may be requested.
+func requestedAudience(form url.Values) (string, error) {
+	var audiences []string
+	for _, audience := range form["audience"] {
+		if audience != "" {
+			audiences = append(audiences, audience)
+		}
+	}
+	for _, resource := range form["resource"] {
+		if resource == "" {
+			continue
+		} else if u, err := url.Parse(resource); err != nil || !u.IsAbs() || u.Fragment != "" {
+			return "", errors.Errorf("Resource %s must be an absolute URI without a fragment", resource)
+		}
+		audiences = append(audiences, resource)
+	}
+
+	if len(audiences) > 1 {
+		return "", errors.New("Only one audience may be requested")
+	} else if len(audiences) == 0 {
+		return "", nil
+	}
+	return audiences[0], nil
+}
+
+// grantAudience restricts the tokens of a session to the audience the client requested. A session which is already
+// restricted, like the session of an authorize code or of a refresh token, keeps its audience.
+func (o *Handler) grantAudience(form url.Values, clientID string, scopes []string, session *Session) error {
+	audience, err := requestedAudience(form)
+	if err != nil || audience == "" {
+		return err
+	}
+
+	if session.Audience != "" {
+		if session.Audience != audience {
+			return errors.Errorf("The grant is restricted to audience %s", session.Audience)
+		}
+		return nil
+	}
+
+	if o.Audiences == nil {
+		return errors.New("Audiences can not be requested")
+	} else if err := o.Audiences.AllowAudience(clientID, audience, scopes); err != nil {
+		return errors.Errorf("Client %s is not allowed to obtain tokens for audience %s", clientID, audience)
+	}
+	session.Audience = audience
+	return nil
+}
+
+// writeInvalidTarget redirects the user agent back to the client with the invalid_target error of RFC 8707.
+func (o *Handler) writeInvalidTarget(w http.ResponseWriter, ar fosite.AuthorizeRequester, err error) {
+	if !ar.IsRedirectURIValid() {
+		o.writeAuthorizeError(w, ar, errors.New(fosite.ErrInvalidRequest))
+		return
+	}
+
+	redirectURI := *ar.GetRedirectURI()
+	query := redirectURI.Query()
+	query.Set("error", "invalid_target")
+	query.Set("error_description", err.Error())
+	if state := ar.GetState(); state != "" {
+		query.Set("state", state)
+	}
+	redirectURI.RawQuery = query.Encode()
+
+	w.Header().Set("Location", redirectURI.String())
+	w.WriteHeader(http.StatusFound)
+}
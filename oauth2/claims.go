@@ -0,0 +1,152 @@
+package oauth2
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/events"
+	"golang.org/x/net/context"
+)
+
+// DefaultScopeClaims are the claims each scope releases into ID tokens, see OpenID Connect Core section 5.4.
+var DefaultScopeClaims = map[string][]string{
+	"profile": {
+		"name", "family_name", "given_name", "middle_name", "nickname", "preferred_username", "profile", "picture",
+		"website", "gender", "birthdate", "zoneinfo", "locale", "updated_at",
+	},
+	"email":   {"email", "email_verified"},
+	"address": {"address"},
+	"phone":   {"phone_number", "phone_number_verified"},
+}
+
+// reservedClaims are set by hydra and never overwritten by a claims source.
+var reservedClaims = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "iat": true, "nbf": true, "jti": true, "auth_time": true,
+	"nonce": true, "acr": true, "amr": true, "azp": true, "at_hash": true, "c_hash": true, "sid": true,
+}
+
+// ClaimsRequest asks a claims source for the claims of a subject.
+type ClaimsRequest struct {
+	Subject  string   `json:"subject"`
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+// ClaimsSource looks up claims of a subject in the user store of a deployment, for example its name and email
+// address, when an ID token is issued. It may return claims regardless of the scopes, claims which the granted scopes
+// do not release are removed.
+type ClaimsSource interface {
+	Claims(ctx context.Context, r *ClaimsRequest) (map[string]interface{}, error)
+}
+
+// ClaimsSourceFunc adapts a function to a ClaimsSource.
+type ClaimsSourceFunc func(ctx context.Context, r *ClaimsRequest) (map[string]interface{}, error)
+
+func (f ClaimsSourceFunc) Claims(ctx context.Context, r *ClaimsRequest) (map[string]interface{}, error) {
+	return f(ctx, r)
+}
+
+var claimsSources = struct {
+	sync.RWMutex
+	sources map[string]ClaimsSource
+}{sources: map[string]ClaimsSource{}}
+
+// RegisterClaimsSource makes a claims source which is compiled into hydra available by name, so that it can be
+// selected with ID_TOKEN_CLAIMS_SOURCE. It is meant to be called from the init function of the source's package.
+func RegisterClaimsSource(name string, source ClaimsSource) {
+	claimsSources.Lock()
+	defer claimsSources.Unlock()
+
+	claimsSources.sources[name] = source
+}
+
+// GetClaimsSource returns the claims source registered with a name.
+func GetClaimsSource(name string) (ClaimsSource, error) {
+	claimsSources.RLock()
+	defer claimsSources.RUnlock()
+
+	source, ok := claimsSources.sources[name]
+	if !ok {
+		return nil, errors.Errorf("Claims source %s is not registered", name)
+	}
+	return source, nil
+}
+
+// DefaultClaimsTimeout is how long WebhookClaimsSource waits for a response unless HTTPClient is set.
+const DefaultClaimsTimeout = 5 * time.Second
+
+// WebhookClaimsSource posts the ClaimsRequest as JSON to URL and expects the claims as a JSON object in response.
+type WebhookClaimsSource struct {
+	URL string
+
+	// Secret signs request bodies like the bodies of event webhooks, see events.SignatureHeader.
+	Secret []byte
+
+	// HTTPClient defaults to a client with a timeout of DefaultClaimsTimeout.
+	HTTPClient *http.Client
+}
+
+func (s *WebhookClaimsSource) Claims(ctx context.Context, r *ClaimsRequest) (map[string]interface{}, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.Secret) > 0 {
+		mac := hmac.New(sha256.New, s.Secret)
+		mac.Write(body)
+		req.Header.Set(events.SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	c := s.HTTPClient
+	if c == nil {
+		c = &http.Client{Timeout: DefaultClaimsTimeout}
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Claims source %s returned status code %d", s.URL, res.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&claims); err != nil {
+		return nil, errors.New(err)
+	}
+	return claims, nil
+}
+
+// releasedClaims returns the claims which the granted scopes release. scopeClaims overrides DefaultScopeClaims per
+// scope. Reserved claims are never released.
+func releasedClaims(claims map[string]interface{}, granted []string, scopeClaims map[string][]string) map[string]interface{} {
+	released := map[string]interface{}{}
+	for _, scope := range granted {
+		names, ok := scopeClaims[scope]
+		if !ok {
+			names = DefaultScopeClaims[scope]
+		}
+
+		for _, name := range names {
+			if value, ok := claims[name]; ok && !reservedClaims[name] {
+				released[name] = value
+			}
+		}
+	}
+	return released
+}
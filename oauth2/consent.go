@@ -1,8 +1,27 @@
 package oauth2
 
-import "github.com/ory-am/fosite"
+import (
+	"time"
+
+	"github.com/ory-am/fosite"
+)
 
 type ConsentStrategy interface {
 	ValidateResponse(authorizeRequest fosite.AuthorizeRequester, token string) (claims *Session, err error)
 	IssueChallenge(authorizeRequest fosite.AuthorizeRequester, redirectURL string) (token string, err error)
+
+	// ValidateChallenge verifies a challenge issued by IssueChallenge and returns its claims.
+	ValidateChallenge(challenge string) (*ConsentChallenge, error)
+}
+
+// ConsentChallenge are the claims of a consent challenge.
+type ConsentChallenge struct {
+	ID          string
+	ClientID    string
+	Scopes      []string
+	RedirectURL string
+
+	// GrantedScopes were requested as well, but the user agent granted them before. They are not part of Scopes.
+	GrantedScopes []string
+	ExpiresAt     time.Time
 }
@@ -0,0 +1,70 @@
+package oauth2
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+const (
+	PendingConsentWaiting = "pending"
+	PendingConsentGranted = "granted"
+	PendingConsentDenied  = "denied"
+)
+
+var (
+	// ErrPendingConsentResolved is returned when a pending consent request is granted or denied twice.
+	ErrPendingConsentResolved = errors.New("The consent request has already been resolved")
+
+	// ErrPendingConsentBound is returned when a pending consent request is already bound to another user agent.
+	ErrPendingConsentBound = errors.New("The consent request is bound to another user agent")
+)
+
+// PendingConsent is a consent challenge for which the consent app has deferred the decision, for example
+// because the user has to approve the request on another device. Hydra holds the authorization request until
+// the consent app resolves it or the request expires.
+type PendingConsent struct {
+	ID           string    `json:"id" gorethink:"id"`
+	ClientID     string    `json:"client_id" gorethink:"client_id"`
+	Scopes       []string  `json:"scopes" gorethink:"scopes"`
+	Status       string    `json:"status" gorethink:"status"`
+	ConsentToken string    `json:"-" gorethink:"consent_token,omitempty"`
+	Reason       string    `json:"reason,omitempty" gorethink:"reason,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at" gorethink:"expires_at"`
+
+	// UserCode identifies the request on a second device in cross-device logins. VerificationURL is the link,
+	// usually shown as a QR code, which opens the consent app on that device.
+	UserCode        string `json:"user_code,omitempty" gorethink:"user_code,omitempty"`
+	VerificationURL string `json:"verification_uri,omitempty" gorethink:"verification_uri,omitempty"`
+
+	// Binding is a hash of the secret which binds the request to the user agent that started it.
+	Binding string `json:"-" gorethink:"binding,omitempty"`
+}
+
+// IsExpired returns true if the consent app did not resolve the request in time.
+func (p *PendingConsent) IsExpired() bool {
+	return !time.Now().Before(p.ExpiresAt)
+}
+
+// PendingConsentManager stores consent requests whose decision has been deferred.
+type PendingConsentManager interface {
+	// CreatePendingConsent stores a new pending consent request.
+	CreatePendingConsent(p *PendingConsent) error
+
+	// GetPendingConsent returns a pending consent request or pkg.ErrNotFound if it does not exist or is expired.
+	GetPendingConsent(id string) (*PendingConsent, error)
+
+	// GetPendingConsentByUserCode returns the pending consent request with the given user code.
+	GetPendingConsentByUserCode(code string) (*PendingConsent, error)
+
+	// BindPendingConsent binds a pending consent request to a user agent. It returns ErrPendingConsentBound
+	// if the request is bound to a different user agent already.
+	BindPendingConsent(id, binding string) error
+
+	// ResolvePendingConsent grants or denies a pending consent request. Granted requests carry the consent
+	// token which is used to complete the authorization request.
+	ResolvePendingConsent(id, status, consentToken, reason string) error
+
+	// DeletePendingConsent removes a pending consent request.
+	DeletePendingConsent(id string) error
+}
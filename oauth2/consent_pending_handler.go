@@ -0,0 +1,231 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/common/rand/sequence"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const (
+	PendingConsentHandlerPath = "/oauth2/consent/pending"
+)
+
+// userCodeRunes leaves out characters which are easily confused when typed from another screen.
+var userCodeRunes = []rune("ABCDEFGHJKLMNPQRSTUVWXYZ23456789")
+
+// PendingConsentHandler lets the consent app defer the decision on a consent challenge and resolve it later,
+// for example after the user approved the request with a push notification.
+type PendingConsentHandler struct {
+	Manager PendingConsentManager
+	Consent ConsentStrategy
+
+	// Lifespan is the maximum time hydra holds a pending authorization request.
+	Lifespan time.Duration
+
+	// ConsentURL is the consent app's address. Cross-device logins link the second device to it.
+	ConsentURL url.URL
+
+	H herodot.Herodot
+	W firewall.Firewall
+}
+
+type pendingConsentRequest struct {
+	Challenge string `json:"challenge"`
+
+	// ExpiresIn is the number of seconds hydra holds the authorization request. It is capped at the handler's
+	// lifespan and the expiry of the challenge.
+	ExpiresIn int64 `json:"expires_in"`
+
+	// CrossDevice requests a user code and a verification link so that the request can be approved on
+	// another device.
+	CrossDevice bool `json:"cross_device"`
+}
+
+type resolveConsentRequest struct {
+	Status  string `json:"status"`
+	Consent string `json:"consent"`
+	Reason  string `json:"reason"`
+}
+
+func (h *PendingConsentHandler) SetRoutes(r *httprouter.Router) {
+	r.POST(PendingConsentHandlerPath, h.Create)
+	r.GET(PendingConsentHandlerPath, h.Find)
+	r.GET(PendingConsentHandlerPath+"/:id", h.Get)
+	r.PUT(PendingConsentHandlerPath+"/:id", h.Resolve)
+}
+
+func (h *PendingConsentHandler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = context.Background()
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:oauth2:consent:pending",
+		Action:   "create",
+	}, "hydra.consent"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	var pr pendingConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+	defer r.Body.Close()
+
+	challenge, err := h.Consent.ValidateChallenge(pr.Challenge)
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	lifespan := h.getLifespan()
+	if pr.ExpiresIn > 0 && time.Duration(pr.ExpiresIn)*time.Second < lifespan {
+		lifespan = time.Duration(pr.ExpiresIn) * time.Second
+	}
+
+	expiresAt := time.Now().Add(lifespan).UTC()
+	if challenge.ExpiresAt.Before(expiresAt) {
+		expiresAt = challenge.ExpiresAt.UTC()
+	}
+
+	p := &PendingConsent{
+		ID:        challenge.ID,
+		ClientID:  challenge.ClientID,
+		Scopes:    challenge.Scopes,
+		Status:    PendingConsentWaiting,
+		ExpiresAt: expiresAt,
+	}
+
+	if pr.CrossDevice {
+		code, err := sequence.RuneSequence(8, userCodeRunes)
+		if err != nil {
+			h.H.WriteError(ctx, w, r, errors.New(err))
+			return
+		}
+		p.UserCode = string(code)
+
+		link := h.ConsentURL
+		q := link.Query()
+		q.Set("user_code", p.UserCode)
+		link.RawQuery = q.Encode()
+		p.VerificationURL = link.String()
+	}
+
+	if err := h.Manager.CreatePendingConsent(p); errors.Is(err, ErrPendingConsentResolved) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.WriteCreated(ctx, w, r, PendingConsentHandlerPath+"/"+p.ID, p)
+}
+
+// Find looks up a pending consent request by its user code. The consent app uses it when the second device of a
+// cross-device login opens the verification link.
+func (h *PendingConsentHandler) Find(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = context.Background()
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:oauth2:consent:pending",
+		Action:   "get",
+	}, "hydra.consent"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	p, err := h.Manager.GetPendingConsentByUserCode(r.URL.Query().Get("user_code"))
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, p)
+}
+
+func (h *PendingConsentHandler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = context.Background()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:oauth2:consent:pending:" + id,
+		Action:   "get",
+	}, "hydra.consent"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	p, err := h.Manager.GetPendingConsent(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, p)
+}
+
+func (h *PendingConsentHandler) Resolve(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = context.Background()
+	var id = ps.ByName("id")
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:oauth2:consent:pending:" + id,
+		Action:   "resolve",
+	}, "hydra.consent"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	var rr resolveConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&rr); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+	defer r.Body.Close()
+
+	switch rr.Status {
+	case PendingConsentGranted:
+		if rr.Consent == "" {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("A granted consent request requires a consent token"))
+			return
+		}
+	case PendingConsentDenied:
+		rr.Consent = ""
+	default:
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.Errorf("Status must be %s or %s", PendingConsentGranted, PendingConsentDenied))
+		return
+	}
+
+	if err := h.Manager.ResolvePendingConsent(id, rr.Status, rr.Consent, rr.Reason); errors.Is(err, ErrPendingConsentResolved) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	p, err := h.Manager.GetPendingConsent(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, p)
+}
+
+func (h *PendingConsentHandler) getLifespan() time.Duration {
+	if h.Lifespan == 0 {
+		return time.Minute * 5
+	}
+	return h.Lifespan
+}
@@ -0,0 +1,100 @@
+package oauth2
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type PendingConsentMemoryManager struct {
+	Consents map[string]*PendingConsent
+	sync.RWMutex
+}
+
+func NewPendingConsentMemoryManager() *PendingConsentMemoryManager {
+	return &PendingConsentMemoryManager{
+		Consents: map[string]*PendingConsent{},
+	}
+}
+
+func (m *PendingConsentMemoryManager) CreatePendingConsent(p *PendingConsent) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if c, ok := m.Consents[p.ID]; ok && !c.IsExpired() {
+		return errors.New(ErrPendingConsentResolved)
+	}
+
+	m.Consents[p.ID] = p
+	return nil
+}
+
+func (m *PendingConsentMemoryManager) GetPendingConsent(id string) (*PendingConsent, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	c, ok := m.Consents[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if c.IsExpired() {
+		delete(m.Consents, id)
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	p := *c
+	return &p, nil
+}
+
+func (m *PendingConsentMemoryManager) GetPendingConsentByUserCode(code string) (*PendingConsent, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, c := range m.Consents {
+		if c.UserCode != "" && c.UserCode == code && !c.IsExpired() {
+			p := *c
+			return &p, nil
+		}
+	}
+	return nil, errors.New(pkg.ErrNotFound)
+}
+
+func (m *PendingConsentMemoryManager) BindPendingConsent(id, binding string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	c, ok := m.Consents[id]
+	if !ok || c.IsExpired() {
+		return errors.New(pkg.ErrNotFound)
+	} else if c.Binding != "" && c.Binding != binding {
+		return errors.New(ErrPendingConsentBound)
+	}
+
+	c.Binding = binding
+	return nil
+}
+
+func (m *PendingConsentMemoryManager) ResolvePendingConsent(id, status, consentToken, reason string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	c, ok := m.Consents[id]
+	if !ok || c.IsExpired() {
+		return errors.New(pkg.ErrNotFound)
+	} else if c.Status != PendingConsentWaiting {
+		return errors.New(ErrPendingConsentResolved)
+	}
+
+	c.Status = status
+	c.ConsentToken = consentToken
+	c.Reason = reason
+	return nil
+}
+
+func (m *PendingConsentMemoryManager) DeletePendingConsent(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Consents, id)
+	return nil
+}
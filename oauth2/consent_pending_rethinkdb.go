@@ -0,0 +1,124 @@
+package oauth2
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// PendingConsentRethinkManager reads and writes directly against the database because the consent app may
+// resolve a request on a different instance than the one the user agent is waiting on.
+type PendingConsentRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *PendingConsentRethinkManager) CreatePendingConsent(p *PendingConsent) error {
+	if _, err := m.GetPendingConsent(p.ID); err == nil {
+		return errors.New(ErrPendingConsentResolved)
+	} else if !errors.Is(err, pkg.ErrNotFound) {
+		return err
+	}
+
+	if _, err := m.Table.Insert(p, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *PendingConsentRethinkManager) GetPendingConsent(id string) (*PendingConsent, error) {
+	res, err := m.Table.Get(id).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var p PendingConsent
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&p); err != nil {
+		return nil, errors.New(err)
+	}
+
+	if p.IsExpired() {
+		if err := m.DeletePendingConsent(id); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return &p, nil
+}
+
+func (m *PendingConsentRethinkManager) GetPendingConsentByUserCode(code string) (*PendingConsent, error) {
+	if code == "" {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	rows, err := m.Table.Filter(map[string]interface{}{"user_code": code}).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var p *PendingConsent
+	for rows.Next(&p) {
+		if !p.IsExpired() {
+			return p, nil
+		}
+		p = nil
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.New(rows.Err())
+	}
+	return nil, errors.New(pkg.ErrNotFound)
+}
+
+func (m *PendingConsentRethinkManager) BindPendingConsent(id, binding string) error {
+	// Only set the binding if there is none so that two user agents can not both bind the request.
+	if _, err := m.Table.Get(id).Update(func(row r.Term) interface{} {
+		return r.Branch(row.HasFields("binding"), map[string]interface{}{}, map[string]interface{}{
+			"binding": binding,
+		})
+	}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+
+	p, err := m.GetPendingConsent(id)
+	if err != nil {
+		return err
+	} else if p.Binding != binding {
+		return errors.New(ErrPendingConsentBound)
+	}
+	return nil
+}
+
+func (m *PendingConsentRethinkManager) ResolvePendingConsent(id, status, consentToken, reason string) error {
+	if p, err := m.GetPendingConsent(id); err != nil {
+		return err
+	} else if p.Status != PendingConsentWaiting {
+		return errors.New(ErrPendingConsentResolved)
+	}
+
+	// Only update the request if it is still pending so that two concurrent decisions can not both succeed.
+	res, err := m.Table.Get(id).Update(func(row r.Term) interface{} {
+		return r.Branch(row.Field("status").Eq(PendingConsentWaiting), map[string]interface{}{
+			"status":        status,
+			"consent_token": consentToken,
+			"reason":        reason,
+		}, map[string]interface{}{})
+	}).RunWrite(m.Session)
+	if err != nil {
+		return errors.New(err)
+	} else if res.Replaced == 0 {
+		return errors.New(ErrPendingConsentResolved)
+	}
+	return nil
+}
+
+func (m *PendingConsentRethinkManager) DeletePendingConsent(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
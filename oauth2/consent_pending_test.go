@@ -0,0 +1,76 @@
+package oauth2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingConsentMemoryManager(t *testing.T) {
+	m := NewPendingConsentMemoryManager()
+
+	require.Nil(t, m.CreatePendingConsent(&PendingConsent{
+		ID:        "foo",
+		ClientID:  "app",
+		Status:    PendingConsentWaiting,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}))
+	require.Nil(t, m.CreatePendingConsent(&PendingConsent{
+		ID:        "expired",
+		ClientID:  "app",
+		Status:    PendingConsentWaiting,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	err := m.CreatePendingConsent(&PendingConsent{ID: "foo", ExpiresAt: time.Now().Add(time.Minute)})
+	assert.True(t, errors.Is(err, ErrPendingConsentResolved))
+
+	p, err := m.GetPendingConsent("foo")
+	require.Nil(t, err)
+	assert.Equal(t, PendingConsentWaiting, p.Status)
+
+	_, err = m.GetPendingConsent("expired")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+	assert.True(t, errors.Is(m.ResolvePendingConsent("expired", PendingConsentGranted, "token", ""), pkg.ErrNotFound))
+
+	require.Nil(t, m.ResolvePendingConsent("foo", PendingConsentGranted, "token", ""))
+	assert.True(t, errors.Is(m.ResolvePendingConsent("foo", PendingConsentDenied, "", "changed my mind"), ErrPendingConsentResolved))
+
+	p, err = m.GetPendingConsent("foo")
+	require.Nil(t, err)
+	assert.Equal(t, PendingConsentGranted, p.Status)
+	assert.Equal(t, "token", p.ConsentToken)
+
+	require.Nil(t, m.DeletePendingConsent("foo"))
+	_, err = m.GetPendingConsent("foo")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+}
+
+func TestPendingConsentCrossDevice(t *testing.T) {
+	m := NewPendingConsentMemoryManager()
+
+	require.Nil(t, m.CreatePendingConsent(&PendingConsent{
+		ID:        "foo",
+		ClientID:  "app",
+		Status:    PendingConsentWaiting,
+		UserCode:  "ABCD2345",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}))
+
+	p, err := m.GetPendingConsentByUserCode("ABCD2345")
+	require.Nil(t, err)
+	assert.Equal(t, "foo", p.ID)
+
+	_, err = m.GetPendingConsentByUserCode("")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+
+	require.Nil(t, m.BindPendingConsent("foo", "browser"))
+	require.Nil(t, m.BindPendingConsent("foo", "browser"))
+	assert.True(t, errors.Is(m.BindPendingConsent("foo", "attacker"), ErrPendingConsentBound))
+	assert.True(t, errors.Is(m.BindPendingConsent("bar", "browser"), pkg.ErrNotFound))
+}
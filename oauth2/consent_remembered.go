@@ -0,0 +1,109 @@
+package oauth2
+
+import (
+	"time"
+
+	"github.com/ory-am/fosite"
+)
+
+// RememberedConsent is a consent decision the consent app asked hydra to remember. While it is valid, authorization
+// requests of the client for a subset of its scopes skip the consent app.
+type RememberedConsent struct {
+	ID       string   `json:"id" gorethink:"id"`
+	Subject  string   `json:"subject" gorethink:"subject"`
+	ClientID string   `json:"client_id" gorethink:"client_id"`
+	Scopes   []string `json:"scopes" gorethink:"scopes"`
+
+	// IDTokenClaims are the claims the consent app added to the ID token when it granted the request.
+	IDTokenClaims map[string]interface{} `json:"id_token_claims,omitempty" gorethink:"id_token_claims,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" gorethink:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" gorethink:"expires_at"`
+
+	// ScopeExpiresAt is when the decision expires for individual scopes, scopes without an entry expire at
+	// ExpiresAt. ExpiresAt is never before any of them.
+	ScopeExpiresAt map[string]time.Time `json:"scope_expires_at,omitempty" gorethink:"scope_expires_at,omitempty"`
+
+	// Binding is a hash of the secret which binds the decision to the user agent it was made on.
+	Binding string `json:"-" gorethink:"binding"`
+}
+
+// IsExpired returns true if the decision must no longer be used.
+func (c *RememberedConsent) IsExpired() bool {
+	return !time.Now().Before(c.ExpiresAt)
+}
+
+// Covers returns true if every requested scope was granted by the decision and has not expired.
+func (c *RememberedConsent) Covers(scopes fosite.Arguments) bool {
+	granted := fosite.Arguments(c.Scopes)
+	for _, scope := range scopes {
+		if !granted.Has(scope) || c.scopeExpired(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Prune removes the scopes whose decision has expired.
+func (c *RememberedConsent) Prune() {
+	scopes := []string{}
+	for _, scope := range c.Scopes {
+		if c.scopeExpired(scope) {
+			delete(c.ScopeExpiresAt, scope)
+			continue
+		}
+		scopes = append(scopes, scope)
+	}
+	c.Scopes = scopes
+}
+
+func (c *RememberedConsent) scopeExpired(scope string) bool {
+	return !time.Now().Before(c.scopeExpiresAt(scope))
+}
+
+func (c *RememberedConsent) scopeExpiresAt(scope string) time.Time {
+	if expiresAt, ok := c.ScopeExpiresAt[scope]; ok {
+		return expiresAt
+	}
+	return c.ExpiresAt
+}
+
+// Granted returns the scopes of the request the decision covers.
+func (c *RememberedConsent) Granted(scopes fosite.Arguments) fosite.Arguments {
+	granted := fosite.Arguments{}
+	for _, scope := range scopes {
+		if fosite.Arguments(c.Scopes).Has(scope) && !c.scopeExpired(scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted
+}
+
+// RememberedConsentManager stores remembered consent decisions.
+type RememberedConsentManager interface {
+	// RememberConsent stores a consent decision.
+	RememberConsent(c *RememberedConsent) error
+
+	// GetRememberedConsent returns a decision or pkg.ErrNotFound if it does not exist or is expired.
+	GetRememberedConsent(id string) (*RememberedConsent, error)
+
+	// GetRememberedConsents returns all valid decisions of a subject.
+	GetRememberedConsents(subject string) ([]*RememberedConsent, error)
+
+	// ForgetConsent revokes a decision.
+	ForgetConsent(id string) error
+}
+
+// IncrementalConsentStrategy is implemented by consent strategies which can ask the consent app for some of the
+// requested scopes only. The consent app is asked for all requested scopes if the consent strategy does not
+// implement it.
+type IncrementalConsentStrategy interface {
+	// IssueIncrementalChallenge issues a challenge for the requested scopes which are not granted already.
+	IssueIncrementalChallenge(authorizeRequest fosite.AuthorizeRequester, redirectURL string, granted fosite.Arguments) (string, error)
+}
+
+// RememberingConsentStrategy is implemented by consent strategies which can create a session from a remembered
+// consent decision. Remembered decisions are ignored if the consent strategy does not implement it.
+type RememberingConsentStrategy interface {
+	RememberedSession(authorizeRequest fosite.AuthorizeRequester, c *RememberedConsent) (*Session, error)
+}
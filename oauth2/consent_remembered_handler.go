@@ -0,0 +1,110 @@
+package oauth2
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/pagination"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const (
+	RememberedConsentHandlerPath = "/oauth2/consent/remembered"
+)
+
+// RememberedConsentHandler lists and revokes the remembered consent decisions of a subject, for example on the
+// user's account page.
+type RememberedConsentHandler struct {
+	Manager RememberedConsentManager
+
+	H herodot.Herodot
+	W firewall.Firewall
+}
+
+func (h *RememberedConsentHandler) SetRoutes(r *httprouter.Router) {
+	r.GET(RememberedConsentHandlerPath, h.List)
+	r.DELETE(RememberedConsentHandlerPath+"/:id", h.Delete)
+}
+
+func (h *RememberedConsentHandler) List(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = context.Background()
+	var subject = r.URL.Query().Get("subject")
+
+	if subject == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Query parameter subject is required"))
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:oauth2:consent:remembered:" + subject,
+		Action:   "get",
+	}, "hydra.consent"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	page, err := pagination.Parse(r, "created_at", "id", "client_id", "expires_at")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	consents, err := h.Manager.GetRememberedConsents(subject)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	indices, total := page.Apply(len(consents), func(i int, field string) string {
+		switch field {
+		case "created_at":
+			return consents[i].CreatedAt.UTC().Format(time.RFC3339)
+		case "id":
+			return consents[i].ID
+		case "client_id":
+			return consents[i].ClientID
+		case "expires_at":
+			return consents[i].ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		return ""
+	})
+
+	res := make([]*RememberedConsent, len(indices))
+	for k, i := range indices {
+		res[k] = consents[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
+}
+
+func (h *RememberedConsentHandler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ctx = context.Background()
+	var id = ps.ByName("id")
+
+	c, err := h.Manager.GetRememberedConsent(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:oauth2:consent:remembered:" + c.Subject,
+		Action:   "delete",
+	}, "hydra.consent"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Manager.ForgetConsent(id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
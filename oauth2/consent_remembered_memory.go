@@ -0,0 +1,76 @@
+package oauth2
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type RememberedConsentMemoryManager struct {
+	Consents map[string]*RememberedConsent
+	sync.RWMutex
+}
+
+func NewRememberedConsentMemoryManager() *RememberedConsentMemoryManager {
+	return &RememberedConsentMemoryManager{
+		Consents: map[string]*RememberedConsent{},
+	}
+}
+
+func (m *RememberedConsentMemoryManager) RememberConsent(c *RememberedConsent) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.Consents[c.ID] = c
+	return nil
+}
+
+func (m *RememberedConsentMemoryManager) GetRememberedConsent(id string) (*RememberedConsent, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	c, ok := m.Consents[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if c.IsExpired() {
+		delete(m.Consents, id)
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	return copyRememberedConsent(c), nil
+}
+
+func (m *RememberedConsentMemoryManager) GetRememberedConsents(subject string) ([]*RememberedConsent, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	consents := []*RememberedConsent{}
+	for _, c := range m.Consents {
+		if c.Subject == subject && !c.IsExpired() {
+			consents = append(consents, copyRememberedConsent(c))
+		}
+	}
+	return consents, nil
+}
+
+func (m *RememberedConsentMemoryManager) ForgetConsent(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Consents, id)
+	return nil
+}
+
+// copyRememberedConsent returns a pruned copy which does not share its scopes with the stored decision.
+func copyRememberedConsent(c *RememberedConsent) *RememberedConsent {
+	r := *c
+	r.Scopes = append([]string{}, c.Scopes...)
+	r.ScopeExpiresAt = map[string]time.Time{}
+	for scope, expiresAt := range c.ScopeExpiresAt {
+		r.ScopeExpiresAt[scope] = expiresAt
+	}
+	r.Prune()
+	return &r
+}
@@ -0,0 +1,76 @@
+package oauth2
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// RememberedConsentRethinkManager reads and writes directly against the database so that a decision which was
+// revoked on one instance is not used by another.
+type RememberedConsentRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *RememberedConsentRethinkManager) RememberConsent(c *RememberedConsent) error {
+	if _, err := m.Table.Insert(c, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RememberedConsentRethinkManager) GetRememberedConsent(id string) (*RememberedConsent, error) {
+	res, err := m.Table.Get(id).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var c RememberedConsent
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&c); err != nil {
+		return nil, errors.New(err)
+	}
+
+	if c.IsExpired() {
+		if err := m.ForgetConsent(id); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	c.Prune()
+	return &c, nil
+}
+
+func (m *RememberedConsentRethinkManager) GetRememberedConsents(subject string) ([]*RememberedConsent, error) {
+	rows, err := m.Table.Filter(map[string]interface{}{"subject": subject}).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	consents := []*RememberedConsent{}
+	var c *RememberedConsent
+	for rows.Next(&c) {
+		if !c.IsExpired() {
+			c.Prune()
+			consents = append(consents, c)
+		}
+		c = nil
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.New(rows.Err())
+	}
+	return consents, nil
+}
+
+func (m *RememberedConsentRethinkManager) ForgetConsent(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package oauth2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRememberedConsentMemoryManager(t *testing.T) {
+	m := NewRememberedConsentMemoryManager()
+
+	require.Nil(t, m.RememberConsent(&RememberedConsent{ID: "foo", Subject: "peter", ClientID: "app", ExpiresAt: time.Now().Add(time.Hour)}))
+	require.Nil(t, m.RememberConsent(&RememberedConsent{ID: "bar", Subject: "peter", ClientID: "other", ExpiresAt: time.Now().Add(time.Hour)}))
+	require.Nil(t, m.RememberConsent(&RememberedConsent{ID: "expired", Subject: "peter", ClientID: "app", ExpiresAt: time.Now().Add(-time.Minute)}))
+	require.Nil(t, m.RememberConsent(&RememberedConsent{ID: "baz", Subject: "alice", ClientID: "app", ExpiresAt: time.Now().Add(time.Hour)}))
+
+	c, err := m.GetRememberedConsent("foo")
+	require.Nil(t, err)
+	assert.Equal(t, "app", c.ClientID)
+
+	_, err = m.GetRememberedConsent("expired")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+
+	consents, err := m.GetRememberedConsents("peter")
+	require.Nil(t, err)
+	assert.Len(t, consents, 2)
+
+	require.Nil(t, m.ForgetConsent("foo"))
+	_, err = m.GetRememberedConsent("foo")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+
+	consents, err = m.GetRememberedConsents("peter")
+	require.Nil(t, err)
+	assert.Len(t, consents, 1)
+}
+
+func TestRememberedSession(t *testing.T) {
+	s := &DefaultConsentStrategy{Issuer: "https://hydra.localhost", KeyManager: keyManager}
+	c := &RememberedConsent{
+		ID:            "foo",
+		Subject:       "peter",
+		ClientID:      "app-client",
+		Scopes:        []string{"openid", "photos"},
+		IDTokenClaims: map[string]interface{}{"email": "peter@hydra.localhost"},
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}
+	authorizeRequest := func(client string, scopes ...string) *fosite.AuthorizeRequest {
+		return &fosite.AuthorizeRequest{Request: fosite.Request{
+			Client: &fosite.DefaultClient{ID: client},
+			Scopes: fosite.Arguments(scopes),
+		}}
+	}
+
+	a := authorizeRequest("app-client", "openid")
+	session, err := s.RememberedSession(a, c)
+	require.Nil(t, err)
+	assert.Equal(t, "peter", session.Subject)
+	assert.Equal(t, fosite.Arguments{"openid"}, a.GetGrantedScopes())
+	assert.Equal(t, "peter@hydra.localhost", session.DefaultSession.Claims.Extra["email"])
+	assert.Empty(t, session.AuthenticationMethods)
+
+	_, err = s.RememberedSession(authorizeRequest("app-client", "openid", "admin"), c)
+	assert.NotNil(t, err, "Scopes which were not granted must be asked for")
+
+	_, err = s.RememberedSession(authorizeRequest("other-client", "openid"), c)
+	assert.NotNil(t, err)
+}
+
+func TestRememberedConsentScopeExpiry(t *testing.T) {
+	m := NewRememberedConsentMemoryManager()
+	require.Nil(t, m.RememberConsent(&RememberedConsent{
+		ID:       "foo",
+		Subject:  "peter",
+		ClientID: "app",
+		Scopes:   []string{"profile", "payments"},
+		ScopeExpiresAt: map[string]time.Time{
+			"profile":  time.Now().Add(time.Hour),
+			"payments": time.Now().Add(-time.Minute),
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	c, err := m.GetRememberedConsent("foo")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"profile"}, c.Scopes)
+	assert.True(t, c.Covers(fosite.Arguments{"profile"}))
+	assert.False(t, c.Covers(fosite.Arguments{"profile", "payments"}), "Expired scopes must be asked for again")
+
+	stored := m.Consents["foo"]
+	assert.False(t, stored.Covers(fosite.Arguments{"payments"}))
+	assert.Len(t, stored.Scopes, 2, "Reading must not modify the stored decision")
+}
+
+func TestRememberedConsentGranted(t *testing.T) {
+	c := &RememberedConsent{
+		Scopes: []string{"profile", "payments"},
+		ScopeExpiresAt: map[string]time.Time{
+			"payments": time.Now().Add(-time.Minute),
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	assert.Equal(t, fosite.Arguments{"profile"}, c.Granted(fosite.Arguments{"profile", "payments", "email"}))
+	assert.Equal(t, fosite.Arguments{}, c.Granted(fosite.Arguments{"email"}))
+}
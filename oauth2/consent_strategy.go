@@ -23,7 +23,13 @@ const (
 type DefaultConsentStrategy struct {
 	Issuer string
 
+	DefaultIDTokenLifespan time.Duration
+
 	KeyManager jwk.Manager
+
+	// RememberConsentScopeLifespans are announced to the consent app in the challenge, so that it can tell the user
+	// how long a decision is remembered for which scopes.
+	RememberConsentScopeLifespans map[string]time.Duration
 }
 
 func (s *DefaultConsentStrategy) ValidateResponse(a fosite.AuthorizeRequester, token string) (claims *Session, err error) {
@@ -58,11 +64,69 @@ func (s *DefaultConsentStrategy) ValidateResponse(a fosite.AuthorizeRequester, t
 		return nil, errors.Errorf("Audience mismatch")
 	}
 
+	webAuthn, err := webAuthnFromClaim(t.Claims["webauthn"])
+	if err != nil {
+		return nil, err
+	}
+
+	amr := toStringSlice(t.Claims["amr"])
+	acr := ejwt.ToString(t.Claims["acr"])
+	if webAuthn != nil {
+		amr = mergeAuthenticationMethods(amr, webAuthn.AuthenticationMethods()...)
+		if acr == "" {
+			acr = webAuthn.AuthenticationContext()
+		}
+	}
+
+	extra := idTokenClaims(t.Claims)
+	if len(amr) > 0 {
+		extra["amr"] = amr
+	}
+	if acr != "" {
+		extra["acr"] = acr
+	}
+
 	subject := ejwt.ToString(t.Claims["sub"])
 	for _, scope := range toStringSlice(t.Claims["scp"]) {
+		if !a.GetScopes().Has(scope) {
+			return nil, errors.Errorf("Scope %s was granted but not requested", scope)
+		}
+		a.GrantScope(scope)
+	}
+
+	session := s.newSession(a, subject, extra)
+	session.AuthenticationMethods = amr
+	session.AuthenticationContext = acr
+	session.WebAuthn = webAuthn
+	// remember_for is the number of seconds hydra skips the consent app for further requests of this client.
+	if rememberFor, ok := t.Claims["remember_for"].(float64); ok && rememberFor > 0 {
+		session.RememberFor = time.Duration(rememberFor) * time.Second
+	}
+	return session, err
+
+}
+
+// RememberedSession grants the requested scopes without asking the consent app. The subject did not authenticate
+// for this request, so the session carries no authentication methods.
+func (s *DefaultConsentStrategy) RememberedSession(a fosite.AuthorizeRequester, c *RememberedConsent) (*Session, error) {
+	if c.ClientID != a.GetClient().GetID() {
+		return nil, errors.New("Consent was remembered for another client")
+	} else if !c.Covers(a.GetScopes()) {
+		return nil, errors.New("Remembered consent does not cover the requested scopes")
+	}
+
+	for _, scope := range a.GetScopes() {
 		a.GrantScope(scope)
 	}
 
+	extra := map[string]interface{}{}
+	for k, v := range c.IDTokenClaims {
+		extra[k] = v
+	}
+	return s.newSession(a, c.Subject, extra), nil
+}
+
+func (s *DefaultConsentStrategy) newSession(a fosite.AuthorizeRequester, subject string, extra map[string]interface{}) *Session {
 	return &Session{
 		Subject: subject,
 		DefaultSession: &strategy.DefaultSession{
@@ -71,32 +135,92 @@ func (s *DefaultConsentStrategy) ValidateResponse(a fosite.AuthorizeRequester, t
 				Subject:   subject,
 				Issuer:    s.Issuer,
 				IssuedAt:  time.Now(),
-				ExpiresAt: time.Now(),
-				Extra:     t.Claims,
+				ExpiresAt: time.Now().Add(s.getIDTokenLifespan()),
+				Extra:     extra,
 			},
 			Headers: &ejwt.Headers{},
 		},
-	}, err
+	}
+}
+
+// idTokenClaims returns the claims the consent app wants to add to the ID token. They are the members of the id_token
+// claim of the consent response. Consent apps which do not set it get all claims of the response added, which is how
+// consent responses were read before.
+func idTokenClaims(claims map[string]interface{}) map[string]interface{} {
+	extra := map[string]interface{}{}
+	source := claims
+	if c, ok := claims["id_token"].(map[string]interface{}); ok {
+		source = c
+	}
 
+	for k, v := range source {
+		extra[k] = v
+	}
+	return extra
+}
+
+func (s *DefaultConsentStrategy) getIDTokenLifespan() time.Duration {
+	if s.DefaultIDTokenLifespan == 0 {
+		return time.Hour
+	}
+	return s.DefaultIDTokenLifespan
 }
 
 func toStringSlice(i interface{}) []string {
-	r, ok := i.([]string)
+	if r, ok := i.([]string); ok {
+		return r
+	}
+
+	// Claims decoded from JSON carry arrays as []interface{}
+	l, ok := i.([]interface{})
 	if !ok {
 		return []string{}
 	}
+
+	r := []string{}
+	for _, v := range l {
+		if s, ok := v.(string); ok {
+			r = append(r, s)
+		}
+	}
 	return r
 }
 
 func (s *DefaultConsentStrategy) IssueChallenge(authorizeRequest fosite.AuthorizeRequester, redirectURL string) (string, error) {
+	return s.issueChallenge(authorizeRequest, redirectURL, authorizeRequest.GetScopes(), nil)
+}
+
+// IssueIncrementalChallenge issues a challenge whose scp claim lists only the requested scopes which were not
+// granted before. The granted ones are listed in scp_granted, so that the consent app can tell the user what the
+// client already has access to.
+func (s *DefaultConsentStrategy) IssueIncrementalChallenge(authorizeRequest fosite.AuthorizeRequester, redirectURL string, granted fosite.Arguments) (string, error) {
+	scopes := fosite.Arguments{}
+	for _, scope := range authorizeRequest.GetScopes() {
+		if !granted.Has(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return s.issueChallenge(authorizeRequest, redirectURL, scopes, granted)
+}
+
+func (s *DefaultConsentStrategy) issueChallenge(authorizeRequest fosite.AuthorizeRequester, redirectURL string, scopes, granted fosite.Arguments) (string, error) {
 	token := jwt.New(jwt.SigningMethodRS256)
 	token.Claims = map[string]interface{}{
 		"jti":   uuid.New(),
-		"scp":   authorizeRequest.GetScopes(),
+		"scp":   scopes,
 		"aud":   authorizeRequest.GetClient().GetID(),
 		"exp":   time.Now().Add(time.Hour).Unix(),
 		"redir": redirectURL,
 	}
+	if len(granted) > 0 {
+		token.Claims["scp_granted"] = granted
+	}
+
+	// scp_remember_for maps the requested scopes with a lifespan of their own to the number of seconds a decision
+	// is remembered for them at most, zero meaning forever.
+	if lifespans := s.scopeLifespans(scopes); len(lifespans) > 0 {
+		token.Claims["scp_remember_for"] = lifespans
+	}
 
 	ks, err := s.KeyManager.GetKey(ConsentChallengeKey, "private")
 	if err != nil {
@@ -118,3 +242,52 @@ func (s *DefaultConsentStrategy) IssueChallenge(authorizeRequest fosite.Authoriz
 	return fmt.Sprintf("%s.%s", encoded, signature), nil
 
 }
+
+func (s *DefaultConsentStrategy) scopeLifespans(scopes fosite.Arguments) map[string]int64 {
+	lifespans := map[string]int64{}
+	for _, scope := range scopes {
+		if lifespan, ok := s.RememberConsentScopeLifespans[scope]; ok {
+			lifespans[scope] = int64(lifespan / time.Second)
+		}
+	}
+	return lifespans
+}
+
+func (s *DefaultConsentStrategy) ValidateChallenge(challenge string) (*ConsentChallenge, error) {
+	t, err := jwt.Parse(challenge, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.Errorf("Unexpected signing method: %v", t.Header["alg"])
+		}
+
+		pk, err := s.KeyManager.GetKey(ConsentChallengeKey, "public")
+		if err != nil {
+			return nil, err
+		}
+
+		rsaKey, ok := jwk.First(pk.Keys).Key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("Could not convert to RSA Public Key")
+		}
+		return rsaKey, nil
+	})
+
+	if err != nil {
+		return nil, errors.Errorf("Couldn't parse challenge: %v", err)
+	} else if !t.Valid {
+		return nil, errors.Errorf("Challenge is invalid")
+	}
+
+	expiresAt := ejwt.ToTime(t.Claims["exp"])
+	if time.Now().After(expiresAt) {
+		return nil, errors.Errorf("Challenge expired")
+	}
+
+	return &ConsentChallenge{
+		ID:            ejwt.ToString(t.Claims["jti"]),
+		ClientID:      ejwt.ToString(t.Claims["aud"]),
+		Scopes:        toStringSlice(t.Claims["scp"]),
+		RedirectURL:   ejwt.ToString(t.Claims["redir"]),
+		ExpiresAt:     expiresAt,
+		GrantedScopes: toStringSlice(t.Claims["scp_granted"]),
+	}, nil
+}
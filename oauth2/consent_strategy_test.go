@@ -0,0 +1,102 @@
+package oauth2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory-am/fosite"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsentStrategyValidateResponse(t *testing.T) {
+	s := &DefaultConsentStrategy{Issuer: "https://hydra.localhost", KeyManager: keyManager}
+	authorizeRequest := func() *fosite.AuthorizeRequest {
+		return &fosite.AuthorizeRequest{Request: fosite.Request{
+			Client: &fosite.DefaultClient{ID: "app-client"},
+			Scopes: fosite.Arguments{"openid", "photos"},
+		}}
+	}
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"jti": uuid.New(),
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"aud": "app-client",
+			"sub": "peter",
+			"scp": []string{"openid"},
+		}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	for k, c := range []struct {
+		claims map[string]interface{}
+		err    bool
+		check  func(a *fosite.AuthorizeRequest, session *Session)
+	}{
+		{
+			claims: claims(map[string]interface{}{"id_token": map[string]interface{}{"email": "peter@hydra.localhost"}, "acr": "1"}),
+			check: func(a *fosite.AuthorizeRequest, session *Session) {
+				assert.Equal(t, "peter", session.Subject)
+				assert.Equal(t, fosite.Arguments{"openid"}, a.GetGrantedScopes())
+				assert.Equal(t, map[string]interface{}{"email": "peter@hydra.localhost", "acr": "1"}, session.DefaultSession.Claims.Extra)
+			},
+		},
+		{
+			claims: claims(map[string]interface{}{"email": "peter@hydra.localhost"}),
+			check: func(a *fosite.AuthorizeRequest, session *Session) {
+				assert.Equal(t, "peter@hydra.localhost", session.DefaultSession.Claims.Extra["email"])
+				assert.Equal(t, "peter", session.DefaultSession.Claims.Extra["sub"])
+			},
+		},
+		{
+			claims: claims(map[string]interface{}{"remember_for": 3600}),
+			check: func(a *fosite.AuthorizeRequest, session *Session) {
+				assert.Equal(t, time.Hour, session.RememberFor)
+			},
+		},
+		{claims: claims(map[string]interface{}{"scp": []string{"openid", "admin"}}), err: true},
+		{claims: claims(map[string]interface{}{"aud": "other-client"}), err: true},
+		{claims: claims(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}), err: true},
+	} {
+		token, err := signConsentToken(c.claims)
+		require.Nil(t, err, "Case %d", k)
+
+		a := authorizeRequest()
+		session, err := s.ValidateResponse(a, token)
+		if c.err {
+			assert.NotNil(t, err, "Case %d", k)
+			continue
+		}
+		require.Nil(t, err, "Case %d", k)
+		c.check(a, session)
+	}
+}
+
+func TestConsentStrategyIssueIncrementalChallenge(t *testing.T) {
+	s := &DefaultConsentStrategy{Issuer: "https://hydra.localhost", KeyManager: keyManager}
+	authorizeRequest := &fosite.AuthorizeRequest{Request: fosite.Request{
+		Client: &fosite.DefaultClient{ID: "app-client"},
+		Scopes: fosite.Arguments{"openid", "photos", "contacts"},
+	}}
+
+	challenge, err := s.IssueIncrementalChallenge(authorizeRequest, "https://hydra.localhost/oauth2/auth", fosite.Arguments{"openid"})
+	require.Nil(t, err)
+
+	c, err := s.ValidateChallenge(challenge)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"photos", "contacts"}, c.Scopes)
+	assert.Equal(t, []string{"openid"}, c.GrantedScopes)
+
+	challenge, err = s.IssueChallenge(authorizeRequest, "https://hydra.localhost/oauth2/auth")
+	require.Nil(t, err)
+
+	c, err = s.ValidateChallenge(challenge)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"openid", "photos", "contacts"}, c.Scopes)
+	assert.Empty(t, c.GrantedScopes)
+}
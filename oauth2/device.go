@@ -0,0 +1,90 @@
+package oauth2
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+const (
+	// DeviceCodeGrantType is the grant type devices use to poll the token endpoint, see RFC 8628 section 3.4.
+	DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	DeviceGrantPending  = "pending"
+	DeviceGrantApproved = "approved"
+	DeviceGrantDenied   = "denied"
+
+	// slowDownIncrement is added to the polling interval of a device which polls too often.
+	slowDownIncrement = 5
+)
+
+var (
+	// ErrDeviceGrantResolved is returned when a device grant is approved or denied twice.
+	ErrDeviceGrantResolved = errors.New("The device grant has already been resolved")
+
+	// ErrDeviceGrantExpired is returned when a device polls for a grant which expired.
+	ErrDeviceGrantExpired = errors.New("The device code has expired")
+
+	// ErrDeviceGrantSlowDown is returned when a device polls before its interval elapsed.
+	ErrDeviceGrantSlowDown = errors.New("The device is polling too frequently")
+)
+
+// DeviceGrant is a pending device authorization request of RFC 8628. The device polls the token endpoint with the
+// device code while the user approves the user code on another device.
+type DeviceGrant struct {
+	// ID is the signature of the device code. The device code itself is never stored.
+	ID       string   `json:"-" gorethink:"id"`
+	UserCode string   `json:"user_code" gorethink:"user_code"`
+	ClientID string   `json:"client_id" gorethink:"client_id"`
+	Scopes   []string `json:"scopes" gorethink:"scopes"`
+	Status   string   `json:"status" gorethink:"status"`
+
+	// GrantedScopes and Session are set when the user approves the grant.
+	GrantedScopes []string `json:"granted_scopes,omitempty" gorethink:"granted_scopes,omitempty"`
+	Session       *Session `json:"-" gorethink:"session,omitempty"`
+
+	// Interval is the minimum number of seconds between two polls of the device.
+	Interval int64     `json:"interval" gorethink:"interval"`
+	PolledAt time.Time `json:"-" gorethink:"polled_at"`
+
+	RequestedAt time.Time `json:"requested_at" gorethink:"requested_at"`
+	ExpiresAt   time.Time `json:"expires_at" gorethink:"expires_at"`
+}
+
+// IsExpired returns true if the user did not approve the grant in time.
+func (g *DeviceGrant) IsExpired() bool {
+	return !time.Now().Before(g.ExpiresAt)
+}
+
+// poll records a poll at now. Polling before the interval elapsed returns ErrDeviceGrantSlowDown and increases the
+// interval as required by RFC 8628 section 3.5.
+func (g *DeviceGrant) poll(now time.Time) error {
+	last := g.PolledAt
+	g.PolledAt = now
+	if !last.IsZero() && now.Before(last.Add(time.Duration(g.Interval)*time.Second)) {
+		g.Interval += slowDownIncrement
+		return errors.New(ErrDeviceGrantSlowDown)
+	}
+	return nil
+}
+
+// DeviceGrantManager stores pending device grants.
+type DeviceGrantManager interface {
+	// CreateDeviceGrant stores a new device grant.
+	CreateDeviceGrant(g *DeviceGrant) error
+
+	// GetDeviceGrantByUserCode returns the device grant with the given user code or pkg.ErrNotFound if it does
+	// not exist or is expired.
+	GetDeviceGrantByUserCode(code string) (*DeviceGrant, error)
+
+	// PollDeviceGrant records a poll of the device and returns the grant. It returns pkg.ErrNotFound for unknown
+	// grants, ErrDeviceGrantExpired for expired ones and ErrDeviceGrantSlowDown if the device polls too often.
+	PollDeviceGrant(id string) (*DeviceGrant, error)
+
+	// ResolveDeviceGrant approves or denies a pending device grant. Approved grants carry the granted scopes and
+	// the session tokens are issued with.
+	ResolveDeviceGrant(id, status string, grantedScopes []string, session *Session) error
+
+	// DeleteDeviceGrant removes a device grant.
+	DeleteDeviceGrant(id string) error
+}
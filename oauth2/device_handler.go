@@ -0,0 +1,416 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/common/rand/sequence"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/scope"
+	"github.com/ory-am/hydra/tracing"
+	"golang.org/x/net/context"
+)
+
+const (
+	DeviceAuthorizationHandlerPath = "/oauth2/device/auth"
+	DeviceVerificationHandlerPath  = "/oauth2/device"
+)
+
+// DeviceHandler implements the device authorization grant of RFC 8628 for devices which lack a browser or have
+// limited input capabilities.
+//
+// The device requests a device and a user code at the device authorization endpoint and shows the user code to the
+// user. The user enters the code on the verification page, which is part of the consent app, and is sent to the
+// verification endpoint with the user_code query parameter. The verification endpoint redirects to the consent app
+// with a challenge like the authorize endpoint does. The consent app approves the grant by redirecting back with
+// a consent token, or denies it by redirecting back with error=access_denied and the challenge. Meanwhile the device
+// polls the token endpoint with the device code.
+type DeviceHandler struct {
+	Manager DeviceGrantManager
+	Clients ClientAuthenticator
+
+	Consent    ConsentStrategy
+	ConsentURL url.URL
+
+	// VerificationURL is the page users enter their user code on. It defaults to the consent app.
+	VerificationURL url.URL
+
+	AccessTokenStrategy  core.AccessTokenStrategy
+	AccessTokenStorage   core.AccessTokenStorage
+	AccessTokenLifespan  time.Duration
+	RefreshTokenStrategy core.RefreshTokenStrategy
+	RefreshTokenStorage  core.RefreshTokenStorage
+
+	// Lifespan is how long a device code is valid, Interval is the minimum time between two polls of a device.
+	Lifespan time.Duration
+	Interval time.Duration
+
+	H herodot.Herodot
+
+	// Settings holds the token lifespans clients override.
+	Settings client.SettingsManager
+
+	// ScopeStrategy must be the strategy of the Handler, nil is scope.Hierarchic.
+	ScopeStrategy scope.Strategy
+
+	// lifespan guards Lifespan, which SetLifespan changes while requests are served.
+	lifespan sync.RWMutex
+}
+
+// SetLifespan changes how long new device codes are valid.
+func (h *DeviceHandler) SetLifespan(lifespan time.Duration) {
+	h.lifespan.Lock()
+	defer h.lifespan.Unlock()
+
+	h.Lifespan = lifespan
+}
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+type tokenError struct {
+	Name        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (h *DeviceHandler) SetRoutes(r *httprouter.Router) {
+	r.POST(DeviceAuthorizationHandlerPath, h.Authorize)
+	r.GET(DeviceVerificationHandlerPath, h.Verify)
+}
+
+// Authorize issues a device code and a user code to an authenticated client.
+func (h *DeviceHandler) Authorize(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+
+	if err := r.ParseForm(); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	c, err := authenticateClient(h.Clients, r)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		w.Header().Set("WWW-Authenticate", `Basic realm="hydra"`)
+		h.H.WriteErrorCode(ctx, w, r, http.StatusUnauthorized, errors.New("Client authentication failed"))
+		return
+	}
+
+	// The device authorization endpoint answers with the errors of the token endpoint, see RFC 8628 section 3.2.
+	scopes := strings.Fields(r.PostForm.Get("scope"))
+	if !c.GetGrantTypes().Has(DeviceCodeGrantType) {
+		writeTokenError(w, http.StatusBadRequest, "unauthorized_client", "The client is not allowed to use the device authorization grant")
+		return
+	} else if err := checkClientScopes(h.ScopeStrategy, c, scopes); err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusBadRequest, "invalid_scope", "The client is not allowed to request the scopes")
+		return
+	}
+
+	deviceCode, err := randomSecret()
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	code, err := sequence.RuneSequence(8, userCodeRunes)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, errors.New(err))
+		return
+	}
+
+	now := time.Now().UTC()
+	g := &DeviceGrant{
+		ID:          bindingHash(deviceCode),
+		UserCode:    string(code),
+		ClientID:    c.GetID(),
+		Scopes:      scopes,
+		Status:      DeviceGrantPending,
+		Interval:    int64(h.getInterval() / time.Second),
+		RequestedAt: now,
+		ExpiresAt:   now.Add(h.getLifespan()),
+	}
+
+	if err := h.Manager.CreateDeviceGrant(g); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	// The user code is shown in two halves so that it is easier to read and type.
+	userCode := g.UserCode[:4] + "-" + g.UserCode[4:]
+	complete := url.URL{Scheme: "https", Host: r.Host, Path: DeviceVerificationHandlerPath}
+	if r.TLS == nil {
+		complete.Scheme = "http"
+	}
+	complete.RawQuery = url.Values{"user_code": {userCode}}.Encode()
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.H.Write(ctx, w, r, &deviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         h.getVerificationURL(),
+		VerificationURIComplete: complete.String(),
+		ExpiresIn:               int64(h.getLifespan() / time.Second),
+		Interval:                g.Interval,
+	})
+}
+
+// Verify asks the consent app to approve the device grant of a user code and records its decision.
+func (h *DeviceHandler) Verify(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+	q := r.URL.Query()
+
+	g, err := h.Manager.GetDeviceGrantByUserCode(normalizeUserCode(q.Get("user_code")))
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	} else if g.Status != DeviceGrantPending {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusConflict, errors.New(ErrDeviceGrantResolved))
+		return
+	}
+
+	authorizeRequest := &fosite.AuthorizeRequest{
+		Request: fosite.Request{
+			RequestedAt: g.RequestedAt,
+			Client:      &fosite.DefaultClient{ID: g.ClientID},
+			Scopes:      g.Scopes,
+		},
+	}
+
+	if q.Get("error") != "" {
+		if err := h.verifyDenial(g, q.Get("challenge")); err != nil {
+			logger.LogRequestError(r, err)
+			h.H.WriteErrorCode(ctx, w, r, http.StatusForbidden, errors.New(fosite.ErrAccessDenied))
+			return
+		}
+		h.resolve(ctx, w, r, g, DeviceGrantDenied, nil, nil)
+		return
+	}
+
+	consent := q.Get("consent")
+	if consent == "" {
+		if err := redirectToConsent(w, r, h.Consent, h.ConsentURL, authorizeRequest); err != nil {
+			h.H.WriteError(ctx, w, r, err)
+		}
+		return
+	}
+
+	session, err := h.Consent.ValidateResponse(authorizeRequest, consent)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		h.H.WriteErrorCode(ctx, w, r, http.StatusForbidden, errors.New(fosite.ErrAccessDenied))
+		return
+	}
+
+	h.resolve(ctx, w, r, g, DeviceGrantApproved, authorizeRequest.GetGrantedScopes(), session)
+}
+
+// verifyDenial makes sure that a denial comes from the consent app by checking that it carries the challenge which
+// was issued for the grant.
+func (h *DeviceHandler) verifyDenial(g *DeviceGrant, challenge string) error {
+	c, err := h.Consent.ValidateChallenge(challenge)
+	if err != nil {
+		return err
+	} else if c.ClientID != g.ClientID {
+		return errors.New("Challenge was issued for another client")
+	}
+
+	redirect, err := url.Parse(c.RedirectURL)
+	if err != nil {
+		return errors.New(err)
+	} else if normalizeUserCode(redirect.Query().Get("user_code")) != g.UserCode {
+		return errors.New("Challenge was issued for another user code")
+	}
+	return nil
+}
+
+func (h *DeviceHandler) resolve(ctx context.Context, w http.ResponseWriter, r *http.Request, g *DeviceGrant, status string, scopes []string, session *Session) {
+	if err := h.Manager.ResolveDeviceGrant(g.ID, status, scopes, session); errors.Is(err, ErrDeviceGrantResolved) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	g.Status = status
+	g.GrantedScopes = scopes
+	h.H.Write(ctx, w, r, g)
+}
+
+// TokenHandler answers the polls of a device at the token endpoint, see RFC 8628 section 3.4.
+func (h *DeviceHandler) TokenHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := tracing.Context(r)
+
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	c, err := authenticateClient(h.Clients, r)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		w.Header().Set("WWW-Authenticate", `Basic realm="hydra"`)
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	deviceCode := r.PostForm.Get("device_code")
+	if deviceCode == "" {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "Parameter device_code is missing")
+		return
+	}
+
+	g, err := h.Manager.PollDeviceGrant(bindingHash(deviceCode))
+	if errors.Is(err, ErrDeviceGrantSlowDown) {
+		writeTokenError(w, http.StatusBadRequest, "slow_down", err.Error())
+		return
+	} else if errors.Is(err, ErrDeviceGrantExpired) {
+		writeTokenError(w, http.StatusBadRequest, "expired_token", err.Error())
+		return
+	} else if errors.Is(err, pkg.ErrNotFound) {
+		writeTokenError(w, http.StatusBadRequest, "invalid_grant", "The device code is invalid")
+		return
+	} else if err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	} else if g.ClientID != c.GetID() {
+		writeTokenError(w, http.StatusBadRequest, "invalid_grant", "The device code was issued to another client")
+		return
+	}
+
+	switch g.Status {
+	case DeviceGrantPending:
+		writeTokenError(w, http.StatusBadRequest, "authorization_pending", "The user has not approved the device yet")
+		return
+	case DeviceGrantDenied:
+		if err := h.Manager.DeleteDeviceGrant(g.ID); err != nil {
+			logger.LogRequestError(r, err)
+		}
+		writeTokenError(w, http.StatusBadRequest, "access_denied", "The user denied the device")
+		return
+	}
+
+	// A device code can only be exchanged once.
+	if err := h.Manager.DeleteDeviceGrant(g.ID); err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	session := g.Session
+	if session == nil {
+		session = &Session{}
+	}
+
+	lifespans, err := clientLifespans(h.Settings, c.GetID())
+	if err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	setTokenLifespans(session, lifespans, time.Now())
+
+	accessRequest := fosite.NewAccessRequest(session)
+	accessRequest.GrantTypes = fosite.Arguments{DeviceCodeGrantType}
+	accessRequest.Client = c
+	accessRequest.Scopes = g.Scopes
+	accessRequest.Form = r.PostForm
+	for _, granted := range g.GrantedScopes {
+		accessRequest.GrantScope(granted)
+	}
+
+	token, signature, err := h.AccessTokenStrategy.GenerateAccessToken(ctx, accessRequest)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	} else if err := h.AccessTokenStorage.CreateAccessTokenSession(ctx, signature, accessRequest); err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	response := map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   expiresIn(lifespans, h.AccessTokenLifespan),
+		"scope":        strings.Join(accessRequest.GetGrantedScopes(), " "),
+	}
+
+	if accessRequest.GetGrantedScopes().Has("offline") {
+		refresh, signature, err := h.RefreshTokenStrategy.GenerateRefreshToken(ctx, accessRequest)
+		if err != nil {
+			logger.LogRequestError(r, err)
+			writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		} else if err := h.RefreshTokenStorage.CreateRefreshTokenSession(ctx, signature, accessRequest); err != nil {
+			logger.LogRequestError(r, err)
+			writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		response["refresh_token"] = refresh
+	}
+
+	writeTokenResponse(w, http.StatusOK, response)
+}
+
+func (h *DeviceHandler) getVerificationURL() string {
+	if h.VerificationURL.Host == "" && h.VerificationURL.Path == "" {
+		return h.ConsentURL.String()
+	}
+	return h.VerificationURL.String()
+}
+
+func (h *DeviceHandler) getLifespan() time.Duration {
+	h.lifespan.RLock()
+	defer h.lifespan.RUnlock()
+
+	if h.Lifespan == 0 {
+		return time.Minute * 10
+	}
+	return h.Lifespan
+}
+
+func (h *DeviceHandler) getInterval() time.Duration {
+	if h.Interval < time.Second {
+		return time.Second * 5
+	}
+	return h.Interval
+}
+
+// normalizeUserCode removes the separators users may type and ignores the case of the code.
+func normalizeUserCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func writeTokenError(w http.ResponseWriter, code int, name, description string) {
+	writeTokenResponse(w, code, &tokenError{Name: name, Description: description})
+}
+
+func writeTokenResponse(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.LogError(errors.New(err))
+	}
+}
@@ -0,0 +1,84 @@
+package oauth2
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type DeviceGrantMemoryManager struct {
+	Grants map[string]*DeviceGrant
+	sync.RWMutex
+}
+
+func NewDeviceGrantMemoryManager() *DeviceGrantMemoryManager {
+	return &DeviceGrantMemoryManager{
+		Grants: map[string]*DeviceGrant{},
+	}
+}
+
+func (m *DeviceGrantMemoryManager) CreateDeviceGrant(g *DeviceGrant) error {
+	m.Lock()
+	defer m.Unlock()
+
+	c := *g
+	m.Grants[g.ID] = &c
+	return nil
+}
+
+func (m *DeviceGrantMemoryManager) GetDeviceGrantByUserCode(code string) (*DeviceGrant, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, g := range m.Grants {
+		if code != "" && g.UserCode == code && !g.IsExpired() {
+			c := *g
+			return &c, nil
+		}
+	}
+	return nil, errors.New(pkg.ErrNotFound)
+}
+
+func (m *DeviceGrantMemoryManager) PollDeviceGrant(id string) (*DeviceGrant, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	g, ok := m.Grants[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if g.IsExpired() {
+		delete(m.Grants, id)
+		return nil, errors.New(ErrDeviceGrantExpired)
+	}
+
+	err := g.poll(time.Now())
+	c := *g
+	return &c, err
+}
+
+func (m *DeviceGrantMemoryManager) ResolveDeviceGrant(id, status string, grantedScopes []string, session *Session) error {
+	m.Lock()
+	defer m.Unlock()
+
+	g, ok := m.Grants[id]
+	if !ok || g.IsExpired() {
+		return errors.New(pkg.ErrNotFound)
+	} else if g.Status != DeviceGrantPending {
+		return errors.New(ErrDeviceGrantResolved)
+	}
+
+	g.Status = status
+	g.GrantedScopes = grantedScopes
+	g.Session = session
+	return nil
+}
+
+func (m *DeviceGrantMemoryManager) DeleteDeviceGrant(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Grants, id)
+	return nil
+}
@@ -0,0 +1,125 @@
+package oauth2
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// DeviceGrantRethinkManager reads and writes directly against the database because the user may approve a grant on
+// a different instance than the one the device is polling.
+type DeviceGrantRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *DeviceGrantRethinkManager) CreateDeviceGrant(g *DeviceGrant) error {
+	if _, err := m.Table.Insert(g, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *DeviceGrantRethinkManager) getDeviceGrant(id string) (*DeviceGrant, error) {
+	res, err := m.Table.Get(id).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var g DeviceGrant
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&g); err != nil {
+		return nil, errors.New(err)
+	}
+	return &g, nil
+}
+
+func (m *DeviceGrantRethinkManager) GetDeviceGrantByUserCode(code string) (*DeviceGrant, error) {
+	if code == "" {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	rows, err := m.Table.Filter(map[string]interface{}{"user_code": code}).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var g *DeviceGrant
+	for rows.Next(&g) {
+		if !g.IsExpired() {
+			return g, nil
+		}
+		g = nil
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.New(rows.Err())
+	}
+	return nil, errors.New(pkg.ErrNotFound)
+}
+
+func (m *DeviceGrantRethinkManager) PollDeviceGrant(id string) (*DeviceGrant, error) {
+	g, err := m.getDeviceGrant(id)
+	if err != nil {
+		return nil, err
+	} else if g.IsExpired() {
+		if err := m.DeleteDeviceGrant(id); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(ErrDeviceGrantExpired)
+	}
+
+	last := g.PolledAt
+	pollErr := g.poll(time.Now().UTC())
+
+	// Only record the poll if no other poll was recorded in the meantime, two concurrent polls are too frequent.
+	res, err := m.Table.Get(id).Update(func(row r.Term) interface{} {
+		return r.Branch(row.Field("polled_at").Eq(last), map[string]interface{}{
+			"polled_at": g.PolledAt,
+			"interval":  g.Interval,
+		}, map[string]interface{}{})
+	}).RunWrite(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	} else if res.Replaced == 0 && pollErr == nil {
+		return g, errors.New(ErrDeviceGrantSlowDown)
+	}
+	return g, pollErr
+}
+
+func (m *DeviceGrantRethinkManager) ResolveDeviceGrant(id, status string, grantedScopes []string, session *Session) error {
+	if g, err := m.getDeviceGrant(id); err != nil {
+		return err
+	} else if g.IsExpired() {
+		return errors.New(pkg.ErrNotFound)
+	} else if g.Status != DeviceGrantPending {
+		return errors.New(ErrDeviceGrantResolved)
+	}
+
+	// Only update the grant if it is still pending so that two concurrent decisions can not both succeed.
+	res, err := m.Table.Get(id).Update(func(row r.Term) interface{} {
+		return r.Branch(row.Field("status").Eq(DeviceGrantPending), map[string]interface{}{
+			"status":         status,
+			"granted_scopes": grantedScopes,
+			"session":        session,
+		}, map[string]interface{}{})
+	}).RunWrite(m.Session)
+	if err != nil {
+		return errors.New(err)
+	} else if res.Replaced == 0 {
+		return errors.New(ErrDeviceGrantResolved)
+	}
+	return nil
+}
+
+func (m *DeviceGrantRethinkManager) DeleteDeviceGrant(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package oauth2_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	hc "github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/herodot"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceGrantMemoryManager(t *testing.T) {
+	m := NewDeviceGrantMemoryManager()
+
+	require.Nil(t, m.CreateDeviceGrant(&DeviceGrant{
+		ID:        "foo",
+		UserCode:  "ABCD2345",
+		ClientID:  "app",
+		Status:    DeviceGrantPending,
+		Interval:  5,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}))
+	require.Nil(t, m.CreateDeviceGrant(&DeviceGrant{
+		ID:        "expired",
+		UserCode:  "EFGH6789",
+		Status:    DeviceGrantPending,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	g, err := m.GetDeviceGrantByUserCode("ABCD2345")
+	require.Nil(t, err)
+	assert.Equal(t, "foo", g.ID)
+
+	_, err = m.GetDeviceGrantByUserCode("EFGH6789")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+
+	_, err = m.PollDeviceGrant("expired")
+	assert.True(t, errors.Is(err, ErrDeviceGrantExpired))
+	_, err = m.PollDeviceGrant("bar")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+
+	g, err = m.PollDeviceGrant("foo")
+	require.Nil(t, err)
+	assert.Equal(t, DeviceGrantPending, g.Status)
+
+	// Polling again before the interval elapsed slows the device down.
+	g, err = m.PollDeviceGrant("foo")
+	assert.True(t, errors.Is(err, ErrDeviceGrantSlowDown))
+	assert.Equal(t, int64(10), g.Interval)
+
+	require.Nil(t, m.ResolveDeviceGrant("foo", DeviceGrantApproved, []string{"core"}, &Session{Subject: "peter"}))
+	assert.True(t, errors.Is(m.ResolveDeviceGrant("foo", DeviceGrantDenied, nil, nil), ErrDeviceGrantResolved))
+	assert.True(t, errors.Is(m.ResolveDeviceGrant("expired", DeviceGrantApproved, nil, nil), pkg.ErrNotFound))
+
+	require.Nil(t, m.DeleteDeviceGrant("foo"))
+	_, err = m.PollDeviceGrant("foo")
+	assert.True(t, errors.Is(err, pkg.ErrNotFound))
+}
+
+func TestDeviceFlow(t *testing.T) {
+	clients := &hc.MemoryManager{Clients: map[string]*fosite.DefaultClient{}, Hasher: hasher}
+	for _, id := range []string{"device-client", "other-client"} {
+		require.Nil(t, clients.CreateClient(&fosite.DefaultClient{
+			ID:            id,
+			Secret:        []byte("secret"),
+			GrantTypes:    []string{DeviceCodeGrantType},
+			GrantedScopes: []string{"core", "offline"},
+		}))
+	}
+	require.Nil(t, clients.CreateClient(&fosite.DefaultClient{ID: "web-client", Secret: []byte("secret"), GrantTypes: []string{"authorization_code"}, GrantedScopes: []string{"core"}}))
+
+	grants := NewDeviceGrantMemoryManager()
+	consentURL, _ := url.Parse("http://consent.localhost/")
+	h := &DeviceHandler{
+		Manager:              grants,
+		Clients:              clients,
+		Consent:              handler.Consent,
+		ConsentURL:           *consentURL,
+		AccessTokenStrategy:  hmacStrategy,
+		AccessTokenStorage:   store,
+		AccessTokenLifespan:  time.Hour,
+		RefreshTokenStrategy: hmacStrategy,
+		RefreshTokenStorage:  store,
+		H:                    &herodot.JSON{},
+	}
+	r := httprouter.New()
+	h.SetRoutes(r)
+	r.POST("/oauth2/token", (&Handler{Device: h}).TokenHandler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	post := func(path, id string, form url.Values, v interface{}) int {
+		req, err := http.NewRequest("POST", server.URL+path, strings.NewReader(form.Encode()))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(id, "secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer res.Body.Close()
+		require.Nil(t, json.NewDecoder(res.Body).Decode(v))
+		return res.StatusCode
+	}
+
+	// Clients may only request the scopes they were granted, and only if they may use the grant.
+	for k, c := range []struct {
+		client string
+		scope  string
+		error  string
+	}{
+		{client: "device-client", scope: "core hydra", error: "invalid_scope"},
+		{client: "web-client", scope: "core", error: "unauthorized_client"},
+	} {
+		var result map[string]interface{}
+		assert.Equal(t, http.StatusBadRequest, post(DeviceAuthorizationHandlerPath, c.client, url.Values{"scope": {c.scope}}, &result), "Case %d", k)
+		assert.Equal(t, c.error, result["error"], "Case %d", k)
+	}
+
+	var auth struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		Interval                int64  `json:"interval"`
+	}
+	require.Equal(t, http.StatusOK, post(DeviceAuthorizationHandlerPath, "device-client", url.Values{"scope": {"core offline"}}, &auth))
+	assert.NotEmpty(t, auth.DeviceCode)
+	assert.Len(t, auth.UserCode, 9)
+	assert.Equal(t, consentURL.String(), auth.VerificationURI)
+	assert.Contains(t, auth.VerificationURIComplete, DeviceVerificationHandlerPath+"?user_code=")
+	assert.Equal(t, int64(5), auth.Interval)
+
+	// poll polls the token endpoint as if the interval had elapsed and returns the error code.
+	poll := func(id string) (int, map[string]interface{}) {
+		grants.Lock()
+		for _, g := range grants.Grants {
+			g.PolledAt = time.Time{}
+		}
+		grants.Unlock()
+
+		var result map[string]interface{}
+		code := post("/oauth2/token", id, url.Values{"grant_type": {DeviceCodeGrantType}, "device_code": {auth.DeviceCode}}, &result)
+		return code, result
+	}
+
+	code, result := poll("device-client")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "authorization_pending", result["error"])
+
+	code, result = poll("other-client")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_grant", result["error"])
+
+	var slow map[string]interface{}
+	post("/oauth2/token", "device-client", url.Values{"grant_type": {DeviceCodeGrantType}, "device_code": {auth.DeviceCode}}, &slow)
+	assert.Equal(t, "slow_down", slow["error"])
+
+	// Without a consent token the user is sent to the consent app.
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "http://hydra.localhost"+DeviceVerificationHandlerPath+"?user_code="+strings.ToLower(auth.UserCode), nil)
+	require.Nil(t, err)
+	h.Verify(w, req, nil)
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.Nil(t, err)
+	assert.Equal(t, "consent.localhost", location.Host)
+	assert.NotEmpty(t, location.Query().Get("challenge"))
+
+	consent, err := signConsentToken(map[string]interface{}{
+		"jti": uuid.New(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+		"aud": "device-client",
+		"sub": "peter",
+		"scp": []string{"core", "offline"},
+	})
+	require.Nil(t, err)
+
+	res, err := http.Get(server.URL + DeviceVerificationHandlerPath + "?user_code=" + auth.UserCode + "&consent=" + consent)
+	require.Nil(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Get(server.URL + DeviceVerificationHandlerPath + "?user_code=" + auth.UserCode + "&consent=" + consent)
+	require.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	code, result = poll("device-client")
+	require.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, result["access_token"])
+	assert.NotEmpty(t, result["refresh_token"])
+	assert.Equal(t, "core offline", result["scope"])
+
+	// The device code can only be exchanged once.
+	code, result = poll("device-client")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid_grant", result["error"])
+}
@@ -0,0 +1,272 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/square/go-jose"
+)
+
+const (
+	WellKnownHandlerPath                   = "/.well-known/openid-configuration"
+	AuthorizationServerMetadataHandlerPath = "/.well-known/oauth-authorization-server"
+	JWKsHandlerPath                        = "/.well-known/jwks.json"
+)
+
+// DiscoveryDocument is the OpenID Connect provider metadata, see OpenID Connect Discovery 1.0 section 3.
+type DiscoveryDocument struct {
+	Issuer                              string   `json:"issuer"`
+	AuthorizationEndpoint               string   `json:"authorization_endpoint"`
+	TokenEndpoint                       string   `json:"token_endpoint"`
+	JWKsURI                             string   `json:"jwks_uri"`
+	RegistrationEndpoint                string   `json:"registration_endpoint,omitempty"`
+	IntrospectionEndpoint               string   `json:"introspection_endpoint"`
+	RevocationEndpoint                  string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint         string   `json:"device_authorization_endpoint,omitempty"`
+	ScopesSupported                     []string `json:"scopes_supported"`
+	ResponseTypesSupported              []string `json:"response_types_supported"`
+	GrantTypesSupported                 []string `json:"grant_types_supported"`
+	SubjectTypesSupported               []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported    []string `json:"id_token_signing_alg_values_supported"`
+	IDTokenEncryptionAlgValuesSupported []string `json:"id_token_encryption_alg_values_supported"`
+	IDTokenEncryptionEncValuesSupported []string `json:"id_token_encryption_enc_values_supported"`
+	TokenEndpointAuthMethodsSupported   []string `json:"token_endpoint_auth_methods_supported"`
+	ClaimsSupported                     []string `json:"claims_supported"`
+	EndSessionEndpoint                  string   `json:"end_session_endpoint,omitempty"`
+	BackChannelLogoutSupported          bool     `json:"backchannel_logout_supported"`
+	BackChannelLogoutSessionSupported   bool     `json:"backchannel_logout_session_supported"`
+}
+
+// AuthorizationServerMetadata is the OAuth 2.0 authorization server metadata of RFC 8414, which is read by clients
+// and gateways that do not speak OpenID Connect.
+type AuthorizationServerMetadata struct {
+	Issuer                                 string   `json:"issuer"`
+	AuthorizationEndpoint                  string   `json:"authorization_endpoint"`
+	TokenEndpoint                          string   `json:"token_endpoint"`
+	JWKsURI                                string   `json:"jwks_uri"`
+	RegistrationEndpoint                   string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported                        []string `json:"scopes_supported"`
+	ResponseTypesSupported                 []string `json:"response_types_supported"`
+	ResponseModesSupported                 []string `json:"response_modes_supported"`
+	GrantTypesSupported                    []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported      []string `json:"token_endpoint_auth_methods_supported"`
+	RevocationEndpoint                     string   `json:"revocation_endpoint"`
+	RevocationEndpointAuthMethodsSupported []string `json:"revocation_endpoint_auth_methods_supported"`
+	IntrospectionEndpoint                  string   `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint            string   `json:"device_authorization_endpoint,omitempty"`
+
+	// MTLSEndpointAliases are the endpoints clients authenticating with a TLS client certificate use instead, see
+	// RFC 8705 section 5.
+	MTLSEndpointAliases *MTLSEndpointAliases `json:"mtls_endpoint_aliases,omitempty"`
+}
+
+// MTLSEndpointAliases are the endpoints of the authorization server metadata which are served at another URL to
+// clients with a TLS client certificate.
+type MTLSEndpointAliases struct {
+	TokenEndpoint               string `json:"token_endpoint"`
+	RevocationEndpoint          string `json:"revocation_endpoint"`
+	IntrospectionEndpoint       string `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+}
+
+// DiscoveryHandler serves the OpenID Connect discovery document and the public keys tokens are signed with. Both
+// are generated from the key manager on every request, so rotated keys and changed algorithms show up right away.
+type DiscoveryHandler struct {
+	Issuer string
+
+	// BaseURL is the public URL of hydra the endpoints are resolved against.
+	BaseURL url.URL
+
+	KeyManager jwk.Manager
+
+	// IDTokenKeySet signs ID tokens. The public keys of it and of KeySets are published at JWKsHandlerPath.
+	IDTokenKeySet string
+	KeySets       []string
+
+	// Scopes are the scopes advertised in addition to openid and offline.
+	Scopes []string
+
+	// GrantTypes are the grant types the token endpoint accepts.
+	GrantTypes []string
+
+	// Registration advertises the dynamic client registration endpoint.
+	Registration bool
+
+	// Logout advertises RP-initiated and back-channel logout.
+	Logout bool
+
+	// MTLSBaseURL is the URL of a proxy which requires TLS client certificates and forwards to hydra. The endpoints
+	// behind it are advertised as mTLS endpoint aliases if it is set.
+	MTLSBaseURL *url.URL
+
+	H herodot.Herodot
+}
+
+func (h *DiscoveryHandler) SetRoutes(r *httprouter.Router) {
+	r.GET(WellKnownHandlerPath, h.WellKnown)
+	r.GET(AuthorizationServerMetadataHandlerPath, h.AuthorizationServerMetadata)
+	r.GET(JWKsHandlerPath, h.JWKs)
+}
+
+func (h *DiscoveryHandler) WellKnown(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	algorithms, err := h.signingAlgorithms()
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	d := &DiscoveryDocument{
+		Issuer:                              h.Issuer,
+		AuthorizationEndpoint:               h.endpoint("/oauth2/auth"),
+		TokenEndpoint:                       h.endpoint("/oauth2/token"),
+		JWKsURI:                             h.endpoint(JWKsHandlerPath),
+		IntrospectionEndpoint:               h.endpoint(IntrospectionHandlerPath),
+		RevocationEndpoint:                  h.endpoint(RevocationHandlerPath),
+		ScopesSupported:                     h.scopes(),
+		ResponseTypesSupported:              responseTypes,
+		GrantTypesSupported:                 h.GrantTypes,
+		SubjectTypesSupported:               []string{"public"},
+		IDTokenSigningAlgValuesSupported:    algorithms,
+		IDTokenEncryptionAlgValuesSupported: sortedKeys(client.IDTokenEncryptionAlgorithms),
+		IDTokenEncryptionEncValuesSupported: sortedKeys(client.IDTokenEncryptionEncodings),
+		TokenEndpointAuthMethodsSupported:   clientAuthMethods,
+		ClaimsSupported:                     []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "acr", "amr"},
+	}
+	if h.Logout {
+		d.EndSessionEndpoint = h.endpoint(LogoutHandlerPath)
+		d.BackChannelLogoutSupported = true
+		d.BackChannelLogoutSessionSupported = true
+		d.ClaimsSupported = append(d.ClaimsSupported, "sid")
+	}
+	if h.Registration {
+		d.RegistrationEndpoint = h.endpoint("/oauth2/register")
+	}
+	if h.deviceFlow() {
+		d.DeviceAuthorizationEndpoint = h.endpoint(DeviceAuthorizationHandlerPath)
+	}
+
+	h.H.Write(ctx, w, r, d)
+}
+
+// AuthorizationServerMetadata serves the metadata of RFC 8414. It describes the same endpoints as the OpenID Connect
+// discovery document.
+func (h *DiscoveryHandler) AuthorizationServerMetadata(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	m := &AuthorizationServerMetadata{
+		Issuer:                                 h.Issuer,
+		AuthorizationEndpoint:                  h.endpoint("/oauth2/auth"),
+		TokenEndpoint:                          h.endpoint("/oauth2/token"),
+		JWKsURI:                                h.endpoint(JWKsHandlerPath),
+		ScopesSupported:                        h.scopes(),
+		ResponseTypesSupported:                 responseTypes,
+		ResponseModesSupported:                 []string{"query", "fragment"},
+		GrantTypesSupported:                    h.GrantTypes,
+		TokenEndpointAuthMethodsSupported:      clientAuthMethods,
+		RevocationEndpoint:                     h.endpoint(RevocationHandlerPath),
+		RevocationEndpointAuthMethodsSupported: clientAuthMethods,
+		IntrospectionEndpoint:                  h.endpoint(IntrospectionHandlerPath),
+	}
+	if h.Registration {
+		m.RegistrationEndpoint = h.endpoint("/oauth2/register")
+	}
+	if h.deviceFlow() {
+		m.DeviceAuthorizationEndpoint = h.endpoint(DeviceAuthorizationHandlerPath)
+	}
+
+	if h.MTLSBaseURL != nil {
+		m.MTLSEndpointAliases = &MTLSEndpointAliases{
+			TokenEndpoint:         pkg.JoinURL(h.MTLSBaseURL, "/oauth2/token").String(),
+			RevocationEndpoint:    pkg.JoinURL(h.MTLSBaseURL, RevocationHandlerPath).String(),
+			IntrospectionEndpoint: pkg.JoinURL(h.MTLSBaseURL, IntrospectionHandlerPath).String(),
+		}
+		if h.deviceFlow() {
+			m.MTLSEndpointAliases.DeviceAuthorizationEndpoint = pkg.JoinURL(h.MTLSBaseURL, DeviceAuthorizationHandlerPath).String()
+		}
+	}
+
+	h.H.Write(ctx, w, r, m)
+}
+
+var (
+	responseTypes = []string{
+		"code", "token", "id_token",
+		"code id_token", "code token", "id_token token", "code id_token token",
+	}
+
+	clientAuthMethods = []string{"client_secret_basic", "client_secret_post"}
+)
+
+func (h *DiscoveryHandler) scopes() []string {
+	return append([]string{"openid", "offline"}, h.Scopes...)
+}
+
+func (h *DiscoveryHandler) deviceFlow() bool {
+	for _, grantType := range h.GrantTypes {
+		if grantType == DeviceCodeGrantType {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *DiscoveryHandler) JWKs(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var ctx = herodot.NewContext()
+
+	set := &jose.JsonWebKeySet{Keys: []jose.JsonWebKey{}}
+	for _, name := range append([]string{h.IDTokenKeySet}, h.KeySets...) {
+		keys, err := h.KeyManager.GetKeySet(name)
+		if errors.Is(err, pkg.ErrNotFound) {
+			continue
+		} else if err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		set.Keys = append(set.Keys, jwk.PublicKeys(keys.Keys)...)
+	}
+
+	h.H.Write(ctx, w, r, set)
+}
+
+// signingAlgorithms returns the algorithms of the public ID token keys, sorted.
+func (h *DiscoveryHandler) signingAlgorithms() ([]string, error) {
+	keys, err := h.KeyManager.GetKeySet(h.IDTokenKeySet)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	algorithms := []string{}
+	for _, key := range jwk.PublicKeys(keys.Keys) {
+		if alg := jwk.SigningAlgorithm(&key); alg != "" && !seen[alg] {
+			seen[alg] = true
+			algorithms = append(algorithms, alg)
+		}
+	}
+	sort.Strings(algorithms)
+	return algorithms, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (h *DiscoveryHandler) endpoint(path string) string {
+	return pkg.JoinURL(&h.BaseURL, path).String()
+}
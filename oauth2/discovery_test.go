@@ -0,0 +1,92 @@
+package oauth2_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/jwk"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscovery(t *testing.T) {
+	km := &jwk.MemoryManager{}
+	keys, err := new(jwk.RS256Generator).Generate("1")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(OpenIDConnectKeyName, keys))
+
+	h := &DiscoveryHandler{
+		Issuer:        "https://hydra.localhost",
+		BaseURL:       url.URL{Scheme: "https", Host: "hydra.localhost"},
+		KeyManager:    km,
+		IDTokenKeySet: OpenIDConnectKeyName,
+		KeySets:       []string{AccessTokenKeyName},
+		Scopes:        []string{"core"},
+		GrantTypes:    []string{"authorization_code", DeviceCodeGrantType},
+		H:             &herodot.JSON{},
+	}
+	r := httprouter.New()
+	h.SetRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	get := func(path string, v interface{}) {
+		res, err := http.Get(server.URL + path)
+		require.Nil(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Nil(t, json.NewDecoder(res.Body).Decode(v))
+	}
+
+	var d DiscoveryDocument
+	get(WellKnownHandlerPath, &d)
+	assert.Equal(t, "https://hydra.localhost", d.Issuer)
+	assert.Equal(t, "https://hydra.localhost/oauth2/auth", d.AuthorizationEndpoint)
+	assert.Equal(t, "https://hydra.localhost/oauth2/token", d.TokenEndpoint)
+	assert.Equal(t, "https://hydra.localhost/.well-known/jwks.json", d.JWKsURI)
+	assert.Equal(t, "https://hydra.localhost/oauth2/device/auth", d.DeviceAuthorizationEndpoint)
+	assert.Empty(t, d.RegistrationEndpoint)
+	assert.Equal(t, []string{"openid", "offline", "core"}, d.ScopesSupported)
+	assert.Equal(t, []string{"RS256"}, d.IDTokenSigningAlgValuesSupported)
+
+	var m AuthorizationServerMetadata
+	get(AuthorizationServerMetadataHandlerPath, &m)
+	assert.Equal(t, "https://hydra.localhost", m.Issuer)
+	assert.Equal(t, "https://hydra.localhost/oauth2/revoke", m.RevocationEndpoint)
+	assert.Equal(t, d.IntrospectionEndpoint, m.IntrospectionEndpoint)
+	assert.Equal(t, d.DeviceAuthorizationEndpoint, m.DeviceAuthorizationEndpoint)
+	assert.Equal(t, d.ScopesSupported, m.ScopesSupported)
+	assert.Nil(t, m.MTLSEndpointAliases)
+
+	h.MTLSBaseURL = &url.URL{Scheme: "https", Host: "mtls.hydra.localhost"}
+	get(AuthorizationServerMetadataHandlerPath, &m)
+	require.NotNil(t, m.MTLSEndpointAliases)
+	assert.Equal(t, "https://mtls.hydra.localhost/oauth2/token", m.MTLSEndpointAliases.TokenEndpoint)
+
+	var set jose.JsonWebKeySet
+	get(JWKsHandlerPath, &set)
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "public:1", set.Keys[0].KeyID)
+
+	// Keys added later are picked up without a restart.
+	ec, err := new(jwk.ECDSA256Generator).Generate("2")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(OpenIDConnectKeyName, ec))
+	access, err := new(jwk.RS256Generator).Generate("3")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(AccessTokenKeyName, access))
+
+	get(WellKnownHandlerPath, &d)
+	assert.Equal(t, []string{"ES256", "RS256"}, d.IDTokenSigningAlgValuesSupported)
+
+	get(JWKsHandlerPath, &set)
+	assert.Len(t, set.Keys, 3)
+	assert.Len(t, jwk.PublicKeys(set.Keys), 3)
+}
@@ -1,17 +1,39 @@
 package oauth2
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/logger"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/quota"
+	"github.com/ory-am/hydra/scope"
+	"github.com/ory-am/hydra/tracing"
+	"github.com/pborman/uuid"
 )
 
 const (
 	OpenIDConnectKeyName = "hydra.openid.connect"
+
+	pendingConsentCookie    = "hydra_pending_consent_"
+	rememberedConsentCookie = "hydra_remembered_consent_"
+
+	// DefaultRememberConsentMaxLifespan is the longest a consent decision is remembered if the handler does not
+	// say otherwise.
+	DefaultRememberConsentMaxLifespan = time.Hour * 24 * 30
 )
 
 type Handler struct {
@@ -19,21 +41,105 @@ type Handler struct {
 	Consent ConsentStrategy
 
 	ConsentURL url.URL
+
+	// PendingConsents holds authorization requests whose consent decision has been deferred by the consent app.
+	// Deferred consent is disabled if PendingConsents is nil.
+	PendingConsents PendingConsentManager
+
+	// Device answers token requests of the device authorization grant. The grant is disabled if Device is nil.
+	Device *DeviceHandler
+
+	// Exchange answers token exchange requests. Token exchange is disabled if Exchange is nil.
+	Exchange *TokenExchangeHandler
+
+	// Introspection describes tokens to the gRPC services, which are served outside of the router.
+	Introspection *IntrospectionHandler
+
+	// RememberedConsents holds the consent decisions the consent app asked to remember. Requests covered by a
+	// remembered decision skip the consent app. Remembering is disabled if RememberedConsents is nil.
+	RememberedConsents RememberedConsentManager
+
+	// RememberConsentMaxLifespan caps how long a decision is remembered.
+	RememberConsentMaxLifespan time.Duration
+
+	// RememberConsentScopeLifespans cap how long the decision is remembered for individual scopes instead of
+	// RememberConsentMaxLifespan. A lifespan of zero does not cap the scope at all.
+	RememberConsentScopeLifespans map[string]time.Duration
+
+	// LoginSessions tracks the browser sessions of authenticated subjects, which is required by RP-initiated and
+	// back-channel logout. Sessions are not tracked if LoginSessions is nil.
+	LoginSessions        LoginSessionManager
+	LoginSessionLifespan time.Duration
+
+	// Lockouts locks clients which fail to authenticate at the token endpoint too often. Clients are never locked
+	// if Lockouts is nil.
+	Lockouts *client.Lockouts
+
+	// Settings holds the token lifespans clients override. All tokens live as long as the server says if Settings
+	// is nil.
+	Settings client.SettingsManager
+
+	// Audiences decides which audiences clients may restrict their tokens to with the audience and resource
+	// parameters. Requests for an audience are refused if Audiences is nil.
+	Audiences AudiencePolicy
+
+	// ScopeStrategy decides whether the scopes granted to a client cover the scopes it requests, nil is
+	// scope.Hierarchic.
+	ScopeStrategy scope.Strategy
+
+	// lifespans guards the lifespans, which SetLifespans changes while requests are served.
+	lifespans sync.RWMutex
+}
+
+// SetLifespans changes how long consent decisions are remembered and login sessions last, for example after the
+// configuration was reloaded.
+func (o *Handler) SetLifespans(rememberConsentMax, loginSession time.Duration) {
+	o.lifespans.Lock()
+	defer o.lifespans.Unlock()
+
+	o.RememberConsentMaxLifespan = rememberConsentMax
+	o.LoginSessionLifespan = loginSession
 }
 
 func (h *Handler) SetRoutes(r *httprouter.Router) {
-	r.POST("/oauth2/token", h.TokenHandler)
-	r.GET("/oauth2/auth", h.AuthHandler)
-	r.POST("/oauth2/auth", h.AuthHandler)
+	r.POST("/oauth2/token", instrument("token", tokenRequestLabels, h.TokenHandler))
+	r.GET("/oauth2/auth", instrument("authorize", authorizeRequestLabels, h.AuthHandler))
+	r.POST("/oauth2/auth", instrument("authorize", authorizeRequestLabels, h.AuthHandler))
 }
 
-func (o *Handler) TokenHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+func (o *Handler) TokenHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	switch grantType := r.PostFormValue("grant_type"); {
+	case o.Device != nil && grantType == DeviceCodeGrantType:
+		o.Device.TokenHandler(w, r, ps)
+		return
+	case o.Exchange != nil && grantType == TokenExchangeGrantType:
+		o.Exchange.TokenHandler(w, r, ps)
+		return
+	}
+
 	var session Session
-	var ctx = fosite.NewContext()
+	var ctx = tracing.Context(r)
+
+	_, clientID := tokenRequestLabels(r)
+	if o.Lockouts != nil {
+		if err := o.Lockouts.Check(clientID); err != nil {
+			logger.LogRequestError(r, err)
+			o.OAuth2.WriteAccessError(w, fosite.NewAccessRequest(&session), errors.New(fosite.ErrInvalidClient))
+			return
+		}
+	}
 
 	accessRequest, err := o.OAuth2.NewAccessRequest(ctx, r, &session)
+	o.recordClientAuthentication(r, clientID, err)
 	if err != nil {
-		pkg.LogError(err)
+		logger.LogRequestError(r, err)
+		o.OAuth2.WriteAccessError(w, accessRequest, err)
+		return
+	}
+	logger.AddFields(r, logger.Fields{"client_id": accessRequest.GetClient().GetID()})
+
+	if err := checkClientScopes(o.ScopeStrategy, accessRequest.GetClient(), accessRequest.GetScopes()); err != nil {
+		logger.LogRequestError(r, err)
 		o.OAuth2.WriteAccessError(w, accessRequest, err)
 		return
 	}
@@ -42,74 +148,550 @@ func (o *Handler) TokenHandler(w http.ResponseWriter, r *http.Request, _ httprou
 		session.Subject = accessRequest.GetClient().GetID()
 	}
 
+	if err := o.grantAudience(accessRequest.GetRequestForm(), accessRequest.GetClient().GetID(), accessRequest.GetGrantedScopes(), &session); err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusBadRequest, "invalid_target", err.Error())
+		return
+	}
+
+	lifespans, err := clientLifespans(o.Settings, accessRequest.GetClient().GetID())
+	if err != nil {
+		logger.LogRequestError(r, err)
+		o.OAuth2.WriteAccessError(w, accessRequest, err)
+		return
+	}
+
+	// Authorize codes and refresh tokens of clients with shorter lifespans than the server's expire before fosite
+	// considers them expired.
+	grantTypes := accessRequest.GetGrantTypes()
+	if (grantTypes.Exact("authorization_code") && session.IsTokenExpired(pkg.TokenKindAuthorizeCode)) ||
+		(grantTypes.Exact("refresh_token") && session.IsTokenExpired(pkg.TokenKindRefreshToken)) {
+		err := errors.New(fosite.ErrInvalidGrant)
+		logger.LogRequestError(r, err)
+		o.OAuth2.WriteAccessError(w, accessRequest, err)
+		return
+	}
+	setTokenLifespans(&session, lifespans, time.Now())
+
 	accessResponse, err := o.OAuth2.NewAccessResponse(ctx, r, accessRequest)
 	if err != nil {
-		pkg.LogError(err)
+		logger.LogRequestError(r, err)
 		o.OAuth2.WriteAccessError(w, accessRequest, err)
 		return
 	}
+	if lifespans.AccessToken > 0 {
+		accessResponse.SetExtra("expires_in", expiresIn(lifespans, 0))
+	}
 
 	o.OAuth2.WriteAccessResponse(w, accessRequest, accessResponse)
 }
 
+// recordClientAuthentication counts a failed client authentication towards a lockout of the client and forgets the
+// failures once the client authenticates.
+func (o *Handler) recordClientAuthentication(r *http.Request, clientID string, err error) {
+	if o.Lockouts == nil {
+		return
+	}
+
+	var lerr error
+	if err == nil {
+		lerr = o.Lockouts.Succeed(clientID)
+	} else if errors.Is(err, fosite.ErrInvalidClient) {
+		lerr = o.Lockouts.Fail(clientID)
+	}
+	if lerr != nil {
+		logger.LogRequestError(r, lerr)
+	}
+}
+
 func (o *Handler) AuthHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	var ctx = fosite.NewContext()
+	var ctx = tracing.Context(r)
 
 	authorizeRequest, err := o.OAuth2.NewAuthorizeRequest(ctx, r)
 	if err != nil {
-		pkg.LogError(err)
+		logger.LogRequestError(r, err)
+		o.writeAuthorizeError(w, authorizeRequest, err)
+		return
+	}
+	logger.AddFields(r, logger.Fields{"client_id": authorizeRequest.GetClient().GetID()})
+
+	if err := checkClientScopes(o.ScopeStrategy, authorizeRequest.GetClient(), authorizeRequest.GetScopes()); err != nil {
+		logger.LogRequestError(r, err)
 		o.writeAuthorizeError(w, authorizeRequest, err)
 		return
 	}
 
 	// A session_token will be available if the user was authenticated an gave consent
 	consentToken := authorizeRequest.GetRequestForm().Get("consent")
+
+	// The consent app may have deferred its decision, in which case the user agent waits until it is resolved
+	if pending := authorizeRequest.GetRequestForm().Get("consent_pending"); consentToken == "" && pending != "" && o.PendingConsents != nil {
+		var ok bool
+		if consentToken, ok = o.awaitPendingConsent(w, r, authorizeRequest, pending); !ok {
+			return
+		}
+	}
+
+	// The user agent may have been granted these scopes before and asked hydra to remember the decision
+	var session *Session
 	if consentToken == "" {
+		session = o.rememberedSession(r, authorizeRequest)
+	}
+
+	// A remembered decision which covers some of the requested scopes is extended by asking the consent app for
+	// the others only
+	var previous *RememberedConsent
+	if session == nil {
+		previous = o.incrementalConsent(r, authorizeRequest)
+	}
+
+	if consentToken == "" && session == nil {
 		// otherwise redirect to log in endpoint
-		if err := o.redirectToConsent(w, r, authorizeRequest); err != nil {
-			pkg.LogError(err)
+		if err := o.redirectToConsent(w, r, authorizeRequest, previous); err != nil {
+			logger.LogRequestError(r, err)
 			o.writeAuthorizeError(w, authorizeRequest, err)
 			return
 		}
 		return
 	}
 
-	// decode consent_token claims
-	// verify anti-CSRF (inject state) and anti-replay token (expiry time, good value would be 10 seconds)
-	session, err := o.Consent.ValidateResponse(authorizeRequest, consentToken)
+	if session == nil {
+		// decode consent_token claims
+		// verify anti-CSRF (inject state) and anti-replay token (expiry time, good value would be 10 seconds)
+		if session, err = o.Consent.ValidateResponse(authorizeRequest, consentToken); err != nil {
+			logger.LogRequestError(r, err)
+			o.writeAuthorizeError(w, authorizeRequest, errors.New(fosite.ErrAccessDenied))
+			return
+		}
+
+		if previous != nil && previous.Subject == session.Subject {
+			mergeGrantedScopes(authorizeRequest, previous)
+		} else {
+			previous = nil
+		}
+
+		if session.RememberFor > 0 && o.RememberedConsents != nil {
+			// Failing to remember the decision only means that the user is asked again.
+			if err := o.rememberConsent(w, r, authorizeRequest, session, previous); err != nil {
+				logger.LogRequestError(r, err)
+			}
+		}
+	}
+
+	if o.LoginSessions != nil {
+		// Failing to track the session only means that the client is not told when it ends, unless the subject
+		// may not start another session.
+		if err := o.trackLoginSession(w, r, authorizeRequest, session); errors.Is(err, quota.ErrQuotaExceeded) {
+			logger.LogRequestError(r, err)
+			o.writeAuthorizeError(w, authorizeRequest, errors.New(fosite.ErrAccessDenied))
+			return
+		} else if err != nil {
+			logger.LogRequestError(r, err)
+		}
+	}
+
+	if err := o.grantAudience(authorizeRequest.GetRequestForm(), authorizeRequest.GetClient().GetID(), authorizeRequest.GetGrantedScopes(), session); err != nil {
+		logger.LogRequestError(r, err)
+		o.writeInvalidTarget(w, authorizeRequest, err)
+		return
+	}
+
+	lifespans, err := clientLifespans(o.Settings, authorizeRequest.GetClient().GetID())
 	if err != nil {
-		pkg.LogError(err)
-		o.writeAuthorizeError(w, authorizeRequest, errors.New(fosite.ErrAccessDenied))
+		logger.LogRequestError(r, err)
+		o.writeAuthorizeError(w, authorizeRequest, err)
 		return
 	}
+	session.SetTokenLifespan(pkg.TokenKindAuthorizeCode, time.Now(), lifespans.AuthorizeCode)
+	setTokenLifespans(session, lifespans, time.Now())
 
 	// done
 	response, err := o.OAuth2.NewAuthorizeResponse(ctx, r, authorizeRequest, session)
 	if err != nil {
-		pkg.LogError(err)
+		logger.LogRequestError(r, err)
 		o.writeAuthorizeError(w, authorizeRequest, err)
 		return
 	}
+	if fragment := response.GetFragment(); lifespans.AccessToken > 0 && fragment.Get("access_token") != "" {
+		fragment.Set("expires_in", strconv.FormatInt(expiresIn(lifespans, 0), 10))
+	}
 
 	o.OAuth2.WriteAuthorizeResponse(w, authorizeRequest, response)
 }
 
-func (o *Handler) redirectToConsent(w http.ResponseWriter, r *http.Request, authorizeRequest fosite.AuthorizeRequester) error {
-	schema := "https"
-	if r.TLS == nil {
-		schema = "http"
+// awaitPendingConsent returns the consent token of a granted consent request. If the request is still pending, the
+// user agent is asked to retry and false is returned.
+func (o *Handler) awaitPendingConsent(w http.ResponseWriter, r *http.Request, authorizeRequest fosite.AuthorizeRequester, id string) (string, bool) {
+	p, err := o.PendingConsents.GetPendingConsent(id)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		o.writeAuthorizeError(w, authorizeRequest, errors.New(fosite.ErrAccessDenied))
+		return "", false
+	} else if p.ClientID != authorizeRequest.GetClient().GetID() {
+		logger.LogRequestError(r, errors.New("Pending consent request was issued for another client"))
+		o.writeAuthorizeError(w, authorizeRequest, errors.New(fosite.ErrAccessDenied))
+		return "", false
+	}
+
+	if err := o.bindPendingConsent(w, r, p); err != nil {
+		logger.LogRequestError(r, err)
+		o.writeAuthorizeError(w, authorizeRequest, errors.New(fosite.ErrAccessDenied))
+		return "", false
+	}
+
+	switch p.Status {
+	case PendingConsentGranted:
+		if err := o.PendingConsents.DeletePendingConsent(id); err != nil {
+			logger.LogRequestError(r, err)
+			o.writeAuthorizeError(w, authorizeRequest, err)
+			return "", false
+		}
+		return p.ConsentToken, true
+	case PendingConsentDenied:
+		if err := o.PendingConsents.DeletePendingConsent(id); err != nil {
+			logger.LogRequestError(r, err)
+		}
+		o.writeAuthorizeError(w, authorizeRequest, errors.New(fosite.ErrAccessDenied))
+		return "", false
+	}
+
+	w.Header().Set("Retry-After", "2")
+	w.Header().Set("Refresh", "2")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte("Waiting for the consent request to be approved."))
+	return "", false
+}
+
+// bindPendingConsent binds a pending consent request to the first user agent waiting for it, so that a request
+// approved on another device only completes the authorization request of the user agent which started it.
+func (o *Handler) bindPendingConsent(w http.ResponseWriter, r *http.Request, p *PendingConsent) error {
+	name := pendingConsentCookie + p.ID
+	if p.Binding != "" {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return errors.New(ErrPendingConsentBound)
+		} else if subtle.ConstantTimeCompare([]byte(bindingHash(cookie.Value)), []byte(p.Binding)) != 1 {
+			return errors.New(ErrPendingConsentBound)
+		}
+		return nil
+	}
+
+	value, err := randomSecret()
+	if err != nil {
+		return err
+	}
+
+	if err := o.PendingConsents.BindPendingConsent(p.ID, bindingHash(value)); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/oauth2/auth",
+		Expires:  p.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+	})
+	return nil
+}
+
+// trackLoginSession records the client in the login session of the user agent and adds the session id to the ID
+// token. A new session is started if the user agent has none or its session belongs to another subject.
+func (o *Handler) trackLoginSession(w http.ResponseWriter, r *http.Request, authorizeRequest fosite.AuthorizeRequester, session *Session) error {
+	clientID := authorizeRequest.GetClient().GetID()
+
+	ls := loginSessionFromCookie(o.LoginSessions, r)
+	if ls != nil && ls.Subject == session.Subject {
+		if err := o.LoginSessions.AddLoginSessionClient(ls.ID, clientID); err != nil {
+			return err
+		}
+	} else {
+		secret, err := randomSecret()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		ls = &LoginSession{
+			ID:              uuid.New(),
+			Subject:         session.Subject,
+			AuthenticatedAt: now,
+			ExpiresAt:       now.Add(o.getLoginSessionLifespan()),
+			Clients:         []string{clientID},
+			UserAgent:       r.UserAgent(),
+			IPAddress:       remoteIP(r),
+			Binding:         bindingHash(secret),
+		}
+		if err := o.LoginSessions.CreateLoginSession(ls); err != nil {
+			return err
+		}
+		setLoginSessionCookie(w, r, ls.ID+"."+secret, ls.ExpiresAt)
+	}
+
+	if session.DefaultSession != nil && session.DefaultSession.Claims != nil {
+		if session.DefaultSession.Claims.Extra == nil {
+			session.DefaultSession.Claims.Extra = map[string]interface{}{}
+		}
+		session.DefaultSession.Claims.Extra["sid"] = ls.ID
+	}
+	return nil
+}
+
+func (o *Handler) getLoginSessionLifespan() time.Duration {
+	o.lifespans.RLock()
+	defer o.lifespans.RUnlock()
+
+	if o.LoginSessionLifespan == 0 {
+		return DefaultLoginSessionLifespan
+	}
+	return o.LoginSessionLifespan
+}
+
+// rememberedSession returns a session for the authorization request if the user agent carries a remembered
+// consent decision which covers it, and nil otherwise. Requests which ask for a new login or consent are never
+// answered from a remembered decision.
+func (o *Handler) rememberedSession(r *http.Request, authorizeRequest fosite.AuthorizeRequester) *Session {
+	strategy, ok := o.Consent.(RememberingConsentStrategy)
+	if o.RememberedConsents == nil || !ok {
+		return nil
 	}
-	challenge, err := o.Consent.IssueChallenge(authorizeRequest, schema+"://"+r.Host+r.URL.String())
+
+	prompt := fosite.Arguments(strings.Split(authorizeRequest.GetRequestForm().Get("prompt"), " "))
+	if prompt.Has("login") || prompt.Has("consent") {
+		return nil
+	}
+
+	c := o.rememberedConsent(r, authorizeRequest.GetClient().GetID())
+	if c == nil {
+		return nil
+	}
+
+	session, err := strategy.RememberedSession(authorizeRequest, c)
+	if err != nil {
+		return nil
+	}
+	return session
+}
+
+// rememberedConsent returns the decision the cookie of the client refers to, if it is still valid and bound to
+// this user agent.
+func (o *Handler) rememberedConsent(r *http.Request, clientID string) *RememberedConsent {
+	cookie, err := r.Cookie(rememberedConsentCookieName(clientID))
+	if err != nil {
+		return nil
+	}
+
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	c, err := o.RememberedConsents.GetRememberedConsent(parts[0])
+	if err != nil {
+		if !errors.Is(err, pkg.ErrNotFound) {
+			logger.LogRequestError(r, err)
+		}
+		return nil
+	} else if c.ClientID != clientID {
+		return nil
+	} else if subtle.ConstantTimeCompare([]byte(bindingHash(parts[1])), []byte(c.Binding)) != 1 {
+		return nil
+	}
+	return c
+}
+
+// incrementalConsent returns the remembered decision of the user agent if it covers some, but not all, of the
+// requested scopes. Requests which ask for consent again are never answered incrementally.
+func (o *Handler) incrementalConsent(r *http.Request, authorizeRequest fosite.AuthorizeRequester) *RememberedConsent {
+	if _, ok := o.Consent.(IncrementalConsentStrategy); o.RememberedConsents == nil || !ok {
+		return nil
+	}
+
+	prompt := fosite.Arguments(strings.Split(authorizeRequest.GetRequestForm().Get("prompt"), " "))
+	if prompt.Has("consent") {
+		return nil
+	}
+
+	c := o.rememberedConsent(r, authorizeRequest.GetClient().GetID())
+	if c == nil || len(c.Granted(authorizeRequest.GetScopes())) == 0 {
+		return nil
+	}
+	return c
+}
+
+// mergeGrantedScopes grants the requested scopes the remembered decision covers, in addition to the ones the
+// consent app granted. With include_granted_scopes=true, every scope of the decision is granted, so that tokens
+// issued for the request, and the tokens they are refreshed with, carry all scopes the client was ever granted.
+func mergeGrantedScopes(authorizeRequest fosite.AuthorizeRequester, previous *RememberedConsent) {
+	scopes := authorizeRequest.GetScopes()
+	if authorizeRequest.GetRequestForm().Get("include_granted_scopes") == "true" {
+		scopes = fosite.Arguments(previous.Scopes)
+	}
+
+	for _, scope := range previous.Granted(scopes) {
+		if !authorizeRequest.GetGrantedScopes().Has(scope) {
+			authorizeRequest.GrantScope(scope)
+		}
+	}
+}
+
+// rememberConsent stores the decision of the consent app and binds it to the user agent with a cookie. A decision
+// which was remembered for the client on this user agent before is replaced. If the decision extends a previous
+// one, the scopes granted before keep their expiry.
+func (o *Handler) rememberConsent(w http.ResponseWriter, r *http.Request, authorizeRequest fosite.AuthorizeRequester, session *Session, previous *RememberedConsent) error {
+	secret, err := randomSecret()
 	if err != nil {
 		return err
 	}
 
-	p := o.ConsentURL
+	// The subject did not authenticate for the requests a remembered decision answers.
+	claims := map[string]interface{}{}
+	if session.DefaultSession != nil && session.DefaultSession.Claims != nil {
+		for k, v := range session.DefaultSession.Claims.Extra {
+			if k != "amr" && k != "acr" {
+				claims[k] = v
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	c := &RememberedConsent{
+		ID:             uuid.New(),
+		Subject:        session.Subject,
+		ClientID:       authorizeRequest.GetClient().GetID(),
+		Scopes:         []string(authorizeRequest.GetGrantedScopes()),
+		IDTokenClaims:  claims,
+		CreatedAt:      now,
+		ExpiresAt:      now,
+		ScopeExpiresAt: map[string]time.Time{},
+		Binding:        bindingHash(secret),
+	}
+	if previous != nil {
+		for _, scope := range previous.Granted(fosite.Arguments(previous.Scopes)) {
+			if !fosite.Arguments(c.Scopes).Has(scope) {
+				c.Scopes = append(c.Scopes, scope)
+			}
+		}
+	}
+	for _, scope := range c.Scopes {
+		expiresAt := now.Add(o.rememberConsentLifespan(scope, session.RememberFor))
+		if previous != nil && previous.Granted(fosite.Arguments{scope}).Has(scope) {
+			expiresAt = previous.scopeExpiresAt(scope)
+		}
+		c.ScopeExpiresAt[scope] = expiresAt
+		if expiresAt.After(c.ExpiresAt) {
+			c.ExpiresAt = expiresAt
+		}
+	}
+
+	if previous := o.rememberedConsent(r, c.ClientID); previous != nil {
+		if err := o.RememberedConsents.ForgetConsent(previous.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := o.RememberedConsents.RememberConsent(c); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     rememberedConsentCookieName(c.ClientID),
+		Value:    c.ID + "." + secret,
+		Path:     "/oauth2/auth",
+		Expires:  c.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+	})
+	return nil
+}
+
+// rememberConsentLifespan returns how long the decision for a scope is remembered. The consent app's wish is capped
+// at the scope's lifespan, or at RememberConsentMaxLifespan if the scope has none.
+func (o *Handler) rememberConsentLifespan(scope string, requested time.Duration) time.Duration {
+	maxLifespan, ok := o.RememberConsentScopeLifespans[scope]
+	if !ok {
+		maxLifespan = o.getRememberConsentMaxLifespan()
+	}
+
+	if maxLifespan > 0 && requested > maxLifespan {
+		return maxLifespan
+	}
+	return requested
+}
+
+func (o *Handler) getRememberConsentMaxLifespan() time.Duration {
+	o.lifespans.RLock()
+	defer o.lifespans.RUnlock()
+
+	if o.RememberConsentMaxLifespan == 0 {
+		return DefaultRememberConsentMaxLifespan
+	}
+	return o.RememberConsentMaxLifespan
+}
+
+// rememberedConsentCookieName derives a cookie name from the client id, which may contain characters cookie names
+// must not.
+func rememberedConsentCookieName(clientID string) string {
+	return rememberedConsentCookie + bindingHash(clientID)[:16]
+}
+
+// randomSecret returns 32 random bytes encoded for use in cookies and URLs.
+func randomSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.New(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func bindingHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// redirectToConsent asks the consent app for the scopes the previous decision does not cover, or for all requested
+// scopes if there is none.
+func (o *Handler) redirectToConsent(w http.ResponseWriter, r *http.Request, authorizeRequest fosite.AuthorizeRequester, previous *RememberedConsent) error {
+	strategy, ok := o.Consent.(IncrementalConsentStrategy)
+	if previous == nil || !ok {
+		return redirectToConsent(w, r, o.Consent, o.ConsentURL, authorizeRequest)
+	}
+
+	challenge, err := strategy.IssueIncrementalChallenge(authorizeRequest, requestURL(r), previous.Granted(authorizeRequest.GetScopes()))
+	if err != nil {
+		return err
+	}
+	redirectWithChallenge(w, r, o.ConsentURL, challenge)
+	return nil
+}
+
+// redirectToConsent sends the user agent to the consent app with a challenge, the consent app redirects back to
+// the requested URL once the user logged in and gave consent.
+func redirectToConsent(w http.ResponseWriter, r *http.Request, consent ConsentStrategy, consentURL url.URL, authorizeRequest fosite.AuthorizeRequester) error {
+	challenge, err := consent.IssueChallenge(authorizeRequest, requestURL(r))
+	if err != nil {
+		return err
+	}
+	redirectWithChallenge(w, r, consentURL, challenge)
+	return nil
+}
+
+func redirectWithChallenge(w http.ResponseWriter, r *http.Request, consentURL url.URL, challenge string) {
+	p := consentURL
 	q := p.Query()
 	q.Set("challenge", challenge)
 	p.RawQuery = q.Encode()
 	http.Redirect(w, r, p.String(), http.StatusFound)
-	return nil
+}
+
+// requestURL returns the absolute URL of r.
+func requestURL(r *http.Request) string {
+	schema := "https"
+	if r.TLS == nil {
+		schema = "http"
+	}
+	return schema + "://" + r.Host + r.URL.String()
 }
 
 func (o *Handler) writeAuthorizeError(w http.ResponseWriter, ar fosite.AuthorizeRequester, err error) {
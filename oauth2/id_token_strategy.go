@@ -0,0 +1,246 @@
+package oauth2
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/oidc"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/square/go-jose"
+	"golang.org/x/net/context"
+)
+
+// ClientIDTokenStrategy signs ID tokens with the algorithm the client declared in its settings. ID tokens of clients
+// which did not declare one are signed by the default strategy with RS256.
+//
+// The claims are always assembled by the default strategy. For other algorithms its token is re-signed with a key of
+// the key set whose algorithm matches, so that all ID tokens carry the same claims. Clients which declared an
+// encryption algorithm receive the signed token as the payload of a JWE encrypted to one of their keys.
+//
+// If Claims is set, the claims it returns for the subject are added to the token as far as the granted scopes
+// release them.
+type ClientIDTokenStrategy struct {
+	oidc.OpenIDConnectTokenStrategy
+
+	// DefaultKey verifies the tokens of the default strategy.
+	DefaultKey *rsa.PublicKey
+
+	KeyManager jwk.Manager
+
+	// Set holds the signing keys, it defaults to OpenIDConnectKeyName.
+	Set string
+
+	Settings client.SettingsManager
+
+	// HTTPClient fetches the jwks_uri of clients, it defaults to a client with a ten second timeout.
+	HTTPClient *http.Client
+
+	// Claims looks up additional claims of the subject, it is optional.
+	Claims ClaimsSource
+
+	// ScopeClaims overrides which claims a scope releases, scopes without an entry release DefaultScopeClaims.
+	ScopeClaims map[string][]string
+}
+
+func (s *ClientIDTokenStrategy) GenerateIDToken(ctx context.Context, r *http.Request, requester fosite.Requester) (string, error) {
+	settings, err := clientSettings(s.Settings, requester.GetClient().GetID())
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.sign(ctx, r, requester, settings.IDTokenSignedResponseAlg, settings.GetLifespans().IDToken)
+	if err != nil {
+		return "", err
+	} else if !settings.EncryptsIDTokens() {
+		return token, nil
+	}
+	return s.encrypt(token, settings)
+}
+
+// sign re-signs the token of the default strategy if the client asked for another algorithm or overrides the lifespan
+// of its ID tokens, in which case the expiry is replaced, or if a claims source adds claims.
+func (s *ClientIDTokenStrategy) sign(ctx context.Context, r *http.Request, requester fosite.Requester, alg string, lifespan time.Duration) (string, error) {
+	token, err := s.OpenIDConnectTokenStrategy.GenerateIDToken(ctx, r, requester)
+	if err != nil {
+		return "", err
+	} else if alg == "" {
+		alg = jwt.SigningMethodRS256.Alg()
+	}
+
+	if alg == jwt.SigningMethodRS256.Alg() && lifespan == 0 && s.Claims == nil {
+		return token, nil
+	}
+
+	key, kid, err := s.signingKey(alg)
+	if err != nil {
+		return "", err
+	}
+
+	original, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		return s.DefaultKey, nil
+	})
+	if err != nil {
+		return "", errors.New(err)
+	}
+
+	resigned := jwt.New(jwt.GetSigningMethod(alg))
+	for k, v := range original.Header {
+		if k != "alg" {
+			resigned.Header[k] = v
+		}
+	}
+	resigned.Header["kid"] = kid
+	resigned.Claims = original.Claims
+	if lifespan > 0 {
+		resigned.Claims["exp"] = time.Now().Add(lifespan).Unix()
+	}
+	if err := s.addClaims(ctx, requester, resigned.Claims); err != nil {
+		return "", err
+	}
+
+	out, err := resigned.SignedString(key)
+	if err != nil {
+		return "", errors.New(err)
+	}
+	return out, nil
+}
+
+// addClaims adds the claims of the subject which the granted scopes release.
+func (s *ClientIDTokenStrategy) addClaims(ctx context.Context, requester fosite.Requester, claims map[string]interface{}) error {
+	subject, _ := claims["sub"].(string)
+	if s.Claims == nil || subject == "" {
+		return nil
+	}
+
+	additional, err := s.Claims.Claims(ctx, &ClaimsRequest{
+		Subject:  subject,
+		ClientID: requester.GetClient().GetID(),
+		Scopes:   requester.GetGrantedScopes(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, value := range releasedClaims(additional, requester.GetGrantedScopes(), s.ScopeClaims) {
+		claims[name] = value
+	}
+	return nil
+}
+
+func (s *ClientIDTokenStrategy) encrypt(token string, settings *client.Settings) (string, error) {
+	key, err := s.encryptionKey(settings)
+	if err != nil {
+		return "", err
+	}
+
+	encrypter, err := jose.NewEncrypter(jose.KeyAlgorithm(settings.IDTokenEncryptedResponseAlg), jose.ContentEncryption(settings.IDTokenEncryptedResponseEnc), key)
+	if err != nil {
+		return "", errors.New(err)
+	}
+
+	object, err := encrypter.Encrypt([]byte(token))
+	if err != nil {
+		return "", errors.New(err)
+	}
+
+	out, err := object.CompactSerialize()
+	if err != nil {
+		return "", errors.New(err)
+	}
+	return out, nil
+}
+
+// encryptionKey returns the first public key of the client which is meant for encryption and fits the algorithm.
+func (s *ClientIDTokenStrategy) encryptionKey(settings *client.Settings) (interface{}, error) {
+	keys := settings.JWKs
+	if settings.JWKsURI != "" {
+		var err error
+		if keys, err = s.fetchKeys(settings.JWKsURI); err != nil {
+			return nil, err
+		}
+	}
+
+	alg := settings.IDTokenEncryptedResponseAlg
+	if keys != nil {
+		for _, key := range keys.Keys {
+			if key.Use != "" && key.Use != "enc" {
+				continue
+			}
+
+			switch k := key.Key.(type) {
+			case *rsa.PublicKey:
+				if strings.HasPrefix(alg, "RSA") {
+					return k, nil
+				}
+			case *ecdsa.PublicKey:
+				if strings.HasPrefix(alg, "ECDH-ES") {
+					return k, nil
+				}
+			}
+		}
+	}
+	return nil, errors.Errorf("Client %s has no public key to encrypt ID tokens with %s", settings.ClientID, alg)
+}
+
+func (s *ClientIDTokenStrategy) fetchKeys(uri string) (*jose.JsonWebKeySet, error) {
+	c := s.HTTPClient
+	if c == nil {
+		c = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	res, err := c.Get(uri)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Fetching %s returned status code %d", uri, res.StatusCode)
+	}
+
+	var keys jose.JsonWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&keys); err != nil {
+		return nil, errors.New(err)
+	}
+	return &keys, nil
+}
+
+// signingKey returns the first private key of the set which signs with alg and the id of its public counterpart.
+func (s *ClientIDTokenStrategy) signingKey(alg string) (interface{}, string, error) {
+	set := s.Set
+	if set == "" {
+		set = OpenIDConnectKeyName
+	}
+
+	keys, err := s.KeyManager.GetKeySet(set)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, key := range keys.Keys {
+		switch key.Key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+			if jwk.SigningAlgorithm(&key) == alg {
+				return key.Key, publicKeyID(key.KeyID), nil
+			}
+		}
+	}
+	return nil, "", errors.Errorf("Key set %s contains no private key for %s, add one to sign ID tokens with it", set, alg)
+}
+
+// publicKeyID returns the id of the public counterpart of a private key, private:foo becomes public:foo. Other key
+// ids are returned unchanged.
+func publicKeyID(kid string) string {
+	if strings.HasPrefix(kid, "private") {
+		return "public" + strings.TrimPrefix(kid, "private")
+	}
+	return kid
+}
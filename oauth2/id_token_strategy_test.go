@@ -0,0 +1,204 @@
+package oauth2_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/jwk"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type staticIDTokenStrategy struct {
+	key *rsa.PrivateKey
+}
+
+func (s *staticIDTokenStrategy) GenerateIDToken(_ context.Context, _ *http.Request, requester fosite.Requester) (string, error) {
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Claims["sub"] = "peter"
+	token.Claims["aud"] = requester.GetClient().GetID()
+	return token.SignedString(s.key)
+}
+
+func TestClientIDTokenStrategy(t *testing.T) {
+	defaultKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(t, err)
+
+	km := &jwk.MemoryManager{}
+	ec, err := new(jwk.ECDSA256Generator).Generate("2")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(OpenIDConnectKeyName, ec))
+
+	clientKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.Nil(t, err)
+	clientECKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&jose.JsonWebKeySet{Keys: []jose.JsonWebKey{
+			{Key: &clientKey.PublicKey, KeyID: "sig", Use: "sig"},
+			{Key: &clientECKey.PublicKey, KeyID: "enc", Use: "enc"},
+		}})
+	}))
+	defer ts.Close()
+
+	settings := client.NewSettingsMemoryManager()
+	for _, s := range []*client.Settings{
+		{ClientID: "es256", IDTokenSignedResponseAlg: "ES256"},
+		{ClientID: "short-lived", IDTokenSignedResponseAlg: "ES256", IDTokenLifespan: "5m"},
+		{
+			ClientID:                    "rsa-oaep",
+			IDTokenEncryptedResponseAlg: "RSA-OAEP",
+			IDTokenEncryptedResponseEnc: "A128CBC-HS256",
+			JWKs:                        &jose.JsonWebKeySet{Keys: []jose.JsonWebKey{{Key: &clientKey.PublicKey, KeyID: "enc"}}},
+		},
+		{
+			ClientID:                    "ecdh-es",
+			IDTokenSignedResponseAlg:    "ES256",
+			IDTokenEncryptedResponseAlg: "ECDH-ES",
+			IDTokenEncryptedResponseEnc: "A256GCM",
+			JWKsURI:                     ts.URL,
+		},
+		{
+			ClientID:                    "no-key",
+			IDTokenEncryptedResponseAlg: "ECDH-ES",
+			IDTokenEncryptedResponseEnc: "A256GCM",
+			JWKs:                        &jose.JsonWebKeySet{Keys: []jose.JsonWebKey{{Key: &clientKey.PublicKey, KeyID: "enc"}}},
+		},
+	} {
+		require.Nil(t, settings.SetSettings(s))
+	}
+
+	s := &ClientIDTokenStrategy{
+		OpenIDConnectTokenStrategy: &staticIDTokenStrategy{key: defaultKey},
+		DefaultKey:                 &defaultKey.PublicKey,
+		KeyManager:                 km,
+		Settings:                   settings,
+	}
+
+	generate := func(clientID string) (string, error) {
+		return s.GenerateIDToken(context.Background(), nil, &fosite.Request{Client: &fosite.DefaultClient{ID: clientID}})
+	}
+
+	decrypt := func(token string, key interface{}) string {
+		object, err := jose.ParseEncrypted(token)
+		require.Nil(t, err)
+		payload, err := object.Decrypt(key)
+		require.Nil(t, err)
+		return string(payload)
+	}
+
+	verify := func(token string, method jwt.SigningMethod, key interface{}) *jwt.Token {
+		parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+			assert.Equal(t, method, t.Method)
+			return key, nil
+		})
+		require.Nil(t, err)
+		assert.Equal(t, "peter", parsed.Claims["sub"])
+		return parsed
+	}
+
+	ecPublic := &jwk.First(ec.Key("private:2")).Key.(*ecdsa.PrivateKey).PublicKey
+
+	token, err := generate("default")
+	require.Nil(t, err)
+	verify(token, jwt.SigningMethodRS256, &defaultKey.PublicKey)
+
+	token, err = generate("es256")
+	require.Nil(t, err)
+	parsed := verify(token, jwt.SigningMethodES256, ecPublic)
+	assert.Equal(t, "public:2", parsed.Header["kid"])
+	assert.Equal(t, "es256", parsed.Claims["aud"])
+
+	token, err = generate("short-lived")
+	require.Nil(t, err)
+	parsed = verify(token, jwt.SigningMethodES256, ecPublic)
+	assert.InDelta(t, time.Now().Add(5*time.Minute).Unix(), parsed.Claims["exp"], 5)
+
+	token, err = generate("rsa-oaep")
+	require.Nil(t, err)
+	verify(decrypt(token, clientKey), jwt.SigningMethodRS256, &defaultKey.PublicKey)
+
+	token, err = generate("ecdh-es")
+	require.Nil(t, err)
+	verify(decrypt(token, clientECKey), jwt.SigningMethodES256, ecPublic)
+
+	_, err = generate("no-key")
+	assert.NotNil(t, err)
+}
+
+func TestClientIDTokenStrategyClaims(t *testing.T) {
+	km := &jwk.MemoryManager{}
+	keys, err := new(jwk.RS256Generator).Generate("1")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(OpenIDConnectKeyName, keys))
+	defaultKey := jwk.MustRSAPrivate(jwk.First(keys.Key("private:1")))
+
+	var received ClaimsRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Nil(t, json.NewDecoder(r.Body).Decode(&received))
+		assert.NotEmpty(t, r.Header.Get(events.SignatureHeader))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"sub":            "mallet",
+			"email":          "peter@example.com",
+			"email_verified": true,
+			"name":           "Peter",
+			"department":     "accounting",
+		})
+	}))
+	defer ts.Close()
+
+	s := &ClientIDTokenStrategy{
+		OpenIDConnectTokenStrategy: &staticIDTokenStrategy{key: defaultKey},
+		DefaultKey:                 &defaultKey.PublicKey,
+		KeyManager:                 km,
+		Settings:                   client.NewSettingsMemoryManager(),
+		Claims:                     &WebhookClaimsSource{URL: ts.URL, Secret: []byte("secret")},
+		ScopeClaims:                map[string][]string{"hr": {"department"}},
+	}
+
+	for k, c := range []struct {
+		scopes   []string
+		released []string
+		withheld []string
+	}{
+		{scopes: []string{"openid"}, withheld: []string{"email", "name", "department"}},
+		{scopes: []string{"openid", "email"}, released: []string{"email", "email_verified"}, withheld: []string{"name"}},
+		{scopes: []string{"openid", "profile", "hr"}, released: []string{"name", "department"}, withheld: []string{"email"}},
+	} {
+		request := &fosite.Request{Client: &fosite.DefaultClient{ID: "app"}, GrantedScopes: c.scopes}
+		token, err := s.GenerateIDToken(context.Background(), nil, request)
+		require.Nil(t, err, "%d", k)
+
+		parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+			return &defaultKey.PublicKey, nil
+		})
+		require.Nil(t, err, "%d", k)
+		assert.Equal(t, "peter", parsed.Claims["sub"], "%d", k)
+		assert.Equal(t, ClaimsRequest{Subject: "peter", ClientID: "app", Scopes: c.scopes}, received, "%d", k)
+		for _, name := range c.released {
+			assert.NotNil(t, parsed.Claims[name], "%d: %s", k, name)
+		}
+		for _, name := range c.withheld {
+			assert.Nil(t, parsed.Claims[name], "%d: %s", k, name)
+		}
+	}
+
+	ts.Close()
+	_, err = s.GenerateIDToken(context.Background(), nil, &fosite.Request{Client: &fosite.DefaultClient{ID: "app"}})
+	assert.NotNil(t, err)
+}
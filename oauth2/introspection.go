@@ -0,0 +1,248 @@
+package oauth2
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/fosite/handler/core/refresh"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/tracing"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const (
+	IntrospectionHandlerPath = "/oauth2/introspect"
+
+	introspectionResource = "rn:hydra:oauth2:tokens"
+	introspectionScope    = "hydra.introspect"
+)
+
+// Introspection is the introspection response defined by RFC 7662. Inactive tokens are only described by
+// Active being false.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Actor     *Actor `json:"act,omitempty"`
+
+	// AuthenticationMethods and AuthenticationContext describe how the subject logged in, see Session.
+	AuthenticationMethods []string `json:"amr,omitempty"`
+	AuthenticationContext string   `json:"acr,omitempty"`
+}
+
+// Introspector describes tokens, it is implemented by IntrospectionHandler and HTTPIntrospector.
+type Introspector interface {
+	IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*Introspection, error)
+}
+
+// ContextFilter restricts what a resource server learns about tokens, it is implemented by warden.WardenHandler.
+type ContextFilter interface {
+	FilterContext(resourceServer string, c *firewall.Context) (*firewall.Context, error)
+}
+
+// IntrospectionRequest is the body of an introspection request. RFC 7662 requires it to be form encoded, callers
+// which exchange CBOR or msgpack with hydra may send it in these encodings instead.
+type IntrospectionRequest struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint,omitempty"`
+}
+
+// IntrospectionHandler implements the token introspection endpoint of RFC 7662. Callers authenticate with their own
+// access token and need to be allowed to introspect tokens by a policy.
+type IntrospectionHandler struct {
+	AccessTokens *core.CoreValidator
+
+	RefreshTokenStrategy core.RefreshTokenStrategy
+	RefreshTokenStorage  refresh.RefreshTokenGrantStorage
+
+	AccessTokenLifespan time.Duration
+	Issuer              string
+
+	// Filter applies the visibility rules of the resource server which introspects a token, like the warden does
+	// for its authorization contexts. Callers see the full introspection if Filter is nil.
+	Filter ContextFilter
+
+	H herodot.Herodot
+	W firewall.Firewall
+}
+
+func (h *IntrospectionHandler) SetRoutes(r *httprouter.Router) {
+	r.POST(IntrospectionHandlerPath, instrument("introspect", introspectionRequestLabels, h.Introspect))
+}
+
+func (h *IntrospectionHandler) Introspect(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.Context(tracing.Context(r))
+
+	caller, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: introspectionResource,
+		Action:   "introspect",
+	}, introspectionScope)
+	if errors.Is(err, pkg.ErrUnauthorized) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusUnauthorized, err)
+		return
+	} else if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	var ir IntrospectionRequest
+	if herodot.IsBinary(r) {
+		if err := herodot.Decode(r, &ir); err != nil {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+			return
+		}
+	} else if err := r.ParseForm(); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	} else {
+		ir.Token = r.PostForm.Get("token")
+		ir.TokenTypeHint = r.PostForm.Get("token_type_hint")
+	}
+
+	token := ir.Token
+	if token == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Parameter token is missing"))
+		return
+	}
+
+	i, err := h.IntrospectToken(ctx, token, ir.TokenTypeHint)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	i, err = h.FilterIntrospection(caller.Subject, i)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+	h.H.Write(ctx, w, r, i)
+}
+
+// AuthorizeCaller checks that the access token of the caller allows it to introspect tokens and returns the
+// context of the caller, whose subject identifies the resource server.
+func (h *IntrospectionHandler) AuthorizeCaller(ctx context.Context, token string) (*firewall.Context, error) {
+	return h.W.ActionAllowed(ctx, token, &ladon.Request{
+		Resource: introspectionResource,
+		Action:   "introspect",
+	}, introspectionScope)
+}
+
+// FilterIntrospection removes the scopes, the subject and the authentication the resource server may not see from an
+// introspection. The actor is removed together with the subject, as it would tell on whose behalf the token was
+// issued.
+func (h *IntrospectionHandler) FilterIntrospection(resourceServer string, i *Introspection) (*Introspection, error) {
+	if h.Filter == nil || !i.Active {
+		return i, nil
+	}
+
+	c, err := h.Filter.FilterContext(resourceServer, &firewall.Context{
+		Subject:               i.Subject,
+		GrantedScopes:         strings.Fields(i.Scope),
+		AuthenticationMethods: i.AuthenticationMethods,
+		AuthenticationContext: i.AuthenticationContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := *i
+	filtered.Scope = strings.Join(c.GrantedScopes, " ")
+	filtered.AuthenticationMethods = c.AuthenticationMethods
+	filtered.AuthenticationContext = c.AuthenticationContext
+	if c.Subject != i.Subject {
+		filtered.Subject = c.Subject
+		filtered.Actor = nil
+	}
+	return &filtered, nil
+}
+
+// IntrospectToken returns the introspection of token, which is not active if the token is unknown or expired. The
+// caller is not checked, see AuthorizeCaller.
+func (h *IntrospectionHandler) IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*Introspection, error) {
+	// The hint only decides which kind of token is looked up first.
+	lookups := []func(context.Context, string) *Introspection{h.introspectAccessToken, h.introspectRefreshToken}
+	if tokenTypeHint == "refresh_token" {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		if i := lookup(ctx, token); i != nil {
+			return i, nil
+		}
+	}
+	return &Introspection{Active: false}, nil
+}
+
+func (h *IntrospectionHandler) introspectAccessToken(ctx context.Context, token string) *Introspection {
+	var session = new(Session)
+	var request = fosite.NewAccessRequest(session)
+	if err := h.AccessTokens.ValidateToken(ctx, request, token); err != nil {
+		return nil
+	}
+
+	i := h.introspection(request, session, "access_token")
+	if expiresAt := accessTokenExpiresAt(request, h.AccessTokenLifespan); !expiresAt.IsZero() {
+		if time.Now().After(expiresAt) {
+			return nil
+		}
+		i.ExpiresAt = expiresAt.Unix()
+	}
+	return i
+}
+
+func (h *IntrospectionHandler) introspectRefreshToken(ctx context.Context, token string) *Introspection {
+	var session = new(Session)
+	signature, err := h.RefreshTokenStrategy.ValidateRefreshToken(ctx, fosite.NewAccessRequest(session), token)
+	if err != nil {
+		return nil
+	}
+
+	request, err := h.RefreshTokenStorage.GetRefreshTokenSession(ctx, signature, session)
+	if err != nil {
+		return nil
+	}
+
+	return h.introspection(request, session, "refresh_token")
+}
+
+func (h *IntrospectionHandler) introspection(request fosite.Requester, session *Session, tokenType string) *Introspection {
+	// Stores which keep requests in memory return the session the token was issued with.
+	if s, ok := request.GetSession().(*Session); ok {
+		session = s
+	}
+
+	audience := request.GetClient().GetID()
+	if session.Audience != "" {
+		audience = session.Audience
+	}
+
+	return &Introspection{
+		Active:    true,
+		Scope:     strings.Join(request.GetGrantedScopes(), " "),
+		ClientID:  request.GetClient().GetID(),
+		Subject:   session.Subject,
+		IssuedAt:  request.GetRequestedAt().Unix(),
+		Audience:  audience,
+		Issuer:    h.Issuer,
+		TokenType: tokenType,
+		Actor:     session.Actor,
+
+		AuthenticationMethods: session.AuthenticationMethods,
+		AuthenticationContext: session.AuthenticationContext,
+	}
+}
@@ -0,0 +1,52 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/go-errors/errors"
+	"golang.org/x/net/context"
+)
+
+// HTTPIntrospector introspects tokens at the introspection endpoint of a cluster. Client needs to authenticate as a
+// client which is allowed to introspect tokens.
+type HTTPIntrospector struct {
+	Client *http.Client
+
+	Endpoint *url.URL
+}
+
+// IntrospectToken returns the introspection of token. An unknown or expired token is not an error, it is described
+// by an introspection which is not active.
+func (i *HTTPIntrospector) IntrospectToken(ctx context.Context, token, tokenTypeHint string) (*Introspection, error) {
+	form := url.Values{"token": {token}}
+	if tokenTypeHint != "" {
+		form.Set("token_type_hint", tokenTypeHint)
+	}
+
+	var ep = new(url.URL)
+	*ep = *i.Endpoint
+	ep.Path = IntrospectionHandlerPath
+	resp, err := i.Client.PostForm(ep.String(), form)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		all, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+
+		return nil, errors.Errorf("Got error (%d): %s", resp.StatusCode, all)
+	}
+
+	var in Introspection
+	if err := json.NewDecoder(resp.Body).Decode(&in); err != nil {
+		return nil, errors.New(err)
+	}
+	return &in, nil
+}
@@ -0,0 +1,163 @@
+package oauth2_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/internal"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/warden"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestIntrospection(t *testing.T) {
+	w, allowed := internal.NewFirewall("hydra", "resource-server", fosite.Arguments{"hydra.introspect"}, &ladon.DefaultPolicy{
+		ID:        "1",
+		Subjects:  []string{"resource-server"},
+		Resources: []string{"rn:hydra:oauth2:tokens"},
+		Actions:   []string{"introspect"},
+		Effect:    ladon.AllowAccess,
+	})
+
+	h := &IntrospectionHandler{
+		AccessTokens: &core.CoreValidator{
+			AccessTokenStrategy: hmacStrategy,
+			AccessTokenStorage:  store,
+		},
+		RefreshTokenStrategy: hmacStrategy,
+		RefreshTokenStorage:  store,
+		AccessTokenLifespan:  time.Hour,
+		Issuer:               "https://hydra.localhost",
+		H:                    &herodot.JSON{},
+		W:                    w,
+	}
+	r := httprouter.New()
+	h.SetRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	tok, err := oauthClientConfig.Token(oauth2.NoContext)
+	pkg.RequireError(t, false, err)
+
+	for k, c := range []struct {
+		client *http.Client
+		token  string
+		status int
+		active bool
+	}{
+		{client: allowed, token: tok.AccessToken, status: http.StatusOK, active: true},
+		{client: allowed, token: tok.AccessToken + "x", status: http.StatusOK},
+		{client: allowed, token: "", status: http.StatusBadRequest},
+		{client: http.DefaultClient, token: tok.AccessToken, status: http.StatusUnauthorized},
+	} {
+		res, err := c.client.PostForm(server.URL+IntrospectionHandlerPath, url.Values{"token": {c.token}})
+		require.Nil(t, err, "Case %d", k)
+		defer res.Body.Close()
+		require.Equal(t, c.status, res.StatusCode, "Case %d", k)
+		if c.status != http.StatusOK {
+			continue
+		}
+
+		var i Introspection
+		require.Nil(t, json.NewDecoder(res.Body).Decode(&i), "Case %d", k)
+		assert.Equal(t, c.active, i.Active, "Case %d", k)
+		if c.active {
+			assert.Equal(t, "app-client", i.ClientID, "Case %d", k)
+			assert.Equal(t, "access_token", i.TokenType, "Case %d", k)
+			assert.Equal(t, "hydra", i.Scope, "Case %d", k)
+			assert.True(t, i.ExpiresAt > time.Now().Unix(), "Case %d", k)
+		}
+	}
+}
+
+func TestIntrospectionFiltersResourceServers(t *testing.T) {
+	w, allowed := internal.NewFirewall("hydra", "resource-server", fosite.Arguments{"hydra.introspect"}, &ladon.DefaultPolicy{
+		ID:        "1",
+		Subjects:  []string{"resource-server"},
+		Resources: []string{"rn:hydra:oauth2:tokens"},
+		Actions:   []string{"introspect"},
+		Effect:    ladon.AllowAccess,
+	})
+
+	tokens := pkg.Tokens(1)
+	tokenStore := pkg.FositeStore()
+	ar := fosite.NewAccessRequest(&Session{
+		Subject:               "alice",
+		Actor:                 &Actor{Subject: "mailer", ClientID: "mailer"},
+		AuthenticationMethods: []string{"hwk", "mfa"},
+		AuthenticationContext: ACRWebAuthn,
+	})
+	ar.Client = &fosite.DefaultClient{ID: "app-client"}
+	ar.GrantedScopes = fosite.Arguments{"photos.read", "calendar"}
+	require.Nil(t, tokenStore.CreateAccessTokenSession(nil, tokens[0][0], ar))
+
+	servers := warden.NewResourceServerMemoryManager()
+	h := &IntrospectionHandler{
+		AccessTokens: &core.CoreValidator{
+			AccessTokenStrategy: pkg.HMACStrategy,
+			AccessTokenStorage:  tokenStore,
+		},
+		RefreshTokenStrategy: pkg.HMACStrategy,
+		RefreshTokenStorage:  tokenStore,
+		AccessTokenLifespan:  time.Hour,
+		Filter:               &warden.WardenHandler{ResourceServers: servers},
+		H:                    &herodot.JSON{},
+		W:                    w,
+	}
+	r := httprouter.New()
+	h.SetRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	introspect := func() *Introspection {
+		res, err := allowed.PostForm(server.URL+IntrospectionHandlerPath, url.Values{"token": {tokens[0][1]}})
+		require.Nil(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var i Introspection
+		require.Nil(t, json.NewDecoder(res.Body).Decode(&i))
+		return &i
+	}
+
+	// Resource servers which are not registered see everything.
+	i := introspect()
+	assert.Equal(t, "photos.read calendar", i.Scope)
+	assert.Equal(t, "alice", i.Subject)
+	require.NotNil(t, i.Actor)
+	assert.Equal(t, []string{"hwk", "mfa"}, i.AuthenticationMethods)
+	assert.Equal(t, ACRWebAuthn, i.AuthenticationContext)
+
+	require.Nil(t, servers.CreateResourceServer(&warden.ResourceServer{ID: "resource-server", Scopes: []string{"photos"}}))
+	i = introspect()
+	assert.True(t, i.Active)
+	assert.Equal(t, "photos.read", i.Scope, "The scopes of other audiences are hidden")
+	assert.Equal(t, "alice", i.Subject)
+	assert.Equal(t, []string{"hwk", "mfa"}, i.AuthenticationMethods)
+
+	require.Nil(t, servers.UpdateResourceServer(&warden.ResourceServer{ID: "resource-server", Scopes: []string{"photos"}, OmitAuthentication: true}))
+	i = introspect()
+	assert.Equal(t, "alice", i.Subject)
+	assert.Nil(t, i.AuthenticationMethods)
+	assert.Equal(t, "", i.AuthenticationContext)
+
+	require.Nil(t, servers.UpdateResourceServer(&warden.ResourceServer{ID: "resource-server", Scopes: []string{"contacts"}, OmitSubject: true}))
+	i = introspect()
+	assert.True(t, i.Active)
+	assert.Equal(t, "", i.Scope)
+	assert.Equal(t, "", i.Subject)
+	assert.Nil(t, i.Actor)
+	assert.Equal(t, "app-client", i.ClientID)
+}
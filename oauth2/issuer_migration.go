@@ -0,0 +1,105 @@
+package oauth2
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ory-am/fosite"
+)
+
+// IssuerMigration lets hydra accept tokens of its previous issuers while it issues tokens with a new one, so that
+// the issuer can be renamed without breaking every relying party at once. It counts the tokens presented with a
+// legacy issuer per client, which tells when a legacy issuer can be dropped.
+type IssuerMigration struct {
+	LegacyIssuers []string
+
+	sync.Mutex
+	usage map[string]*LegacyIssuerUsage
+}
+
+// LegacyIssuerUsage counts the tokens a client presented which were issued by a legacy issuer.
+type LegacyIssuerUsage struct {
+	Issuer   string    `json:"issuer"`
+	ClientID string    `json:"client_id"`
+	Tokens   int64     `json:"tokens"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// IsLegacy returns true if tokens of the issuer are still accepted.
+func (m *IssuerMigration) IsLegacy(issuer string) bool {
+	for _, legacy := range m.LegacyIssuers {
+		if legacy == issuer {
+			return true
+		}
+	}
+	return false
+}
+
+// Observe records that a token of a legacy issuer was accepted.
+func (m *IssuerMigration) Observe(issuer, clientID string) {
+	observeLegacyIssuer(issuer)
+
+	m.Lock()
+	defer m.Unlock()
+
+	if m.usage == nil {
+		m.usage = map[string]*LegacyIssuerUsage{}
+	}
+
+	key := issuer + " " + clientID
+	u, ok := m.usage[key]
+	if !ok {
+		u = &LegacyIssuerUsage{Issuer: issuer, ClientID: clientID}
+		m.usage[key] = u
+	}
+	u.Tokens++
+	u.LastSeen = time.Now().UTC()
+}
+
+// Usage returns the recorded usage of the legacy issuers since this instance started, ordered by issuer and client.
+func (m *IssuerMigration) Usage() []LegacyIssuerUsage {
+	m.Lock()
+	defer m.Unlock()
+
+	usage := []LegacyIssuerUsage{}
+	for _, u := range m.usage {
+		usage = append(usage, *u)
+	}
+	sort.Sort(byIssuerAndClient(usage))
+	return usage
+}
+
+// References returns the URLs of the client which point to the host of a legacy issuer. Issuers which are not
+// URLs or share their host with the current issuer are ignored.
+func (m *IssuerMigration) References(issuer string, c *fosite.DefaultClient) []string {
+	hosts := map[string]bool{}
+	for _, legacy := range m.LegacyIssuers {
+		if u, err := url.Parse(legacy); err == nil && u.Host != "" {
+			hosts[u.Host] = true
+		}
+	}
+	if u, err := url.Parse(issuer); err == nil {
+		delete(hosts, u.Host)
+	}
+
+	references := []string{}
+	for _, ref := range append([]string{c.ClientURI, c.PolicyURI, c.TermsOfServiceURI, c.LogoURI}, c.RedirectURIs...) {
+		if u, err := url.Parse(ref); err == nil && hosts[u.Host] {
+			references = append(references, ref)
+		}
+	}
+	return references
+}
+
+type byIssuerAndClient []LegacyIssuerUsage
+
+func (u byIssuerAndClient) Len() int      { return len(u) }
+func (u byIssuerAndClient) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+func (u byIssuerAndClient) Less(i, j int) bool {
+	if u[i].Issuer != u[j].Issuer {
+		return u[i].Issuer < u[j].Issuer
+	}
+	return u[i].ClientID < u[j].ClientID
+}
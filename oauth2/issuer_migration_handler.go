@@ -0,0 +1,83 @@
+package oauth2
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/ladon"
+)
+
+const IssuerMigrationHandlerPath = "/oauth2/issuer/migration"
+
+// IssuerMigrationReport tells which clients still depend on a legacy issuer.
+type IssuerMigrationReport struct {
+	Issuer        string   `json:"issuer"`
+	LegacyIssuers []string `json:"legacy_issuers"`
+
+	// Tokens counts the tokens of legacy issuers presented since this instance started.
+	Tokens []LegacyIssuerUsage `json:"tokens"`
+
+	// Clients are the clients whose URLs point to the host of a legacy issuer.
+	Clients []LegacyIssuerClient `json:"clients"`
+}
+
+type LegacyIssuerClient struct {
+	ClientID   string   `json:"client_id"`
+	References []string `json:"references"`
+}
+
+type IssuerMigrationHandler struct {
+	Issuer    string
+	Migration *IssuerMigration
+	Clients   client.Storage
+
+	H herodot.Herodot
+	W firewall.Firewall
+}
+
+func (h *IssuerMigrationHandler) SetRoutes(r *httprouter.Router) {
+	r.GET(IssuerMigrationHandlerPath, h.Report)
+}
+
+func (h *IssuerMigrationHandler) Report(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: "rn:hydra:oauth2:issuer:migration",
+		Action:   "get",
+	}, "hydra.issuer"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	clients, err := h.Clients.GetClients()
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	report := &IssuerMigrationReport{
+		Issuer:        h.Issuer,
+		LegacyIssuers: h.Migration.LegacyIssuers,
+		Tokens:        h.Migration.Usage(),
+		Clients:       []LegacyIssuerClient{},
+	}
+	for id, c := range clients {
+		if references := h.Migration.References(h.Issuer, c); len(references) > 0 {
+			report.Clients = append(report.Clients, LegacyIssuerClient{ClientID: id, References: references})
+		}
+	}
+	sort.Sort(byLegacyClientID(report.Clients))
+
+	h.H.Write(ctx, w, r, report)
+}
+
+type byLegacyClientID []LegacyIssuerClient
+
+func (c byLegacyClientID) Len() int           { return len(c) }
+func (c byLegacyClientID) Less(i, j int) bool { return c[i].ClientID < c[j].ClientID }
+func (c byLegacyClientID) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
@@ -0,0 +1,68 @@
+package oauth2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/jwk"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestIssuerMigration(t *testing.T) {
+	km := &jwk.MemoryManager{}
+	keys, err := new(jwk.RS256Generator).Generate("1")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(AccessTokenKeyName, keys))
+
+	legacy := &JWTAccessTokenStrategy{
+		CoreStrategy:        hmacStrategy,
+		KeyManager:          km,
+		Issuer:              "https://auth.old.localhost",
+		AccessTokenLifespan: time.Hour,
+	}
+	other := &JWTAccessTokenStrategy{
+		CoreStrategy:        hmacStrategy,
+		KeyManager:          km,
+		Issuer:              "https://elsewhere.localhost",
+		AccessTokenLifespan: time.Hour,
+	}
+
+	m := &IssuerMigration{LegacyIssuers: []string{"https://auth.old.localhost"}}
+	s := &JWTAccessTokenStrategy{
+		CoreStrategy:        hmacStrategy,
+		KeyManager:          km,
+		Issuer:              "https://auth.new.localhost",
+		AccessTokenLifespan: time.Hour,
+		IssuerMigration:     m,
+	}
+
+	ctx := context.Background()
+	request := fosite.NewAccessRequest(&Session{Subject: "peter"})
+	request.Client = &fosite.DefaultClient{ID: "app"}
+
+	token, _, err := legacy.GenerateAccessToken(ctx, request)
+	require.Nil(t, err)
+	_, err = s.ValidateAccessToken(ctx, request, token)
+	require.Nil(t, err)
+	_, err = s.ValidateAccessToken(ctx, request, token)
+	require.Nil(t, err)
+
+	token, _, err = other.GenerateAccessToken(ctx, request)
+	require.Nil(t, err)
+	_, err = s.ValidateAccessToken(ctx, request, token)
+	assert.NotNil(t, err)
+
+	usage := m.Usage()
+	require.Len(t, usage, 1)
+	assert.Equal(t, "https://auth.old.localhost", usage[0].Issuer)
+	assert.Equal(t, "app", usage[0].ClientID)
+	assert.Equal(t, int64(2), usage[0].Tokens)
+
+	assert.Equal(t, []string{"https://auth.old.localhost/cb"}, m.References(s.Issuer, &fosite.DefaultClient{
+		RedirectURIs: []string{"https://auth.old.localhost/cb", "https://auth.new.localhost/cb"},
+	}))
+}
@@ -0,0 +1,157 @@
+package oauth2
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	ejwt "github.com/ory-am/fosite/token/jwt"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/pborman/uuid"
+	"golang.org/x/net/context"
+)
+
+// AccessTokenKeyName is the key set JWT access tokens are signed with. Resource servers verify tokens with the
+// public keys of this set.
+const AccessTokenKeyName = "hydra.access-token"
+
+// JWTAccessTokenStrategy issues self-contained access tokens which are signed JSON web tokens instead of opaque
+// HMAC tokens. Refresh tokens and authorize codes are handled by the embedded strategy.
+//
+// The token's signature identifies its session in the store exactly like the signature of an opaque token does, so
+// introspection reports revoked tokens as inactive. Resource servers which only verify the signature accept a revoked
+// token until it expires.
+type JWTAccessTokenStrategy struct {
+	core.CoreStrategy
+
+	KeyManager jwk.Manager
+
+	// Set is the key set to sign with, it defaults to AccessTokenKeyName.
+	Set string
+
+	Issuer              string
+	AccessTokenLifespan time.Duration
+
+	// IssuerMigration, if set, accepts tokens of the previous issuers.
+	IssuerMigration *IssuerMigration
+}
+
+func (s *JWTAccessTokenStrategy) GenerateAccessToken(_ context.Context, requester fosite.Requester) (string, string, error) {
+	key, kid, err := s.signingKey()
+	if err != nil {
+		return "", "", err
+	}
+
+	requestedAt := requester.GetRequestedAt()
+	if requestedAt.IsZero() {
+		requestedAt = time.Now()
+	}
+
+	claims := map[string]interface{}{
+		"jti":       uuid.New(),
+		"iss":       s.Issuer,
+		"aud":       requester.GetClient().GetID(),
+		"client_id": requester.GetClient().GetID(),
+		"scp":       []string(requester.GetGrantedScopes()),
+		"iat":       requestedAt.Unix(),
+		"nbf":       requestedAt.Unix(),
+		"exp":       requestedAt.Add(s.AccessTokenLifespan).Unix(),
+	}
+	if expiresAt := accessTokenExpiresAt(requester, 0); !expiresAt.IsZero() {
+		claims["exp"] = expiresAt.Unix()
+	}
+	if session, ok := requester.GetSession().(*Session); ok {
+		claims["sub"] = session.Subject
+		if session.Audience != "" {
+			claims["aud"] = session.Audience
+		}
+		if session.Actor != nil {
+			claims["act"] = map[string]interface{}{"sub": session.Actor.Subject, "client_id": session.Actor.ClientID}
+		}
+	}
+
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Header["kid"] = kid
+	token.Claims = claims
+
+	var signature, encoded string
+	if encoded, err = token.SigningString(); err != nil {
+		return "", "", errors.New(err)
+	} else if signature, err = token.Method.Sign(encoded, key); err != nil {
+		return "", "", errors.New(err)
+	}
+	return fmt.Sprintf("%s.%s", encoded, signature), signature, nil
+}
+
+func (s *JWTAccessTokenStrategy) ValidateAccessToken(_ context.Context, _ fosite.Requester, token string) (string, error) {
+	t, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.Errorf("Unexpected signing method: %v", t.Header["alg"])
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		return s.verificationKey(kid)
+	})
+	if err != nil {
+		return "", errors.Errorf("Couldn't parse token: %v", err)
+	} else if !t.Valid {
+		return "", errors.New("Token is invalid")
+	}
+
+	if iss := ejwt.ToString(t.Claims["iss"]); iss != s.Issuer {
+		if s.IssuerMigration == nil || !s.IssuerMigration.IsLegacy(iss) {
+			return "", errors.Errorf("Token was issued by %s", iss)
+		}
+		s.IssuerMigration.Observe(iss, ejwt.ToString(t.Claims["client_id"]))
+	}
+
+	parts := strings.Split(token, ".")
+	return parts[len(parts)-1], nil
+}
+
+func (s *JWTAccessTokenStrategy) set() string {
+	if s.Set == "" {
+		return AccessTokenKeyName
+	}
+	return s.Set
+}
+
+// signingKey returns the first RSA private key of the set and the id of its public counterpart, which is the key
+// resource servers look up.
+func (s *JWTAccessTokenStrategy) signingKey() (*rsa.PrivateKey, string, error) {
+	keys, err := s.KeyManager.GetKeySet(s.set())
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, key := range keys.Keys {
+		if k, ok := key.Key.(*rsa.PrivateKey); ok {
+			return k, publicKeyID(key.KeyID), nil
+		}
+	}
+	return nil, "", errors.Errorf("Key set %s contains no RSA private key", s.set())
+}
+
+func (s *JWTAccessTokenStrategy) verificationKey(kid string) (*rsa.PublicKey, error) {
+	if kid == "" {
+		return nil, errors.New("Token has no key id")
+	}
+
+	keys, err := s.KeyManager.GetKey(s.set(), kid)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := jwk.First(keys.Keys).Key.(type) {
+	case *rsa.PublicKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+	}
+	return nil, errors.Errorf("Key %s is not an RSA key", kid)
+}
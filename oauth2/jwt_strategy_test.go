@@ -0,0 +1,91 @@
+package oauth2_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/hydra/jwk"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestJWTAccessTokenStrategy(t *testing.T) {
+	km := &jwk.MemoryManager{}
+	keys, err := new(jwk.RS256Generator).Generate("1")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(AccessTokenKeyName, keys))
+
+	s := &JWTAccessTokenStrategy{
+		CoreStrategy:        hmacStrategy,
+		KeyManager:          km,
+		Issuer:              "https://hydra.localhost",
+		AccessTokenLifespan: time.Hour,
+	}
+
+	ctx := context.Background()
+	request := fosite.NewAccessRequest(&Session{Subject: "peter", Actor: &Actor{Subject: "photo-service"}})
+	request.Client = &fosite.DefaultClient{ID: "app"}
+	request.GrantScope("core")
+	request.GrantScope("photos")
+
+	token, signature, err := s.GenerateAccessToken(ctx, request)
+	require.Nil(t, err)
+	assert.True(t, strings.HasSuffix(token, "."+signature))
+
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		assert.Equal(t, "public:1", t.Header["kid"])
+		return &jwk.MustRSAPrivate(jwk.First(keys.Key("private:1"))).PublicKey, nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "peter", parsed.Claims["sub"])
+	assert.Equal(t, "app", parsed.Claims["aud"])
+	assert.Equal(t, "app", parsed.Claims["client_id"])
+	assert.Equal(t, "https://hydra.localhost", parsed.Claims["iss"])
+	assert.Equal(t, []interface{}{"core", "photos"}, parsed.Claims["scp"])
+	assert.Equal(t, "photo-service", parsed.Claims["act"].(map[string]interface{})["sub"])
+
+	actual, err := s.ValidateAccessToken(ctx, fosite.NewAccessRequest(new(Session)), token)
+	require.Nil(t, err)
+	assert.Equal(t, signature, actual)
+
+	other, err := new(jwk.RS256Generator).Generate("1")
+	require.Nil(t, err)
+	forged := jwt.New(jwt.SigningMethodRS256)
+	forged.Header["kid"] = "public:1"
+	forged.Claims = parsed.Claims
+	forgedToken, err := forged.SignedString(jwk.MustRSAPrivate(jwk.First(other.Key("private:1"))))
+	require.Nil(t, err)
+
+	expired := fosite.NewAccessRequest(&Session{Subject: "peter"})
+	expired.Client = &fosite.DefaultClient{ID: "app"}
+	expired.RequestedAt = time.Now().Add(-2 * time.Hour)
+	expiredToken, _, err := s.GenerateAccessToken(ctx, expired)
+	require.Nil(t, err)
+
+	for k, c := range []string{
+		token[:len(token)-2],
+		strings.Replace(token, ".", "x.", 1),
+		forgedToken,
+		expiredToken,
+		"not-a-jwt",
+	} {
+		_, err := s.ValidateAccessToken(ctx, fosite.NewAccessRequest(new(Session)), c)
+		assert.NotNil(t, err, "Case %d", k)
+	}
+
+	// The token is tied to its session in the store, removing the session revokes it.
+	validator := &core.CoreValidator{AccessTokenStrategy: s, AccessTokenStorage: store}
+	require.Nil(t, store.CreateAccessTokenSession(ctx, signature, request))
+	require.Nil(t, validator.ValidateToken(ctx, fosite.NewAccessRequest(new(Session)), token))
+	require.Nil(t, store.DeleteAccessTokenSession(ctx, signature))
+	assert.NotNil(t, validator.ValidateToken(ctx, fosite.NewAccessRequest(new(Session)), token))
+
+	_, _, err = (&JWTAccessTokenStrategy{KeyManager: km, Set: "does-not-exist"}).GenerateAccessToken(ctx, request)
+	assert.NotNil(t, err)
+}
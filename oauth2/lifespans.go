@@ -0,0 +1,66 @@
+package oauth2
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/pkg"
+)
+
+// clientSettings returns the settings of a client, or empty settings if the client has none or m is nil.
+func clientSettings(m client.SettingsManager, clientID string) (*client.Settings, error) {
+	if m == nil {
+		return &client.Settings{ClientID: clientID}, nil
+	}
+
+	settings, err := m.GetSettings(clientID)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return &client.Settings{ClientID: clientID}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// clientLifespans returns the lifespans a client overrides in its settings.
+func clientLifespans(m client.SettingsManager, clientID string) (client.Lifespans, error) {
+	settings, err := clientSettings(m, clientID)
+	if err != nil {
+		return client.Lifespans{}, err
+	}
+	return settings.GetLifespans(), nil
+}
+
+// setTokenLifespans records when the access and refresh tokens issued now expire. The session is stored with the
+// tokens, which is how the lifespans of a client outlive the request.
+func setTokenLifespans(session *Session, lifespans client.Lifespans, now time.Time) {
+	session.SetTokenLifespan(pkg.TokenKindAccessToken, now, lifespans.AccessToken)
+	session.SetTokenLifespan(pkg.TokenKindRefreshToken, now, lifespans.RefreshToken)
+}
+
+// expiresIn returns the number of seconds an access token is valid, the client's lifespan if it overrides the
+// server's.
+func expiresIn(lifespans client.Lifespans, lifespan time.Duration) int64 {
+	if lifespans.AccessToken > 0 {
+		lifespan = lifespans.AccessToken
+	}
+	return int64(lifespan / time.Second)
+}
+
+// accessTokenExpiresAt returns when the access token of a request expires. The tokens of clients which override the
+// server's lifespan carry their expiry in the session, all others expire lifespan after they were requested. The
+// zero time is returned if the token does not expire.
+func accessTokenExpiresAt(request fosite.Requester, lifespan time.Duration) time.Time {
+	if session, ok := request.GetSession().(*Session); ok {
+		if expiresAt, ok := session.GetTokenExpiresAt(pkg.TokenKindAccessToken); ok {
+			return expiresAt
+		}
+	}
+
+	if lifespan == 0 {
+		return time.Time{}
+	}
+	return request.GetRequestedAt().Add(lifespan)
+}
@@ -0,0 +1,103 @@
+package oauth2
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+)
+
+// LoginSession is a browser session in which a subject authenticated. It records the clients which received tokens
+// in it, so that they can be told when the session ends.
+type LoginSession struct {
+	ID              string    `json:"id" gorethink:"id"`
+	Subject         string    `json:"subject" gorethink:"subject"`
+	AuthenticatedAt time.Time `json:"authenticated_at" gorethink:"authenticated_at"`
+	ExpiresAt       time.Time `json:"expires_at" gorethink:"expires_at"`
+	Clients         []string  `json:"clients" gorethink:"clients"`
+
+	// UserAgent and IPAddress describe the device the session was started on, so that users can tell their
+	// sessions apart.
+	UserAgent string `json:"user_agent,omitempty" gorethink:"user_agent,omitempty"`
+	IPAddress string `json:"ip_address,omitempty" gorethink:"ip_address,omitempty"`
+
+	// Binding is a hash of the secret which binds the session to its user agent.
+	Binding string `json:"-" gorethink:"binding"`
+}
+
+// IsExpired returns true if the session has ended.
+func (s *LoginSession) IsExpired() bool {
+	return !time.Now().Before(s.ExpiresAt)
+}
+
+// LoginSessionManager stores login sessions.
+type LoginSessionManager interface {
+	CreateLoginSession(s *LoginSession) error
+
+	// GetLoginSession returns a session or pkg.ErrNotFound if it does not exist or is expired.
+	GetLoginSession(id string) (*LoginSession, error)
+
+	// GetLoginSessions returns all sessions of a subject which have not expired.
+	GetLoginSessions(subject string) ([]*LoginSession, error)
+
+	// AddLoginSessionClient records that a client received tokens in the session.
+	AddLoginSessionClient(id, clientID string) error
+
+	DeleteLoginSession(id string) error
+}
+
+const (
+	loginSessionCookie = "hydra_login_session"
+
+	// DefaultLoginSessionLifespan is how long a login session lasts if the handler does not say otherwise.
+	DefaultLoginSessionLifespan = time.Hour * 24
+)
+
+// loginSessionFromCookie returns the session the user agent's cookie refers to, or nil if it has none or the cookie
+// was not issued for the session.
+func loginSessionFromCookie(m LoginSessionManager, r *http.Request) *LoginSession {
+	cookie, err := r.Cookie(loginSessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+
+	s, err := m.GetLoginSession(parts[0])
+	if err != nil {
+		if !errors.Is(err, pkg.ErrNotFound) {
+			logger.LogRequestError(r, err)
+		}
+		return nil
+	} else if subtle.ConstantTimeCompare([]byte(bindingHash(parts[1])), []byte(s.Binding)) != 1 {
+		return nil
+	}
+	return s
+}
+
+// remoteIP returns the address of the user agent without its port.
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func setLoginSessionCookie(w http.ResponseWriter, r *http.Request, value string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginSessionCookie,
+		Value:    value,
+		Path:     "/oauth2",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+	})
+}
@@ -0,0 +1,87 @@
+package oauth2
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type LoginSessionMemoryManager struct {
+	Sessions map[string]*LoginSession
+	sync.RWMutex
+}
+
+func NewLoginSessionMemoryManager() *LoginSessionMemoryManager {
+	return &LoginSessionMemoryManager{
+		Sessions: map[string]*LoginSession{},
+	}
+}
+
+func (m *LoginSessionMemoryManager) CreateLoginSession(s *LoginSession) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.Sessions[s.ID] = s
+	return nil
+}
+
+func (m *LoginSessionMemoryManager) GetLoginSession(id string) (*LoginSession, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.Sessions[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if s.IsExpired() {
+		delete(m.Sessions, id)
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	return copyLoginSession(s), nil
+}
+
+func (m *LoginSessionMemoryManager) GetLoginSessions(subject string) ([]*LoginSession, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	sessions := []*LoginSession{}
+	for _, s := range m.Sessions {
+		if s.Subject == subject && !s.IsExpired() {
+			sessions = append(sessions, copyLoginSession(s))
+		}
+	}
+	return sessions, nil
+}
+
+func (m *LoginSessionMemoryManager) AddLoginSessionClient(id, clientID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.Sessions[id]
+	if !ok || s.IsExpired() {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	for _, c := range s.Clients {
+		if c == clientID {
+			return nil
+		}
+	}
+	s.Clients = append(s.Clients, clientID)
+	return nil
+}
+
+func (m *LoginSessionMemoryManager) DeleteLoginSession(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Sessions, id)
+	return nil
+}
+
+func copyLoginSession(s *LoginSession) *LoginSession {
+	c := *s
+	c.Clients = append([]string{}, s.Clients...)
+	return &c
+}
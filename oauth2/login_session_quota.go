@@ -0,0 +1,25 @@
+package oauth2
+
+import (
+	"github.com/ory-am/hydra/quota"
+)
+
+// LoginSessionQuotaManager refuses to create a login session once its subject has as many sessions as Quota allows.
+// The sessions are counted and the new session is created while holding the quota's lease of the subject, see
+// quota.Quota.Admit.
+type LoginSessionQuotaManager struct {
+	LoginSessionManager
+	Quota *quota.Quota
+}
+
+func (m *LoginSessionQuotaManager) CreateLoginSession(s *LoginSession) error {
+	return m.Quota.Admit(s.Subject, func() (int, error) {
+		sessions, err := m.LoginSessionManager.GetLoginSessions(s.Subject)
+		if err != nil {
+			return 0, err
+		}
+		return len(sessions), nil
+	}, func() error {
+		return m.LoginSessionManager.CreateLoginSession(s)
+	})
+}
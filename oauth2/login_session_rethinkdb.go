@@ -0,0 +1,86 @@
+package oauth2
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// LoginSessionRethinkManager reads and writes directly against the database because a session started on one
+// instance may be ended on another.
+type LoginSessionRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *LoginSessionRethinkManager) CreateLoginSession(s *LoginSession) error {
+	if _, err := m.Table.Insert(s, r.InsertOpts{Conflict: "replace"}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *LoginSessionRethinkManager) GetLoginSession(id string) (*LoginSession, error) {
+	res, err := m.Table.Get(id).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer res.Close()
+
+	var s LoginSession
+	if res.IsNil() {
+		return nil, errors.New(pkg.ErrNotFound)
+	} else if err := res.One(&s); err != nil {
+		return nil, errors.New(err)
+	}
+
+	if s.IsExpired() {
+		if err := m.DeleteLoginSession(id); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return &s, nil
+}
+
+func (m *LoginSessionRethinkManager) GetLoginSessions(subject string) ([]*LoginSession, error) {
+	rows, err := m.Table.Filter(map[string]interface{}{"subject": subject}).Run(m.Session)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	sessions := []*LoginSession{}
+	var s *LoginSession
+	for rows.Next(&s) {
+		if !s.IsExpired() {
+			sessions = append(sessions, s)
+		}
+		s = nil
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.New(rows.Err())
+	}
+	return sessions, nil
+}
+
+func (m *LoginSessionRethinkManager) AddLoginSessionClient(id, clientID string) error {
+	if _, err := m.GetLoginSession(id); err != nil {
+		return err
+	}
+
+	if _, err := m.Table.Get(id).Update(func(row r.Term) interface{} {
+		return map[string]interface{}{"clients": row.Field("clients").Default([]string{}).SetInsert(clientID)}
+	}).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *LoginSessionRethinkManager) DeleteLoginSession(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package oauth2
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/pborman/uuid"
+	"github.com/square/go-jose"
+)
+
+const (
+	LogoutHandlerPath = "/oauth2/sessions/logout"
+
+	// BackChannelLogoutEvent is the event of logout tokens, see OpenID Connect Back-Channel Logout 1.0 section 2.4.
+	BackChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+)
+
+// LogoutHandler implements OpenID Connect RP-Initiated Logout 1.0 and sends the logout tokens of OpenID Connect
+// Back-Channel Logout 1.0 to the clients which took part in the ended session.
+type LogoutHandler struct {
+	Issuer string
+
+	Sessions LoginSessionManager
+	Settings client.SettingsManager
+
+	// KeyManager holds OpenIDConnectKeyName, which ID tokens given as id_token_hint were signed with and logout
+	// tokens are signed with.
+	KeyManager jwk.Manager
+
+	// LogoutURL is where the user agent is sent if the client did not ask for a post_logout_redirect_uri.
+	LogoutURL url.URL
+
+	// HTTPClient delivers logout tokens. A client with a timeout of five seconds is used if it is nil.
+	HTTPClient *http.Client
+
+	H herodot.Herodot
+}
+
+// logoutHint are the claims of an id_token_hint.
+type logoutHint struct {
+	Issuer    string      `json:"iss"`
+	Subject   string      `json:"sub"`
+	Audience  interface{} `json:"aud"`
+	SessionID string      `json:"sid"`
+}
+
+func (h *LogoutHandler) SetRoutes(r *httprouter.Router) {
+	r.GET(LogoutHandlerPath, h.Logout)
+	r.POST(LogoutHandlerPath, h.Logout)
+}
+
+// Logout ends the login session of the user agent. Relying parties identify themselves with an id_token_hint, which
+// is required to be sent to a post_logout_redirect_uri. The hint is accepted after the ID token expired, which is why
+// its sid never ends a session on its own: only the session of the user agent's cookie is ended.
+func (h *LogoutHandler) Logout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+
+	if err := r.ParseForm(); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	var hint *logoutHint
+	if token := r.Form.Get("id_token_hint"); token != "" {
+		var err error
+		if hint, err = h.verifyHint(token); err != nil {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	redirect, err := h.postLogoutRedirect(hint, r.Form.Get("post_logout_redirect_uri"), r.Form.Get("state"))
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	session := loginSessionFromCookie(h.Sessions, r)
+	if session != nil && hint != nil && session.Subject != hint.Subject {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("The id_token_hint was issued to another subject than the one logged in"))
+		return
+	}
+
+	if session != nil {
+		if err := h.Sessions.DeleteLoginSession(session.ID); err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		h.notifyClients(session)
+	}
+	setLoginSessionCookie(w, r, "", time.Unix(0, 0))
+
+	if redirect == "" {
+		redirect = h.LogoutURL.String()
+	}
+	if redirect == "" {
+		w.Write([]byte("You have been logged out."))
+		return
+	}
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// verifyHint checks the signature and issuer of an ID token. Its expiry is ignored on purpose.
+func (h *LogoutHandler) verifyHint(token string) (*logoutHint, error) {
+	sig, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, errors.New(err)
+	} else if len(sig.Signatures) != 1 {
+		return nil, errors.New("The id_token_hint must carry exactly one signature")
+	}
+
+	keys, err := h.KeyManager.GetKey(OpenIDConnectKeyName, sig.Signatures[0].Header.KeyID)
+	if err != nil {
+		return nil, errors.New("The id_token_hint was not signed by this server")
+	}
+
+	var key interface{}
+	switch k := jwk.First(keys.Keys).Key.(type) {
+	case *rsa.PrivateKey:
+		key = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		key = &k.PublicKey
+	default:
+		key = k
+	}
+
+	payload, err := sig.Verify(key)
+	if err != nil {
+		return nil, errors.New("The signature of the id_token_hint is invalid")
+	}
+
+	var hint logoutHint
+	if err := json.Unmarshal(payload, &hint); err != nil {
+		return nil, errors.New(err)
+	} else if hint.Issuer != h.Issuer {
+		return nil, errors.Errorf("The id_token_hint was issued by %s", hint.Issuer)
+	}
+	return &hint, nil
+}
+
+// postLogoutRedirect returns the post_logout_redirect_uri with the state if it is registered for the client the
+// hint was issued to.
+func (h *LogoutHandler) postLogoutRedirect(hint *logoutHint, uri, state string) (string, error) {
+	if uri == "" {
+		return "", nil
+	} else if hint == nil {
+		return "", errors.New("A post_logout_redirect_uri requires an id_token_hint")
+	}
+
+	for _, clientID := range hint.audience() {
+		settings, err := h.Settings.GetSettings(clientID)
+		if errors.Is(err, pkg.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		for _, registered := range settings.PostLogoutRedirectURIs {
+			if registered != uri {
+				continue
+			}
+
+			u, err := url.Parse(uri)
+			if err != nil {
+				return "", errors.New(err)
+			}
+			if state != "" {
+				q := u.Query()
+				q.Set("state", state)
+				u.RawQuery = q.Encode()
+			}
+			return u.String(), nil
+		}
+	}
+	return "", errors.Errorf("The post_logout_redirect_uri %s is not registered", uri)
+}
+
+// notifyClients sends a logout token to every client of the session which registered a back-channel logout uri.
+// Failed deliveries are logged, they do not keep the session alive.
+func (h *LogoutHandler) notifyClients(session *LoginSession) {
+	var wg sync.WaitGroup
+	for _, clientID := range session.Clients {
+		settings, err := h.Settings.GetSettings(clientID)
+		if errors.Is(err, pkg.ErrNotFound) {
+			continue
+		} else if err != nil {
+			logger.LogError(err)
+			continue
+		} else if settings.BackChannelLogoutURI == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(settings *client.Settings) {
+			defer wg.Done()
+			if err := h.sendLogoutToken(session, settings); err != nil {
+				logrus.Warnf("Could not notify client %s of the end of session %s: %s", settings.ClientID, session.ID, err)
+			}
+		}(settings)
+	}
+	wg.Wait()
+}
+
+func (h *LogoutHandler) sendLogoutToken(session *LoginSession, settings *client.Settings) error {
+	token, err := h.LogoutToken(session, settings)
+	if err != nil {
+		return err
+	}
+
+	res, err := h.httpClient().PostForm(settings.BackChannelLogoutURI, url.Values{"logout_token": {token}})
+	if err != nil {
+		return errors.New(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("Expected status code %d but got %d", http.StatusOK, res.StatusCode)
+	}
+	return nil
+}
+
+// LogoutToken returns the logout token telling a client that the session ended, see OpenID Connect Back-Channel
+// Logout 1.0 section 2.4.
+func (h *LogoutHandler) LogoutToken(session *LoginSession, settings *client.Settings) (string, error) {
+	keys, err := h.KeyManager.GetKeySet(OpenIDConnectKeyName)
+	if err != nil {
+		return "", err
+	}
+
+	var key *rsa.PrivateKey
+	var kid string
+	for _, k := range keys.Keys {
+		if rsaKey, ok := k.Key.(*rsa.PrivateKey); ok {
+			key, kid = rsaKey, publicKeyID(k.KeyID)
+			break
+		}
+	}
+	if key == nil {
+		return "", errors.Errorf("Key set %s contains no RSA private key", OpenIDConnectKeyName)
+	}
+
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Header["kid"] = kid
+	token.Header["typ"] = "logout+jwt"
+	token.Claims = map[string]interface{}{
+		"iss":    h.Issuer,
+		"aud":    settings.ClientID,
+		"iat":    time.Now().Unix(),
+		"jti":    uuid.New(),
+		"sub":    session.Subject,
+		"sid":    session.ID,
+		"events": map[string]interface{}{BackChannelLogoutEvent: map[string]interface{}{}},
+	}
+
+	var signature, encoded string
+	if encoded, err = token.SigningString(); err != nil {
+		return "", errors.New(err)
+	} else if signature, err = token.Method.Sign(encoded, key); err != nil {
+		return "", errors.New(err)
+	}
+	return fmt.Sprintf("%s.%s", encoded, signature), nil
+}
+
+func (h *LogoutHandler) httpClient() *http.Client {
+	if h.HTTPClient == nil {
+		return &http.Client{Timeout: time.Second * 5}
+	}
+	return h.HTTPClient
+}
+
+// audience returns the aud claim, which is a string or an array of strings.
+func (h *logoutHint) audience() []string {
+	if aud, ok := h.Audience.(string); ok {
+		return []string{aud}
+	}
+	return toStringSlice(h.Audience)
+}
@@ -0,0 +1,134 @@
+package oauth2_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/jwk"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogout(t *testing.T) {
+	km := &jwk.MemoryManager{}
+	keys, err := new(jwk.RS256Generator).Generate("1")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(OpenIDConnectKeyName, keys))
+
+	tokens := make(chan string, 1)
+	rp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens <- r.PostFormValue("logout_token")
+	}))
+	defer rp.Close()
+
+	settings := client.NewSettingsMemoryManager()
+	require.Nil(t, settings.SetSettings(&client.Settings{
+		ClientID:               "app",
+		PostLogoutRedirectURIs: []string{"https://app.localhost/logged-out"},
+		BackChannelLogoutURI:   rp.URL,
+	}))
+
+	binding := sha256.Sum256([]byte("secret"))
+	sessions := NewLoginSessionMemoryManager()
+	require.Nil(t, sessions.CreateLoginSession(&LoginSession{
+		ID:        "session",
+		Subject:   "peter",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Clients:   []string{"app", "unknown"},
+		Binding:   hex.EncodeToString(binding[:]),
+	}))
+
+	h := &LogoutHandler{
+		Issuer:     "https://hydra.localhost",
+		Sessions:   sessions,
+		Settings:   settings,
+		KeyManager: km,
+		H:          &herodot.JSON{},
+	}
+	router := httprouter.New()
+	h.SetRoutes(router)
+
+	// The hints have expired long ago, which is fine for logging out.
+	hint := func(issuer string) string {
+		signer, err := jose.NewSigner(jose.RS256, &jose.JsonWebKey{Key: jwk.First(keys.Key("private:1")).Key, KeyID: "public:1"})
+		require.Nil(t, err)
+		payload := []byte(`{"iss":"` + issuer + `","sub":"peter","aud":"app","sid":"session","exp":1}`)
+		sig, err := signer.Sign(payload)
+		require.Nil(t, err)
+		token, err := sig.CompactSerialize()
+		require.Nil(t, err)
+		return token
+	}
+
+	logout := func(query url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", LogoutHandlerPath+"?"+query.Encode(), nil)
+		require.Nil(t, err)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	res := logout(url.Values{"post_logout_redirect_uri": {"https://app.localhost/logged-out"}})
+	assert.Equal(t, http.StatusBadRequest, res.Code, "A redirect requires a hint")
+
+	res = logout(url.Values{"id_token_hint": {hint("https://other.localhost")}})
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = logout(url.Values{
+		"id_token_hint":            {hint("https://hydra.localhost")},
+		"post_logout_redirect_uri": {"https://evil.localhost/"},
+	})
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	// Without the cookie of the session, the sid of the hint alone does not end it.
+	valid := url.Values{
+		"id_token_hint":            {hint("https://hydra.localhost")},
+		"post_logout_redirect_uri": {"https://app.localhost/logged-out"},
+		"state":                    {"abc"},
+	}
+	res = logout(valid)
+	require.Equal(t, http.StatusFound, res.Code)
+	_, err = sessions.GetLoginSession("session")
+	require.Nil(t, err)
+	assert.Len(t, tokens, 0)
+
+	res = logout(valid, &http.Cookie{Name: "hydra_login_session", Value: "session.wrong"})
+	require.Equal(t, http.StatusFound, res.Code)
+	_, err = sessions.GetLoginSession("session")
+	require.Nil(t, err)
+
+	res = logout(valid, &http.Cookie{Name: "hydra_login_session", Value: "session.secret"})
+	require.Equal(t, http.StatusFound, res.Code)
+	assert.Equal(t, "https://app.localhost/logged-out?state=abc", res.Header().Get("Location"))
+
+	_, err = sessions.GetLoginSession("session")
+	assert.NotNil(t, err)
+
+	select {
+	case token := <-tokens:
+		parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+			return jwk.MustRSAPublic(jwk.First(keys.Key("public:1"))), nil
+		})
+		require.Nil(t, err)
+		assert.Equal(t, "app", parsed.Claims["aud"])
+		assert.Equal(t, "session", parsed.Claims["sid"])
+		assert.Equal(t, "peter", parsed.Claims["sub"])
+		assert.Contains(t, parsed.Claims["events"], BackChannelLogoutEvent)
+	default:
+		t.Fatal("The client was not notified")
+	}
+}
@@ -0,0 +1,150 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The results of requests to the OAuth2 endpoints.
+const (
+	ResultSuccess  = "success"
+	ResultRejected = "rejected"
+	ResultError    = "error"
+)
+
+var accessTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "oauth2",
+	Name:      "access_tokens_total",
+	Help:      "Number of access tokens issued, validated and rejected, partitioned by token format.",
+}, []string{"format", "operation"})
+
+var legacyIssuerTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "oauth2",
+	Name:      "legacy_issuer_tokens_total",
+	Help:      "Number of accepted tokens which were issued by a legacy issuer.",
+}, []string{"issuer"})
+
+var requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "oauth2",
+	Name:      "requests_total",
+	Help:      "Number of requests to the token, authorization and introspection endpoints, partitioned by grant type, hashed client id and result.",
+}, []string{"endpoint", "grant_type", "client", "result"})
+
+var requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "hydra",
+	Subsystem: "oauth2",
+	Name:      "request_duration_seconds",
+	Help:      "Latency of requests to the token, authorization and introspection endpoints.",
+}, []string{"endpoint", "grant_type", "client", "result"})
+
+func init() {
+	prometheus.MustRegister(accessTokens)
+	prometheus.MustRegister(legacyIssuerTokens)
+	prometheus.MustRegister(requests, requestDuration)
+}
+
+func observeAccessToken(format, operation string) {
+	accessTokens.WithLabelValues(format, operation).Inc()
+}
+
+func observeLegacyIssuer(issuer string) {
+	legacyIssuerTokens.WithLabelValues(issuer).Inc()
+}
+
+// HashClientID returns a short hash of a client id, so that metrics can be broken down by client without
+// exposing client ids to everyone who can read them.
+func HashClientID(id string) string {
+	if id == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:6])
+}
+
+// requestLabels returns the grant type and the client id of a request.
+type requestLabels func(r *http.Request) (grantType, clientID string)
+
+// instrument counts the requests of an endpoint and observes their latency. The result is derived from the
+// response: server errors are errors, client errors and authorization errors redirected to the client are
+// rejections.
+func instrument(endpoint string, labels requestLabels, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r, ps)
+
+		grantType, clientID := labels(r)
+		result := ResultSuccess
+		if rec.status >= 500 {
+			result = ResultError
+		} else if rec.status >= 400 || redirectsError(rec.Header().Get("Location")) {
+			result = ResultRejected
+		}
+
+		values := []string{endpoint, grantType, HashClientID(clientID), result}
+		requests.WithLabelValues(values...).Inc()
+		requestDuration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+	}
+}
+
+func tokenRequestLabels(r *http.Request) (string, string) {
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+	return r.PostForm.Get("grant_type"), clientID
+}
+
+func authorizeRequestLabels(r *http.Request) (string, string) {
+	grantType := "authorization_code"
+	for _, t := range strings.Fields(r.Form.Get("response_type")) {
+		if t == "token" || t == "id_token" {
+			grantType = "implicit"
+		}
+	}
+	return grantType, r.Form.Get("client_id")
+}
+
+func introspectionRequestLabels(r *http.Request) (string, string) {
+	// Resource servers authenticate with an access token, the client they act as is not known here.
+	return "", ""
+}
+
+// redirectsError tells whether a redirect carries an OAuth2 error to the client.
+func redirectsError(location string) bool {
+	if location == "" {
+		return false
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	if u.Query().Get("error") != "" {
+		return true
+	}
+
+	// The implicit flow returns errors in the fragment.
+	fragment, err := url.ParseQuery(u.Fragment)
+	return err == nil && fragment.Get("error") != ""
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,97 @@
+package oauth2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestCount(t *testing.T, endpoint, grantType, client, result string) float64 {
+	var d dto.Metric
+	require.Nil(t, requests.WithLabelValues(endpoint, grantType, client, result).Write(&d))
+	return d.Counter.GetValue()
+}
+
+func TestHashClientID(t *testing.T) {
+	assert.Equal(t, "", HashClientID(""))
+	assert.Equal(t, HashClientID("app"), HashClientID("app"))
+	assert.NotEqual(t, HashClientID("app"), HashClientID("other-app"))
+	assert.Len(t, HashClientID("app"), 12)
+	assert.False(t, strings.Contains(HashClientID("app"), "app"))
+}
+
+func TestInstrument(t *testing.T) {
+	for k, c := range []struct {
+		endpoint  string
+		labels    requestLabels
+		method    string
+		query     string
+		form      url.Values
+		basicAuth string
+		status    int
+		location  string
+		grantType string
+		client    string
+		result    string
+	}{
+		{
+			endpoint: "token", labels: tokenRequestLabels, method: "POST",
+			form: url.Values{"grant_type": {"client_credentials"}}, basicAuth: "app",
+			status: http.StatusOK, grantType: "client_credentials", client: "app", result: ResultSuccess,
+		},
+		{
+			endpoint: "token", labels: tokenRequestLabels, method: "POST",
+			form:   url.Values{"grant_type": {"refresh_token"}, "client_id": {"public-app"}},
+			status: http.StatusUnauthorized, grantType: "refresh_token", client: "public-app", result: ResultRejected,
+		},
+		{
+			endpoint: "token", labels: tokenRequestLabels, method: "POST",
+			form: url.Values{"grant_type": {"authorization_code"}}, basicAuth: "app",
+			status: http.StatusInternalServerError, grantType: "authorization_code", client: "app", result: ResultError,
+		},
+		{
+			endpoint: "authorize", labels: authorizeRequestLabels, method: "GET",
+			query:  "response_type=code&client_id=app",
+			status: http.StatusFound, location: "https://app/cb?code=foo",
+			grantType: "authorization_code", client: "app", result: ResultSuccess,
+		},
+		{
+			endpoint: "authorize", labels: authorizeRequestLabels, method: "GET",
+			query:  "response_type=id_token+token&client_id=app",
+			status: http.StatusFound, location: "https://app/cb#error=access_denied",
+			grantType: "implicit", client: "app", result: ResultRejected,
+		},
+		{
+			endpoint: "introspect", labels: introspectionRequestLabels, method: "POST",
+			form:   url.Values{"token": {"foo"}},
+			status: http.StatusOK, result: ResultSuccess,
+		},
+	} {
+		before := requestCount(t, c.endpoint, c.grantType, HashClientID(c.client), c.result)
+
+		h := instrument(c.endpoint, c.labels, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			require.Nil(t, r.ParseForm())
+			if c.location != "" {
+				w.Header().Set("Location", c.location)
+			}
+			w.WriteHeader(c.status)
+		})
+
+		r, err := http.NewRequest(c.method, "http://localhost/"+c.endpoint+"?"+c.query, strings.NewReader(c.form.Encode()))
+		require.Nil(t, err)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if c.basicAuth != "" {
+			r.SetBasicAuth(c.basicAuth, "secret")
+		}
+		h(httptest.NewRecorder(), r, nil)
+
+		assert.Equal(t, before+1, requestCount(t, c.endpoint, c.grantType, HashClientID(c.client), c.result), "Case %d", k)
+	}
+}
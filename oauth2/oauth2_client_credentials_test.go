@@ -1,10 +1,21 @@
 package oauth2_test
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	. "github.com/ory-am/hydra/oauth2"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/scope"
+	"github.com/ory-am/ladon"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 )
 
@@ -13,3 +24,121 @@ func TestClientCredentials(t *testing.T) {
 	pkg.RequireError(t, false, err)
 	assert.NotEmpty(t, tok.AccessToken)
 }
+
+func TestClientCredentialsAudience(t *testing.T) {
+	handler.Audiences = &LadonAudiencePolicy{Warden: &ladon.Ladon{
+		Manager: &ladon.MemoryManager{
+			Policies: map[string]ladon.Policy{
+				"1": &ladon.DefaultPolicy{
+					ID:        "1",
+					Subjects:  []string{"app-client"},
+					Resources: []string{"rn:hydra:oauth2:audiences:https://photos.example.com"},
+					Actions:   []string{"request"},
+					Effect:    ladon.AllowAccess,
+				},
+			},
+		},
+	}}
+	defer func() { handler.Audiences = nil }()
+
+	token := func(form url.Values) (int, map[string]interface{}) {
+		form.Set("grant_type", "client_credentials")
+		form.Set("scope", "hydra")
+		req, err := http.NewRequest("POST", oauthClientConfig.TokenURL, strings.NewReader(form.Encode()))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("app-client", "secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer res.Body.Close()
+
+		var body map[string]interface{}
+		require.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+		return res.StatusCode, body
+	}
+
+	for k, c := range []struct {
+		form  url.Values
+		error string
+	}{
+		{form: url.Values{"resource": {"https://photos.example.com"}}},
+		{form: url.Values{"audience": {"https://photos.example.com"}}},
+		{form: url.Values{"resource": {"https://billing.example.com"}}, error: "invalid_target"},
+		{form: url.Values{"resource": {"photos"}}, error: "invalid_target"},
+		{form: url.Values{"resource": {"https://photos.example.com", "https://billing.example.com"}}, error: "invalid_target"},
+	} {
+		code, body := token(c.form)
+		if c.error != "" {
+			assert.Equal(t, http.StatusBadRequest, code, "Case %d", k)
+			assert.Equal(t, c.error, body["error"], "Case %d", k)
+			continue
+		}
+		require.Equal(t, http.StatusOK, code, "Case %d: %v", k, body)
+
+		request := fosite.NewAccessRequest(new(Session))
+		validator := &core.CoreValidator{AccessTokenStrategy: hmacStrategy, AccessTokenStorage: store}
+		require.Nil(t, validator.ValidateToken(context.Background(), request, body["access_token"].(string)), "Case %d", k)
+		assert.Equal(t, "https://photos.example.com", request.GetSession().(*Session).Audience, "Case %d", k)
+	}
+}
+
+func TestClientCredentialsScopeStrategy(t *testing.T) {
+	secret, err := hasher.Hash([]byte("secret"))
+	require.Nil(t, err)
+	store.Clients["wildcard-client"] = &fosite.DefaultClient{
+		ID:            "wildcard-client",
+		Secret:        secret,
+		GrantTypes:    []string{"client_credentials"},
+		GrantedScopes: []string{"hydra", "photos.*.read"},
+	}
+	store.Clients["exact-client"] = &fosite.DefaultClient{
+		ID:            "exact-client",
+		Secret:        secret,
+		GrantTypes:    []string{"client_credentials"},
+		GrantedScopes: []string{"hydra"},
+	}
+	defer func() {
+		delete(store.Clients, "wildcard-client")
+		delete(store.Clients, "exact-client")
+		handler.ScopeStrategy = nil
+		clientCredentialsHandler.ScopeStrategy = nil
+	}()
+
+	for k, c := range []struct {
+		strategy scope.Strategy
+		client   string
+		scope    string
+		error    string
+	}{
+		{strategy: scope.Wildcard, client: "wildcard-client", scope: "hydra photos.album.read"},
+		{strategy: scope.Wildcard, client: "wildcard-client", scope: "hydra photos.album.write", error: "invalid_scope"},
+		{strategy: scope.Hierarchic, client: "wildcard-client", scope: "hydra photos.album.read", error: "invalid_scope"},
+		{strategy: scope.Exact, client: "exact-client", scope: "hydra"},
+		{strategy: scope.Exact, client: "exact-client", scope: "hydra hydra.keys", error: "invalid_scope"},
+		{strategy: scope.Hierarchic, client: "exact-client", scope: "hydra hydra.keys"},
+	} {
+		handler.ScopeStrategy = c.strategy
+		clientCredentialsHandler.ScopeStrategy = c.strategy
+
+		form := url.Values{"grant_type": {"client_credentials"}, "scope": {c.scope}}
+		req, err := http.NewRequest("POST", oauthClientConfig.TokenURL, strings.NewReader(form.Encode()))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c.client, "secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err, "Case %d", k)
+		var body map[string]interface{}
+		err = json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		require.Nil(t, err, "Case %d", k)
+
+		if c.error != "" {
+			assert.NotEqual(t, http.StatusOK, res.StatusCode, "Case %d", k)
+			assert.Equal(t, c.error, body["error"], "Case %d", k)
+			continue
+		}
+		assert.Equal(t, http.StatusOK, res.StatusCode, "Case %d: %v", k, body)
+	}
+}
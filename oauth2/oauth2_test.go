@@ -47,6 +47,16 @@ var authCodeHandler = &explicit.AuthorizeExplicitGrantTypeHandler{
 
 var hasher = &hash.BCrypt{}
 
+var clientCredentialsHandler = &ClientCredentialsGrantHandler{
+	TokenEndpointHandler: &client.ClientCredentialsGrantHandler{
+		HandleHelper: &core.HandleHelper{
+			AccessTokenStrategy: hmacStrategy,
+			AccessTokenStorage:  store,
+			AccessTokenLifespan: time.Hour,
+		},
+	},
+}
+
 var handler = &Handler{
 	OAuth2: &fosite.Fosite{
 		Store:          store,
@@ -56,16 +66,10 @@ var handler = &Handler{
 		},
 		TokenEndpointHandlers: fosite.TokenEndpointHandlers{
 			authCodeHandler,
-			&client.ClientCredentialsGrantHandler{
-				HandleHelper: &core.HandleHelper{
-					AccessTokenStrategy: hmacStrategy,
-					AccessTokenStorage:  store,
-					AccessTokenLifespan: time.Hour,
-				},
-			},
+			clientCredentialsHandler,
 		},
 		AuthorizedRequestValidators: fosite.AuthorizedRequestValidators{},
-		Hasher: hasher,
+		Hasher:                      hasher,
 	},
 	Consent: &DefaultConsentStrategy{
 		Issuer:     "https://hydra.localhost",
@@ -0,0 +1,34 @@
+package oauth2
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+// ReuseDetectingStore makes refresh tokens one-time-use. Every refresh grant rotates the refresh token, if a rotated
+// token is used again it was most likely stolen and every token of its grant is revoked, including the refresh token
+// the legitimate client holds.
+type ReuseDetectingStore struct {
+	pkg.FositeStorer
+}
+
+func (s *ReuseDetectingStore) GetRefreshTokenSession(ctx context.Context, signature string, session interface{}) (fosite.Requester, error) {
+	request, err := s.FositeStorer.GetRefreshTokenSession(ctx, signature, session)
+	if !errors.Is(err, fosite.ErrNotFound) {
+		return request, err
+	}
+
+	if rotated, rerr := s.FositeStorer.IsRefreshTokenRotated(ctx, signature); rerr != nil {
+		return nil, rerr
+	} else if rotated {
+		revoked, rerr := s.FositeStorer.RevokeRefreshToken(ctx, signature)
+		if rerr != nil {
+			return nil, rerr
+		}
+		logrus.WithField("revoked_access_tokens", len(revoked)).Warnln("A rotated refresh token was used again, all tokens of its grant were revoked.")
+	}
+	return nil, err
+}
@@ -0,0 +1,57 @@
+package oauth2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/internal"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRefreshTokenReuseRevokesGrant(t *testing.T) {
+	inner := &internal.FositeMemoryStore{
+		AuthorizeCodes: make(map[string]fosite.Requester),
+		IDSessions:     make(map[string]fosite.Requester),
+		AccessTokens:   make(map[string]fosite.Requester),
+		Implicit:       make(map[string]fosite.Requester),
+		RefreshTokens:  make(map[string]fosite.Requester),
+	}
+	s := &ReuseDetectingStore{FositeStorer: inner}
+
+	ctx := context.Background()
+	request := &fosite.Request{
+		RequestedAt: time.Now(),
+		Client:      &fosite.DefaultClient{ID: "app-client"},
+		Session:     &Session{Subject: "peter"},
+	}
+
+	require.Nil(t, s.CreateAuthorizeCodeSession(ctx, "code", request))
+	require.Nil(t, s.PersistAuthorizeCodeGrantSession(ctx, "code", "access-1", "refresh-1", request))
+
+	_, err := s.GetRefreshTokenSession(ctx, "refresh-1", nil)
+	require.Nil(t, err)
+	require.Nil(t, s.PersistRefreshTokenGrantSession(ctx, "refresh-1", "access-2", "refresh-2", request))
+
+	// The legitimate client keeps using the rotated token.
+	_, err = s.GetRefreshTokenSession(ctx, "refresh-2", nil)
+	require.Nil(t, err)
+
+	// The old token is presented again, which revokes the whole grant.
+	_, err = s.GetRefreshTokenSession(ctx, "refresh-1", nil)
+	assert.Equal(t, fosite.ErrNotFound, err)
+
+	_, err = s.GetRefreshTokenSession(ctx, "refresh-2", nil)
+	assert.NotNil(t, err)
+	for _, signature := range []string{"access-1", "access-2"} {
+		_, err = s.GetAccessTokenSession(ctx, signature, nil)
+		assert.NotNil(t, err, "%s", signature)
+	}
+
+	// Unknown tokens are not found and do not revoke anything.
+	_, err = s.GetRefreshTokenSession(ctx, "unknown", nil)
+	assert.Equal(t, fosite.ErrNotFound, err)
+}
@@ -0,0 +1,135 @@
+package oauth2
+
+import (
+	"net/http"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/tracing"
+	"golang.org/x/net/context"
+)
+
+const RevocationHandlerPath = "/oauth2/revoke"
+
+// ClientAuthenticator authenticates OAuth2 clients by their credentials.
+type ClientAuthenticator interface {
+	Authenticate(id string, secret []byte) (*fosite.DefaultClient, error)
+}
+
+// RevocationStorage is the storage used to revoke access and refresh tokens.
+type RevocationStorage interface {
+	core.AccessTokenStorage
+	pkg.TokenRevocationStorage
+
+	GetRefreshTokenSession(ctx context.Context, signature string, session interface{}) (fosite.Requester, error)
+}
+
+// RevocationHandler implements the token revocation endpoint of RFC 7009. Clients authenticate with their client
+// credentials and may only revoke tokens which were issued to them. Revoking a refresh token revokes all access tokens
+// of the same authorization grant.
+type RevocationHandler struct {
+	Clients ClientAuthenticator
+
+	AccessTokenStrategy  core.AccessTokenStrategy
+	RefreshTokenStrategy core.RefreshTokenStrategy
+	Storage              RevocationStorage
+
+	H herodot.Herodot
+}
+
+func (h *RevocationHandler) SetRoutes(r *httprouter.Router) {
+	r.POST(RevocationHandlerPath, h.Revoke)
+}
+
+func (h *RevocationHandler) Revoke(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.Context(tracing.Context(r))
+
+	if err := r.ParseForm(); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	c, err := authenticateClient(h.Clients, r)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		w.Header().Set("WWW-Authenticate", `Basic realm="hydra"`)
+		h.H.WriteErrorCode(ctx, w, r, http.StatusUnauthorized, errors.New("Client authentication failed"))
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Parameter token is missing"))
+		return
+	}
+
+	// The hint only decides which kind of token is looked up first.
+	revocations := []func(context.Context, string, string) error{h.revokeAccessToken, h.revokeRefreshToken}
+	if r.PostForm.Get("token_type_hint") == "refresh_token" {
+		revocations[0], revocations[1] = revocations[1], revocations[0]
+	}
+
+	for _, revoke := range revocations {
+		if err := revoke(ctx, c.GetID(), token); errors.Is(err, pkg.ErrNotFound) {
+			continue
+		} else if errors.Is(err, pkg.ErrForbidden) {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusForbidden, errors.New("The token was not issued to this client"))
+			return
+		} else if err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		break
+	}
+
+	// Unknown and invalid tokens are answered like revoked ones, see RFC 7009 section 2.2.
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *RevocationHandler) revokeAccessToken(ctx context.Context, clientID, token string) error {
+	signature, err := h.AccessTokenStrategy.ValidateAccessToken(ctx, fosite.NewAccessRequest(new(Session)), token)
+	if err != nil {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	request, err := h.Storage.GetAccessTokenSession(ctx, signature, new(Session))
+	if err != nil {
+		return errors.New(pkg.ErrNotFound)
+	} else if request.GetClient().GetID() != clientID {
+		return errors.New(pkg.ErrForbidden)
+	}
+
+	return h.Storage.DeleteAccessTokenSession(ctx, signature)
+}
+
+func (h *RevocationHandler) revokeRefreshToken(ctx context.Context, clientID, token string) error {
+	signature, err := h.RefreshTokenStrategy.ValidateRefreshToken(ctx, fosite.NewAccessRequest(new(Session)), token)
+	if err != nil {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	request, err := h.Storage.GetRefreshTokenSession(ctx, signature, new(Session))
+	if err != nil {
+		return errors.New(pkg.ErrNotFound)
+	} else if request.GetClient().GetID() != clientID {
+		return errors.New(pkg.ErrForbidden)
+	}
+
+	_, err = h.Storage.RevokeRefreshToken(ctx, signature)
+	return err
+}
+
+// authenticateClient authenticates the client of a form request by HTTP basic auth or, if absent, by the client_id and
+// client_secret form parameters.
+func authenticateClient(clients ClientAuthenticator, r *http.Request) (*fosite.DefaultClient, error) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	return clients.Authenticate(id, []byte(secret))
+}
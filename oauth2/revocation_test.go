@@ -0,0 +1,94 @@
+package oauth2_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	hc "github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/internal"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestRevocation(t *testing.T) {
+	clients := &hc.MemoryManager{Clients: map[string]*fosite.DefaultClient{}, Hasher: hasher}
+	require.Nil(t, clients.CreateClient(&fosite.DefaultClient{ID: "revoke-client", Secret: []byte("secret")}))
+	require.Nil(t, clients.CreateClient(&fosite.DefaultClient{ID: "other-client", Secret: []byte("secret")}))
+
+	revocationStore := &internal.FositeMemoryStore{
+		Manager:        clients,
+		AuthorizeCodes: make(map[string]fosite.Requester),
+		IDSessions:     make(map[string]fosite.Requester),
+		AccessTokens:   make(map[string]fosite.Requester),
+		Implicit:       make(map[string]fosite.Requester),
+		RefreshTokens:  make(map[string]fosite.Requester),
+	}
+
+	h := &RevocationHandler{
+		Clients:              clients,
+		AccessTokenStrategy:  hmacStrategy,
+		RefreshTokenStrategy: hmacStrategy,
+		Storage:              revocationStore,
+		H:                    &herodot.JSON{},
+	}
+	r := httprouter.New()
+	h.SetRoutes(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx := context.Background()
+	request := &fosite.Request{
+		RequestedAt: time.Now(),
+		Client:      &fosite.DefaultClient{ID: "revoke-client"},
+		Session:     &Session{Subject: "peter"},
+	}
+
+	tokens := pkg.Tokens(3)
+	code, access, refresh := tokens[0], tokens[1], tokens[2]
+	require.Nil(t, revocationStore.CreateAuthorizeCodeSession(ctx, code[0], request))
+	require.Nil(t, revocationStore.PersistAuthorizeCodeGrantSession(ctx, code[0], access[0], refresh[0], request))
+
+	revoke := func(id, token, hint string) int {
+		form := url.Values{"token": {token}}
+		if hint != "" {
+			form.Set("token_type_hint", hint)
+		}
+
+		req, err := http.NewRequest("POST", server.URL+RevocationHandlerPath, strings.NewReader(form.Encode()))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(id, "secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, revoke("unknown-client", access[1], ""))
+	assert.Equal(t, http.StatusBadRequest, revoke("revoke-client", "", ""))
+	assert.Equal(t, http.StatusOK, revoke("revoke-client", "invalid-token", ""))
+
+	assert.Equal(t, http.StatusForbidden, revoke("other-client", refresh[1], "refresh_token"))
+	_, err := revocationStore.GetRefreshTokenSession(ctx, refresh[0], nil)
+	require.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, revoke("revoke-client", refresh[1], "refresh_token"))
+	_, err = revocationStore.GetRefreshTokenSession(ctx, refresh[0], nil)
+	assert.NotNil(t, err)
+	_, err = revocationStore.GetAccessTokenSession(ctx, access[0], nil)
+	assert.NotNil(t, err)
+
+	// Revoking a token twice succeeds.
+	assert.Equal(t, http.StatusOK, revoke("revoke-client", refresh[1], ""))
+}
@@ -0,0 +1,55 @@
+package oauth2
+
+import (
+	"net/http"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/scope"
+	"golang.org/x/net/context"
+)
+
+// checkClientScopes refuses requests for scopes which the scopes granted to the client do not cover according to
+// the strategy.
+func checkClientScopes(strategy scope.Strategy, c fosite.Client, requested []string) error {
+	dc, ok := c.(*fosite.DefaultClient)
+	if !ok {
+		return nil
+	}
+
+	if !scope.Covers(strategy, dc.GrantedScopes, requested) {
+		return errors.New(fosite.ErrInvalidScope)
+	}
+	return nil
+}
+
+// ClientCredentialsGrantHandler wraps fosite's client credentials handler, which matches the requested scopes
+// against the scopes granted to the client hierarchically. It checks the scopes with ScopeStrategy instead and
+// hands fosite a client which is granted exactly the requested scopes.
+type ClientCredentialsGrantHandler struct {
+	fosite.TokenEndpointHandler
+
+	// ScopeStrategy must be the strategy of the Handler, nil is scope.Hierarchic.
+	ScopeStrategy scope.Strategy
+}
+
+func (h *ClientCredentialsGrantHandler) HandleTokenEndpointRequest(ctx context.Context, r *http.Request, request fosite.AccessRequester) error {
+	ar, ok := request.(*fosite.AccessRequest)
+	if !ok || !request.GetGrantTypes().Exact("client_credentials") {
+		return h.TokenEndpointHandler.HandleTokenEndpointRequest(ctx, r, request)
+	}
+
+	dc, ok := ar.Client.(*fosite.DefaultClient)
+	if !ok {
+		return h.TokenEndpointHandler.HandleTokenEndpointRequest(ctx, r, request)
+	} else if !scope.Covers(h.ScopeStrategy, dc.GrantedScopes, request.GetScopes()) {
+		return errors.New(fosite.ErrInvalidScope)
+	}
+
+	scoped := *dc
+	scoped.GrantedScopes = append([]string{}, request.GetScopes()...)
+	ar.Client = &scoped
+	defer func() { ar.Client = dc }()
+
+	return h.TokenEndpointHandler.HandleTokenEndpointRequest(ctx, r, request)
+}
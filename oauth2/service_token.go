@@ -0,0 +1,132 @@
+package oauth2
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+	xoauth2 "golang.org/x/oauth2"
+)
+
+// ServiceSubjectPrefix prefixes the subject of tokens minted for hydra's own components. The subject shows up in
+// warden contexts and thus in audit logs.
+const ServiceSubjectPrefix = "hydra.service:"
+
+// ServiceSubject returns the subject of a component's tokens, for example hydra.service:janitor.
+func ServiceSubject(component string) string {
+	return ServiceSubjectPrefix + component
+}
+
+// ServiceAccount is the identity of a background component and everything it may do.
+type ServiceAccount struct {
+	Name string
+
+	// Scopes are granted to the component's tokens.
+	Scopes []string
+
+	// Resources and Actions make up the policy which is created for the component.
+	Resources []string
+	Actions   []string
+}
+
+func (a *ServiceAccount) policyID() string {
+	return "hydra.service." + a.Name
+}
+
+// ServiceTokenMinter mints access tokens for hydra's background components so that they do not have to share the
+// root client's credentials. The tokens never pass the token endpoint, they are written to the token store directly
+// and are only valid for the scopes and the policy of their account.
+//
+// Tokens are rotated at half their lifespan, so a token which is in use when a new one is minted stays valid for
+// long enough to finish the request.
+type ServiceTokenMinter struct {
+	Strategy core.AccessTokenStrategy
+	Store    core.AccessTokenStorage
+	Policies ladon.Manager
+
+	// Lifespan must equal the lifespan the token validator enforces.
+	Lifespan time.Duration
+
+	accounts map[string]*ServiceAccount
+	sync.RWMutex
+}
+
+// Register adds an account and replaces its policy, so that changed permissions take effect on the next start.
+func (m *ServiceTokenMinter) Register(a *ServiceAccount) error {
+	if err := m.Policies.Delete(a.policyID()); err != nil {
+		return errors.New(err)
+	}
+
+	if err := m.Policies.Create(&ladon.DefaultPolicy{
+		ID:          a.policyID(),
+		Description: "This policy is managed by hydra and grants the " + a.Name + " component what it needs.",
+		Subjects:    []string{ServiceSubject(a.Name)},
+		Effect:      ladon.AllowAccess,
+		Resources:   a.Resources,
+		Actions:     a.Actions,
+	}); err != nil {
+		return errors.New(err)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if m.accounts == nil {
+		m.accounts = map[string]*ServiceAccount{}
+	}
+	m.accounts[a.Name] = a
+	return nil
+}
+
+// Mint issues a new token for a registered account.
+func (m *ServiceTokenMinter) Mint(ctx context.Context, name string) (*xoauth2.Token, error) {
+	m.RLock()
+	a, ok := m.accounts[name]
+	m.RUnlock()
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+
+	request := fosite.NewAccessRequest(&Session{Subject: ServiceSubject(a.Name)})
+	request.Client = &fosite.DefaultClient{ID: ServiceSubject(a.Name), GrantedScopes: a.Scopes}
+	for _, scope := range a.Scopes {
+		request.GrantScope(scope)
+	}
+
+	token, signature, err := m.Strategy.GenerateAccessToken(ctx, request)
+	if err != nil {
+		return nil, err
+	} else if err := m.Store.CreateAccessTokenSession(ctx, signature, request); err != nil {
+		return nil, err
+	}
+
+	return &xoauth2.Token{
+		AccessToken: token,
+		TokenType:   "bearer",
+		Expiry:      request.GetRequestedAt().Add(m.Lifespan / 2),
+	}, nil
+}
+
+// TokenSource returns a source of tokens for a registered account which rotates them automatically.
+func (m *ServiceTokenMinter) TokenSource(name string) xoauth2.TokenSource {
+	return xoauth2.ReuseTokenSource(nil, &serviceTokenSource{minter: m, name: name})
+}
+
+// Client returns a HTTP client which authorizes its requests as a registered account.
+func (m *ServiceTokenMinter) Client(name string) *http.Client {
+	return xoauth2.NewClient(xoauth2.NoContext, m.TokenSource(name))
+}
+
+type serviceTokenSource struct {
+	minter *ServiceTokenMinter
+	name   string
+}
+
+func (s *serviceTokenSource) Token() (*xoauth2.Token, error) {
+	return s.minter.Mint(context.Background(), s.name)
+}
@@ -0,0 +1,57 @@
+package oauth2_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestServiceTokenMinter(t *testing.T) {
+	policies := ladon.NewMemoryManager()
+	m := &ServiceTokenMinter{
+		Strategy: hmacStrategy,
+		Store:    store,
+		Policies: policies,
+		Lifespan: time.Hour,
+	}
+
+	account := &ServiceAccount{
+		Name:      "janitor",
+		Scopes:    []string{"hydra.keys.delete"},
+		Resources: []string{"rn:hydra:keys:<.*>"},
+		Actions:   []string{"delete"},
+	}
+	require.Nil(t, m.Register(account))
+
+	account.Actions = []string{"get", "delete"}
+	require.Nil(t, m.Register(account))
+
+	policy, err := policies.Get("hydra.service.janitor")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"hydra.service:janitor"}, policy.GetSubjects())
+	assert.Equal(t, []string{"get", "delete"}, policy.GetActions())
+
+	_, err = m.Mint(context.Background(), "exporter")
+	assert.NotNil(t, err)
+
+	token, err := m.Mint(context.Background(), "janitor")
+	require.Nil(t, err)
+	assert.True(t, token.Expiry.Before(time.Now().Add(time.Hour/2+time.Second)))
+
+	validator := &core.CoreValidator{AccessTokenStrategy: hmacStrategy, AccessTokenStorage: store}
+	request := fosite.NewAccessRequest(new(Session))
+	require.Nil(t, validator.ValidateToken(context.Background(), request, token.AccessToken))
+	assert.Equal(t, "hydra.service:janitor", request.GetSession().(*Session).Subject)
+	assert.Equal(t, fosite.Arguments{"hydra.keys.delete"}, request.GetGrantedScopes())
+
+	cached, err := m.TokenSource("janitor").Token()
+	require.Nil(t, err)
+	assert.NotEqual(t, token.AccessToken, cached.AccessToken)
+}
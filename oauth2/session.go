@@ -1,8 +1,68 @@
 package oauth2
 
-import "github.com/ory-am/fosite/handler/oidc/strategy"
+import (
+	"time"
+
+	"github.com/ory-am/fosite/handler/oidc/strategy"
+)
 
 type Session struct {
 	Subject                  string `json:"sub"`
 	*strategy.DefaultSession `json:"idToken"`
+
+	// AuthenticationMethods and AuthenticationContext describe how the subject logged in.
+	AuthenticationMethods []string `json:"amr,omitempty"`
+	AuthenticationContext string   `json:"acr,omitempty"`
+
+	// WebAuthn is set if the login app authenticated the subject with a WebAuthn credential.
+	WebAuthn *WebAuthnAssertion `json:"webauthn,omitempty"`
+
+	// Audience is the service an exchanged token is meant for. Tokens of the other grants are meant for their client.
+	Audience string `json:"aud,omitempty"`
+
+	// Actor is the party acting on behalf of the subject of a delegated token, see RFC 8693 section 4.1.
+	Actor *Actor `json:"act,omitempty"`
+
+	// TokenExpiresAt are the expiry times of the session's tokens by token kind, see pkg.TokenKindAccessToken. It is
+	// only set for clients which override the server's lifespans, tokens of kinds without an entry expire after the
+	// server's lifespan.
+	TokenExpiresAt map[string]time.Time `json:"token_exp,omitempty"`
+
+	// RememberFor is set if the consent app asked to remember the decision. It is not stored with the tokens.
+	RememberFor time.Duration `json:"-"`
+}
+
+// Actor identifies the party a subject delegated to.
+type Actor struct {
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id,omitempty"`
+
+	// Actor is the party which acted before, if a delegated token was exchanged again.
+	Actor *Actor `json:"act,omitempty"`
+}
+
+// SetTokenLifespan lets the token of a kind expire lifespan after from. A zero lifespan falls back to the server's
+// lifespan.
+func (s *Session) SetTokenLifespan(kind string, from time.Time, lifespan time.Duration) {
+	if lifespan == 0 {
+		delete(s.TokenExpiresAt, kind)
+		return
+	}
+
+	if s.TokenExpiresAt == nil {
+		s.TokenExpiresAt = map[string]time.Time{}
+	}
+	s.TokenExpiresAt[kind] = from.Add(lifespan)
+}
+
+// GetTokenExpiresAt returns when the token of a kind expires, or false if it expires after the server's lifespan.
+func (s *Session) GetTokenExpiresAt(kind string) (time.Time, bool) {
+	expiresAt, ok := s.TokenExpiresAt[kind]
+	return expiresAt, ok
+}
+
+// IsTokenExpired returns true if the token of a kind expired before the server's lifespan did.
+func (s *Session) IsTokenExpired(kind string) bool {
+	expiresAt, ok := s.GetTokenExpiresAt(kind)
+	return ok && time.Now().After(expiresAt)
 }
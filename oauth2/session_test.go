@@ -0,0 +1,30 @@
+package oauth2_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionTokenLifespans(t *testing.T) {
+	now := time.Now()
+	session := &Session{}
+	_, ok := session.GetTokenExpiresAt(pkg.TokenKindAccessToken)
+	assert.False(t, ok)
+	assert.False(t, session.IsTokenExpired(pkg.TokenKindAccessToken), "Tokens without a lifespan of their own expire after the server's lifespan")
+
+	session.SetTokenLifespan(pkg.TokenKindAccessToken, now, 5*time.Minute)
+	expiresAt, ok := session.GetTokenExpiresAt(pkg.TokenKindAccessToken)
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(5*time.Minute), expiresAt)
+	assert.False(t, session.IsTokenExpired(pkg.TokenKindAccessToken))
+
+	session.SetTokenLifespan(pkg.TokenKindRefreshToken, now.Add(-2*time.Hour), time.Hour)
+	assert.True(t, session.IsTokenExpired(pkg.TokenKindRefreshToken))
+
+	session.SetTokenLifespan(pkg.TokenKindRefreshToken, now, 0)
+	assert.False(t, session.IsTokenExpired(pkg.TokenKindRefreshToken))
+}
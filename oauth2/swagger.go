@@ -0,0 +1,164 @@
+package oauth2
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/job"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/swagger"
+	"github.com/square/go-jose"
+)
+
+// tokenResponse is the body of successful token responses (RFC 6749).
+type tokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	IDToken         string `json:"id_token,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+}
+
+// Operations describes the endpoints of the handlers of this package.
+var Operations = []*swagger.Operation{
+	{
+		Method: "POST", Path: "/oauth2/token", ID: "token", Tag: "oauth2", ClientAuth: true,
+		Summary: "Exchange a grant for tokens",
+		Form: []string{
+			"grant_type", "code", "redirect_uri", "refresh_token", "scope", "device_code",
+			"subject_token", "subject_token_type", "actor_token", "actor_token_type", "requested_token_type",
+		},
+		Responses: map[int]interface{}{http.StatusOK: &tokenResponse{}},
+	},
+	{
+		Method: "GET", Path: "/oauth2/auth", ID: "authorize", Tag: "oauth2",
+		Summary:   "Start an authorization code, implicit or hybrid flow",
+		Query:     []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "consent"},
+		Responses: map[int]interface{}{http.StatusFound: nil},
+	},
+	{
+		Method: "POST", Path: "/oauth2/auth", ID: "authorizeWithForm", Tag: "oauth2",
+		Summary:   "Start an authorization code, implicit or hybrid flow with a form encoded request",
+		Form:      []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "consent"},
+		Responses: map[int]interface{}{http.StatusFound: nil},
+	},
+	{
+		Method: "POST", Path: IntrospectionHandlerPath, ID: "introspectToken", Tag: "oauth2", Scope: introspectionScope,
+		Summary:   "Introspect an access or refresh token (RFC 7662)",
+		Form:      []string{"token", "token_type_hint"},
+		Responses: map[int]interface{}{http.StatusOK: &Introspection{}},
+	},
+	{
+		Method: "POST", Path: RevocationHandlerPath, ID: "revokeToken", Tag: "oauth2", ClientAuth: true,
+		Summary:   "Revoke an access or refresh token (RFC 7009)",
+		Form:      []string{"token", "token_type_hint"},
+		Responses: map[int]interface{}{http.StatusOK: nil},
+	},
+	{
+		Method: "DELETE", Path: TokensHandlerPath, ID: "revokeClientTokens", Tag: "oauth2", Scope: "hydra.tokens",
+		Summary: "Revoke all tokens of a client, in the background if async is true",
+		Query:   []string{"client_id", "async"},
+		Responses: map[int]interface{}{
+			http.StatusOK:       &pkg.BulkDeletion{},
+			http.StatusAccepted: &job.Job{},
+		},
+	},
+	{
+		Method: "POST", Path: DeviceAuthorizationHandlerPath, ID: "authorizeDevice", Tag: "oauth2", ClientAuth: true,
+		Summary:   "Start a device authorization grant (RFC 8628)",
+		Form:      []string{"scope"},
+		Responses: map[int]interface{}{http.StatusOK: &deviceAuthorizationResponse{}},
+	},
+	{
+		Method: "GET", Path: DeviceVerificationHandlerPath, ID: "verifyDevice", Tag: "oauth2",
+		Summary: "Verify the user code of a device grant, the user is redirected to the consent app first",
+		Query:   []string{"user_code", "consent", "error", "challenge"},
+		Responses: map[int]interface{}{
+			http.StatusOK:    &DeviceGrant{},
+			http.StatusFound: nil,
+		},
+	},
+	{
+		Method: "GET", Path: LogoutHandlerPath, ID: "logout", Tag: "oauth2",
+		Summary:   "End the login session of a user (OpenID Connect RP-Initiated Logout)",
+		Query:     []string{"id_token_hint", "post_logout_redirect_uri", "state"},
+		Responses: map[int]interface{}{http.StatusFound: nil},
+	},
+	{
+		Method: "POST", Path: LogoutHandlerPath, ID: "logoutWithForm", Tag: "oauth2",
+		Summary:   "End the login session of a user with a form encoded request",
+		Form:      []string{"id_token_hint", "post_logout_redirect_uri", "state"},
+		Responses: map[int]interface{}{http.StatusFound: nil},
+	},
+	{
+		Method: "POST", Path: PendingConsentHandlerPath, ID: "createPendingConsent", Tag: "consent", Scope: "hydra.consent",
+		Summary:   "Create a consent request which is resolved later",
+		Body:      &pendingConsentRequest{},
+		Responses: map[int]interface{}{http.StatusCreated: &PendingConsent{}},
+	},
+	{
+		Method: "GET", Path: PendingConsentHandlerPath, ID: "findPendingConsent", Tag: "consent", Scope: "hydra.consent",
+		Summary:   "Find a consent request by its user code",
+		Query:     []string{"user_code"},
+		Responses: map[int]interface{}{http.StatusOK: &PendingConsent{}},
+	},
+	{
+		Method: "GET", Path: PendingConsentHandlerPath + "/:id", ID: "getPendingConsent", Tag: "consent", Scope: "hydra.consent",
+		Summary:   "Get a consent request",
+		Responses: map[int]interface{}{http.StatusOK: &PendingConsent{}},
+	},
+	{
+		Method: "PUT", Path: PendingConsentHandlerPath + "/:id", ID: "resolvePendingConsent", Tag: "consent", Scope: "hydra.consent",
+		Summary:   "Grant or deny a consent request",
+		Body:      &resolveConsentRequest{},
+		Responses: map[int]interface{}{http.StatusOK: &PendingConsent{}},
+	},
+	{
+		Method: "GET", Path: RememberedConsentHandlerPath, ID: "listRememberedConsents", Tag: "consent", Scope: "hydra.consent",
+		Summary:    "List the consents a subject gave",
+		Query:      []string{"subject"},
+		Pagination: []string{"created_at", "id", "client_id", "expires_at"},
+		Responses:  map[int]interface{}{http.StatusOK: []*RememberedConsent{}},
+	},
+	{
+		Method: "DELETE", Path: RememberedConsentHandlerPath + "/:id", ID: "deleteRememberedConsent", Tag: "consent", Scope: "hydra.consent",
+		Summary:   "Forget a consent",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "GET", Path: AccountHandlerPath, ID: "getAccount", Tag: "account", Scope: "hydra.account",
+		Summary:   "Get the sessions and applications of the user the access token was issued to",
+		Responses: map[int]interface{}{http.StatusOK: &Account{}},
+	},
+	{
+		Method: "DELETE", Path: AccountHandlerPath + "/sessions/:id", ID: "deleteAccountSession", Tag: "account", Scope: "hydra.account",
+		Summary:   "End a login session of the user",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "DELETE", Path: AccountHandlerPath + "/applications/:id", ID: "deleteAccountApplication", Tag: "account", Scope: "hydra.account",
+		Summary:   "Revoke the access of an application to the account of the user",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "GET", Path: IssuerMigrationHandlerPath, ID: "getIssuerMigrationReport", Tag: "oauth2", Scope: "hydra.issuer",
+		Summary:   "List the clients which still reference a previous issuer",
+		Responses: map[int]interface{}{http.StatusOK: &IssuerMigrationReport{}},
+	},
+	{
+		Method: "GET", Path: WellKnownHandlerPath, ID: "discoverOpenIDConfiguration", Tag: "discovery",
+		Summary:   "Get the OpenID Connect discovery document",
+		Responses: map[int]interface{}{http.StatusOK: &DiscoveryDocument{}},
+	},
+	{
+		Method: "GET", Path: AuthorizationServerMetadataHandlerPath, ID: "discoverAuthorizationServer", Tag: "discovery",
+		Summary:   "Get the authorization server metadata (RFC 8414)",
+		Responses: map[int]interface{}{http.StatusOK: &AuthorizationServerMetadata{}},
+	},
+	{
+		Method: "GET", Path: JWKsHandlerPath, ID: "getJSONWebKeys", Tag: "discovery",
+		Summary:   "Get the public keys tokens are signed with",
+		Responses: map[int]interface{}{http.StatusOK: &jose.JsonWebKeySet{}},
+	},
+}
@@ -0,0 +1,250 @@
+package oauth2
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/logger"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/tracing"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const (
+	// TokenExchangeGrantType is the grant type of RFC 8693 token exchange requests.
+	TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// AccessTokenType identifies access tokens in token exchange requests and responses.
+	AccessTokenType = "urn:ietf:params:oauth:token-type:access_token"
+
+	tokenExchangeResource = "rn:hydra:oauth2:token-exchange:%s"
+)
+
+// TokenExchangeRequest is a validated token exchange request which is passed to the TokenExchangePolicy.
+type TokenExchangeRequest struct {
+	// ClientID is the client asking for the exchange.
+	ClientID string
+
+	// Subject is the subject of the subject token, Actor the subject of the actor token. Actor is empty for
+	// impersonation.
+	Subject string
+	Actor   string
+
+	Audience string
+	Scopes   []string
+}
+
+// IsDelegation returns true if the issued token acts on behalf of the subject instead of impersonating it.
+func (r *TokenExchangeRequest) IsDelegation() bool {
+	return r.Actor != ""
+}
+
+// TokenExchangePolicy decides which clients may exchange tokens for which audiences.
+type TokenExchangePolicy interface {
+	// AllowExchange returns an error if the exchange is not allowed.
+	AllowExchange(r *TokenExchangeRequest) error
+}
+
+// LadonTokenExchangePolicy allows exchanges which are allowed by a policy. The policy's subject is the client, the
+// resource is rn:hydra:oauth2:token-exchange:<audience> and the action is either impersonate or delegate. The
+// subject and actor of the tokens are available to conditions.
+type LadonTokenExchangePolicy struct {
+	Warden ladon.Warden
+}
+
+func (p *LadonTokenExchangePolicy) AllowExchange(r *TokenExchangeRequest) error {
+	action := "impersonate"
+	if r.IsDelegation() {
+		action = "delegate"
+	}
+
+	return p.Warden.IsAllowed(&ladon.Request{
+		Subject:  r.ClientID,
+		Resource: fmt.Sprintf(tokenExchangeResource, r.Audience),
+		Action:   action,
+		Context: ladon.Context{
+			"subject": r.Subject,
+			"actor":   r.Actor,
+			"scopes":  r.Scopes,
+		},
+	})
+}
+
+// TokenExchangeHandler implements the token exchange grant of RFC 8693. A service exchanges an access token it
+// received for an access token which is meant for another audience and carries at most the scopes of the original
+// token. If an actor token is given, the issued token is delegated to the actor's subject, otherwise the service
+// impersonates the subject.
+type TokenExchangeHandler struct {
+	Clients ClientAuthenticator
+
+	AccessTokens        *core.CoreValidator
+	AccessTokenStrategy core.AccessTokenStrategy
+	AccessTokenStorage  core.AccessTokenStorage
+	AccessTokenLifespan time.Duration
+
+	Policy TokenExchangePolicy
+
+	// Settings holds the access token lifespans clients override.
+	Settings client.SettingsManager
+}
+
+// TokenHandler answers token exchange requests at the token endpoint.
+func (h *TokenExchangeHandler) TokenHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := tracing.Context(r)
+
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	c, err := authenticateClient(h.Clients, r)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		w.Header().Set("WWW-Authenticate", `Basic realm="hydra"`)
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	subjectRequest, subject, err := h.validateToken(ctx, r.PostForm.Get("subject_token"), r.PostForm.Get("subject_token_type"))
+	if err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "The subject token is invalid: "+err.Error())
+		return
+	}
+
+	er := &TokenExchangeRequest{
+		ClientID: c.GetID(),
+		Subject:  subject.Subject,
+		Audience: c.GetID(),
+		Scopes:   subjectRequest.GetGrantedScopes(),
+	}
+
+	var actor *Actor
+	if token := r.PostForm.Get("actor_token"); token != "" {
+		actorRequest, actorSession, err := h.validateToken(ctx, token, r.PostForm.Get("actor_token_type"))
+		if err != nil {
+			logger.LogRequestError(r, err)
+			writeTokenError(w, http.StatusBadRequest, "invalid_request", "The actor token is invalid: "+err.Error())
+			return
+		}
+		// A delegated subject token keeps its actors, the new actor becomes the current one.
+		actor = &Actor{Subject: actorSession.Subject, ClientID: actorRequest.GetClient().GetID(), Actor: subject.Actor}
+		er.Actor = actor.Subject
+	} else {
+		actor = subject.Actor
+	}
+
+	if audience, err := requestedAudience(r.PostForm); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_target", err.Error())
+		return
+	} else if audience != "" {
+		er.Audience = audience
+	}
+
+	// The issued token can only be downscoped.
+	if scope := r.PostForm.Get("scope"); scope != "" {
+		er.Scopes = strings.Fields(scope)
+		for _, s := range er.Scopes {
+			if !subjectRequest.GetGrantedScopes().Has(s) {
+				writeTokenError(w, http.StatusBadRequest, "invalid_scope", "The subject token was not granted scope "+s)
+				return
+			}
+		}
+	}
+
+	if requested := r.PostForm.Get("requested_token_type"); requested != "" && requested != AccessTokenType {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request", "Only access tokens can be requested")
+		return
+	}
+
+	if err := h.Policy.AllowExchange(er); err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusBadRequest, "unauthorized_client", "The client is not allowed to exchange this token for audience "+er.Audience)
+		return
+	}
+
+	session := &Session{
+		Subject:               subject.Subject,
+		AuthenticationMethods: subject.AuthenticationMethods,
+		AuthenticationContext: subject.AuthenticationContext,
+		Audience:              er.Audience,
+		Actor:                 actor,
+	}
+
+	lifespans, err := clientLifespans(h.Settings, c.GetID())
+	if err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	now := time.Now()
+	session.SetTokenLifespan(pkg.TokenKindAccessToken, now, lifespans.AccessToken)
+	expires := expiresIn(lifespans, h.AccessTokenLifespan)
+
+	// The issued token does not outlive the subject token.
+	lifespan := time.Duration(expires) * time.Second
+	if subjectExpiresAt := accessTokenExpiresAt(subjectRequest, h.AccessTokenLifespan); !subjectExpiresAt.IsZero() && (lifespan == 0 || subjectExpiresAt.Before(now.Add(lifespan))) {
+		lifespan = subjectExpiresAt.Sub(now)
+		session.SetTokenLifespan(pkg.TokenKindAccessToken, now, lifespan)
+		expires = int64(lifespan / time.Second)
+	}
+
+	accessRequest := fosite.NewAccessRequest(session)
+	accessRequest.GrantTypes = fosite.Arguments{TokenExchangeGrantType}
+	accessRequest.Client = c
+	accessRequest.Scopes = er.Scopes
+	accessRequest.Form = r.PostForm
+	for _, scope := range er.Scopes {
+		accessRequest.GrantScope(scope)
+	}
+
+	token, signature, err := h.AccessTokenStrategy.GenerateAccessToken(ctx, accessRequest)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	} else if err := h.AccessTokenStorage.CreateAccessTokenSession(ctx, signature, accessRequest); err != nil {
+		logger.LogRequestError(r, err)
+		writeTokenError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	writeTokenResponse(w, http.StatusOK, map[string]interface{}{
+		"access_token":      token,
+		"issued_token_type": AccessTokenType,
+		"token_type":        "bearer",
+		"expires_in":        expires,
+		"scope":             strings.Join(accessRequest.GetGrantedScopes(), " "),
+	})
+}
+
+// validateToken returns the request and the session of an active access token.
+func (h *TokenExchangeHandler) validateToken(ctx context.Context, token, tokenType string) (fosite.Requester, *Session, error) {
+	if token == "" {
+		return nil, nil, errors.New("Token is missing")
+	} else if tokenType != AccessTokenType {
+		return nil, nil, errors.Errorf("Token type must be %s", AccessTokenType)
+	}
+
+	var session = new(Session)
+	var request = fosite.NewAccessRequest(session)
+	if err := h.AccessTokens.ValidateToken(ctx, request, token); err != nil {
+		return nil, nil, err
+	} else if expiresAt := accessTokenExpiresAt(request, h.AccessTokenLifespan); !expiresAt.IsZero() && time.Now().After(expiresAt) {
+		return nil, nil, errors.New("Token expired")
+	}
+
+	// Stores which keep requests in memory return the session the token was issued with.
+	if s, ok := request.GetSession().(*Session); ok {
+		session = s
+	}
+	return request, session, nil
+}
@@ -0,0 +1,182 @@
+package oauth2_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	hc "github.com/ory-am/hydra/client"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestTokenExchange(t *testing.T) {
+	clients := &hc.MemoryManager{Clients: map[string]*fosite.DefaultClient{}, Hasher: hasher}
+	require.Nil(t, clients.CreateClient(&fosite.DefaultClient{ID: "gateway", Secret: []byte("secret")}))
+	require.Nil(t, clients.CreateClient(&fosite.DefaultClient{ID: "other", Secret: []byte("secret")}))
+
+	validator := &core.CoreValidator{AccessTokenStrategy: hmacStrategy, AccessTokenStorage: store}
+	h := &TokenExchangeHandler{
+		Clients:             clients,
+		AccessTokens:        validator,
+		AccessTokenStrategy: hmacStrategy,
+		AccessTokenStorage:  store,
+		AccessTokenLifespan: time.Hour,
+		Policy: &LadonTokenExchangePolicy{Warden: &ladon.Ladon{
+			Manager: &ladon.MemoryManager{
+				Policies: map[string]ladon.Policy{
+					"1": &ladon.DefaultPolicy{
+						ID:        "1",
+						Subjects:  []string{"gateway"},
+						Resources: []string{"rn:hydra:oauth2:token-exchange:photos-api"},
+						Actions:   []string{"<impersonate|delegate>"},
+						Effect:    ladon.AllowAccess,
+					},
+				},
+			},
+		}},
+	}
+	r := httprouter.New()
+	r.POST("/oauth2/token", (&Handler{Exchange: h}).TokenHandler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx := context.Background()
+	issue := func(subject string, scopes ...string) string {
+		request := fosite.NewAccessRequest(&Session{Subject: subject})
+		request.Client = &fosite.DefaultClient{ID: "app"}
+		for _, scope := range scopes {
+			request.GrantScope(scope)
+		}
+
+		token, signature, err := hmacStrategy.GenerateAccessToken(ctx, request)
+		require.Nil(t, err)
+		require.Nil(t, store.CreateAccessTokenSession(ctx, signature, request))
+		return token
+	}
+	subjectToken := issue("peter", "core", "photos")
+	actorToken := issue("photo-service", "core")
+
+	exchange := func(id string, form url.Values) (int, map[string]interface{}) {
+		form.Set("grant_type", TokenExchangeGrantType)
+		req, err := http.NewRequest("POST", server.URL+"/oauth2/token", strings.NewReader(form.Encode()))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(id, "secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer res.Body.Close()
+
+		var result map[string]interface{}
+		require.Nil(t, json.NewDecoder(res.Body).Decode(&result))
+		return res.StatusCode, result
+	}
+
+	for k, c := range []struct {
+		id       string
+		form     url.Values
+		expected string
+	}{
+		{id: "gateway", form: url.Values{"subject_token": {"invalid"}, "subject_token_type": {AccessTokenType}}, expected: "invalid_request"},
+		{id: "gateway", form: url.Values{"subject_token": {subjectToken}}, expected: "invalid_request"},
+		{id: "gateway", form: url.Values{"subject_token": {subjectToken}, "subject_token_type": {AccessTokenType}, "audience": {"photos-api"}, "scope": {"admin"}}, expected: "invalid_scope"},
+		{id: "gateway", form: url.Values{"subject_token": {subjectToken}, "subject_token_type": {AccessTokenType}, "audience": {"billing-api"}}, expected: "unauthorized_client"},
+		{id: "other", form: url.Values{"subject_token": {subjectToken}, "subject_token_type": {AccessTokenType}, "audience": {"photos-api"}}, expected: "unauthorized_client"},
+		{id: "gateway", form: url.Values{"subject_token": {subjectToken}, "subject_token_type": {AccessTokenType}, "audience": {"photos-api", "billing-api"}}, expected: "invalid_target"},
+	} {
+		code, result := exchange(c.id, c.form)
+		assert.Equal(t, http.StatusBadRequest, code, "Case %d", k)
+		assert.Equal(t, c.expected, result["error"], "Case %d", k)
+	}
+
+	code, result := exchange("gateway", url.Values{
+		"subject_token":      {subjectToken},
+		"subject_token_type": {AccessTokenType},
+		"actor_token":        {actorToken},
+		"actor_token_type":   {AccessTokenType},
+		"audience":           {"photos-api"},
+		"scope":              {"photos"},
+	})
+	require.Equal(t, http.StatusOK, code, "%v", result)
+	assert.Equal(t, AccessTokenType, result["issued_token_type"])
+	assert.Equal(t, "photos", result["scope"])
+
+	session := new(Session)
+	request := fosite.NewAccessRequest(session)
+	require.Nil(t, validator.ValidateToken(ctx, request, result["access_token"].(string)))
+	if s, ok := request.GetSession().(*Session); ok {
+		session = s
+	}
+	assert.Equal(t, "peter", session.Subject)
+	assert.Equal(t, "photos-api", session.Audience)
+	require.NotNil(t, session.Actor)
+	assert.Equal(t, "photo-service", session.Actor.Subject)
+	assert.Equal(t, "gateway", request.GetClient().GetID())
+
+	// A delegated token which expires soon is exchanged again: the actors are kept and the expiry is capped.
+	expiresAt := time.Now().Add(time.Minute * 10).Round(time.Second)
+	delegated := fosite.NewAccessRequest(&Session{
+		Subject:        "peter",
+		Actor:          &Actor{Subject: "photo-service", ClientID: "app"},
+		TokenExpiresAt: map[string]time.Time{pkg.TokenKindAccessToken: expiresAt},
+	})
+	delegated.Client = &fosite.DefaultClient{ID: "app"}
+	delegated.GrantScope("photos")
+	delegatedToken, signature, err := hmacStrategy.GenerateAccessToken(ctx, delegated)
+	require.Nil(t, err)
+	require.Nil(t, store.CreateAccessTokenSession(ctx, signature, delegated))
+
+	code, result = exchange("gateway", url.Values{
+		"subject_token":      {delegatedToken},
+		"subject_token_type": {AccessTokenType},
+		"actor_token":        {actorToken},
+		"actor_token_type":   {AccessTokenType},
+		"audience":           {"photos-api"},
+	})
+	require.Equal(t, http.StatusOK, code, "%v", result)
+	assert.True(t, result["expires_in"].(float64) <= 600, "%v", result["expires_in"])
+
+	session = new(Session)
+	request = fosite.NewAccessRequest(session)
+	require.Nil(t, validator.ValidateToken(ctx, request, result["access_token"].(string)))
+	if s, ok := request.GetSession().(*Session); ok {
+		session = s
+	}
+	issuedExpiresAt, ok := session.GetTokenExpiresAt(pkg.TokenKindAccessToken)
+	require.True(t, ok)
+	assert.Equal(t, expiresAt, issuedExpiresAt.Round(time.Second))
+	require.NotNil(t, session.Actor)
+	assert.Equal(t, "photo-service", session.Actor.Subject)
+	require.NotNil(t, session.Actor.Actor)
+	assert.Equal(t, "photo-service", session.Actor.Actor.Subject)
+	assert.Equal(t, "app", session.Actor.Actor.ClientID)
+
+	// Impersonating the subject of a delegated token keeps its actors as well.
+	code, result = exchange("gateway", url.Values{
+		"subject_token":      {delegatedToken},
+		"subject_token_type": {AccessTokenType},
+		"audience":           {"photos-api"},
+	})
+	require.Equal(t, http.StatusOK, code, "%v", result)
+	session = new(Session)
+	request = fosite.NewAccessRequest(session)
+	require.Nil(t, validator.ValidateToken(ctx, request, result["access_token"].(string)))
+	if s, ok := request.GetSession().(*Session); ok {
+		session = s
+	}
+	require.NotNil(t, session.Actor)
+	assert.Equal(t, "app", session.Actor.ClientID)
+	assert.Nil(t, session.Actor.Actor)
+}
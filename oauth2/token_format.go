@@ -0,0 +1,100 @@
+package oauth2
+
+import (
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"golang.org/x/net/context"
+)
+
+const (
+	// TokenFormatOpaqueV1 are fosite's HMAC tokens, key.signature.
+	TokenFormatOpaqueV1 = "opaque-v1"
+
+	// TokenFormatOpaqueV2 are HMAC tokens behind OpaqueV2Prefix, which lets secret scanners and humans recognize
+	// them.
+	TokenFormatOpaqueV2 = "opaque-v2"
+
+	// TokenFormatJWT are the signed tokens of JWTAccessTokenStrategy.
+	TokenFormatJWT = "jwt"
+
+	OpaqueV2Prefix = "hydra_at2_"
+)
+
+// TokenFormat returns the format of an access token without validating it.
+func TokenFormat(token string) string {
+	switch {
+	case strings.HasPrefix(token, OpaqueV2Prefix):
+		return TokenFormatOpaqueV2
+	case strings.Count(token, ".") == 2:
+		return TokenFormatJWT
+	}
+	return TokenFormatOpaqueV1
+}
+
+// OpaqueV2Strategy prefixes the tokens of an HMAC strategy with OpaqueV2Prefix. The signature is not changed, so
+// the tokens are stored exactly like opaque v1 tokens.
+type OpaqueV2Strategy struct {
+	core.AccessTokenStrategy
+}
+
+func (s *OpaqueV2Strategy) GenerateAccessToken(ctx context.Context, requester fosite.Requester) (string, string, error) {
+	token, signature, err := s.AccessTokenStrategy.GenerateAccessToken(ctx, requester)
+	if err != nil {
+		return "", "", err
+	}
+	return OpaqueV2Prefix + token, signature, nil
+}
+
+func (s *OpaqueV2Strategy) ValidateAccessToken(ctx context.Context, requester fosite.Requester, token string) (string, error) {
+	if !strings.HasPrefix(token, OpaqueV2Prefix) {
+		return "", errors.New("Token is not an opaque v2 token")
+	}
+	return s.AccessTokenStrategy.ValidateAccessToken(ctx, requester, strings.TrimPrefix(token, OpaqueV2Prefix))
+}
+
+// VersionedAccessTokenStrategy issues access tokens in one format and accepts every format it has a strategy for.
+// This allows changing the format without a flag day: keep the old format in Formats until its last tokens have
+// expired. Refresh tokens and authorize codes are handled by the embedded strategy.
+type VersionedAccessTokenStrategy struct {
+	core.CoreStrategy
+
+	// Issue is the format of new tokens, it must be a key of Formats.
+	Issue string
+
+	// Formats are the strategies of the accepted formats.
+	Formats map[string]core.AccessTokenStrategy
+}
+
+func (s *VersionedAccessTokenStrategy) GenerateAccessToken(ctx context.Context, requester fosite.Requester) (string, string, error) {
+	strategy, ok := s.Formats[s.Issue]
+	if !ok {
+		return "", "", errors.Errorf("No strategy issues access tokens of format %s", s.Issue)
+	}
+
+	token, signature, err := strategy.GenerateAccessToken(ctx, requester)
+	if err != nil {
+		return "", "", err
+	}
+	observeAccessToken(s.Issue, "issued")
+	return token, signature, nil
+}
+
+func (s *VersionedAccessTokenStrategy) ValidateAccessToken(ctx context.Context, requester fosite.Requester, token string) (string, error) {
+	format := TokenFormat(token)
+	strategy, ok := s.Formats[format]
+	if !ok {
+		observeAccessToken(format, "rejected")
+		return "", errors.Errorf("Access tokens of format %s are no longer accepted", format)
+	}
+
+	signature, err := strategy.ValidateAccessToken(ctx, requester, token)
+	if err != nil {
+		observeAccessToken(format, "rejected")
+		return "", err
+	}
+	observeAccessToken(format, "validated")
+	return signature, nil
+}
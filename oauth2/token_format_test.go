@@ -0,0 +1,78 @@
+package oauth2_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/hydra/jwk"
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestTokenFormat(t *testing.T) {
+	assert.Equal(t, TokenFormatOpaqueV1, TokenFormat("key.signature"))
+	assert.Equal(t, TokenFormatOpaqueV2, TokenFormat(OpaqueV2Prefix+"key.signature"))
+	assert.Equal(t, TokenFormatJWT, TokenFormat("header.claims.signature"))
+}
+
+func TestVersionedAccessTokenStrategy(t *testing.T) {
+	km := &jwk.MemoryManager{}
+	keys, err := new(jwk.RS256Generator).Generate("1")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(AccessTokenKeyName, keys))
+
+	jwtStrategy := &JWTAccessTokenStrategy{
+		CoreStrategy:        hmacStrategy,
+		KeyManager:          km,
+		Issuer:              "https://hydra.localhost",
+		AccessTokenLifespan: time.Hour,
+	}
+
+	s := &VersionedAccessTokenStrategy{
+		CoreStrategy: hmacStrategy,
+		Issue:        TokenFormatOpaqueV1,
+		Formats: map[string]core.AccessTokenStrategy{
+			TokenFormatOpaqueV1: hmacStrategy,
+			TokenFormatOpaqueV2: &OpaqueV2Strategy{AccessTokenStrategy: hmacStrategy},
+			TokenFormatJWT:      jwtStrategy,
+		},
+	}
+
+	ctx := context.Background()
+	request := fosite.NewAccessRequest(&Session{Subject: "peter"})
+	request.Client = &fosite.DefaultClient{ID: "app"}
+
+	tokens := map[string]string{}
+	for _, format := range []string{TokenFormatOpaqueV1, TokenFormatOpaqueV2, TokenFormatJWT} {
+		s.Issue = format
+		token, signature, err := s.GenerateAccessToken(ctx, request)
+		require.Nil(t, err, "%s", format)
+		assert.Equal(t, format, TokenFormat(token))
+		assert.True(t, strings.HasSuffix(token, signature), "%s", format)
+		tokens[format] = token
+
+		actual, err := s.ValidateAccessToken(ctx, request, token)
+		require.Nil(t, err, "%s", format)
+		assert.Equal(t, signature, actual)
+	}
+
+	// Tokens of formats which are no longer accepted are rejected, tokens of the new format keep working.
+	delete(s.Formats, TokenFormatOpaqueV1)
+	s.Issue = TokenFormatOpaqueV2
+	_, err = s.ValidateAccessToken(ctx, request, tokens[TokenFormatOpaqueV1])
+	assert.NotNil(t, err)
+	_, err = s.ValidateAccessToken(ctx, request, tokens[TokenFormatOpaqueV2])
+	assert.Nil(t, err)
+
+	_, err = s.ValidateAccessToken(ctx, request, OpaqueV2Prefix+"forged.signature")
+	assert.NotNil(t, err)
+
+	s.Issue = TokenFormatOpaqueV1
+	_, _, err = s.GenerateAccessToken(ctx, request)
+	assert.NotNil(t, err)
+}
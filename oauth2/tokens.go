@@ -0,0 +1,84 @@
+package oauth2
+
+import (
+	"net/http"
+
+	"github.com/go-errors/errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/job"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+)
+
+const TokensHandlerPath = "/oauth2/tokens"
+
+// TokensHandler lets administrators revoke the tokens of a client in bulk, for example after its credentials leaked.
+type TokensHandler struct {
+	Storage pkg.TokenRevocationStorage
+
+	// Jobs runs revocations requested with async=true in the background. Revocations always run synchronously
+	// if Jobs is nil.
+	Jobs *job.Dispatcher
+
+	H herodot.Herodot
+	W firewall.Firewall
+}
+
+func (h *TokensHandler) SetRoutes(r *httprouter.Router) {
+	r.DELETE(TokensHandlerPath, h.DeleteByClient)
+}
+
+// DeleteByClient revokes every access and refresh token issued to the client given by the query parameter client_id.
+// Clients with many tokens should be revoked with async=true, which answers with the job doing the revocation.
+func (h *TokensHandler) DeleteByClient(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+	clientID := r.URL.Query().Get("client_id")
+
+	if clientID == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Missing query parameter client_id"))
+		return
+	} else if !pkg.BulkConfirmed(r) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, pkg.ErrBulkNotConfirmed)
+		return
+	}
+
+	if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: introspectionResource,
+		Action:   "delete",
+		Context: ladon.Context{
+			"client": clientID,
+		},
+	}, "hydra.tokens"); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	revoke := func(ctx context.Context) (interface{}, error) {
+		revoked, err := h.Storage.RevokeClientTokens(ctx, clientID)
+		if err != nil {
+			return nil, err
+		}
+		return &pkg.BulkDeletion{Deleted: len(revoked)}, nil
+	}
+
+	if r.URL.Query().Get("async") == "true" && h.Jobs != nil {
+		j, err := h.Jobs.Submit("oauth2.tokens.revoke", revoke)
+		if err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+		job.WriteAccepted(ctx, h.H, w, r, j)
+		return
+	}
+
+	res, err := revoke(ctx)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, res)
+}
@@ -0,0 +1,86 @@
+package oauth2
+
+import (
+	"encoding/json"
+
+	"github.com/go-errors/errors"
+)
+
+const (
+	// ACRWebAuthn is the authentication context of sessions backed by a WebAuthn credential.
+	ACRWebAuthn = "webauthn"
+
+	// ACRWebAuthnUserVerified is the authentication context of sessions backed by a WebAuthn credential for which
+	// the authenticator verified the user, for example with a PIN or a fingerprint.
+	ACRWebAuthnUserVerified = "webauthn-uv"
+)
+
+// Attestation types whose keys are known to be stored in an authenticator's hardware.
+var hardwareAttestations = map[string]bool{
+	"basic":  true,
+	"attca":  true,
+	"anonca": true,
+}
+
+// WebAuthnAssertion is the metadata of the WebAuthn assertion a user logged in with. The login app passes it to
+// hydra in the "webauthn" claim of the consent response.
+type WebAuthnAssertion struct {
+	CredentialID    string `json:"credential_id"`
+	AttestationType string `json:"attestation_type,omitempty"`
+	UserVerified    bool   `json:"user_verified"`
+}
+
+func webAuthnFromClaim(claim interface{}) (*WebAuthnAssertion, error) {
+	if claim == nil {
+		return nil, nil
+	}
+
+	out, err := json.Marshal(claim)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	var a WebAuthnAssertion
+	if err := json.Unmarshal(out, &a); err != nil {
+		return nil, errors.Errorf("Claim webauthn is malformed: %s", err)
+	} else if a.CredentialID == "" {
+		return nil, errors.New("Claim webauthn is missing the credential_id")
+	}
+	return &a, nil
+}
+
+// AuthenticationMethods returns the authentication method references (RFC 8176) of the assertion.
+func (a *WebAuthnAssertion) AuthenticationMethods() []string {
+	amr := []string{"swk"}
+	if hardwareAttestations[a.AttestationType] {
+		amr = []string{"hwk"}
+	}
+
+	if a.UserVerified {
+		amr = append(amr, "user", "mfa")
+	}
+	return amr
+}
+
+// AuthenticationContext returns the authentication context class reference of the assertion.
+func (a *WebAuthnAssertion) AuthenticationContext() string {
+	if a.UserVerified {
+		return ACRWebAuthnUserVerified
+	}
+	return ACRWebAuthn
+}
+
+func mergeAuthenticationMethods(methods []string, add ...string) []string {
+	seen := map[string]bool{}
+	for _, m := range methods {
+		seen[m] = true
+	}
+
+	for _, m := range add {
+		if !seen[m] {
+			methods = append(methods, m)
+			seen[m] = true
+		}
+	}
+	return methods
+}
@@ -0,0 +1,40 @@
+package oauth2_test
+
+import (
+	"testing"
+
+	. "github.com/ory-am/hydra/oauth2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebAuthnAssertion(t *testing.T) {
+	for k, c := range []struct {
+		a   *WebAuthnAssertion
+		amr []string
+		acr string
+	}{
+		{
+			a:   &WebAuthnAssertion{CredentialID: "foo", AttestationType: "none"},
+			amr: []string{"swk"},
+			acr: ACRWebAuthn,
+		},
+		{
+			a:   &WebAuthnAssertion{CredentialID: "foo", AttestationType: "self", UserVerified: true},
+			amr: []string{"swk", "user", "mfa"},
+			acr: ACRWebAuthnUserVerified,
+		},
+		{
+			a:   &WebAuthnAssertion{CredentialID: "foo", AttestationType: "basic"},
+			amr: []string{"hwk"},
+			acr: ACRWebAuthn,
+		},
+		{
+			a:   &WebAuthnAssertion{CredentialID: "foo", AttestationType: "attca", UserVerified: true},
+			amr: []string{"hwk", "user", "mfa"},
+			acr: ACRWebAuthnUserVerified,
+		},
+	} {
+		assert.Equal(t, c.amr, c.a.AuthenticationMethods(), "Case %d", k)
+		assert.Equal(t, c.acr, c.a.AuthenticationContext(), "Case %d", k)
+	}
+}
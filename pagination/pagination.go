@@ -0,0 +1,191 @@
+// Package pagination implements the limit, offset, sort and filter query parameters which are shared by all list
+// endpoints.
+//
+//	GET /clients?limit=20&offset=40&sort=-client_name&owner=peter
+//
+// Listings are complete unless limit is given, so that existing callers keep receiving every item. The total number
+// of matching items is returned in the X-Total-Count header and links to the neighbouring pages in the Link header
+// (RFC 5988).
+package pagination
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+const (
+	// MaxLimit is the largest page size a caller may request.
+	MaxLimit = 1000
+
+	TotalCountHeader = "X-Total-Count"
+)
+
+// Page describes the part of a listing a caller asked for.
+type Page struct {
+	// Limit is the maximum number of items on the page. Zero means no limit.
+	Limit int
+
+	// Offset is the number of matching items skipped before the page starts.
+	Offset int
+
+	// Sort is the field items are sorted by, Descending reverses the order.
+	Sort       string
+	Descending bool
+
+	// Filters maps field names to the value items need to have.
+	Filters map[string]string
+}
+
+// Fields returns the value of field for the i-th item of a listing.
+type Fields func(i int, field string) string
+
+// Parse reads the page from the query of r. fields are the names items can be sorted and filtered by, the first
+// field is the default sort order. A query parameter named like a field filters by it. Sorting descending is
+// requested by prefixing the field with a dash.
+func Parse(r *http.Request, fields ...string) (*Page, error) {
+	q := r.URL.Query()
+	p := &Page{Filters: map[string]string{}}
+
+	if limit := q.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > MaxLimit {
+			return nil, errors.Errorf("Query parameter limit must be a number between 1 and %d", MaxLimit)
+		}
+		p.Limit = l
+	}
+
+	if offset := q.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil || o < 0 {
+			return nil, errors.New("Query parameter offset must be a positive number")
+		}
+		p.Offset = o
+	}
+
+	if len(fields) > 0 {
+		p.Sort = fields[0]
+	}
+	if s := q.Get("sort"); s != "" {
+		p.Descending = strings.HasPrefix(s, "-")
+		p.Sort = strings.TrimPrefix(s, "-")
+		if !contains(fields, p.Sort) {
+			return nil, errors.Errorf("Listing can not be sorted by %s, use one of %s", p.Sort, strings.Join(fields, ", "))
+		}
+	}
+
+	for _, field := range fields {
+		if _, ok := q[field]; ok {
+			p.Filters[field] = q.Get(field)
+		}
+	}
+	return p, nil
+}
+
+// Apply filters and sorts the n items of a listing and returns the indices of the items on the page together with
+// the number of items which matched the filters.
+func (p *Page) Apply(n int, value Fields) ([]int, int) {
+	indices := []int{}
+	for i := 0; i < n; i++ {
+		if p.matches(i, value) {
+			indices = append(indices, i)
+		}
+	}
+
+	if p.Sort != "" {
+		sort.Stable(&byField{indices: indices, value: value, field: p.Sort, descending: p.Descending})
+	}
+
+	total := len(indices)
+	if p.Offset >= total {
+		return []int{}, total
+	}
+
+	end := total
+	if p.Limit > 0 && p.Offset+p.Limit < total {
+		end = p.Offset + p.Limit
+	}
+	return indices[p.Offset:end], total
+}
+
+// WriteHeaders sets the X-Total-Count header and, if the listing is limited, a Link header pointing to the first,
+// previous, next and last page.
+func (p *Page) WriteHeaders(w http.ResponseWriter, r *http.Request, total int) {
+	w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+	if p.Limit == 0 {
+		return
+	}
+
+	links := []string{p.link(r, "first", 0)}
+	if p.Offset > 0 {
+		prev := p.Offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, p.link(r, "prev", prev))
+	}
+	if p.Offset+p.Limit < total {
+		links = append(links, p.link(r, "next", p.Offset+p.Limit))
+	}
+
+	last := 0
+	if total > 0 {
+		last = (total - 1) / p.Limit * p.Limit
+	}
+	links = append(links, p.link(r, "last", last))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func (p *Page) link(r *http.Request, rel string, offset int) string {
+	q := r.URL.Query()
+	q.Set("limit", strconv.Itoa(p.Limit))
+	q.Set("offset", strconv.Itoa(offset))
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+}
+
+func (p *Page) matches(i int, value Fields) bool {
+	for field, expected := range p.Filters {
+		if value(i, field) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+type byField struct {
+	indices    []int
+	value      Fields
+	field      string
+	descending bool
+}
+
+func (s *byField) Len() int {
+	return len(s.indices)
+}
+
+func (s *byField) Swap(i, j int) {
+	s.indices[i], s.indices[j] = s.indices[j], s.indices[i]
+}
+
+func (s *byField) Less(i, j int) bool {
+	a, b := s.value(s.indices[i], s.field), s.value(s.indices[j], s.field)
+	if s.descending {
+		return a > b
+	}
+	return a < b
+}
+
+func contains(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
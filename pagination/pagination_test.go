@@ -0,0 +1,114 @@
+package pagination_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/ory-am/hydra/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	id    string
+	owner string
+}
+
+var items = []item{
+	{id: "c", owner: "peter"},
+	{id: "a", owner: "alice"},
+	{id: "e", owner: "peter"},
+	{id: "b", owner: "peter"},
+	{id: "d", owner: "alice"},
+}
+
+func value(i int, field string) string {
+	switch field {
+	case "id":
+		return items[i].id
+	case "owner":
+		return items[i].owner
+	}
+	return ""
+}
+
+func ids(indices []int) (res []string) {
+	for _, i := range indices {
+		res = append(res, items[i].id)
+	}
+	return res
+}
+
+func TestParse(t *testing.T) {
+	for k, c := range []struct {
+		query     string
+		expectErr bool
+		expected  *Page
+	}{
+		{query: "", expected: &Page{Sort: "id", Filters: map[string]string{}}},
+		{query: "limit=2&offset=4&sort=-owner", expected: &Page{Limit: 2, Offset: 4, Sort: "owner", Descending: true, Filters: map[string]string{}}},
+		{query: "owner=peter&foo=bar", expected: &Page{Sort: "id", Filters: map[string]string{"owner": "peter"}}},
+		{query: "limit=0", expectErr: true},
+		{query: "limit=abc", expectErr: true},
+		{query: "limit=100000", expectErr: true},
+		{query: "offset=-1", expectErr: true},
+		{query: "sort=secret", expectErr: true},
+	} {
+		r, err := http.NewRequest("GET", "/clients?"+c.query, nil)
+		require.Nil(t, err)
+
+		p, err := Parse(r, "id", "owner")
+		if c.expectErr {
+			assert.NotNil(t, err, "Case %d", k)
+			continue
+		}
+		require.Nil(t, err, "Case %d", k)
+		assert.Equal(t, c.expected, p, "Case %d", k)
+	}
+}
+
+func TestApply(t *testing.T) {
+	for k, c := range []struct {
+		page     *Page
+		expected []string
+		total    int
+	}{
+		{page: &Page{}, expected: []string{"c", "a", "e", "b", "d"}, total: 5},
+		{page: &Page{Sort: "id"}, expected: []string{"a", "b", "c", "d", "e"}, total: 5},
+		{page: &Page{Sort: "id", Descending: true, Limit: 2}, expected: []string{"e", "d"}, total: 5},
+		{page: &Page{Sort: "id", Limit: 2, Offset: 4}, expected: []string{"e"}, total: 5},
+		{page: &Page{Sort: "id", Offset: 10}, expected: nil, total: 5},
+		{page: &Page{Sort: "id", Filters: map[string]string{"owner": "peter"}}, expected: []string{"b", "c", "e"}, total: 3},
+		{page: &Page{Sort: "owner", Filters: map[string]string{"owner": "nobody"}}, expected: nil, total: 0},
+	} {
+		indices, total := c.page.Apply(len(items), value)
+		assert.Equal(t, c.expected, ids(indices), "Case %d", k)
+		assert.Equal(t, c.total, total, "Case %d", k)
+	}
+}
+
+func TestWriteHeaders(t *testing.T) {
+	r, err := http.NewRequest("GET", "/clients?owner=peter&limit=2&offset=2", nil)
+	require.Nil(t, err)
+	p, err := Parse(r, "id", "owner")
+	require.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	p.WriteHeaders(w, r, 5)
+	assert.Equal(t, "5", w.Header().Get(TotalCountHeader))
+	assert.Equal(t, `</clients?limit=2&offset=0&owner=peter>; rel="first", `+
+		`</clients?limit=2&offset=0&owner=peter>; rel="prev", `+
+		`</clients?limit=2&offset=4&owner=peter>; rel="next", `+
+		`</clients?limit=2&offset=4&owner=peter>; rel="last"`, w.Header().Get("Link"))
+
+	r, err = http.NewRequest("GET", "/clients", nil)
+	require.Nil(t, err)
+	p, err = Parse(r, "id")
+	require.Nil(t, err)
+
+	w = httptest.NewRecorder()
+	p.WriteHeaders(w, r, 3)
+	assert.Equal(t, "3", w.Header().Get(TotalCountHeader))
+	assert.Empty(t, w.Header().Get("Link"))
+}
@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrBudgetExceeded is returned if a storage call did not complete within its latency budget.
+var ErrBudgetExceeded = errors.New("The storage did not respond within the latency budget")
+
+var budgetExceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "storage",
+	Name:      "budget_exceeded_total",
+	Help:      "Number of storage calls which did not complete within their latency budget, partitioned by operation.",
+}, []string{"operation"})
+
+func init() {
+	prometheus.MustRegister(budgetExceeded)
+}
+
+// WithinBudget calls f and returns ErrBudgetExceeded if f did not return within budget. f keeps running in the
+// background and its result is discarded, so f must not write to variables the caller reads after an exceeded
+// budget. A budget of zero waits for f to return.
+func WithinBudget(operation string, budget time.Duration, f func() error) error {
+	if budget <= 0 {
+		return f()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		budgetExceeded.WithLabelValues(operation).Inc()
+		return errors.New(ErrBudgetExceeded)
+	}
+}
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/ladon"
+)
+
+// ErrBulkNotConfirmed is returned by bulk delete endpoints if the request lacks the query parameter confirm=true.
+var ErrBulkNotConfirmed = errors.New("Bulk deletions must be confirmed with the query parameter confirm=true")
+
+// BulkDeletion is the response of bulk delete endpoints.
+type BulkDeletion struct {
+	// Deleted is the number of records which were deleted.
+	Deleted int `json:"deleted"`
+}
+
+// BulkConfirmed returns true if the request explicitly confirmed a bulk deletion.
+func BulkConfirmed(r *http.Request) bool {
+	return r.URL.Query().Get("confirm") == "true"
+}
+
+// MaxBulkOperations is the largest number of operations a bulk request may contain.
+const MaxBulkOperations = 1000
+
+// The actions of bulk operations.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkOperation is one item of a bulk request. ID identifies the record of update and delete operations, Data holds
+// the record of create and update operations. IfMatch works like the If-Match header of single updates and deletes.
+type BulkOperation struct {
+	Action  string          `json:"action"`
+	ID      string          `json:"id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	IfMatch string          `json:"if_match,omitempty"`
+}
+
+// BulkResult is the outcome of a single operation. Status is the status code the operation would have had on its own.
+type BulkResult struct {
+	ID     string      `json:"id,omitempty"`
+	Status int         `json:"status"`
+	Error  string      `json:"error,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// BulkResponse holds the results in the order of the operations. Operations are applied independently, a failed
+// operation neither stops nor rolls back the others, so callers need to check Failed.
+type BulkResponse struct {
+	Results   []*BulkResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// DecodeBulkOperations decodes the list of operations in the body of a bulk request.
+func DecodeBulkOperations(r *http.Request) ([]*BulkOperation, error) {
+	var ops []*BulkOperation
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		return nil, errors.New(err)
+	} else if len(ops) == 0 {
+		return nil, errors.New("The request contains no operations")
+	} else if len(ops) > MaxBulkOperations {
+		return nil, errors.Errorf("A bulk request may contain at most %d operations", MaxBulkOperations)
+	}
+
+	for k, op := range ops {
+		switch op.Action {
+		case BulkCreate:
+		case BulkUpdate, BulkDelete:
+			if op.ID == "" {
+				return nil, errors.Errorf("Operation %d must have an id", k)
+			}
+		default:
+			return nil, errors.Errorf("Operation %d has unknown action %s, use %s, %s or %s", k, op.Action, BulkCreate, BulkUpdate, BulkDelete)
+		}
+	}
+	return ops, nil
+}
+
+// Succeed records a successful operation.
+func (b *BulkResponse) Succeed(id string, status int, data interface{}) {
+	b.Results = append(b.Results, &BulkResult{ID: id, Status: status, Data: data})
+	b.Succeeded++
+}
+
+// Reject records an operation which failed because it was malformed.
+func (b *BulkResponse) Reject(id string, err error) {
+	b.Results = append(b.Results, &BulkResult{ID: id, Status: http.StatusBadRequest, Error: err.Error()})
+	b.Failed++
+}
+
+// Fail records a failed operation. The status code is taken from err if it is a herodot error.
+func (b *BulkResponse) Fail(id string, err error) {
+	status := herodot.ToError(err).Code
+	if errors.Is(err, ErrNotFound) {
+		status = http.StatusNotFound
+	} else if errors.Is(err, ladon.ErrRequestDenied) || errors.Is(err, ladon.ErrRequestForcefullyDenied) {
+		status = http.StatusForbidden
+	} else if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	b.Results = append(b.Results, &BulkResult{ID: id, Status: status, Error: err.Error()})
+	b.Failed++
+}
@@ -0,0 +1,207 @@
+package pkg
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+	"gopkg.in/dancannon/gorethink.v2/encoding"
+)
+
+const (
+	changeFeedMinWait = time.Millisecond * 500
+	changeFeedMaxWait = time.Second * 15
+)
+
+// Change is a single change of a document reported by a changefeed. OldValue is nil for inserts and NewValue is
+// nil for deletes.
+type Change struct {
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// Decode decodes the old and the new value of the change into oldVal and newVal. A value which is not part of the
+// change leaves its target untouched.
+func (c *Change) Decode(oldVal, newVal interface{}) error {
+	if c.OldValue != nil {
+		if err := encoding.Decode(oldVal, c.OldValue); err != nil {
+			return errors.New(err)
+		}
+	}
+	if c.NewValue != nil {
+		if err := encoding.Decode(newVal, c.NewValue); err != nil {
+			return errors.New(err)
+		}
+	}
+	return nil
+}
+
+// changeCursor is the part of a gorethink cursor the feed reads changes from.
+type changeCursor interface {
+	Next(dest interface{}) bool
+	Err() error
+	Close() error
+}
+
+type changeSubscriber struct {
+	resync   func() error
+	onChange func(change *Change) error
+}
+
+// ChangeFeed streams the changes of a RethinkDB table to its subscribers. It reconnects with an exponential back
+// off until its context is canceled. Every time the feed connects, subscribers are asked to resynchronize before
+// any change is delivered, so that changes which happened while the feed was down are not lost.
+type ChangeFeed struct {
+	Session *r.Session
+	Table   r.Term
+
+	subscribers []*changeSubscriber
+	connected   bool
+	sync.RWMutex
+
+	// open opens the changefeed of Table unless it is replaced, for example by tests which run without a database.
+	open func() (changeCursor, error)
+}
+
+var (
+	startedFeeds     []*ChangeFeed
+	startedFeedsLock sync.RWMutex
+)
+
+// StartedChangeFeeds returns all feeds which were started, for example to report whether they are connected.
+func StartedChangeFeeds() []*ChangeFeed {
+	startedFeedsLock.RLock()
+	defer startedFeedsLock.RUnlock()
+
+	return append([]*ChangeFeed{}, startedFeeds...)
+}
+
+// Connected tells whether the feed listens to its table and its subscribers have resynchronized.
+func (f *ChangeFeed) Connected() bool {
+	f.RLock()
+	defer f.RUnlock()
+
+	return f.connected
+}
+
+func (f *ChangeFeed) setConnected(connected bool) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.connected = connected
+}
+
+// Subscribe registers a subscriber. resync is called after the feed (re)connected and may be nil, onChange is
+// called for every change. Errors returned by onChange are logged and do not interrupt the feed.
+func (f *ChangeFeed) Subscribe(resync func() error, onChange func(change *Change) error) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.subscribers = append(f.subscribers, &changeSubscriber{resync: resync, onChange: onChange})
+}
+
+// Start listens to the table in the background until ctx is canceled.
+func (f *ChangeFeed) Start(ctx context.Context) {
+	startedFeedsLock.Lock()
+	startedFeeds = append(startedFeeds, f)
+	startedFeedsLock.Unlock()
+
+	go f.run(ctx)
+}
+
+func (f *ChangeFeed) run(ctx context.Context) {
+	wait := changeFeedMinWait
+	for {
+		start := time.Now()
+		err := f.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		} else if err == nil {
+			err = errors.New("Changefeed was closed by the database")
+		}
+		logger.LogError(err)
+
+		// The feed was up for a while, so this is a new outage and not a failing reconnect.
+		if time.Now().Sub(start) > changeFeedMaxWait {
+			wait = changeFeedMinWait
+		}
+
+		logrus.Infof("Reconnecting changefeed in %f seconds...", wait.Seconds())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		wait = wait * 2
+		if wait > changeFeedMaxWait {
+			wait = changeFeedMaxWait
+		}
+	}
+}
+
+func (f *ChangeFeed) openChanges() (changeCursor, error) {
+	if f.open != nil {
+		return f.open()
+	}
+	return f.Table.Changes().Run(f.Session)
+}
+
+func (f *ChangeFeed) listen(ctx context.Context) error {
+	changes, err := f.openChanges()
+	if err != nil {
+		return errors.New(err)
+	}
+	defer changes.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			changes.Close()
+		case <-stop:
+		}
+	}()
+
+	f.RLock()
+	subscribers := f.subscribers
+	f.RUnlock()
+
+	// The feed is already open, so nothing which changes during the resync is missed.
+	for _, s := range subscribers {
+		if s.resync == nil {
+			continue
+		} else if err := s.resync(); err != nil {
+			return err
+		}
+	}
+
+	f.setConnected(true)
+	defer f.setConnected(false)
+	for {
+		var update map[string]interface{}
+		if !changes.Next(&update) {
+			break
+		}
+
+		change := &Change{OldValue: update["old_val"], NewValue: update["new_val"]}
+		f.RLock()
+		subscribers = f.subscribers
+		f.RUnlock()
+		for _, s := range subscribers {
+			if err := s.onChange(change); err != nil {
+				logger.LogError(err)
+			}
+		}
+	}
+
+	if changes.Err() != nil {
+		return errors.New(changes.Err())
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package pkg
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// fakeCursor delivers the changes sent to it until changes is closed, which looks like the database closing the
+// feed, or until the feed closes the cursor.
+type fakeCursor struct {
+	changes chan map[string]interface{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newFakeCursor(changes ...interface{}) *fakeCursor {
+	c := &fakeCursor{changes: make(chan map[string]interface{}, 10), done: make(chan struct{})}
+	for _, v := range changes {
+		c.changes <- map[string]interface{}{"new_val": v}
+	}
+	return c
+}
+
+func (c *fakeCursor) Next(dest interface{}) bool {
+	select {
+	case change, ok := <-c.changes:
+		if !ok {
+			return false
+		}
+		*dest.(*map[string]interface{}) = change
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+func (c *fakeCursor) Err() error {
+	return nil
+}
+
+func (c *fakeCursor) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+// fakeCursors returns an opener which hands out the cursors one after another.
+func fakeCursors(cursors ...*fakeCursor) func() (changeCursor, error) {
+	var lock sync.Mutex
+	return func() (changeCursor, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if len(cursors) == 0 {
+			return nil, errors.New("No cursor left")
+		}
+		c := cursors[0]
+		cursors = cursors[1:]
+		return c, nil
+	}
+}
+
+func receive(t *testing.T, c chan interface{}) interface{} {
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(time.Second * 5):
+		require.FailNow(t, "Timed out waiting for the changefeed")
+		return nil
+	}
+}
+
+func TestChangeFeedDispatchesToAllSubscribers(t *testing.T) {
+	cursor := newFakeCursor("a", "b")
+	f := &ChangeFeed{open: fakeCursors(cursor)}
+
+	first, second := make(chan interface{}, 10), make(chan interface{}, 10)
+	f.Subscribe(nil, func(change *Change) error {
+		first <- change.NewValue
+		return errors.New("The first subscriber fails")
+	})
+	f.Subscribe(nil, func(change *Change) error {
+		second <- change.NewValue
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.Start(ctx)
+
+	// A failing subscriber neither stops the feed nor keeps the change from the others.
+	for _, expected := range []string{"a", "b"} {
+		assert.Equal(t, expected, receive(t, first))
+		assert.Equal(t, expected, receive(t, second))
+	}
+}
+
+func TestChangeFeedResyncsBeforeDeliveringOnReconnect(t *testing.T) {
+	first, second := newFakeCursor("a"), newFakeCursor("b")
+	f := &ChangeFeed{open: fakeCursors(first, second)}
+
+	events := make(chan interface{}, 10)
+	f.Subscribe(func() error {
+		assert.False(t, f.Connected(), "The feed is connected once all subscribers resynchronized")
+		events <- "resync"
+		return nil
+	}, func(change *Change) error {
+		assert.True(t, f.Connected())
+		events <- change.NewValue
+		return nil
+	})
+
+	assert.False(t, f.Connected())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.Start(ctx)
+
+	assert.Equal(t, "resync", receive(t, events))
+	assert.Equal(t, "a", receive(t, events))
+
+	// The database closes the feed, the second connection resynchronizes before it delivers the buffered change.
+	close(first.changes)
+	assert.Equal(t, "resync", receive(t, events))
+	assert.Equal(t, "b", receive(t, events))
+	assert.True(t, f.Connected())
+
+	cancel()
+	for k := 0; k < 50 && f.Connected(); k++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	assert.False(t, f.Connected())
+}
+
+func TestChangeFeedIsNotConnectedWhileResyncFails(t *testing.T) {
+	f := &ChangeFeed{open: fakeCursors(newFakeCursor("a"))}
+
+	resyncs := make(chan interface{}, 10)
+	f.Subscribe(func() error {
+		resyncs <- "resync"
+		return errors.New("The subscriber could not resynchronize")
+	}, func(change *Change) error {
+		assert.Fail(t, "Changes must not be delivered to subscribers which did not resynchronize")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.Start(ctx)
+
+	assert.Equal(t, "resync", receive(t, resyncs))
+	time.Sleep(time.Millisecond * 50)
+	assert.False(t, f.Connected())
+}
@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/go-errors/errors"
+)
+
+// The attributes of the table which the DynamoDB backends share. Every item is addressed by its partition and sort
+// key, whose values are prefixed by the kind of the item so that clients, keys and tokens do not collide. The two
+// global secondary indexes are sparse, only the items which set their attributes are indexed. Items which expire
+// set DynamoDBTTL to the unix time they expire at, the table deletes them some time after.
+const (
+	DynamoDBPartitionKey = "pk"
+	DynamoDBSortKey      = "sk"
+	DynamoDBTTL          = "ttl"
+
+	DynamoDBIndex1             = "gsi1"
+	DynamoDBIndex1PartitionKey = "gsi1pk"
+	DynamoDBIndex1SortKey      = "gsi1sk"
+
+	DynamoDBIndex2             = "gsi2"
+	DynamoDBIndex2PartitionKey = "gsi2pk"
+	DynamoDBIndex2SortKey      = "gsi2sk"
+)
+
+// DynamoDBBatchSize is the most items a batch or transactional write of DynamoDB accepts.
+const DynamoDBBatchSize = 25
+
+// CreateDynamoDBTable creates the table of the DynamoDB backends unless it exists, waits until it is active and
+// enables the expiry of items. The table is billed per request, so that it does not need to be provisioned.
+func CreateDynamoDBTable(db dynamodbiface.DynamoDBAPI, table string) error {
+	index := func(name, partitionKey, sortKey string) *dynamodb.GlobalSecondaryIndex {
+		return &dynamodb.GlobalSecondaryIndex{
+			IndexName: aws.String(name),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String(partitionKey), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				{AttributeName: aws.String(sortKey), KeyType: aws.String(dynamodb.KeyTypeRange)},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		}
+	}
+
+	var attributes []*dynamodb.AttributeDefinition
+	for _, name := range []string{
+		DynamoDBPartitionKey, DynamoDBSortKey,
+		DynamoDBIndex1PartitionKey, DynamoDBIndex1SortKey,
+		DynamoDBIndex2PartitionKey, DynamoDBIndex2SortKey,
+	} {
+		attributes = append(attributes, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(name),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		})
+	}
+
+	if _, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName:            aws.String(table),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: attributes,
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(DynamoDBPartitionKey), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String(DynamoDBSortKey), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			index(DynamoDBIndex1, DynamoDBIndex1PartitionKey, DynamoDBIndex1SortKey),
+			index(DynamoDBIndex2, DynamoDBIndex2PartitionKey, DynamoDBIndex2SortKey),
+		},
+	}); err != nil && !isDynamoDBError(err, dynamodb.ErrCodeResourceInUseException) {
+		return errors.New(err)
+	}
+
+	if err := db.WaitUntilTableExists(&dynamodb.DescribeTableInput{TableName: aws.String(table)}); err != nil {
+		return errors.New(err)
+	}
+
+	ttl, err := db.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(table)})
+	if err != nil {
+		return errors.New(err)
+	} else if d := ttl.TimeToLiveDescription; d != nil && d.TimeToLiveStatus != nil &&
+		(*d.TimeToLiveStatus == dynamodb.TimeToLiveStatusEnabled || *d.TimeToLiveStatus == dynamodb.TimeToLiveStatusEnabling) {
+		return nil
+	}
+
+	if _, err := db.UpdateTimeToLive(&dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(table),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(DynamoDBTTL),
+			Enabled:       aws.Bool(true),
+		},
+	}); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// IsDynamoDBConditionFailed returns true if a write was rejected because its condition did not hold, for example
+// because an item which must be unique exists already.
+func IsDynamoDBConditionFailed(err error) bool {
+	return isDynamoDBError(err, dynamodb.ErrCodeConditionalCheckFailedException)
+}
+
+// IsDynamoDBTransactionCanceled returns true if a transaction was rejected, either because one of its conditions
+// did not hold or because it conflicted with another transaction.
+func IsDynamoDBTransactionCanceled(err error) bool {
+	return isDynamoDBError(err, dynamodb.ErrCodeTransactionCanceledException)
+}
+
+func isDynamoDBError(err error, code string) bool {
+	if e, ok := err.(*errors.Error); ok {
+		err = e.Err
+	}
+	e, ok := err.(awserr.Error)
+	return ok && e.Code() == code
+}
+
+// DeleteDynamoDBItems deletes the items with the keys in batches, retrying the deletes DynamoDB did not process.
+func DeleteDynamoDBItems(db dynamodbiface.DynamoDBAPI, table string, keys []map[string]*dynamodb.AttributeValue) error {
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > DynamoDBBatchSize {
+			n = DynamoDBBatchSize
+		}
+
+		var requests []*dynamodb.WriteRequest
+		for _, key := range keys[:n] {
+			requests = append(requests, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: key}})
+		}
+		keys = keys[n:]
+
+		// DynamoDB leaves deletes unprocessed when the table is throttled, they are retried with a growing delay.
+		wait := time.Millisecond * 50
+		for len(requests) > 0 {
+			out, err := db.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+				RequestItems: map[string][]*dynamodb.WriteRequest{table: requests},
+			})
+			if err != nil {
+				return errors.New(err)
+			}
+
+			requests = out.UnprocessedItems[table]
+			if len(requests) > 0 {
+				time.Sleep(wait)
+				wait *= 2
+			}
+		}
+	}
+	return nil
+}
+
+// DynamoDBKey returns the primary key of an item.
+func DynamoDBKey(partitionKey, sortKey string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		DynamoDBPartitionKey: {S: aws.String(partitionKey)},
+		DynamoDBSortKey:      {S: aws.String(sortKey)},
+	}
+}
@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/herodot"
+)
+
+// ErrPreconditionFailed rejects a write whose If-Match header does not match the resource, because someone else
+// changed it since the caller read it.
+var ErrPreconditionFailed = &herodot.Error{
+	Err:  errors.New("The resource was changed in the meantime, fetch it again and retry"),
+	Code: http.StatusPreconditionFailed,
+}
+
+// ETag returns a strong entity tag of a resource. It is derived from the JSON encoding, so that it changes with every
+// change of the resource without the stores having to keep version numbers.
+func ETag(v interface{}) (string, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.New(err)
+	}
+
+	sum := sha256.Sum256(out)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// WriteETag sets the ETag header of a response to the entity tag of the resource.
+func WriteETag(w http.ResponseWriter, v interface{}) {
+	if tag, err := ETag(v); err == nil {
+		w.Header().Set("ETag", tag)
+	}
+}
+
+// Preconditions makes the If-Match check and the write which follows it atomic across the cluster. Every write to
+// a resource holds a lease named after the resource while it checks and writes, so that no other write can change
+// the resource in between.
+type Preconditions struct {
+	Leases LeaseManager
+
+	// TTL bounds how long a write holds the lease and how long it waits for it, DefaultLeaseTTL if zero. A write
+	// which takes longer than TTL loses its lease.
+	TTL time.Duration
+}
+
+// Write calls write if ifMatch matches the current resource, see IfMatch. Writes without If-Match take the lease as
+// well, so that they can not slip between the check and the write of a conditional one. If p is nil or has no
+// leases, the check and the write are not atomic.
+func (p *Preconditions) Write(resource, ifMatch string, current func() (interface{}, error), write func() error) error {
+	checkAndWrite := func() error {
+		if err := IfMatch(ifMatch, current); err != nil {
+			return err
+		}
+		return write()
+	}
+
+	if p == nil || p.Leases == nil {
+		return checkAndWrite()
+	}
+	return WithLease(p.Leases, "if-match:"+resource, p.TTL, checkAndWrite)
+}
+
+// IfMatch returns ErrPreconditionFailed unless ifMatch, the value of an If-Match header, is empty or lists the entity
+// tag of the current resource. current is only called if ifMatch is set and returns ErrNotFound if the resource does
+// not exist. The check and the following write are not atomic on their own, writes use Preconditions to make them.
+func IfMatch(ifMatch string, current func() (interface{}, error)) error {
+	if ifMatch == "" {
+		return nil
+	}
+
+	v, err := current()
+	if errors.Is(err, ErrNotFound) {
+		return errors.New(ErrPreconditionFailed)
+	} else if err != nil {
+		return err
+	} else if strings.TrimSpace(ifMatch) == "*" {
+		return nil
+	}
+
+	tag, err := ETag(v)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(t) == tag {
+			return nil
+		}
+	}
+	return errors.New(ErrPreconditionFailed)
+}
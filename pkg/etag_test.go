@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIfMatch(t *testing.T) {
+	type client struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	current := &client{ID: "a", Name: "foo"}
+	tag, err := ETag(current)
+	require.Nil(t, err)
+
+	changed, err := ETag(&client{ID: "a", Name: "bar"})
+	require.Nil(t, err)
+	assert.NotEqual(t, tag, changed)
+
+	found := func() (interface{}, error) { return current, nil }
+	missing := func() (interface{}, error) { return nil, errors.New(ErrNotFound) }
+
+	for k, c := range []struct {
+		ifMatch string
+		current func() (interface{}, error)
+		ok      bool
+	}{
+		{ifMatch: "", current: missing, ok: true},
+		{ifMatch: tag, current: found, ok: true},
+		{ifMatch: changed + ", " + tag, current: found, ok: true},
+		{ifMatch: "*", current: found, ok: true},
+		{ifMatch: changed, current: found},
+		{ifMatch: "W/" + tag, current: found},
+		{ifMatch: "*", current: missing},
+		{ifMatch: tag, current: missing},
+	} {
+		err := IfMatch(c.ifMatch, c.current)
+		if c.ok {
+			assert.Nil(t, err, "Case %d", k)
+			continue
+		}
+
+		require.NotNil(t, err, "Case %d", k)
+		assert.Equal(t, http.StatusPreconditionFailed, herodot.ToError(err).Code, "Case %d", k)
+	}
+}
+
+func TestPreconditionsWrite(t *testing.T) {
+	var lock sync.Mutex
+	var version int
+	current := func() (interface{}, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return version, nil
+	}
+
+	tag, err := ETag(0)
+	require.Nil(t, err)
+
+	// Every write read the same version, only the first to take the lease may write.
+	p := &Preconditions{Leases: &MemoryLeaseManager{}}
+	var wg sync.WaitGroup
+	var written int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Write("foo", tag, current, func() error {
+				time.Sleep(time.Millisecond * 5)
+				lock.Lock()
+				defer lock.Unlock()
+				version++
+				return nil
+			}); err == nil {
+				atomic.AddInt32(&written, 1)
+			} else {
+				assert.Equal(t, http.StatusPreconditionFailed, herodot.ToError(err).Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), written)
+	assert.Equal(t, 1, version)
+
+	leases := &MemoryLeaseManager{}
+	ok, err := leases.AcquireLease("if-match:foo", "someone-else", time.Minute)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	err = (&Preconditions{Leases: leases, TTL: time.Millisecond * 50}).Write("foo", "", current, func() error { return nil })
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusConflict, herodot.ToError(err).Code)
+
+	var nilPreconditions *Preconditions
+	assert.Nil(t, nilPreconditions.Write("foo", tag, func() (interface{}, error) { return 0, nil }, func() error { return nil }))
+}
@@ -1,12 +1,15 @@
 package pkg
 
 import (
+	"time"
+
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/fosite/handler/core"
 	"github.com/ory-am/fosite/handler/core/explicit"
 	"github.com/ory-am/fosite/handler/core/implicit"
 	"github.com/ory-am/fosite/handler/core/refresh"
 	"github.com/ory-am/fosite/handler/oidc"
+	"golang.org/x/net/context"
 )
 
 type FositeStorer interface {
@@ -16,4 +19,51 @@ type FositeStorer interface {
 	refresh.RefreshTokenGrantStorage
 	implicit.ImplicitGrantStorage
 	oidc.OpenIDConnectRequestStorage
+	TokenRevocationStorage
+}
+
+// TokenRevocationStorage revokes refresh tokens together with the access tokens which were issued by the same
+// authorization grant, as recommended by RFC 7009.
+type TokenRevocationStorage interface {
+	// RevokeRefreshToken deletes the refresh token and every token of its grant. It returns the signatures of the
+	// access tokens which were deleted.
+	RevokeRefreshToken(ctx context.Context, signature string) ([]string, error)
+
+	// IsRefreshTokenRotated returns true if the refresh token was already exchanged by a refresh grant.
+	IsRefreshTokenRotated(ctx context.Context, signature string) (bool, error)
+
+	// RevokeClientTokens deletes every access and refresh token issued to the client. It returns the signatures of
+	// the deleted tokens.
+	RevokeClientTokens(ctx context.Context, clientID string) ([]string, error)
+
+	// RevokeSubjectTokens deletes every access and refresh token issued to the client on behalf of the subject. It
+	// returns the signatures of the deleted tokens.
+	RevokeSubjectTokens(ctx context.Context, subject, clientID string) ([]string, error)
+}
+
+// TokenCounter is implemented by stores which can count the tokens of a client, so that the number of tokens a
+// client holds can be limited.
+type TokenCounter interface {
+	// CountClientAccessTokens returns how many access tokens, implicit ones included, of the client are stored.
+	// Expired tokens are counted until they are deleted.
+	CountClientAccessTokens(ctx context.Context, clientID string) (int, error)
+}
+
+// The kinds of tokens a TokenFlusher deletes.
+const (
+	TokenKindAuthorizeCode        = "authorize_code"
+	TokenKindOpenIDConnectSession = "openid_connect_session"
+	TokenKindAccessToken          = "access_token"
+	TokenKindImplicitAccessToken  = "implicit_access_token"
+	TokenKindRefreshToken         = "refresh_token"
+)
+
+// TokenKinds are all kinds of tokens a TokenFlusher deletes.
+var TokenKinds = []string{TokenKindAuthorizeCode, TokenKindOpenIDConnectSession, TokenKindAccessToken, TokenKindImplicitAccessToken, TokenKindRefreshToken}
+
+// TokenFlusher is implemented by stores which can delete expired tokens in batches.
+type TokenFlusher interface {
+	// FlushExpiredTokens deletes at most limit tokens of the kind which were requested before requestedBefore. It
+	// returns how many tokens were deleted, fewer than limit means that no expired token of the kind is left.
+	FlushExpiredTokens(kind string, requestedBefore time.Time, limit int) (int, error)
 }
@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"crypto/subtle"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
+)
+
+var (
+	// ErrUnknownHashFormat is returned by a Hasher if the hash was not created by it.
+	ErrUnknownHashFormat = errors.New("Unknown hash format")
+
+	// ErrHashMismatch is returned by a Hasher if the data does not match the hash.
+	ErrHashMismatch = errors.New("Hash and data do not match")
+)
+
+// Hasher hashes secrets and compares them against stored hashes.
+type Hasher interface {
+	// Hash returns the hash of data.
+	Hash(data []byte) ([]byte, error)
+
+	// Compare returns nil if data matches hash, ErrUnknownHashFormat if the hash was not created by this hasher and
+	// ErrHashMismatch otherwise.
+	Compare(hash, data []byte) error
+
+	// NeedsRehash returns true if hash was not created by this hasher using its current parameters.
+	NeedsRehash(hash []byte) bool
+}
+
+// MigratingHasher hashes secrets with Hasher. It also accepts secrets hashed by one of the Legacy hashers and secrets
+// which are stored in plain text. Once such a secret, or one that Hasher would hash with different parameters, has been
+// compared successfully, the secret is hashed again and handed to OnRehash so that the stored hash can be replaced.
+type MigratingHasher struct {
+	Hasher Hasher
+	Legacy []Hasher
+
+	// OnRehash is called with the outdated and the new hash of a secret.
+	OnRehash func(oldHash, newHash []byte)
+}
+
+func (h *MigratingHasher) Hash(data []byte) ([]byte, error) {
+	return h.Hasher.Hash(data)
+}
+
+func (h *MigratingHasher) NeedsRehash(hash []byte) bool {
+	return h.Hasher.NeedsRehash(hash)
+}
+
+func (h *MigratingHasher) Compare(hash, data []byte) error {
+	if err := h.compare(hash, data); err != nil {
+		return err
+	}
+
+	if h.OnRehash != nil && h.Hasher.NeedsRehash(hash) {
+		rehashed, err := h.Hasher.Hash(data)
+		if err != nil {
+			// The secret is valid, failing to upgrade its hash must not fail the authentication.
+			logger.LogError(err)
+			return nil
+		}
+		h.OnRehash(hash, rehashed)
+	}
+	return nil
+}
+
+func (h *MigratingHasher) compare(hash, data []byte) error {
+	for _, hasher := range append([]Hasher{h.Hasher}, h.Legacy...) {
+		if err := hasher.Compare(hash, data); !errors.Is(err, ErrUnknownHashFormat) {
+			return err
+		}
+	}
+
+	// None of the hashers knows the format, so this is a plain text secret.
+	if len(hash) == 0 || subtle.ConstantTimeCompare(hash, data) != 1 {
+		return errors.New(ErrHashMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2Prefix = "$argon2id$"
+
+// Argon2 hashes secrets using argon2id. Hashes are encoded in the PHC string format, for example
+// $argon2id$v=19$m=65536,t=1,p=2$<salt>$<hash>.
+type Argon2 struct {
+	// Iterations defaults to 1.
+	Iterations uint32
+
+	// Memory is the amount of memory used in KiB. It defaults to 64 MiB.
+	Memory uint32
+
+	// Parallelism defaults to 2.
+	Parallelism uint8
+}
+
+type argon2Params struct {
+	iterations  uint32
+	memory      uint32
+	parallelism uint8
+}
+
+func (a *Argon2) params() argon2Params {
+	p := argon2Params{iterations: a.Iterations, memory: a.Memory, parallelism: a.Parallelism}
+	if p.iterations == 0 {
+		p.iterations = 1
+	}
+	if p.memory == 0 {
+		p.memory = 64 * 1024
+	}
+	if p.parallelism == 0 {
+		p.parallelism = 2
+	}
+	return p
+}
+
+func (a *Argon2) Hash(data []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, errors.New(err)
+	}
+
+	p := a.params()
+	key := argon2.IDKey(data, salt, p.iterations, p.memory, p.parallelism, 32)
+	return []byte(fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix, argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func (a *Argon2) Compare(hash, data []byte) error {
+	if !bytes.HasPrefix(hash, []byte(argon2Prefix)) {
+		return errors.New(ErrUnknownHashFormat)
+	}
+
+	p, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return err
+	}
+
+	computed := argon2.IDKey(data, salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return errors.New(ErrHashMismatch)
+	}
+	return nil
+}
+
+func (a *Argon2) NeedsRehash(hash []byte) bool {
+	if !bytes.HasPrefix(hash, []byte(argon2Prefix)) {
+		return true
+	}
+
+	p, _, _, err := decodeArgon2(hash)
+	return err != nil || p != a.params()
+}
+
+func decodeArgon2(hash []byte) (p argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errors.Errorf("Malformed argon2 hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, errors.Errorf("Malformed argon2 hash version: %s", err)
+	} else if version != argon2.Version {
+		return p, nil, nil, errors.Errorf("Unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, errors.Errorf("Malformed argon2 hash parameters: %s", err)
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, errors.New(err)
+	} else if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, errors.New(err)
+	}
+	return p, salt, key, nil
+}
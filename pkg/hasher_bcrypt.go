@@ -0,0 +1,60 @@
+package pkg
+
+import (
+	"bytes"
+
+	"github.com/go-errors/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BCrypt hashes secrets using bcrypt.
+type BCrypt struct {
+	// WorkFactor is the bcrypt cost. It defaults to bcrypt.DefaultCost.
+	WorkFactor int
+}
+
+func (b *BCrypt) Hash(data []byte) ([]byte, error) {
+	hash, err := bcrypt.GenerateFromPassword(data, b.workFactor())
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return hash, nil
+}
+
+func (b *BCrypt) Compare(hash, data []byte) error {
+	if !isBCryptHash(hash) {
+		return errors.New(ErrUnknownHashFormat)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, data); err == bcrypt.ErrMismatchedHashAndPassword {
+		return errors.New(ErrHashMismatch)
+	} else if err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (b *BCrypt) NeedsRehash(hash []byte) bool {
+	if !isBCryptHash(hash) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != b.workFactor()
+}
+
+func (b *BCrypt) workFactor() int {
+	if b.WorkFactor == 0 {
+		return bcrypt.DefaultCost
+	}
+	return b.WorkFactor
+}
+
+func isBCryptHash(hash []byte) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if bytes.HasPrefix(hash, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/go-errors/errors"
+	. "github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashers(t *testing.T) {
+	for k, h := range []Hasher{
+		&BCrypt{WorkFactor: 4},
+		&Argon2{Iterations: 1, Memory: 1024, Parallelism: 1},
+	} {
+		hash, err := h.Hash([]byte("secret"))
+		require.Nil(t, err, "Case %d", k)
+		assert.NotEqual(t, "secret", string(hash), "Case %d", k)
+
+		assert.Nil(t, h.Compare(hash, []byte("secret")), "Case %d", k)
+		assert.True(t, errors.Is(h.Compare(hash, []byte("wrong")), ErrHashMismatch), "Case %d", k)
+		assert.True(t, errors.Is(h.Compare([]byte("secret"), []byte("secret")), ErrUnknownHashFormat), "Case %d", k)
+		assert.False(t, h.NeedsRehash(hash), "Case %d", k)
+		assert.True(t, h.NeedsRehash([]byte("secret")), "Case %d", k)
+	}
+
+	weak, err := (&BCrypt{WorkFactor: 4}).Hash([]byte("secret"))
+	require.Nil(t, err)
+	assert.True(t, (&BCrypt{WorkFactor: 5}).NeedsRehash(weak))
+}
+
+func TestMigratingHasher(t *testing.T) {
+	argon2, err := (&Argon2{Iterations: 1, Memory: 1024, Parallelism: 1}).Hash([]byte("secret"))
+	require.Nil(t, err)
+
+	var rehashed [][]byte
+	h := &MigratingHasher{
+		Hasher: &BCrypt{WorkFactor: 4},
+		Legacy: []Hasher{&Argon2{Iterations: 1, Memory: 1024, Parallelism: 1}},
+		OnRehash: func(oldHash, newHash []byte) {
+			rehashed = append(rehashed, oldHash)
+			assert.Nil(t, (&BCrypt{}).Compare(newHash, []byte("secret")))
+		},
+	}
+
+	current, err := h.Hash([]byte("secret"))
+	require.Nil(t, err)
+
+	for k, c := range []struct {
+		hash   []byte
+		data   string
+		valid  bool
+		rehash bool
+	}{
+		{hash: current, data: "secret", valid: true},
+		{hash: current, data: "wrong"},
+		{hash: argon2, data: "secret", valid: true, rehash: true},
+		{hash: argon2, data: "wrong"},
+		{hash: []byte("secret"), data: "secret", valid: true, rehash: true},
+		{hash: []byte("secret"), data: "wrong"},
+		{hash: []byte{}, data: ""},
+	} {
+		rehashed = nil
+		err := h.Compare(c.hash, []byte(c.data))
+		assert.Equal(t, c.valid, err == nil, "Case %d: %s", k, err)
+		if c.rehash {
+			assert.Equal(t, [][]byte{c.hash}, rehashed, "Case %d", k)
+		} else {
+			assert.Empty(t, rehashed, "Case %d", k)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultHedgePercentile is the latency percentile after which a second read is sent if the hedger does not
+	// say otherwise.
+	DefaultHedgePercentile = 0.95
+
+	hedgeWindow     = 128
+	hedgeMinSamples = 20
+)
+
+// HedgeableStore is implemented by stores whose reads go to a remote backend instead of a local cache. Reads of
+// those stores are idempotent and may be sent twice.
+type HedgeableStore interface {
+	SupportsHedgedReads() bool
+}
+
+// SupportsHedgedReads returns true if reads of the store may be hedged.
+func SupportsHedgedReads(store interface{}) bool {
+	h, ok := store.(HedgeableStore)
+	return ok && h.SupportsHedgedReads()
+}
+
+var (
+	hedgedReads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "hydra",
+		Subsystem: "storage",
+		Name:      "hedged_reads_total",
+		Help:      "Number of reads which were sent a second time because the first took too long, partitioned by operation.",
+	}, []string{"operation"})
+
+	hedgeWins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "hydra",
+		Subsystem: "storage",
+		Name:      "hedge_wins_total",
+		Help:      "Number of hedged reads whose second read answered first, partitioned by operation.",
+	}, []string{"operation"})
+)
+
+func init() {
+	prometheus.MustRegister(hedgedReads, hedgeWins)
+}
+
+// Hedger sends a second read once a read took longer than Percentile of the recent reads of the same
+// operation, and returns whichever answers first. Reads are not hedged until enough of them were observed.
+type Hedger struct {
+	// Percentile is DefaultHedgePercentile if zero.
+	Percentile float64
+
+	// MinDelay is the least time to wait before hedging, so that fast backends are not flooded with reads.
+	MinDelay time.Duration
+
+	windows map[string]*latencyWindow
+	sync.Mutex
+}
+
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+type hedgeResult struct {
+	value  interface{}
+	err    error
+	hedged bool
+}
+
+// Do calls f and calls it a second time if the first call is slow. f must be safe to call concurrently.
+func (h *Hedger) Do(operation string, f func() (interface{}, error)) (interface{}, error) {
+	results := make(chan hedgeResult, 2)
+	attempt := func(hedged bool) {
+		start := time.Now()
+		v, err := f()
+		h.observe(operation, time.Since(start))
+		results <- hedgeResult{value: v, err: err, hedged: hedged}
+	}
+
+	go attempt(false)
+	delay, ok := h.delay(operation)
+	if !ok {
+		r := <-results
+		return r.value, r.err
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case r := <-results:
+		return r.value, r.err
+	case <-timer.C:
+	}
+
+	hedgedReads.WithLabelValues(operation).Inc()
+	go attempt(true)
+	r := <-results
+	if r.hedged {
+		hedgeWins.WithLabelValues(operation).Inc()
+	}
+	return r.value, r.err
+}
+
+// delay returns how long to wait before hedging, or false if too few reads were observed to tell.
+func (h *Hedger) delay(operation string) (time.Duration, bool) {
+	h.Lock()
+	defer h.Unlock()
+
+	w, ok := h.windows[operation]
+	if !ok || len(w.samples) < hedgeMinSamples {
+		return 0, false
+	}
+
+	percentile := h.Percentile
+	if percentile <= 0 || percentile >= 1 {
+		percentile = DefaultHedgePercentile
+	}
+
+	sorted := make([]time.Duration, len(w.samples))
+	copy(sorted, w.samples)
+	sort.Sort(durations(sorted))
+	d := sorted[int(float64(len(sorted)-1)*percentile)]
+	if d < h.MinDelay {
+		d = h.MinDelay
+	}
+	return d, true
+}
+
+func (h *Hedger) observe(operation string, d time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.windows == nil {
+		h.windows = map[string]*latencyWindow{}
+	}
+	w, ok := h.windows[operation]
+	if !ok {
+		w = &latencyWindow{}
+		h.windows[operation] = w
+	}
+
+	if len(w.samples) < hedgeWindow {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % hedgeWindow
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedger(t *testing.T) {
+	h := &Hedger{}
+	var calls int32
+	fast := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Millisecond)
+		return "fast", nil
+	}
+
+	_, ok := h.delay("get")
+	assert.False(t, ok, "reads are not hedged before enough were observed")
+	for i := 0; i < hedgeMinSamples; i++ {
+		v, err := h.Do("get", fast)
+		require.Nil(t, err)
+		assert.Equal(t, "fast", v)
+	}
+	assert.Equal(t, int32(hedgeMinSamples), calls)
+
+	delay, ok := h.delay("get")
+	require.True(t, ok)
+	assert.True(t, delay < time.Millisecond*50, "%s", delay)
+
+	// The first read hangs, the hedged read answers.
+	var first int32 = 1
+	start := time.Now()
+	v, err := h.Do("get", func() (interface{}, error) {
+		if atomic.CompareAndSwapInt32(&first, 1, 0) {
+			time.Sleep(time.Millisecond * 200)
+			return "slow", nil
+		}
+		return "hedged", nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "hedged", v)
+	assert.True(t, time.Since(start) < time.Millisecond*200)
+}
@@ -0,0 +1,197 @@
+package pkg
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+	"github.com/pborman/uuid"
+	"golang.org/x/net/context"
+)
+
+// DefaultLeaseTTL is how long a leader keeps its lease without renewing it if the elector does not say otherwise.
+const DefaultLeaseTTL = time.Second * 15
+
+// Leader tells whether this node runs the periodic jobs which must run on exactly one node of the cluster.
+type Leader interface {
+	IsLeader() bool
+}
+
+// LeaseManager grants named leases. A lease belongs to at most one holder at a time and expires unless its
+// holder renews it. Leases are compared against the clocks of the nodes, which should therefore be synchronized
+// far more precisely than the ttl.
+type LeaseManager interface {
+	// AcquireLease acquires the lease for holder, or renews it if holder already holds it, until ttl passed. It
+	// returns false if another holder's lease did not expire yet.
+	AcquireLease(name, holder string, ttl time.Duration) (bool, error)
+
+	// ReleaseLease releases the lease if holder holds it.
+	ReleaseLease(name, holder string) error
+}
+
+// ErrResourceBusy rejects a change of a resource whose lease another change held for longer than WithLease waits.
+var ErrResourceBusy = &herodot.Error{
+	Err:  errors.New("The resource is being changed by another request, retry later"),
+	Code: http.StatusConflict,
+}
+
+// withLeaseRetryInterval is how long WithLease waits before it asks again for a lease held by someone else.
+const withLeaseRetryInterval = time.Millisecond * 20
+
+// WithLease calls fn while holding the lease name, so that fn never runs concurrently with another fn holding the
+// same lease anywhere in the cluster. It waits at most ttl, DefaultLeaseTTL if zero, for the lease and returns
+// ErrResourceBusy if it does not get it. fn loses the lease if it takes longer than ttl.
+func WithLease(leases LeaseManager, name string, ttl time.Duration, fn func() error) error {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	holder := uuid.New()
+	deadline := time.Now().Add(ttl)
+	for {
+		ok, err := leases.AcquireLease(name, holder, ttl)
+		if err != nil {
+			return err
+		} else if ok {
+			break
+		} else if time.Now().After(deadline) {
+			return errors.New(ErrResourceBusy)
+		}
+		time.Sleep(withLeaseRetryInterval)
+	}
+	defer func() {
+		if err := leases.ReleaseLease(name, holder); err != nil {
+			logger.LogError(err)
+		}
+	}()
+
+	return fn()
+}
+
+// Elector campaigns for a lease and holds it as long as the node is alive. If the leader dies or can not reach
+// the lease manager, its lease expires and another node takes over.
+type Elector struct {
+	Leases LeaseManager
+	Name   string
+
+	// ID identifies this node, a random id is generated if empty.
+	ID string
+
+	// TTL is DefaultLeaseTTL if zero. The lease is renewed every third of the TTL.
+	TTL time.Duration
+
+	expiresAt time.Time
+	sync.RWMutex
+}
+
+// IsLeader returns true if this node holds a lease which did not expire yet.
+func (e *Elector) IsLeader() bool {
+	e.RLock()
+	defer e.RUnlock()
+
+	return time.Now().Before(e.expiresAt)
+}
+
+// Campaign acquires and renews the lease in the background until ctx is done, then releases it.
+func (e *Elector) Campaign(ctx context.Context) {
+	e.Lock()
+	if e.ID == "" {
+		e.ID = uuid.New()
+	}
+	if e.TTL <= 0 {
+		e.TTL = DefaultLeaseTTL
+	}
+	e.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(e.TTL / 3)
+		defer ticker.Stop()
+		for {
+			e.campaign()
+			select {
+			case <-ctx.Done():
+				e.resign()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (e *Elector) campaign() {
+	// The lease is valid for ttl from the moment it was requested, not from the moment the answer arrived.
+	start := time.Now()
+	ok, err := e.Leases.AcquireLease(e.Name, e.ID, e.TTL)
+	if err != nil {
+		logger.LogError(err)
+	}
+
+	wasLeader := e.IsLeader()
+	if ok && err == nil {
+		e.Lock()
+		e.expiresAt = start.Add(e.TTL)
+		e.Unlock()
+		if !wasLeader {
+			logrus.WithField("lease", e.Name).WithField("node", e.ID).Info("Acquired leadership")
+		}
+	} else if wasLeader && err == nil {
+		e.Lock()
+		e.expiresAt = time.Time{}
+		e.Unlock()
+		logrus.WithField("lease", e.Name).WithField("node", e.ID).Warn("Lost leadership")
+	}
+}
+
+func (e *Elector) resign() {
+	if !e.IsLeader() {
+		return
+	}
+
+	e.Lock()
+	e.expiresAt = time.Time{}
+	e.Unlock()
+	if err := e.Leases.ReleaseLease(e.Name, e.ID); err != nil {
+		logger.LogError(err)
+	}
+}
+
+type lease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+// MemoryLeaseManager grants leases within a single process.
+type MemoryLeaseManager struct {
+	leases map[string]lease
+	sync.Mutex
+}
+
+func (m *MemoryLeaseManager) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.leases == nil {
+		m.leases = map[string]lease{}
+	}
+
+	now := time.Now()
+	if l, ok := m.leases[name]; ok && l.holder != holder && now.Before(l.expiresAt) {
+		return false, nil
+	}
+	m.leases[name] = lease{holder: holder, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *MemoryLeaseManager) ReleaseLease(name, holder string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if l, ok := m.leases[name]; ok && l.holder == holder {
+		delete(m.leases, name)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/go-errors/errors"
+)
+
+const dynamoLeasePrefix = "lease#"
+
+// DynamoDBLeaseManager stores one item per lease in the table which the DynamoDB backends share. Leases are acquired
+// with a conditional put, which DynamoDB applies atomically. Released and expired leases are deleted by the table
+// some time after they expired.
+type DynamoDBLeaseManager struct {
+	DB    dynamodbiface.DynamoDBAPI
+	Table string
+}
+
+func (m *DynamoDBLeaseManager) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	item := DynamoDBKey(dynamoLeasePrefix+name, dynamoLeasePrefix+name)
+	item["holder"] = &dynamodb.AttributeValue{S: aws.String(holder)}
+	item["expires_at"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(expiresAt.UnixNano(), 10))}
+	item[DynamoDBTTL] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(expiresAt.Unix()+1, 10))}
+
+	if _, err := m.DB.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(m.Table),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(pk) OR holder = :holder OR expires_at < :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":holder": {S: aws.String(holder)},
+			":now":    {N: aws.String(strconv.FormatInt(now.UnixNano(), 10))},
+		},
+	}); IsDynamoDBConditionFailed(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.New(err)
+	}
+	return true, nil
+}
+
+func (m *DynamoDBLeaseManager) ReleaseLease(name, holder string) error {
+	if _, err := m.DB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:           aws.String(m.Table),
+		Key:                 DynamoDBKey(dynamoLeasePrefix+name, dynamoLeasePrefix+name),
+		ConditionExpression: aws.String("holder = :holder"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":holder": {S: aws.String(holder)},
+		},
+	}); err != nil && !IsDynamoDBConditionFailed(err) {
+		return errors.New(err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package pkg
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// RethinkDBLeaseManager stores one document per lease. Leases are acquired with a single replace, which RethinkDB
+// applies atomically.
+type RethinkDBLeaseManager struct {
+	Session *r.Session
+	Table   r.Term
+}
+
+func (m *RethinkDBLeaseManager) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	res, err := m.Table.Get(name).Replace(func(row r.Term) interface{} {
+		return r.Branch(
+			row.Eq(nil).Or(row.Field("holder").Eq(holder)).Or(row.Field("expires_at").Lt(now)),
+			map[string]interface{}{"id": name, "holder": holder, "expires_at": now.Add(ttl)},
+			row,
+		)
+	}).RunWrite(m.Session)
+	if err != nil {
+		return false, errors.New(err)
+	}
+
+	return res.Inserted+res.Replaced > 0, nil
+}
+
+func (m *RethinkDBLeaseManager) ReleaseLease(name, holder string) error {
+	if _, err := m.Table.GetAll(name).Filter(map[string]interface{}{"holder": holder}).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
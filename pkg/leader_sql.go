@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+var leaseSchemas = map[string][]string{
+	"postgres": {`CREATE TABLE IF NOT EXISTS hydra_lease (
+	name       varchar(255) NOT NULL PRIMARY KEY,
+	holder     varchar(255) NOT NULL,
+	expires_at timestamp NOT NULL
+)`},
+	"mysql": {`CREATE TABLE IF NOT EXISTS hydra_lease (
+	name       varchar(255) NOT NULL PRIMARY KEY,
+	holder     varchar(255) NOT NULL,
+	expires_at datetime NOT NULL
+)`},
+}
+
+var leaseMigrations = []*Migration{
+	{
+		ID: "1",
+		Up: leaseSchemas,
+		Down: map[string][]string{
+			"postgres": {`DROP TABLE IF EXISTS hydra_lease`},
+			"mysql":    {`DROP TABLE IF EXISTS hydra_lease`},
+		},
+	},
+}
+
+// SQLLeaseManager stores one row per lease. A lease is renewed or taken over with a conditional update, and
+// created with an insert which fails if another node created it first.
+type SQLLeaseManager struct {
+	DB     *sql.DB
+	Driver string
+}
+
+// Migrator returns the migrator of the lease table.
+func (m *SQLLeaseManager) Migrator() *Migrator {
+	return &Migrator{DB: m.DB, Driver: m.Driver, Module: "lease", Migrations: leaseMigrations}
+}
+
+func (m *SQLLeaseManager) CreateSchemas() error {
+	_, err := m.Migrator().Up()
+	return err
+}
+
+func (m *SQLLeaseManager) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	res, err := m.DB.Exec(RebindSQL(m.Driver, "UPDATE hydra_lease SET holder=?, expires_at=? WHERE name=? AND (holder=? OR expires_at<?)"),
+		holder, now.Add(ttl), name, holder, now)
+	if err != nil {
+		return false, errors.New(err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, errors.New(err)
+	} else if n > 0 {
+		return true, nil
+	}
+
+	if _, err := m.DB.Exec(RebindSQL(m.Driver, "INSERT INTO hydra_lease (name, holder, expires_at) VALUES (?, ?, ?)"), name, holder, now.Add(ttl)); err == nil {
+		return true, nil
+	}
+
+	// The insert fails if another node holds the lease, anything else is an error.
+	var current string
+	if err := m.DB.QueryRow(RebindSQL(m.Driver, "SELECT holder FROM hydra_lease WHERE name=?"), name).Scan(&current); err != nil {
+		return false, errors.New(err)
+	}
+	return false, nil
+}
+
+func (m *SQLLeaseManager) ReleaseLease(name, holder string) error {
+	if _, err := m.DB.Exec(RebindSQL(m.Driver, "DELETE FROM hydra_lease WHERE name=? AND holder=?"), name, holder); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
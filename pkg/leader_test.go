@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLeaseManager(t *testing.T) {
+	m := &MemoryLeaseManager{}
+
+	ok, err := m.AcquireLease("jobs", "a", time.Hour)
+	require.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = m.AcquireLease("jobs", "b", time.Hour)
+	require.Nil(t, err)
+	assert.False(t, ok, "the lease of a did not expire yet")
+
+	ok, err = m.AcquireLease("jobs", "a", time.Hour)
+	require.Nil(t, err)
+	assert.True(t, ok, "a renews its own lease")
+
+	require.Nil(t, m.ReleaseLease("jobs", "b"))
+	ok, _ = m.AcquireLease("jobs", "b", time.Hour)
+	assert.False(t, ok, "b can not release the lease of a")
+
+	require.Nil(t, m.ReleaseLease("jobs", "a"))
+	ok, _ = m.AcquireLease("jobs", "b", time.Hour)
+	assert.True(t, ok)
+}
+
+func TestElectorFailover(t *testing.T) {
+	m := &MemoryLeaseManager{}
+	a := &Elector{Leases: m, Name: "jobs", ID: "a", TTL: time.Millisecond * 50}
+	b := &Elector{Leases: m, Name: "jobs", ID: "b", TTL: time.Millisecond * 50}
+
+	a.campaign()
+	b.campaign()
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+
+	// a dies and stops renewing its lease.
+	time.Sleep(time.Millisecond * 60)
+	assert.False(t, a.IsLeader())
+
+	b.campaign()
+	assert.True(t, b.IsLeader())
+
+	b.resign()
+	assert.False(t, b.IsLeader())
+	a.campaign()
+	assert.True(t, a.IsLeader())
+}
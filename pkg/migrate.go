@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+// Migration is a versioned schema change. Up and Down map database drivers to the statements which apply and
+// revert the change. Released migrations must never change, add a new migration instead.
+type Migration struct {
+	ID   string
+	Up   map[string][]string
+	Down map[string][]string
+}
+
+// MigrationStatus tells whether and when a migration was applied.
+type MigrationStatus struct {
+	Module    string     `json:"module"`
+	ID        string     `json:"id"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+var migrationSchemas = map[string]string{
+	"postgres": `CREATE TABLE IF NOT EXISTS hydra_migration (
+	module     varchar(255) NOT NULL,
+	id         varchar(255) NOT NULL,
+	applied_at timestamp NOT NULL,
+	PRIMARY KEY (module, id)
+)`,
+	"mysql": `CREATE TABLE IF NOT EXISTS hydra_migration (
+	module     varchar(255) NOT NULL,
+	id         varchar(255) NOT NULL,
+	applied_at datetime NOT NULL,
+	PRIMARY KEY (module, id)
+)`,
+}
+
+// Migrator applies the migrations of a module in order and records them in the hydra_migration table. Every
+// migration runs in a transaction together with its record. PostgreSQL rolls back a migration which failed half
+// way, MySQL commits every schema change right away and leaves it in place.
+type Migrator struct {
+	DB *sql.DB
+
+	// Driver is the name of the database/sql driver, either "postgres" or "mysql".
+	Driver string
+
+	Module     string
+	Migrations []*Migration
+}
+
+// Up applies all migrations which were not applied yet and returns how many were applied.
+func (m *Migrator) Up() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.ID]; ok {
+			continue
+		}
+
+		statements, ok := migration.Up[m.Driver]
+		if !ok {
+			return n, errors.Errorf("Migration %s of %s does not support database driver %s", migration.ID, m.Module, m.Driver)
+		}
+		if err := m.run(statements, "INSERT INTO hydra_migration (module, id, applied_at) VALUES (?, ?, ?)", m.Module, migration.ID, time.Now().UTC()); err != nil {
+			return n, errors.Errorf("Could not apply migration %s of %s: %s", migration.ID, m.Module, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Down reverts the last steps applied migrations, all of them if steps is zero, and returns how many were reverted.
+func (m *Migrator) Down(steps int) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for i := len(m.Migrations) - 1; i >= 0 && (steps <= 0 || n < steps); i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.ID]; !ok {
+			continue
+		}
+
+		statements, ok := migration.Down[m.Driver]
+		if !ok {
+			return n, errors.Errorf("Migration %s of %s does not support database driver %s", migration.ID, m.Module, m.Driver)
+		}
+		if err := m.run(statements, "DELETE FROM hydra_migration WHERE module = ? AND id = ?", m.Module, migration.ID); err != nil {
+			return n, errors.Errorf("Could not revert migration %s of %s: %s", migration.ID, m.Module, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Status returns the status of every migration in order.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var status []MigrationStatus
+	for _, migration := range m.Migrations {
+		s := MigrationStatus{Module: m.Module, ID: migration.ID}
+		if at, ok := applied[migration.ID]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+func (m *Migrator) applied() (map[string]time.Time, error) {
+	schema, ok := migrationSchemas[m.Driver]
+	if !ok {
+		return nil, errors.Errorf("Database driver %s is not supported", m.Driver)
+	} else if _, err := m.DB.Exec(schema); err != nil {
+		return nil, errors.New(err)
+	}
+
+	rows, err := m.DB.Query(RebindSQL(m.Driver, "SELECT id, applied_at FROM hydra_migration WHERE module = ?"), m.Module)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	applied := map[string]time.Time{}
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, errors.New(err)
+		}
+		applied[id] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	}
+	return applied, nil
+}
+
+func (m *Migrator) run(statements []string, record string, args ...interface{}) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return errors.New(err)
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return errors.New(err)
+		}
+	}
+	if _, err := tx.Exec(RebindSQL(m.Driver, record), args...); err != nil {
+		tx.Rollback()
+		return errors.New(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
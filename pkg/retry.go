@@ -5,6 +5,7 @@ import (
 
 	"github.com/Sirupsen/logrus"
 	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
 )
 
 func Retry(maxWait time.Duration, failAfter time.Duration, f func() error) (err error) {
@@ -22,7 +23,7 @@ func Retry(maxWait time.Duration, failAfter time.Duration, f func() error) (err
 			retryStart = time.Now()
 		}
 
-		LogError(err)
+		logger.LogError(err)
 		logrus.Infof("Retrying in %f seconds...", loopWait.Seconds())
 		time.Sleep(loopWait)
 		loopWait = loopWait * time.Duration(int64(2))
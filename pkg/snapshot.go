@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/logger"
+	"golang.org/x/net/context"
+)
+
+// Snapshotter is implemented by in-memory managers whose state can be written to disk and read back.
+type Snapshotter interface {
+	// Snapshot returns the state of the manager encoded as JSON.
+	Snapshot() ([]byte, error)
+
+	// Restore replaces the state of the manager with a snapshot.
+	Restore(snapshot []byte) error
+}
+
+// SnapshotFile persists the state of in-memory managers to a single JSON file, so that deployments without a
+// database survive restarts. The file is replaced atomically, a crash while saving leaves the previous snapshot
+// intact.
+type SnapshotFile struct {
+	Path string
+
+	// Snapshotters are stored under their name in the file.
+	Snapshotters map[string]Snapshotter
+
+	sync.Mutex
+}
+
+// Load restores all snapshotters from the file. It does nothing if the file does not exist yet.
+func (f *SnapshotFile) Load() error {
+	f.Lock()
+	defer f.Unlock()
+
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.New(err)
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return errors.Errorf("Could not decode snapshot %s: %s", f.Path, err)
+	}
+
+	for name, s := range f.Snapshotters {
+		section, ok := sections[name]
+		if !ok {
+			continue
+		}
+		if err := s.Restore(section); err != nil {
+			return errors.Errorf("Could not restore %s from snapshot %s: %s", name, f.Path, err)
+		}
+	}
+	return nil
+}
+
+// Save writes the state of all snapshotters to a temporary file next to Path and renames it to Path.
+func (f *SnapshotFile) Save() error {
+	f.Lock()
+	defer f.Unlock()
+
+	sections := map[string]json.RawMessage{}
+	for name, s := range f.Snapshotters {
+		section, err := s.Snapshot()
+		if err != nil {
+			return err
+		}
+		sections[name] = section
+	}
+
+	data, err := json.Marshal(sections)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), "."+filepath.Base(f.Path))
+	if err != nil {
+		return errors.New(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// The snapshot contains client secret hashes and private keys.
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return errors.New(err)
+	} else if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.New(err)
+	} else if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.New(err)
+	} else if err := tmp.Close(); err != nil {
+		return errors.New(err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.Path); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// Start saves a snapshot every interval until ctx is done.
+func (f *SnapshotFile) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := f.Save(); err != nil {
+					logger.LogError(err)
+				} else {
+					logrus.Debugf("Saved snapshot to %s", f.Path)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package pkg_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/ory-am/hydra/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapSnapshotter struct {
+	values map[string]string
+}
+
+func (m *mapSnapshotter) Snapshot() ([]byte, error) {
+	return json.Marshal(m.values)
+}
+
+func (m *mapSnapshotter) Restore(snapshot []byte) error {
+	m.values = map[string]string{}
+	return json.Unmarshal(snapshot, &m.values)
+}
+
+func TestSnapshotFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hydra-snapshot")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.json")
+
+	clients := &mapSnapshotter{}
+	f := &SnapshotFile{Path: path, Snapshotters: map[string]Snapshotter{"clients": clients}}
+	require.Nil(t, f.Load(), "a missing snapshot is not an error")
+
+	clients.values = map[string]string{"foo": "bar"}
+	require.Nil(t, f.Save())
+	clients.values = map[string]string{"foo": "baz"}
+	require.Nil(t, f.Save())
+
+	info, err := os.Stat(path)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, files, 1, "temporary files are removed")
+
+	restored := &mapSnapshotter{}
+	g := &SnapshotFile{Path: path, Snapshotters: map[string]Snapshotter{"clients": restored, "keys": &mapSnapshotter{}}}
+	require.Nil(t, g.Load())
+	assert.Equal(t, map[string]string{"foo": "baz"}, restored.values)
+}
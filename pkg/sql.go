@@ -0,0 +1,27 @@
+package pkg
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// RebindSQL replaces the ? placeholders of a query with the placeholders of the driver, so that queries can be
+// written once for MySQL and PostgreSQL.
+func RebindSQL(driver, query string) string {
+	if driver != "postgres" {
+		return query
+	}
+
+	var b bytes.Buffer
+	var n int
+	for i := 0; i < len(query); i++ {
+		if query[i] != '?' {
+			b.WriteByte(query[i])
+			continue
+		}
+		n++
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+	}
+	return b.String()
+}
@@ -56,7 +56,9 @@ func (s *SuperAgent) Get(o interface{}) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode == http.StatusNotFound {
+		return errors.New(ErrNotFound)
+	} else if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
 		return errors.Errorf("Expected status code %d, got %d.\n%s\n", http.StatusOK, resp.StatusCode, body)
 	} else if err := json.NewDecoder(resp.Body).Decode(o); err != nil {
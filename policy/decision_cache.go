@@ -0,0 +1,199 @@
+package policy
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+)
+
+const (
+	// DefaultDecisionCacheTTL is how long a DecisionCache keeps a decision if TTL is not set.
+	DefaultDecisionCacheTTL = time.Second * 5
+
+	// DefaultDecisionCacheSize is how many decisions a DecisionCache keeps if Size is not set.
+	DefaultDecisionCacheSize = 16384
+)
+
+// DecisionCache remembers the decisions of a warden for TTL, so that a resource server which asks for the same
+// subject, action, resource and context over and over again does not make the warden evaluate the policies every
+// time. Only allowed and denied requests are cached, errors of the warden are not.
+//
+// Decisions are stale once policies, or the groups and labels they are resolved through, change. Watch the
+// changefeeds of these tables to drop the cache whenever they change. TTL bounds how long a decision is served
+// while a feed is down.
+type DecisionCache struct {
+	Warden ladon.Warden
+
+	// TTL defaults to DefaultDecisionCacheTTL.
+	TTL time.Duration
+
+	// Size defaults to DefaultDecisionCacheSize. When the cache is full, the decision which was used least recently
+	// is evicted.
+	Size int
+
+	entries    map[decisionKey]*list.Element
+	order      *list.List
+	generation uint64
+	sync.Mutex
+}
+
+type decisionKey struct {
+	subject  string
+	action   string
+	resource string
+	context  [sha256.Size]byte
+}
+
+type decision struct {
+	key       decisionKey
+	allowed   bool
+	forced    bool
+	expiresAt time.Time
+}
+
+// err returns a new error for every hit, so that callers do not share the stack of the cached one.
+func (d *decision) err() error {
+	if d.allowed {
+		return nil
+	} else if d.forced {
+		return errors.New(ladon.ErrRequestForcefullyDenied)
+	}
+	return errors.New(ladon.ErrRequestDenied)
+}
+
+// IsAllowed returns the cached decision of the request, or asks the warden if there is none.
+func (c *DecisionCache) IsAllowed(r *ladon.Request) error {
+	key, ok := newDecisionKey(r)
+	if !ok {
+		observeDecisionLookup(false)
+		return c.Warden.IsAllowed(r)
+	}
+
+	c.Lock()
+	if e, ok := c.entries[key]; ok {
+		d := e.Value.(*decision)
+		if time.Now().Before(d.expiresAt) {
+			c.order.MoveToFront(e)
+			c.Unlock()
+			observeDecisionLookup(true)
+			return d.err()
+		}
+		c.remove(e)
+	}
+	generation := c.generation
+	c.Unlock()
+	observeDecisionLookup(false)
+
+	err := c.Warden.IsAllowed(r)
+	if err != nil && !errors.Is(err, ladon.ErrRequestDenied) && !errors.Is(err, ladon.ErrRequestForcefullyDenied) {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if generation != c.generation {
+		// The cache was invalidated while the warden decided, the decision might already be stale.
+		return err
+	}
+
+	c.init()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.order.PushFront(&decision{
+		key:       key,
+		allowed:   err == nil,
+		forced:    errors.Is(err, ladon.ErrRequestForcefullyDenied),
+		expiresAt: time.Now().Add(c.ttl()),
+	})
+	for c.order.Len() > c.size() {
+		c.remove(c.order.Back())
+		decisionCacheEvictions.Inc()
+	}
+	decisionCacheSize.Set(float64(c.order.Len()))
+	return err
+}
+
+// Invalidate drops all cached decisions.
+func (c *DecisionCache) Invalidate() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	c.entries = nil
+	c.order = nil
+	decisionCacheInvalidations.Inc()
+	decisionCacheSize.Set(0)
+}
+
+// Watch drops all cached decisions whenever feed reports a change, and when feed reconnects because changes might
+// have been missed while it was down. A single change can affect the decisions of any subject, because policies
+// match subjects by patterns.
+func (c *DecisionCache) Watch(feed *pkg.ChangeFeed) {
+	feed.Subscribe(func() error {
+		c.Invalidate()
+		return nil
+	}, func(*pkg.Change) error {
+		c.Invalidate()
+		return nil
+	})
+}
+
+// Len returns the number of cached decisions.
+func (c *DecisionCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.order == nil {
+		return 0
+	}
+	return c.order.Len()
+}
+
+func (c *DecisionCache) init() {
+	if c.entries == nil {
+		c.entries = map[decisionKey]*list.Element{}
+		c.order = list.New()
+	}
+}
+
+func (c *DecisionCache) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*decision).key)
+}
+
+func (c *DecisionCache) ttl() time.Duration {
+	if c.TTL == 0 {
+		return DefaultDecisionCacheTTL
+	}
+	return c.TTL
+}
+
+func (c *DecisionCache) size() int {
+	if c.Size == 0 {
+		return DefaultDecisionCacheSize
+	}
+	return c.Size
+}
+
+// newDecisionKey returns the cache key of a request. The context is part of the key because conditions decide on
+// it, requests whose context can not be encoded are not cached.
+func newDecisionKey(r *ladon.Request) (decisionKey, bool) {
+	context, err := json.Marshal(r.Context)
+	if err != nil {
+		return decisionKey{}, false
+	}
+
+	return decisionKey{
+		subject:  r.Subject,
+		action:   r.Action,
+		resource: r.Resource,
+		context:  sha256.Sum256(context),
+	}, true
+}
@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingWarden struct {
+	ladon.Warden
+	calls int
+}
+
+func (w *countingWarden) IsAllowed(r *ladon.Request) error {
+	w.calls++
+	return w.Warden.IsAllowed(r)
+}
+
+func TestDecisionCache(t *testing.T) {
+	policies := &ladon.MemoryManager{Policies: map[string]ladon.Policy{
+		"1": &ladon.DefaultPolicy{
+			ID:        "1",
+			Subjects:  []string{"peter"},
+			Resources: []string{"rn:articles:<.*>"},
+			Actions:   []string{"get"},
+			Effect:    ladon.AllowAccess,
+		},
+		"2": &ladon.DefaultPolicy{
+			ID:        "2",
+			Subjects:  []string{"peter"},
+			Resources: []string{"rn:articles:secret"},
+			Actions:   []string{"get"},
+			Effect:    ladon.DenyAccess,
+		},
+	}}
+	w := &countingWarden{Warden: &Warden{Manager: policies, Matchers: NewMatcherCache(16)}}
+	c := &DecisionCache{Warden: w, TTL: time.Millisecond * 50, Size: 2}
+
+	allowed := &ladon.Request{Subject: "peter", Resource: "rn:articles:1", Action: "get"}
+	forbidden := &ladon.Request{Subject: "peter", Resource: "rn:articles:secret", Action: "get"}
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, c.IsAllowed(allowed))
+		assert.True(t, errors.Is(c.IsAllowed(forbidden), ladon.ErrRequestForcefullyDenied))
+	}
+	assert.Equal(t, 2, w.calls)
+
+	// The context is part of the key.
+	assert.Nil(t, c.IsAllowed(&ladon.Request{Subject: "peter", Resource: "rn:articles:1", Action: "get", Context: ladon.Context{"amr": []string{"pwd"}}}))
+	assert.Equal(t, 3, w.calls)
+	assert.Equal(t, 2, c.Len(), "The least recently used decision is evicted")
+
+	// Changes of the policies drop the cache.
+	delete(policies.Policies, "2")
+	c.Invalidate()
+	assert.Equal(t, 0, c.Len())
+	assert.Nil(t, c.IsAllowed(forbidden))
+	assert.Equal(t, 4, w.calls)
+
+	// Decisions expire after TTL.
+	time.Sleep(time.Millisecond * 60)
+	assert.Nil(t, c.IsAllowed(forbidden))
+	assert.Equal(t, 5, w.calls)
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/hydra/firewall"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/label"
+	"github.com/ory-am/hydra/pagination"
+	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/ladon"
 	"github.com/pborman/uuid"
 )
@@ -24,6 +27,13 @@ type Handler struct {
 	Manager ladon.Manager
 	H       herodot.Herodot
 	W       firewall.Firewall
+
+	// Labels resolves the policies deleted by label and are removed with their policies. Deletion by label is
+	// disabled if Labels is nil.
+	Labels label.Manager
+
+	// Preconditions makes the If-Match check and the write atomic, see pkg.Preconditions.
+	Preconditions *pkg.Preconditions
 }
 
 func (h *Handler) SetRoutes(r *httprouter.Router) {
@@ -31,6 +41,8 @@ func (h *Handler) SetRoutes(r *httprouter.Router) {
 	r.GET(endpoint, h.Find)
 	r.GET(endpoint+"/:id", h.Get)
 	r.DELETE(endpoint+"/:id", h.Delete)
+	r.DELETE(endpoint, h.DeleteByLabel)
+	r.PATCH(endpoint, h.Bulk)
 }
 
 func (h *Handler) Find(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -48,12 +60,37 @@ func (h *Handler) Find(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 		return
 	}
 
+	page, err := pagination.Parse(r, "id", "description", "effect")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
 	policies, err := h.Manager.FindPoliciesForSubject(subject)
 	if err != nil {
 		h.H.WriteError(ctx, w, r, errors.New(err))
 		return
 	}
-	h.H.Write(ctx, w, r, policies)
+
+	indices, total := page.Apply(len(policies), func(i int, field string) string {
+		switch field {
+		case "id":
+			return policies[i].GetID()
+		case "description":
+			return policies[i].GetDescription()
+		case "effect":
+			return policies[i].GetEffect()
+		}
+		return ""
+	})
+
+	res := make(ladon.Policies, len(indices))
+	for k, i := range indices {
+		res[k] = policies[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -75,15 +112,22 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
+	if err := h.create(&p); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+	h.H.WriteCreated(ctx, w, r, "/policies/"+p.ID, &p)
+}
+
+func (h *Handler) create(p *ladon.DefaultPolicy) error {
 	if p.ID == "" {
 		p.ID = uuid.New()
 	}
 
-	if err := h.Manager.Create(&p); err != nil {
-		h.H.WriteError(ctx, w, r, errors.New(err))
-		return
+	if err := h.Manager.Create(p); err != nil {
+		return errors.New(err)
 	}
-	h.H.WriteCreated(ctx, w, r, "/policies/"+p.ID, &p)
+	return nil
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -102,6 +146,7 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 		h.H.WriteError(ctx, w, r, errors.New(err))
 		return
 	}
+	pkg.WriteETag(w, policy)
 	h.H.Write(ctx, w, r, policy)
 }
 
@@ -117,10 +162,156 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
-	if err := h.Manager.Delete(id); err != nil {
-		h.H.WriteError(ctx, w, r, errors.New("Could not delete client"))
+	if err := h.write(id, r.Header.Get("If-Match"), func() error { return h.delete(id) }); err != nil {
+		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Bulk creates, updates and deletes many policies in one request, see pkg.BulkOperation. Every operation is
+// authorized and applied on its own, in the order of the request, and has its own result in the response.
+func (h *Handler) Bulk(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+
+	ops, err := pkg.DecodeBulkOperations(r)
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	res := &pkg.BulkResponse{Results: []*pkg.BulkResult{}}
+	for _, op := range ops {
+		resource := policyResource
+		if op.Action != pkg.BulkCreate {
+			resource = fmt.Sprintf(policiesResource, op.ID)
+		}
+
+		if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+			Resource: resource,
+			Action:   op.Action,
+		}, scope); err != nil {
+			res.Fail(op.ID, err)
+			continue
+		}
+
+		if op.Action == pkg.BulkDelete {
+			if err := h.write(op.ID, op.IfMatch, func() error { return h.delete(op.ID) }); err != nil {
+				res.Fail(op.ID, err)
+			} else {
+				res.Succeed(op.ID, http.StatusNoContent, nil)
+			}
+			continue
+		}
+
+		p := &ladon.DefaultPolicy{Conditions: ladon.Conditions{}}
+		if err := json.Unmarshal(op.Data, p); err != nil {
+			res.Reject(op.ID, errors.New(err))
+			continue
+		}
+
+		if op.Action == pkg.BulkCreate {
+			if err := h.create(p); err != nil {
+				res.Fail(p.ID, err)
+			} else {
+				res.Succeed(p.ID, http.StatusCreated, p)
+			}
+		} else if err := h.write(op.ID, op.IfMatch, func() error { return h.replace(op.ID, p) }); err != nil {
+			res.Fail(op.ID, err)
+		} else {
+			res.Succeed(op.ID, http.StatusOK, p)
+		}
+	}
+
+	h.H.Write(ctx, w, r, res)
+}
+
+// delete deletes a policy and its labels.
+func (h *Handler) delete(id string) error {
+	if err := h.Manager.Delete(id); err != nil {
+		return errors.New(err)
+	}
+
+	if h.Labels != nil {
+		return h.Labels.DeleteLabels(label.KindPolicies, id)
+	}
+	return nil
+}
+
+// write calls write if the If-Match header of a write to a policy matches the policy, see pkg.Preconditions.
+func (h *Handler) write(id, ifMatch string, write func() error) error {
+	return h.Preconditions.Write(fmt.Sprintf(policiesResource, id), ifMatch, func() (interface{}, error) {
+		p, err := h.Manager.Get(id)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+		return p, nil
+	}, write)
+}
+
+// replace updates a policy by deleting and recreating it, because ladon can not update policies. The previous policy
+// is restored if the new one can not be created.
+func (h *Handler) replace(id string, p *ladon.DefaultPolicy) error {
+	previous, err := h.Manager.Get(id)
+	if err != nil {
+		return errors.New(err)
+	}
+
+	if err := h.Manager.Delete(id); err != nil {
+		return errors.New(err)
+	}
+
+	p.ID = id
+	if err := h.Manager.Create(p); err != nil {
+		if rerr := h.Manager.Create(previous); rerr != nil {
+			return errors.Errorf("Could not update policy %s: %s, restoring the previous policy failed as well: %s", id, err, rerr)
+		}
+		return errors.New(err)
+	}
+	return nil
+}
+
+// DeleteByLabel deletes every policy carrying the label given by the query parameter label, for example
+// label=team=payments.
+func (h *Handler) DeleteByLabel(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+
+	key, value, ok := label.ParseSelector(label.SelectorPrefix + r.URL.Query().Get("label"))
+	if !ok {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Query parameter label must look like key=value"))
+		return
+	} else if !pkg.BulkConfirmed(r) {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, pkg.ErrBulkNotConfirmed)
+		return
+	} else if h.Labels == nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusNotImplemented, errors.New("Policy labels are not available"))
+		return
+	}
+
+	ids, err := h.Labels.FindIDs(label.KindPolicies, key, value)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	// Every policy is checked before the first one is deleted, a single denied policy aborts the whole deletion.
+	for _, id := range ids {
+		if _, err := h.W.HTTPActionAllowed(ctx, r, &ladon.Request{
+			Resource: fmt.Sprintf(policiesResource, id),
+			Action:   "delete",
+		}, scope); err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+	}
+
+	for _, id := range ids {
+		if err := h.write(id, "", func() error { return h.delete(id) }); err != nil {
+			h.H.WriteError(ctx, w, r, err)
+			return
+		}
+	}
+
+	h.H.Write(ctx, w, r, &pkg.BulkDeletion{Deleted: len(ids)})
+}
@@ -0,0 +1,196 @@
+package policy
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+// RethinkManager keeps every policy in memory and follows the changes of Table, so that policy decisions do not
+// have to query the database. Writes go to the database and reach the cache through the changefeed.
+type RethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+	sync.RWMutex
+
+	Policies map[string]ladon.Policy
+
+	// Feed streams the changes of Table. It is created by Watch if nil and can be subscribed to by other
+	// subsystems which need to react to policy changes.
+	Feed *pkg.ChangeFeed
+}
+
+type rethinkSchema struct {
+	ID          string   `gorethink:"id"`
+	Description string   `gorethink:"description"`
+	Subjects    []string `gorethink:"subjects"`
+	Effect      string   `gorethink:"effect"`
+	Resources   []string `gorethink:"resources"`
+	Actions     []string `gorethink:"actions"`
+	Conditions  []byte   `gorethink:"conditions"`
+}
+
+func (m *RethinkManager) Create(policy ladon.Policy) error {
+	s, err := newRethinkSchema(policy)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.Table.Insert(s).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// Update replaces a policy.
+func (m *RethinkManager) Update(policy ladon.Policy) error {
+	s, err := newRethinkSchema(policy)
+	if err != nil {
+		return err
+	}
+
+	res, err := m.Table.Get(s.ID).Replace(s).RunWrite(m.Session)
+	if err != nil {
+		return errors.New(err)
+	} else if res.Replaced+res.Unchanged == 0 {
+		return errors.New(pkg.ErrNotFound)
+	}
+	return nil
+}
+
+func (m *RethinkManager) Get(id string) (ladon.Policy, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	p, ok := m.Policies[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return p, nil
+}
+
+func (m *RethinkManager) Delete(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *RethinkManager) FindPoliciesForSubject(subject string) (ladon.Policies, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return filterBySubject(m.Policies, subject)
+}
+
+// GetPolicies returns all policies.
+func (m *RethinkManager) GetPolicies() (ladon.Policies, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	policies := ladon.Policies{}
+	for _, p := range m.Policies {
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+func (m *RethinkManager) ColdStart() error {
+	rows, err := m.Table.Run(m.Session)
+	if err != nil {
+		return errors.New(err)
+	}
+	defer rows.Close()
+
+	policies := map[string]ladon.Policy{}
+	var s *rethinkSchema
+	for rows.Next(&s) {
+		p, err := s.toPolicy()
+		if err != nil {
+			return err
+		}
+		policies[p.ID] = p
+		s = nil
+	}
+
+	if rows.Err() != nil {
+		return errors.New(rows.Err())
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Policies = policies
+	return nil
+}
+
+func (m *RethinkManager) Watch(ctx context.Context) {
+	if m.Feed == nil {
+		m.Feed = &pkg.ChangeFeed{Session: m.Session, Table: m.Table}
+	}
+
+	m.Feed.Subscribe(m.ColdStart, func(change *pkg.Change) error {
+		var newVal, oldVal *rethinkSchema
+		if err := change.Decode(&oldVal, &newVal); err != nil {
+			return err
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		if m.Policies == nil {
+			m.Policies = map[string]ladon.Policy{}
+		}
+		if oldVal != nil {
+			delete(m.Policies, oldVal.ID)
+		}
+		if newVal != nil {
+			p, err := newVal.toPolicy()
+			if err != nil {
+				return err
+			}
+			m.Policies[p.ID] = p
+		}
+		return nil
+	})
+	m.Feed.Start(ctx)
+}
+
+func newRethinkSchema(p ladon.Policy) (*rethinkSchema, error) {
+	conditions, err := json.Marshal(p.GetConditions())
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	return &rethinkSchema{
+		ID:          p.GetID(),
+		Description: p.GetDescription(),
+		Subjects:    p.GetSubjects(),
+		Effect:      p.GetEffect(),
+		Resources:   p.GetResources(),
+		Actions:     p.GetActions(),
+		Conditions:  conditions,
+	}, nil
+}
+
+func (s *rethinkSchema) toPolicy() (*ladon.DefaultPolicy, error) {
+	conditions := ladon.Conditions{}
+	if len(s.Conditions) > 0 {
+		if err := json.Unmarshal(s.Conditions, &conditions); err != nil {
+			return nil, errors.New(err)
+		}
+	}
+
+	return &ladon.DefaultPolicy{
+		ID:          s.ID,
+		Description: s.Description,
+		Subjects:    s.Subjects,
+		Effect:      s.Effect,
+		Resources:   s.Resources,
+		Actions:     s.Actions,
+		Conditions:  conditions,
+	}, nil
+}
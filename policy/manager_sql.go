@@ -0,0 +1,408 @@
+package policy
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+)
+
+// sqlSchemas create the policy tables. Subjects, resources and actions are stored in tables of their own, which
+// are indexed by their template so that policies which name a subject or resource literally are found without
+// evaluating any regular expression.
+var sqlSchemas = map[string][]string{
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS hydra_policy (
+	id          varchar(255) NOT NULL PRIMARY KEY,
+	description text NOT NULL,
+	effect      varchar(255) NOT NULL,
+	conditions  text NOT NULL
+)`,
+		`CREATE TABLE IF NOT EXISTS hydra_policy_subject (
+	policy    varchar(255) NOT NULL REFERENCES hydra_policy (id) ON DELETE CASCADE,
+	template  varchar(511) NOT NULL,
+	has_regex bool NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS hydra_policy_subject_template_idx ON hydra_policy_subject (template)`,
+		`CREATE INDEX IF NOT EXISTS hydra_policy_subject_regex_idx ON hydra_policy_subject (has_regex)`,
+		`CREATE TABLE IF NOT EXISTS hydra_policy_resource (
+	policy    varchar(255) NOT NULL REFERENCES hydra_policy (id) ON DELETE CASCADE,
+	template  varchar(511) NOT NULL,
+	has_regex bool NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS hydra_policy_resource_template_idx ON hydra_policy_resource (template)`,
+		`CREATE INDEX IF NOT EXISTS hydra_policy_resource_regex_idx ON hydra_policy_resource (has_regex)`,
+		`CREATE TABLE IF NOT EXISTS hydra_policy_action (
+	policy    varchar(255) NOT NULL REFERENCES hydra_policy (id) ON DELETE CASCADE,
+	template  varchar(511) NOT NULL,
+	has_regex bool NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS hydra_policy_action_policy_idx ON hydra_policy_action (policy)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS hydra_policy (
+	id          varchar(255) NOT NULL PRIMARY KEY,
+	description text NOT NULL,
+	effect      varchar(255) NOT NULL,
+	conditions  text NOT NULL
+)`,
+		`CREATE TABLE IF NOT EXISTS hydra_policy_subject (
+	policy    varchar(255) NOT NULL,
+	template  varchar(511) NOT NULL,
+	has_regex bool NOT NULL,
+	INDEX hydra_policy_subject_template_idx (template),
+	INDEX hydra_policy_subject_regex_idx (has_regex),
+	FOREIGN KEY (policy) REFERENCES hydra_policy (id) ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS hydra_policy_resource (
+	policy    varchar(255) NOT NULL,
+	template  varchar(511) NOT NULL,
+	has_regex bool NOT NULL,
+	INDEX hydra_policy_resource_template_idx (template),
+	INDEX hydra_policy_resource_regex_idx (has_regex),
+	FOREIGN KEY (policy) REFERENCES hydra_policy (id) ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS hydra_policy_action (
+	policy    varchar(255) NOT NULL,
+	template  varchar(511) NOT NULL,
+	has_regex bool NOT NULL,
+	FOREIGN KEY (policy) REFERENCES hydra_policy (id) ON DELETE CASCADE
+)`,
+	},
+}
+
+// migrations are the versions of the policy tables. The first version creates the tables only if they do not
+// exist, so that databases which were set up before migrations were versioned are adopted as they are.
+var migrations = []*pkg.Migration{
+	{
+		ID: "1",
+		Up: sqlSchemas,
+		Down: map[string][]string{
+			"postgres": dropPolicyTables,
+			"mysql":    dropPolicyTables,
+		},
+	},
+}
+
+var dropPolicyTables = []string{
+	`DROP TABLE IF EXISTS hydra_policy_action`,
+	`DROP TABLE IF EXISTS hydra_policy_resource`,
+	`DROP TABLE IF EXISTS hydra_policy_subject`,
+	`DROP TABLE IF EXISTS hydra_policy`,
+}
+
+// SQLManager stores policies in PostgreSQL or MySQL. Unlike RethinkManager it does not cache policies, every
+// lookup is answered by the database.
+type SQLManager struct {
+	DB *sql.DB
+
+	// Driver is the name of the database/sql driver, either "postgres" or "mysql".
+	Driver string
+}
+
+// Migrator returns the migrator of the policy tables.
+func (m *SQLManager) Migrator() *pkg.Migrator {
+	return &pkg.Migrator{DB: m.DB, Driver: m.Driver, Module: "policy", Migrations: migrations}
+}
+
+// CreateSchemas applies all migrations of the policy tables which were not applied yet.
+func (m *SQLManager) CreateSchemas() error {
+	_, err := m.Migrator().Up()
+	return err
+}
+
+func (m *SQLManager) Create(policy ladon.Policy) error {
+	conditions, err := json.Marshal(policy.GetConditions())
+	if err != nil {
+		return errors.New(err)
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return errors.New(err)
+	}
+
+	if _, err := tx.Exec(m.rebind("INSERT INTO hydra_policy (id, description, effect, conditions) VALUES (?, ?, ?, ?)"),
+		policy.GetID(), policy.GetDescription(), policy.GetEffect(), string(conditions)); err != nil {
+		tx.Rollback()
+		return errors.New(err)
+	}
+
+	if err := m.insertTemplates(tx, policy); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+// Update replaces a policy.
+func (m *SQLManager) Update(policy ladon.Policy) error {
+	conditions, err := json.Marshal(policy.GetConditions())
+	if err != nil {
+		return errors.New(err)
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return errors.New(err)
+	}
+
+	res, err := tx.Exec(m.rebind("UPDATE hydra_policy SET description = ?, effect = ?, conditions = ? WHERE id = ?"),
+		policy.GetDescription(), policy.GetEffect(), string(conditions), policy.GetID())
+	if err != nil {
+		tx.Rollback()
+		return errors.New(err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		tx.Rollback()
+		return errors.New(err)
+	} else if n == 0 {
+		// MySQL does not count rows whose values did not change, so the policy may still exist.
+		var id string
+		if err := tx.QueryRow(m.rebind("SELECT id FROM hydra_policy WHERE id = ?"), policy.GetID()).Scan(&id); err == sql.ErrNoRows {
+			tx.Rollback()
+			return errors.New(pkg.ErrNotFound)
+		} else if err != nil {
+			tx.Rollback()
+			return errors.New(err)
+		}
+	}
+
+	for _, table := range templateTables {
+		if _, err := tx.Exec(m.rebind("DELETE FROM "+table+" WHERE policy = ?"), policy.GetID()); err != nil {
+			tx.Rollback()
+			return errors.New(err)
+		}
+	}
+
+	if err := m.insertTemplates(tx, policy); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) Get(id string) (ladon.Policy, error) {
+	policies, err := m.load([]string{id})
+	if err != nil {
+		return nil, err
+	} else if len(policies) == 0 {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return policies[0], nil
+}
+
+func (m *SQLManager) Delete(id string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return errors.New(err)
+	}
+
+	// The templates are deleted explicitly because MySQL ignores foreign keys of MyISAM tables.
+	for _, table := range templateTables {
+		if _, err := tx.Exec(m.rebind("DELETE FROM "+table+" WHERE policy = ?"), id); err != nil {
+			tx.Rollback()
+			return errors.New(err)
+		}
+	}
+
+	if _, err := tx.Exec(m.rebind("DELETE FROM hydra_policy WHERE id = ?"), id); err != nil {
+		tx.Rollback()
+		return errors.New(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) FindPoliciesForSubject(subject string) (ladon.Policies, error) {
+	return m.find("hydra_policy_subject", subject)
+}
+
+// FindPoliciesForResource returns the policies which apply to the resource.
+func (m *SQLManager) FindPoliciesForResource(resource string) (ladon.Policies, error) {
+	return m.find("hydra_policy_resource", resource)
+}
+
+// GetPolicies returns all policies.
+func (m *SQLManager) GetPolicies() (ladon.Policies, error) {
+	rows, err := m.DB.Query("SELECT id FROM hydra_policy")
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	ids, err := scanStrings(rows)
+	if err != nil {
+		return nil, err
+	}
+	return m.load(ids)
+}
+
+var templateTables = []string{"hydra_policy_subject", "hydra_policy_resource", "hydra_policy_action"}
+
+// find looks up the policies whose templates in table match value. Literal templates are found by the index, only
+// regular expressions are evaluated.
+func (m *SQLManager) find(table, value string) (ladon.Policies, error) {
+	rows, err := m.DB.Query(m.rebind("SELECT policy, template, has_regex FROM "+table+" WHERE (has_regex = ? AND template = ?) OR has_regex = ?"), false, value, true)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	var seen = map[string]bool{}
+	for rows.Next() {
+		var id, template string
+		var hasRegex bool
+		if err := rows.Scan(&id, &template, &hasRegex); err != nil {
+			return nil, errors.New(err)
+		} else if seen[id] {
+			continue
+		}
+
+		if hasRegex {
+			if ok, err := matches([]string{template}, value); err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+		}
+
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	}
+	return m.load(ids)
+}
+
+// load reads the policies with the given ids. Policies which do not exist are skipped.
+func (m *SQLManager) load(ids []string) (ladon.Policies, error) {
+	policies := ladon.Policies{}
+	if len(ids) == 0 {
+		return policies, nil
+	}
+
+	in := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")"
+	args := make([]interface{}, len(ids))
+	for k, id := range ids {
+		args[k] = id
+	}
+
+	rows, err := m.DB.Query(m.rebind("SELECT id, description, effect, conditions FROM hydra_policy WHERE id IN "+in), args...)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	defer rows.Close()
+
+	byID := map[string]*ladon.DefaultPolicy{}
+	for rows.Next() {
+		var p = &ladon.DefaultPolicy{Conditions: ladon.Conditions{}, Subjects: []string{}, Resources: []string{}, Actions: []string{}}
+		var conditions string
+		if err := rows.Scan(&p.ID, &p.Description, &p.Effect, &conditions); err != nil {
+			return nil, errors.New(err)
+		} else if err := json.Unmarshal([]byte(conditions), &p.Conditions); err != nil {
+			return nil, errors.New(err)
+		}
+		byID[p.ID] = p
+		policies = append(policies, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	}
+
+	for _, table := range templateTables {
+		rows, err := m.DB.Query(m.rebind("SELECT policy, template FROM "+table+" WHERE policy IN "+in), args...)
+		if err != nil {
+			return nil, errors.New(err)
+		}
+
+		for rows.Next() {
+			var id, template string
+			if err := rows.Scan(&id, &template); err != nil {
+				rows.Close()
+				return nil, errors.New(err)
+			}
+
+			p, ok := byID[id]
+			if !ok {
+				continue
+			}
+			switch table {
+			case "hydra_policy_subject":
+				p.Subjects = append(p.Subjects, template)
+			case "hydra_policy_resource":
+				p.Resources = append(p.Resources, template)
+			case "hydra_policy_action":
+				p.Actions = append(p.Actions, template)
+			}
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, errors.New(err)
+		}
+	}
+	return policies, nil
+}
+
+func (m *SQLManager) insertTemplates(tx *sql.Tx, policy ladon.Policy) error {
+	for table, templates := range map[string][]string{
+		"hydra_policy_subject":  policy.GetSubjects(),
+		"hydra_policy_resource": policy.GetResources(),
+		"hydra_policy_action":   policy.GetActions(),
+	} {
+		for _, template := range templates {
+			if isPattern(template) {
+				if _, err := compilePattern(template); err != nil {
+					return err
+				}
+			}
+
+			if _, err := tx.Exec(m.rebind("INSERT INTO "+table+" (policy, template, has_regex) VALUES (?, ?, ?)"),
+				policy.GetID(), template, isPattern(template)); err != nil {
+				return errors.New(err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *SQLManager) rebind(query string) string {
+	return pkg.RebindSQL(m.Driver, query)
+}
+
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, errors.New(err)
+		}
+		result = append(result, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.New(err)
+	}
+	return result, nil
+}
@@ -1,8 +1,11 @@
 package policy
 
 import (
+	"database/sql"
+	"log"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"testing"
 
 	"time"
@@ -10,12 +13,15 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/fosite"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/integration"
 	"github.com/ory-am/hydra/internal"
 	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/ladon"
 	"github.com/pborman/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
 )
 
 var managers = map[string]ladon.Manager{}
@@ -50,6 +56,35 @@ func init() {
 	}
 }
 
+func TestMain(m *testing.M) {
+	session := integration.ConnectToRethinkDB("hydra", "hydra_policies")
+	defer session.Close()
+
+	rethinkManager := &RethinkManager{
+		Session:  session,
+		Table:    r.Table("hydra_policies"),
+		Policies: map[string]ladon.Policy{},
+	}
+	rethinkManager.Watch(context.Background())
+	time.Sleep(500 * time.Millisecond)
+	managers["rethink"] = rethinkManager
+
+	for driver, db := range map[string]*sql.DB{
+		"postgres": integration.ConnectToPostgres(),
+		"mysql":    integration.ConnectToMySQL(),
+	} {
+		sm := &SQLManager{DB: db, Driver: driver}
+		if err := sm.CreateSchemas(); err != nil {
+			log.Fatalf("Could not create %s schemas: %s", driver, err)
+		}
+		managers[driver] = sm
+	}
+
+	retCode := m.Run()
+	integration.KillAll()
+	os.Exit(retCode)
+}
+
 func TestManagers(t *testing.T) {
 	p := &ladon.DefaultPolicy{
 		ID:          uuid.New(),
@@ -88,6 +123,8 @@ func TestManagers(t *testing.T) {
 
 		pkg.AssertError(t, false, m.Delete(p.ID), k)
 
+		time.Sleep(200 * time.Millisecond)
+
 		_, err = m.Get(p.ID)
 		pkg.AssertError(t, true, err, k)
 	}
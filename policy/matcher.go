@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/ladon"
+)
+
+const (
+	delimiterStart = '<'
+	delimiterEnd   = '>'
+)
+
+// isPattern returns true if the subject, resource or action of a policy contains a regular expression.
+func isPattern(s string) bool {
+	return strings.IndexByte(s, delimiterStart) >= 0
+}
+
+// compilePattern compiles a ladon pattern such as "rn:hydra:<.*>" into an anchored regular expression. Everything
+// outside of the delimiters is matched literally.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	var expr = []byte{'^'}
+	var depth, start int
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case delimiterStart:
+			if depth == 0 {
+				expr = append(expr, regexp.QuoteMeta(pattern[start:i])...)
+				start = i + 1
+			}
+			depth++
+		case delimiterEnd:
+			depth--
+			if depth < 0 {
+				return nil, errors.Errorf("Pattern %s closes a delimiter which was never opened", pattern)
+			} else if depth == 0 {
+				expr = append(expr, '(')
+				expr = append(expr, pattern[start:i]...)
+				expr = append(expr, ')')
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.Errorf("Pattern %s does not close all delimiters", pattern)
+	}
+	expr = append(expr, regexp.QuoteMeta(pattern[start:])...)
+	expr = append(expr, '$')
+
+	re, err := regexp.Compile(string(expr))
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return re, nil
+}
+
+// matches returns true if the value matches one of the patterns.
+func matches(patterns []string, value string) (bool, error) {
+	return DefaultMatchers.Matches(patterns, value)
+}
+
+// filterBySubject returns the policies which apply to the subject.
+func filterBySubject(policies map[string]ladon.Policy, subject string) (ladon.Policies, error) {
+	result := ladon.Policies{}
+	for _, p := range policies {
+		if ok, err := matches(p.GetSubjects(), subject); err != nil {
+			return nil, err
+		} else if ok {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
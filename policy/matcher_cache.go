@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+)
+
+// DefaultMatcherCacheSize is how many compiled patterns DefaultMatchers keeps.
+const DefaultMatcherCacheSize = 4096
+
+// DefaultMatchers is shared by the policy managers and wardens of this package, so that a pattern is compiled once
+// no matter how many requests it is evaluated for.
+var DefaultMatchers = NewMatcherCache(DefaultMatcherCacheSize)
+
+// MatcherCache keeps the compiled regular expressions of policy patterns. When it is full, the pattern which was
+// used least recently is evicted.
+type MatcherCache struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	sync.Mutex
+}
+
+type compiledPattern struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// NewMatcherCache returns a cache which keeps up to size compiled patterns.
+func NewMatcherCache(size int) *MatcherCache {
+	return &MatcherCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Compile returns the compiled pattern. Patterns which do not compile are not cached.
+func (c *MatcherCache) Compile(pattern string) (*regexp.Regexp, error) {
+	c.Lock()
+	if e, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(e)
+		c.Unlock()
+		observeMatcherLookup(true)
+		return e.Value.(*compiledPattern).re, nil
+	}
+	c.Unlock()
+	observeMatcherLookup(false)
+
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[pattern]; ok {
+		// Another request compiled the pattern in the meantime.
+		c.order.MoveToFront(e)
+		return e.Value.(*compiledPattern).re, nil
+	}
+
+	c.entries[pattern] = c.order.PushFront(&compiledPattern{pattern: pattern, re: re})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*compiledPattern).pattern)
+		matcherCacheEvictions.Inc()
+	}
+	matcherCacheSize.Set(float64(c.order.Len()))
+	return re, nil
+}
+
+// Matches returns true if the value matches one of the patterns.
+func (c *MatcherCache) Matches(patterns []string, value string) (bool, error) {
+	for _, pattern := range patterns {
+		if !isPattern(pattern) {
+			if pattern == value {
+				return true, nil
+			}
+			continue
+		}
+
+		re, err := c.Compile(pattern)
+		if err != nil {
+			return false, err
+		} else if re.MatchString(value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Len returns the number of cached patterns.
+func (c *MatcherCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.order.Len()
+}
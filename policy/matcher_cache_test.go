@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcherCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMatcherCache(2)
+
+	ok, err := c.Matches([]string{"<a|b>"}, "a")
+	require.Nil(t, err)
+	assert.True(t, ok)
+	_, err = c.Compile("<c|d>")
+	require.Nil(t, err)
+
+	// <a|b> is used again, so <c|d> is evicted next.
+	_, err = c.Compile("<a|b>")
+	require.Nil(t, err)
+	_, err = c.Compile("<e|f>")
+	require.Nil(t, err)
+
+	assert.Equal(t, 2, c.Len())
+	assert.Contains(t, c.entries, "<a|b>")
+	assert.Contains(t, c.entries, "<e|f>")
+	assert.NotContains(t, c.entries, "<c|d>")
+
+	_, err = c.Compile("<g")
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, c.Len(), "Patterns which do not compile are not cached")
+}
+
+func TestWarden(t *testing.T) {
+	w := &Warden{
+		Manager: &ladon.MemoryManager{Policies: map[string]ladon.Policy{
+			"1": &ladon.DefaultPolicy{
+				ID:        "1",
+				Subjects:  []string{"<peter|max>"},
+				Resources: []string{"rn:articles:<.*>"},
+				Actions:   []string{"<get|update>"},
+				Effect:    ladon.AllowAccess,
+			},
+			"2": &ladon.DefaultPolicy{
+				ID:        "2",
+				Subjects:  []string{"max"},
+				Resources: []string{"rn:articles:secret"},
+				Actions:   []string{"<.*>"},
+				Effect:    ladon.DenyAccess,
+			},
+		}},
+		Matchers: NewMatcherCache(16),
+	}
+
+	for k, c := range []struct {
+		req     *ladon.Request
+		allowed bool
+	}{
+		{req: &ladon.Request{Subject: "peter", Resource: "rn:articles:1", Action: "get"}, allowed: true},
+		{req: &ladon.Request{Subject: "peter", Resource: "rn:articles:1", Action: "delete"}, allowed: false},
+		{req: &ladon.Request{Subject: "peter", Resource: "rn:users:1", Action: "get"}, allowed: false},
+		{req: &ladon.Request{Subject: "peter", Resource: "rn:articles:secret", Action: "get"}, allowed: true},
+		{req: &ladon.Request{Subject: "max", Resource: "rn:articles:secret", Action: "get"}, allowed: false},
+		{req: &ladon.Request{Subject: "stan", Resource: "rn:articles:1", Action: "get"}, allowed: false},
+	} {
+		assert.Equal(t, c.allowed, w.IsAllowed(c.req) == nil, "%d", k)
+	}
+}
+
+var benchmarkPatterns = func() []string {
+	patterns := make([]string, 50)
+	for k := range patterns {
+		patterns[k] = fmt.Sprintf("rn:hydra:%d:<[a-z]+>:<get|update|delete>", k)
+	}
+	return patterns
+}()
+
+func BenchmarkMatchesUncached(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, pattern := range benchmarkPatterns {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				b.Fatal(err)
+			}
+			re.MatchString("rn:hydra:49:articles:delete")
+		}
+	}
+}
+
+func BenchmarkMatchesCached(b *testing.B) {
+	c := NewMatcherCache(DefaultMatcherCacheSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Matches(benchmarkPatterns, "rn:hydra:49:articles:delete"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
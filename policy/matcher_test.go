@@ -0,0 +1,45 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatches(t *testing.T) {
+	for k, c := range []struct {
+		patterns  []string
+		value     string
+		expectErr bool
+		matches   bool
+	}{
+		{patterns: []string{"peter"}, value: "peter", matches: true},
+		{patterns: []string{"peter"}, value: "peter2", matches: false},
+		{patterns: []string{"<peter|max>"}, value: "max", matches: true},
+		{patterns: []string{"<peter|max>"}, value: "maxi", matches: false},
+		{patterns: []string{"rn:hydra:<.*>"}, value: "rn:hydra:clients", matches: true},
+		{patterns: []string{"rn.hydra:<.*>"}, value: "rnxhydra:clients", matches: false},
+		{patterns: []string{"rn:<[0-9]{2}>:<a|b>"}, value: "rn:12:b", matches: true},
+		{patterns: []string{"foo", "<bar>"}, value: "bar", matches: true},
+		{patterns: []string{"<foo"}, value: "foo", expectErr: true},
+		{patterns: []string{"foo>"}, value: "foo>", matches: true},
+	} {
+		ok, err := matches(c.patterns, c.value)
+		pkg.AssertError(t, c.expectErr, err, k)
+		assert.Equal(t, c.matches, ok, "%d", k)
+	}
+}
+
+func TestFilterBySubject(t *testing.T) {
+	policies := map[string]ladon.Policy{
+		"1": &ladon.DefaultPolicy{ID: "1", Subjects: []string{"peter"}},
+		"2": &ladon.DefaultPolicy{ID: "2", Subjects: []string{"<.*>"}},
+		"3": &ladon.DefaultPolicy{ID: "3", Subjects: []string{"max"}},
+	}
+
+	ps, err := filterBySubject(policies, "peter")
+	assert.Nil(t, err)
+	assert.Len(t, ps, 2)
+}
@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var matcherCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "policy",
+	Name:      "matcher_cache_lookups_total",
+	Help:      "Number of compiled pattern lookups, partitioned by whether the pattern was cached.",
+}, []string{"result"})
+
+var matcherCacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "policy",
+	Name:      "matcher_cache_evictions_total",
+	Help:      "Number of compiled patterns evicted from the cache.",
+})
+
+var matcherCacheSize = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "hydra",
+	Subsystem: "policy",
+	Name:      "matcher_cache_size",
+	Help:      "Number of compiled patterns in the cache.",
+})
+
+var decisionCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "policy",
+	Name:      "decision_cache_lookups_total",
+	Help:      "Number of warden decision lookups, partitioned by whether the decision was cached.",
+}, []string{"result"})
+
+var decisionCacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "policy",
+	Name:      "decision_cache_evictions_total",
+	Help:      "Number of warden decisions evicted from the cache because it was full.",
+})
+
+var decisionCacheInvalidations = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "hydra",
+	Subsystem: "policy",
+	Name:      "decision_cache_invalidations_total",
+	Help:      "Number of times the warden decision cache was dropped because policies, groups or labels changed.",
+})
+
+var decisionCacheSize = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: "hydra",
+	Subsystem: "policy",
+	Name:      "decision_cache_size",
+	Help:      "Number of warden decisions in the cache.",
+})
+
+func init() {
+	prometheus.MustRegister(matcherCacheLookups)
+	prometheus.MustRegister(matcherCacheEvictions)
+	prometheus.MustRegister(matcherCacheSize)
+	prometheus.MustRegister(decisionCacheLookups)
+	prometheus.MustRegister(decisionCacheEvictions)
+	prometheus.MustRegister(decisionCacheInvalidations)
+	prometheus.MustRegister(decisionCacheSize)
+}
+
+func observeMatcherLookup(hit bool) {
+	if hit {
+		matcherCacheLookups.WithLabelValues("hit").Inc()
+		return
+	}
+	matcherCacheLookups.WithLabelValues("miss").Inc()
+}
+
+func observeDecisionLookup(hit bool) {
+	if hit {
+		decisionCacheLookups.WithLabelValues("hit").Inc()
+		return
+	}
+	decisionCacheLookups.WithLabelValues("miss").Inc()
+}
@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"encoding/json"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/ladon"
+)
+
+// MemorySnapshotter snapshots the policies of a ladon memory manager.
+type MemorySnapshotter struct {
+	Manager *ladon.MemoryManager
+}
+
+// Snapshot returns all policies encoded as JSON.
+func (s *MemorySnapshotter) Snapshot() ([]byte, error) {
+	s.Manager.RLock()
+	defer s.Manager.RUnlock()
+
+	out, err := json.Marshal(s.Manager.Policies)
+	if err != nil {
+		return nil, errors.New(err)
+	}
+	return out, nil
+}
+
+// Restore replaces all policies with the ones of the snapshot.
+func (s *MemorySnapshotter) Restore(snapshot []byte) error {
+	var decoded map[string]*ladon.DefaultPolicy
+	if err := json.Unmarshal(snapshot, &decoded); err != nil {
+		return errors.New(err)
+	}
+
+	policies := map[string]ladon.Policy{}
+	for id, p := range decoded {
+		policies[id] = p
+	}
+
+	s.Manager.Lock()
+	defer s.Manager.Unlock()
+	s.Manager.Policies = policies
+	return nil
+}
@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/swagger"
+	"github.com/ory-am/ladon"
+)
+
+// Operations describes the endpoints of Handler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "POST", Path: endpoint, ID: "createPolicy", Tag: "policies", Scope: scope,
+		Summary:   "Create a policy",
+		Body:      &ladon.DefaultPolicy{},
+		Responses: map[int]interface{}{http.StatusCreated: &ladon.DefaultPolicy{}},
+	},
+	{
+		Method: "GET", Path: endpoint, ID: "listPolicies", Tag: "policies", Scope: scope,
+		Summary:    "List the policies of a subject",
+		Query:      []string{"subject"},
+		Pagination: []string{"id", "description", "effect"},
+		Responses:  map[int]interface{}{http.StatusOK: []*ladon.DefaultPolicy{}},
+	},
+	{
+		Method: "PATCH", Path: endpoint, ID: "bulkPolicies", Tag: "policies", Scope: scope,
+		Summary:   "Create, update and delete policies in bulk",
+		Body:      []*pkg.BulkOperation{},
+		Responses: map[int]interface{}{http.StatusOK: &pkg.BulkResponse{}},
+	},
+	{
+		Method: "DELETE", Path: endpoint, ID: "deletePoliciesByLabel", Tag: "policies", Scope: scope,
+		Summary:   "Delete the policies which carry a label",
+		Query:     []string{"label", "confirm"},
+		Responses: map[int]interface{}{http.StatusOK: &pkg.BulkDeletion{}},
+	},
+	{
+		Method: "GET", Path: endpoint + "/:id", ID: "getPolicy", Tag: "policies", Scope: scope,
+		Summary:   "Get a policy, the ETag header identifies its version",
+		Responses: map[int]interface{}{http.StatusOK: &ladon.DefaultPolicy{}},
+	},
+	{
+		Method: "DELETE", Path: endpoint + "/:id", ID: "deletePolicy", Tag: "policies", Scope: scope,
+		Summary:   "Delete a policy, unless If-Match names a version which is not the current one",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+}
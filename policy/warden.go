@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/ory-am/ladon"
+)
+
+// Warden decides access requests the way ladon.Ladon does, but matches subjects, actions and resources with the
+// compiled patterns of a MatcherCache instead of compiling every pattern of every policy per request.
+type Warden struct {
+	Manager ladon.Manager
+
+	// Matchers are DefaultMatchers if nil.
+	Matchers *MatcherCache
+}
+
+// IsAllowed returns nil if a policy allows the request and no policy denies it.
+func (w *Warden) IsAllowed(r *ladon.Request) error {
+	policies, err := w.Manager.FindPoliciesForSubject(r.Subject)
+	if err != nil {
+		return err
+	}
+	return w.decide(r, policies)
+}
+
+func (w *Warden) decide(r *ladon.Request, policies ladon.Policies) error {
+	matchers := w.Matchers
+	if matchers == nil {
+		matchers = DefaultMatchers
+	}
+
+	var allowed bool
+	for _, p := range policies {
+		// Managers may return more policies than apply to the subject, so it is checked again.
+		if ok, err := matchers.Matches(p.GetSubjects(), r.Subject); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		if ok, err := matchers.Matches(p.GetActions(), r.Action); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		if ok, err := matchers.Matches(p.GetResources(), r.Resource); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		if !conditionsFulfilled(p, r) {
+			continue
+		}
+
+		if p.GetEffect() == ladon.DenyAccess {
+			return errors.New(ladon.ErrRequestForcefullyDenied)
+		}
+		allowed = true
+	}
+
+	if !allowed {
+		return errors.New(ladon.ErrRequestDenied)
+	}
+	return nil
+}
+
+func conditionsFulfilled(p ladon.Policy, r *ladon.Request) bool {
+	for key, condition := range p.GetConditions() {
+		if !condition.Fulfills(r.Context[key], r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package quota
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/pkg"
+)
+
+const (
+	// EventWarning is published when the usage of a quota crosses one of its warning thresholds.
+	EventWarning = "quota.warning"
+
+	// EventExceeded is published when a request is rejected because the quota is exhausted.
+	EventExceeded = "quota.exceeded"
+)
+
+var ErrQuotaExceeded = errors.New("Quota exceeded")
+
+// DefaultThresholds are used when a quota has no thresholds configured.
+var DefaultThresholds = []float64{0.8, 0.9}
+
+// Quota is a hard limit on some resource. Before the limit is reached, a warning event is published every time the
+// usage crosses one of the thresholds, so operators get paged before requests start failing. A quota may limit the
+// usage per scope, for example the tokens of every client, in which case every scope is limited and warned about on
+// its own. Clients are limited by client.QuotaManager, tokens per client by internal.FositeQuotaStore and login
+// sessions per subject by oauth2.LoginSessionQuotaManager.
+type Quota struct {
+	// Name identifies the quota in events, for example "clients".
+	Name string
+
+	// Limit is the maximum usage. A limit of zero or less disables the quota.
+	Limit int
+
+	// Thresholds are fractions of the limit at which warnings are published, for example 0.8 for 80%.
+	Thresholds []float64
+
+	Publisher events.Publisher
+
+	// Leases serialize Admit across the cluster, so that instances counting at the same time can not exceed the
+	// quota together. Without leases Admit is only serialized within this process.
+	Leases pkg.LeaseManager
+
+	// warned is the highest threshold a warning was published for, per scope.
+	warned map[string]float64
+
+	// admitting serializes Admit if there are no leases.
+	admitting sync.Mutex
+
+	sync.Mutex
+}
+
+// Admit calls create unless the usage of scope, which count returns, has reached the limit. The usage is counted and
+// create is called while holding a lease on the scope, so that every instance of the cluster has to wait until
+// create returned before it counts again.
+func (q *Quota) Admit(scope string, count func() (int, error), create func() error) error {
+	if q == nil || q.Limit <= 0 {
+		return create()
+	}
+
+	admit := func() error {
+		used, err := count()
+		if err != nil {
+			return err
+		} else if err := q.CheckScope(scope, used+1); err != nil {
+			return err
+		}
+		return create()
+	}
+
+	if q.Leases == nil {
+		q.admitting.Lock()
+		defer q.admitting.Unlock()
+		return admit()
+	}
+	return pkg.WithLease(q.Leases, "quota:"+q.Name+":"+scope, 0, admit)
+}
+
+// Check verifies that used does not exceed the limit of a quota without scopes, see CheckScope.
+func (q *Quota) Check(used int) error {
+	return q.CheckScope("", used)
+}
+
+// CheckScope verifies that used does not exceed the limit of the scope. It returns ErrQuotaExceeded if it does and
+// publishes warnings when used crosses a threshold for the first time. Once usage drops below a threshold, crossing
+// it again publishes another warning.
+func (q *Quota) CheckScope(scope string, used int) error {
+	if q == nil || q.Limit <= 0 {
+		return nil
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if used > q.Limit {
+		q.publish(EventExceeded, scope, used, 1)
+		return errors.New(ErrQuotaExceeded)
+	}
+
+	ratio := float64(used) / float64(q.Limit)
+	var crossed float64
+	for _, t := range q.thresholds() {
+		if ratio >= t {
+			crossed = t
+		}
+	}
+
+	if q.warned == nil {
+		q.warned = map[string]float64{}
+	}
+	if crossed > q.warned[scope] {
+		q.publish(EventWarning, scope, used, crossed)
+	}
+	if crossed > 0 {
+		q.warned[scope] = crossed
+	} else {
+		delete(q.warned, scope)
+	}
+	return nil
+}
+
+func (q *Quota) thresholds() []float64 {
+	ts := q.Thresholds
+	if len(ts) == 0 {
+		ts = DefaultThresholds
+	}
+
+	sorted := make([]float64, len(ts))
+	copy(sorted, ts)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+func (q *Quota) publish(eventType, scope string, used int, threshold float64) {
+	if q.Publisher == nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"quota":     q.Name,
+		"limit":     q.Limit,
+		"used":      used,
+		"threshold": threshold,
+	}
+	if scope != "" {
+		data["scope"] = scope
+	}
+	q.Publisher.Publish(events.New(eventType, data))
+}
@@ -0,0 +1,118 @@
+package quota_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/events"
+	"github.com/ory-am/hydra/pkg"
+	. "github.com/ory-am/hydra/quota"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder []*events.Event
+
+func (r *recorder) Publish(e *events.Event) {
+	*r = append(*r, e)
+}
+
+func TestQuota(t *testing.T) {
+	var published recorder
+	q := &Quota{
+		Name:      "clients",
+		Limit:     10,
+		Publisher: &published,
+	}
+
+	for used := 1; used <= 7; used++ {
+		assert.Nil(t, q.Check(used))
+	}
+	assert.Len(t, published, 0)
+
+	assert.Nil(t, q.Check(8))
+	assert.Nil(t, q.Check(8))
+	assert.Len(t, published, 1)
+	assert.Equal(t, EventWarning, published[0].Type)
+	assert.Equal(t, 0.8, published[0].Data["threshold"])
+
+	assert.Nil(t, q.Check(9))
+	assert.Nil(t, q.Check(10))
+	assert.Len(t, published, 2)
+	assert.Equal(t, 0.9, published[1].Data["threshold"])
+
+	err := q.Check(11)
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.Len(t, published, 3)
+	assert.Equal(t, EventExceeded, published[2].Type)
+
+	assert.Nil(t, q.Check(5))
+	assert.Nil(t, q.Check(8))
+	assert.Len(t, published, 4)
+}
+
+func TestQuotaDisabled(t *testing.T) {
+	var q *Quota
+	assert.Nil(t, q.Check(100))
+	assert.Nil(t, (&Quota{}).Check(100))
+}
+
+func TestQuotaScopes(t *testing.T) {
+	var published recorder
+	q := &Quota{Name: "tokens", Limit: 10, Publisher: &published}
+
+	assert.Nil(t, q.CheckScope("a", 8))
+	assert.Nil(t, q.CheckScope("b", 8))
+	assert.Len(t, published, 2)
+	assert.Equal(t, "a", published[0].Data["scope"])
+	assert.Equal(t, "b", published[1].Data["scope"])
+
+	assert.True(t, errors.Is(q.CheckScope("a", 11), ErrQuotaExceeded))
+	assert.Nil(t, q.CheckScope("b", 10))
+}
+
+func TestQuotaAdmit(t *testing.T) {
+	// Two instances share the leases but count on their own, together they may not exceed the limit.
+	leases := &pkg.MemoryLeaseManager{}
+	instances := []*Quota{
+		{Name: "clients", Limit: 5, Leases: leases},
+		{Name: "clients", Limit: 5, Leases: leases},
+	}
+
+	var lock sync.Mutex
+	var used int
+	count := func() (int, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return used, nil
+	}
+	create := func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		used++
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	var exceeded int32
+	for k := 0; k < 20; k++ {
+		wg.Add(1)
+		go func(q *Quota) {
+			defer wg.Done()
+			if err := q.Admit("", count, create); errors.Is(err, ErrQuotaExceeded) {
+				atomic.AddInt32(&exceeded, 1)
+			} else {
+				assert.Nil(t, err)
+			}
+		}(instances[k%2])
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, used)
+	assert.Equal(t, int32(15), exceeded)
+
+	var disabled *Quota
+	assert.Nil(t, disabled.Admit("", count, create))
+	assert.Equal(t, 6, used)
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
+)
+
+var ErrTooManyRequests = &herodot.Error{
+	Err:  errors.New("Too many requests"),
+	Code: http.StatusTooManyRequests,
+}
+
+// Authenticator verifies the credentials of a client, client.Manager implements it.
+type Authenticator interface {
+	Authenticate(id string, secret []byte) (*fosite.DefaultClient, error)
+}
+
+// Middleware limits the requests to Paths per client and per remote address. Requests to other paths are passed
+// on untouched. A request which exceeds a limit is answered with 429 Too Many Requests and a Retry-After header.
+type Middleware struct {
+	Store Store
+
+	// PerClient limits the requests of a client which authenticated with its basic auth credentials or the
+	// client_id and client_secret form parameters. Requests which do not authenticate a client are only limited per
+	// address, so that nobody can exhaust the limit of a client by naming it.
+	PerClient Limit
+
+	// Clients verifies the credentials of the clients. Requests are only limited per address if it is nil.
+	Clients Authenticator
+
+	// PerAddress limits the requests of a remote address.
+	PerAddress Limit
+
+	// Paths are the paths which are limited, for example /oauth2/token.
+	Paths []string
+
+	H herodot.Herodot
+}
+
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	paths := map[string]bool{}
+	for _, p := range m.Paths {
+		paths[p] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !paths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if wait, ok := m.allow(r); !ok {
+			logger.FromRequest(r).WithField("path", r.URL.Path).Warnf("Rate limit exceeded.")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			m.H.WriteError(herodot.NewContext(), w, r, ErrTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from the bucket of the remote address and, once the client authenticated, from the bucket
+// of the client. The client is only authenticated if the address has tokens left, so that the limit of the address
+// also bounds how fast credentials can be guessed here. If the store fails the request is allowed, an unavailable
+// store must not take the token endpoint down with it.
+func (m *Middleware) allow(r *http.Request) (time.Duration, bool) {
+	if m.PerAddress.Enabled() {
+		if wait, ok := m.take(r, "address:"+remoteHost(r.RemoteAddr), m.PerAddress); !ok {
+			return wait, false
+		}
+	}
+	if id := m.authenticatedClient(r); id != "" {
+		return m.take(r, "client:"+id, m.PerClient)
+	}
+	return 0, true
+}
+
+func (m *Middleware) take(r *http.Request, key string, l Limit) (time.Duration, bool) {
+	ok, wait, err := m.Store.Take(key, l)
+	if err != nil {
+		logger.LogRequestError(r, err)
+		return 0, true
+	}
+	return wait, ok
+}
+
+// authenticatedClient returns the id of the client which authenticated the request, or an empty string if the
+// request has no client credentials or they are wrong.
+func (m *Middleware) authenticatedClient(r *http.Request) string {
+	if !m.PerClient.Enabled() || m.Clients == nil {
+		return ""
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if id == "" || secret == "" {
+		return ""
+	}
+
+	if _, err := m.Clients.Authenticate(id, []byte(secret)); err != nil {
+		return ""
+	}
+	return id
+}
+
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,58 @@
+// Package ratelimit limits the rate of requests per client and per remote address with token buckets, so that
+// credentials can not be guessed at the speed of the network.
+package ratelimit
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+// Limit allows Requests requests per Period. Unused requests accumulate up to Requests, so a client which was idle
+// may send a burst of Requests requests at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled returns false for the zero limit, which does not limit anything.
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// ParseLimit parses limits like "10/s", "100/m" or "500/15m". An empty spec returns the zero limit.
+func ParseLimit(spec string) (Limit, error) {
+	if spec == "" {
+		return Limit{}, nil
+	}
+
+	parts := strings.SplitN(spec, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, errors.Errorf("Rate limit %s must look like 10/s", spec)
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		return Limit{}, errors.Errorf("Rate limit %s must allow a positive number of requests", spec)
+	}
+
+	period := parts[1]
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, errors.Errorf("Rate limit %s must have a positive period", spec)
+	}
+
+	return Limit{Requests: requests, Period: d}, nil
+}
+
+// Store keeps the token buckets.
+type Store interface {
+	// Take takes a token from the bucket named key, which is refilled according to l. If the bucket is empty, Take
+	// returns false and how long it takes until the next token is available.
+	Take(key string, l Limit) (bool, time.Duration, error)
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	for k, c := range []struct {
+		spec  string
+		limit Limit
+		err   bool
+	}{
+		{spec: "", limit: Limit{}},
+		{spec: "10/s", limit: Limit{Requests: 10, Period: time.Second}},
+		{spec: "500/15m", limit: Limit{Requests: 500, Period: time.Minute * 15}},
+		{spec: "10", err: true},
+		{spec: "0/s", err: true},
+		{spec: "10/fortnight", err: true},
+	} {
+		l, err := ParseLimit(c.spec)
+		if c.err {
+			assert.NotNil(t, err, "%d", k)
+			continue
+		}
+		require.Nil(t, err, "%d", k)
+		assert.Equal(t, c.limit, l, "%d", k)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	l := Limit{Requests: 2, Period: time.Second}
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := s.Take("foo", l)
+		require.Nil(t, err)
+		assert.True(t, ok, "%d", i)
+	}
+
+	ok, wait, err := s.Take("foo", l)
+	require.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, time.Millisecond*500, wait)
+
+	// Other buckets are not affected.
+	ok, _, err = s.Take("bar", l)
+	require.Nil(t, err)
+	assert.True(t, ok)
+
+	now = now.Add(time.Millisecond * 500)
+	ok, _, err = s.Take("foo", l)
+	require.Nil(t, err)
+	assert.True(t, ok)
+}
+
+type authenticator map[string]string
+
+func (a authenticator) Authenticate(id string, secret []byte) (*fosite.DefaultClient, error) {
+	if s, ok := a[id]; !ok || s != string(secret) {
+		return nil, errors.New("invalid credentials")
+	}
+	return &fosite.DefaultClient{ID: id}, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	m := &Middleware{
+		Store:      NewMemoryStore(),
+		PerClient:  Limit{Requests: 1, Period: time.Hour},
+		PerAddress: Limit{Requests: 3, Period: time.Hour},
+		Clients:    authenticator{"foo": "secret", "bar": "secret"},
+		Paths:      []string{"/oauth2/token"},
+		H:          &herodot.JSON{},
+	}
+	handler := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	request := func(path, addr, client, secret string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("POST", "http://localhost"+path, strings.NewReader(url.Values{"client_id": {client}}.Encode()))
+		require.Nil(t, err)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if secret != "" {
+			r.SetBasicAuth(client, secret)
+		}
+		r.RemoteAddr = addr + ":1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Naming a client without authenticating does not touch its limit.
+	assert.Equal(t, http.StatusNoContent, request("/oauth2/token", "127.0.0.2", "foo", "").Code)
+	assert.Equal(t, http.StatusNoContent, request("/oauth2/token", "127.0.0.2", "foo", "wrong").Code)
+
+	assert.Equal(t, http.StatusNoContent, request("/oauth2/token", "127.0.0.1", "foo", "secret").Code)
+	w := request("/oauth2/token", "127.0.0.1", "foo", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// The client is limited from every address.
+	assert.Equal(t, http.StatusTooManyRequests, request("/oauth2/token", "127.0.0.3", "foo", "secret").Code)
+
+	// Every request counts against the address, the third one runs out of it.
+	assert.Equal(t, http.StatusNoContent, request("/oauth2/token", "127.0.0.1", "bar", "secret").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("/oauth2/token", "127.0.0.1", "baz", "").Code)
+
+	// Other paths are not limited.
+	assert.Equal(t, http.StatusNoContent, request("/clients", "127.0.0.1", "foo", "secret").Code)
+}
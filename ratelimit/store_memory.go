@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is the number of takes after which buckets which refilled completely are dropped.
+const sweepInterval = 1024
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+func (b *bucket) refill(now time.Time) {
+	capacity := float64(b.limit.Requests)
+	b.tokens += float64(now.Sub(b.updated)) * capacity / float64(b.limit.Period)
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.updated = now
+}
+
+// MemoryStore keeps the buckets in memory. Every instance limits on its own, use RedisStore to share the buckets
+// of a cluster.
+type MemoryStore struct {
+	buckets map[string]*bucket
+	takes   int
+	now     func() time.Time
+
+	sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(key string, l Limit) (bool, time.Duration, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	s.takes++
+	if s.takes%sweepInterval == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != l {
+		b = &bucket{tokens: float64(l.Requests), updated: now, limit: l}
+		s.buckets[key] = b
+	}
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) * float64(l.Period) / float64(l.Requests))
+	return false, wait, nil
+}
+
+// sweep drops the buckets which are full, they are recreated on the next take.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.limit.Period {
+			delete(s.buckets, key)
+		}
+	}
+}
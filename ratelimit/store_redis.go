@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/go-errors/errors"
+)
+
+// takeScript refills and takes from a bucket atomically. The bucket expires once it would be full again.
+var takeScript = redis.NewScript(1, `
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1]) or capacity
+local updated = tonumber(bucket[2]) or now
+
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * capacity / period)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * period / capacity)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], period)
+return {allowed, wait}
+`)
+
+// RedisStore keeps the buckets in Redis, so that all instances of a cluster share them. The time of the instance
+// which takes a token is used, so the clocks of the instances should be synchronized.
+type RedisStore struct {
+	Pool *redis.Pool
+
+	// Prefix is prepended to the keys of the buckets.
+	Prefix string
+}
+
+// NewRedisStore connects to the Redis server at a URL like redis://:password@localhost:6379/0.
+func NewRedisStore(url string) *RedisStore {
+	return &RedisStore{
+		Pool: &redis.Pool{
+			MaxIdle:     10,
+			IdleTimeout: time.Minute * 5,
+			Dial: func() (redis.Conn, error) {
+				return redis.DialURL(url)
+			},
+		},
+		Prefix: "hydra:ratelimit:",
+	}
+}
+
+func (s *RedisStore) Take(key string, l Limit) (bool, time.Duration, error) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	period := int64(l.Period / time.Millisecond)
+	if period < 1 {
+		period = 1
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	reply, err := redis.Int64s(takeScript.Do(conn, s.Prefix+key, l.Requests, period, now))
+	if err != nil {
+		return false, 0, errors.New(err)
+	} else if len(reply) != 2 {
+		return false, 0, errors.Errorf("Expected two values from the rate limit script but got %d", len(reply))
+	}
+
+	return reply[0] == 1, time.Duration(reply[1]) * time.Millisecond, nil
+}
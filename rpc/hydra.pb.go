@@ -0,0 +1,644 @@
+// Code generated by protoc-gen-go.
+// source: hydra.proto
+// DO NOT EDIT!
+
+/*
+Package rpc is a generated protocol buffer package.
+
+It is generated from these files:
+
+	hydra.proto
+
+It has these top-level messages:
+
+	AuthorizedRequest
+	AccessRequest
+	AuthorizationContext
+	AllowedResponse
+	IntrospectRequest
+	IntrospectResponse
+	Actor
+*/
+package rpc
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type AuthorizedRequest struct {
+	Token  string   `protobuf:"bytes,1,opt,name=token" json:"token,omitempty"`
+	Scopes []string `protobuf:"bytes,2,rep,name=scopes" json:"scopes,omitempty"`
+}
+
+func (m *AuthorizedRequest) Reset()                    { *m = AuthorizedRequest{} }
+func (m *AuthorizedRequest) String() string            { return proto.CompactTextString(m) }
+func (*AuthorizedRequest) ProtoMessage()               {}
+func (*AuthorizedRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *AuthorizedRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *AuthorizedRequest) GetScopes() []string {
+	if m != nil {
+		return m.Scopes
+	}
+	return nil
+}
+
+type AccessRequest struct {
+	// token and scopes are required by TokenAllowed, Allowed checks the token instead of the subject if it is set.
+	Token    string   `protobuf:"bytes,1,opt,name=token" json:"token,omitempty"`
+	Scopes   []string `protobuf:"bytes,2,rep,name=scopes" json:"scopes,omitempty"`
+	Subject  string   `protobuf:"bytes,3,opt,name=subject" json:"subject,omitempty"`
+	Resource string   `protobuf:"bytes,4,opt,name=resource" json:"resource,omitempty"`
+	Action   string   `protobuf:"bytes,5,opt,name=action" json:"action,omitempty"`
+	// context is the JSON encoded context of the access request.
+	Context []byte `protobuf:"bytes,6,opt,name=context,proto3" json:"context,omitempty"`
+	// template names a resource template which is expanded with values to the resource.
+	Template string            `protobuf:"bytes,7,opt,name=template" json:"template,omitempty"`
+	Values   map[string]string `protobuf:"bytes,8,rep,name=values" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *AccessRequest) Reset()                    { *m = AccessRequest{} }
+func (m *AccessRequest) String() string            { return proto.CompactTextString(m) }
+func (*AccessRequest) ProtoMessage()               {}
+func (*AccessRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *AccessRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *AccessRequest) GetScopes() []string {
+	if m != nil {
+		return m.Scopes
+	}
+	return nil
+}
+
+func (m *AccessRequest) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *AccessRequest) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *AccessRequest) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *AccessRequest) GetContext() []byte {
+	if m != nil {
+		return m.Context
+	}
+	return nil
+}
+
+func (m *AccessRequest) GetTemplate() string {
+	if m != nil {
+		return m.Template
+	}
+	return ""
+}
+
+func (m *AccessRequest) GetValues() map[string]string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+type AuthorizationContext struct {
+	Subject       string   `protobuf:"bytes,1,opt,name=subject" json:"subject,omitempty"`
+	GrantedScopes []string `protobuf:"bytes,2,rep,name=granted_scopes,json=grantedScopes" json:"granted_scopes,omitempty"`
+	Issuer        string   `protobuf:"bytes,3,opt,name=issuer" json:"issuer,omitempty"`
+	Audience      string   `protobuf:"bytes,4,opt,name=audience" json:"audience,omitempty"`
+	// issued_at and expires_at are seconds since the epoch.
+	IssuedAt              int64    `protobuf:"varint,5,opt,name=issued_at,json=issuedAt" json:"issued_at,omitempty"`
+	ExpiresAt             int64    `protobuf:"varint,6,opt,name=expires_at,json=expiresAt" json:"expires_at,omitempty"`
+	AuthenticationMethods []string `protobuf:"bytes,7,rep,name=authentication_methods,json=authenticationMethods" json:"authentication_methods,omitempty"`
+	AuthenticationContext string   `protobuf:"bytes,8,opt,name=authentication_context,json=authenticationContext" json:"authentication_context,omitempty"`
+	UserVerified          bool     `protobuf:"varint,9,opt,name=user_verified,json=userVerified" json:"user_verified,omitempty"`
+}
+
+func (m *AuthorizationContext) Reset()                    { *m = AuthorizationContext{} }
+func (m *AuthorizationContext) String() string            { return proto.CompactTextString(m) }
+func (*AuthorizationContext) ProtoMessage()               {}
+func (*AuthorizationContext) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *AuthorizationContext) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *AuthorizationContext) GetGrantedScopes() []string {
+	if m != nil {
+		return m.GrantedScopes
+	}
+	return nil
+}
+
+func (m *AuthorizationContext) GetIssuer() string {
+	if m != nil {
+		return m.Issuer
+	}
+	return ""
+}
+
+func (m *AuthorizationContext) GetAudience() string {
+	if m != nil {
+		return m.Audience
+	}
+	return ""
+}
+
+func (m *AuthorizationContext) GetIssuedAt() int64 {
+	if m != nil {
+		return m.IssuedAt
+	}
+	return 0
+}
+
+func (m *AuthorizationContext) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *AuthorizationContext) GetAuthenticationMethods() []string {
+	if m != nil {
+		return m.AuthenticationMethods
+	}
+	return nil
+}
+
+func (m *AuthorizationContext) GetAuthenticationContext() string {
+	if m != nil {
+		return m.AuthenticationContext
+	}
+	return ""
+}
+
+func (m *AuthorizationContext) GetUserVerified() bool {
+	if m != nil {
+		return m.UserVerified
+	}
+	return false
+}
+
+type AllowedResponse struct {
+	Allowed bool `protobuf:"varint,1,opt,name=allowed" json:"allowed,omitempty"`
+}
+
+func (m *AllowedResponse) Reset()                    { *m = AllowedResponse{} }
+func (m *AllowedResponse) String() string            { return proto.CompactTextString(m) }
+func (*AllowedResponse) ProtoMessage()               {}
+func (*AllowedResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *AllowedResponse) GetAllowed() bool {
+	if m != nil {
+		return m.Allowed
+	}
+	return false
+}
+
+type IntrospectRequest struct {
+	Token         string `protobuf:"bytes,1,opt,name=token" json:"token,omitempty"`
+	TokenTypeHint string `protobuf:"bytes,2,opt,name=token_type_hint,json=tokenTypeHint" json:"token_type_hint,omitempty"`
+}
+
+func (m *IntrospectRequest) Reset()                    { *m = IntrospectRequest{} }
+func (m *IntrospectRequest) String() string            { return proto.CompactTextString(m) }
+func (*IntrospectRequest) ProtoMessage()               {}
+func (*IntrospectRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *IntrospectRequest) GetToken() string {
+	if m != nil {
+		return m.Token
+	}
+	return ""
+}
+
+func (m *IntrospectRequest) GetTokenTypeHint() string {
+	if m != nil {
+		return m.TokenTypeHint
+	}
+	return ""
+}
+
+type IntrospectResponse struct {
+	Active   bool   `protobuf:"varint,1,opt,name=active" json:"active,omitempty"`
+	Scope    string `protobuf:"bytes,2,opt,name=scope" json:"scope,omitempty"`
+	ClientId string `protobuf:"bytes,3,opt,name=client_id,json=clientId" json:"client_id,omitempty"`
+	Subject  string `protobuf:"bytes,4,opt,name=subject" json:"subject,omitempty"`
+	// expires_at and issued_at are seconds since the epoch.
+	ExpiresAt int64  `protobuf:"varint,5,opt,name=expires_at,json=expiresAt" json:"expires_at,omitempty"`
+	IssuedAt  int64  `protobuf:"varint,6,opt,name=issued_at,json=issuedAt" json:"issued_at,omitempty"`
+	Audience  string `protobuf:"bytes,7,opt,name=audience" json:"audience,omitempty"`
+	Issuer    string `protobuf:"bytes,8,opt,name=issuer" json:"issuer,omitempty"`
+	TokenType string `protobuf:"bytes,9,opt,name=token_type,json=tokenType" json:"token_type,omitempty"`
+	Actor     *Actor `protobuf:"bytes,10,opt,name=actor" json:"actor,omitempty"`
+}
+
+func (m *IntrospectResponse) Reset()                    { *m = IntrospectResponse{} }
+func (m *IntrospectResponse) String() string            { return proto.CompactTextString(m) }
+func (*IntrospectResponse) ProtoMessage()               {}
+func (*IntrospectResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *IntrospectResponse) GetActive() bool {
+	if m != nil {
+		return m.Active
+	}
+	return false
+}
+
+func (m *IntrospectResponse) GetScope() string {
+	if m != nil {
+		return m.Scope
+	}
+	return ""
+}
+
+func (m *IntrospectResponse) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func (m *IntrospectResponse) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *IntrospectResponse) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *IntrospectResponse) GetIssuedAt() int64 {
+	if m != nil {
+		return m.IssuedAt
+	}
+	return 0
+}
+
+func (m *IntrospectResponse) GetAudience() string {
+	if m != nil {
+		return m.Audience
+	}
+	return ""
+}
+
+func (m *IntrospectResponse) GetIssuer() string {
+	if m != nil {
+		return m.Issuer
+	}
+	return ""
+}
+
+func (m *IntrospectResponse) GetTokenType() string {
+	if m != nil {
+		return m.TokenType
+	}
+	return ""
+}
+
+func (m *IntrospectResponse) GetActor() *Actor {
+	if m != nil {
+		return m.Actor
+	}
+	return nil
+}
+
+// Actor is the party which acts on behalf of the subject of an exchanged token.
+type Actor struct {
+	Subject  string `protobuf:"bytes,1,opt,name=subject" json:"subject,omitempty"`
+	ClientId string `protobuf:"bytes,2,opt,name=client_id,json=clientId" json:"client_id,omitempty"`
+}
+
+func (m *Actor) Reset()                    { *m = Actor{} }
+func (m *Actor) String() string            { return proto.CompactTextString(m) }
+func (*Actor) ProtoMessage()               {}
+func (*Actor) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *Actor) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *Actor) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*AuthorizedRequest)(nil), "hydra.AuthorizedRequest")
+	proto.RegisterType((*AccessRequest)(nil), "hydra.AccessRequest")
+	proto.RegisterType((*AuthorizationContext)(nil), "hydra.AuthorizationContext")
+	proto.RegisterType((*AllowedResponse)(nil), "hydra.AllowedResponse")
+	proto.RegisterType((*IntrospectRequest)(nil), "hydra.IntrospectRequest")
+	proto.RegisterType((*IntrospectResponse)(nil), "hydra.IntrospectResponse")
+	proto.RegisterType((*Actor)(nil), "hydra.Actor")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Warden service
+
+type WardenClient interface {
+	// Authorized checks whether a token is valid and carries the scopes.
+	Authorized(ctx context.Context, in *AuthorizedRequest, opts ...grpc.CallOption) (*AuthorizationContext, error)
+	// TokenAllowed checks whether a token is allowed to perform an action on a resource.
+	TokenAllowed(ctx context.Context, in *AccessRequest, opts ...grpc.CallOption) (*AuthorizationContext, error)
+	// Allowed checks whether a subject is allowed to perform an action on a resource.
+	Allowed(ctx context.Context, in *AccessRequest, opts ...grpc.CallOption) (*AllowedResponse, error)
+}
+
+type wardenClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewWardenClient(cc *grpc.ClientConn) WardenClient {
+	return &wardenClient{cc}
+}
+
+func (c *wardenClient) Authorized(ctx context.Context, in *AuthorizedRequest, opts ...grpc.CallOption) (*AuthorizationContext, error) {
+	out := new(AuthorizationContext)
+	err := grpc.Invoke(ctx, "/hydra.Warden/Authorized", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wardenClient) TokenAllowed(ctx context.Context, in *AccessRequest, opts ...grpc.CallOption) (*AuthorizationContext, error) {
+	out := new(AuthorizationContext)
+	err := grpc.Invoke(ctx, "/hydra.Warden/TokenAllowed", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wardenClient) Allowed(ctx context.Context, in *AccessRequest, opts ...grpc.CallOption) (*AllowedResponse, error) {
+	out := new(AllowedResponse)
+	err := grpc.Invoke(ctx, "/hydra.Warden/Allowed", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Warden service
+
+type WardenServer interface {
+	// Authorized checks whether a token is valid and carries the scopes.
+	Authorized(context.Context, *AuthorizedRequest) (*AuthorizationContext, error)
+	// TokenAllowed checks whether a token is allowed to perform an action on a resource.
+	TokenAllowed(context.Context, *AccessRequest) (*AuthorizationContext, error)
+	// Allowed checks whether a subject is allowed to perform an action on a resource.
+	Allowed(context.Context, *AccessRequest) (*AllowedResponse, error)
+}
+
+func RegisterWardenServer(s *grpc.Server, srv WardenServer) {
+	s.RegisterService(&_Warden_serviceDesc, srv)
+}
+
+func _Warden_Authorized_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WardenServer).Authorized(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hydra.Warden/Authorized",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WardenServer).Authorized(ctx, req.(*AuthorizedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Warden_TokenAllowed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WardenServer).TokenAllowed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hydra.Warden/TokenAllowed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WardenServer).TokenAllowed(ctx, req.(*AccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Warden_Allowed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WardenServer).Allowed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hydra.Warden/Allowed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WardenServer).Allowed(ctx, req.(*AccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Warden_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "hydra.Warden",
+	HandlerType: (*WardenServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Authorized",
+			Handler:    _Warden_Authorized_Handler,
+		},
+		{
+			MethodName: "TokenAllowed",
+			Handler:    _Warden_TokenAllowed_Handler,
+		},
+		{
+			MethodName: "Allowed",
+			Handler:    _Warden_Allowed_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "hydra.proto",
+}
+
+// Client API for Introspection service
+
+type IntrospectionClient interface {
+	// Introspect describes an access or refresh token. Unknown and expired tokens are not active.
+	Introspect(ctx context.Context, in *IntrospectRequest, opts ...grpc.CallOption) (*IntrospectResponse, error)
+}
+
+type introspectionClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewIntrospectionClient(cc *grpc.ClientConn) IntrospectionClient {
+	return &introspectionClient{cc}
+}
+
+func (c *introspectionClient) Introspect(ctx context.Context, in *IntrospectRequest, opts ...grpc.CallOption) (*IntrospectResponse, error) {
+	out := new(IntrospectResponse)
+	err := grpc.Invoke(ctx, "/hydra.Introspection/Introspect", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Introspection service
+
+type IntrospectionServer interface {
+	// Introspect describes an access or refresh token. Unknown and expired tokens are not active.
+	Introspect(context.Context, *IntrospectRequest) (*IntrospectResponse, error)
+}
+
+func RegisterIntrospectionServer(s *grpc.Server, srv IntrospectionServer) {
+	s.RegisterService(&_Introspection_serviceDesc, srv)
+}
+
+func _Introspection_Introspect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IntrospectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IntrospectionServer).Introspect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hydra.Introspection/Introspect",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IntrospectionServer).Introspect(ctx, req.(*IntrospectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Introspection_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "hydra.Introspection",
+	HandlerType: (*IntrospectionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Introspect",
+			Handler:    _Introspection_Introspect_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "hydra.proto",
+}
+
+func init() { proto.RegisterFile("hydra.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 660 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x94, 0x41, 0x6f, 0xd3, 0x30,
+	0x14, 0xc7, 0x95, 0x76, 0x49, 0x93, 0xd7, 0x96, 0x31, 0x6b, 0x54, 0xa6, 0xd3, 0xa4, 0x28, 0x08,
+	0x54, 0x09, 0x69, 0x87, 0x22, 0xc4, 0xe0, 0x80, 0x14, 0x26, 0x24, 0x76, 0xe0, 0x40, 0x98, 0x86,
+	0xc4, 0x25, 0xca, 0x92, 0x07, 0x0d, 0xeb, 0xe2, 0x60, 0x3b, 0x63, 0xe5, 0x33, 0x71, 0xe6, 0x3b,
+	0xf0, 0x15, 0xf8, 0x34, 0xc8, 0x8e, 0xd3, 0x36, 0xdd, 0xd4, 0x03, 0xb7, 0xfe, 0xff, 0xcf, 0x7e,
+	0xb1, 0x7f, 0xef, 0x5f, 0x43, 0x7f, 0xb6, 0xc8, 0x78, 0x72, 0x54, 0x72, 0x26, 0x19, 0xb1, 0xb5,
+	0x08, 0x42, 0xd8, 0x0b, 0x2b, 0x39, 0x63, 0x3c, 0xff, 0x89, 0x59, 0x84, 0xdf, 0x2b, 0x14, 0x92,
+	0xec, 0x83, 0x2d, 0xd9, 0x25, 0x16, 0xd4, 0xf2, 0xad, 0x89, 0x17, 0xd5, 0x82, 0x8c, 0xc0, 0x11,
+	0x29, 0x2b, 0x51, 0xd0, 0x8e, 0xdf, 0x9d, 0x78, 0x91, 0x51, 0xc1, 0xef, 0x0e, 0x0c, 0xc3, 0x34,
+	0x45, 0x21, 0xfe, 0x6b, 0x3f, 0xa1, 0xd0, 0x13, 0xd5, 0xc5, 0x37, 0x4c, 0x25, 0xed, 0xea, 0xf5,
+	0x8d, 0x24, 0x63, 0x70, 0x39, 0x0a, 0x56, 0xf1, 0x14, 0xe9, 0x8e, 0x2e, 0x2d, 0xb5, 0xea, 0x96,
+	0xa4, 0x32, 0x67, 0x05, 0xb5, 0x75, 0xc5, 0x28, 0xd5, 0x2d, 0x65, 0x85, 0xc4, 0x1b, 0x49, 0x1d,
+	0xdf, 0x9a, 0x0c, 0xa2, 0x46, 0xaa, 0x6e, 0x12, 0xaf, 0xca, 0x79, 0x22, 0x91, 0xf6, 0xea, 0x6e,
+	0x8d, 0x26, 0xc7, 0xe0, 0x5c, 0x27, 0xf3, 0x0a, 0x05, 0x75, 0xfd, 0xee, 0xa4, 0x3f, 0xf5, 0x8f,
+	0x6a, 0x56, 0xad, 0x7b, 0x1d, 0x9d, 0xeb, 0x25, 0x6f, 0x0b, 0xc9, 0x17, 0x91, 0x59, 0x3f, 0x7e,
+	0x09, 0xfd, 0x35, 0x9b, 0xdc, 0x87, 0xee, 0x25, 0x2e, 0xcc, 0xc5, 0xd5, 0x4f, 0x05, 0x43, 0x2f,
+	0xa5, 0x9d, 0x1a, 0x86, 0x16, 0xaf, 0x3a, 0xc7, 0x56, 0xf0, 0xb7, 0x03, 0xfb, 0x0d, 0xfc, 0x44,
+	0x1d, 0xfe, 0xc4, 0x9c, 0x74, 0x8d, 0x88, 0xd5, 0x26, 0xf2, 0x18, 0xee, 0x7d, 0xe5, 0x49, 0x21,
+	0x31, 0x8b, 0x5b, 0x2c, 0x87, 0xc6, 0xfd, 0x58, 0x23, 0x1d, 0x81, 0x93, 0x0b, 0x51, 0x21, 0x37,
+	0x44, 0x8d, 0x52, 0x08, 0x92, 0x2a, 0xcb, 0xb1, 0x58, 0x01, 0x6d, 0x34, 0x39, 0x00, 0x4f, 0xaf,
+	0xca, 0xe2, 0x44, 0x6a, 0xa6, 0xdd, 0xc8, 0xad, 0x8d, 0x50, 0x92, 0x43, 0x00, 0xbc, 0x29, 0x73,
+	0x8e, 0x22, 0x4e, 0x6a, 0xb0, 0xdd, 0xc8, 0x33, 0x4e, 0x28, 0xc9, 0x73, 0x18, 0x25, 0x95, 0x9c,
+	0x61, 0x21, 0xf3, 0x54, 0xdf, 0x24, 0xbe, 0x42, 0x39, 0x63, 0x99, 0xa0, 0x3d, 0x7d, 0xbc, 0x07,
+	0xed, 0xea, 0xfb, 0xba, 0x78, 0xc7, 0xb6, 0x66, 0x74, 0xae, 0x6f, 0xdd, 0xde, 0xd6, 0xe0, 0x79,
+	0x04, 0xc3, 0x4a, 0x20, 0x8f, 0xaf, 0x91, 0xe7, 0x5f, 0x72, 0xcc, 0xa8, 0xe7, 0x5b, 0x13, 0x37,
+	0x1a, 0x28, 0xf3, 0xdc, 0x78, 0xc1, 0x53, 0xd8, 0x0d, 0xe7, 0x73, 0xf6, 0x43, 0xa5, 0x5a, 0x94,
+	0xac, 0x10, 0xa8, 0xb0, 0x26, 0xb5, 0xa5, 0xb1, 0xba, 0x51, 0x23, 0x83, 0x0f, 0xb0, 0x77, 0x5a,
+	0x48, 0xce, 0x44, 0x89, 0xa9, 0xdc, 0x9e, 0xe2, 0x27, 0xb0, 0xab, 0x7f, 0xc4, 0x72, 0x51, 0x62,
+	0x3c, 0xcb, 0x0b, 0x69, 0x06, 0x3b, 0xd4, 0xf6, 0xd9, 0xa2, 0xc4, 0x77, 0x79, 0x21, 0x83, 0x5f,
+	0x1d, 0x20, 0xeb, 0x3d, 0xcd, 0x19, 0x4c, 0x6c, 0xaf, 0xd1, 0x1c, 0xc1, 0x28, 0xf5, 0x31, 0x3d,
+	0xd0, 0x26, 0x25, 0x5a, 0xa8, 0x99, 0xa4, 0xf3, 0x1c, 0x0b, 0x19, 0xe7, 0x99, 0x19, 0xa5, 0x5b,
+	0x1b, 0xa7, 0xd9, 0x7a, 0x4a, 0x76, 0xda, 0x29, 0x69, 0x4f, 0xcb, 0xde, 0x9c, 0x56, 0x6b, 0xd2,
+	0xce, 0xc6, 0xa4, 0xd7, 0x23, 0xd2, 0xdb, 0x88, 0xc8, 0x2a, 0x56, 0x6e, 0x2b, 0x56, 0x87, 0x00,
+	0x2b, 0x26, 0x7a, 0x1a, 0x5e, 0xe4, 0x2d, 0x71, 0x90, 0x00, 0xec, 0x24, 0x95, 0x8c, 0x53, 0xf0,
+	0xad, 0x49, 0x7f, 0x3a, 0x58, 0xfe, 0xb7, 0x24, 0xe3, 0x51, 0x5d, 0x0a, 0x5e, 0x83, 0xad, 0xf5,
+	0x96, 0xec, 0xb7, 0x60, 0x74, 0xda, 0x30, 0xa6, 0x7f, 0x2c, 0x70, 0x3e, 0x25, 0x3c, 0xc3, 0x82,
+	0x9c, 0x00, 0xac, 0x9e, 0x34, 0x42, 0x9b, 0xaf, 0x6d, 0xbe, 0x72, 0xe3, 0x83, 0x8d, 0x4a, 0x2b,
+	0x63, 0x21, 0x0c, 0xce, 0xd4, 0x05, 0x4c, 0x86, 0xc8, 0xfe, 0x5d, 0x0f, 0xc2, 0xf6, 0x16, 0x2f,
+	0xa0, 0xb7, 0x7d, 0xf7, 0xa8, 0x71, 0xdb, 0x39, 0x9d, 0x46, 0x30, 0x5c, 0x25, 0x47, 0xbd, 0x69,
+	0x21, 0xc0, 0xca, 0x58, 0xde, 0xe8, 0x56, 0x62, 0xc7, 0x0f, 0xef, 0xa8, 0xd4, 0x3d, 0xdf, 0xd8,
+	0x9f, 0xbb, 0xbc, 0x4c, 0x2f, 0x1c, 0xfd, 0xf8, 0x3f, 0xfb, 0x37, 0x00, 0x1a, 0xb5, 0xb3, 0x59,
+	0x0b, 0x06, 0x00, 0x00,
+}
@@ -0,0 +1,265 @@
+package rpc
+
+//go:generate protoc --go_out=plugins=grpc:. hydra.proto
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/warden"
+	"github.com/ory-am/ladon"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// AuthorizationMetadata is the metadata key of the access token callers authenticate with, as "bearer <token>".
+const AuthorizationMetadata = "authorization"
+
+var errMissingToken = grpc.Errorf(codes.Unauthenticated, "The call carries no bearer token in its %s metadata", AuthorizationMetadata)
+
+// Server answers the gRPC services with the handlers of the HTTP endpoints, so that both transports give the same
+// answers. Callers authenticate with the access token they would send to the HTTP endpoints and need to be allowed
+// the same actions.
+type Server struct {
+	Warden *warden.WardenHandler
+
+	// Introspection answers the introspection service, which is not registered if Introspection is nil.
+	Introspection *oauth2.IntrospectionHandler
+}
+
+// Register adds the services to the gRPC server.
+func (s *Server) Register(g *grpc.Server) {
+	RegisterWardenServer(g, s)
+	if s.Introspection != nil {
+		RegisterIntrospectionServer(g, s)
+	}
+}
+
+func (s *Server) Authorized(ctx context.Context, in *AuthorizedRequest) (*AuthorizationContext, error) {
+	caller, err := s.authorizeCaller(ctx, "an:hydra:warden:authorized")
+	if err != nil {
+		return nil, err
+	}
+
+	authContext, err := s.Warden.Warden.Authorized(ctx, in.Token, in.Scopes...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.authorizationContext(caller, authContext)
+}
+
+func (s *Server) TokenAllowed(ctx context.Context, in *AccessRequest) (*AuthorizationContext, error) {
+	caller, err := s.authorizeCaller(ctx, "an:hydra:warden:allowed")
+	if err != nil {
+		return nil, err
+	}
+
+	ar, err := s.accessRequest(in)
+	if err != nil {
+		return nil, err
+	}
+
+	authContext, err := s.Warden.Warden.ActionAllowed(ctx, in.Token, ar, in.Scopes...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.authorizationContext(caller, authContext)
+}
+
+// Allowed checks the subject of the request, or the token if one is set. Denied requests are not an error.
+func (s *Server) Allowed(ctx context.Context, in *AccessRequest) (*AllowedResponse, error) {
+	if _, err := s.authorizeCaller(ctx, "an:hydra:warden:allowed"); err != nil {
+		return nil, err
+	}
+
+	ar, err := s.accessRequest(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Token != "" {
+		_, err = s.Warden.Warden.ActionAllowed(ctx, in.Token, ar, in.Scopes...)
+		if errors.Is(err, herodot.ErrForbidden) || isDenied(err) {
+			return &AllowedResponse{Allowed: false}, nil
+		} else if err != nil {
+			return nil, toStatus(err)
+		}
+		return &AllowedResponse{Allowed: true}, nil
+	}
+
+	if ar.Subject == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Either subject or token is required")
+	}
+
+	if err := s.Warden.Ladon.IsAllowed(ar); isDenied(err) {
+		return &AllowedResponse{Allowed: false}, nil
+	} else if err != nil {
+		return nil, toStatus(err)
+	}
+	return &AllowedResponse{Allowed: true}, nil
+}
+
+func (s *Server) Introspect(ctx context.Context, in *IntrospectRequest) (*IntrospectResponse, error) {
+	token := tokenFromContext(ctx)
+	if token == "" {
+		return nil, errMissingToken
+	}
+
+	caller, err := s.Introspection.AuthorizeCaller(ctx, token)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if in.Token == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Parameter token is missing")
+	}
+
+	i, err := s.Introspection.IntrospectToken(ctx, in.Token, in.TokenTypeHint)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	i, err = s.Introspection.FilterIntrospection(caller.Subject, i)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	out := &IntrospectResponse{
+		Active:    i.Active,
+		Scope:     i.Scope,
+		ClientId:  i.ClientID,
+		Subject:   i.Subject,
+		ExpiresAt: i.ExpiresAt,
+		IssuedAt:  i.IssuedAt,
+		Audience:  i.Audience,
+		Issuer:    i.Issuer,
+		TokenType: i.TokenType,
+	}
+	if i.Actor != nil {
+		out.Actor = &Actor{Subject: i.Actor.Subject, ClientId: i.Actor.ClientID}
+	}
+	return out, nil
+}
+
+// authorizeCaller checks that the token of the call allows the resource server to ask the warden.
+func (s *Server) authorizeCaller(ctx context.Context, action string) (*firewall.Context, error) {
+	token := tokenFromContext(ctx)
+	if token == "" {
+		return nil, errMissingToken
+	}
+
+	caller, err := s.Warden.AuthorizeCaller(ctx, token, action)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return caller, nil
+}
+
+// accessRequest converts the request and expands its resource template like the HTTP endpoints do.
+func (s *Server) accessRequest(in *AccessRequest) (*ladon.Request, error) {
+	ar := &ladon.Request{
+		Subject:  in.Subject,
+		Resource: in.Resource,
+		Action:   in.Action,
+	}
+	if len(in.Context) > 0 {
+		if err := json.Unmarshal(in.Context, &ar.Context); err != nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "Context is not a JSON object: %s", err)
+		}
+	}
+
+	if in.Template == "" {
+		return ar, nil
+	} else if s.Warden.Templates == nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Resource templates are not enabled")
+	}
+
+	err := warden.ExpandTemplate(s.Warden.Templates, ar, &warden.WardenTemplateRequest{Template: in.Template, Values: in.Values})
+	if errors.Is(err, pkg.ErrNotFound) {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Resource template %s is not registered", in.Template)
+	} else if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%s", err)
+	}
+	return ar, nil
+}
+
+// authorizationContext filters the context for the resource server which asked, like the HTTP endpoints do.
+func (s *Server) authorizationContext(caller, c *firewall.Context) (*AuthorizationContext, error) {
+	c.Audience = caller.Subject
+	filtered, err := s.Warden.FilterContext(caller.Subject, c)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &AuthorizationContext{
+		Subject:               filtered.Subject,
+		GrantedScopes:         filtered.GrantedScopes,
+		Issuer:                filtered.Issuer,
+		Audience:              filtered.Audience,
+		IssuedAt:              unix(filtered.IssuedAt),
+		ExpiresAt:             unix(filtered.ExpiresAt),
+		AuthenticationMethods: filtered.AuthenticationMethods,
+		AuthenticationContext: filtered.AuthenticationContext,
+		UserVerified:          filtered.UserVerified,
+	}, nil
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// tokenFromContext returns the bearer token of the authorization metadata of the call.
+func tokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md[AuthorizationMetadata]) == 0 {
+		return ""
+	}
+
+	split := strings.SplitN(md[AuthorizationMetadata][0], " ", 2)
+	if len(split) != 2 || !strings.EqualFold(split[0], "bearer") {
+		return ""
+	}
+	return split[1]
+}
+
+func isDenied(err error) bool {
+	return errors.Is(err, ladon.ErrRequestDenied) || errors.Is(err, ladon.ErrRequestForcefullyDenied)
+}
+
+// toStatus converts the errors of the warden to the gRPC status codes which correspond to the HTTP status codes of
+// the HTTP endpoints.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, pkg.ErrUnauthorized), errors.Is(err, fosite.ErrRequestUnauthorized):
+		return grpc.Errorf(codes.Unauthenticated, "%s", err)
+	case errors.Is(err, pkg.ErrForbidden), isDenied(err):
+		return grpc.Errorf(codes.PermissionDenied, "%s", err)
+	case errors.Is(err, pkg.ErrNotFound):
+		return grpc.Errorf(codes.NotFound, "%s", err)
+	}
+
+	switch herodot.ToError(err).Code {
+	case http.StatusBadRequest:
+		return grpc.Errorf(codes.InvalidArgument, "%s", err)
+	case http.StatusUnauthorized:
+		return grpc.Errorf(codes.Unauthenticated, "%s", err)
+	case http.StatusForbidden:
+		return grpc.Errorf(codes.PermissionDenied, "%s", err)
+	case http.StatusNotFound:
+		return grpc.Errorf(codes.NotFound, "%s", err)
+	}
+	return grpc.Errorf(codes.Internal, "%s", err)
+}
@@ -0,0 +1,137 @@
+package rpc_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ory-am/fosite"
+	"github.com/ory-am/fosite/handler/core"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	. "github.com/ory-am/hydra/rpc"
+	"github.com/ory-am/hydra/warden"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestServer(t *testing.T) {
+	ladonWarden := pkg.LadonWarden(map[string]ladon.Policy{
+		"1": &ladon.DefaultPolicy{
+			ID:        "1",
+			Subjects:  []string{"alice"},
+			Resources: []string{"matrix"},
+			Actions:   []string{"create"},
+			Effect:    ladon.AllowAccess,
+		},
+		"2": &ladon.DefaultPolicy{
+			ID:        "2",
+			Subjects:  []string{"siri"},
+			Resources: []string{"<.*>"},
+			Actions:   []string{"an:hydra:warden:allowed", "an:hydra:warden:authorized", "introspect"},
+			Effect:    ladon.AllowAccess,
+		},
+	})
+
+	store := pkg.FositeStore()
+	tokens := pkg.Tokens(2)
+	ar := fosite.NewAccessRequest(&oauth2.Session{Subject: "alice"})
+	ar.GrantedScopes = fosite.Arguments{"core"}
+	store.CreateAccessTokenSession(nil, tokens[0][0], ar)
+	ar = fosite.NewAccessRequest(&oauth2.Session{Subject: "siri"})
+	ar.GrantedScopes = fosite.Arguments{"hydra.warden", "hydra.introspect"}
+	store.CreateAccessTokenSession(nil, tokens[1][0], ar)
+
+	validator := &core.CoreValidator{AccessTokenStrategy: pkg.HMACStrategy, AccessTokenStorage: store}
+	w := &warden.LocalWarden{Warden: ladonWarden, TokenValidator: validator, Issuer: "tests"}
+
+	servers := warden.NewResourceServerMemoryManager()
+	wardenHandler := &warden.WardenHandler{Warden: w, Ladon: ladonWarden, ResourceServers: servers}
+
+	g := grpc.NewServer()
+	(&Server{
+		Warden: wardenHandler,
+		Introspection: &oauth2.IntrospectionHandler{
+			AccessTokens:         validator,
+			RefreshTokenStrategy: pkg.HMACStrategy,
+			RefreshTokenStorage:  store,
+			AccessTokenLifespan:  time.Hour,
+			Issuer:               "tests",
+			Filter:               wardenHandler,
+			W:                    w,
+		},
+	}).Register(g)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	go g.Serve(l)
+	defer g.Stop()
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure())
+	require.Nil(t, err)
+	defer conn.Close()
+
+	wardenClient := NewWardenClient(conn)
+	introspectionClient := NewIntrospectionClient(conn)
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(AuthorizationMetadata, "bearer "+tokens[1][1]))
+
+	_, err = wardenClient.Authorized(context.Background(), &AuthorizedRequest{Token: tokens[0][1], Scopes: []string{"core"}})
+	assert.Equal(t, codes.Unauthenticated, grpc.Code(err))
+
+	authContext, err := wardenClient.Authorized(ctx, &AuthorizedRequest{Token: tokens[0][1], Scopes: []string{"core"}})
+	require.Nil(t, err)
+	assert.Equal(t, "alice", authContext.Subject)
+	assert.Equal(t, "siri", authContext.Audience)
+
+	_, err = wardenClient.Authorized(ctx, &AuthorizedRequest{Token: tokens[0][1], Scopes: []string{"foo"}})
+	assert.Equal(t, codes.PermissionDenied, grpc.Code(err))
+
+	authContext, err = wardenClient.TokenAllowed(ctx, &AccessRequest{
+		Token: tokens[0][1], Scopes: []string{"core"}, Resource: "matrix", Action: "create",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "alice", authContext.Subject)
+
+	for k, c := range []struct {
+		req     *AccessRequest
+		allowed bool
+	}{
+		{req: &AccessRequest{Subject: "alice", Resource: "matrix", Action: "create"}, allowed: true},
+		{req: &AccessRequest{Subject: "alice", Resource: "matrix", Action: "delete"}},
+		{req: &AccessRequest{Token: tokens[0][1], Scopes: []string{"core"}, Resource: "matrix", Action: "create"}, allowed: true},
+		{req: &AccessRequest{Token: tokens[0][1], Scopes: []string{"core"}, Resource: "matrix", Action: "delete"}},
+	} {
+		res, err := wardenClient.Allowed(ctx, c.req)
+		require.Nil(t, err, "Case %d", k)
+		assert.Equal(t, c.allowed, res.Allowed, "Case %d", k)
+	}
+
+	_, err = wardenClient.Allowed(ctx, &AccessRequest{Resource: "matrix", Action: "create"})
+	assert.Equal(t, codes.InvalidArgument, grpc.Code(err))
+
+	i, err := introspectionClient.Introspect(ctx, &IntrospectRequest{Token: tokens[0][1]})
+	require.Nil(t, err)
+	assert.True(t, i.Active)
+	assert.Equal(t, "alice", i.Subject)
+	assert.Equal(t, "access_token", i.TokenType)
+
+	i, err = introspectionClient.Introspect(ctx, &IntrospectRequest{Token: "invalid"})
+	require.Nil(t, err)
+	assert.False(t, i.Active)
+
+	_, err = introspectionClient.Introspect(context.Background(), &IntrospectRequest{Token: tokens[0][1]})
+	assert.Equal(t, codes.Unauthenticated, grpc.Code(err))
+
+	// A restricted resource server sees neither the scopes of other audiences nor the subject.
+	require.Nil(t, servers.CreateResourceServer(&warden.ResourceServer{ID: "siri", Scopes: []string{"photos"}, OmitSubject: true}))
+	i, err = introspectionClient.Introspect(ctx, &IntrospectRequest{Token: tokens[0][1]})
+	require.Nil(t, err)
+	assert.True(t, i.Active)
+	assert.Equal(t, "", i.Subject)
+	assert.Equal(t, "", i.Scope)
+}
@@ -0,0 +1,91 @@
+// Package scope decides whether granted scopes cover a requested scope. The token and authorize endpoints apply a
+// strategy to the scopes a client may request, the warden to the scopes a token was granted.
+package scope
+
+import (
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// Strategy returns true if one of the granted scopes covers the requested scope.
+type Strategy func(granted []string, requested string) bool
+
+const (
+	// StrategyHierarchic is the name of Hierarchic, which is the default strategy.
+	StrategyHierarchic = "hierarchic"
+	StrategyExact      = "exact"
+	StrategyWildcard   = "wildcard"
+)
+
+// Exact only covers a requested scope by the same scope.
+func Exact(granted []string, requested string) bool {
+	for _, g := range granted {
+		if g == requested {
+			return true
+		}
+	}
+	return false
+}
+
+// Hierarchic covers a requested scope by the scope itself and by its parents in dot notation, hydra.keys covers
+// hydra.keys.get but neither hydra nor hydra.keysets.
+func Hierarchic(granted []string, requested string) bool {
+	for _, g := range granted {
+		if g == requested || strings.HasPrefix(requested, g+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Wildcard covers a requested scope by scopes whose segments are either equal or *, hydra.*.get covers hydra.keys.get
+// and hydra.clients.get. A * matches exactly one segment, so hydra.* covers hydra.keys but not hydra.keys.get.
+func Wildcard(granted []string, requested string) bool {
+	needle := strings.Split(requested, ".")
+	for _, g := range granted {
+		haystack := strings.Split(g, ".")
+		if len(haystack) != len(needle) {
+			continue
+		}
+
+		matches := true
+		for k, segment := range haystack {
+			if segment != "*" && segment != needle[k] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse returns the strategy of a name, the empty name is StrategyHierarchic.
+func Parse(name string) (Strategy, error) {
+	switch name {
+	case "", StrategyHierarchic:
+		return Hierarchic, nil
+	case StrategyExact:
+		return Exact, nil
+	case StrategyWildcard:
+		return Wildcard, nil
+	}
+	return nil, errors.Errorf("Scope strategy %s is not supported, use %s, %s or %s", name, StrategyHierarchic, StrategyExact, StrategyWildcard)
+}
+
+// Covers returns true if the granted scopes cover every requested scope. A nil strategy is Hierarchic.
+func Covers(strategy Strategy, granted []string, requested []string) bool {
+	if strategy == nil {
+		strategy = Hierarchic
+	}
+
+	for _, r := range requested {
+		if !strategy(granted, r) {
+			return false
+		}
+	}
+	return true
+}
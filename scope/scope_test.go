@@ -0,0 +1,37 @@
+package scope_test
+
+import (
+	"testing"
+
+	. "github.com/ory-am/hydra/scope"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrategies(t *testing.T) {
+	granted := []string{"core", "hydra.keys", "photos.*.read"}
+	for k, c := range []struct {
+		requested  string
+		exact      bool
+		hierarchic bool
+		wildcard   bool
+	}{
+		{requested: "core", exact: true, hierarchic: true, wildcard: true},
+		{requested: "hydra.keys", exact: true, hierarchic: true, wildcard: true},
+		{requested: "hydra.keys.get", hierarchic: true},
+		{requested: "hydra.keysets"},
+		{requested: "hydra"},
+		{requested: "photos.albums.read", wildcard: true},
+		{requested: "photos.albums.write"},
+		{requested: "photos.albums.covers.read"},
+	} {
+		assert.Equal(t, c.exact, Exact(granted, c.requested), "Case %d", k)
+		assert.Equal(t, c.hierarchic, Hierarchic(granted, c.requested), "Case %d", k)
+		assert.Equal(t, c.wildcard, Wildcard(granted, c.requested), "Case %d", k)
+	}
+
+	assert.True(t, Covers(nil, granted, []string{"core", "hydra.keys.get"}))
+	assert.False(t, Covers(Exact, granted, []string{"core", "hydra.keys.get"}))
+
+	_, err := Parse("regex")
+	assert.NotNil(t, err)
+}
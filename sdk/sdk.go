@@ -0,0 +1,132 @@
+// Package sdk connects Go programs to the HTTP API of a hydra cluster. It bundles the HTTP clients of the keys,
+// clients, policies, warden and introspection endpoints, fetches and renews access tokens with the client credentials
+// grant and retries requests which failed because the cluster was temporarily unavailable.
+//
+//	c, err := sdk.Connect(&sdk.Options{
+//		ClusterURL:   "https://localhost:4444",
+//		ClientID:     "my-service",
+//		ClientSecret: "secret",
+//	})
+//	if err != nil {
+//		// ...
+//	}
+//
+//	ctx, err := c.Warden.ActionAllowed(context.Background(), token, &ladon.Request{
+//		Resource: "rn:my-service:articles:1",
+//		Action:   "read",
+//	}, "articles")
+package sdk
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/client"
+	"github.com/ory-am/hydra/jwk"
+	hoauth2 "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/policy"
+	"github.com/ory-am/hydra/warden"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// DefaultScopes are requested if Options does not name any scopes. These are the scopes the CLI requests.
+var DefaultScopes = []string{"core", "hydra"}
+
+// Options describes how to connect to a cluster.
+type Options struct {
+	// ClusterURL is the URL of the cluster, for example https://localhost:4444.
+	ClusterURL string
+
+	// ClientID and ClientSecret are the credentials of the client the SDK acts as.
+	ClientID     string
+	ClientSecret string
+
+	// Scopes are requested for the access token, they default to DefaultScopes.
+	Scopes []string
+
+	// HTTPClient sends the token requests and its transport all other requests. It defaults to http.DefaultClient
+	// and may be used to configure TLS.
+	HTTPClient *http.Client
+
+	// MaxRetries is how often a request is retried, it defaults to DefaultMaxRetries. A negative value disables
+	// retries.
+	MaxRetries int
+}
+
+// Client gives access to the HTTP API of a cluster. All fields share one HTTP client which authenticates with the
+// access token of Options.ClientID.
+type Client struct {
+	Clients       *client.HTTPManager
+	Keys          *jwk.HTTPManager
+	Policies      *policy.HTTPManager
+	Warden        *warden.HTTPWarden
+	Introspection *hoauth2.HTTPIntrospector
+
+	// HTTPClient is the authenticated client, it may be used for endpoints which have no typed client yet.
+	HTTPClient *http.Client
+}
+
+// Connect returns a client of the cluster described by o. The access token is requested by the first request and
+// renewed once it expires.
+func Connect(o *Options) (*Client, error) {
+	if o.ClusterURL == "" {
+		return nil, errors.New("The cluster URL is missing")
+	} else if o.ClientID == "" || o.ClientSecret == "" {
+		return nil, errors.New("The client id and client secret are missing")
+	}
+
+	cluster, err := url.Parse(strings.TrimRight(o.ClusterURL, "/"))
+	if err != nil {
+		return nil, errors.New(err)
+	}
+
+	scopes := o.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+
+	base := o.HTTPClient
+	if base == nil {
+		base = http.DefaultClient
+	}
+
+	maxRetries := o.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultMaxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	retrying := &http.Client{
+		Transport: &Transport{
+			Base:       base.Transport,
+			MaxRetries: maxRetries,
+		},
+		CheckRedirect: base.CheckRedirect,
+		Jar:           base.Jar,
+		Timeout:       base.Timeout,
+	}
+
+	credentials := &clientcredentials.Config{
+		ClientID:     o.ClientID,
+		ClientSecret: o.ClientSecret,
+		TokenURL:     pkg.JoinURL(cluster, "/oauth2/token").String(),
+		Scopes:       scopes,
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, retrying)
+	authenticated := credentials.Client(ctx)
+
+	return &Client{
+		Clients:       &client.HTTPManager{Client: authenticated, Endpoint: pkg.JoinURL(cluster, "/clients")},
+		Keys:          &jwk.HTTPManager{Client: authenticated, Endpoint: pkg.JoinURL(cluster, "/keys")},
+		Policies:      &policy.HTTPManager{Client: authenticated, Endpoint: pkg.JoinURL(cluster, "/policies")},
+		Warden:        &warden.HTTPWarden{Client: authenticated, Endpoint: cluster},
+		Introspection: &hoauth2.HTTPIntrospector{Client: authenticated, Endpoint: cluster},
+		HTTPClient:    authenticated,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package sdk_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory-am/hydra/idempotency"
+	. "github.com/ory-am/hydra/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestTransport(t *testing.T) {
+	var calls int
+	var keys []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		keys = append(keys, r.Header.Get(idempotency.KeyHeader))
+		body, _ := ioutil.ReadAll(r.Body)
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	c := &http.Client{Transport: &Transport{MaxRetries: 2, MinWait: time.Millisecond}}
+
+	resp, err := c.Post(ts.URL+"/clients", "application/json", strings.NewReader("foo"))
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "foo", string(body))
+	assert.Equal(t, 3, calls)
+	require.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+
+	calls = 0
+	resp, err = c.Get(ts.URL + "/clients")
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	calls = -10
+	resp, err = c.Get(ts.URL + "/clients")
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, -7, calls)
+}
+
+func TestConnect(t *testing.T) {
+	var tokens int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/token":
+			tokens++
+			id, secret, _ := r.BasicAuth()
+			assert.Equal(t, "foo", id)
+			assert.Equal(t, "bar", secret)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "token_type": "bearer", "expires_in": 3600})
+		case "/oauth2/introspect":
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			assert.Equal(t, "some-token", r.PostFormValue("token"))
+			json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "sub": "peter"})
+		case "/clients/foo":
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": "foo"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	_, err := Connect(&Options{ClusterURL: ts.URL})
+	assert.NotNil(t, err)
+
+	c, err := Connect(&Options{ClusterURL: ts.URL + "/", ClientID: "foo", ClientSecret: "bar"})
+	require.Nil(t, err)
+
+	cl, err := c.Clients.GetClient("foo")
+	require.Nil(t, err)
+	assert.Equal(t, "foo", cl.GetID())
+
+	in, err := c.Introspection.IntrospectToken(context.Background(), "some-token", "")
+	require.Nil(t, err)
+	assert.True(t, in.Active)
+	assert.Equal(t, "peter", in.Subject)
+	assert.Equal(t, 1, tokens)
+}
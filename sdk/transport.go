@@ -0,0 +1,145 @@
+package sdk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/idempotency"
+	hoauth2 "github.com/ory-am/hydra/oauth2"
+	"github.com/ory-am/hydra/warden"
+	"github.com/pborman/uuid"
+)
+
+const (
+	// DefaultMaxRetries is how often a request is retried if Options does not say otherwise.
+	DefaultMaxRetries = 3
+
+	// DefaultMinWait is the wait before the first retry, it doubles with every retry.
+	DefaultMinWait = time.Millisecond * 250
+
+	// DefaultMaxWait limits the wait between two retries.
+	DefaultMaxWait = time.Second * 5
+)
+
+// readOnlyPaths are answered with POST although the requests change nothing, so that they may be retried.
+var readOnlyPaths = map[string]bool{
+	warden.AuthorizedHandlerPath:     true,
+	warden.AllowedHandlerPath:        true,
+	warden.TokenAllowedHandlerPath:   true,
+	hoauth2.IntrospectionHandlerPath: true,
+}
+
+// Transport retries requests which failed because of a network error or because the cluster was unavailable or
+// rate limited them. POST and PATCH requests which create or change resources are sent with an Idempotency-Key
+// header, so that the cluster executes them once even if a retry follows a request whose response got lost.
+type Transport struct {
+	// Base sends the requests, it defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	MaxRetries int
+
+	// MinWait and MaxWait default to DefaultMinWait and DefaultMaxWait. A Retry-After header of the response takes
+	// precedence.
+	MinWait time.Duration
+	MaxWait time.Duration
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.MaxRetries <= 0 {
+		return t.base().RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, errors.New(err)
+		}
+	}
+
+	// A RoundTripper must not change the request it was given.
+	header := http.Header{}
+	for k, v := range req.Header {
+		header[k] = append([]string{}, v...)
+	}
+	if (req.Method == "POST" || req.Method == "PATCH") && !readOnlyPaths[req.URL.Path] && header.Get(idempotency.KeyHeader) == "" {
+		header.Set(idempotency.KeyHeader, uuid.New())
+	}
+
+	wait := t.minWait()
+	for retry := 0; ; retry++ {
+		attempt := new(http.Request)
+		*attempt = *req
+		attempt.Header = header
+		if req.Body != nil {
+			attempt.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := t.base().RoundTrip(attempt)
+		if retry >= t.MaxRetries || !retryable(resp, err) {
+			return resp, err
+		}
+
+		next := wait
+		if resp != nil {
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+				next = time.Second * time.Duration(seconds)
+			}
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		if next > t.maxWait() {
+			next = t.maxWait()
+		}
+
+		select {
+		case <-time.After(next):
+		case <-req.Cancel:
+			return nil, errors.New("The request was canceled")
+		}
+
+		if wait *= 2; wait > t.maxWait() {
+			wait = t.maxWait()
+		}
+	}
+}
+
+// retryable tells whether a request may succeed if it is sent again.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) minWait() time.Duration {
+	if t.MinWait > 0 {
+		return t.MinWait
+	}
+	return DefaultMinWait
+}
+
+func (t *Transport) maxWait() time.Duration {
+	if t.MaxWait > 0 {
+		return t.MaxWait
+	}
+	return DefaultMaxWait
+}
@@ -0,0 +1,49 @@
+package swagger
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory-am/hydra/herodot"
+)
+
+// Handler serves the document. It is not protected by the warden, so that clients can be generated without
+// credentials.
+type Handler struct {
+	Document *Document
+	H        herodot.Herodot
+}
+
+func (h *Handler) SetRoutes(r *httprouter.Router) {
+	r.GET(SpecHandlerPath, h.Get)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.H.Write(herodot.NewContext(), w, r, h.Document)
+}
+
+// Routed returns the operations which are served by router. Handlers which are disabled by the configuration do
+// not register their routes, so that their operations are not described.
+func Routed(router *httprouter.Router, ops ...[]*Operation) []*Operation {
+	var routed []*Operation
+	for _, o := range ops {
+		for _, op := range o {
+			if handle, _, _ := router.Lookup(op.Method, examplePath(op.Path)); handle != nil {
+				routed = append(routed, op)
+			}
+		}
+	}
+	return routed
+}
+
+// examplePath replaces the parameters of path with values.
+func examplePath(path string) string {
+	segments := strings.Split(path, "/")
+	for k, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[k] = "x"
+		}
+	}
+	return strings.Join(segments, "/")
+}
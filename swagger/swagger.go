@@ -0,0 +1,230 @@
+// Package swagger generates the OpenAPI (Swagger 2.0) document of the HTTP API. Each package describes the endpoints
+// of its handlers with Operations, the schemas of request and response bodies are derived from the Go types which
+// the handlers read and write.
+package swagger
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SpecHandlerPath serves the document.
+	SpecHandlerPath = "/swagger.json"
+
+	// APIVersion is the version of the HTTP API, it changes when endpoints change incompatibly.
+	APIVersion = "1.0"
+
+	oauth2Security = "oauth2"
+	basicSecurity  = "basic"
+	bearerSecurity = "bearer"
+)
+
+// Operation describes an endpoint.
+type Operation struct {
+	// Method and Path of the route, Path uses the syntax of httprouter, for example /clients/:id.
+	Method string
+	Path   string
+
+	ID      string
+	Tag     string
+	Summary string
+
+	// Scope is the OAuth2 scope the access token of the caller needs. Operations without a scope are not protected
+	// by the warden, unless ClientAuth or BearerAuth is set.
+	Scope string
+
+	// ClientAuth is set if the caller authenticates with its client id and secret.
+	ClientAuth bool
+
+	// BearerAuth is set if the caller authenticates with a token which is not an access token, for example a
+	// registration access token.
+	BearerAuth bool
+
+	// Query and Form name the parameters read from the query and from a form encoded body.
+	Query []string
+	Form  []string
+
+	// Pagination names the fields a listing can be sorted and filtered by, see the pagination package.
+	Pagination []string
+
+	// Body is a value of the type of the request body, or nil.
+	Body interface{}
+
+	// Responses maps status codes to a value of the type of the response body, or to nil if there is none.
+	Responses map[int]interface{}
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Document struct {
+	Swagger             string                          `json:"swagger"`
+	Info                Info                            `json:"info"`
+	Consumes            []string                        `json:"consumes"`
+	Produces            []string                        `json:"produces"`
+	Paths               map[string]map[string]*Endpoint `json:"paths"`
+	Definitions         map[string]*Schema              `json:"definitions"`
+	SecurityDefinitions map[string]*SecurityScheme      `json:"securityDefinitions"`
+
+	// types maps the names of the definitions to the types they describe.
+	types map[string]reflect.Type
+}
+
+type Endpoint struct {
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Consumes    []string              `json:"consumes,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Type     string  `json:"type,omitempty"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string             `json:"description"`
+	Schema      *Schema            `json:"schema,omitempty"`
+	Headers     map[string]*Header `json:"headers,omitempty"`
+}
+
+type Header struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type     string            `json:"type"`
+	Flow     string            `json:"flow,omitempty"`
+	TokenURL string            `json:"tokenUrl,omitempty"`
+	Scopes   map[string]string `json:"scopes,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	In       string            `json:"in,omitempty"`
+}
+
+// New returns an empty document of the API served for issuer.
+func New(issuer string) *Document {
+	return &Document{
+		Swagger:     "2.0",
+		Info:        Info{Title: "Hydra", Version: APIVersion},
+		Consumes:    []string{"application/json"},
+		Produces:    []string{"application/json"},
+		Paths:       map[string]map[string]*Endpoint{},
+		Definitions: map[string]*Schema{},
+		SecurityDefinitions: map[string]*SecurityScheme{
+			oauth2Security: {
+				Type:     "oauth2",
+				Flow:     "application",
+				TokenURL: strings.TrimRight(issuer, "/") + "/oauth2/token",
+				Scopes:   map[string]string{},
+			},
+			basicSecurity: {Type: "basic"},
+			bearerSecurity: {
+				Type: "apiKey",
+				Name: "Authorization",
+				In:   "header",
+			},
+		},
+	}
+}
+
+// Add describes the operations in the document.
+func (d *Document) Add(ops ...*Operation) {
+	for _, op := range ops {
+		path, params := pathParameters(op.Path)
+		e := &Endpoint{
+			OperationID: op.ID,
+			Summary:     op.Summary,
+			Parameters:  params,
+			Responses:   map[string]*Response{},
+		}
+		if op.Tag != "" {
+			e.Tags = []string{op.Tag}
+		}
+
+		for _, name := range op.Query {
+			e.Parameters = append(e.Parameters, &Parameter{Name: name, In: "query", Type: "string"})
+		}
+		if len(op.Pagination) > 0 {
+			e.Parameters = append(e.Parameters,
+				&Parameter{Name: "limit", In: "query", Type: "integer"},
+				&Parameter{Name: "offset", In: "query", Type: "integer"},
+				&Parameter{Name: "sort", In: "query", Type: "string"},
+			)
+			for _, field := range op.Pagination {
+				e.Parameters = append(e.Parameters, &Parameter{Name: field, In: "query", Type: "string"})
+			}
+		}
+		if len(op.Form) > 0 {
+			e.Consumes = []string{"application/x-www-form-urlencoded"}
+			for _, name := range op.Form {
+				e.Parameters = append(e.Parameters, &Parameter{Name: name, In: "formData", Type: "string"})
+			}
+		}
+		if op.Body != nil {
+			e.Parameters = append(e.Parameters, &Parameter{Name: "body", In: "body", Required: true, Schema: d.schema(reflect.TypeOf(op.Body))})
+		}
+
+		for status, body := range op.Responses {
+			res := &Response{Description: http.StatusText(status)}
+			if body != nil {
+				res.Schema = d.schema(reflect.TypeOf(body))
+			}
+			if len(op.Pagination) > 0 && status == http.StatusOK {
+				res.Headers = map[string]*Header{
+					"X-Total-Count": {Type: "integer", Description: "The number of items which matched the filters."},
+					"Link":          {Type: "string", Description: "Links to the first, previous, next and last page."},
+				}
+			}
+			e.Responses[strconv.Itoa(status)] = res
+		}
+		e.Responses["default"] = &Response{Description: "Error", Schema: d.schema(reflect.TypeOf(&Error{}))}
+
+		switch {
+		case op.Scope != "":
+			e.Security = []map[string][]string{{oauth2Security: {op.Scope}}}
+			d.SecurityDefinitions[oauth2Security].Scopes[op.Scope] = "Grants access to " + op.Tag + "."
+		case op.ClientAuth:
+			e.Security = []map[string][]string{{basicSecurity: {}}}
+		case op.BearerAuth:
+			e.Security = []map[string][]string{{bearerSecurity: {}}}
+		}
+
+		if d.Paths[path] == nil {
+			d.Paths[path] = map[string]*Endpoint{}
+		}
+		d.Paths[path][strings.ToLower(op.Method)] = e
+	}
+}
+
+// Error is the body of error responses.
+type Error struct {
+	RequestID string `json:"request"`
+	Error     string `json:"error"`
+	Code      int    `json:"code"`
+}
+
+// pathParameters converts the syntax of httprouter to the one of Swagger and returns the path parameters.
+func pathParameters(path string) (string, []*Parameter) {
+	var params []*Parameter
+	segments := strings.Split(path, "/")
+	for k, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			segments[k] = "{" + name + "}"
+			params = append(params, &Parameter{Name: name, In: "path", Type: "string", Required: true})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
@@ -0,0 +1,70 @@
+package swagger_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/ory-am/hydra/swagger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type embedded struct {
+	Owner string `json:"owner"`
+}
+
+type item struct {
+	*embedded
+	ID        string            `json:"id"`
+	CreatedAt time.Time         `json:"created_at"`
+	Secret    []byte            `json:"secret,omitempty"`
+	Labels    map[string]string `json:"labels"`
+	Parent    *item             `json:"parent,omitempty"`
+	Ignored   string            `json:"-"`
+}
+
+func TestDocument(t *testing.T) {
+	d := New("https://localhost:4444/")
+	d.Add(&Operation{
+		Method: "PUT", Path: "/items/:id", ID: "updateItem", Tag: "items", Scope: "hydra.items",
+		Body:       &item{},
+		Pagination: []string{"owner"},
+		Responses:  map[int]interface{}{http.StatusOK: []*item{}, http.StatusNoContent: nil},
+	}, &Operation{
+		Method: "POST", Path: "/items/token", ID: "itemToken", Tag: "items", ClientAuth: true,
+		Form:      []string{"grant_type"},
+		Responses: map[int]interface{}{http.StatusOK: &item{}},
+	})
+
+	assert.Equal(t, "https://localhost:4444/oauth2/token", d.SecurityDefinitions["oauth2"].TokenURL)
+	assert.Contains(t, d.SecurityDefinitions["oauth2"].Scopes, "hydra.items")
+
+	e := d.Paths["/items/{id}"]["put"]
+	require.NotNil(t, e)
+	assert.Equal(t, "updateItem", e.OperationID)
+	assert.Equal(t, []map[string][]string{{"oauth2": {"hydra.items"}}}, e.Security)
+	assert.Equal(t, "path", e.Parameters[0].In)
+	assert.Equal(t, "id", e.Parameters[0].Name)
+	assert.Equal(t, "body", e.Parameters[len(e.Parameters)-1].In)
+	assert.Equal(t, "#/definitions/item", e.Parameters[len(e.Parameters)-1].Schema.Ref)
+	assert.Equal(t, "array", e.Responses["200"].Schema.Type)
+	assert.NotNil(t, e.Responses["200"].Headers["X-Total-Count"])
+	assert.Nil(t, e.Responses["204"].Schema)
+	assert.Equal(t, "#/definitions/Error", e.Responses["default"].Schema.Ref)
+
+	e = d.Paths["/items/token"]["post"]
+	require.NotNil(t, e)
+	assert.Equal(t, []string{"application/x-www-form-urlencoded"}, e.Consumes)
+	assert.Equal(t, "formData", e.Parameters[0].In)
+	assert.Equal(t, []map[string][]string{{"basic": {}}}, e.Security)
+
+	s := d.Definitions["item"]
+	require.NotNil(t, s)
+	assert.Equal(t, "string", s.Properties["owner"].Type)
+	assert.Equal(t, "date-time", s.Properties["created_at"].Format)
+	assert.Equal(t, "byte", s.Properties["secret"].Format)
+	assert.Equal(t, "string", s.Properties["labels"].AdditionalProperties.Type)
+	assert.Equal(t, "#/definitions/item", s.Properties["parent"].Ref)
+	assert.NotContains(t, s.Properties, "Ignored")
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"io"
+
+	"github.com/go-errors/errors"
+	"github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go/config"
+)
+
+// DefaultServiceName is the name spans are reported under if no other name is configured.
+const DefaultServiceName = "hydra"
+
+// NewTracer creates the tracer of a provider, which is either empty or "jaeger". An empty provider disables
+// tracing. The closer flushes the spans which were not reported yet.
+func NewTracer(provider, serviceName, agentAddress string) (opentracing.Tracer, io.Closer, error) {
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+
+	switch provider {
+	case "":
+		return opentracing.NoopTracer{}, nopCloser{}, nil
+	case "jaeger":
+		cfg := &jaeger.Configuration{
+			Sampler: &jaeger.SamplerConfig{
+				Type:  "const",
+				Param: 1,
+			},
+			Reporter: &jaeger.ReporterConfig{
+				LocalAgentHostPort: agentAddress,
+			},
+		}
+		tracer, closer, err := cfg.New(serviceName)
+		if err != nil {
+			return nil, nil, errors.New(err)
+		}
+		return tracer, closer, nil
+	}
+	return nil, nil, errors.Errorf("Tracing provider %s is not supported", provider)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,115 @@
+// Package tracing traces requests and storage calls with OpenTracing.
+//
+// Requests do not carry a context in Go 1.6, so the middleware keeps the span of every request which is being
+// served in a registry. Handlers hand the span on to the warden and storage with Context(r), and storage wrappers
+// start child spans with StartSpanFromContext. Calls which do not receive a context, like client and key lookups,
+// are part of the span of their caller.
+package tracing
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+)
+
+var (
+	spans     = map[*http.Request]opentracing.Span{}
+	spansLock sync.RWMutex
+)
+
+// Middleware starts a span for every request. The span continues the trace of the caller if the request carries
+// one in its headers.
+type Middleware struct {
+	// Tracer is opentracing.GlobalTracer() if nil.
+	Tracer opentracing.Tracer
+}
+
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracer := m.Tracer
+		if tracer == nil {
+			tracer = opentracing.GlobalTracer()
+		}
+
+		// A request without a trace or with a malformed one starts a new trace.
+		parent, _ := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+		span := tracer.StartSpan("HTTP "+r.Method+" "+r.URL.Path, ext.RPCServerOption(parent))
+		ext.HTTPMethod.Set(span, r.Method)
+
+		// The query is left out, it may carry credentials.
+		ext.HTTPUrl.Set(span, r.URL.Path)
+
+		spansLock.Lock()
+		spans[r] = span
+		spansLock.Unlock()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			spansLock.Lock()
+			delete(spans, r)
+			spansLock.Unlock()
+
+			ext.HTTPStatusCode.Set(span, uint16(rec.status))
+			if rec.status >= 500 {
+				ext.Error.Set(span, true)
+			}
+			span.Finish()
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// FromRequest returns the span of a request which is being served, or nil if the request is not traced.
+func FromRequest(r *http.Request) opentracing.Span {
+	spansLock.RLock()
+	defer spansLock.RUnlock()
+
+	return spans[r]
+}
+
+// Context returns a context which carries the span of a request. Use it instead of context.Background() in
+// handlers, so that the spans of the warden and of storage become children of the request.
+func Context(r *http.Request) context.Context {
+	return WithRequest(context.Background(), r)
+}
+
+// WithRequest adds the span of a request to ctx unless ctx carries a span already.
+func WithRequest(ctx context.Context, r *http.Request) context.Context {
+	if opentracing.SpanFromContext(ctx) != nil {
+		return ctx
+	} else if span := FromRequest(r); span != nil {
+		return opentracing.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
+
+// StartSpanFromContext starts a span which is a child of the span in ctx, if any.
+func StartSpanFromContext(ctx context.Context, operation string) (opentracing.Span, context.Context) {
+	return opentracing.StartSpanFromContext(ctx, operation)
+}
+
+// Finish marks the span as failed if err is not nil and finishes it. Lookups of items which do not exist are not
+// failures.
+func Finish(span opentracing.Span, err error) {
+	if err != nil && !errors.Is(err, pkg.ErrNotFound) {
+		ext.Error.Set(span, true)
+		span.SetTag("error.message", err.Error())
+	}
+	span.Finish()
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	handler := (&Middleware{}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NotNil(t, FromRequest(r))
+
+		span, _ := StartSpanFromContext(Context(r), "storage")
+		Finish(span, nil)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	// The trace of the caller is continued.
+	caller := tracer.StartSpan("caller")
+	r, err := http.NewRequest("GET", "http://localhost/oauth2/token?secret=foo", nil)
+	require.Nil(t, err)
+	require.Nil(t, tracer.Inject(caller.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)))
+
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Nil(t, FromRequest(r))
+
+	finished := tracer.FinishedSpans()
+	require.Len(t, finished, 2)
+	storage, request := finished[0], finished[1]
+	assert.Equal(t, "storage", storage.OperationName)
+	assert.Equal(t, request.SpanContext.SpanID, storage.ParentID)
+	assert.Equal(t, caller.Context().(mocktracer.MockSpanContext).SpanID, request.ParentID)
+	assert.Equal(t, "/oauth2/token", request.Tag("http.url"))
+	assert.Equal(t, true, request.Tag("error"))
+}
@@ -2,38 +2,67 @@ package warden
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory-am/hydra/config"
 	"github.com/ory-am/hydra/firewall"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/pagination"
+	"github.com/ory-am/hydra/pkg"
 	"github.com/ory-am/ladon"
+	"github.com/pborman/uuid"
 	"golang.org/x/net/context"
 )
 
 const (
-	AuthorizedHandlerPath = "/warden/authorized"
-	AllowedHandlerPath    = "/warden/allowed"
+	AuthorizedHandlerPath   = "/warden/authorized"
+	AllowedHandlerPath      = "/warden/allowed"
+	TokenAllowedHandlerPath = "/warden/token/allowed"
+	TemplatesHandlerPath    = "/warden/templates"
+
+	ResourceServersHandlerPath = "/warden/resource-servers"
+)
+
+const (
+	templatesResource = "rn:hydra:warden:templates"
+	templateResource  = "rn:hydra:warden:templates:%s"
+	templatesScope    = "hydra.warden.templates"
+
+	resourceServersResource = "rn:hydra:warden:resource-servers"
+	resourceServerResource  = "rn:hydra:warden:resource-servers:%s"
+	resourceServersScope    = "hydra.warden.resource-servers"
 )
 
 type WardenHandler struct {
-	H      herodot.Herodot
-	Warden firewall.Firewall
-	Ladon  ladon.Warden
+	H         herodot.Herodot
+	Warden    firewall.Firewall
+	Ladon     ladon.Warden
+	Templates TemplateManager
+
+	// ResourceServers filters the authorization contexts returned to the resource servers calling the warden.
+	ResourceServers ResourceServerManager
+
+	// Snapshots exports the policies and keys edge devices enforce decisions with offline. The snapshot endpoint
+	// responds with 404 if Snapshots is nil.
+	Snapshots *SnapshotExporter
 }
 
 func NewHandler(c *config.Config, router *httprouter.Router) *WardenHandler {
 	ctx := c.Context()
 
 	h := &WardenHandler{
-		H:      &herodot.JSON{},
+		H:      &herodot.Negotiating{},
 		Warden: ctx.Warden,
 		Ladon: &ladon.Ladon{
 			Manager: ctx.LadonManager,
 		},
+		Templates:       NewTemplateMemoryManager(),
+		ResourceServers: NewResourceServerMemoryManager(),
 	}
 	h.SetRoutes(router)
 
@@ -52,11 +81,31 @@ type WardenAuthorizedRequest struct {
 type WardenAccessRequest struct {
 	*ladon.Request
 	*WardenAuthorizedRequest
+	*WardenTemplateRequest
+}
+
+// WardenAllowedResponse is the answer to an access request which names the subject instead of carrying its token.
+type WardenAllowedResponse struct {
+	Allowed bool `json:"allowed"`
 }
 
 func (h *WardenHandler) SetRoutes(r *httprouter.Router) {
 	r.POST(AuthorizedHandlerPath, h.Authorized)
 	r.POST(AllowedHandlerPath, h.Allowed)
+	r.POST(TokenAllowedHandlerPath, h.TokenAllowed)
+
+	r.POST(TemplatesHandlerPath, h.CreateTemplate)
+	r.GET(TemplatesHandlerPath, h.GetTemplates)
+	r.GET(TemplatesHandlerPath+"/:id", h.GetTemplate)
+	r.DELETE(TemplatesHandlerPath+"/:id", h.DeleteTemplate)
+
+	r.POST(ResourceServersHandlerPath, h.CreateResourceServer)
+	r.GET(ResourceServersHandlerPath, h.GetResourceServers)
+	r.GET(ResourceServersHandlerPath+"/:id", h.GetResourceServer)
+	r.PUT(ResourceServersHandlerPath+"/:id", h.UpdateResourceServer)
+	r.DELETE(ResourceServersHandlerPath+"/:id", h.DeleteResourceServer)
+
+	r.GET(SnapshotHandlerPath, h.GetSnapshot)
 }
 
 func (h *WardenHandler) Authorized(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -68,7 +117,7 @@ func (h *WardenHandler) Authorized(w http.ResponseWriter, r *http.Request, _ htt
 	}
 
 	var ar WardenAuthorizedRequest
-	if err := json.NewDecoder(r.Body).Decode(&ar); err != nil {
+	if err := herodot.Decode(r, &ar); err != nil {
 		h.H.WriteError(ctx, w, r, err)
 		return
 	}
@@ -81,10 +130,19 @@ func (h *WardenHandler) Authorized(w http.ResponseWriter, r *http.Request, _ htt
 	}
 
 	authContext.Audience = clientCtx.Subject
-	h.H.Write(ctx, w, r, authContext)
+	filtered, err := h.FilterContext(clientCtx.Subject, authContext)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, filtered)
 
 }
 
+// Allowed checks if the subject of the request is allowed to perform the action on the resource. Requests which
+// carry an assertion are answered like TokenAllowed, which is how this endpoint worked before subjects could be
+// checked directly.
 func (h *WardenHandler) Allowed(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	ctx := herodot.NewContext()
 	clientCtx, err := h.authorizeClient(ctx, w, r, "an:hydra:warden:allowed")
@@ -93,12 +151,51 @@ func (h *WardenHandler) Allowed(w http.ResponseWriter, r *http.Request, _ httpro
 		return
 	}
 
-	var ar WardenAccessRequest
-	if err := json.NewDecoder(r.Body).Decode(&ar); err != nil {
-		h.H.WriteError(ctx, w, r, errors.New(err))
+	ar, ok := h.decodeAccessRequest(ctx, w, r)
+	if !ok {
+		return
+	} else if ar.WardenAuthorizedRequest != nil && ar.Assertion != "" {
+		h.tokenAllowed(ctx, w, r, clientCtx, ar)
+		return
+	}
+
+	if ar.Subject == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Either subject or assertion is required"))
+		return
+	}
+
+	err = h.Ladon.IsAllowed(ar.Request)
+	if errors.Is(err, ladon.ErrRequestDenied) || errors.Is(err, ladon.ErrRequestForcefullyDenied) {
+		h.H.Write(ctx, w, r, &WardenAllowedResponse{Allowed: false})
+		return
+	} else if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, &WardenAllowedResponse{Allowed: true})
+}
+
+// TokenAllowed validates the assertion and checks if its subject is allowed to perform the action on the resource.
+func (h *WardenHandler) TokenAllowed(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+	clientCtx, err := h.authorizeClient(ctx, w, r, "an:hydra:warden:allowed")
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
 		return
 	}
 
+	ar, ok := h.decodeAccessRequest(ctx, w, r)
+	if !ok {
+		return
+	} else if ar.WardenAuthorizedRequest == nil {
+		ar.WardenAuthorizedRequest = &WardenAuthorizedRequest{}
+	}
+
+	h.tokenAllowed(ctx, w, r, clientCtx, ar)
+}
+
+func (h *WardenHandler) tokenAllowed(ctx context.Context, w http.ResponseWriter, r *http.Request, clientCtx *firewall.Context, ar *WardenAccessRequest) {
 	authContext, err := h.Warden.ActionAllowed(ctx, ar.Assertion, ar.Request, ar.Scopes...)
 	if err != nil {
 		h.H.WriteError(ctx, w, r, err)
@@ -106,11 +203,51 @@ func (h *WardenHandler) Allowed(w http.ResponseWriter, r *http.Request, _ httpro
 	}
 
 	authContext.Audience = clientCtx.Subject
-	h.H.Write(ctx, w, r, authContext)
+	filtered, err := h.FilterContext(clientCtx.Subject, authContext)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, filtered)
+}
+
+// decodeAccessRequest reads an access request and expands its resource template. The error response has been
+// written if false is returned.
+func (h *WardenHandler) decodeAccessRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) (*WardenAccessRequest, bool) {
+	var ar WardenAccessRequest
+	if err := herodot.Decode(r, &ar); err != nil {
+		h.H.WriteError(ctx, w, r, errors.New(err))
+		return nil, false
+	}
+
+	if ar.Request == nil {
+		ar.Request = &ladon.Request{}
+	}
+
+	if ar.WardenTemplateRequest != nil && ar.Template != "" {
+		if h.Templates == nil {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Resource templates are not enabled"))
+			return nil, false
+		} else if err := ExpandTemplate(h.Templates, ar.Request, ar.WardenTemplateRequest); errors.Is(err, pkg.ErrNotFound) {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.Errorf("Resource template %s is not registered", ar.Template))
+			return nil, false
+		} else if err != nil {
+			h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+			return nil, false
+		}
+	}
+	return &ar, true
 }
 
 func (h *WardenHandler) authorizeClient(ctx context.Context, w http.ResponseWriter, r *http.Request, action string) (*firewall.Context, error) {
-	authctx, err := h.Warden.ActionAllowed(ctx, TokenFromRequest(r), &ladon.Request{
+	return h.AuthorizeCaller(ctx, TokenFromRequest(r), action)
+}
+
+// AuthorizeCaller checks that the access token of the resource server which asks the warden allows the action, like
+// an:hydra:warden:allowed. The returned context describes the resource server.
+func (h *WardenHandler) AuthorizeCaller(ctx context.Context, token, action string) (*firewall.Context, error) {
+	authctx, err := h.Warden.ActionAllowed(ctx, token, &ladon.Request{
 		Action: action,
 	}, "hydra.warden")
 	if err != nil {
@@ -120,6 +257,302 @@ func (h *WardenHandler) authorizeClient(ctx context.Context, w http.ResponseWrit
 	return authctx, nil
 }
 
+// FilterContext applies the visibility rules of the resource server which asked the warden.
+func (h *WardenHandler) FilterContext(resourceServer string, c *firewall.Context) (*firewall.Context, error) {
+	if h.ResourceServers == nil {
+		return c, nil
+	}
+
+	s, err := h.ResourceServers.GetResourceServer(resourceServer)
+	if errors.Is(err, pkg.ErrNotFound) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s.Filter(c), nil
+}
+
+func (h *WardenHandler) CreateTemplate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var t ResourceTemplate
+	ctx := herodot.NewContext()
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: templatesResource,
+		Action:   "create",
+	}, templatesScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	if t.Template == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("Template must not be empty"))
+		return
+	} else if err := t.compile(); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := h.Templates.FindTemplate(t.Template); err == nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusConflict, errors.Errorf("Template %s is already registered", t.Template))
+		return
+	}
+
+	if t.ID == "" {
+		t.ID = uuid.New()
+	}
+
+	if err := h.Templates.CreateTemplate(&t); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.WriteCreated(ctx, w, r, TemplatesHandlerPath+"/"+t.ID, &t)
+}
+
+func (h *WardenHandler) GetTemplates(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: templatesResource,
+		Action:   "get",
+	}, templatesScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	page, err := pagination.Parse(r, "id", "description")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ts, err := h.Templates.GetTemplates()
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	templates := make([]*ResourceTemplate, 0, len(ts))
+	for _, t := range ts {
+		templates = append(templates, t)
+	}
+
+	indices, total := page.Apply(len(templates), func(i int, field string) string {
+		switch field {
+		case "id":
+			return templates[i].ID
+		case "description":
+			return templates[i].Description
+		}
+		return ""
+	})
+
+	res := make(map[string]*ResourceTemplate, len(indices))
+	for _, i := range indices {
+		res[templates[i].ID] = templates[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
+}
+
+func (h *WardenHandler) GetTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := herodot.NewContext()
+	id := ps.ByName("id")
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(templateResource, id),
+		Action:   "get",
+	}, templatesScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	t, err := h.Templates.GetTemplate(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, t)
+}
+
+func (h *WardenHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := herodot.NewContext()
+	id := ps.ByName("id")
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(templateResource, id),
+		Action:   "delete",
+	}, templatesScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.Templates.DeleteTemplate(id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WardenHandler) CreateResourceServer(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var s ResourceServer
+	ctx := herodot.NewContext()
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: resourceServersResource,
+		Action:   "create",
+	}, resourceServersScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	if s.ID == "" {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New("The id must be the id of the resource server's OAuth2 client"))
+		return
+	}
+
+	if _, err := h.ResourceServers.GetResourceServer(s.ID); err == nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusConflict, errors.Errorf("Resource server %s is already registered", s.ID))
+		return
+	}
+
+	if err := h.ResourceServers.CreateResourceServer(&s); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.WriteCreated(ctx, w, r, ResourceServersHandlerPath+"/"+s.ID, &s)
+}
+
+func (h *WardenHandler) GetResourceServers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: resourceServersResource,
+		Action:   "get",
+	}, resourceServersScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	page, err := pagination.Parse(r, "id", "description")
+	if err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	ss, err := h.ResourceServers.GetResourceServers()
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	servers := make([]*ResourceServer, 0, len(ss))
+	for _, s := range ss {
+		servers = append(servers, s)
+	}
+
+	indices, total := page.Apply(len(servers), func(i int, field string) string {
+		switch field {
+		case "id":
+			return servers[i].ID
+		case "description":
+			return servers[i].Description
+		}
+		return ""
+	})
+
+	res := make(map[string]*ResourceServer, len(indices))
+	for _, i := range indices {
+		res[servers[i].ID] = servers[i]
+	}
+
+	page.WriteHeaders(w, r, total)
+	h.H.Write(ctx, w, r, res)
+}
+
+func (h *WardenHandler) GetResourceServer(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := herodot.NewContext()
+	id := ps.ByName("id")
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(resourceServerResource, id),
+		Action:   "get",
+	}, resourceServersScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	s, err := h.ResourceServers.GetResourceServer(id)
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, s)
+}
+
+func (h *WardenHandler) UpdateResourceServer(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var s ResourceServer
+	ctx := herodot.NewContext()
+	id := ps.ByName("id")
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(resourceServerResource, id),
+		Action:   "update",
+	}, resourceServersScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusBadRequest, errors.New(err))
+		return
+	}
+
+	s.ID = id
+	if err := h.ResourceServers.UpdateResourceServer(&s); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	h.H.Write(ctx, w, r, &s)
+}
+
+func (h *WardenHandler) DeleteResourceServer(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := herodot.NewContext()
+	id := ps.ByName("id")
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: fmt.Sprintf(resourceServerResource, id),
+		Action:   "delete",
+	}, resourceServersScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if err := h.ResourceServers.DeleteResourceServer(id); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func TokenFromRequest(r *http.Request) string {
 	auth := r.Header.Get("Authorization")
 	split := strings.SplitN(auth, " ", 2)
@@ -129,3 +562,31 @@ func TokenFromRequest(r *http.Request) string {
 
 	return split[1]
 }
+
+// GetSnapshot responds with a signed snapshot of the policies, public keys and revoked tokens as a compact JWS.
+func (h *WardenHandler) GetSnapshot(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := herodot.NewContext()
+
+	if _, err := h.Warden.HTTPActionAllowed(ctx, r, &ladon.Request{
+		Resource: snapshotResource,
+		Action:   "get",
+	}, snapshotScope); err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	if h.Snapshots == nil {
+		h.H.WriteErrorCode(ctx, w, r, http.StatusNotFound, errors.New("Snapshots are not enabled"))
+		return
+	}
+
+	snapshot, err := h.Snapshots.Export(time.Now())
+	if err != nil {
+		h.H.WriteError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jose")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(snapshot))
+}
@@ -0,0 +1,44 @@
+package warden
+
+import (
+	"time"
+
+	. "github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/oauth2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	decisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "hydra",
+		Subsystem: "warden",
+		Name:      "decisions_total",
+		Help:      "Number of warden decisions, partitioned by operation, hashed client id of the token and result.",
+	}, []string{"operation", "client", "result"})
+
+	decisionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "hydra",
+		Subsystem: "warden",
+		Name:      "decision_duration_seconds",
+		Help:      "Latency of warden decisions, including the validation of the token.",
+	}, []string{"operation", "client", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(decisions, decisionDuration)
+}
+
+// observeDecision records a decision of the local warden. Use it with defer and named results:
+// defer func(start time.Time) { observeDecision("authorized", start, c, err) }(time.Now())
+func observeDecision(operation string, start time.Time, c *Context, err error) {
+	var client string
+	result := "allowed"
+	if err != nil {
+		result = "denied"
+	} else if c != nil {
+		client = oauth2.HashClientID(c.Audience)
+	}
+
+	decisions.WithLabelValues(operation, client, result).Inc()
+	decisionDuration.WithLabelValues(operation, client, result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,33 @@
+package warden
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	. "github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/oauth2"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decisionCount(t *testing.T, operation, client, result string) float64 {
+	var d dto.Metric
+	require.Nil(t, decisions.WithLabelValues(operation, client, result).Write(&d))
+	return d.Counter.GetValue()
+}
+
+func TestObserveDecision(t *testing.T) {
+	client := oauth2.HashClientID("app")
+	assert.NotEqual(t, "app", client)
+
+	allowed := decisionCount(t, "authorized", client, "allowed")
+	observeDecision("authorized", time.Now(), &Context{Subject: "peter", Audience: "app"}, nil)
+	assert.Equal(t, allowed+1, decisionCount(t, "authorized", client, "allowed"))
+
+	// Denied requests carry no trusted token, so they are not attributed to a client.
+	denied := decisionCount(t, "action_allowed", "", "denied")
+	observeDecision("action_allowed", time.Now(), nil, errors.New("Forbidden"))
+	assert.Equal(t, denied+1, decisionCount(t, "action_allowed", "", "denied"))
+}
@@ -0,0 +1,64 @@
+package warden
+
+import (
+	"strings"
+
+	"github.com/ory-am/hydra/firewall"
+)
+
+// ResourceServer restricts what a resource server learns about the tokens it sends to the warden. The resource
+// server is identified by the OAuth2 client it uses to authenticate against the warden, so its ID is the ID of that
+// client. Resource servers which are not registered see the full authorization context.
+type ResourceServer struct {
+	ID          string `json:"id" gorethink:"id"`
+	Description string `json:"description" gorethink:"description"`
+
+	// Scopes lists the scopes the resource server may see. A granted scope is visible if it equals one of these or
+	// is one of its sub scopes, so "photos" reveals "photos" and "photos.read". All scopes are visible if empty.
+	Scopes []string `json:"scopes" gorethink:"scopes"`
+
+	// OmitSubject hides the subject of the token.
+	OmitSubject bool `json:"omit_subject" gorethink:"omit_subject"`
+
+	// OmitAuthentication hides how the subject logged in (amr, acr and WebAuthn user verification).
+	OmitAuthentication bool `json:"omit_authentication" gorethink:"omit_authentication"`
+}
+
+func (s *ResourceServer) GetID() string {
+	return s.ID
+}
+
+// Filter returns a copy of the context which contains only what the resource server may see.
+func (s *ResourceServer) Filter(c *firewall.Context) *firewall.Context {
+	filtered := *c
+
+	if len(s.Scopes) > 0 {
+		filtered.GrantedScopes = []string{}
+		for _, scope := range c.GrantedScopes {
+			if s.scopeVisible(scope) {
+				filtered.GrantedScopes = append(filtered.GrantedScopes, scope)
+			}
+		}
+	}
+
+	if s.OmitSubject {
+		filtered.Subject = ""
+	}
+
+	if s.OmitAuthentication {
+		filtered.AuthenticationMethods = nil
+		filtered.AuthenticationContext = ""
+		filtered.UserVerified = false
+	}
+
+	return &filtered
+}
+
+func (s *ResourceServer) scopeVisible(scope string) bool {
+	for _, visible := range s.Scopes {
+		if scope == visible || strings.HasPrefix(scope, visible+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package warden
+
+// ResourceServerManager stores the visibility rules of resource servers.
+type ResourceServerManager interface {
+	CreateResourceServer(s *ResourceServer) error
+
+	// UpdateResourceServer replaces the rules of a registered resource server.
+	UpdateResourceServer(s *ResourceServer) error
+
+	GetResourceServer(id string) (*ResourceServer, error)
+
+	GetResourceServers() (map[string]*ResourceServer, error)
+
+	DeleteResourceServer(id string) error
+}
@@ -0,0 +1,64 @@
+package warden
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type ResourceServerMemoryManager struct {
+	ResourceServers map[string]*ResourceServer
+	sync.RWMutex
+}
+
+func NewResourceServerMemoryManager() *ResourceServerMemoryManager {
+	return &ResourceServerMemoryManager{
+		ResourceServers: map[string]*ResourceServer{},
+	}
+}
+
+func (m *ResourceServerMemoryManager) CreateResourceServer(s *ResourceServer) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.ResourceServers[s.GetID()] = s
+	return nil
+}
+
+func (m *ResourceServerMemoryManager) UpdateResourceServer(s *ResourceServer) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.ResourceServers[s.GetID()]; !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+	m.ResourceServers[s.GetID()] = s
+	return nil
+}
+
+func (m *ResourceServerMemoryManager) GetResourceServer(id string) (*ResourceServer, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	s, ok := m.ResourceServers[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return s, nil
+}
+
+func (m *ResourceServerMemoryManager) GetResourceServers() (map[string]*ResourceServer, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.ResourceServers, nil
+}
+
+func (m *ResourceServerMemoryManager) DeleteResourceServer(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.ResourceServers, id)
+	return nil
+}
@@ -0,0 +1,120 @@
+package warden
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+type ResourceServerRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+
+	ResourceServers map[string]*ResourceServer
+
+	// Feed streams the changes of Table. It is created by Watch if nil.
+	Feed *pkg.ChangeFeed
+
+	sync.RWMutex
+}
+
+func (m *ResourceServerRethinkManager) CreateResourceServer(s *ResourceServer) error {
+	if _, err := m.Table.Insert(s).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *ResourceServerRethinkManager) UpdateResourceServer(s *ResourceServer) error {
+	m.RLock()
+	_, ok := m.ResourceServers[s.GetID()]
+	m.RUnlock()
+	if !ok {
+		return errors.New(pkg.ErrNotFound)
+	}
+
+	if _, err := m.Table.Get(s.GetID()).Replace(s).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *ResourceServerRethinkManager) GetResourceServer(id string) (*ResourceServer, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	s, ok := m.ResourceServers[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return s, nil
+}
+
+func (m *ResourceServerRethinkManager) GetResourceServers() (map[string]*ResourceServer, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	servers := make(map[string]*ResourceServer, len(m.ResourceServers))
+	for id, s := range m.ResourceServers {
+		servers[id] = s
+	}
+	return servers, nil
+}
+
+func (m *ResourceServerRethinkManager) DeleteResourceServer(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *ResourceServerRethinkManager) ColdStart() error {
+	rows, err := m.Table.Run(m.Session)
+	if err != nil {
+		return errors.New(err)
+	}
+	defer rows.Close()
+
+	servers := map[string]*ResourceServer{}
+	var s *ResourceServer
+	for rows.Next(&s) {
+		servers[s.ID] = s
+		s = nil
+	}
+
+	if rows.Err() != nil {
+		return errors.New(rows.Err())
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.ResourceServers = servers
+	return nil
+}
+
+func (m *ResourceServerRethinkManager) Watch(ctx context.Context) {
+	if m.Feed == nil {
+		m.Feed = &pkg.ChangeFeed{Session: m.Session, Table: m.Table}
+	}
+
+	m.Feed.Subscribe(m.ColdStart, func(change *pkg.Change) error {
+		var newVal, oldVal *ResourceServer
+		if err := change.Decode(&oldVal, &newVal); err != nil {
+			return err
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		if oldVal != nil {
+			delete(m.ResourceServers, oldVal.GetID())
+		}
+		if newVal != nil {
+			m.ResourceServers[newVal.GetID()] = newVal
+		}
+		return nil
+	})
+	m.Feed.Start(ctx)
+}
@@ -0,0 +1,74 @@
+package warden_test
+
+import (
+	"testing"
+
+	"github.com/ory-am/hydra/firewall"
+	. "github.com/ory-am/hydra/warden"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceServerFilter(t *testing.T) {
+	c := &firewall.Context{
+		Subject:               "alice",
+		GrantedScopes:         []string{"core", "photos", "photos.read", "photosynthesis", "orders.write"},
+		Audience:              "app",
+		AuthenticationMethods: []string{"hwk", "mfa"},
+		AuthenticationContext: "webauthn-uv",
+		UserVerified:          true,
+	}
+
+	for k, cs := range []struct {
+		s        *ResourceServer
+		scopes   []string
+		subject  string
+		verified bool
+	}{
+		{
+			s:        &ResourceServer{ID: "all"},
+			scopes:   c.GrantedScopes,
+			subject:  "alice",
+			verified: true,
+		},
+		{
+			s:        &ResourceServer{ID: "photos", Scopes: []string{"photos"}},
+			scopes:   []string{"photos", "photos.read"},
+			subject:  "alice",
+			verified: true,
+		},
+		{
+			s:      &ResourceServer{ID: "anonymous", Scopes: []string{"billing"}, OmitSubject: true, OmitAuthentication: true},
+			scopes: []string{},
+		},
+	} {
+		f := cs.s.Filter(c)
+		assert.Equal(t, cs.scopes, f.GrantedScopes, "Case %d", k)
+		assert.Equal(t, cs.subject, f.Subject, "Case %d", k)
+		assert.Equal(t, cs.verified, f.UserVerified, "Case %d", k)
+		assert.Equal(t, "app", f.Audience, "Case %d", k)
+	}
+
+	assert.Equal(t, "alice", c.Subject)
+	assert.Len(t, c.GrantedScopes, 5)
+}
+
+func TestResourceServerMemoryManager(t *testing.T) {
+	m := NewResourceServerMemoryManager()
+	require.Nil(t, m.CreateResourceServer(&ResourceServer{ID: "photos", Scopes: []string{"photos"}}))
+
+	s, err := m.GetResourceServer("photos")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"photos"}, s.Scopes)
+
+	require.Nil(t, m.UpdateResourceServer(&ResourceServer{ID: "photos", OmitSubject: true}))
+	s, err = m.GetResourceServer("photos")
+	require.Nil(t, err)
+	assert.True(t, s.OmitSubject)
+
+	assert.NotNil(t, m.UpdateResourceServer(&ResourceServer{ID: "unknown"}))
+
+	require.Nil(t, m.DeleteResourceServer("photos"))
+	_, err = m.GetResourceServer("photos")
+	assert.NotNil(t, err)
+}
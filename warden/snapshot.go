@@ -0,0 +1,255 @@
+package warden
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/canonical"
+	"github.com/ory-am/hydra/history"
+	"github.com/ory-am/hydra/jwk"
+	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/ladon"
+	"github.com/square/go-jose"
+)
+
+const (
+	SnapshotHandlerPath = "/warden/snapshot"
+
+	// SnapshotKeyName is the key set snapshots are signed with.
+	SnapshotKeyName = "hydra.warden.snapshot"
+
+	SnapshotVersion = 1
+
+	// DefaultSnapshotMaxAge is how long a snapshot may be used if the exporter does not say otherwise.
+	DefaultSnapshotMaxAge = time.Hour
+)
+
+const (
+	snapshotResource = "rn:hydra:warden:snapshot"
+	snapshotScope    = "hydra.warden.snapshot"
+)
+
+// Snapshot is everything an edge device needs to make warden decisions without reaching hydra: the policies, the
+// public keys tokens are signed with and the tokens which were revoked. A snapshot must not be used after ExpiresAt.
+// Policies are exported as stored, label selectors in them are not resolved.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	Issuer    string    `json:"iss"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	Policies []*ladon.DefaultPolicy `json:"policies"`
+	Keys     *jose.JsonWebKeySet    `json:"keys"`
+
+	// RevokedTokens are the history.TokenID of the signatures of revoked tokens which had not expired when the
+	// snapshot was taken. Revocations are only recorded if token history is enabled, otherwise
+	// RevocationsIncluded is false and revoked tokens can not be told apart offline.
+	RevokedTokens       []string `json:"revoked_tokens"`
+	RevocationsIncluded bool     `json:"revocations_included"`
+}
+
+// IsRevoked returns true if the token with the given signature is on the revocation list.
+func (s *Snapshot) IsRevoked(signature string) bool {
+	id := history.TokenID(signature)
+	i := sort.SearchStrings(s.RevokedTokens, id)
+	return i < len(s.RevokedTokens) && s.RevokedTokens[i] == id
+}
+
+// Ladon returns a warden which decides with the policies of the snapshot.
+func (s *Snapshot) Ladon() *ladon.Ladon {
+	m := ladon.NewMemoryManager()
+	for _, p := range s.Policies {
+		m.Policies[p.ID] = p
+	}
+	return &ladon.Ladon{Manager: m}
+}
+
+// PolicyLister is implemented by policy managers which can list all policies.
+type PolicyLister interface {
+	GetPolicies() (ladon.Policies, error)
+}
+
+// SnapshotExporter takes signed snapshots of the warden's state.
+type SnapshotExporter struct {
+	Issuer string
+
+	Policies   ladon.Manager
+	KeyManager jwk.Manager
+
+	// KeySets are the sets whose public keys are included, for example the JWT access token keys.
+	KeySets []string
+
+	// History supplies the revocation list, it may be nil.
+	History history.Manager
+
+	MaxAge time.Duration
+}
+
+// Export returns a snapshot taken at the given time as a compact JWS signed with a key of SnapshotKeyName.
+func (e *SnapshotExporter) Export(now time.Time) (string, error) {
+	s, err := e.snapshot(now)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := canonical.Marshal(s)
+	if err != nil {
+		return "", errors.New(err)
+	}
+
+	keys, err := e.KeyManager.GetKey(SnapshotKeyName, "private")
+	if err != nil {
+		return "", err
+	}
+
+	key := jwk.First(keys.Keys)
+	if _, ok := key.Key.(*rsa.PrivateKey); !ok {
+		return "", errors.New("Snapshot key is not an RSA private key")
+	}
+
+	// Verifiers look the key up by the id of its public counterpart.
+	kid := "public" + strings.TrimPrefix(key.KeyID, "private")
+	signer, err := jose.NewSigner(jose.RS256, &jose.JsonWebKey{Key: key.Key, KeyID: kid})
+	if err != nil {
+		return "", errors.New(err)
+	}
+
+	sig, err := signer.Sign(payload)
+	if err != nil {
+		return "", errors.New(err)
+	}
+
+	out, err := sig.CompactSerialize()
+	if err != nil {
+		return "", errors.New(err)
+	}
+	return out, nil
+}
+
+func (e *SnapshotExporter) snapshot(now time.Time) (*Snapshot, error) {
+	maxAge := e.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultSnapshotMaxAge
+	}
+
+	s := &Snapshot{
+		Version:       SnapshotVersion,
+		Issuer:        e.Issuer,
+		IssuedAt:      now.UTC(),
+		ExpiresAt:     now.Add(maxAge).UTC(),
+		Policies:      []*ladon.DefaultPolicy{},
+		Keys:          &jose.JsonWebKeySet{Keys: []jose.JsonWebKey{}},
+		RevokedTokens: []string{},
+	}
+
+	policies, err := ListPolicies(e.Policies)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range policies {
+		s.Policies = append(s.Policies, &ladon.DefaultPolicy{
+			ID:          p.GetID(),
+			Description: p.GetDescription(),
+			Subjects:    p.GetSubjects(),
+			Effect:      p.GetEffect(),
+			Resources:   p.GetResources(),
+			Actions:     p.GetActions(),
+			Conditions:  p.GetConditions(),
+		})
+	}
+	sort.Sort(byPolicyID(s.Policies))
+
+	for _, set := range e.KeySets {
+		keys, err := e.KeyManager.GetKeySet(set)
+		if errors.Is(err, pkg.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		s.Keys.Keys = append(s.Keys.Keys, jwk.PublicKeys(keys.Keys)...)
+	}
+
+	if e.History != nil {
+		tokens, err := e.History.GetRevokedTokens(now)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tokens {
+			s.RevokedTokens = append(s.RevokedTokens, t.ID)
+		}
+		sort.Strings(s.RevokedTokens)
+		s.RevocationsIncluded = true
+	}
+	return s, nil
+}
+
+// VerifySnapshot checks the signature of a snapshot with the public keys of SnapshotKeyName and rejects snapshots
+// which are not valid at the given time.
+func VerifySnapshot(token string, keys *jose.JsonWebKeySet, now time.Time) (*Snapshot, error) {
+	sig, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, errors.New(err)
+	} else if len(sig.Signatures) != 1 {
+		return nil, errors.New("Snapshot must carry exactly one signature")
+	}
+
+	var payload []byte
+	for _, key := range keys.Key(sig.Signatures[0].Header.KeyID) {
+		if payload, err = sig.Verify(key.Key); err == nil {
+			break
+		}
+	}
+	if payload == nil {
+		return nil, errors.New("Snapshot signature is invalid")
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return nil, errors.New(err)
+	}
+
+	if s.Version != SnapshotVersion {
+		return nil, errors.Errorf("Snapshot version %d is not supported", s.Version)
+	} else if now.Before(s.IssuedAt) {
+		return nil, errors.New("Snapshot was issued in the future")
+	} else if !now.Before(s.ExpiresAt) {
+		return nil, errors.Errorf("Snapshot is stale, it expired at %s", s.ExpiresAt)
+	}
+	return &s, nil
+}
+
+// ListPolicies returns all policies of managers which implement PolicyLister and of ladon's own managers, which
+// keep every policy in memory.
+func ListPolicies(m ladon.Manager) (ladon.Policies, error) {
+	switch m := m.(type) {
+	case PolicyLister:
+		return m.GetPolicies()
+	case *ladon.MemoryManager:
+		m.RLock()
+		defer m.RUnlock()
+		return policyValues(m.Policies), nil
+	case *ladon.RethinkManager:
+		m.RLock()
+		defer m.RUnlock()
+		return policyValues(m.Policies), nil
+	}
+	return nil, errors.New("The policy store does not support listing policies")
+}
+
+func policyValues(policies map[string]ladon.Policy) ladon.Policies {
+	result := ladon.Policies{}
+	for _, p := range policies {
+		result = append(result, p)
+	}
+	return result
+}
+
+type byPolicyID []*ladon.DefaultPolicy
+
+func (p byPolicyID) Len() int           { return len(p) }
+func (p byPolicyID) Less(i, j int) bool { return p[i].ID < p[j].ID }
+func (p byPolicyID) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
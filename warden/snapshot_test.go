@@ -0,0 +1,82 @@
+package warden_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory-am/hydra/history"
+	"github.com/ory-am/hydra/jwk"
+	. "github.com/ory-am/hydra/warden"
+	"github.com/ory-am/ladon"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	now := time.Now().UTC()
+
+	policies := ladon.NewMemoryManager()
+	require.Nil(t, policies.Create(&ladon.DefaultPolicy{
+		ID:        "photos",
+		Subjects:  []string{"peter"},
+		Effect:    ladon.AllowAccess,
+		Resources: []string{"rn:photos:<.*>"},
+		Actions:   []string{"get"},
+	}))
+
+	km := &jwk.MemoryManager{}
+	signing, err := new(jwk.RS256Generator).Generate("")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet(SnapshotKeyName, signing))
+	access, err := new(jwk.RS256Generator).Generate("access")
+	require.Nil(t, err)
+	require.Nil(t, km.AddKeySet("access", access))
+
+	revokedAt := now.Add(-time.Minute)
+	hm := history.NewMemoryManager()
+	require.Nil(t, hm.AddToken(&history.TokenRecord{ID: history.TokenID("revoked"), IssuedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}))
+	require.Nil(t, hm.AddToken(&history.TokenRecord{ID: history.TokenID("active"), IssuedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}))
+
+	e := &SnapshotExporter{
+		Issuer:     "https://hydra.localhost",
+		Policies:   policies,
+		KeyManager: km,
+		KeySets:    []string{"access", "missing"},
+		History:    hm,
+		MaxAge:     time.Hour,
+	}
+
+	token, err := e.Export(now)
+	require.Nil(t, err)
+
+	public := &jose.JsonWebKeySet{Keys: jwk.PublicKeys(signing.Keys)}
+	s, err := VerifySnapshot(token, public, now.Add(time.Minute))
+	require.Nil(t, err)
+	assert.Equal(t, "https://hydra.localhost", s.Issuer)
+	assert.True(t, s.RevocationsIncluded)
+	assert.True(t, s.IsRevoked("revoked"))
+	assert.False(t, s.IsRevoked("active"))
+	assert.Len(t, s.Keys.Key("public:access"), 1)
+	assert.Len(t, s.Keys.Key("private:access"), 0)
+
+	assert.Nil(t, s.Ladon().IsAllowed(&ladon.Request{Subject: "peter", Resource: "rn:photos:1", Action: "get"}))
+	assert.NotNil(t, s.Ladon().IsAllowed(&ladon.Request{Subject: "peter", Resource: "rn:photos:1", Action: "delete"}))
+
+	_, err = VerifySnapshot(token, public, now.Add(time.Hour))
+	assert.NotNil(t, err, "Stale snapshots must be rejected")
+
+	_, err = VerifySnapshot(token, public, now.Add(-time.Minute))
+	assert.NotNil(t, err)
+
+	_, err = VerifySnapshot(token, &jose.JsonWebKeySet{Keys: jwk.PublicKeys(access.Keys)}, now)
+	assert.NotNil(t, err)
+
+	e.History = nil
+	token, err = e.Export(now)
+	require.Nil(t, err)
+	s, err = VerifySnapshot(token, public, now)
+	require.Nil(t, err)
+	assert.False(t, s.RevocationsIncluded)
+	assert.Empty(t, s.RevokedTokens)
+}
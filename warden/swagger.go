@@ -0,0 +1,85 @@
+package warden
+
+import (
+	"net/http"
+
+	"github.com/ory-am/hydra/firewall"
+	"github.com/ory-am/hydra/swagger"
+)
+
+// Operations describes the endpoints of WardenHandler.
+var Operations = []*swagger.Operation{
+	{
+		Method: "POST", Path: AuthorizedHandlerPath, ID: "wardenAuthorized", Tag: "warden", Scope: "hydra.warden",
+		Summary:   "Check whether a token is valid and carries the scopes",
+		Body:      &WardenAuthorizedRequest{},
+		Responses: map[int]interface{}{http.StatusOK: &firewall.Context{}},
+	},
+	{
+		Method: "POST", Path: AllowedHandlerPath, ID: "wardenAllowed", Tag: "warden", Scope: "hydra.warden",
+		Summary:   "Check whether a subject is allowed to perform an action on a resource",
+		Body:      &WardenAccessRequest{},
+		Responses: map[int]interface{}{http.StatusOK: &WardenAllowedResponse{}},
+	},
+	{
+		Method: "POST", Path: TokenAllowedHandlerPath, ID: "wardenTokenAllowed", Tag: "warden", Scope: "hydra.warden",
+		Summary:   "Check whether a token is allowed to perform an action on a resource",
+		Body:      &WardenAccessRequest{},
+		Responses: map[int]interface{}{http.StatusOK: &firewall.Context{}},
+	},
+	{
+		Method: "POST", Path: TemplatesHandlerPath, ID: "createResourceTemplate", Tag: "warden", Scope: templatesScope,
+		Summary:   "Create a resource template",
+		Body:      &ResourceTemplate{},
+		Responses: map[int]interface{}{http.StatusCreated: &ResourceTemplate{}},
+	},
+	{
+		Method: "GET", Path: TemplatesHandlerPath, ID: "listResourceTemplates", Tag: "warden", Scope: templatesScope,
+		Summary:    "List resource templates, keyed by their id",
+		Pagination: []string{"id", "description"},
+		Responses:  map[int]interface{}{http.StatusOK: map[string]*ResourceTemplate{}},
+	},
+	{
+		Method: "GET", Path: TemplatesHandlerPath + "/:id", ID: "getResourceTemplate", Tag: "warden", Scope: templatesScope,
+		Summary:   "Get a resource template",
+		Responses: map[int]interface{}{http.StatusOK: &ResourceTemplate{}},
+	},
+	{
+		Method: "DELETE", Path: TemplatesHandlerPath + "/:id", ID: "deleteResourceTemplate", Tag: "warden", Scope: templatesScope,
+		Summary:   "Delete a resource template",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "POST", Path: ResourceServersHandlerPath, ID: "createResourceServer", Tag: "warden", Scope: resourceServersScope,
+		Summary:   "Register a resource server",
+		Body:      &ResourceServer{},
+		Responses: map[int]interface{}{http.StatusCreated: &ResourceServer{}},
+	},
+	{
+		Method: "GET", Path: ResourceServersHandlerPath, ID: "listResourceServers", Tag: "warden", Scope: resourceServersScope,
+		Summary:    "List resource servers, keyed by their id",
+		Pagination: []string{"id", "description"},
+		Responses:  map[int]interface{}{http.StatusOK: map[string]*ResourceServer{}},
+	},
+	{
+		Method: "GET", Path: ResourceServersHandlerPath + "/:id", ID: "getResourceServer", Tag: "warden", Scope: resourceServersScope,
+		Summary:   "Get a resource server",
+		Responses: map[int]interface{}{http.StatusOK: &ResourceServer{}},
+	},
+	{
+		Method: "PUT", Path: ResourceServersHandlerPath + "/:id", ID: "updateResourceServer", Tag: "warden", Scope: resourceServersScope,
+		Summary:   "Update a resource server",
+		Body:      &ResourceServer{},
+		Responses: map[int]interface{}{http.StatusOK: &ResourceServer{}},
+	},
+	{
+		Method: "DELETE", Path: ResourceServersHandlerPath + "/:id", ID: "deleteResourceServer", Tag: "warden", Scope: resourceServersScope,
+		Summary:   "Delete a resource server",
+		Responses: map[int]interface{}{http.StatusNoContent: nil},
+	},
+	{
+		Method: "GET", Path: SnapshotHandlerPath, ID: "getWardenSnapshot", Tag: "warden", Scope: snapshotScope,
+		Summary:   "Get a snapshot of the policies, public keys and revoked tokens, signed as a compact JWS",
+		Responses: map[int]interface{}{http.StatusOK: ""},
+	},
+}
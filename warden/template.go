@@ -0,0 +1,134 @@
+package warden
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/ladon"
+)
+
+var templateVariable = regexp.MustCompile(`\{([a-zA-Z0-9_]+)\}`)
+
+// ResourceTemplate describes a resource name with placeholders, for example "rn:api:orders:{id}". Resource servers
+// register templates once and send the concrete values with each access request, so policies can match the
+// template and use conditions on the values instead of regular expressions built by the resource server.
+type ResourceTemplate struct {
+	ID          string `json:"id" gorethink:"id"`
+	Description string `json:"description" gorethink:"description"`
+	Template    string `json:"template" valid:"required" gorethink:"template"`
+
+	// Constraints maps a variable name to the regular expression its value must match. The expression must match the
+	// whole value.
+	Constraints map[string]string `json:"constraints" gorethink:"constraints"`
+
+	// compiled are the anchored constraints, they are compiled when the template is stored.
+	compiled map[string]*regexp.Regexp
+}
+
+// WardenTemplateRequest references a registered template and the values for its variables.
+type WardenTemplateRequest struct {
+	Template string            `json:"template,omitempty"`
+	Values   map[string]string `json:"values,omitempty"`
+}
+
+func (t *ResourceTemplate) GetID() string {
+	return t.ID
+}
+
+// Variables returns the names of all placeholders in the template.
+func (t *ResourceTemplate) Variables() []string {
+	var vars []string
+	for _, match := range templateVariable.FindAllStringSubmatch(t.Template, -1) {
+		vars = append(vars, match[1])
+	}
+	return vars
+}
+
+// Validate checks that every variable has a value and that the value satisfies its constraint.
+func (t *ResourceTemplate) Validate(values map[string]string) error {
+	constraints := t.compiled
+	if constraints == nil {
+		var err error
+		if constraints, err = compileConstraints(t.Constraints); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range t.Variables() {
+		value, ok := values[v]
+		if !ok || value == "" {
+			return errors.Errorf("Missing value for template variable %s", v)
+		}
+
+		if constraint, ok := constraints[v]; ok && !constraint.MatchString(value) {
+			return errors.Errorf("Value of template variable %s does not match constraint %s", v, t.Constraints[v])
+		}
+	}
+
+	for k := range values {
+		if !strings.Contains(t.Template, "{"+k+"}") {
+			return errors.Errorf("Template %s has no variable %s", t.Template, k)
+		}
+	}
+	return nil
+}
+
+// compile compiles the constraints of a template which is about to be stored.
+func (t *ResourceTemplate) compile() error {
+	compiled, err := compileConstraints(t.Constraints)
+	if err != nil {
+		return err
+	}
+	t.compiled = compiled
+	return nil
+}
+
+// compileConstraints anchors every constraint, so that it matches the whole value instead of a part of it.
+func compileConstraints(constraints map[string]string) (map[string]*regexp.Regexp, error) {
+	compiled := map[string]*regexp.Regexp{}
+	for k, c := range constraints {
+		re, err := regexp.Compile(`^(?:` + c + `)$`)
+		if err != nil {
+			return nil, errors.Errorf("Constraint of template variable %s is invalid: %s", k, err)
+		}
+		compiled[k] = re
+	}
+	return compiled, nil
+}
+
+// Expand replaces the placeholders with the given values.
+func (t *ResourceTemplate) Expand(values map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(t.Template, func(match string) string {
+		return values[match[1:len(match)-1]]
+	})
+}
+
+// ExpandTemplate rewrites an access request which references a template. The request's resource is set to the
+// template itself so policies can match it literally, and the concrete values as well as the expanded resource
+// name are added to the request context where conditions can inspect them.
+func ExpandTemplate(m TemplateManager, a *ladon.Request, tr *WardenTemplateRequest) error {
+	if tr == nil || tr.Template == "" {
+		return nil
+	}
+
+	t, err := m.FindTemplate(tr.Template)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Validate(tr.Values); err != nil {
+		return err
+	}
+
+	if a.Context == nil {
+		a.Context = ladon.Context{}
+	}
+
+	for k, v := range tr.Values {
+		a.Context[k] = v
+	}
+	a.Context["resource"] = t.Expand(tr.Values)
+	a.Resource = t.Template
+	return nil
+}
@@ -0,0 +1,15 @@
+package warden
+
+// TemplateManager stores resource templates.
+type TemplateManager interface {
+	CreateTemplate(t *ResourceTemplate) error
+
+	GetTemplate(id string) (*ResourceTemplate, error)
+
+	// FindTemplate returns the template with the given template string, for example "rn:api:orders:{id}".
+	FindTemplate(template string) (*ResourceTemplate, error)
+
+	GetTemplates() (map[string]*ResourceTemplate, error)
+
+	DeleteTemplate(id string) error
+}
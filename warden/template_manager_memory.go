@@ -0,0 +1,83 @@
+package warden
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+)
+
+type TemplateMemoryManager struct {
+	Templates map[string]*ResourceTemplate
+	sync.RWMutex
+}
+
+func NewTemplateMemoryManager() *TemplateMemoryManager {
+	return &TemplateMemoryManager{
+		Templates: map[string]*ResourceTemplate{},
+	}
+}
+
+func (m *TemplateMemoryManager) CreateTemplate(t *ResourceTemplate) error {
+	if err := t.compile(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.Templates[t.GetID()] = t
+	return nil
+}
+
+func (m *TemplateMemoryManager) GetTemplate(id string) (*ResourceTemplate, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	t, ok := m.Templates[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return t, nil
+}
+
+func (m *TemplateMemoryManager) FindTemplate(template string) (*ResourceTemplate, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return findTemplate(m.Templates, template)
+}
+
+func (m *TemplateMemoryManager) GetTemplates() (map[string]*ResourceTemplate, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return copyTemplates(m.Templates), nil
+}
+
+func (m *TemplateMemoryManager) DeleteTemplate(id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.Templates, id)
+	return nil
+}
+
+func findTemplate(templates map[string]*ResourceTemplate, template string) (*ResourceTemplate, error) {
+	for _, t := range templates {
+		if t.Template == template {
+			return t, nil
+		}
+	}
+	return nil, errors.New(pkg.ErrNotFound)
+}
+
+// copyTemplates copies the map of templates, so that callers can not modify the cache of a manager. Stored templates
+// are not changed, they are replaced.
+func copyTemplates(templates map[string]*ResourceTemplate) map[string]*ResourceTemplate {
+	result := make(map[string]*ResourceTemplate, len(templates))
+	for id, t := range templates {
+		result[id] = t
+	}
+	return result
+}
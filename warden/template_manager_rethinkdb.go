@@ -0,0 +1,120 @@
+package warden
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/ory-am/hydra/pkg"
+	"golang.org/x/net/context"
+	r "gopkg.in/dancannon/gorethink.v2"
+)
+
+type TemplateRethinkManager struct {
+	Session *r.Session
+	Table   r.Term
+
+	Templates map[string]*ResourceTemplate
+
+	// Feed streams the changes of Table. It is created by Watch if nil and can be subscribed to by other
+	// subsystems which need to react to template changes.
+	Feed *pkg.ChangeFeed
+
+	sync.RWMutex
+}
+
+func (m *TemplateRethinkManager) CreateTemplate(t *ResourceTemplate) error {
+	if err := t.compile(); err != nil {
+		return err
+	}
+
+	if _, err := m.Table.Insert(t).RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *TemplateRethinkManager) GetTemplate(id string) (*ResourceTemplate, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	t, ok := m.Templates[id]
+	if !ok {
+		return nil, errors.New(pkg.ErrNotFound)
+	}
+	return t, nil
+}
+
+func (m *TemplateRethinkManager) FindTemplate(template string) (*ResourceTemplate, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return findTemplate(m.Templates, template)
+}
+
+func (m *TemplateRethinkManager) GetTemplates() (map[string]*ResourceTemplate, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return copyTemplates(m.Templates), nil
+}
+
+func (m *TemplateRethinkManager) DeleteTemplate(id string) error {
+	if _, err := m.Table.Get(id).Delete().RunWrite(m.Session); err != nil {
+		return errors.New(err)
+	}
+	return nil
+}
+
+func (m *TemplateRethinkManager) ColdStart() error {
+	rows, err := m.Table.Run(m.Session)
+	if err != nil {
+		return errors.New(err)
+	}
+	defer rows.Close()
+
+	templates := map[string]*ResourceTemplate{}
+	var t *ResourceTemplate
+	for rows.Next(&t) {
+		if err := t.compile(); err != nil {
+			return err
+		}
+		templates[t.ID] = t
+		t = nil
+	}
+
+	if rows.Err() != nil {
+		return errors.New(rows.Err())
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.Templates = templates
+	return nil
+}
+
+func (m *TemplateRethinkManager) Watch(ctx context.Context) {
+	if m.Feed == nil {
+		m.Feed = &pkg.ChangeFeed{Session: m.Session, Table: m.Table}
+	}
+
+	m.Feed.Subscribe(m.ColdStart, func(change *pkg.Change) error {
+		var newVal, oldVal *ResourceTemplate
+		if err := change.Decode(&oldVal, &newVal); err != nil {
+			return err
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		if oldVal != nil {
+			delete(m.Templates, oldVal.GetID())
+		}
+		if newVal != nil {
+			if err := newVal.compile(); err != nil {
+				return err
+			}
+			m.Templates[newVal.GetID()] = newVal
+		}
+		return nil
+	})
+	m.Feed.Start(ctx)
+}
@@ -0,0 +1,96 @@
+package warden_test
+
+import (
+	"testing"
+
+	"github.com/ory-am/hydra/pkg"
+	. "github.com/ory-am/hydra/warden"
+	"github.com/ory-am/ladon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceTemplate(t *testing.T) {
+	rt := &ResourceTemplate{
+		ID:       "orders",
+		Template: "rn:api:tenants:{tenant}:orders:{id}",
+		Constraints: map[string]string{
+			"id": "^[0-9]+$",
+		},
+	}
+
+	assert.Equal(t, []string{"tenant", "id"}, rt.Variables())
+	assert.Nil(t, rt.Validate(map[string]string{"tenant": "acme", "id": "42"}))
+	assert.NotNil(t, rt.Validate(map[string]string{"tenant": "acme"}))
+	assert.NotNil(t, rt.Validate(map[string]string{"tenant": "acme", "id": "abc"}))
+	assert.NotNil(t, rt.Validate(map[string]string{"tenant": "acme", "id": "42", "foo": "bar"}))
+	assert.Equal(t, "rn:api:tenants:acme:orders:42", rt.Expand(map[string]string{"tenant": "acme", "id": "42"}))
+}
+
+func TestResourceTemplateAnchorsConstraints(t *testing.T) {
+	m := NewTemplateMemoryManager()
+	rt := &ResourceTemplate{
+		ID:       "orders",
+		Template: "rn:api:tenants:{tenant}:orders:{id}",
+		Constraints: map[string]string{
+			"tenant": "acme|initech",
+			"id":     "[0-9]+",
+		},
+	}
+	require.Nil(t, m.CreateTemplate(rt))
+
+	assert.Nil(t, rt.Validate(map[string]string{"tenant": "initech", "id": "42"}))
+	for _, values := range []map[string]string{
+		{"tenant": "acme", "id": "42abc"},
+		{"tenant": "acme", "id": "abc42"},
+		{"tenant": "acme-evil", "id": "42"},
+		{"tenant": "evil-initech", "id": "42"},
+	} {
+		assert.NotNil(t, rt.Validate(values), "%v", values)
+	}
+
+	assert.NotNil(t, m.CreateTemplate(&ResourceTemplate{
+		ID:          "invalid",
+		Template:    "rn:api:orders:{id}",
+		Constraints: map[string]string{"id": "[0-9"},
+	}))
+}
+
+func TestGetTemplatesReturnsCopy(t *testing.T) {
+	m := NewTemplateMemoryManager()
+	require.Nil(t, m.CreateTemplate(&ResourceTemplate{ID: "orders", Template: "rn:api:orders:{id}"}))
+
+	templates, err := m.GetTemplates()
+	require.Nil(t, err)
+	delete(templates, "orders")
+	templates["foo"] = &ResourceTemplate{ID: "foo", Template: "rn:api:foo"}
+
+	templates, err = m.GetTemplates()
+	require.Nil(t, err)
+	assert.Len(t, templates, 1)
+	assert.NotNil(t, templates["orders"])
+}
+
+func TestExpandTemplate(t *testing.T) {
+	m := NewTemplateMemoryManager()
+	require.Nil(t, m.CreateTemplate(&ResourceTemplate{
+		ID:       "orders",
+		Template: "rn:api:orders:{id}",
+	}))
+
+	a := &ladon.Request{Subject: "alice", Action: "get"}
+	require.Nil(t, ExpandTemplate(m, a, &WardenTemplateRequest{
+		Template: "rn:api:orders:{id}",
+		Values:   map[string]string{"id": "42"},
+	}))
+	assert.Equal(t, "rn:api:orders:{id}", a.Resource)
+	assert.Equal(t, "42", a.Context["id"])
+	assert.Equal(t, "rn:api:orders:42", a.Context["resource"])
+
+	a = &ladon.Request{Resource: "rn:api:orders:42"}
+	require.Nil(t, ExpandTemplate(m, a, nil))
+	assert.Equal(t, "rn:api:orders:42", a.Resource)
+
+	err := ExpandTemplate(m, &ladon.Request{}, &WardenTemplateRequest{Template: "rn:api:unknown:{id}"})
+	pkg.AssertError(t, true, err)
+}
@@ -27,7 +27,7 @@ func (w *HTTPWarden) SetClient(c *clientcredentials.Config) {
 }
 
 func (w *HTTPWarden) ActionAllowed(ctx context.Context, token string, a *ladon.Request, scopes ...string) (*Context, error) {
-	return w.doRequest(AllowedHandlerPath, &WardenAccessRequest{
+	return w.doRequest(TokenAllowedHandlerPath, &WardenAccessRequest{
 		Request: a,
 		WardenAuthorizedRequest: &WardenAuthorizedRequest{
 			Assertion: token,
@@ -36,6 +36,22 @@ func (w *HTTPWarden) ActionAllowed(ctx context.Context, token string, a *ladon.R
 	})
 }
 
+// TemplateActionAllowed checks if token is allowed to perform the action on the resource described by a registered
+// resource template and the values of its variables.
+func (w *HTTPWarden) TemplateActionAllowed(ctx context.Context, token string, a *ladon.Request, template string, values map[string]string, scopes ...string) (*Context, error) {
+	return w.doRequest(TokenAllowedHandlerPath, &WardenAccessRequest{
+		Request: a,
+		WardenAuthorizedRequest: &WardenAuthorizedRequest{
+			Assertion: token,
+			Scopes:    scopes,
+		},
+		WardenTemplateRequest: &WardenTemplateRequest{
+			Template: template,
+			Values:   values,
+		},
+	})
+}
+
 func (w *HTTPWarden) HTTPActionAllowed(ctx context.Context, r *http.Request, a *ladon.Request, scopes ...string) (*Context, error) {
 	token := TokenFromRequest(r)
 	if token == "" {
@@ -61,10 +77,28 @@ func (w *HTTPWarden) HTTPAuthorized(ctx context.Context, r *http.Request, scopes
 	return w.Authorized(ctx, token, scopes...)
 }
 
+// IsAllowed checks if the subject of the request is allowed to perform the action on the resource without
+// validating a token.
+func (w *HTTPWarden) IsAllowed(ctx context.Context, a *ladon.Request) (bool, error) {
+	var epResp WardenAllowedResponse
+	if err := w.post(AllowedHandlerPath, &WardenAccessRequest{Request: a}, &epResp); err != nil {
+		return false, err
+	}
+	return epResp.Allowed, nil
+}
+
 func (w *HTTPWarden) doRequest(path string, request interface{}) (*Context, error) {
+	var epResp WardenResponse
+	if err := w.post(path, request, &epResp); err != nil {
+		return nil, err
+	}
+	return epResp.Context, nil
+}
+
+func (w *HTTPWarden) post(path string, request, response interface{}) error {
 	out, err := json.Marshal(request)
 	if err != nil {
-		return nil, errors.New(err)
+		return errors.New(err)
 	}
 
 	var ep = new(url.URL)
@@ -72,29 +106,27 @@ func (w *HTTPWarden) doRequest(path string, request interface{}) (*Context, erro
 	ep.Path = path
 	req, err := http.NewRequest("POST", ep.String(), bytes.NewBuffer(out))
 	if err != nil {
-		return nil, errors.New(err)
+		return errors.New(err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := w.Client.Do(req)
 	if err != nil {
-		return nil, errors.New(err)
+		return errors.New(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		all, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, errors.New(err)
+			return errors.New(err)
 		}
 
-		return nil, errors.Errorf("Got error (%d): %s", resp.StatusCode, all)
+		return errors.Errorf("Got error (%d): %s", resp.StatusCode, all)
 	}
 
-	var epResp WardenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&epResp); err != nil {
-		return nil, errors.New(err)
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return errors.New(err)
 	}
-
-	return epResp.Context, nil
+	return nil
 }
@@ -2,6 +2,7 @@ package warden
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/go-errors/errors"
@@ -9,8 +10,11 @@ import (
 	"github.com/ory-am/fosite/handler/core"
 	. "github.com/ory-am/hydra/firewall"
 	"github.com/ory-am/hydra/herodot"
+	"github.com/ory-am/hydra/logger"
 	"github.com/ory-am/hydra/oauth2"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/scope"
+	"github.com/ory-am/hydra/tracing"
 	"github.com/ory-am/ladon"
 	"golang.org/x/net/context"
 )
@@ -20,6 +24,10 @@ type LocalWarden struct {
 	TokenValidator *core.CoreValidator
 
 	Issuer string
+
+	// ScopeStrategy decides whether the scopes granted to a token cover the requested scopes, nil is
+	// scope.Hierarchic.
+	ScopeStrategy scope.Strategy
 }
 
 func (w *LocalWarden) actionAllowed(ctx context.Context, a *ladon.Request, scopes []string, oauthRequest fosite.AccessRequester, session *oauth2.Session) (*Context, error) {
@@ -28,11 +36,12 @@ func (w *LocalWarden) actionAllowed(ctx context.Context, a *ladon.Request, scope
 		return nil, errors.New("Subject mismatch " + a.Subject + " - " + session.Subject)
 	}
 
-	if !matchScopes(oauthRequest.GetGrantedScopes(), scopes, session, oauthRequest.GetClient()) {
+	if !w.matchScopes(oauthRequest.GetGrantedScopes(), scopes, session, oauthRequest.GetClient()) {
 		return nil, errors.New(herodot.ErrForbidden)
 	}
 
 	a.Subject = session.Subject
+	a.Context = withAuthenticationContext(a.Context, session)
 	if err := w.Warden.IsAllowed(a); err != nil {
 		return nil, err
 	}
@@ -44,16 +53,16 @@ func (w *LocalWarden) actionAllowed(ctx context.Context, a *ladon.Request, scope
 		"request":  a,
 	}).Infof("Access granted")
 
-	return &Context{
-		Subject:       session.Subject,
-		GrantedScopes: oauthRequest.GetGrantedScopes(),
-		Issuer:        w.Issuer,
-		Audience:      oauthRequest.GetClient().GetID(),
-		IssuedAt:      oauthRequest.GetRequestedAt(),
-	}, nil
+	return w.newContext(oauthRequest, session), nil
 }
 
-func (w *LocalWarden) ActionAllowed(ctx context.Context, token string, a *ladon.Request, scopes ...string) (*Context, error) {
+func (w *LocalWarden) ActionAllowed(ctx context.Context, token string, a *ladon.Request, scopes ...string) (c *Context, err error) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "warden.action_allowed")
+	defer func(start time.Time) {
+		tracing.Finish(span, err)
+		observeDecision("action_allowed", start, c, err)
+	}(time.Now())
+
 	var session = new(oauth2.Session)
 	var oauthRequest = fosite.NewAccessRequest(session)
 	if err := w.TokenValidator.ValidateToken(ctx, oauthRequest, token); err != nil {
@@ -63,7 +72,14 @@ func (w *LocalWarden) ActionAllowed(ctx context.Context, token string, a *ladon.
 	return w.actionAllowed(ctx, a, scopes, oauthRequest, session)
 }
 
-func (w *LocalWarden) HTTPActionAllowed(ctx context.Context, r *http.Request, a *ladon.Request, scopes ...string) (*Context, error) {
+func (w *LocalWarden) HTTPActionAllowed(ctx context.Context, r *http.Request, a *ladon.Request, scopes ...string) (c *Context, err error) {
+	span, ctx := tracing.StartSpanFromContext(tracing.WithRequest(ctx, r), "warden.action_allowed")
+	defer func(start time.Time) {
+		tracing.Finish(span, err)
+		observeDecision("action_allowed", start, c, err)
+		annotateRequest(r, c)
+	}(time.Now())
+
 	var session = new(oauth2.Session)
 	var oauthRequest = fosite.NewAccessRequest(session)
 
@@ -76,7 +92,13 @@ func (w *LocalWarden) HTTPActionAllowed(ctx context.Context, r *http.Request, a
 	return w.actionAllowed(ctx, a, scopes, oauthRequest, session)
 }
 
-func (w *LocalWarden) Authorized(ctx context.Context, token string, scopes ...string) (*Context, error) {
+func (w *LocalWarden) Authorized(ctx context.Context, token string, scopes ...string) (c *Context, err error) {
+	span, ctx := tracing.StartSpanFromContext(ctx, "warden.authorized")
+	defer func(start time.Time) {
+		tracing.Finish(span, err)
+		observeDecision("authorized", start, c, err)
+	}(time.Now())
+
 	var session = new(oauth2.Session)
 	var oauthRequest = fosite.NewAccessRequest(session)
 
@@ -85,20 +107,24 @@ func (w *LocalWarden) Authorized(ctx context.Context, token string, scopes ...st
 	}
 
 	session = oauthRequest.GetSession().(*oauth2.Session)
-	if !matchScopes(oauthRequest.GetGrantedScopes(), scopes, session, oauthRequest.Client) {
+	if session.IsTokenExpired(pkg.TokenKindAccessToken) {
+		return nil, errors.New(fosite.ErrRequestUnauthorized)
+	}
+	if !w.matchScopes(oauthRequest.GetGrantedScopes(), scopes, session, oauthRequest.Client) {
 		return nil, errors.New(herodot.ErrForbidden)
 	}
 
-	return &Context{
-		Subject:       session.Subject,
-		GrantedScopes: oauthRequest.GetGrantedScopes(),
-		Issuer:        w.Issuer,
-		Audience:      oauthRequest.GetClient().GetID(),
-		IssuedAt:      oauthRequest.GetRequestedAt(),
-	}, nil
+	return w.newContext(oauthRequest, session), nil
 }
 
-func (w *LocalWarden) HTTPAuthorized(ctx context.Context, r *http.Request, scopes ...string) (*Context, error) {
+func (w *LocalWarden) HTTPAuthorized(ctx context.Context, r *http.Request, scopes ...string) (c *Context, err error) {
+	span, ctx := tracing.StartSpanFromContext(tracing.WithRequest(ctx, r), "warden.authorized")
+	defer func(start time.Time) {
+		tracing.Finish(span, err)
+		observeDecision("authorized", start, c, err)
+		annotateRequest(r, c)
+	}(time.Now())
+
 	var session = new(oauth2.Session)
 	var oauthRequest = fosite.NewAccessRequest(session)
 
@@ -109,32 +135,64 @@ func (w *LocalWarden) HTTPAuthorized(ctx context.Context, r *http.Request, scope
 	}
 
 	session = oauthRequest.GetSession().(*oauth2.Session)
-	if !matchScopes(oauthRequest.GetGrantedScopes(), scopes, session, oauthRequest.Client) {
+	if session.IsTokenExpired(pkg.TokenKindAccessToken) {
+		return nil, errors.New(fosite.ErrRequestUnauthorized)
+	}
+	if !w.matchScopes(oauthRequest.GetGrantedScopes(), scopes, session, oauthRequest.Client) {
 		return nil, errors.New(herodot.ErrForbidden)
 	}
 
+	return w.newContext(oauthRequest, session), nil
+}
+
+// annotateRequest adds the subject and client of an authorized request to the following log lines of the request.
+func annotateRequest(r *http.Request, c *Context) {
+	if c != nil {
+		logger.AddFields(r, logger.Fields{"subject": c.Subject, "client_id": c.Audience})
+	}
+}
+
+func (w *LocalWarden) newContext(oauthRequest fosite.AccessRequester, session *oauth2.Session) *Context {
+	audience := oauthRequest.GetClient().GetID()
+	if session.Audience != "" {
+		audience = session.Audience
+	}
+
 	return &Context{
-		Subject:       session.Subject,
-		GrantedScopes: oauthRequest.GetGrantedScopes(),
-		Issuer:        w.Issuer,
-		Audience:      oauthRequest.GetClient().GetID(),
-		IssuedAt:      oauthRequest.GetRequestedAt(),
-	}, nil
+		Subject:               session.Subject,
+		GrantedScopes:         oauthRequest.GetGrantedScopes(),
+		Issuer:                w.Issuer,
+		Audience:              audience,
+		IssuedAt:              oauthRequest.GetRequestedAt(),
+		AuthenticationMethods: session.AuthenticationMethods,
+		AuthenticationContext: session.AuthenticationContext,
+		UserVerified:          session.WebAuthn != nil && session.WebAuthn.UserVerified,
+	}
+}
+
+// withAuthenticationContext exposes how the subject logged in to policy conditions. The values are always taken from
+// the session so that callers can not claim a stronger login than the one that happened.
+func withAuthenticationContext(c ladon.Context, session *oauth2.Session) ladon.Context {
+	if c == nil {
+		c = ladon.Context{}
+	}
+
+	c["amr"] = session.AuthenticationMethods
+	c["acr"] = session.AuthenticationContext
+	c["webauthn_uv"] = session.WebAuthn != nil && session.WebAuthn.UserVerified
+	return c
 }
 
-func matchScopes(granted []string, requested []string, session *oauth2.Session, c fosite.Client) bool {
-	scopes := &fosite.DefaultScopes{Scopes: granted}
-	for _, r := range requested {
-		if !scopes.Grant(r) {
-			logrus.WithFields(logrus.Fields{
-				"reason":           "scope mismatch",
-				"granted_scopes":   granted,
-				"requested_scopes": requested,
-				"audience":         c.GetID(),
-				"subject":          session.Subject,
-			}).Infof("Authentication failed.")
-			return false
-		}
+func (w *LocalWarden) matchScopes(granted []string, requested []string, session *oauth2.Session, c fosite.Client) bool {
+	if !scope.Covers(w.ScopeStrategy, granted, requested) {
+		logrus.WithFields(logrus.Fields{
+			"reason":           "scope mismatch",
+			"granted_scopes":   granted,
+			"requested_scopes": requested,
+			"audience":         c.GetID(),
+			"subject":          session.Subject,
+		}).Infof("Authentication failed.")
+		return false
 	}
 
 	return true
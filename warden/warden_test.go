@@ -15,6 +15,7 @@ import (
 	"github.com/ory-am/hydra/herodot"
 	"github.com/ory-am/hydra/oauth2"
 	"github.com/ory-am/hydra/pkg"
+	"github.com/ory-am/hydra/scope"
 	"github.com/ory-am/hydra/warden"
 	"github.com/ory-am/ladon"
 	"github.com/stretchr/testify/assert"
@@ -239,3 +240,60 @@ func TestAuthorized(t *testing.T) {
 		}
 	}
 }
+
+func TestScopeStrategy(t *testing.T) {
+	for k, c := range []struct {
+		strategy  scope.Strategy
+		scopes    []string
+		expectErr bool
+	}{
+		{strategy: nil, scopes: []string{"hydra.warden.allowed"}, expectErr: false},
+		{strategy: scope.Hierarchic, scopes: []string{"hydra"}, expectErr: true},
+		{strategy: scope.Exact, scopes: []string{"hydra.warden.allowed"}, expectErr: true},
+		{strategy: scope.Exact, scopes: []string{"hydra.warden"}, expectErr: false},
+		{strategy: scope.Wildcard, scopes: []string{"hydra.warden.allowed"}, expectErr: true},
+	} {
+		w := &warden.LocalWarden{
+			Warden: ladonWarden,
+			TokenValidator: &core.CoreValidator{
+				AccessTokenStrategy: pkg.HMACStrategy,
+				AccessTokenStorage:  fositeStore,
+			},
+			Issuer:        "tests",
+			ScopeStrategy: c.strategy,
+		}
+
+		_, err := w.Authorized(context.Background(), tokens[1][1], c.scopes...)
+		pkg.AssertError(t, c.expectErr, err, "Authorized", k)
+	}
+}
+
+func TestIsAllowed(t *testing.T) {
+	w := wardens["http"].(*warden.HTTPWarden)
+	for k, c := range []struct {
+		req       *ladon.Request
+		expectErr bool
+		allowed   bool
+	}{
+		{
+			req:       &ladon.Request{Resource: "matrix", Action: "create"},
+			expectErr: true,
+		},
+		{
+			req:     &ladon.Request{Subject: "alice", Resource: "matrix", Action: "create"},
+			allowed: true,
+		},
+		{
+			req:     &ladon.Request{Subject: "alice", Resource: "matrix", Action: "delete"},
+			allowed: false,
+		},
+		{
+			req:     &ladon.Request{Subject: "mallet", Resource: "matrix", Action: "create"},
+			allowed: false,
+		},
+	} {
+		allowed, err := w.IsAllowed(context.Background(), c.req)
+		pkg.AssertError(t, c.expectErr, err, "IsAllowed", k)
+		assert.Equal(t, c.allowed, allowed, "%d", k)
+	}
+}